@@ -21,6 +21,7 @@ package main
 import (
 	"context"
 	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -123,6 +124,10 @@ func appTokensCreateCommand() *command {
 			return formatError(generateAppPasswordResponse.Status)
 		}
 
+		if jsonOutput() {
+			return printJSON(os.Stdout, generateAppPasswordResponse.AppPassword)
+		}
+
 		err = printTableAppPasswords([]*authapp.AppPassword{generateAppPasswordResponse.AppPassword})
 		if err != nil {
 			return err
@@ -54,6 +54,10 @@ func appTokensListCommand() *command {
 			return formatError(listResponse.Status)
 		}
 
+		if jsonOutput() {
+			return printJSON(os.Stdout, listResponse.AppPasswords)
+		}
+
 		err = printTableAppPasswords(listResponse.AppPasswords)
 		if err != nil {
 			return err
@@ -24,7 +24,6 @@ import (
 
 	applicationsv1beta1 "github.com/cs3org/go-cs3apis/cs3/auth/applications/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
-	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 )
 
 func appTokensRemoveCommand() *command {
@@ -34,7 +33,7 @@ func appTokensRemoveCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() != 1 {
-			return errtypes.BadRequest("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		token := cmd.Arg(0)
@@ -57,8 +56,9 @@ func appTokensRemoveCommand() *command {
 			return formatError(response.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(response.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 
 	return cmd
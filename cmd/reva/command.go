@@ -36,7 +36,7 @@ type command struct {
 
 // newCommand creates a new command.
 func newCommand(name string) *command {
-	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	cmd := &command{
 		Name: name,
 		Usage: func() string {
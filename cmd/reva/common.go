@@ -30,10 +30,18 @@ import (
 )
 
 const (
-	viewerPermission string = "viewer"
-	editorPermission string = "editor"
-	collabPermission string = "collab"
-	denyPermission   string = "denied"
+	viewerPermission  string = "viewer"
+	editorPermission  string = "editor"
+	collabPermission  string = "collab"
+	denyPermission    string = "denied"
+	managerPermission string = "manager"
+)
+
+// the environment variables used instead of the configuration and token files,
+// e.g. when the cli is used in scripts
+const (
+	hostEnv  = "REVA_HOST"
+	tokenEnv = "REVA_TOKEN"
 )
 
 type config struct {
@@ -81,6 +89,9 @@ func getTokenFile() string {
 }
 
 func readToken() (string, error) {
+	if t := os.Getenv(tokenEnv); t != "" {
+		return t, nil
+	}
 	data, err := os.ReadFile(getTokenFile())
 	if err != nil {
 		return "", err
@@ -0,0 +1,100 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+	"path"
+	"path/filepath"
+
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+func cpCommand() *command {
+	cmd := newCommand("cp")
+	cmd.Description = func() string {
+		return "copy files and directories between the local filesystem and the remote server, prefix remote paths with " + remotePrefix
+	}
+	cmd.Usage = func() string { return "Usage: cp [-flags] <source> <target>" }
+	recursive := cmd.Bool("r", false, "copy directories recursively")
+	parallel := cmd.Int("parallel", 4, "the number of files to transfer in parallel")
+	protocol := cmd.String("protocol", "tus", "the protocol to be used for uploads")
+
+	cmd.ResetFlags = func() {
+		*recursive, *parallel, *protocol = false, 4, "tus"
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 2 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		src, err := parseLocation(cmd.Args()[0])
+		if err != nil {
+			return err
+		}
+		dst, err := parseLocation(cmd.Args()[1])
+		if err != nil {
+			return err
+		}
+		if src.remote == dst.remote {
+			return errors.New("either the source or the target must be remote, prefix remote paths with " + remotePrefix)
+		}
+
+		ctx := getAuthContext()
+		gwc, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		st, err := walk(ctx, gwc, src)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			return errtypes.NotFound(src.String())
+		}
+		if st["."].dir && !*recursive {
+			return errors.New(src.String() + " is a directory, use -r to copy it")
+		}
+
+		dt, err := walk(ctx, gwc, dst)
+		if err != nil {
+			return err
+		}
+		// like cp, copy into existing directories
+		if dt != nil && dt["."].dir {
+			base := path.Base(src.path)
+			if !src.remote {
+				base = filepath.Base(src.path)
+			}
+			dst = dst.join(base)
+			if dt, err = walk(ctx, gwc, dst); err != nil {
+				return err
+			}
+		}
+
+		deletes, dirs, jobs := plan(src, dst, st, dt, false)
+		if err := applyPlan(ctx, gwc, deletes, dirs, jobs, *parallel, *protocol); err != nil {
+			return err
+		}
+		return printResult(newTreeSummary(len(st), deletes, dirs, jobs), func() {})
+	}
+	return cmd
+}
@@ -19,7 +19,6 @@
 package main
 
 import (
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -43,7 +42,7 @@ func downloadCommand() *command {
 	cmd.Usage = func() string { return "Usage: download [-flags] <remote_file> <local_file>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 2 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		remote := cmd.Args()[0]
@@ -85,7 +84,7 @@ func downloadCommand() *command {
 		}
 
 		// TODO(labkode): upload to data server
-		fmt.Printf("Downloading from: %s\n", p.DownloadEndpoint)
+		infof("Downloading from: %s\n", p.DownloadEndpoint)
 
 		content, err := checkDownloadWebdavRef(res.Protocols)
 		if err != nil {
@@ -109,7 +108,7 @@ func downloadCommand() *command {
 			defer httpRes.Body.Close()
 
 			if httpRes.StatusCode != http.StatusOK {
-				return err
+				return errtypes.NewErrtypeFromHTTPStatusCode(httpRes.StatusCode, "download: GET request returned "+httpRes.Status)
 			}
 			content = httpRes.Body
 		}
@@ -127,11 +126,12 @@ func downloadCommand() *command {
 		if err != nil {
 			return err
 		}
+		defer fd.Close()
 		if _, err := io.Copy(fd, reader); err != nil {
 			return err
 		}
 		bar.Finish()
-		return nil
+		return printResult(info, func() {})
 	}
 	return cmd
 }
@@ -0,0 +1,115 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+)
+
+// duUsage is the result of the du command
+type duUsage struct {
+	Path        string    `json:"path"`
+	Size        uint64    `json:"size"`
+	Files       int       `json:"files"`
+	Directories int       `json:"directories"`
+	Entries     []duEntry `json:"entries"`
+}
+
+type duEntry struct {
+	Path string `json:"path"`
+	Size uint64 `json:"size"`
+}
+
+func duCommand() *command {
+	cmd := newCommand("du")
+	cmd.Description = func() string { return "summarize the disk usage of a remote directory tree" }
+	cmd.Usage = func() string { return "Usage: du [-flags] <path>" }
+	depth := cmd.Int("d", -1, "only print directories up to the given depth below the path, -1 prints all")
+	all := cmd.Bool("a", false, "print files as well as directories")
+
+	cmd.ResetFlags = func() {
+		*depth, *all = -1, false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		root := path.Clean("/" + strings.TrimPrefix(cmd.Args()[0], remotePrefix))
+
+		ctx := getAuthContext()
+		gwc, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		t, err := walkRemote(ctx, gwc, root)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return errtypes.NotFound(root)
+		}
+
+		// add up the sizes of the files instead of trusting the tree sizes of the storage
+		sizes := map[string]uint64{}
+		files, dirs := 0, 0
+		for rel, e := range t {
+			if e.dir {
+				dirs++
+				continue
+			}
+			files++
+			for p := rel; ; p = path.Dir(p) {
+				sizes[p] += e.size
+				if p == "." {
+					break
+				}
+			}
+		}
+
+		usage := duUsage{Path: root, Size: sizes["."], Files: files, Directories: dirs}
+		paths := t.sortedPaths()
+		// list children before their parents, like du does
+		for i := len(paths) - 1; i >= 0; i-- {
+			rel := paths[i]
+			e := t[rel]
+			if !e.dir && !*all && rel != "." {
+				continue
+			}
+			if *depth >= 0 && rel != "." && strings.Count(rel, "/")+1 > *depth {
+				continue
+			}
+			usage.Entries = append(usage.Entries, duEntry{Path: path.Join(root, rel), Size: sizes[rel]})
+		}
+
+		return printResult(usage, func() {
+			for _, e := range usage.Entries {
+				fmt.Printf("%d\t%s\n", e.Size, e.Path)
+			}
+			fmt.Printf("%d files and %d directories\n", files, dirs)
+		})
+	}
+	return cmd
+}
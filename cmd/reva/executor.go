@@ -19,13 +19,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // Executor provides exec command handler.
@@ -43,14 +47,52 @@ func (e *Executor) Execute(s string) {
 		os.Exit(0)
 	}
 
-	args := strings.Split(s, " ")
+	args, err := splitArgs(s)
+	if err == nil {
+		err = e.Run(args)
+	}
+	if err != nil {
+		printError(os.Stdout, err)
+	}
+}
 
+// ExecuteScript executes the commands read from r, one per line. Empty lines and
+// lines starting with # are skipped. The execution stops at the first command
+// that fails, the returned exit code is the one of the failed command.
+func (e *Executor) ExecuteScript(r io.Reader) int {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		switch {
+		case s == "", strings.HasPrefix(s, "#"):
+			continue
+		case s == "exit", s == "quit":
+			return 0
+		}
+
+		args, err := splitArgs(s)
+		if err == nil {
+			err = e.Run(args)
+		}
+		if err != nil {
+			printError(os.Stderr, fmt.Errorf("line %d: %w", line, err))
+			return exitCode(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		printError(os.Stderr, err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+// Run executes the command with the given arguments, the first argument being the name of the command.
+func (e *Executor) Run(args []string) error {
 	// Verify that the configuration is set, either in memory or in a file.
 	if conf == nil || conf.Host == "" {
 		c, err := readConfig()
 		if err != nil && args[0] != "configure" {
-			fmt.Println("reva is not configured, please pass the -host flag or run the configure command")
-			return
+			return errors.New("reva is not configured, please pass the -host flag, set " + hostEnv + " or run the configure command")
 		} else if args[0] != "configure" {
 			conf = c
 		}
@@ -59,47 +101,45 @@ func (e *Executor) Execute(s string) {
 	action := args[0]
 	for _, v := range commands {
 		if v.Name == action {
+			defer v.ResetFlags()
 			if err := v.Parse(args[1:]); err != nil {
-				fmt.Println(err)
-				return
+				if errors.Is(err, flag.ErrHelp) {
+					return nil
+				}
+				return usageError(err.Error())
 			}
-			defer v.ResetFlags()
 
-			ctx := context.Background()
-			ctx, cancel := context.WithCancel(ctx)
+			ctx, cancel := context.WithCancelCause(context.Background())
 			signalChan := make(chan os.Signal, 1)
 			signal.Notify(signalChan, os.Interrupt)
 			defer func() {
 				signal.Stop(signalChan)
-				cancel()
+				cancel(nil)
 			}()
 
 			go func() {
 				if e.Timeout > 0 {
 					select {
 					case <-signalChan:
-						cancel()
+						cancel(errCancelled)
 					case <-time.After(time.Duration(e.Timeout * int64(time.Second))):
-						cancel()
+						cancel(context.DeadlineExceeded)
 					case <-ctx.Done():
 					}
 				} else {
 					select {
 					case <-signalChan:
-						cancel()
+						cancel(errCancelled)
 					case <-ctx.Done():
 					}
 				}
 			}()
 
-			if err := executeWithContext(ctx, v); err != nil {
-				fmt.Println(err.Error())
-			}
-			return
+			return executeWithContext(ctx, v)
 		}
 	}
 
-	fmt.Println("Invalid command. Use \"help\" to list the available commands.")
+	return usageError("Invalid command. Use \"help\" to list the available commands.")
 }
 
 func executeWithContext(ctx context.Context, cmd *command) error {
@@ -109,8 +149,51 @@ func executeWithContext(ctx context.Context, cmd *command) error {
 	}()
 	select {
 	case <-ctx.Done():
-		return errors.New("cancelled by user")
+		return context.Cause(ctx)
 	case err := <-c:
 		return err
 	}
 }
+
+// exitCode returns the exit code for the error returned by a command
+func exitCode(err error) int {
+	return exitCodeFromCode(errorStatus(err).Code)
+}
+
+// splitArgs splits a command line into its arguments. Arguments
+// containing spaces can be enclosed in single or double quotes.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, usageError("unterminated quote in: " + s)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -19,7 +19,6 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"io"
 
@@ -37,7 +36,7 @@ func getlockCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -62,9 +61,9 @@ func getlockCommand() *command {
 			return formatError(res.Status)
 		}
 
-		fmt.Println(render.Render(res.Lock))
-
-		return nil
+		return printResult(res.Lock, func() {
+			fmt.Println(render.Render(res.Lock))
+		})
 	}
 	return cmd
 }
@@ -21,7 +21,6 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
 	"log"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -35,7 +34,7 @@ import (
 
 func getAuthContext() context.Context {
 	ctx := context.Background()
-	// read token from file
+	// read token from the environment or the file
 	t, err := readToken()
 	if err != nil {
 		log.Println(err)
@@ -67,5 +66,5 @@ func getConn() (*grpc.ClientConn, error) {
 }
 
 func formatError(status *rpc.Status) error {
-	return fmt.Errorf("error: code=%+v msg=%q support_trace=%q", status.Code, status.Message, status.Trace)
+	return &statusError{status: status}
 }
@@ -27,8 +27,9 @@ var helpCommand = func() *command {
 	cmd := newCommand("help")
 	cmd.Description = func() string { return "help for using reva CLI" }
 	cmd.Action = func(w ...io.Writer) error {
-		fmt.Println(helpCommandOutput)
-		return nil
+		return printResult(helpCommandUsage, func() {
+			fmt.Println(helpCommandOutput)
+		})
 	}
 	return cmd
 }
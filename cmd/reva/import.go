@@ -20,11 +20,10 @@ package main
 
 import (
 	"io"
-	"log"
 	"path"
 
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/migrate"
-	"github.com/pkg/errors"
 )
 
 func importCommand() *command {
@@ -39,7 +38,7 @@ func importCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 		exportPath := cmd.Args()[0]
 
@@ -52,15 +51,13 @@ func importCommand() *command {
 		ns := path.Join("/", *namespaceFlag)
 
 		if err := migrate.ImportMetadata(ctx, client, exportPath, ns); err != nil {
-			log.Fatal(err)
 			return err
 		}
 		if err := migrate.ImportShares(ctx, client, exportPath, ns); err != nil {
-			log.Fatal(err)
 			return err
 		}
 
-		return nil
+		return printResult(&rpc.Status{Code: rpc.Code_CODE_OK}, func() {})
 	}
 	return cmd
 }
@@ -22,7 +22,6 @@ import (
 	"bufio"
 	"context"
 	"encoding/gob"
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -66,7 +65,9 @@ var loginCommand = func() *command {
 				return formatError(res.Status)
 			}
 
-			if len(w) == 0 {
+			if len(w) == 0 && jsonOutput() {
+				return printJSON(os.Stdout, res.Types)
+			} else if len(w) == 0 {
 				fmt.Println("Available login methods:")
 				for _, v := range res.Types {
 					fmt.Printf("- %s\n", v)
@@ -81,7 +82,7 @@ var loginCommand = func() *command {
 		}
 
 		if cmd.NArg() != 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		authType := cmd.Args()[0]
@@ -97,13 +98,13 @@ var loginCommand = func() *command {
 		} else {
 			// for the other methods, take the username and pw from the stdin
 			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("username: ")
+			infof("username: ")
 			username, err = read(reader)
 			if err != nil {
 				return err
 			}
 
-			fmt.Print("password: ")
+			infof("password: ")
 			password, err = readPassword(0)
 			if err != nil {
 				return err
@@ -132,8 +133,10 @@ var loginCommand = func() *command {
 		}
 
 		writeToken(res.Token)
-		fmt.Println("OK")
-		return nil
+		// the response contains the token, scripts can pass it on with the REVA_TOKEN variable
+		return printResult(res, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
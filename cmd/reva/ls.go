@@ -22,11 +22,11 @@ import (
 	"encoding/gob"
 	"fmt"
 	"io"
+	"os"
 	"path"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func lsCommand() *command {
@@ -42,7 +42,7 @@ func lsCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -65,6 +65,9 @@ func lsCommand() *command {
 		}
 
 		infos := res.Infos
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, infos)
+		}
 		for _, info := range infos {
 			p := info.Path
 			if !*fullFlag {
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/c-bata/go-prompt"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 )
 
@@ -35,8 +36,10 @@ var (
 	host                                                        string
 	insecure, skipverify, disableargprompt, insecuredatagateway bool
 	timeout                                                     int64
+	outputFormat, script                                        string
 
 	helpCommandOutput string
+	helpCommandUsage  []map[string]string
 
 	gitCommit, buildDate, version, goVersion string
 
@@ -55,6 +58,9 @@ var (
 		rmCommand(),
 		moveCommand(),
 		mkdirCommand(),
+		cpCommand(),
+		syncCommand(),
+		duCommand(),
 		ocmFindAcceptedUsersCommand(),
 		ocmRemoveAcceptedUser(),
 		ocmInviteGenerateCommand(),
@@ -82,6 +88,15 @@ var (
 		shareUpdateCommand(),
 		shareListReceivedCommand(),
 		shareUpdateReceivedCommand(),
+		spaceListCommand(),
+		spaceCreateCommand(),
+		spaceUpdateCommand(),
+		spaceDisableCommand(),
+		spaceRestoreCommand(),
+		spaceDeleteCommand(),
+		spaceMemberListCommand(),
+		spaceMemberAddCommand(),
+		spaceMemberRemoveCommand(),
 		transferGetStatusCommand(),
 		transferCancelCommand(),
 		transferListCommand(),
@@ -113,16 +128,31 @@ func init() {
 	)
 	flag.BoolVar(&disableargprompt, "disable-arg-prompt", false, "whether to disable prompts for command arguments")
 	flag.Int64Var(&timeout, "timeout", -1, "the timeout in seconds for executing the commands, -1 means no timeout")
+	flag.StringVar(&outputFormat, "output", outputText, "the format to print the results of the commands in. One of: [text, json]")
+	flag.StringVar(
+		&script,
+		"script",
+		"",
+		"execute the commands read from the given file, one per line, and exit. Use - to read the commands from stdin",
+	)
 	flag.Parse()
 }
 
 func main() {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		fmt.Fprintf(os.Stderr, "invalid output format %q\n", outputFormat)
+		os.Exit(exitCodeFromCode(rpc.Code_CODE_INVALID_ARGUMENT))
+	}
+
 	if host != "" {
 		conf = &config{host}
 		if err := writeConfig(conf); err != nil {
 			fmt.Println("error writing to config file")
 			os.Exit(1)
 		}
+	} else if h := os.Getenv(hostEnv); h != "" {
+		// the host from the environment is only used for this invocation
+		conf = &config{h}
 	}
 
 	client = rhttp.GetHTTPClient(
@@ -135,8 +165,15 @@ func main() {
 	completer := Completer{DisableArgPrompt: disableargprompt}
 	completer.init()
 
+	if script != "" {
+		os.Exit(runScript(&executor, script))
+	}
+
 	if len(flag.Args()) > 0 {
-		executor.Execute(strings.Join(flag.Args(), " "))
+		if err := executor.Run(flag.Args()); err != nil {
+			printError(os.Stderr, err)
+			os.Exit(exitCode(err))
+		}
 		return
 	}
 
@@ -152,6 +189,20 @@ func main() {
 	p.Run()
 }
 
+// runScript executes the commands of the script file, or of stdin if the file is -
+func runScript(executor *Executor, file string) int {
+	if file == "-" {
+		return executor.ExecuteScript(os.Stdin)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		printError(os.Stderr, err)
+		return exitCode(err)
+	}
+	defer f.Close()
+	return executor.ExecuteScript(f)
+}
+
 func generateMainUsage() {
 	n := 0
 	for _, cmd := range commands {
@@ -169,5 +220,10 @@ func generateMainUsage() {
 			strings.Repeat(" ", 4+(n-len(cmd.Name))),
 			cmd.Description(),
 		)
+		helpCommandUsage = append(helpCommandUsage, map[string]string{
+			"name":        cmd.Name,
+			"description": cmd.Description(),
+			"usage":       cmd.Usage(),
+		})
 	}
 }
@@ -23,7 +23,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func mkdirCommand() *command {
@@ -32,7 +31,7 @@ func mkdirCommand() *command {
 	cmd.Usage = func() string { return "Usage: mkdir [-flags] <container_name>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -54,7 +53,7 @@ func mkdirCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printResult(res.Status, func() {})
 	}
 	return cmd
 }
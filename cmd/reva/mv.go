@@ -23,7 +23,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func moveCommand() *command {
@@ -32,7 +31,7 @@ func moveCommand() *command {
 	cmd.Usage = func() string { return "Usage: mv [-flags] <source> <destination>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 2 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		src := cmd.Args()[0]
@@ -56,7 +55,7 @@ func moveCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printResult(res.Status, func() {})
 	}
 	return cmd
 }
@@ -80,8 +80,9 @@ func ocmInviteForwardCommand() *command {
 		if res.Status.Code != rpc.Code_CODE_OK {
 			return formatError(res.Status)
 		}
-		fmt.Println(res)
-		return nil
+		return printResult(res, func() {
+			fmt.Println(res)
+		})
 	}
 	return cmd
 }
@@ -70,8 +70,9 @@ func ocmRemoveAcceptedUser() *command {
 			return formatError(res.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(res.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -143,6 +143,10 @@ func ocmShareCreateCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Share)
+		}
+
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
 		t.AppendHeader(table.Row{"#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Type", "Grantee.Idp", "Grantee.OpaqueId", "Created", "Updated"})
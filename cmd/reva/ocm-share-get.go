@@ -19,9 +19,9 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"io"
+	"os"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
@@ -34,7 +34,7 @@ func ocmShareGetCommand() *command {
 	cmd.Usage = func() string { return "Usage: ocm-share-get" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		ctx := getAuthContext()
@@ -61,6 +61,10 @@ func ocmShareGetCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Share)
+		}
+
 		d, err := utils.MarshalProtoV1ToJSON(shareRes.Share)
 		if err != nil {
 			return err
@@ -73,6 +73,10 @@ func ocmShareListCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Shares)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
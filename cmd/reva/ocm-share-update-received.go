@@ -43,7 +43,7 @@ func ocmShareUpdateReceivedCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		// validate flags
@@ -108,8 +108,9 @@ func ocmShareUpdateReceivedCommand() *command {
 			return formatError(updateRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(updateRes.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
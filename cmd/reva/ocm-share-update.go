@@ -40,7 +40,7 @@ func ocmShareUpdateCommand() *command {
 	}
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		id := cmd.Args()[0]
@@ -110,8 +110,9 @@ func ocmShareUpdateCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(shareRes.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -27,7 +27,6 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
-	"github.com/pkg/errors"
 )
 
 func openInAppCommand() *command {
@@ -51,7 +50,7 @@ func openInAppCommand() *command {
 	cmd.Action = func(w ...io.Writer) error {
 		ctx := getAuthContext()
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 		path := cmd.Args()[0]
 
@@ -87,9 +86,9 @@ func openInAppCommand() *command {
 			return formatError(openRes.Status)
 		}
 
-		fmt.Printf("App URL: %+v\n", openRes.AppUrl)
-
-		return nil
+		return printResult(openRes.AppUrl, func() {
+			fmt.Printf("App URL: %+v\n", openRes.AppUrl)
+		})
 	}
 	return cmd
 }
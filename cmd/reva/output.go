@@ -0,0 +1,252 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// the formats the results of the commands can be printed in
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// errCancelled is returned when a command is interrupted or runs into the timeout
+var errCancelled = errors.New("cancelled by user")
+
+// statusError is returned by the commands when a request did not succeed
+type statusError struct {
+	status *rpc.Status
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("error: code=%+v msg=%q support_trace=%q", e.status.Code, e.status.Message, e.status.Trace)
+}
+
+// usageError is returned by the commands when they are called with invalid arguments
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// a mapping from the CS3 status codes to the exit codes of the cli, the exit codes
+// are part of the interface for scripts and must not be changed
+var exitCodes = map[rpc.Code]int{
+	rpc.Code_CODE_OK:                   0,
+	rpc.Code_CODE_INVALID_ARGUMENT:     2,
+	rpc.Code_CODE_OUT_OF_RANGE:         2,
+	rpc.Code_CODE_NOT_FOUND:            3,
+	rpc.Code_CODE_UNAUTHENTICATED:      4,
+	rpc.Code_CODE_PERMISSION_DENIED:    5,
+	rpc.Code_CODE_ALREADY_EXISTS:       6,
+	rpc.Code_CODE_FAILED_PRECONDITION:  7,
+	rpc.Code_CODE_ABORTED:              7,
+	rpc.Code_CODE_LOCKED:               7,
+	rpc.Code_CODE_INSUFFICIENT_STORAGE: 8,
+	rpc.Code_CODE_RESOURCE_EXHAUSTED:   8,
+	rpc.Code_CODE_UNIMPLEMENTED:        9,
+	rpc.Code_CODE_UNAVAILABLE:          10,
+	rpc.Code_CODE_DEADLINE_EXCEEDED:    10,
+	rpc.Code_CODE_TOO_EARLY:            10,
+	rpc.Code_CODE_CANCELLED:            130, // like a shell interrupted by SIGINT
+}
+
+// exitCodeFromCode returns the exit code for the rpc code. It returns
+// 1 for the codes that do not have a dedicated exit code
+func exitCodeFromCode(code rpc.Code) int {
+	if c, ok := exitCodes[code]; ok {
+		return c
+	}
+	return 1
+}
+
+// errorStatus returns the status describing the error returned by a command
+func errorStatus(err error) *rpc.Status {
+	var se *statusError
+	var ue usageError
+	switch {
+	case err == nil:
+		return &rpc.Status{Code: rpc.Code_CODE_OK}
+	case errors.As(err, &se):
+		return se.status
+	case errors.As(err, &ue):
+		return &rpc.Status{Code: rpc.Code_CODE_INVALID_ARGUMENT, Message: err.Error()}
+	case errors.Is(err, errCancelled), errors.Is(err, context.Canceled):
+		return &rpc.Status{Code: rpc.Code_CODE_CANCELLED, Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &rpc.Status{Code: rpc.Code_CODE_DEADLINE_EXCEEDED, Message: err.Error()}
+	}
+
+	code := rpc.Code_CODE_UNKNOWN
+	if st, ok := status.FromError(errors.Cause(err)); ok {
+		switch st.Code() {
+		case codes.Canceled:
+			code = rpc.Code_CODE_CANCELLED
+		case codes.DeadlineExceeded:
+			code = rpc.Code_CODE_DEADLINE_EXCEEDED
+		case codes.NotFound:
+			code = rpc.Code_CODE_NOT_FOUND
+		case codes.PermissionDenied:
+			code = rpc.Code_CODE_PERMISSION_DENIED
+		case codes.Unauthenticated:
+			code = rpc.Code_CODE_UNAUTHENTICATED
+		case codes.Unimplemented:
+			code = rpc.Code_CODE_UNIMPLEMENTED
+		case codes.Unavailable:
+			code = rpc.Code_CODE_UNAVAILABLE
+		}
+	}
+	switch errors.Cause(err).(type) {
+	case errtypes.IsNotFound:
+		code = rpc.Code_CODE_NOT_FOUND
+	case errtypes.IsAlreadyExists:
+		code = rpc.Code_CODE_ALREADY_EXISTS
+	case errtypes.IsPermissionDenied:
+		code = rpc.Code_CODE_PERMISSION_DENIED
+	case errtypes.IsInvalidCredentials:
+		code = rpc.Code_CODE_UNAUTHENTICATED
+	case errtypes.IsBadRequest:
+		code = rpc.Code_CODE_INVALID_ARGUMENT
+	case errtypes.IsLocked:
+		code = rpc.Code_CODE_LOCKED
+	case errtypes.IsAborted:
+		code = rpc.Code_CODE_ABORTED
+	case errtypes.IsPreconditionFailed:
+		code = rpc.Code_CODE_FAILED_PRECONDITION
+	case errtypes.IsNotSupported:
+		code = rpc.Code_CODE_UNIMPLEMENTED
+	case errtypes.IsInsufficientStorage:
+		code = rpc.Code_CODE_INSUFFICIENT_STORAGE
+	case errtypes.IsTooEarly:
+		code = rpc.Code_CODE_TOO_EARLY
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		code = rpc.Code_CODE_NOT_FOUND
+	case errors.Is(err, fs.ErrPermission):
+		code = rpc.Code_CODE_PERMISSION_DENIED
+	}
+	return &rpc.Status{Code: code, Message: err.Error()}
+}
+
+// jsonOutput reports whether the results of the commands are printed as JSON
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// printResult prints v as JSON when the JSON output is selected and calls text otherwise
+func printResult(v interface{}, text func()) error {
+	if !jsonOutput() {
+		text()
+		return nil
+	}
+	return printJSON(os.Stdout, v)
+}
+
+// infof prints informational messages of a command. They are written to stderr
+// when the JSON output is selected to keep stdout parseable.
+func infof(format string, a ...interface{}) {
+	w := io.Writer(os.Stdout)
+	if jsonOutput() {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, format, a...)
+}
+
+// printError prints the error returned by a command, as a JSON encoded status
+// when the JSON output is selected
+func printError(w io.Writer, err error) {
+	if !jsonOutput() {
+		fmt.Fprintln(w, err.Error())
+		return
+	}
+	if perr := printJSON(w, errorStatus(err)); perr != nil {
+		fmt.Fprintln(w, err.Error())
+	}
+}
+
+// printJSON prints v as indented JSON. Protobuf messages, also when contained
+// in slices or maps, are encoded with their canonical JSON mapping
+func printJSON(w io.Writer, v interface{}) error {
+	raw, err := marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func marshalJSON(v interface{}) (json.RawMessage, error) {
+	if m, ok := v.(proto.Message); ok {
+		if reflect.ValueOf(m).IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return utils.MarshalProtoV1ToJSON(m)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return json.RawMessage("[]"), nil
+		}
+		items := make([]json.RawMessage, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := marshalJSON(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return json.Marshal(items)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		items := make(map[string]json.RawMessage, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			item, err := marshalJSON(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[iter.Key().String()] = item
+		}
+		return json.Marshal(items)
+	}
+	return json.Marshal(v)
+}
@@ -24,7 +24,6 @@ import (
 
 	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
-	"github.com/pkg/errors"
 )
 
 var preferencesCommand = func() *command {
@@ -35,7 +34,7 @@ var preferencesCommand = func() *command {
 	cmd.Action = func(w ...io.Writer) error {
 
 		if cmd.NArg() < 2 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		subcommand := cmd.Args()[0]
@@ -52,7 +51,7 @@ var preferencesCommand = func() *command {
 		switch subcommand {
 		case "set":
 			if cmd.NArg() < 4 {
-				return errors.New("Invalid arguments: " + cmd.Usage())
+				return usageError("Invalid arguments: " + cmd.Usage())
 			}
 			value := cmd.Args()[3]
 			req := &preferences.SetKeyRequest{
@@ -71,6 +70,7 @@ var preferencesCommand = func() *command {
 			if res.Status.Code != rpc.Code_CODE_OK {
 				return formatError(res.Status)
 			}
+			return printResult(res.Status, func() {})
 
 		case "get":
 			req := &preferences.GetKeyRequest{
@@ -89,12 +89,13 @@ var preferencesCommand = func() *command {
 				return formatError(res.Status)
 			}
 
-			fmt.Println(res.Val)
+			return printResult(res, func() {
+				fmt.Println(res.Val)
+			})
 
 		default:
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
-		return nil
 	}
 	return cmd
 }
@@ -27,7 +27,6 @@ import (
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/jedib0t/go-pretty/table"
-	"github.com/pkg/errors"
 )
 
 func publicShareCreateCommand() *command {
@@ -44,7 +43,7 @@ func publicShareCreateCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -93,6 +92,10 @@ func publicShareCreateCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Share)
+		}
+
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
 		t.AppendHeader(table.Row{"#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Permissions", "Token", "Expiration", "Created", "Updated", "Description"})
@@ -73,6 +73,10 @@ func publicShareListCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Share)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
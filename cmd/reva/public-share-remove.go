@@ -24,7 +24,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func publicShareRemoveCommand() *command {
@@ -33,7 +32,7 @@ func publicShareRemoveCommand() *command {
 	cmd.Usage = func() string { return "Usage: public-share-remove [-flags] <share_id>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		id := cmd.Args()[0]
@@ -63,8 +62,9 @@ func publicShareRemoveCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(shareRes.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
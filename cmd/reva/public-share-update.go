@@ -39,7 +39,7 @@ func publicShareUpdateCommand() *command {
 	}
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		// validate flags
@@ -96,6 +96,7 @@ func publicShareUpdateCommand() *command {
 			})
 		}
 
+		status := &rpc.Status{Code: rpc.Code_CODE_OK}
 		for _, u := range updates {
 			shareRes, err := shareClient.UpdatePublicShare(ctx, u)
 			if err != nil {
@@ -105,10 +106,12 @@ func publicShareUpdateCommand() *command {
 			if shareRes.Status.Code != rpc.Code_CODE_OK {
 				return formatError(shareRes.Status)
 			}
+			status = shareRes.Status
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -59,10 +59,11 @@ func recycleListCommand() *command {
 		}
 
 		items := res.RecycleItems
-		for _, item := range items {
-			fmt.Printf("%+v\n", item)
-		}
-		return nil
+		return printResult(items, func() {
+			for _, item := range items {
+				fmt.Printf("%+v\n", item)
+			}
+		})
 	}
 	return cmd
 }
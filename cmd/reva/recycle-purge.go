@@ -58,7 +58,7 @@ func recyclePurgeCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printResult(res.Status, func() {})
 	}
 	return cmd
 }
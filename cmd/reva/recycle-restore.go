@@ -23,7 +23,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func recycleRestoreCommand() *command {
@@ -33,7 +32,7 @@ func recycleRestoreCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		key := cmd.Args()[0]
@@ -66,7 +65,7 @@ func recycleRestoreCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printResult(res.Status, func() {})
 	}
 	return cmd
 }
@@ -23,7 +23,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageproviderv1beta1pb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func rmCommand() *command {
@@ -32,7 +31,7 @@ func rmCommand() *command {
 	cmd.Usage = func() string { return "Usage: rm [-flags] <file_name>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -53,7 +52,7 @@ func rmCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printResult(res.Status, func() {})
 	}
 	return cmd
 }
@@ -50,7 +50,7 @@ func setlockCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -94,9 +94,9 @@ func setlockCommand() *command {
 			}
 		}
 
-		fmt.Println("OK")
-
-		return nil
+		return printResult(lock, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -50,7 +50,7 @@ func shareCreateCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		// validate flags
@@ -122,6 +122,10 @@ func shareCreateCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Share)
+		}
+
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
 		t.AppendHeader(table.Row{"#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Permissions", "Type", "Grantee.Idp", "Grantee.OpaqueId", "Created", "Updated"})
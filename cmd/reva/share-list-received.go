@@ -52,6 +52,10 @@ func shareListReceivedCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, shareRes.Shares)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
@@ -24,7 +24,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func shareRemoveCommand() *command {
@@ -33,7 +32,7 @@ func shareRemoveCommand() *command {
 	cmd.Usage = func() string { return "Usage: share-remove [-flags] <share_id>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		id := cmd.Args()[0]
@@ -63,8 +62,9 @@ func shareRemoveCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(shareRes.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -40,7 +40,7 @@ func shareUpdateReceivedCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		// validate flags
@@ -89,8 +89,9 @@ func shareUpdateReceivedCommand() *command {
 			return formatError(updateRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(updateRes.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -38,7 +38,7 @@ func shareUpdateCommand() *command {
 	}
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		// validate flags
@@ -85,8 +85,9 @@ func shareUpdateCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printResult(shareRes.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
@@ -0,0 +1,80 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+func spaceCreateCommand() *command {
+	cmd := newCommand("space-create")
+	cmd.Description = func() string { return "create a storage space" }
+	cmd.Usage = func() string { return "Usage: space-create [-flags] <name>" }
+	spaceType := cmd.String("type", "project", "the type of the space")
+	quota := cmd.Uint64("quota", 0, "the quota of the space in bytes, 0 means the default quota")
+	description := cmd.String("description", "", "the description of the space")
+	template := cmd.String("template", "", "the space template to create the space from")
+
+	cmd.ResetFlags = func() {
+		*spaceType, *quota, *description, *template = "project", 0, "", ""
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		req := &provider.CreateStorageSpaceRequest{
+			Type: *spaceType,
+			Name: cmd.Args()[0],
+		}
+		if *quota > 0 {
+			req.Quota = &provider.Quota{QuotaMaxBytes: *quota}
+		}
+		if *description != "" {
+			req.Opaque = utils.AppendPlainToOpaque(req.Opaque, "description", *description)
+		}
+		if *template != "" {
+			req.Opaque = utils.AppendPlainToOpaque(req.Opaque, "template", *template)
+		}
+
+		res, err := client.CreateStorageSpace(ctx, req)
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		return printResult(res.StorageSpace, func() {
+			printSpaces(res.StorageSpace)
+		})
+	}
+	return cmd
+}
@@ -0,0 +1,97 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func spaceDisableCommand() *command {
+	cmd := newCommand("space-disable")
+	cmd.Description = func() string { return "disable a storage space, it can be restored with space-restore" }
+	cmd.Usage = func() string { return "Usage: space-disable <space_id>" }
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		status, err := deleteSpace(cmd.Args()[0], false)
+		if err != nil {
+			return err
+		}
+		return printResult(status, func() {
+			fmt.Println("disabled space " + cmd.Args()[0])
+		})
+	}
+	return cmd
+}
+
+func spaceDeleteCommand() *command {
+	cmd := newCommand("space-delete")
+	cmd.Description = func() string { return "permanently delete a disabled storage space" }
+	cmd.Usage = func() string { return "Usage: space-delete <space_id>" }
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		status, err := deleteSpace(cmd.Args()[0], true)
+		if err != nil {
+			return err
+		}
+		return printResult(status, func() {
+			fmt.Println("deleted space " + cmd.Args()[0])
+		})
+	}
+	return cmd
+}
+
+// deleteSpace disables a space or, with purge, deletes a disabled space
+func deleteSpace(spaceID string, purge bool) (*rpc.Status, error) {
+	ctx := getAuthContext()
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &provider.DeleteStorageSpaceRequest{
+		Id: &provider.StorageSpaceId{OpaqueId: spaceID},
+	}
+	if purge {
+		req.Opaque = &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"purge": {},
+			},
+		}
+	}
+
+	res, err := client.DeleteStorageSpace(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, formatError(res.Status)
+	}
+	return res.Status, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func spaceListCommand() *command {
+	cmd := newCommand("space-list")
+	cmd.Description = func() string { return "list storage spaces" }
+	cmd.Usage = func() string { return "Usage: space-list [-flags]" }
+	spaceType := cmd.String("type", "", "filter by space type (personal, project, ...)")
+	spaceID := cmd.String("id", "", "filter by space id")
+	all := cmd.Bool("all", false, "list all spaces, not only the ones you are a member of. Requires the permission to list all spaces")
+
+	cmd.ResetFlags = func() {
+		*spaceType, *spaceID, *all = "", "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		req := &provider.ListStorageSpacesRequest{}
+		if *spaceType != "" {
+			req.Filters = append(req.Filters, &provider.ListStorageSpacesRequest_Filter{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: *spaceType},
+			})
+		}
+		if *spaceID != "" {
+			req.Filters = append(req.Filters, &provider.ListStorageSpacesRequest_Filter{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+				Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: *spaceID}},
+			})
+		}
+		if *all {
+			req.Opaque = utils.AppendPlainToOpaque(req.Opaque, "unrestricted", "true")
+		}
+
+		res, err := client.ListStorageSpaces(ctx, req)
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		return printResult(res.StorageSpaces, func() {
+			printSpaces(res.StorageSpaces...)
+		})
+	}
+	return cmd
+}
+
+// printSpaces prints the given spaces as a table
+func printSpaces(spaces ...*provider.StorageSpace) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Id", "Name", "Type", "Owner", "Quota", "Used", "Disabled"})
+	for _, s := range spaces {
+		quota, used := "", ""
+		if s.Quota != nil {
+			quota = strconv.FormatUint(s.Quota.QuotaMaxBytes, 10)
+		}
+		if s.RootInfo != nil {
+			used = strconv.FormatUint(s.RootInfo.Size, 10)
+		}
+		t.AppendRow(table.Row{
+			s.GetId().GetOpaqueId(), s.Name, s.SpaceType, s.GetOwner().GetId().GetOpaqueId(), quota, used,
+			utils.ReadPlainFromOpaque(s.Opaque, "trashed") == "trashed",
+		})
+	}
+	t.Render()
+}
+
+// spaceRootID returns the resource id of the root of a space
+func spaceRootID(spaceID string) (*provider.ResourceId, error) {
+	rid, err := storagespace.ParseID(spaceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid space id")
+	}
+	if rid.SpaceId == "" {
+		return nil, errors.New("invalid space id: " + spaceID)
+	}
+	if rid.OpaqueId == "" {
+		rid.OpaqueId = rid.SpaceId
+	}
+	return &rid, nil
+}
+
+// updateSpace sends an UpdateStorageSpace request and prints the updated space
+func updateSpace(space *provider.StorageSpace, opaque *types.Opaque) error {
+	ctx := getAuthContext()
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.UpdateStorageSpace(ctx, &provider.UpdateStorageSpaceRequest{
+		Opaque:       opaque,
+		StorageSpace: space,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+
+	return printResult(res.StorageSpace, func() {
+		printSpaces(res.StorageSpace)
+	})
+}
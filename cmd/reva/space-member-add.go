@@ -0,0 +1,193 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/conversions"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func spaceMemberAddCommand() *command {
+	cmd := newCommand("space-member-add")
+	cmd.Description = func() string { return "add a member to a storage space or change the role of a member" }
+	cmd.Usage = func() string { return "Usage: space-member-add [-flags] <space_id>" }
+	grantType := cmd.String("type", "user", "grantee type (user or group)")
+	grantee := cmd.String("grantee", "", "the grantee")
+	idp := cmd.String("idp", "", "the idp of the grantee, default to same idp as the user triggering the action")
+	role := cmd.String("role", "viewer", "the role of the member (viewer, editor, manager)")
+	userType := cmd.String("user-type", "primary", "the type of user account, defaults to primary")
+
+	cmd.ResetFlags = func() {
+		*grantType, *grantee, *idp, *role, *userType = "user", "", "", "viewer", "primary"
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		if *grantee == "" {
+			return errors.New("Grantee cannot be empty: use -grantee flag\n" + cmd.Usage())
+		}
+
+		spaceID := cmd.Args()[0]
+		root, err := spaceRootID(spaceID)
+		if err != nil {
+			return err
+		}
+		perms, err := getSpacePerm(*role)
+		if err != nil {
+			return err
+		}
+		g, err := getGrantee(*grantType, *grantee, *idp, *userType)
+		if err != nil {
+			return err
+		}
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		statRes, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: root}})
+		if err != nil {
+			return err
+		}
+		if statRes.Status.Code != rpc.Code_CODE_OK {
+			return formatError(statRes.Status)
+		}
+
+		grants, err := listSpaceGrants(spaceID)
+		if err != nil {
+			return err
+		}
+
+		var st *rpc.Status
+		if hasGrantee(grants, g) {
+			// the gateway updates space grants instead of shares when told so
+			req := &collaboration.UpdateShareRequest{
+				Opaque: &types.Opaque{
+					Map: map[string]*types.OpaqueEntry{
+						"spacegrant": {},
+					},
+				},
+				Share: &collaboration.Share{
+					ResourceId:  root,
+					Permissions: &collaboration.SharePermissions{Permissions: perms},
+					Grantee:     g,
+				},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"permissions"}},
+			}
+			req.Opaque = utils.AppendPlainToOpaque(req.Opaque, "spacetype", statRes.GetInfo().GetSpace().GetSpaceType())
+			res, err := client.UpdateShare(ctx, req)
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		} else {
+			res, err := client.CreateShare(ctx, &collaboration.CreateShareRequest{
+				ResourceInfo: statRes.Info,
+				Grant: &collaboration.ShareGrant{
+					Permissions: &collaboration.SharePermissions{Permissions: perms},
+					Grantee:     g,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		}
+		if st.Code != rpc.Code_CODE_OK {
+			return formatError(st)
+		}
+
+		return printResult(st, func() {
+			fmt.Printf("%s %s is %s of space %s\n", *grantType, *grantee, *role, spaceID)
+		})
+	}
+	return cmd
+}
+
+func getGrantee(grantType, grantee, idp, userType string) (*provider.Grantee, error) {
+	g := &provider.Grantee{Type: getGrantType(grantType)}
+	switch grantType {
+	case "user":
+		g.Id = &provider.Grantee_UserId{UserId: &userpb.UserId{
+			Idp:      idp,
+			OpaqueId: grantee,
+			Type:     utils.UserTypeMap(userType),
+		}}
+	case "group":
+		g.Id = &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{
+			Idp:      idp,
+			OpaqueId: grantee,
+		}}
+	default:
+		return nil, errors.New("Invalid grantee type argument: " + grantType)
+	}
+	return g, nil
+}
+
+func getSpacePerm(role string) (*provider.ResourcePermissions, error) {
+	var perms *provider.ResourcePermissions
+	switch role {
+	case viewerPermission:
+		perms = conversions.NewSpaceViewerRole().CS3ResourcePermissions()
+	case editorPermission:
+		perms = conversions.NewSpaceEditorRole().CS3ResourcePermissions()
+	case managerPermission:
+		perms = conversions.NewManagerRole().CS3ResourcePermissions()
+	default:
+		return nil, errors.New("invalid role: " + role)
+	}
+	// all members of a space need to be able to list its members
+	perms.ListGrants = true
+	return perms, nil
+}
+
+// hasGrantee returns true if one of the grants is for the given grantee
+func hasGrantee(grants []*provider.Grant, g *provider.Grantee) bool {
+	for _, grant := range grants {
+		if grant.GetGrantee().GetType() != g.GetType() {
+			continue
+		}
+		switch g.GetType() {
+		case provider.GranteeType_GRANTEE_TYPE_USER:
+			if grant.GetGrantee().GetUserId().GetOpaqueId() == g.GetUserId().GetOpaqueId() {
+				return true
+			}
+		case provider.GranteeType_GRANTEE_TYPE_GROUP:
+			if grant.GetGrantee().GetGroupId().GetOpaqueId() == g.GetGroupId().GetOpaqueId() {
+				return true
+			}
+		}
+	}
+	return false
+}
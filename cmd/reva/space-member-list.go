@@ -0,0 +1,143 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/opencloud-eu/reva/v2/pkg/conversions"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func spaceMemberListCommand() *command {
+	cmd := newCommand("space-member-list")
+	cmd.Description = func() string { return "list the members of a storage space" }
+	cmd.Usage = func() string { return "Usage: space-member-list <space_id>" }
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+
+		grants, err := listSpaceGrants(cmd.Args()[0])
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput() {
+			return printJSON(os.Stdout, grants)
+		}
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Type", "Grantee.Idp", "Grantee.OpaqueId", "Role", "Expiration"})
+		for _, g := range grants {
+			var idp, opaque string
+			switch g.Grantee.Type {
+			case provider.GranteeType_GRANTEE_TYPE_USER:
+				idp, opaque = g.Grantee.GetUserId().GetIdp(), g.Grantee.GetUserId().GetOpaqueId()
+			case provider.GranteeType_GRANTEE_TYPE_GROUP:
+				idp, opaque = g.Grantee.GetGroupId().GetIdp(), g.Grantee.GetGroupId().GetOpaqueId()
+			}
+			expiration := ""
+			if g.Expiration != nil {
+				expiration = utils.TSToTime(g.Expiration).Format(time.RFC3339)
+			}
+			t.AppendRow(table.Row{
+				g.Grantee.Type.String(), idp, opaque,
+				conversions.RoleFromResourcePermissions(g.Permissions, false).Name, expiration,
+			})
+		}
+		t.Render()
+		return nil
+	}
+	return cmd
+}
+
+// listSpaceGrants returns the grants of a space. Storage providers list them in the opaque of the space.
+func listSpaceGrants(spaceID string) ([]*provider.Grant, error) {
+	ctx := getAuthContext()
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+			Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: spaceID}},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, formatError(res.Status)
+	}
+	if len(res.StorageSpaces) != 1 {
+		return nil, errors.New("space not found: " + spaceID)
+	}
+	space := res.StorageSpaces[0]
+
+	permissions := map[string]*provider.ResourcePermissions{}
+	expirations := map[string]*types.Timestamp{}
+	groups := map[string]struct{}{}
+	if err := utils.ReadJSONFromOpaque(space.Opaque, "grants", &permissions); err != nil {
+		return nil, errors.Wrap(err, "could not read space grants")
+	}
+	_ = utils.ReadJSONFromOpaque(space.Opaque, "grants_expirations", &expirations)
+	_ = utils.ReadJSONFromOpaque(space.Opaque, "groups", &groups)
+
+	ids := make([]string, 0, len(permissions))
+	for id := range permissions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	grants := make([]*provider.Grant, 0, len(ids))
+	for _, id := range ids {
+		g := &provider.Grant{
+			Permissions: permissions[id],
+			Expiration:  expirations[id],
+		}
+		if _, ok := groups[id]; ok {
+			g.Grantee = &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+				Id:   &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: id}},
+			}
+		} else {
+			g.Grantee = &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_USER,
+				Id:   &provider.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: id}},
+			}
+		}
+		grants = append(grants, g)
+	}
+	return grants, nil
+}
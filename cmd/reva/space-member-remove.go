@@ -0,0 +1,89 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/pkg/errors"
+)
+
+func spaceMemberRemoveCommand() *command {
+	cmd := newCommand("space-member-remove")
+	cmd.Description = func() string { return "remove a member from a storage space" }
+	cmd.Usage = func() string { return "Usage: space-member-remove [-flags] <space_id>" }
+	grantType := cmd.String("type", "user", "grantee type (user or group)")
+	grantee := cmd.String("grantee", "", "the grantee")
+	idp := cmd.String("idp", "", "the idp of the grantee, default to same idp as the user triggering the action")
+	userType := cmd.String("user-type", "primary", "the type of user account, defaults to primary")
+
+	cmd.ResetFlags = func() {
+		*grantType, *grantee, *idp, *userType = "user", "", "", "primary"
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		if *grantee == "" {
+			return errors.New("Grantee cannot be empty: use -grantee flag\n" + cmd.Usage())
+		}
+
+		spaceID := cmd.Args()[0]
+		root, err := spaceRootID(spaceID)
+		if err != nil {
+			return err
+		}
+		g, err := getGrantee(*grantType, *grantee, *idp, *userType)
+		if err != nil {
+			return err
+		}
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		res, err := client.RemoveShare(ctx, &collaboration.RemoveShareRequest{
+			Ref: &collaboration.ShareReference{
+				Spec: &collaboration.ShareReference_Key{
+					Key: &collaboration.ShareKey{
+						ResourceId: root,
+						Grantee:    g,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		return printResult(res.Status, func() {
+			fmt.Printf("removed %s %s from space %s\n", *grantType, *grantee, spaceID)
+		})
+	}
+	return cmd
+}
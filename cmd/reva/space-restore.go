@@ -0,0 +1,46 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func spaceRestoreCommand() *command {
+	cmd := newCommand("space-restore")
+	cmd.Description = func() string { return "restore a disabled storage space" }
+	cmd.Usage = func() string { return "Usage: space-restore <space_id>" }
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+
+		opaque := &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"restore": {},
+			},
+		}
+		return updateSpace(&provider.StorageSpace{Id: &provider.StorageSpaceId{OpaqueId: cmd.Args()[0]}}, opaque)
+	}
+	return cmd
+}
@@ -0,0 +1,63 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func spaceUpdateCommand() *command {
+	cmd := newCommand("space-update")
+	cmd.Description = func() string { return "rename a storage space or change its quota or description" }
+	cmd.Usage = func() string { return "Usage: space-update [-flags] <space_id>" }
+	name := cmd.String("name", "", "the new name of the space")
+	quota := cmd.Uint64("quota", 0, "the new quota of the space in bytes")
+	description := cmd.String("description", "", "the new description of the space")
+
+	cmd.ResetFlags = func() {
+		*name, *quota, *description = "", 0, ""
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+
+		space := &provider.StorageSpace{
+			Id:   &provider.StorageSpaceId{OpaqueId: cmd.Args()[0]},
+			Name: *name,
+		}
+		if *quota > 0 {
+			space.Quota = &provider.Quota{QuotaMaxBytes: *quota}
+		}
+		if *description != "" {
+			space.Opaque = utils.AppendPlainToOpaque(space.Opaque, "description", *description)
+		}
+		if space.Name == "" && space.Quota == nil && space.Opaque == nil {
+			return errors.New("Nothing to update: use the -name, -quota or -description flags\n" + cmd.Usage())
+		}
+
+		return updateSpace(space, nil)
+	}
+	return cmd
+}
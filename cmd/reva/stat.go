@@ -24,7 +24,6 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	"github.com/pkg/errors"
 )
 
 func statCommand() *command {
@@ -33,7 +32,7 @@ func statCommand() *command {
 	cmd.Usage = func() string { return "Usage: stat [-flags] <file_name>" }
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -55,8 +54,9 @@ func statCommand() *command {
 			return formatError(res.Status)
 		}
 
-		fmt.Println(res.Info)
-		return nil
+		return printResult(res.Info, func() {
+			fmt.Println(res.Info)
+		})
 	}
 	return cmd
 }
@@ -0,0 +1,103 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+func syncCommand() *command {
+	cmd := newCommand("sync")
+	cmd.Description = func() string {
+		return "make the target a mirror of the source, prefix remote paths with " + remotePrefix
+	}
+	cmd.Usage = func() string { return "Usage: sync [-flags] <source> <target>" }
+	del := cmd.Bool("delete", false, "delete files and directories of the target that do not exist in the source")
+	dryRun := cmd.Bool("dry-run", false, "only print what would be done")
+	parallel := cmd.Int("parallel", 4, "the number of files to transfer in parallel")
+	protocol := cmd.String("protocol", "tus", "the protocol to be used for uploads")
+
+	cmd.ResetFlags = func() {
+		*del, *dryRun, *parallel, *protocol = false, false, 4, "tus"
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 2 {
+			return usageError("Invalid arguments: " + cmd.Usage())
+		}
+		src, err := parseLocation(cmd.Args()[0])
+		if err != nil {
+			return err
+		}
+		dst, err := parseLocation(cmd.Args()[1])
+		if err != nil {
+			return err
+		}
+		if src.remote == dst.remote {
+			return errors.New("either the source or the target must be remote, prefix remote paths with " + remotePrefix)
+		}
+
+		ctx := getAuthContext()
+		gwc, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		st, err := walk(ctx, gwc, src)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			return errtypes.NotFound(src.String())
+		}
+		dt, err := walk(ctx, gwc, dst)
+		if err != nil {
+			return err
+		}
+
+		deletes, dirs, jobs := plan(src, dst, st, dt, *del)
+		summary := newTreeSummary(len(st), deletes, dirs, jobs)
+		if *dryRun {
+			summary.DryRun = true
+			return printResult(summary, func() {
+				for _, l := range deletes {
+					fmt.Printf("delete %s\n", l)
+				}
+				for _, l := range dirs {
+					fmt.Printf("mkdir %s\n", l)
+				}
+				for _, j := range jobs {
+					fmt.Printf("copy %s -> %s\n", j.src, j.dst)
+				}
+				fmt.Printf("%d to delete, %d directories to create, %d files to transfer, %d unchanged\n",
+					len(summary.Deleted), len(summary.Created), len(summary.Transferred), summary.Unchanged)
+			})
+		}
+
+		if err := applyPlan(ctx, gwc, deletes, dirs, jobs, *parallel, *protocol); err != nil {
+			return err
+		}
+		return printResult(summary, func() {})
+	}
+	return cmd
+}
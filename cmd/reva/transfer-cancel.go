@@ -60,6 +60,10 @@ func transferCancelCommand() *command {
 			return formatError(cancelResponse.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, cancelResponse.TxInfo)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
@@ -60,6 +60,10 @@ func transferGetStatusCommand() *command {
 			return formatError(getStatusResponse.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, getStatusResponse.TxInfo)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
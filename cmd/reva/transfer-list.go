@@ -67,6 +67,10 @@ func transferListCommand() *command {
 			return formatError(listTransfersResponse.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, listTransfersResponse.Transfers)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
@@ -62,6 +62,10 @@ func transferRetryCommand() *command {
 			return formatError(retryResponse.Status)
 		}
 
+		if len(w) == 0 && jsonOutput() {
+			return printJSON(os.Stdout, retryResponse.TxInfo)
+		}
+
 		if len(w) == 0 {
 			t := table.NewWriter()
 			t.SetOutputMirror(os.Stdout)
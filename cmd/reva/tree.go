@@ -0,0 +1,589 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/eventials/go-tus"
+	"github.com/eventials/go-tus/memorystore"
+	"github.com/opencloud-eu/reva/v2/internal/grpc/services/storageprovider"
+	"github.com/opencloud-eu/reva/v2/internal/http/services/datagateway"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// remotePrefix marks arguments of the tree commands that refer to the remote server
+const remotePrefix = "reva:"
+
+// uploadRetries is the number of times an interrupted tus upload is resumed
+const uploadRetries = 3
+
+// location is a local or remote path given to a tree command
+type location struct {
+	path   string
+	remote bool
+}
+
+func parseLocation(arg string) (location, error) {
+	if p, ok := strings.CutPrefix(arg, remotePrefix); ok {
+		return location{path: path.Clean("/" + p), remote: true}, nil
+	}
+	p, err := utils.ResolvePath(arg)
+	if err != nil {
+		return location{}, err
+	}
+	return location{path: p}, nil
+}
+
+func (l location) String() string {
+	if l.remote {
+		return remotePrefix + l.path
+	}
+	return l.path
+}
+
+// join returns the location of a path relative to l
+func (l location) join(rel string) location {
+	if rel == "." || rel == "" {
+		return l
+	}
+	if l.remote {
+		return location{path: path.Join(l.path, rel), remote: true}
+	}
+	return location{path: filepath.Join(l.path, filepath.FromSlash(rel))}
+}
+
+// treeEntry is a file or directory found while walking a tree
+type treeEntry struct {
+	// rel is the slash separated path relative to the root of the walk
+	rel   string
+	dir   bool
+	size  uint64
+	mtime time.Time
+	// checksum is only known for remote files
+	checksum *provider.ResourceChecksum
+}
+
+// tree holds the entries of a walked tree by their relative path
+type tree map[string]*treeEntry
+
+// sortedPaths returns the relative paths of the tree, parents before their children
+func (t tree) sortedPaths() []string {
+	paths := make([]string, 0, len(t))
+	for p := range t {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		// the root comes first, "-" and other characters sort before "."
+		if paths[i] == "." || paths[j] == "." {
+			return paths[i] == "."
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// walk returns the tree below a location. It returns nil if the location does not exist.
+func walk(ctx context.Context, gwc gateway.GatewayAPIClient, l location) (tree, error) {
+	if l.remote {
+		return walkRemote(ctx, gwc, l.path)
+	}
+	return walkLocal(l.path)
+}
+
+func walkRemote(ctx context.Context, gwc gateway.GatewayAPIClient, root string) (tree, error) {
+	res, err := gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: root}})
+	if err != nil {
+		return nil, err
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	default:
+		return nil, formatError(res.Status)
+	}
+
+	t := tree{}
+	t["."] = remoteEntry(".", res.Info)
+	if res.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return t, nil
+	}
+
+	dirs := []string{"."}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		lres, err := gwc.ListContainer(ctx, &provider.ListContainerRequest{Ref: &provider.Reference{Path: path.Join(root, dir)}})
+		if err != nil {
+			return nil, err
+		}
+		if lres.Status.Code != rpc.Code_CODE_OK {
+			return nil, formatError(lres.Status)
+		}
+		for _, info := range lres.Infos {
+			rel := path.Join(dir, path.Base(info.Path))
+			e := remoteEntry(rel, info)
+			t[rel] = e
+			if e.dir {
+				dirs = append(dirs, rel)
+			}
+		}
+	}
+	return t, nil
+}
+
+func remoteEntry(rel string, info *provider.ResourceInfo) *treeEntry {
+	return &treeEntry{
+		rel:      rel,
+		dir:      info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+		size:     info.Size,
+		mtime:    utils.TSToTime(info.Mtime),
+		checksum: info.Checksum,
+	}
+}
+
+func walkLocal(root string) (tree, error) {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	t := tree{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			// symlinks, devices and sockets can not be transferred
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		t[rel] = &treeEntry{
+			rel:   rel,
+			dir:   d.IsDir(),
+			size:  uint64(fi.Size()),
+			mtime: fi.ModTime(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// unchanged returns true if the target file does not need to be transferred
+// again. Files are compared by their remote checksum if one is known and by
+// their modification time otherwise.
+func unchanged(src location, se *treeEntry, dst location, de *treeEntry) bool {
+	if de == nil || de.dir != se.dir {
+		return false
+	}
+	if se.dir {
+		return true
+	}
+	if se.size != de.size {
+		return false
+	}
+
+	remote, local := se, dst
+	if src.remote == dst.remote {
+		return se.mtime.Unix() == de.mtime.Unix()
+	}
+	if !src.remote {
+		remote, local = de, src
+	}
+	if xs := remote.checksum; xs != nil && xs.Sum != "" && xs.Type != provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_UNSET {
+		fd, err := os.Open(local.join(remote.rel).path)
+		if err != nil {
+			return false
+		}
+		defer fd.Close()
+		sum, err := computeXS(xs.Type, fd)
+		return err == nil && sum == xs.Sum
+	}
+	return se.mtime.Unix() == de.mtime.Unix()
+}
+
+// transferFile copies a single file between the local filesystem and the remote server
+func transferFile(ctx context.Context, gwc gateway.GatewayAPIClient, src, dst location, e *treeEntry, protocol string) error {
+	switch {
+	case !src.remote && dst.remote:
+		return uploadTreeFile(ctx, gwc, src.path, dst.path, e, protocol)
+	case src.remote && !dst.remote:
+		return downloadTreeFile(ctx, gwc, src.path, dst.path, e)
+	default:
+		return errors.New("either the source or the target must be remote, prefix remote paths with " + remotePrefix)
+	}
+}
+
+// makeDir creates a local or remote directory
+func makeDir(ctx context.Context, gwc gateway.GatewayAPIClient, l location) error {
+	if !l.remote {
+		return os.MkdirAll(l.path, 0755)
+	}
+	res, err := gwc.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: &provider.Reference{Path: l.path}})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
+		return formatError(res.Status)
+	}
+	return nil
+}
+
+// removePath deletes a local or remote file or directory
+func removePath(ctx context.Context, gwc gateway.GatewayAPIClient, l location) error {
+	if !l.remote {
+		return os.RemoveAll(l.path)
+	}
+	res, err := gwc.Delete(ctx, &provider.DeleteRequest{Ref: &provider.Reference{Path: l.path}})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		return formatError(res.Status)
+	}
+	return nil
+}
+
+func uploadTreeFile(ctx context.Context, gwc gateway.GatewayAPIClient, local, target string, e *treeEntry, protocol string) error {
+	fd, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	res, err := gwc.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{Path: target},
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatUint(e.size, 10)),
+				},
+				// keep the modification time so unchanged files can be detected without checksums
+				"X-OC-Mtime": {
+					Decoder: "plain",
+					Value:   []byte(utils.TimeToOCMtime(e.mtime)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+
+	p, err := getUploadProtocolInfo(res.Protocols, protocol)
+	if err != nil {
+		return err
+	}
+	xsType, err := guessXS("negotiate", p.AvailableChecksums)
+	if err != nil {
+		xsType = provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_UNSET
+	}
+	xs, err := computeXS(xsType, fd)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if protocol == "simple" {
+		httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, p.UploadEndpoint, fd)
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set(datagateway.TokenTransportHeader, p.Token)
+		if xs != "" {
+			q := httpReq.URL.Query()
+			q.Add("xs", xs)
+			q.Add("xs_type", storageprovider.GRPC2PKGXS(xsType).String())
+			httpReq.URL.RawQuery = q.Encode()
+		}
+		httpRes, err := client.Do(httpReq)
+		if err != nil {
+			return err
+		}
+		defer httpRes.Body.Close()
+		if httpRes.StatusCode != http.StatusOK {
+			return errors.New("upload: PUT request returned " + httpRes.Status)
+		}
+		return nil
+	}
+
+	c := tus.DefaultConfig()
+	c.Resume = true
+	c.HttpClient = client
+	c.Store, err = memorystore.NewMemoryStore()
+	if err != nil {
+		return err
+	}
+	c.Header.Add(datagateway.TokenTransportHeader, p.Token)
+	tusc, err := tus.NewClient(p.UploadEndpoint, c)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]string{
+		"filename": path.Base(target),
+		"dir":      path.Dir(target),
+	}
+	if xs != "" {
+		metadata["checksum"] = fmt.Sprintf("%s %s", storageprovider.GRPC2PKGXS(xsType).String(), xs)
+	}
+	upload := tus.NewUpload(fd, int64(e.size), metadata, fmt.Sprintf("%s-%d-%s-%s", local, e.size, e.mtime, xs))
+	c.Store.Set(upload.Fingerprint, p.UploadEndpoint)
+
+	err = tus.NewUploader(tusc, p.UploadEndpoint, upload, 0).Upload()
+	for i := 0; err != nil && i < uploadRetries; i++ {
+		// the upload session still exists, continue at the offset the server has received
+		uploader, rerr := tusc.ResumeUpload(upload)
+		if rerr != nil {
+			return errors.Wrap(err, "could not resume upload: "+rerr.Error())
+		}
+		err = uploader.Upload()
+	}
+	return err
+}
+
+func downloadTreeFile(ctx context.Context, gwc gateway.GatewayAPIClient, remote, local string, e *treeEntry) error {
+	res, err := gwc.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: &provider.Reference{Path: remote}})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+
+	p, err := getDownloadProtocolInfo(res.Protocols, "simple")
+	if err != nil {
+		return err
+	}
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, p.DownloadEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, p.Token)
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return errors.New("download: GET request returned " + httpRes.Status)
+	}
+
+	// write to a temporary file first so an interrupted download does not look like an unchanged file
+	tmp := local + ".reva-part"
+	fd, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, httpRes.Body); err != nil {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chtimes(tmp, e.mtime, e.mtime); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, local)
+}
+
+// transferJob is a single file transfer of a tree operation
+type transferJob struct {
+	src, dst location
+	entry    *treeEntry
+}
+
+// treeSummary is the result of a tree operation
+type treeSummary struct {
+	Deleted     []string `json:"deleted"`
+	Created     []string `json:"created"`
+	Transferred []string `json:"transferred"`
+	Unchanged   int      `json:"unchanged"`
+	DryRun      bool     `json:"dry_run,omitempty"`
+}
+
+// newTreeSummary returns the summary of a plan for a source tree with the given number of entries
+func newTreeSummary(entries int, deletes, dirs []location, jobs []transferJob) *treeSummary {
+	s := &treeSummary{
+		Deleted:     make([]string, 0, len(deletes)),
+		Created:     make([]string, 0, len(dirs)),
+		Transferred: make([]string, 0, len(jobs)),
+		Unchanged:   entries - len(dirs) - len(jobs),
+	}
+	for _, l := range deletes {
+		s.Deleted = append(s.Deleted, l.String())
+	}
+	for _, l := range dirs {
+		s.Created = append(s.Created, l.String())
+	}
+	for _, j := range jobs {
+		s.Transferred = append(s.Transferred, j.dst.String())
+	}
+	return s
+}
+
+// applyPlan deletes, creates and transfers the entries returned by plan
+func applyPlan(ctx context.Context, gwc gateway.GatewayAPIClient, deletes, dirs []location, jobs []transferJob, parallel int, protocol string) error {
+	for _, l := range deletes {
+		if err := removePath(ctx, gwc, l); err != nil {
+			return err
+		}
+		infof("deleted %s\n", l)
+	}
+	for _, l := range dirs {
+		if err := makeDir(ctx, gwc, l); err != nil {
+			return err
+		}
+	}
+	return joinErrors(runTransfers(ctx, gwc, jobs, parallel, protocol))
+}
+
+// runTransfers runs the jobs with the given number of parallel workers and
+// returns the errors of the failed transfers
+func runTransfers(ctx context.Context, gwc gateway.GatewayAPIClient, jobs []transferJob, parallel int, protocol string) []error {
+	if parallel < 1 {
+		parallel = 1
+	}
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	ch := make(chan transferJob)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				if err := transferFile(ctx, gwc, j.src, j.dst, j.entry, protocol); err != nil {
+					mu.Lock()
+					errs = append(errs, errors.Wrapf(err, "%s -> %s", j.src, j.dst))
+					mu.Unlock()
+					continue
+				}
+				infof("%s -> %s\n", j.src, j.dst)
+			}
+		}()
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+	return errs
+}
+
+// plan compares a source and a target tree and returns the entries of the
+// target to delete, the directories to create and the files to transfer, in
+// the order they have to be applied. Entries that do not exist in the source
+// are only deleted if requested.
+func plan(src, dst location, st, dt tree, del bool) (deletes []location, dirs []location, jobs []transferJob) {
+	deleted := map[string]bool{}
+	for _, rel := range st.sortedPaths() {
+		se := st[rel]
+		de := dt[rel]
+		if de != nil && de.dir != se.dir {
+			// a file replaced by a directory or vice versa
+			deletes = append(deletes, dst.join(rel))
+			deleted[rel] = true
+			de = nil
+		}
+		if se.dir {
+			if de == nil {
+				dirs = append(dirs, dst.join(rel))
+			}
+			continue
+		}
+		if unchanged(src, se, dst, de) {
+			continue
+		}
+		jobs = append(jobs, transferJob{src: src.join(rel), dst: dst.join(rel), entry: se})
+	}
+
+	if !del {
+		return deletes, dirs, jobs
+	}
+	for _, rel := range dt.sortedPaths() {
+		if _, ok := st[rel]; ok || rel == "." {
+			continue
+		}
+		// children of deleted directories are removed with them
+		if parent := path.Dir(rel); deleted[parent] {
+			deleted[rel] = true
+			continue
+		}
+		deleted[rel] = true
+		deletes = append(deletes, dst.join(rel))
+	}
+	return deletes, dirs, jobs
+}
+
+// joinErrors combines the errors of a tree operation into one
+func joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	// wrap all errors to keep their status for the exit code
+	format := "%d transfers failed:" + strings.Repeat("\n%w", len(errs))
+	args := []interface{}{len(errs)}
+	for _, err := range errs {
+		args = append(args, err)
+	}
+	return fmt.Errorf(format, args...)
+}
@@ -19,7 +19,6 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"io"
 
@@ -40,7 +39,7 @@ func unlockCommand() *command {
 
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -85,9 +84,9 @@ func unlockCommand() *command {
 			return formatError(res.Status)
 		}
 
-		fmt.Println("OK")
-
-		return nil
+		return printResult(res.Status, func() {
+			fmt.Println("OK")
+		})
 	}
 	return cmd
 }
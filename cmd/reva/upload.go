@@ -19,6 +19,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math"
@@ -61,7 +62,7 @@ func uploadCommand() *command {
 		ctx := getAuthContext()
 
 		if cmd.NArg() < 2 {
-			return errors.New("Invalid arguments: " + cmd.Usage())
+			return usageError("Invalid arguments: " + cmd.Usage())
 		}
 
 		fn := cmd.Args()[0]
@@ -83,7 +84,7 @@ func uploadCommand() *command {
 			return err
 		}
 
-		fmt.Printf("Local file size: %d bytes\n", md.Size())
+		infof("Local file size: %d bytes\n", md.Size())
 
 		gwc, err := getClient()
 		if err != nil {
@@ -116,7 +117,7 @@ func uploadCommand() *command {
 				return err
 			}
 		} else {
-			return nil
+			return printUploaded(ctx, gwc, target)
 		}
 
 		p, err := getUploadProtocolInfo(res.Protocols, *protocolFlag)
@@ -124,21 +125,21 @@ func uploadCommand() *command {
 			return err
 		}
 
-		fmt.Printf("Data server: %s\n", p.UploadEndpoint)
-		fmt.Printf("Allowed checksums: %+v\n", p.AvailableChecksums)
+		infof("Data server: %s\n", p.UploadEndpoint)
+		infof("Allowed checksums: %+v\n", p.AvailableChecksums)
 
 		xsType, err := guessXS(*xsFlag, p.AvailableChecksums)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Checksum selected: %s\n", xsType)
+		infof("Checksum selected: %s\n", xsType)
 
 		xs, err := computeXS(xsType, fd)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Local XS: %s:%s\n", xsType, xs)
+		infof("Local XS: %s:%s\n", xsType, xs)
 		// seek back reader to 0
 		if _, err := fd.Seek(0, 0); err != nil {
 			return err
@@ -203,25 +204,29 @@ func uploadCommand() *command {
 			}
 		}
 
-		req2 := &provider.StatRequest{
-			Ref: &provider.Reference{Path: target},
-		}
-		res2, err := gwc.Stat(ctx, req2)
-		if err != nil {
-			return err
-		}
+		return printUploaded(ctx, gwc, target)
+	}
+	return cmd
+}
 
-		if res2.Status.Code != rpc.Code_CODE_OK {
-			return formatError(res2.Status)
-		}
+// printUploaded prints the metadata of the uploaded file
+func printUploaded(ctx context.Context, gwc gateway.GatewayAPIClient, target string) error {
+	req := &provider.StatRequest{
+		Ref: &provider.Reference{Path: target},
+	}
+	res, err := gwc.Stat(ctx, req)
+	if err != nil {
+		return err
+	}
 
-		info := res2.Info
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
 
+	info := res.Info
+	return printResult(info, func() {
 		fmt.Printf("File uploaded: %s:%s %d %s\n", info.Id.StorageId, info.Id.OpaqueId, info.Size, info.Path)
-
-		return nil
-	}
-	return cmd
+	})
 }
 
 func getUploadProtocolInfo(protocolInfos []*gateway.FileUploadProtocol, protocol string) (*gateway.FileUploadProtocol, error) {
@@ -275,7 +280,6 @@ func checkUploadWebdavRef(protocols []*gateway.FileUploadProtocol, md os.FileInf
 		return err
 	}
 
-	fmt.Println("File uploaded")
 	return nil
 }
 
@@ -32,8 +32,15 @@ var versionCommand = func() *command {
 		msg += "go_version=%s "
 		msg += "build_date=%s\n"
 
-		fmt.Printf(msg, version, gitCommit, goVersion, buildDate)
-		return nil
+		v := map[string]string{
+			"version":    version,
+			"commit":     gitCommit,
+			"go_version": goVersion,
+			"build_date": buildDate,
+		}
+		return printResult(v, func() {
+			fmt.Printf(msg, version, gitCommit, goVersion, buildDate)
+		})
 	}
 	return cmd
 }
@@ -0,0 +1,113 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/decomposed/blobstore"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/fsck"
+	"github.com/rs/zerolog"
+)
+
+// runFsck implements the "revad fsck" subcommand. It exits with 0 if the
+// storage is consistent, with 1 on errors and with 2 if unresolved issues remain.
+func runFsck(args []string) {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	root := fs.String("root", "", "root directory of the storage to check")
+	layout := fs.String("layout", fsck.LayoutDecomposed, "layout of the storage. One of: [decomposed, posix]")
+	backend := fs.String("metadata-backend", "", "metadata backend of the storage. Defaults to messagepack for the decomposed and hybrid for the posix layout")
+	repair := fs.Bool("repair", false, "repair the issues that can be repaired instead of only reporting them")
+	skipBlobs := fs.Bool("skip-blobs", false, "do not check for missing and dangling blobs")
+	jsonOut := fs.Bool("json", false, "print the report as JSON")
+	verbose := fs.Bool("v", false, "log every issue while checking")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: revad fsck -root <dir> [options]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *root == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	log := zerolog.Nop()
+	if *verbose {
+		log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+	}
+
+	var bs fsck.Blobstore
+	if *layout == fsck.LayoutDecomposed && !*skipBlobs {
+		b, err := blobstore.New(*root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening blobstore: %s\n", err.Error())
+			os.Exit(1)
+		}
+		bs = b
+	}
+
+	c, err := fsck.New(fsck.Options{
+		Root:            *root,
+		Layout:          *layout,
+		MetadataBackend: *backend,
+		Repair:          *repair,
+	}, bs, &log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	report, err := c.Run(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error checking storage: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "error encoding report: %s\n", err.Error())
+			os.Exit(1)
+		}
+	} else {
+		for _, i := range report.Issues {
+			status := ""
+			switch {
+			case i.Repaired:
+				status = " (repaired)"
+			case i.RepairError != "":
+				status = " (repair failed: " + i.RepairError + ")"
+			}
+			fmt.Fprintf(os.Stdout, "%s\tspace=%s node=%s path=%s: %s%s\n", i.Kind, i.SpaceID, i.NodeID, i.Path, i.Message, status)
+		}
+		fmt.Fprintf(os.Stdout, "checked %d spaces, %d nodes, %d blobs and %d uploads: %d issues, %d unresolved\n",
+			report.Spaces, report.Nodes, report.Blobs, report.Uploads, len(report.Issues), report.Unresolved())
+	}
+
+	if report.Unresolved() > 0 {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
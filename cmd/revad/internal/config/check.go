@@ -0,0 +1,226 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+)
+
+// pluggable sections hold the configurations of services, middlewares and interceptors keyed by their name
+var pluggable = []string{"grpc.services", "grpc.interceptors", "http.services", "http.middlewares"}
+
+// Report holds the result of a configuration check.
+type Report struct {
+	// Errors lists unknown keys and type errors together with their position.
+	Errors []error
+	// Unchecked lists the enabled sections no configuration schema has been registered for.
+	Unchecked []string
+}
+
+// Check validates a configuration read from the given toml source against the
+// configuration structs registered with cfg.Register. The file name is used
+// to report the position of the offending keys.
+func Check(file string, src []byte, v map[string]interface{}) *Report {
+	report := &Report{}
+	pos := positions(src)
+
+	for _, s := range cfg.Schemas() {
+		section, ok := lookup(v, s.Path())
+		if !ok {
+			continue
+		}
+		m, ok := section.(map[string]interface{})
+		if !ok {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %s: expected table", pos.at(file, s.Path()), strings.Join(s.Path(), ".")))
+			continue
+		}
+		errs := s.Validate(m)
+		sort.Slice(errs, func(i, j int) bool {
+			return strings.Join(errs[i].Path, ".") < strings.Join(errs[j].Path, ".")
+		})
+		for _, err := range errs {
+			path := append(s.Path(), err.Path...)
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %s: %s", pos.at(file, path), strings.Join(path, "."), err.Msg))
+		}
+		report.Unchecked = append(report.Unchecked, uncheckedDrivers(s, m)...)
+	}
+
+	for _, kind := range pluggable {
+		section, ok := lookup(v, strings.Split(kind, "."))
+		if !ok {
+			continue
+		}
+		m, ok := section.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name := range m {
+			if _, ok := cfg.Lookup(kind, name); !ok {
+				report.Unchecked = append(report.Unchecked, kind+"."+name)
+			}
+		}
+	}
+	sort.Strings(report.Unchecked)
+
+	return report
+}
+
+// uncheckedDrivers returns the paths of the enabled drivers of a section no schema has been registered for
+func uncheckedDrivers(s cfg.Schema, m map[string]interface{}) []string {
+	unchecked := []string{}
+	for _, d := range s.Drivers {
+		drivers, ok := m[d.Map].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		selected, _ := m[d.Selector].(string)
+		for name := range drivers {
+			if selected != "" && name != selected {
+				continue
+			}
+			if _, ok := cfg.Lookup(d.Kind, name); !ok {
+				unchecked = append(unchecked, strings.Join(append(s.Path(), d.Map, name), "."))
+			}
+		}
+	}
+	return unchecked
+}
+
+func lookup(v map[string]interface{}, path []string) (interface{}, bool) {
+	var cur interface{} = v
+	for _, key := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// keyPositions maps dotted keys of a toml document to the line they are defined on
+type keyPositions map[string]int
+
+// at returns the position of the key or of its closest parent that has been found in the document
+func (p keyPositions) at(file string, path []string) string {
+	for i := len(path); i > 0; i-- {
+		if line, ok := p[strings.Join(path[:i], ".")]; ok {
+			return fmt.Sprintf("%s:%d", file, line)
+		}
+	}
+	return file
+}
+
+// positions scans a toml document for table headers and keys. It does not fully parse
+// toml, keys defined inside of inline tables are reported at the position of the table.
+func positions(src []byte) keyPositions {
+	pos := keyPositions{}
+	table := []string{}
+	multiline := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if multiline != "" {
+			if strings.Count(text, multiline)%2 == 1 {
+				multiline = ""
+			}
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			// handles both [table] and [[array.of.tables]]
+			header := strings.Trim(strings.TrimSpace(strings.SplitN(text, "#", 2)[0]), "[]")
+			table = splitKey(header)
+			if _, ok := pos[strings.Join(table, ".")]; !ok {
+				pos[strings.Join(table, ".")] = line
+			}
+			continue
+		}
+
+		key, value, ok := cutAssignment(text)
+		if !ok {
+			continue
+		}
+		full := append(append([]string{}, table...), splitKey(key)...)
+		if _, ok := pos[strings.Join(full, ".")]; !ok {
+			pos[strings.Join(full, ".")] = line
+		}
+		for _, delim := range []string{`"""`, `'''`} {
+			if strings.Count(value, delim)%2 == 1 {
+				multiline = delim
+			}
+		}
+	}
+	return pos
+}
+
+// cutAssignment splits a `key = value` line at the first equal sign outside of quotes
+func cutAssignment(text string) (string, string, bool) {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '=':
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// splitKey splits a dotted toml key into its parts, keeping dots inside quoted parts
+func splitKey(key string) []string {
+	parts := []string{}
+	var quote rune
+	var cur strings.Builder
+	for _, r := range key {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '.':
+			parts = append(parts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(cur.String()))
+}
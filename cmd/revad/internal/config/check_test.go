@@ -0,0 +1,72 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+	"github.com/stretchr/testify/assert"
+)
+
+type checkTestConfig struct {
+	Root    string `mapstructure:"root"`
+	Enabled bool   `mapstructure:"enabled"`
+}
+
+const checkTestSource = `# test configuration
+[grpc.services.checktest]
+root = "/var/tmp"
+enabled = "yes"
+"dotted.key" = 1
+
+[grpc.services.unknown]
+foo = "bar"
+
+[http.services.checktest]
+inline = { a = 1 }
+`
+
+func TestCheck(t *testing.T) {
+	cfg.Register("grpc.services", "checktest", checkTestConfig{})
+
+	v, err := Read(bytes.NewReader([]byte(checkTestSource)))
+	assert.NoError(t, err)
+
+	report := Check("revad.toml", []byte(checkTestSource), v)
+	errs := []string{}
+	for _, err := range report.Errors {
+		errs = append(errs, err.Error())
+	}
+	assert.ElementsMatch(t, []string{
+		"revad.toml:4: grpc.services.checktest.enabled: expected boolean, got string",
+		"revad.toml:5: grpc.services.checktest.dotted.key: unknown key",
+	}, errs)
+	assert.Equal(t, []string{"grpc.services.unknown", "http.services.checktest"}, report.Unchecked)
+}
+
+func TestPositions(t *testing.T) {
+	pos := positions([]byte(checkTestSource))
+	assert.Equal(t, 2, pos["grpc.services.checktest"])
+	assert.Equal(t, 3, pos["grpc.services.checktest.root"])
+	assert.Equal(t, 5, pos["grpc.services.checktest.dotted.key"])
+	assert.Equal(t, "revad.toml:11", pos.at("revad.toml", []string{"http", "services", "checktest", "inline", "a"}))
+	assert.Equal(t, "revad.toml", pos.at("revad.toml", []string{"core"}))
+}
@@ -20,12 +20,20 @@ package config
 
 import (
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
 )
 
-// Read reads the configuration from the reader.
+// includeKey is the top level key listing the files a configuration is based on
+const includeKey = "include"
+
+// Read reads the configuration from the reader. Environment variables and secret
+// files referenced in string values are substituted, see Interpolate.
 func Read(r io.Reader) (map[string]interface{}, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
@@ -33,12 +41,126 @@ func Read(r io.Reader) (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	v := map[string]interface{}{}
-	err = toml.Unmarshal(data, &v)
+	v, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Interpolate(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ReadFile reads the configuration from the given file. The files listed in the top level
+// `include` key are read first, in order, and the configuration of the file is merged on
+// top of them. Include paths are relative to the including file and may contain globs.
+// Environment variables and secret files are substituted after merging, see Interpolate.
+func ReadFile(name string) (map[string]interface{}, error) {
+	v, err := readFile(name, map[string]bool{})
 	if err != nil {
-		err = errors.Wrap(err, "config: error decoding toml data")
 		return nil, err
 	}
 
+	if err := Interpolate(v); err != nil {
+		return nil, err
+	}
 	return v, nil
 }
+
+func decode(data []byte) (map[string]interface{}, error) {
+	v := map[string]interface{}{}
+	if err := toml.Unmarshal(data, &v); err != nil {
+		err = errors.Wrap(err, "config: error decoding toml data")
+		return nil, err
+	}
+	return v, nil
+}
+
+func readFile(name string, reading map[string]bool) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if reading[abs] {
+		return nil, errors.Errorf("config: include cycle detected at %s", name)
+	}
+	reading[abs] = true
+	defer delete(reading, abs)
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "config: error reading file")
+	}
+	v, err := decode(data)
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+
+	includes, err := includesOf(v)
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	delete(v, includeKey)
+	if len(includes) == 0 {
+		return v, nil
+	}
+
+	merged := map[string]interface{}{}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(name), include)
+		}
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return nil, errors.Wrapf(err, "config: invalid include %s", include)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(include, "*?[") {
+			return nil, errors.Errorf("config: included file %s does not exist", include)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			iv, err := readFile(match, reading)
+			if err != nil {
+				return nil, err
+			}
+			merge(merged, iv)
+		}
+	}
+	merge(merged, v)
+	return merged, nil
+}
+
+func includesOf(v map[string]interface{}) ([]string, error) {
+	switch include := v[includeKey].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{include}, nil
+	case []interface{}:
+		includes := make([]string, 0, len(include))
+		for _, i := range include {
+			s, ok := i.(string)
+			if !ok {
+				return nil, errors.New("config: include must be a string or an array of strings")
+			}
+			includes = append(includes, s)
+		}
+		return includes, nil
+	default:
+		return nil, errors.New("config: include must be a string or an array of strings")
+	}
+}
+
+// merge merges src into dst. Tables are merged recursively, all other values in src replace the ones in dst.
+func merge(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				merge(dm, sm)
+				continue
+			}
+		}
+		dst[k] = sv
+	}
+}
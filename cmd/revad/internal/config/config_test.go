@@ -0,0 +1,164 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("REVA_TEST_SECRET", "s3cr3t")
+	t.Setenv("REVA_TEST_EMPTY", "")
+	secret := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secret, []byte("from-file\n"), 0600))
+
+	v := map[string]interface{}{
+		"shared": map[string]interface{}{
+			"jwt_secret": "${REVA_TEST_SECRET}",
+			"gatewaysvc": "${REVA_TEST_UNSET:-localhost:9142}",
+			"empty":      "${REVA_TEST_EMPTY:-default}",
+			"mixed":      "pre-${REVA_TEST_SECRET}-post",
+			"escaped":    "$${REVA_TEST_SECRET}",
+			"file":       "@file:" + secret,
+			"list":       []interface{}{"${REVA_TEST_SECRET}", int64(1)},
+		},
+		"number": int64(1),
+	}
+	assert.NoError(t, Interpolate(v))
+	assert.Equal(t, map[string]interface{}{
+		"jwt_secret": "s3cr3t",
+		"gatewaysvc": "localhost:9142",
+		"empty":      "default",
+		"mixed":      "pre-s3cr3t-post",
+		"escaped":    "${REVA_TEST_SECRET}",
+		"file":       "from-file",
+		"list":       []interface{}{"s3cr3t", int64(1)},
+	}, v["shared"])
+
+	assert.Error(t, Interpolate(map[string]interface{}{"a": "${REVA_TEST_UNSET}"}))
+	assert.Error(t, Interpolate(map[string]interface{}{"a": "${REVA_TEST_SECRET"}))
+	assert.Error(t, Interpolate(map[string]interface{}{"a": "@file:/does/not/exist"}))
+}
+
+type interpolateTestConfig struct {
+	Port    int           `mapstructure:"port"`
+	Enabled bool          `mapstructure:"enabled"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	Name    string        `mapstructure:"name"`
+}
+
+func TestInterpolateConvertsToFieldType(t *testing.T) {
+	cfg.Register("grpc.services", "interpolatetest", interpolateTestConfig{})
+	t.Setenv("REVA_TEST_PORT", "9142")
+	t.Setenv("REVA_TEST_ENABLED", "true")
+
+	v := map[string]interface{}{
+		"grpc": map[string]interface{}{
+			"services": map[string]interface{}{
+				"interpolatetest": map[string]interface{}{
+					"port":    "${REVA_TEST_PORT}",
+					"enabled": "${REVA_TEST_ENABLED}",
+					"timeout": "${REVA_TEST_UNSET:-5s}",
+					"name":    "${REVA_TEST_PORT}",
+				},
+			},
+		},
+	}
+	assert.NoError(t, Interpolate(v))
+	assert.Equal(t, map[string]interface{}{
+		"port":    int64(9142),
+		"enabled": true,
+		"timeout": int64(5 * time.Second),
+		"name":    "9142",
+	}, v["grpc"].(map[string]interface{})["services"].(map[string]interface{})["interpolatetest"])
+
+	t.Setenv("REVA_TEST_PORT", "not-a-port")
+	v["grpc"].(map[string]interface{})["services"].(map[string]interface{})["interpolatetest"] = map[string]interface{}{"port": "${REVA_TEST_PORT}"}
+	assert.Error(t, Interpolate(v))
+}
+
+func TestReadFileIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0700))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0600))
+		return p
+	}
+	write("base.toml", `
+[shared]
+jwt_secret = "base"
+gatewaysvc = "localhost:9142"
+
+[grpc.services.gateway]
+commit_share_to_storage_grant = true
+`)
+	write("overlays/a.toml", `
+[grpc.services.gateway]
+usershareprovidersvc = "a"
+`)
+	node := write("node.toml", `
+include = ["base.toml", "overlays/*.toml"]
+
+[shared]
+jwt_secret = "${REVA_TEST_NODE_SECRET:-node}"
+`)
+
+	v, err := ReadFile(node)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"shared": map[string]interface{}{
+			"jwt_secret": "node",
+			"gatewaysvc": "localhost:9142",
+		},
+		"grpc": map[string]interface{}{
+			"services": map[string]interface{}{
+				"gateway": map[string]interface{}{
+					"commit_share_to_storage_grant": true,
+					"usershareprovidersvc":          "a",
+				},
+			},
+		},
+	}, v)
+
+	v, err = ReadFile(write("escaped.toml", `
+[shared]
+jwt_secret = "$${REVA_TEST_NODE_SECRET:-node}"
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "${REVA_TEST_NODE_SECRET:-node}", v["shared"].(map[string]interface{})["jwt_secret"])
+
+	_, err = ReadFile(write("unset.toml", `
+[shared]
+jwt_secret = "${REVA_TEST_UNSET}"
+`))
+	assert.Error(t, err)
+
+	_, err = ReadFile(write("missing.toml", `include = "nope.toml"`))
+	assert.Error(t, err)
+
+	_, err = ReadFile(write("cycle.toml", `include = "cycle.toml"`))
+	assert.Error(t, err)
+}
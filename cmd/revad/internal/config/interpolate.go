@@ -0,0 +1,194 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+	"github.com/pkg/errors"
+)
+
+// filePrefix marks a value that is replaced with the content of a file, e.g. a mounted secret
+const filePrefix = "@file:"
+
+// Interpolate substitutes references in all string values of the configuration:
+//
+//   - `${VAR}` is replaced with the value of the environment variable VAR, it is an error if VAR is not set
+//   - `${VAR:-default}` is replaced with the value of VAR or with default if VAR is unset or empty
+//   - `$${` is replaced with a literal `${`
+//   - a value of the form `@file:/run/secrets/x` is replaced with the content of the file,
+//     without the trailing newline
+//
+// Substituted values are converted to the type of the configuration field they are assigned to,
+// e.g. `port = "${PORT}"` results in an integer, if a schema has been registered for the section.
+func Interpolate(v map[string]interface{}) error {
+	return interpolateMap(nil, v)
+}
+
+func interpolateMap(path []string, v map[string]interface{}) error {
+	for k, val := range v {
+		iv, err := interpolateValue(append(path[:len(path):len(path)], k), val)
+		if err != nil {
+			return errors.Wrap(err, k)
+		}
+		v[k] = iv
+	}
+	return nil
+}
+
+func interpolateValue(path []string, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		s, expanded, err := expand(val)
+		if err != nil || !expanded {
+			return s, err
+		}
+		return convert(path, s)
+	case map[string]interface{}:
+		return val, interpolateMap(path, val)
+	case []interface{}:
+		for i := range val {
+			iv, err := interpolateValue(append(path[:len(path):len(path)], strconv.Itoa(i)), val[i])
+			if err != nil {
+				return nil, err
+			}
+			val[i] = iv
+		}
+		return val, nil
+	case []map[string]interface{}:
+		for i, m := range val {
+			if err := interpolateMap(append(path[:len(path):len(path)], strconv.Itoa(i)), m); err != nil {
+				return nil, err
+			}
+		}
+		return val, nil
+	}
+	return v, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// convert converts a substituted value to the type of the configuration field at the given path
+func convert(path []string, s string) (interface{}, error) {
+	t, ok := cfg.FieldType(path)
+	if !ok {
+		return s, nil
+	}
+	var (
+		v   interface{}
+		err error
+	)
+	switch t.Kind() {
+	case reflect.Bool:
+		v, err = strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err = strconv.ParseInt(s, 10, 64)
+		if err != nil && t == durationType {
+			var d time.Duration
+			d, err = time.ParseDuration(s)
+			v = int64(d)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err = strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		v, err = strconv.ParseFloat(s, 64)
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("config: cannot convert %q to %s", s, t.Kind())
+	}
+	return v, nil
+}
+
+// expand substitutes the references in s. The returned bool is true if s contained a reference.
+func expand(s string) (string, bool, error) {
+	if name, ok := strings.CutPrefix(s, filePrefix); ok {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", false, errors.Wrap(err, "config: error reading secret file")
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+
+	if !strings.Contains(s, "${") {
+		return s, false, nil
+	}
+
+	var b strings.Builder
+	expanded := false
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), expanded, nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			// escaped reference
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.Index(s[i:], "}")
+		if end < 0 {
+			return "", false, errors.Errorf("config: unterminated reference in %q", s)
+		}
+		b.WriteString(s[:i])
+		value, err := resolve(s[i+2 : i+end])
+		if err != nil {
+			return "", false, err
+		}
+		b.WriteString(value)
+		expanded = true
+		s = s[i+end+1:]
+	}
+}
+
+func resolve(ref string) (string, error) {
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if !validName(name) {
+		return "", errors.Errorf("config: invalid environment variable name %q", name)
+	}
+	value, ok := os.LookupEnv(name)
+	switch {
+	case hasDefault && value == "":
+		return def, nil
+	case !ok:
+		return "", errors.Errorf("config: environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
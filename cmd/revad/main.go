@@ -19,6 +19,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -31,6 +32,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/cmd/revad/internal/grace"
 	"github.com/opencloud-eu/reva/v2/cmd/revad/runtime"
 	"github.com/opencloud-eu/reva/v2/pkg/sysinfo"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 
 	"github.com/google/uuid"
 )
@@ -43,12 +45,19 @@ var (
 	pidFlag     = flag.String("p", "", "pid file. If empty defaults to a random file in the OS temporary directory")
 	logFlag     = flag.String("log", "", "log messages with the given severity or above. One of: [trace, debug, info, warn, error, fatal, panic]")
 	dirFlag     = flag.String("dev-dir", "", "runs any toml file in the specified directory. Intended for development use only")
+	checkFlag   = flag.Bool("check-config", false, "validate the configuration of the enabled services and drivers that registered a schema, list the unchecked ones and exit")
+	schemaFlag  = flag.Bool("dump-config-schema", false, "print a JSON schema of the configuration of all registered services and drivers and exit")
 
 	// Compile time variables initialized with gcc flags.
 	gitCommit, buildDate, version, goVersion string
 )
 
 func main() {
+	// subcommands bring their own flags
+	if len(os.Args) > 1 && os.Args[1] == "fsck" {
+		runFsck(os.Args[2:])
+	}
+
 	flag.Parse()
 
 	// initialize the global system information
@@ -59,8 +68,9 @@ func main() {
 
 	handleVersionFlag()
 	handleSignalFlag()
+	handleSchemaFlag()
 
-	confs, err := getConfigs()
+	confs, invalid, err := getConfigs()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error reading the configuration file(s): %s\n", err.Error())
 		os.Exit(1)
@@ -79,6 +89,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	// if check flag is true we exit, failing if any configuration contains unknown keys or type errors.
+	if *checkFlag {
+		if invalid {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	runConfigs(confs)
 }
 
@@ -134,13 +152,25 @@ func handleSignalFlag() {
 	}
 }
 
-func getConfigs() ([]map[string]interface{}, error) {
+func handleSchemaFlag() {
+	if *schemaFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cfg.JSONSchema()); err != nil {
+			fmt.Fprintf(os.Stderr, "error encoding configuration schema: %s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+func getConfigs() ([]map[string]interface{}, bool, error) {
 	var confs []string
 	// give priority to read from dev-dir
 	if *dirFlag != "" {
 		cfgs, err := getConfigsFromDir(*dirFlag)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		confs = append(confs, cfgs...)
 	} else {
@@ -153,12 +183,7 @@ func getConfigs() ([]map[string]interface{}, error) {
 		os.Exit(1)
 	}
 
-	configs, err := readConfigs(confs)
-	if err != nil {
-		return nil, err
-	}
-
-	return configs, nil
+	return readConfigs(confs)
 }
 
 func getConfigsFromDir(dir string) (confs []string, err error) {
@@ -178,22 +203,35 @@ func getConfigsFromDir(dir string) (confs []string, err error) {
 	return
 }
 
-func readConfigs(files []string) ([]map[string]interface{}, error) {
+// readConfigs reads and checks the given configuration files. Problems found while checking are
+// printed to stderr, the returned bool is true if any configuration contains unknown keys or type errors.
+func readConfigs(files []string) ([]map[string]interface{}, bool, error) {
 	confs := make([]map[string]interface{}, 0, len(files))
+	invalid := false
 	for _, conf := range files {
-		fd, err := os.Open(conf)
+		v, err := config.ReadFile(conf)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
-		defer fd.Close()
-
-		v, err := config.Read(fd)
+		// keys are reported at their position in the given file, keys from included files at the file itself
+		data, err := os.ReadFile(conf)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		confs = append(confs, v)
+
+		report := config.Check(conf, data, v)
+		for _, err := range report.Errors {
+			fmt.Fprintf(os.Stderr, "invalid configuration: %s\n", err.Error())
+		}
+		if *checkFlag {
+			for _, section := range report.Unchecked {
+				fmt.Fprintf(os.Stderr, "%s: no configuration schema registered for %s, skipping\n", conf, section)
+			}
+		}
+		invalid = invalid || len(report.Errors) > 0
 	}
-	return confs, nil
+	return confs, invalid, nil
 }
 
 func runConfigs(confs []map[string]interface{}) {
@@ -35,6 +35,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	rtrace "github.com/opencloud-eu/reva/v2/pkg/trace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
@@ -59,6 +60,10 @@ func RunWithOptions(mainConf map[string]interface{}, pidFile string, opts ...Opt
 	run(mainConf, coreConf, options.Logger, options.TraceProvider, pidFile)
 }
 
+func init() {
+	cfg.Register("", "core", coreConf{})
+}
+
 type coreConf struct {
 	MaxCPUs            string `mapstructure:"max_cpus"`
 	TracingEnabled     bool   `mapstructure:"tracing_enabled"`
@@ -27,6 +27,7 @@ import (
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
@@ -234,6 +235,18 @@ func FileUploaded(r *provider.InitiateFileUploadResponse, req *provider.Initiate
 	}
 }
 
+// FileDropped converts the request of an upload into a secure file drop to an event
+func FileDropped(ref *provider.Reference, opaque *types.Opaque, spaceOwner *user.UserId, executant *user.User) events.FileDropped {
+	return events.FileDropped{
+		SpaceOwner: spaceOwner,
+		Owner:      spaceOwner,
+		Executant:  executant.GetId(),
+		Ref:        ref,
+		Label:      utils.ReadPlainFromOpaque(opaque, events.FileDropLabelOpaqueKey),
+		Timestamp:  utils.TSNow(),
+	}
+}
+
 // FileDownloaded converts the response to an event
 func FileDownloaded(r *provider.InitiateFileDownloadResponse, req *provider.InitiateFileDownloadRequest, executant *user.User) events.FileDownloaded {
 	return events.FileDownloaded{
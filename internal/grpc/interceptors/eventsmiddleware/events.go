@@ -30,6 +30,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 	revactx "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
@@ -203,6 +204,22 @@ func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error
 			}
 		}
 
+		// empty files dropped into secure file drops are announced to the owner in addition to the
+		// regular events, the storage drivers announce uploads with content when they have finished
+		var dropped interface{}
+		if v, ok := res.(*provider.TouchFileResponse); ok && isSuccess(v) {
+			r := req.(*provider.TouchFileRequest)
+			scopes, _ := revactx.ContextGetScopes(ctx)
+			if utils.ExistsInOpaque(r.Opaque, events.FileDropLabelOpaqueKey) && scope.IsPublicShareUploader(scopes) {
+				dropped = FileDropped(r.Ref, r.Opaque, ownerID, executant)
+			}
+		}
+		if dropped != nil {
+			if err := events.Publish(ctx, publisher, dropped); err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Interface("event", dropped).Msg("publishing event failed")
+			}
+		}
+
 		return res, nil
 	}
 	return interceptor, defaultPriority, nil
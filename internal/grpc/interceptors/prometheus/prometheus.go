@@ -20,58 +20,212 @@ package prometheus
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
+	"github.com/opencloud-eu/reva/v2/pkg/trace"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	defaultPriority = 100
 )
 
+// the classes requests are sorted into by their CS3 status code
+const (
+	classSuccess     = "success"
+	classClientError = "client_error"
+	classServerError = "server_error"
+)
+
 func init() {
 	rgrpc.RegisterUnaryInterceptor("prometheus", NewUnary)
+	rgrpc.RegisterStreamInterceptor("prometheus", NewStream)
+}
+
+type config struct {
+	Namespace string    `mapstructure:"namespace"`
+	Subsystem string    `mapstructure:"subsystem"`
+	Buckets   []float64 `mapstructure:"buckets"`
+}
+
+func (c *config) init() {
+	if c.Namespace == "" {
+		c.Namespace = "reva"
+	}
+	if len(c.Buckets) == 0 {
+		c.Buckets = prometheus.DefBuckets
+	}
+}
+
+type metrics struct {
+	requests prometheus.Counter
+	duration *prometheus.HistogramVec
 }
 
 // NewUnary returns a new unary interceptor
-// that counts grpc calls.
+// that counts grpc calls and records their duration.
 func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
-	interceptor, err := interceptorFromConfig(m)
+	metrics, err := metricsFromConfig(m)
 	if err != nil {
 		return nil, 0, err
 	}
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		metrics.observe(ctx, info.FullMethod, storageID(req), statusCode(res, err), time.Since(start))
+		return res, err
+	}
 	return interceptor, defaultPriority, nil
 }
 
 // NewStream returns a new server stream interceptor
-// that counts grpc calls.
-func NewStream() grpc.StreamServerInterceptor {
+// that counts grpc calls and records their duration.
+func NewStream(m map[string]interface{}) (grpc.StreamServerInterceptor, int, error) {
+	metrics, err := metricsFromConfig(m)
+	if err != nil {
+		return nil, 0, err
+	}
 	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
 		err := handler(srv, ss)
-		// TODO count res codes & errors
+		metrics.observe(ss.Context(), info.FullMethod, "", statusCode(nil, err), time.Since(start))
 		return err
 	}
-	return interceptor
+	return interceptor, defaultPriority, nil
 }
 
-func interceptorFromConfig(m map[string]interface{}) (grpc.UnaryServerInterceptor, error) {
-	namespace := m["namespace"].(string)
-	if namespace == "" {
-		namespace = "reva"
+func metricsFromConfig(m map[string]interface{}) (*metrics, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, err
 	}
-	subsystem := m["subsystem"].(string)
-	reqProcessed := promauto.NewCounter(prometheus.CounterOpts{
-		Namespace: namespace,
-		Subsystem: subsystem,
+	c.init()
+
+	requests := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: c.Namespace,
+		Subsystem: c.Subsystem,
 		Name:      "grpc_requests_total",
-		Help:      "The total number of processed " + subsystem + " GRPC requests for " + namespace,
+		Help:      "The total number of processed " + c.Subsystem + " GRPC requests for " + c.Namespace,
 	})
-	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		res, err := handler(ctx, req)
-		reqProcessed.Inc()
-		return res, err
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: c.Namespace,
+		Subsystem: c.Subsystem,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "The duration of the processed " + c.Subsystem + " GRPC requests for " + c.Namespace + " by method, CS3 status code and storage provider",
+		Buckets:   c.Buckets,
+	}, []string{"method", "code", "class", "provider"})
+
+	var err error
+	if requests, err = register(requests); err != nil {
+		return nil, err
+	}
+	if duration, err = register(duration); err != nil {
+		return nil, err
+	}
+	return &metrics{requests: requests, duration: duration}, nil
+}
+
+// register registers c with the default registry. If an identical collector
+// was registered before, e.g. by another server, that one is returned instead.
+func register[T prometheus.Collector](c T) (T, error) {
+	if err := prometheus.Register(c); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+func (m *metrics) observe(ctx context.Context, method, storageID string, code rpc.Code, d time.Duration) {
+	m.requests.Inc()
+	o := m.duration.WithLabelValues(method, code.String(), class(code), storageID)
+	if traceID, ok := trace.ContextGetTraceID(ctx); ok {
+		o.(prometheus.ExemplarObserver).ObserveWithExemplar(d.Seconds(), prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	o.Observe(d.Seconds())
+}
+
+// statusCode returns the CS3 status code of a response. CS3 services signal most
+// errors in the status of an otherwise successful response, transport errors
+// are mapped to the equivalent CS3 code.
+func statusCode(res interface{}, err error) rpc.Code {
+	if err != nil {
+		code := status.Code(err)
+		if code == codes.Unknown {
+			code = status.FromContextError(err).Code()
+		}
+		if c, ok := grpcCodes[code]; ok {
+			return c
+		}
+		return rpc.Code_CODE_UNKNOWN
+	}
+	if r, ok := res.(interface{ GetStatus() *rpc.Status }); ok && r.GetStatus() != nil {
+		return r.GetStatus().GetCode()
+	}
+	return rpc.Code_CODE_OK
+}
+
+// storageID returns the id of the storage provider a request references
+func storageID(req interface{}) string {
+	if r, ok := req.(interface{ GetRef() *provider.Reference }); ok {
+		return r.GetRef().GetResourceId().GetStorageId()
 	}
-	return interceptor, nil
+	return ""
+}
+
+// class sorts a CS3 status code into success, client or server errors
+func class(code rpc.Code) string {
+	switch code {
+	case rpc.Code_CODE_OK, rpc.Code_CODE_REDIRECTION:
+		return classSuccess
+	case rpc.Code_CODE_CANCELLED,
+		rpc.Code_CODE_INVALID_ARGUMENT,
+		rpc.Code_CODE_NOT_FOUND,
+		rpc.Code_CODE_ALREADY_EXISTS,
+		rpc.Code_CODE_PERMISSION_DENIED,
+		rpc.Code_CODE_UNAUTHENTICATED,
+		rpc.Code_CODE_RESOURCE_EXHAUSTED,
+		rpc.Code_CODE_FAILED_PRECONDITION,
+		rpc.Code_CODE_ABORTED,
+		rpc.Code_CODE_OUT_OF_RANGE,
+		rpc.Code_CODE_INSUFFICIENT_STORAGE,
+		rpc.Code_CODE_LOCKED,
+		rpc.Code_CODE_TOO_EARLY:
+		return classClientError
+	default:
+		return classServerError
+	}
+}
+
+var grpcCodes = map[codes.Code]rpc.Code{
+	codes.OK:                 rpc.Code_CODE_OK,
+	codes.Canceled:           rpc.Code_CODE_CANCELLED,
+	codes.Unknown:            rpc.Code_CODE_UNKNOWN,
+	codes.InvalidArgument:    rpc.Code_CODE_INVALID_ARGUMENT,
+	codes.DeadlineExceeded:   rpc.Code_CODE_DEADLINE_EXCEEDED,
+	codes.NotFound:           rpc.Code_CODE_NOT_FOUND,
+	codes.AlreadyExists:      rpc.Code_CODE_ALREADY_EXISTS,
+	codes.PermissionDenied:   rpc.Code_CODE_PERMISSION_DENIED,
+	codes.ResourceExhausted:  rpc.Code_CODE_RESOURCE_EXHAUSTED,
+	codes.FailedPrecondition: rpc.Code_CODE_FAILED_PRECONDITION,
+	codes.Aborted:            rpc.Code_CODE_ABORTED,
+	codes.OutOfRange:         rpc.Code_CODE_OUT_OF_RANGE,
+	codes.Unimplemented:      rpc.Code_CODE_UNIMPLEMENTED,
+	codes.Internal:           rpc.Code_CODE_INTERNAL,
+	codes.Unavailable:        rpc.Code_CODE_UNAVAILABLE,
+	codes.DataLoss:           rpc.Code_CODE_DATA_LOSS,
+	codes.Unauthenticated:    rpc.Code_CODE_UNAUTHENTICATED,
 }
@@ -0,0 +1,121 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package prometheus
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusCode(t *testing.T) {
+	type test struct {
+		res      interface{}
+		err      error
+		expected rpc.Code
+	}
+
+	tests := []*test{
+		// errors in the status of the response are not successes
+		{
+			res:      &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}},
+			expected: rpc.Code_CODE_NOT_FOUND,
+		},
+		{
+			res:      &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}},
+			expected: rpc.Code_CODE_OK,
+		},
+		// responses without a status are successful
+		{
+			res:      &provider.StatResponse{},
+			expected: rpc.Code_CODE_OK,
+		},
+		// transport errors are mapped to the CS3 codes
+		{
+			err:      status.Error(codes.Unavailable, "down"),
+			expected: rpc.Code_CODE_UNAVAILABLE,
+		},
+		{
+			err:      context.Canceled,
+			expected: rpc.Code_CODE_CANCELLED,
+		},
+	}
+
+	for _, tt := range tests {
+		if code := statusCode(tt.res, tt.err); code != tt.expected {
+			t.Errorf("statusCode(%v, %v) = %v, expected %v", tt.res, tt.err, code, tt.expected)
+		}
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor, _, err := NewUnary(map[string]interface{}{"subsystem": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second server with the same config shares the metrics
+	if _, _, err := NewUnary(map[string]interface{}{"subsystem": "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/cs3.storage.provider.v1beta1.ProviderAPI/Stat"}
+	req := &provider.StatRequest{Ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage"}}}
+	for _, c := range []rpc.Code{rpc.Code_CODE_OK, rpc.Code_CODE_INTERNAL, rpc.Code_CODE_INTERNAL, rpc.Code_CODE_NOT_FOUND} {
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &provider.StatResponse{Status: &rpc.Status{Code: c}}, nil
+		}
+		if _, err := interceptor(context.Background(), req, info, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := metricsFromConfig(map[string]interface{}{"subsystem": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+# HELP reva_test_grpc_requests_total The total number of processed test GRPC requests for reva
+# TYPE reva_test_grpc_requests_total counter
+reva_test_grpc_requests_total 4
+`
+	if err := testutil.CollectAndCompare(m.requests, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	for code, n := range map[rpc.Code]uint64{rpc.Code_CODE_OK: 1, rpc.Code_CODE_INTERNAL: 2, rpc.Code_CODE_NOT_FOUND: 1} {
+		metric := &dto.Metric{}
+		if err := m.duration.WithLabelValues(info.FullMethod, code.String(), class(code), "storage").(prometheus.Metric).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		if count := metric.GetHistogram().GetSampleCount(); count != n {
+			t.Errorf("expected %d requests with %s, got %d", n, code, count)
+		}
+	}
+	if count := testutil.CollectAndCount(m.duration); count != 3 {
+		t.Errorf("expected 3 series, got %d", count)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -35,6 +36,7 @@ import (
 
 func init() {
 	rgrpc.Register("applicationauth", New)
+	cfg.Register("grpc.services", "applicationauth", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "appauth.manager"})
 }
 
 type config struct {
@@ -39,12 +39,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 )
 
 func init() {
 	rgrpc.Register("appprovider", New)
+	cfg.Register("grpc.services", "appprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "app.provider"})
 }
 
 type service struct {
@@ -30,11 +30,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	rgrpc.Register("appregistry", New)
+	cfg.Register("grpc.services", "appregistry", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "app.registry"})
 }
 
 type svc struct {
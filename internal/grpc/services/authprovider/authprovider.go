@@ -32,6 +32,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/plugin"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -39,6 +40,7 @@ import (
 
 func init() {
 	rgrpc.Register("authprovider", New)
+	cfg.Register("grpc.services", "authprovider", config{}, cfg.Driver{Selector: "auth_manager", Map: "auth_managers", Kind: "auth.manager"})
 }
 
 type config struct {
@@ -28,12 +28,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 )
 
 func init() {
 	rgrpc.Register("authregistry", New)
+	cfg.Register("grpc.services", "authregistry", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "auth.registry"})
 }
 
 type service struct {
@@ -36,6 +36,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -43,6 +44,10 @@ import (
 
 func init() {
 	rgrpc.Register("datatx", New)
+	cfg.Register("grpc.services", "datatx", config{},
+		cfg.Driver{Selector: "txdriver", Map: "txdrivers", Kind: "datatx.manager"},
+		cfg.Driver{Selector: "storage_driver", Map: "storage_drivers", Kind: "datatx.repository"},
+	)
 }
 
 type config struct {
@@ -31,6 +31,8 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
 	"github.com/opencloud-eu/reva/v2/pkg/token"
 	"github.com/opencloud-eu/reva/v2/pkg/token/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -44,6 +46,7 @@ const (
 
 func init() {
 	rgrpc.Register("gateway", New)
+	cfg.Register("grpc.services", "gateway", config{}, cfg.Driver{Selector: "token_manager", Map: "token_managers", Kind: "token.manager"})
 }
 
 type config struct {
@@ -183,6 +186,7 @@ func New(m map[string]interface{}, _ *grpc.Server, _ *zerolog.Logger) (rgrpc.Ser
 
 func (s *svc) Register(ss *grpc.Server) {
 	gateway.RegisterGatewayAPIServer(ss, s)
+	provisioning.RegisterUserProvisioningGatewayAPIServer(ss, s)
 }
 
 func (s *svc) Close() error {
@@ -24,6 +24,7 @@ import (
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
 	"github.com/pkg/errors"
 )
 
@@ -90,3 +91,19 @@ func (s *svc) GetUserGroups(ctx context.Context, req *user.GetUserGroupsRequest)
 
 	return res, nil
 }
+
+func (s *svc) UpdateUser(ctx context.Context, req *provisioning.UpdateUserRequest) (*provisioning.UpdateUserResponse, error) {
+	c, err := pool.GetUserProvisioningServiceClient(s.c.UserProviderEndpoint)
+	if err != nil {
+		return &provisioning.UpdateUserResponse{
+			Status: status.NewInternal(ctx, "error getting user provisioning client"),
+		}, nil
+	}
+
+	res, err := c.UpdateUser(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling UpdateUser")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package groupprovider
+
+import (
+	"context"
+
+	adminpb "github.com/cs3org/go-cs3apis/cs3/admin/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/group"
+	"github.com/opencloud-eu/reva/v2/pkg/permission"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+// CreateGroup creates a group.
+func (s *service) CreateGroup(ctx context.Context, req *adminpb.CreateGroupRequest) (*adminpb.CreateGroupResponse, error) {
+	if st := s.checkPermission(ctx); st != nil {
+		return &adminpb.CreateGroupResponse{Status: st}, nil
+	}
+	p, ok := s.groupmgr.(group.Provisioner)
+	if !ok {
+		return &adminpb.CreateGroupResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the group manager does not support provisioning"),
+		}, nil
+	}
+	if req.Group == nil {
+		return &adminpb.CreateGroupResponse{
+			Status: status.NewInvalid(ctx, "group missing"),
+		}, nil
+	}
+
+	g, err := p.CreateGroup(ctx, req.Group)
+	if err != nil {
+		return &adminpb.CreateGroupResponse{
+			Status: status.NewStatusFromErrType(ctx, "error creating group", err),
+		}, nil
+	}
+
+	s.publish(ctx, events.GroupCreated{
+		Executant: executant(ctx),
+		GroupID:   g.GetId().GetOpaqueId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	return &adminpb.CreateGroupResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// DeleteGroup deletes a group.
+func (s *service) DeleteGroup(ctx context.Context, req *adminpb.DeleteGroupRequest) (*adminpb.DeleteGroupResponse, error) {
+	if st := s.checkPermission(ctx); st != nil {
+		return &adminpb.DeleteGroupResponse{Status: st}, nil
+	}
+	p, ok := s.groupmgr.(group.Provisioner)
+	if !ok {
+		return &adminpb.DeleteGroupResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the group manager does not support provisioning"),
+		}, nil
+	}
+	if req.GroupId == nil {
+		return &adminpb.DeleteGroupResponse{
+			Status: status.NewInvalid(ctx, "groupid missing"),
+		}, nil
+	}
+
+	if err := p.DeleteGroup(ctx, req.GroupId); err != nil {
+		return &adminpb.DeleteGroupResponse{
+			Status: status.NewStatusFromErrType(ctx, "error deleting group", err),
+		}, nil
+	}
+
+	s.publish(ctx, events.GroupDeleted{
+		Executant: executant(ctx),
+		GroupID:   req.GroupId.GetOpaqueId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	return &adminpb.DeleteGroupResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// AddUserToGroup adds a user to the members of a group.
+func (s *service) AddUserToGroup(ctx context.Context, req *adminpb.AddUserToGroupRequest) (*adminpb.AddUserToGroupResponse, error) {
+	if st := s.checkPermission(ctx); st != nil {
+		return &adminpb.AddUserToGroupResponse{Status: st}, nil
+	}
+	p, ok := s.groupmgr.(group.Provisioner)
+	if !ok {
+		return &adminpb.AddUserToGroupResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the group manager does not support provisioning"),
+		}, nil
+	}
+	if req.GroupId == nil || req.UserId == nil {
+		return &adminpb.AddUserToGroupResponse{
+			Status: status.NewInvalid(ctx, "groupid or userid missing"),
+		}, nil
+	}
+
+	if err := p.AddMember(ctx, req.GroupId, req.UserId); err != nil {
+		return &adminpb.AddUserToGroupResponse{
+			Status: status.NewStatusFromErrType(ctx, "error adding group member", err),
+		}, nil
+	}
+
+	s.publish(ctx, events.GroupMemberAdded{
+		Executant: executant(ctx),
+		GroupID:   req.GroupId.GetOpaqueId(),
+		UserID:    req.UserId.GetOpaqueId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	return &adminpb.AddUserToGroupResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// RemoveUserFromGroup removes a user from the members of a group.
+func (s *service) RemoveUserFromGroup(ctx context.Context, req *adminpb.RemoveUserFromGroupRequest) (*adminpb.RemoveUserFromGroupResponse, error) {
+	if st := s.checkPermission(ctx); st != nil {
+		return &adminpb.RemoveUserFromGroupResponse{Status: st}, nil
+	}
+	p, ok := s.groupmgr.(group.Provisioner)
+	if !ok {
+		return &adminpb.RemoveUserFromGroupResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the group manager does not support provisioning"),
+		}, nil
+	}
+	if req.GroupId == nil || req.UserId == nil {
+		return &adminpb.RemoveUserFromGroupResponse{
+			Status: status.NewInvalid(ctx, "groupid or userid missing"),
+		}, nil
+	}
+
+	if err := p.RemoveMember(ctx, req.GroupId, req.UserId); err != nil {
+		return &adminpb.RemoveUserFromGroupResponse{
+			Status: status.NewStatusFromErrType(ctx, "error removing group member", err),
+		}, nil
+	}
+
+	s.publish(ctx, events.GroupMemberRemoved{
+		Executant: executant(ctx),
+		GroupID:   req.GroupId.GetOpaqueId(),
+		UserID:    req.UserId.GetOpaqueId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	return &adminpb.RemoveUserFromGroupResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// checkPermission returns a non-nil status if the current user is not allowed
+// to manage groups.
+func (s *service) checkPermission(ctx context.Context) *rpc.Status {
+	if _, ok := ctxpkg.ContextGetUser(ctx); !ok {
+		return status.NewPermissionDenied(ctx, nil, "no user in context")
+	}
+
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return status.NewInternal(ctx, "error getting gateway client")
+	}
+	allowed, err := utils.CheckPermission(ctx, permission.WriteGroups, gatewayClient)
+	if err != nil {
+		return status.NewInternal(ctx, "error checking permission")
+	}
+	if !allowed {
+		return status.NewPermissionDenied(ctx, nil, "no permission to manage groups")
+	}
+	return nil
+}
+
+func (s *service) publish(ctx context.Context, ev interface{}) {
+	if s.stream == nil {
+		return
+	}
+	if err := events.Publish(ctx, s.stream, ev); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Interface("event", ev).Msg("error publishing event")
+	}
+}
+
+func executant(ctx context.Context) *userpb.UserId {
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		return u.Id
+	}
+	return nil
+}
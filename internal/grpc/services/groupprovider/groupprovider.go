@@ -23,13 +23,20 @@ import (
 	"fmt"
 	"sort"
 
+	adminpb "github.com/cs3org/go-cs3apis/cs3/admin/group/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
 	"github.com/opencloud-eu/reva/v2/pkg/group"
 	"github.com/opencloud-eu/reva/v2/pkg/group/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -37,17 +44,32 @@ import (
 
 func init() {
 	rgrpc.Register("groupprovider", New)
+	cfg.Register("grpc.services", "groupprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "group.manager"})
 }
 
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	Events  eventconfig                       `mapstructure:"events" docs:"0;Event stream configuration"`
+	// GatewayAddr is used to check the permissions of provisioning requests.
+	GatewayAddr string `mapstructure:"gateway_addr"`
+}
+
+type eventconfig struct {
+	Endpoint             string `mapstructure:"nats_address" docs:"address of the nats server"`
+	Cluster              string `mapstructure:"nats_clusterid" docs:"clusterid of the nats server"`
+	TLSInsecure          bool   `mapstructure:"tls_insecure"  docs:"Whether to verify the server TLS certificates."`
+	TLSRootCACertificate string `mapstructure:"tls_root_ca_cert"  docs:"The root CA certificate used to validate the server's TLS certificate."`
+	EnableTLS            bool   `mapstructure:"nats_enable_tls" docs:"events tls switch"`
+	AuthUsername         string `mapstructure:"nats_username" docs:"event stream username"`
+	AuthPassword         string `mapstructure:"nats_password" docs:"event stream password"`
 }
 
 func (c *config) init() {
 	if c.Driver == "" {
 		c.Driver = "json"
 	}
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -80,13 +102,25 @@ func New(m map[string]interface{}, ss *grpc.Server, _ *zerolog.Logger) (rgrpc.Se
 		return nil, err
 	}
 
-	svc := &service{groupmgr: groupManager}
+	evstream, err := estreamFromConfig(c.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	gatewaySelector, err := pool.GatewaySelector(c.GatewayAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &service{groupmgr: groupManager, stream: evstream, gatewaySelector: gatewaySelector}
 
 	return svc, nil
 }
 
 type service struct {
-	groupmgr group.Manager
+	groupmgr        group.Manager
+	stream          events.Stream
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
 }
 
 func (s *service) Close() error {
@@ -99,6 +133,7 @@ func (s *service) UnprotectedEndpoints() []string {
 
 func (s *service) Register(ss *grpc.Server) {
 	grouppb.RegisterGroupAPIServer(ss, s)
+	adminpb.RegisterGroupAPIServer(ss, s)
 }
 
 func (s *service) GetGroup(ctx context.Context, req *grouppb.GetGroupRequest) (*grouppb.GetGroupResponse, error) {
@@ -201,3 +236,11 @@ func (s *service) HasMember(ctx context.Context, req *grouppb.HasMemberRequest)
 		Ok:     ok,
 	}, nil
 }
+
+func estreamFromConfig(c eventconfig) (events.Stream, error) {
+	if c.Endpoint == "" {
+		return nil, nil
+	}
+
+	return stream.NatsFromConfig("groupprovider", false, stream.NatsConfig(c))
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/internal/grpc/services/helloworld/proto"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -32,6 +33,7 @@ import (
 
 func init() {
 	rgrpc.Register("helloworld", New)
+	cfg.Register("grpc.services", "helloworld", conf{})
 }
 
 type conf struct {
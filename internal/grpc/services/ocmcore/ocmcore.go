@@ -39,6 +39,7 @@ import (
 
 func init() {
 	rgrpc.Register("ocmcore", New)
+	cfg.Register("grpc.services", "ocmcore", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "ocm.share.repository"})
 }
 
 type config struct {
@@ -46,6 +46,7 @@ import (
 
 func init() {
 	rgrpc.Register("ocminvitemanager", New)
+	cfg.Register("grpc.services", "ocminvitemanager", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "ocm.invite.repository"})
 }
 
 type config struct {
@@ -28,6 +28,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/ocm/provider/authorizer/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -35,6 +36,7 @@ import (
 
 func init() {
 	rgrpc.Register("ocmproviderauthorizer", New)
+	cfg.Register("grpc.services", "ocmproviderauthorizer", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "ocm.provider.authorizer"})
 }
 
 type config struct {
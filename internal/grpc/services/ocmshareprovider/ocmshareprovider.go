@@ -55,6 +55,7 @@ import (
 
 func init() {
 	rgrpc.Register("ocmshareprovider", New)
+	cfg.Register("grpc.services", "ocmshareprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "ocm.share.repository"})
 }
 
 type config struct {
@@ -28,6 +28,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/permission"
 	"github.com/opencloud-eu/reva/v2/pkg/permission/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -35,6 +36,7 @@ import (
 
 func init() {
 	rgrpc.Register("permissions", New)
+	cfg.Register("grpc.services", "permissions", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "permission.manager"})
 }
 
 type config struct {
@@ -30,12 +30,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/preferences/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	rgrpc.Register("preferences", New)
+	cfg.Register("grpc.services", "preferences", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "preferences"})
 }
 
 type config struct {
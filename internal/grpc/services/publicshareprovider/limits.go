@@ -0,0 +1,115 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshareprovider
+
+import (
+	"context"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// limitsFromOpaque returns the usage limits requested in the opaque, nil if none were given.
+// Requesting limits fails if the share manager does not support them.
+func (s *service) limitsFromOpaque(o *typesv1beta1.Opaque) (*publicshare.Limits, error) {
+	if !utils.ExistsInOpaque(o, publicshare.LimitsOpaqueKey) {
+		return nil, nil
+	}
+	l := &publicshare.Limits{}
+	if err := utils.ReadJSONFromOpaque(o, publicshare.LimitsOpaqueKey, l); err != nil {
+		return nil, errtypes.BadRequest("invalid public share limits")
+	}
+	if _, ok := s.sm.(publicshare.LimitedManager); !ok && l.Limited() {
+		return nil, errtypes.NotSupported("the public share manager does not support usage limits")
+	}
+	return l, nil
+}
+
+// setLimits sets the limits of the share if the manager supports them
+func (s *service) setLimits(ctx context.Context, ps *link.PublicShare, l *publicshare.Limits) error {
+	lm, ok := s.sm.(publicshare.LimitedManager)
+	if !ok || l == nil {
+		return nil
+	}
+	u, _ := ctxpkg.ContextGetUser(ctx)
+	return lm.SetLimits(ctx, u, &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: ps.GetId()}}, *l)
+}
+
+// recordAccess records the access requested in the opaque of a GetPublicShare request.
+// Only clients authenticated for the share itself can record accesses.
+func (s *service) recordAccess(ctx context.Context, ps *link.PublicShare, o *typesv1beta1.Opaque) (*publicshare.Usage, error) {
+	lm, ok := s.sm.(publicshare.LimitedManager)
+	if !ok || !utils.ExistsInOpaque(o, publicshare.AccessOpaqueKey) {
+		return nil, nil
+	}
+	scopes, _ := ctxpkg.ContextGetScopes(ctx)
+	if _, ok := scopes["publicshare:"+ps.GetId().GetOpaqueId()]; !ok {
+		return nil, errtypes.PermissionDenied("accesses can only be recorded with the public share scope")
+	}
+	a := publicshare.Access{}
+	if err := utils.ReadJSONFromOpaque(o, publicshare.AccessOpaqueKey, &a); err != nil {
+		return nil, errtypes.BadRequest("invalid public share access")
+	}
+	// the managers only store hashes of the ids handed out to the visitors
+	a.Visitor = publicshare.HashVisitor(a.Visitor)
+	return lm.RecordAccess(ctx, &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: ps.GetId()}}, a)
+}
+
+// appendUsage adds the usage of the share to the opaque, if it has limits
+func (s *service) appendUsage(ctx context.Context, o *typesv1beta1.Opaque, ps *link.PublicShare) *typesv1beta1.Opaque {
+	lm, ok := s.sm.(publicshare.LimitedManager)
+	if !ok || ps == nil {
+		return o
+	}
+	usages, err := lm.GetUsage(ctx, []string{ps.GetId().GetOpaqueId()})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("share", ps.GetId().GetOpaqueId()).Msg("could not get public share usage")
+		return o
+	}
+	if u, ok := usages[ps.GetId().GetOpaqueId()]; ok {
+		return utils.AppendJSONToOpaque(o, publicshare.UsageOpaqueKey, u)
+	}
+	return o
+}
+
+// appendUsages adds the usages of the shares with limits to the opaque
+func (s *service) appendUsages(ctx context.Context, o *typesv1beta1.Opaque, shares []*link.PublicShare) (*typesv1beta1.Opaque, error) {
+	lm, ok := s.sm.(publicshare.LimitedManager)
+	if !ok || len(shares) == 0 {
+		return o, nil
+	}
+	ids := make([]string, 0, len(shares))
+	for _, ps := range shares {
+		ids = append(ids, ps.GetId().GetOpaqueId())
+	}
+	usages, err := lm.GetUsage(ctx, ids)
+	if err != nil {
+		return o, errors.Wrap(err, "could not get public share usages")
+	}
+	if len(usages) == 0 {
+		return o, nil
+	}
+	return utils.AppendJSONToOpaque(o, publicshare.UsageOpaqueKey, usages), nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshareprovider
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+)
+
+// validatePassword checks a link password against the policy of the space the
+// shared resource lives in and, if subject is set, against the password history.
+// Violations are returned as the inner error of an invalid argument status.
+func (s *service) validatePassword(ctx context.Context, info *provider.ResourceInfo, subject, pw string) *rpc.Status {
+	err := s.passwordPolicies.For(info.GetSpace().GetSpaceType()).Validate(pw)
+	if err == nil && subject != "" {
+		err = s.passwordHistory.Check(subject, pw)
+	}
+	if err == nil {
+		return nil
+	}
+	violations := password.ViolationsOpaqueEntry(err)
+	if violations == nil {
+		return status.NewInternal(ctx, "error validating password: "+err.Error())
+	}
+	st := status.NewInvalidArg(ctx, err.Error())
+	st.InnerError = violations
+	return st
+}
+
+// rememberPassword adds the password of a link to its history
+func (s *service) rememberPassword(ctx context.Context, ps *link.PublicShare, pw string) {
+	if pw == "" {
+		return
+	}
+	if err := s.passwordHistory.Remember(password.LinkSubject(ps.GetId().GetOpaqueId()), pw); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("share", ps.GetId().GetOpaqueId()).Msg("could not remember password of public share")
+	}
+}
@@ -49,12 +49,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 const getUserCtxErrMsg = "error getting user from context"
 
 func init() {
 	rgrpc.Register("publicshareprovider", NewDefault)
+	cfg.Register("grpc.services", "publicshareprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "publicshare.manager"})
 }
 
 type config struct {
@@ -69,12 +71,7 @@ type config struct {
 }
 
 type passwordPolicy struct {
-	MinCharacters          int                 `mapstructure:"min_characters"`
-	MinLowerCaseCharacters int                 `mapstructure:"min_lowercase_characters"`
-	MinUpperCaseCharacters int                 `mapstructure:"min_uppercase_characters"`
-	MinDigits              int                 `mapstructure:"min_digits"`
-	MinSpecialCharacters   int                 `mapstructure:"min_special_characters"`
-	BannedPasswordsList    map[string]struct{} `mapstructure:"banned_passwords_list"`
+	password.PolicyConfig `mapstructure:",squash"`
 }
 
 func (c *config) init() {
@@ -88,7 +85,8 @@ type service struct {
 	sm                    publicshare.Manager
 	gatewaySelector       pool.Selectable[gateway.GatewayAPIClient]
 	allowedPathsForShares []*regexp.Regexp
-	passwordValidator     password.Validator
+	passwordPolicies      *password.SpacePolicies
+	passwordHistory       *password.History
 }
 
 func getShareManager(c *config) (publicshare.Manager, error) {
@@ -169,24 +167,24 @@ func New(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], sm publicsha
 		sm:                    sm,
 		gatewaySelector:       gatewaySelector,
 		allowedPathsForShares: allowedPathsForShares,
-		passwordValidator:     newPasswordPolicy(p),
+		passwordPolicies:      newPasswordPolicies(p),
+	}
+	if p != nil {
+		history, err := p.History.History()
+		if err != nil {
+			return nil, err
+		}
+		service.passwordHistory = history
 	}
 
 	return service, nil
 }
 
-func newPasswordPolicy(c *passwordPolicy) password.Validator {
+func newPasswordPolicies(c *passwordPolicy) *password.SpacePolicies {
 	if c == nil {
-		return password.NewPasswordPolicy(0, 0, 0, 0, 0, nil)
-	}
-	return password.NewPasswordPolicy(
-		c.MinCharacters,
-		c.MinLowerCaseCharacters,
-		c.MinUpperCaseCharacters,
-		c.MinDigits,
-		c.MinSpecialCharacters,
-		c.BannedPasswordsList,
-	)
+		return password.PolicyConfig{}.Policies()
+	}
+	return c.Policies()
 }
 
 func (s *service) isPathAllowed(path string) bool {
@@ -316,24 +314,41 @@ func (s *service) CreatePublicShare(ctx context.Context, req *link.CreatePublicS
 
 	// validate password policy
 	if len(setPassword) > 0 {
-		if err := s.passwordValidator.Validate(setPassword); err != nil {
+		if st := s.validatePassword(ctx, sRes.GetInfo(), "", setPassword); st != nil {
 			return &link.CreatePublicShareResponse{
-				Status: status.NewInvalidArg(ctx, err.Error()),
+				Status: st,
 			}, nil
 		}
 	}
 
+	limits, err := s.limitsFromOpaque(req.GetOpaque())
+	if err != nil {
+		return &link.CreatePublicShareResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
 	user := ctxpkg.ContextMustGetUser(ctx)
 	res := &link.CreatePublicShareResponse{}
 	share, err := s.sm.CreatePublicShare(ctx, user, req.GetResourceInfo(), req.GetGrant())
+	if err == nil {
+		if err = s.setLimits(ctx, share, limits); err != nil {
+			// do not leave a link behind that lacks the requested limits
+			if rerr := s.sm.RevokePublicShare(ctx, user, &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: share.GetId()}}); rerr != nil {
+				log.Error().Err(rerr).Str("share", share.GetId().GetOpaqueId()).Msg("could not revoke public share without limits")
+			}
+		}
+	}
 	switch {
 	case err != nil:
 		log.Error().Err(err).Interface("request", req).Msg("could not write public share")
 		res.Status = status.NewInternal(ctx, "error persisting public share:"+err.Error())
 	default:
+		s.rememberPassword(ctx, share, setPassword)
 		res.Status = status.NewOK(ctx)
 		res.Share = share
 		res.Opaque = utils.AppendPlainToOpaque(nil, "resourcename", sRes.GetInfo().GetName())
+		res.Opaque = s.appendUsage(ctx, res.Opaque, share)
 	}
 
 	return res, nil
@@ -376,6 +391,9 @@ func (s *service) RemovePublicShare(ctx context.Context, req *link.RemovePublicS
 			Status: status.NewInternal(ctx, "error deleting public share"),
 		}, err
 	}
+	if err := s.passwordHistory.Forget(password.LinkSubject(ps.GetId().GetOpaqueId())); err != nil {
+		log.Error().Err(err).Str("share", ps.GetId().GetOpaqueId()).Msg("could not remove password history of public share")
+	}
 	o := utils.AppendJSONToOpaque(nil, "resourceid", ps.GetResourceId())
 	o = utils.AppendPlainToOpaque(o, "resourcename", sRes.GetInfo().GetName())
 	return &link.RemovePublicShareResponse{
@@ -437,12 +455,36 @@ func (s *service) GetPublicShare(ctx context.Context, req *link.GetPublicShareRe
 		return &link.GetPublicShareResponse{
 			Status: status.NewNotFound(ctx, "not found"),
 		}, nil
-	default:
+	}
+
+	usage, err := s.recordAccess(ctx, ps, req.GetOpaque())
+	if err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsPermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, err.Error())
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, err.Error())
+		case errtypes.IsBadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		default:
+			st = status.NewInternal(ctx, err.Error())
+		}
 		return &link.GetPublicShareResponse{
-			Status: status.NewOK(ctx),
-			Share:  ps,
+			Status: st,
 		}, nil
 	}
+
+	res := &link.GetPublicShareResponse{
+		Status: status.NewOK(ctx),
+		Share:  ps,
+	}
+	if usage != nil {
+		res.Opaque = utils.AppendJSONToOpaque(nil, publicshare.UsageOpaqueKey, usage)
+	} else {
+		res.Opaque = s.appendUsage(ctx, nil, ps)
+	}
+	return res, nil
 }
 
 func (s *service) ListPublicShares(ctx context.Context, req *link.ListPublicSharesRequest) (*link.ListPublicSharesResponse, error) {
@@ -458,9 +500,18 @@ func (s *service) ListPublicShares(ctx context.Context, req *link.ListPublicShar
 		}, nil
 	}
 
+	o, err := s.appendUsages(ctx, nil, shares)
+	if err != nil {
+		log.Err(err).Msg("error listing public share usages")
+		return &link.ListPublicSharesResponse{
+			Status: status.NewInternal(ctx, "error listing public shares"),
+		}, nil
+	}
+
 	res := &link.ListPublicSharesResponse{
 		Status: status.NewOK(ctx),
 		Share:  shares,
+		Opaque: o,
 	}
 	return res, nil
 }
@@ -590,25 +641,46 @@ func (s *service) UpdatePublicShare(ctx context.Context, req *link.UpdatePublicS
 
 	// validate password policy
 	if updatePassword && len(setPassword) > 0 {
-		if err := s.passwordValidator.Validate(setPassword); err != nil {
+		if st := s.validatePassword(ctx, sRes.GetInfo(), password.LinkSubject(ps.GetId().GetOpaqueId()), setPassword); st != nil {
 			return &link.UpdatePublicShareResponse{
-				Status: status.NewInvalidArg(ctx, err.Error()),
+				Status: st,
 			}, nil
 		}
 	}
 
-	updateR, err := s.sm.UpdatePublicShare(ctx, user, req)
+	limits, err := s.limitsFromOpaque(req.GetOpaque())
 	if err != nil {
+		return &link.UpdatePublicShareResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
+	updateR := ps
+	// requests may only carry new limits
+	if req.GetUpdate().GetType() != link.UpdatePublicShareRequest_Update_TYPE_INVALID || limits == nil {
+		updateR, err = s.sm.UpdatePublicShare(ctx, user, req)
+		if err != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: status.NewInternal(ctx, err.Error()),
+			}, nil
+		}
+	}
+
+	if err := s.setLimits(ctx, updateR, limits); err != nil {
 		return &link.UpdatePublicShareResponse{
 			Status: status.NewInternal(ctx, err.Error()),
 		}, nil
 	}
+	if updatePassword {
+		s.rememberPassword(ctx, updateR, setPassword)
+	}
 
 	res := &link.UpdatePublicShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  updateR,
 		Opaque: utils.AppendPlainToOpaque(nil, "resourcename", sRes.GetInfo().GetName()),
 	}
+	res.Opaque = s.appendUsage(ctx, res.Opaque, updateR)
 	return res, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
@@ -15,6 +16,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/grpc/services/publicshareprovider"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
 	"github.com/opencloud-eu/reva/v2/pkg/permission"
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/registry"
@@ -44,6 +46,37 @@ func createPublicShareProvider(revaConfig map[string]interface{}, gs pool.Select
 	return publicshareproviderService.(link.LinkAPIServer), nil
 }
 
+// limitedManager adds usage limits to the mocked share manager
+type limitedManager struct {
+	*mocks.Manager
+	limits    map[string]publicshare.Limits
+	accesses  []publicshare.Access
+	recordErr error
+}
+
+func (m *limitedManager) SetLimits(_ context.Context, _ *userpb.User, ref *link.PublicShareReference, l publicshare.Limits) error {
+	m.limits[ref.GetId().GetOpaqueId()] = l
+	return nil
+}
+
+func (m *limitedManager) GetUsage(_ context.Context, ids []string) (map[string]*publicshare.Usage, error) {
+	usages := map[string]*publicshare.Usage{}
+	for _, id := range ids {
+		if l, ok := m.limits[id]; ok && l.Limited() {
+			usages[id] = &publicshare.Usage{Limits: l}
+		}
+	}
+	return usages, nil
+}
+
+func (m *limitedManager) RecordAccess(_ context.Context, ref *link.PublicShareReference, a publicshare.Access) (*publicshare.Usage, error) {
+	if m.recordErr != nil {
+		return nil, m.recordErr
+	}
+	m.accesses = append(m.accesses, a)
+	return &publicshare.Usage{Limits: m.limits[ref.GetId().GetOpaqueId()], Downloads: 1}, nil
+}
+
 var _ = Describe("PublicShareProvider", func() {
 	// declare in container nodes
 	var (
@@ -1676,4 +1709,236 @@ var _ = Describe("PublicShareProvider", func() {
 			})
 		})
 	})
+
+	Describe("Limiting the usage of a PublicShare", func() {
+		var (
+			limited       *limitedManager
+			shareRef      *link.PublicShareReference
+			shareScopeCtx context.Context
+		)
+		BeforeEach(func() {
+			limited = &limitedManager{Manager: manager, limits: map[string]publicshare.Limits{}}
+			var err error
+			provider, err = createPublicShareProvider(revaConfig, gatewaySelector, limited)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdLink = &link.PublicShare{
+				Id:    &link.PublicShareId{OpaqueId: "share"},
+				Token: "token",
+				Permissions: &link.PublicSharePermissions{
+					Permissions: linkPermissions,
+				},
+				Creator: user.Id,
+			}
+			shareRef = &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: "token"}}
+			shareScopeCtx = ctxpkg.ContextSetScopes(ctx, map[string]*authpb.Scope{"publicshare:share": {}})
+		})
+
+		It("rejects limits when the manager does not support them", func() {
+			provider, _ = createPublicShareProvider(revaConfig, gatewaySelector, manager)
+			gatewayClient.EXPECT().CheckPermission(mock.Anything, mock.Anything).Return(checkPermissionResponse, nil)
+			gatewayClient.EXPECT().Stat(mock.Anything, mock.Anything).Return(statResourceResponse, nil)
+
+			res, err := provider.CreatePublicShare(ctx, &link.CreatePublicShareRequest{
+				Opaque:       utils.AppendJSONToOpaque(nil, publicshare.LimitsOpaqueKey, publicshare.Limits{OneTime: true}),
+				ResourceInfo: &providerpb.ResourceInfo{Path: "./NewFolder/file.txt"},
+				Grant: &link.Grant{
+					Permissions: &link.PublicSharePermissions{Permissions: linkPermissions},
+					Password:    "SecretPassw0rd!",
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_INVALID_ARGUMENT))
+		})
+
+		It("sets the limits when creating a share", func() {
+			gatewayClient.EXPECT().CheckPermission(mock.Anything, mock.Anything).Return(checkPermissionResponse, nil)
+			gatewayClient.EXPECT().Stat(mock.Anything, mock.Anything).Return(statResourceResponse, nil)
+			manager.EXPECT().CreatePublicShare(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(createdLink, nil)
+
+			res, err := provider.CreatePublicShare(ctx, &link.CreatePublicShareRequest{
+				Opaque:       utils.AppendJSONToOpaque(nil, publicshare.LimitsOpaqueKey, publicshare.Limits{MaxDownloads: 3}),
+				ResourceInfo: &providerpb.ResourceInfo{Path: "./NewFolder/file.txt"},
+				Grant: &link.Grant{
+					Permissions: &link.PublicSharePermissions{Permissions: linkPermissions},
+					Password:    "SecretPassw0rd!",
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+			Expect(limited.limits).To(HaveKeyWithValue("share", publicshare.Limits{MaxDownloads: 3}))
+
+			u := publicshare.Usage{}
+			Expect(utils.ReadJSONFromOpaque(res.GetOpaque(), publicshare.UsageOpaqueKey, &u)).To(Succeed())
+			Expect(u.MaxDownloads).To(Equal(uint64(3)))
+		})
+
+		It("updates only the limits", func() {
+			manager.EXPECT().GetPublicShare(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(createdLink, nil)
+			gatewayClient.EXPECT().Stat(mock.Anything, mock.Anything).Return(statResourceResponse, nil)
+			gatewayClient.EXPECT().CheckPermission(mock.Anything, mock.Anything).Return(checkPermissionResponse, nil)
+
+			res, err := provider.UpdatePublicShare(ctx, &link.UpdatePublicShareRequest{
+				Opaque: utils.AppendJSONToOpaque(nil, publicshare.LimitsOpaqueKey, publicshare.Limits{MaxVisitors: 2}),
+				Ref:    &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: createdLink.GetId()}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+			Expect(res.GetShare()).To(Equal(createdLink))
+			Expect(limited.limits).To(HaveKeyWithValue("share", publicshare.Limits{MaxVisitors: 2}))
+			manager.AssertNotCalled(GinkgoT(), "UpdatePublicShare", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("records accesses with hashed visitors", func() {
+			manager.EXPECT().GetPublicShare(mock.Anything, mock.Anything, shareRef, false).Return(createdLink, nil)
+
+			res, err := provider.GetPublicShare(shareScopeCtx, &link.GetPublicShareRequest{
+				Opaque: utils.AppendJSONToOpaque(nil, publicshare.AccessOpaqueKey, publicshare.Access{Visitor: "visitor"}),
+				Ref:    shareRef,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+			Expect(limited.accesses).To(ConsistOf(publicshare.Access{Visitor: publicshare.HashVisitor("visitor")}))
+			Expect(utils.ExistsInOpaque(res.GetOpaque(), publicshare.UsageOpaqueKey)).To(BeTrue())
+		})
+
+		It("denies access when the limits are reached", func() {
+			limited.recordErr = errtypes.PermissionDenied("public share download limit reached")
+			manager.EXPECT().GetPublicShare(mock.Anything, mock.Anything, shareRef, false).Return(createdLink, nil)
+
+			res, err := provider.GetPublicShare(shareScopeCtx, &link.GetPublicShareRequest{
+				Opaque: utils.AppendJSONToOpaque(nil, publicshare.AccessOpaqueKey, publicshare.Access{Download: true}),
+				Ref:    shareRef,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_PERMISSION_DENIED))
+			Expect(res.GetShare()).To(BeNil())
+		})
+
+		It("only records accesses of clients authenticated for the share", func() {
+			manager.EXPECT().GetPublicShare(mock.Anything, mock.Anything, shareRef, false).Return(createdLink, nil)
+
+			res, err := provider.GetPublicShare(ctx, &link.GetPublicShareRequest{
+				Opaque: utils.AppendJSONToOpaque(nil, publicshare.AccessOpaqueKey, publicshare.Access{Download: true}),
+				Ref:    shareRef,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_PERMISSION_DENIED))
+			Expect(limited.accesses).To(BeEmpty())
+		})
+
+		It("lists the usages of shares with limits", func() {
+			limited.limits["share"] = publicshare.Limits{OneTime: true}
+			manager.EXPECT().ListPublicShares(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return([]*link.PublicShare{createdLink, {Id: &link.PublicShareId{OpaqueId: "other"}}}, nil)
+
+			res, err := provider.ListPublicShares(ctx, &link.ListPublicSharesRequest{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+
+			usages := map[string]*publicshare.Usage{}
+			Expect(utils.ReadJSONFromOpaque(res.GetOpaque(), publicshare.UsageOpaqueKey, &usages)).To(Succeed())
+			Expect(usages).To(HaveLen(1))
+			Expect(usages["share"].OneTime).To(BeTrue())
+		})
+	})
+
+	Describe("Enforcing password policies", func() {
+		var passwordLink *link.PublicShare
+
+		create := func(pw string) *link.CreatePublicShareResponse {
+			res, err := provider.CreatePublicShare(ctx, &link.CreatePublicShareRequest{
+				ResourceInfo: &providerpb.ResourceInfo{Path: "./NewFolder/file.txt"},
+				Grant: &link.Grant{
+					Permissions: &link.PublicSharePermissions{Permissions: linkPermissions},
+					Password:    pw,
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return res
+		}
+		updatePassword := func(pw string) *link.UpdatePublicShareResponse {
+			res, err := provider.UpdatePublicShare(ctx, &link.UpdatePublicShareRequest{
+				Ref: &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: passwordLink.GetId()}},
+				Update: &link.UpdatePublicShareRequest_Update{
+					Type:  link.UpdatePublicShareRequest_Update_TYPE_PASSWORD,
+					Grant: &link.Grant{Password: pw},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return res
+		}
+		codes := func(st *rpc.Status) []string {
+			var c []string
+			for _, v := range password.ViolationsFromStatus(st) {
+				c = append(c, v.Code)
+			}
+			return c
+		}
+
+		BeforeEach(func() {
+			gatewayClient.EXPECT().CheckPermission(mock.Anything, mock.Anything).Return(checkPermissionResponse, nil)
+			gatewayClient.EXPECT().Stat(mock.Anything, mock.Anything).Return(statResourceResponse, nil)
+
+			passwordLink = &link.PublicShare{
+				Id:                &link.PublicShareId{OpaqueId: "share"},
+				PasswordProtected: true,
+				Permissions: &link.PublicSharePermissions{
+					Permissions: linkPermissions,
+				},
+				Creator: user.Id,
+			}
+		})
+
+		It("returns the violations in the status", func() {
+			res := create("Test")
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_INVALID_ARGUMENT))
+			Expect(codes(res.GetStatus())).To(ConsistOf(
+				password.ViolationMinCharacters,
+				password.ViolationMinDigits,
+				password.ViolationMinSpecialCharacters,
+			))
+		})
+
+		It("applies the policy of the space type", func() {
+			revaConfig["password_policy"].(map[string]interface{})["space_types"] = map[string]interface{}{
+				"project": map[string]interface{}{
+					"min_characters": 20,
+					"min_score":      3,
+				},
+			}
+			var err error
+			provider, err = createPublicShareProvider(revaConfig, gatewaySelector, manager)
+			Expect(err).ToNot(HaveOccurred())
+			statResourceResponse.Info.Space = &providerpb.StorageSpace{SpaceType: "project"}
+
+			res := create("SecretPassw0rd!")
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_INVALID_ARGUMENT))
+			Expect(codes(res.GetStatus())).To(ConsistOf(password.ViolationMinCharacters, password.ViolationTooWeak))
+		})
+
+		It("rejects reusing a previous password", func() {
+			revaConfig["password_policy"].(map[string]interface{})["history"] = map[string]interface{}{
+				"depth": 2,
+				"store": "memory",
+			}
+			var err error
+			provider, err = createPublicShareProvider(revaConfig, gatewaySelector, manager)
+			Expect(err).ToNot(HaveOccurred())
+			manager.EXPECT().CreatePublicShare(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(passwordLink, nil)
+			manager.EXPECT().GetPublicShare(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(passwordLink, nil)
+			manager.EXPECT().UpdatePublicShare(mock.Anything, mock.Anything, mock.Anything).Return(passwordLink, nil)
+
+			Expect(create("SecretPassw0rd!").GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+
+			res := updatePassword("SecretPassw0rd!")
+			Expect(res.GetStatus().GetCode()).To(Equal(rpc.Code_CODE_INVALID_ARGUMENT))
+			Expect(codes(res.GetStatus())).To(ConsistOf(password.ViolationReused))
+
+			Expect(updatePassword("OtherPassw0rd!").GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+			Expect(updatePassword("ThirdPassw0rd!").GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+			// only the last two passwords are remembered
+			Expect(updatePassword("SecretPassw0rd!").GetStatus().GetCode()).To(Equal(rpc.Code_CODE_OK))
+		})
+	})
 })
@@ -37,11 +37,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
@@ -55,6 +57,7 @@ const tracerName = "publicstorageprovider"
 
 func init() {
 	rgrpc.Register("publicstorageprovider", New)
+	cfg.Register("grpc.services", "publicstorageprovider", config{})
 }
 
 type config struct {
@@ -246,7 +249,7 @@ func (s *service) translatePublicRefToCS3Ref(ctx context.Context, ref *provider.
 }
 
 func (s *service) initiateFileDownload(ctx context.Context, req *provider.InitiateFileDownloadRequest) (*provider.InitiateFileDownloadResponse, error) {
-	ref, info, _, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	ref, info, token, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
 	switch {
 	case err != nil:
 		return &provider.InitiateFileDownloadResponse{
@@ -257,6 +260,11 @@ func (s *service) initiateFileDownload(ctx context.Context, req *provider.Initia
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant InitiateFileDownload permission"),
 		}, nil
 	}
+	if err := s.recordDownload(ctx, token, utils.ReadPlainFromOpaque(req.GetOpaque(), publicshare.VisitorOpaqueKey)); err != nil {
+		return &provider.InitiateFileDownloadResponse{
+			Status: status.NewStatusFromErrType(ctx, "failed to record download", err),
+		}, nil
+	}
 	dReq := &provider.InitiateFileDownloadRequest{
 		Ref: ref,
 	}
@@ -540,6 +548,42 @@ func (s *service) extractLinkFromScope(ctx context.Context) (*provider.ResourceI
 	}
 	return nil, nil, nil, "", errtypes.NotFound("No public storage info found in scopes")
 }
+
+// recordDownload counts a download against the limits of the public share in the scope.
+func (s *service) recordDownload(ctx context.Context, token, visitor string) error {
+	scopes, _ := ctxpkg.ContextGetScopes(ctx)
+	isPublicShare := false
+	for k := range scopes {
+		if strings.HasPrefix(k, "publicshare:") {
+			isPublicShare = true
+			break
+		}
+	}
+	if !isPublicShare {
+		// ocm shares have no usage limits
+		return nil
+	}
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+	res, err := gatewayClient.GetPublicShare(ctx, &link.GetPublicShareRequest{
+		Opaque: utils.AppendJSONToOpaque(nil, publicshare.AccessOpaqueKey, publicshare.Access{Download: true, Visitor: visitor}),
+		Ref: &link.PublicShareReference{
+			Spec: &link.PublicShareReference_Token{
+				Token: token,
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return err
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(res.GetStatus())
+	}
+	return nil
+}
+
 func (s *service) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
 	return nil, gstatus.Errorf(codes.Unimplemented, "method not implemented")
 }
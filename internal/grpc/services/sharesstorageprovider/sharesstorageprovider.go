@@ -48,6 +48,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
@@ -57,6 +58,7 @@ const (
 
 func init() {
 	rgrpc.Register("sharesstorageprovider", NewDefault)
+	cfg.Register("grpc.services", "sharesstorageprovider", config{})
 }
 
 type config struct {
@@ -35,6 +35,7 @@ import (
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 	"github.com/opencloud-eu/reva/v2/pkg/conversions"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
@@ -48,6 +49,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
@@ -59,6 +61,7 @@ const tracerName = "storageprovider"
 
 func init() {
 	rgrpc.Register("storageprovider", New)
+	cfg.Register("grpc.services", "storageprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "storage.fs"})
 }
 
 type config struct {
@@ -71,6 +74,7 @@ type config struct {
 	MountID             string                            `mapstructure:"mount_id"`
 	UploadExpiration    int64                             `mapstructure:"upload_expiration" docs:"0;Duration for how long uploads will be valid."`
 	Events              eventconfig                       `mapstructure:"events" docs:"0;Event stream configuration"`
+	TrashRetention      trashRetentionConfig              `mapstructure:"trash_retention" docs:"0;Retention policies for the trash bins of the spaces"`
 }
 
 type eventconfig struct {
@@ -101,6 +105,8 @@ func (c *config) init() {
 	if len(c.AvailableXS) == 0 {
 		c.AvailableXS = map[string]uint32{"md5": 100, "unset": 1000}
 	}
+
+	c.TrashRetention.init()
 }
 
 type Service struct {
@@ -108,9 +114,13 @@ type Service struct {
 	Storage       storage.FS
 	dataServerURL *url.URL
 	availableXS   []*provider.ResourceChecksumPriority
+	trashPurger   *trashPurger
 }
 
 func (s *Service) Close() error {
+	if s.trashPurger != nil {
+		s.trashPurger.Stop()
+	}
 	return s.Storage.Shutdown(context.Background())
 }
 
@@ -175,7 +185,12 @@ func New(m map[string]interface{}, ss *grpc.Server, log *zerolog.Logger) (rgrpc.
 
 	c.init()
 
-	fs, err := getFS(c, log)
+	evstream, err := estreamFromConfig(c.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := getFS(c, evstream, log)
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +224,11 @@ func New(m map[string]interface{}, ss *grpc.Server, log *zerolog.Logger) (rgrpc.
 		availableXS:   xsTypes,
 	}
 
+	if c.TrashRetention.Interval > 0 {
+		service.trashPurger = newTrashPurger(c.TrashRetention, fs, evstream, c.MountID, log)
+		service.trashPurger.Start()
+	}
+
 	return service, nil
 }
 
@@ -402,6 +422,13 @@ func (s *Service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 		if req.Opaque.Map["X-OC-Mtime"] != nil {
 			metadata["mtime"] = string(req.Opaque.Map["X-OC-Mtime"].Value)
 		}
+		// the label of secure file drop uploads is announced when the upload has finished,
+		// it is only accepted from the uploaders of a public link
+		if req.Opaque.Map[events.FileDropLabelOpaqueKey] != nil {
+			if scopes, ok := ctxpkg.ContextGetScopes(ctx); ok && scope.IsPublicShareUploader(scopes) {
+				metadata[events.FileDropLabelOpaqueKey] = string(req.Opaque.Map[events.FileDropLabelOpaqueKey].Value)
+			}
+		}
 	}
 
 	// pass on the provider it to be persisted with the upload info. that is required to correlate the upload with the proper provider later on
@@ -1276,12 +1303,7 @@ func (s *Service) addMissingStorageProviderID(resourceID *provider.ResourceId, s
 	}
 }
 
-func getFS(c *config, log *zerolog.Logger) (storage.FS, error) {
-	evstream, err := estreamFromConfig(c.Events)
-	if err != nil {
-		return nil, err
-	}
-
+func getFS(c *config, evstream events.Stream, log *zerolog.Logger) (storage.FS, error) {
 	if f, ok := registry.NewFuncs[c.Driver]; ok {
 		driverConf := c.Drivers[c.Driver]
 		driverConf["mount_id"] = c.MountID // pass the mount id to the driver
@@ -0,0 +1,167 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/storage"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/rs/zerolog"
+)
+
+type trashRetentionConfig struct {
+	Interval         time.Duration                    `mapstructure:"interval" docs:"0;How often the trash bins are checked for expired items. 0 disables the purger."`
+	ServiceAccountID string                           `mapstructure:"service_account_id" docs:"trash-purger;The id of the service account the purger acts as when listing and purging trash bins."`
+	Policies         map[string]retention.TrashPolicy `mapstructure:"policies" docs:"nil;The retention policies per space type, e.g. personal or project."`
+}
+
+func (c *trashRetentionConfig) init() {
+	if c.ServiceAccountID == "" {
+		c.ServiceAccountID = "trash-purger"
+	}
+}
+
+// trashPurger periodically purges expired items from the trash bins of all spaces
+type trashPurger struct {
+	conf    trashRetentionConfig
+	fs      storage.FS
+	stream  events.Stream
+	mountID string
+	log     zerolog.Logger
+
+	cancel context.CancelFunc
+}
+
+func newTrashPurger(c trashRetentionConfig, fs storage.FS, stream events.Stream, mountID string, log *zerolog.Logger) *trashPurger {
+	return &trashPurger{
+		conf:    c,
+		fs:      fs,
+		stream:  stream,
+		mountID: mountID,
+		log:     log.With().Str("component", "trashpurger").Logger(),
+	}
+}
+
+// Start runs the purger in the background until Stop is called
+func (p *trashPurger) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	go func() {
+		t := time.NewTicker(p.conf.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				p.PurgeExpired(ctx, time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background purging
+func (p *trashPurger) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// PurgeExpired purges all trash items that violate the retention policy of their space type
+func (p *trashPurger) PurgeExpired(ctx context.Context, now time.Time) {
+	serviceUser := &userpb.User{
+		Id: &userpb.UserId{
+			OpaqueId: p.conf.ServiceAccountID,
+			Type:     userpb.UserType_USER_TYPE_SERVICE,
+		},
+	}
+	ctx = ctxpkg.ContextSetUser(ctx, serviceUser)
+	ctx = appctx.WithLogger(ctx, &p.log)
+
+	for spaceType, policy := range p.conf.Policies {
+		if !policy.Enabled() {
+			continue
+		}
+		spaces, err := p.fs.ListStorageSpaces(ctx, []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: spaceType},
+			},
+		}, true)
+		if err != nil {
+			p.log.Error().Err(err).Str("spacetype", spaceType).Msg("could not list spaces")
+			continue
+		}
+		for _, space := range spaces {
+			if ctx.Err() != nil {
+				return
+			}
+			p.purgeSpace(ctx, serviceUser, space, policy, now)
+		}
+	}
+}
+
+func (p *trashPurger) purgeSpace(ctx context.Context, serviceUser *userpb.User, space *provider.StorageSpace, policy retention.TrashPolicy, now time.Time) {
+	log := p.log.With().Str("spaceid", space.GetRoot().GetSpaceId()).Logger()
+	spaceRef := &provider.Reference{ResourceId: space.GetRoot()}
+
+	items, err := p.fs.ListRecycle(ctx, spaceRef, "", "")
+	if err != nil {
+		log.Error().Err(err).Msg("could not list trash bin")
+		return
+	}
+
+	for _, item := range policy.Expired(items, now) {
+		if err := p.fs.PurgeRecycleItem(ctx, spaceRef, item.GetKey(), ""); err != nil {
+			log.Error().Err(err).Str("key", item.GetKey()).Msg("could not purge expired trash item")
+			continue
+		}
+		log.Debug().Str("key", item.GetKey()).Uint64("size", item.GetSize()).Msg("purged expired trash item")
+
+		if p.stream == nil {
+			continue
+		}
+		ev := events.ItemPurged{
+			Executant: serviceUser.GetId(),
+			ID: &provider.ResourceId{
+				StorageId: p.mountID,
+				SpaceId:   space.GetRoot().GetSpaceId(),
+				OpaqueId:  item.GetKey(),
+			},
+			Ref: &provider.Reference{
+				ResourceId: space.GetRoot(),
+				Path:       utils.MakeRelativePath(item.GetRef().GetPath()),
+			},
+			Owner:     space.GetOwner().GetId(),
+			Timestamp: utils.TSNow(),
+		}
+		if err := events.Publish(ctx, p.stream, ev); err != nil {
+			log.Error().Err(err).Str("key", item.GetKey()).Msg("could not publish ItemPurged event")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/storage"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
+	"github.com/rs/zerolog"
+)
+
+// purgerFS is a storage.FS holding the trash bins of some spaces in memory
+type purgerFS struct {
+	storage.FS
+
+	spaces map[string][]*provider.StorageSpace
+	trash  map[string][]*provider.RecycleItem
+	purged []string
+}
+
+func (fs *purgerFS) ListStorageSpaces(_ context.Context, filter []*provider.ListStorageSpacesRequest_Filter, _ bool) ([]*provider.StorageSpace, error) {
+	return fs.spaces[filter[0].GetSpaceType()], nil
+}
+
+func (fs *purgerFS) ListRecycle(_ context.Context, ref *provider.Reference, _, _ string) ([]*provider.RecycleItem, error) {
+	return fs.trash[ref.GetResourceId().GetSpaceId()], nil
+}
+
+func (fs *purgerFS) PurgeRecycleItem(_ context.Context, ref *provider.Reference, key, _ string) error {
+	fs.purged = append(fs.purged, ref.GetResourceId().GetSpaceId()+"/"+key)
+	return nil
+}
+
+func trashItem(key string, size uint64, deleted time.Time) *provider.RecycleItem {
+	return &provider.RecycleItem{
+		Key:          key,
+		Size:         size,
+		DeletionTime: &types.Timestamp{Seconds: uint64(deleted.Unix())},
+	}
+}
+
+func TestTrashPurgerPurgeExpired(t *testing.T) {
+	now := time.Now()
+	fs := &purgerFS{
+		spaces: map[string][]*provider.StorageSpace{
+			"personal": {{Root: &provider.ResourceId{SpaceId: "personal-1"}}},
+			"project":  {{Root: &provider.ResourceId{SpaceId: "project-1"}}},
+		},
+		trash: map[string][]*provider.RecycleItem{
+			"personal-1": {
+				trashItem("old", 10, now.Add(-48*time.Hour)),
+				trashItem("new", 10, now.Add(-time.Hour)),
+			},
+			"project-1": {
+				trashItem("oldest", 60, now.Add(-3*time.Hour)),
+				trashItem("older", 60, now.Add(-2*time.Hour)),
+				trashItem("newest", 60, now.Add(-time.Hour)),
+			},
+		},
+	}
+
+	log := zerolog.Nop()
+	p := newTrashPurger(trashRetentionConfig{
+		Policies: map[string]retention.TrashPolicy{
+			"personal": {MaxAge: 24 * time.Hour},
+			"project":  {MaxSize: 100},
+			// disabled policies are skipped
+			"mountpoint": {},
+		},
+	}, fs, nil, "storage-1", &log)
+	p.PurgeExpired(context.Background(), now)
+
+	sort.Strings(fs.purged)
+	expected := []string{"personal-1/old", "project-1/older", "project-1/oldest"}
+	if !reflect.DeepEqual(fs.purged, expected) {
+		t.Errorf("purged %v, expected %v", fs.purged, expected)
+	}
+}
+
+func TestTrashPurgerStops(t *testing.T) {
+	log := zerolog.Nop()
+	fs := &purgerFS{}
+	p := newTrashPurger(trashRetentionConfig{
+		Policies: map[string]retention.TrashPolicy{"personal": {MaxAge: time.Hour}},
+	}, fs, nil, "storage-1", &log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// nothing is listed once the purger has been stopped
+	fs.spaces = map[string][]*provider.StorageSpace{"personal": {{Root: &provider.ResourceId{SpaceId: "personal-1"}}}}
+	fs.trash = map[string][]*provider.RecycleItem{"personal-1": {trashItem("old", 1, time.Now().Add(-2*time.Hour))}}
+	p.PurgeExpired(ctx, time.Now())
+	if len(fs.purged) != 0 {
+		t.Errorf("purged %v after the purger was cancelled", fs.purged)
+	}
+}
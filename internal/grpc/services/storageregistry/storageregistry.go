@@ -35,12 +35,14 @@ import (
 	sdk "github.com/opencloud-eu/reva/v2/pkg/sdk/common"
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/registry/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 )
 
 func init() {
 	rgrpc.Register("storageregistry", New)
+	cfg.Register("grpc.services", "storageregistry", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "storage.registry"})
 }
 
 type service struct {
@@ -0,0 +1,283 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package userprovider
+
+import (
+	"context"
+
+	adminpb "github.com/cs3org/go-cs3apis/cs3/admin/user/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/permission"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/user"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+// The CS3 admin API has no field for credentials, the password of new users is
+// passed as opaque value of the request.
+const opaquePassword = "password"
+
+// CreateUser creates a user account.
+func (s *service) CreateUser(ctx context.Context, req *adminpb.CreateUserRequest) (*adminpb.CreateUserResponse, error) {
+	if _, st := s.checkPermission(ctx, nil); st != nil {
+		return &adminpb.CreateUserResponse{Status: st}, nil
+	}
+	p, ok := s.usermgr.(user.Provisioner)
+	if !ok {
+		return &adminpb.CreateUserResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the user manager does not support provisioning"),
+		}, nil
+	}
+	if req.User == nil {
+		return &adminpb.CreateUserResponse{
+			Status: status.NewInvalid(ctx, "user missing"),
+		}, nil
+	}
+
+	password := utils.ReadPlainFromOpaque(req.Opaque, opaquePassword)
+	if password != "" && s.passwords == nil {
+		return &adminpb.CreateUserResponse{
+			Status: status.NewUnimplemented(ctx, nil, "setting passwords is not supported"),
+		}, nil
+	}
+
+	u, err := p.CreateUser(ctx, req.User)
+	if err != nil {
+		return &adminpb.CreateUserResponse{
+			Status: status.NewStatusFromErrType(ctx, "error creating user", err),
+		}, nil
+	}
+	if password != "" {
+		if err := s.passwords.SetPassword(ctx, u.Username, password); err != nil {
+			// don't leave behind an account without the requested password
+			if derr := p.DeleteUser(ctx, u.Id); derr != nil {
+				appctx.GetLogger(ctx).Error().Err(derr).Str("userid", u.GetId().GetOpaqueId()).Msg("error removing user after failed password change")
+			}
+			return &adminpb.CreateUserResponse{
+				Status: status.NewStatusFromErrType(ctx, "error setting password", err),
+			}, nil
+		}
+	}
+
+	s.publish(ctx, events.UserCreated{
+		Executant: executant(ctx),
+		UserID:    u.GetId().GetOpaqueId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	return &adminpb.CreateUserResponse{
+		Status: status.NewOK(ctx),
+		User:   u,
+	}, nil
+}
+
+// UpdateUser implements provisioning.UserProvisioningAPIServer. It updates the
+// display name, mail and, if given, the password of a user account. Users may
+// update their own account without the account management permission, they
+// have to provide their current password to change it and can't change the
+// verification state of their mail.
+func (s *service) UpdateUser(ctx context.Context, req *provisioning.UpdateUserRequest) (*provisioning.UpdateUserResponse, error) {
+	u := req.GetUser()
+	if u.GetId() == nil {
+		return &provisioning.UpdateUserResponse{
+			Status: status.NewInvalid(ctx, "userid missing"),
+		}, nil
+	}
+	selfService, st := s.checkPermission(ctx, u.Id)
+	if st != nil {
+		return &provisioning.UpdateUserResponse{Status: st}, nil
+	}
+	p, ok := s.usermgr.(user.Provisioner)
+	if !ok {
+		return &provisioning.UpdateUserResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the user manager does not support provisioning"),
+		}, nil
+	}
+	password := req.GetPassword()
+	if password != "" && s.passwords == nil {
+		return &provisioning.UpdateUserResponse{
+			Status: status.NewUnimplemented(ctx, nil, "setting passwords is not supported"),
+		}, nil
+	}
+
+	old, err := s.usermgr.GetUser(ctx, u.Id, true)
+	if err != nil {
+		return &provisioning.UpdateUserResponse{
+			Status: status.NewStatusFromErrType(ctx, "error getting user", err),
+		}, nil
+	}
+
+	if selfService {
+		u = proto.Clone(u).(*userpb.User)
+		u.MailVerified = old.MailVerified
+	}
+
+	// the password is set first, a password violating the policy leaves
+	// the account unchanged
+	if password != "" {
+		if selfService {
+			if st := s.verifyPassword(ctx, old.Username, req.GetCurrentPassword()); st != nil {
+				return &provisioning.UpdateUserResponse{Status: st}, nil
+			}
+		}
+		if err := s.passwords.SetPassword(ctx, old.Username, password); err != nil {
+			return &provisioning.UpdateUserResponse{
+				Status: status.NewStatusFromErrType(ctx, "error setting password", err),
+			}, nil
+		}
+	}
+
+	updated, err := p.UpdateUser(ctx, u)
+	if err != nil {
+		return &provisioning.UpdateUserResponse{
+			Status: status.NewStatusFromErrType(ctx, "error updating user", err),
+		}, nil
+	}
+
+	var features []events.UserFeature
+	if old.DisplayName != updated.DisplayName {
+		features = append(features, events.UserFeature{Name: "displayname", Value: updated.DisplayName, OldValue: &old.DisplayName})
+	}
+	if old.Mail != updated.Mail {
+		features = append(features, events.UserFeature{Name: "email", Value: updated.Mail, OldValue: &old.Mail})
+	}
+	if password != "" {
+		features = append(features, events.UserFeature{Name: "password"})
+	}
+	if len(features) > 0 {
+		s.publish(ctx, events.UserFeatureChanged{
+			Executant: executant(ctx),
+			UserID:    updated.GetId().GetOpaqueId(),
+			Features:  features,
+			Timestamp: utils.TSNow(),
+		})
+	}
+
+	return &provisioning.UpdateUserResponse{
+		Status: status.NewOK(ctx),
+		User:   updated,
+	}, nil
+}
+
+// DeleteUser deletes a user account.
+func (s *service) DeleteUser(ctx context.Context, req *adminpb.DeleteUserRequest) (*adminpb.DeleteUserResponse, error) {
+	if _, st := s.checkPermission(ctx, nil); st != nil {
+		return &adminpb.DeleteUserResponse{Status: st}, nil
+	}
+	p, ok := s.usermgr.(user.Provisioner)
+	if !ok {
+		return &adminpb.DeleteUserResponse{
+			Status: status.NewUnimplemented(ctx, nil, "the user manager does not support provisioning"),
+		}, nil
+	}
+	if req.UserId == nil {
+		return &adminpb.DeleteUserResponse{
+			Status: status.NewInvalid(ctx, "userid missing"),
+		}, nil
+	}
+
+	if err := p.DeleteUser(ctx, req.UserId); err != nil {
+		return &adminpb.DeleteUserResponse{
+			Status: status.NewStatusFromErrType(ctx, "error deleting user", err),
+		}, nil
+	}
+
+	s.publish(ctx, events.UserDeleted{
+		Executant: executant(ctx),
+		UserID:    req.UserId.GetOpaqueId(),
+		Timestamp: utils.TSNow(),
+	})
+
+	return &adminpb.DeleteUserResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// checkPermission returns a non-nil status if the current user is not allowed
+// to manage accounts. If self is set, users may act on their own account, the
+// returned bool reports whether access was only granted for that reason.
+func (s *service) checkPermission(ctx context.Context, self *userpb.UserId) (bool, *rpc.Status) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return false, status.NewPermissionDenied(ctx, nil, "no user in context")
+	}
+
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return false, status.NewInternal(ctx, "error getting gateway client")
+	}
+	allowed, err := utils.CheckPermission(ctx, permission.WriteAccounts, gatewayClient)
+	if err != nil {
+		return false, status.NewInternal(ctx, "error checking permission")
+	}
+	if allowed {
+		return false, nil
+	}
+	if self != nil && utils.UserIDEqual(u.GetId(), self) {
+		return true, nil
+	}
+	return false, status.NewPermissionDenied(ctx, nil, "no permission to manage accounts")
+}
+
+// verifyPassword returns a non-nil status if the password is not the current
+// password of the user.
+func (s *service) verifyPassword(ctx context.Context, username, password string) *rpc.Status {
+	if password == "" {
+		return status.NewPermissionDenied(ctx, nil, "current password missing")
+	}
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return status.NewInternal(ctx, "error getting gateway client")
+	}
+	res, err := gatewayClient.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         s.passwordAuthType,
+		ClientId:     username,
+		ClientSecret: password,
+	})
+	if err != nil {
+		return status.NewInternal(ctx, "error verifying current password")
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return status.NewPermissionDenied(ctx, nil, "wrong current password")
+	}
+	return nil
+}
+
+func (s *service) publish(ctx context.Context, ev interface{}) {
+	if s.stream == nil {
+		return
+	}
+	if err := events.Publish(ctx, s.stream, ev); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Interface("event", ev).Msg("error publishing event")
+	}
+}
+
+func executant(ctx context.Context) *userpb.UserId {
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		return u.Id
+	}
+	return nil
+}
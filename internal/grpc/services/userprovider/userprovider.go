@@ -24,15 +24,25 @@ import (
 	"path/filepath"
 	"sort"
 
+	adminpb "github.com/cs3org/go-cs3apis/cs3/admin/user/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/auth"
+	authregistry "github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
 	"github.com/opencloud-eu/reva/v2/pkg/plugin"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
@@ -40,17 +50,43 @@ import (
 
 func init() {
 	rgrpc.Register("userprovider", New)
+	cfg.Register("grpc.services", "userprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "user.manager"})
 }
 
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	Events  eventconfig                       `mapstructure:"events" docs:"0;Event stream configuration"`
+	// GatewayAddr is used to check the permissions of provisioning requests.
+	GatewayAddr string `mapstructure:"gateway_addr"`
+	// PasswordManager is the auth manager that sets the passwords of
+	// provisioned users. If empty, the user manager sets the passwords if it
+	// can, otherwise changing passwords is not supported.
+	PasswordManager  string                            `mapstructure:"password_manager" docs:";The auth manager used to set passwords, e.g. json or owncloudsql."`
+	PasswordManagers map[string]map[string]interface{} `mapstructure:"password_managers"`
+	// PasswordAuthType is the auth type used to verify the current password
+	// when users change their own password.
+	PasswordAuthType string `mapstructure:"password_auth_type" docs:"basic;The auth type used to verify the current password of self-service password changes."`
+}
+
+type eventconfig struct {
+	Endpoint             string `mapstructure:"nats_address" docs:"address of the nats server"`
+	Cluster              string `mapstructure:"nats_clusterid" docs:"clusterid of the nats server"`
+	TLSInsecure          bool   `mapstructure:"tls_insecure"  docs:"Whether to verify the server TLS certificates."`
+	TLSRootCACertificate string `mapstructure:"tls_root_ca_cert"  docs:"The root CA certificate used to validate the server's TLS certificate."`
+	EnableTLS            bool   `mapstructure:"nats_enable_tls" docs:"events tls switch"`
+	AuthUsername         string `mapstructure:"nats_username" docs:"event stream username"`
+	AuthPassword         string `mapstructure:"nats_password" docs:"event stream password"`
 }
 
 func (c *config) init() {
 	if c.Driver == "" {
 		c.Driver = "json"
 	}
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
+	if c.PasswordAuthType == "" {
+		c.PasswordAuthType = "basic"
+	}
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -88,6 +124,28 @@ func getDriver(c *config) (user.Manager, *plugin.RevaPlugin, error) {
 	return nil, nil, errtypes.NotFound(fmt.Sprintf("driver %s not found for user manager", c.Driver))
 }
 
+// getPasswordSetter returns the configured password manager or, if none is
+// configured, the user manager if it can set passwords.
+func getPasswordSetter(c *config, um user.Manager) (auth.PasswordSetter, error) {
+	if c.PasswordManager == "" {
+		ps, _ := um.(auth.PasswordSetter)
+		return ps, nil
+	}
+	f, ok := authregistry.NewFuncs[c.PasswordManager]
+	if !ok {
+		return nil, errtypes.NotFound(fmt.Sprintf("driver %s not found for password manager", c.PasswordManager))
+	}
+	mgr, err := f(c.PasswordManagers[c.PasswordManager])
+	if err != nil {
+		return nil, err
+	}
+	ps, ok := mgr.(auth.PasswordSetter)
+	if !ok {
+		return nil, fmt.Errorf("auth manager %s can't set passwords", c.PasswordManager)
+	}
+	return ps, nil
+}
+
 // New returns a new UserProviderServiceServer.
 func New(m map[string]interface{}, ss *grpc.Server, _ *zerolog.Logger) (rgrpc.Service, error) {
 	c, err := parseConfig(m)
@@ -98,17 +156,37 @@ func New(m map[string]interface{}, ss *grpc.Server, _ *zerolog.Logger) (rgrpc.Se
 	if err != nil {
 		return nil, err
 	}
+	passwords, err := getPasswordSetter(c, userManager)
+	if err != nil {
+		return nil, err
+	}
+	evstream, err := estreamFromConfig(c.Events)
+	if err != nil {
+		return nil, err
+	}
+	gatewaySelector, err := pool.GatewaySelector(c.GatewayAddr)
+	if err != nil {
+		return nil, err
+	}
 	svc := &service{
-		usermgr: userManager,
-		plugin:  plug,
+		usermgr:          userManager,
+		passwords:        passwords,
+		passwordAuthType: c.PasswordAuthType,
+		plugin:           plug,
+		stream:           evstream,
+		gatewaySelector:  gatewaySelector,
 	}
 
 	return svc, nil
 }
 
 type service struct {
-	usermgr user.Manager
-	plugin  *plugin.RevaPlugin
+	usermgr          user.Manager
+	passwords        auth.PasswordSetter
+	passwordAuthType string
+	plugin           *plugin.RevaPlugin
+	stream           events.Stream
+	gatewaySelector  pool.Selectable[gateway.GatewayAPIClient]
 }
 
 func (s *service) Close() error {
@@ -124,6 +202,8 @@ func (s *service) UnprotectedEndpoints() []string {
 
 func (s *service) Register(ss *grpc.Server) {
 	userpb.RegisterUserAPIServer(ss, s)
+	adminpb.RegisterUserAPIServer(ss, s)
+	provisioning.RegisterUserProvisioningAPIServer(ss, s)
 }
 
 func (s *service) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
@@ -215,3 +295,11 @@ func (s *service) GetUserGroups(ctx context.Context, req *userpb.GetUserGroupsRe
 	}
 	return res, nil
 }
+
+func estreamFromConfig(c eventconfig) (events.Stream, error) {
+	if c.Endpoint == "" {
+		return nil, nil
+	}
+
+	return stream.NatsFromConfig("userprovider", false, stream.NatsConfig(c))
+}
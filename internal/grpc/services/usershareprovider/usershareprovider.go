@@ -48,6 +48,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/share/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 const (
@@ -58,6 +59,7 @@ const (
 
 func init() {
 	rgrpc.Register("usershareprovider", NewDefault)
+	cfg.Register("grpc.services", "usershareprovider", config{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "share.manager"})
 }
 
 type config struct {
@@ -19,11 +19,18 @@
 package prometheus
 
 import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/trace"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 const (
@@ -34,27 +41,74 @@ func init() {
 	global.RegisterMiddleware("prometheus", New)
 }
 
-// New returns a new HTTP middleware that counts requests for prometheus metrics
+type config struct {
+	Namespace string    `mapstructure:"namespace"`
+	Subsystem string    `mapstructure:"subsystem"`
+	Buckets   []float64 `mapstructure:"buckets"`
+}
+
+func (c *config) init() {
+	if c.Namespace == "" {
+		c.Namespace = "reva"
+	}
+	if len(c.Buckets) == 0 {
+		c.Buckets = prometheus.DefBuckets
+	}
+}
+
+// New returns a new HTTP middleware that counts requests and records their duration for prometheus metrics
 func New(m map[string]interface{}) (global.Middleware, int, error) {
-	namespace := m["namespace"].(string)
-	if namespace == "" {
-		namespace = "reva"
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, 0, err
 	}
-	subsystem := m["subsystem"].(string)
+	c.init()
+
 	ph := prometheusHandler{
-		counter: promauto.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: m["subsystem"].(string),
+		counter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: c.Namespace,
+			Subsystem: c.Subsystem,
 			Name:      "http_requests_total",
-			Help:      "The total number of processed " + subsystem + " HTTP requests for " + namespace,
+			Help:      "The total number of processed " + c.Subsystem + " HTTP requests for " + c.Namespace,
 		}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.Namespace,
+			Subsystem: c.Subsystem,
+			Name:      "http_request_duration_seconds",
+			Help:      "The duration of the processed " + c.Subsystem + " HTTP requests for " + c.Namespace + " by method and status code",
+			Buckets:   c.Buckets,
+		}, []string{"method", "code", "class"}),
+	}
+
+	var err error
+	if ph.counter, err = register(ph.counter); err != nil {
+		return nil, 0, err
+	}
+	if ph.duration, err = register(ph.duration); err != nil {
+		return nil, 0, err
 	}
 	return ph.handler, defaultPriority, nil
 }
 
+// register registers c with the default registry. If an identical collector
+// was registered before, e.g. by another server, that one is returned instead.
+func register[T prometheus.Collector](c T) (T, error) {
+	if err := prometheus.Register(c); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
 type prometheusHandler struct {
-	h       http.Handler
-	counter prometheus.Counter
+	h        http.Handler
+	counter  prometheus.Counter
+	duration *prometheus.HistogramVec
 }
 
 // handler is a logging middleware
@@ -64,6 +118,88 @@ func (ph prometheusHandler) handler(h http.Handler) http.Handler {
 }
 
 func (ph prometheusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ph.h.ServeHTTP(w, r)
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	var rw http.ResponseWriter = sw
+	if _, ok := w.(http.Hijacker); ok {
+		rw = hijackStatusWriter{sw}
+	}
+	ph.h.ServeHTTP(rw, r)
 	ph.counter.Inc()
+
+	o := ph.duration.WithLabelValues(r.Method, strconv.Itoa(sw.status), class(sw.status))
+	d := time.Since(start).Seconds()
+	if traceID, ok := trace.ContextGetTraceID(r.Context()); ok {
+		o.(prometheus.ExemplarObserver).ObserveWithExemplar(d, prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	o.Observe(d)
+}
+
+// class sorts a HTTP status code into success, client or server errors
+func class(status int) string {
+	switch {
+	case status >= 500:
+		return "server_error"
+	case status >= 400:
+		return "client_error"
+	default:
+		return "success"
+	}
+}
+
+// statusWriter remembers the status code written to the wrapped ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped ResponseWriter for the http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush implements http.Flusher if the wrapped ResponseWriter does
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ReadFrom implements io.ReaderFrom, using the wrapped ResponseWriter if it does
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.wroteHeader = true
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// hijackStatusWriter is a statusWriter for ResponseWriters implementing http.Hijacker
+type hijackStatusWriter struct {
+	*statusWriter
+}
+
+func (w hijackStatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h := w.ResponseWriter.(http.Hijacker)
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
 }
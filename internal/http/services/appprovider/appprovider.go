@@ -42,6 +42,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/protobuf/proto"
@@ -49,6 +50,7 @@ import (
 
 func init() {
 	global.Register("appprovider", New)
+	cfg.Register("http.services", "appprovider", Config{})
 }
 
 // Config holds the config options for the HTTP appprovider service
@@ -35,6 +35,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/internal/http/services/archiver/manager"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
@@ -42,6 +43,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/downloader"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/walker"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
@@ -69,6 +71,7 @@ type Config struct {
 
 func init() {
 	global.Register("archiver", New)
+	cfg.Register("http.services", "archiver", Config{})
 }
 
 // New creates a new archiver service
@@ -221,6 +224,9 @@ func (s *svc) Handler() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		// get the paths and/or the resources id from the query
 		ctx := r.Context()
+		// the files of a public link archive count as one download of the visitor
+		visitor, _ := publicshare.Visitor(r)
+		ctx = publicshare.ContextSetVisitor(ctx, visitor)
 		v := r.URL.Query()
 
 		paths, ok := v["path"]
@@ -34,6 +34,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel"
@@ -54,6 +55,7 @@ const (
 
 func init() {
 	global.Register("datagateway", New)
+	cfg.Register("http.services", "datagateway", config{})
 }
 
 // transferClaims are custom claims for a JWT token to be used between the metadata and data gateways.
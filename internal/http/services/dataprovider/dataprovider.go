@@ -33,10 +33,15 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/router"
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	global.Register("dataprovider", New)
+	cfg.Register("http.services", "dataprovider", config{},
+		cfg.Driver{Selector: "driver", Map: "drivers", Kind: "storage.fs"},
+		cfg.Driver{Map: "data_txs", Kind: "rhttp.datatx.manager"},
+	)
 }
 
 type config struct {
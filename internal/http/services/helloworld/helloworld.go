@@ -24,11 +24,13 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	global.Register("helloworld", New)
+	cfg.Register("http.services", "helloworld", config{})
 }
 
 // New returns a new helloworld service
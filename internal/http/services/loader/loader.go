@@ -37,5 +37,6 @@ import (
 	_ "github.com/opencloud-eu/reva/v2/internal/http/services/siteacc"
 	_ "github.com/opencloud-eu/reva/v2/internal/http/services/sysinfo"
 	_ "github.com/opencloud-eu/reva/v2/internal/http/services/wellknown"
+	_ "github.com/opencloud-eu/reva/v2/internal/http/services/wopi"
 	// Add your own service here
 )
@@ -30,10 +30,12 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/mentix/config"
 	"github.com/opencloud-eu/reva/v2/pkg/mentix/exchangers"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	global.Register(serviceName, New)
+	cfg.Register("http.services", serviceName, config.Configuration{})
 }
 
 type svc struct {
@@ -32,10 +32,12 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/metrics"
 	"github.com/opencloud-eu/reva/v2/pkg/metrics/config"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	global.Register(serviceName, New)
+	cfg.Register("http.services", serviceName, config.Config{})
 }
 
 const (
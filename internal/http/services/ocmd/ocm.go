@@ -31,6 +31,7 @@ import (
 
 func init() {
 	global.Register("ocmd", New)
+	cfg.Register("http.services", "ocmd", config{})
 }
 
 type config struct {
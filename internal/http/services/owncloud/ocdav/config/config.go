@@ -38,6 +38,10 @@ type Config struct {
 	NameValidation NameValidation `mapstructure:"validation"`
 
 	MachineAuthAPIKey string `mapstructure:"machine_auth_apikey"`
+
+	// SecureFileDrop isolates the uploaders of secret file drops from each other. Their uploads are placed
+	// into a subfolder named by the label they send in the X-File-Drop-Label header or by the upload time.
+	SecureFileDrop bool `mapstructure:"secure_file_drop"`
 }
 
 // NameValidation is the validation configuration for file and folder names
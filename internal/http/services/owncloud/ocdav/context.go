@@ -7,6 +7,7 @@ import (
 )
 
 type tokenStatInfoKey struct{}
+type fileDropLabelKey struct{}
 
 // ContextWithTokenStatInfo adds the token stat info to the context
 func ContextWithTokenStatInfo(ctx context.Context, info *cs3storage.ResourceInfo) context.Context {
@@ -18,3 +19,14 @@ func TokenStatInfoFromContext(ctx context.Context) (*cs3storage.ResourceInfo, bo
 	v, ok := ctx.Value(tokenStatInfoKey{}).(*cs3storage.ResourceInfo)
 	return v, ok
 }
+
+// ContextWithFileDropLabel adds the uploader label of a secure file drop to the context
+func ContextWithFileDropLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, fileDropLabelKey{}, label)
+}
+
+// FileDropLabelFromContext returns the uploader label of a secure file drop from the context
+func FileDropLabelFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(fileDropLabelKey{}).(string)
+	return v, ok
+}
@@ -388,6 +388,18 @@ func (h *DavHandler) Handler(s *svc) http.Handler {
 
 			ctx := ContextWithTokenStatInfo(ctx, sRes.Info)
 			r = r.WithContext(ctx)
+			if r.Method != http.MethodOptions {
+				var ok bool
+				if r, ok = s.recordPublicLinkVisit(w, r, token); !ok {
+					return
+				}
+			}
+			if s.c.SecureFileDrop && sRes.Info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && isSecretFileDrop(sRes.Info) {
+				var ok bool
+				if r, ok = s.isolateFileDropUpload(w, r, h.PublicFolderHandler.namespace, token); !ok {
+					return
+				}
+			}
 			if sRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 				h.PublicFileHandler.Handler(s).ServeHTTP(w, r)
 			} else {
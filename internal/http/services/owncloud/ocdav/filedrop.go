@@ -3,15 +3,178 @@ package ocdav
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	gatewayv1beta1 "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ocdaverrors "github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/errors"
+	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/net"
+	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/spacelookup"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp/router"
 )
 
+const (
+	// FileDropLabelKey is the arbitrary metadata key holding the label of the uploader of a secure file drop folder
+	FileDropLabelKey = "filedrop.label"
+	// FileDropCreatedKey is the arbitrary metadata key holding the time the secure file drop folder was created
+	FileDropCreatedKey = "filedrop.created"
+
+	// fileDropFolderIDLength is the number of characters of the hashed visitor id in the folder names
+	fileDropFolderIDLength = 8
+	// fileDropFolderTimeFormat is the format of the names of the folders of uploaders without a label
+	fileDropFolderTimeFormat = "2006-01-02 15-04-05"
+	// fileDropFolderTTL is how long the folder of an uploader without a label is used for further uploads
+	fileDropFolderTTL = time.Hour
+	// fileDropFolderAttempts is the number of names tried when the folder of an uploader already exists
+	fileDropFolderAttempts = 100
+)
+
+// isSecretFileDrop reports if the public link only allows uploading.
+// We assume that when the uploader can create containers, but is not allowed to list them, it is a secret file drop
+func isSecretFileDrop(info *provider.ResourceInfo) bool {
+	return info.GetPermissionSet().GetCreateContainer() && !info.GetPermissionSet().GetListContainer()
+}
+
+// isolateFileDropUpload moves write requests to a secret file drop into the folder of the uploader.
+// The folder is named after the label sent by the uploader and the hashed id of the visitor, so that
+// uploaders with the same label do not share a folder. Uploaders without a label get a folder named
+// after the time of their first upload, which is renamed if it is taken and remembered for the
+// following requests, so that all requests of one upload end up in the same folder. It is created on
+// demand. It returns false if the request must not be served, the response has been written in that case.
+func (s *svc) isolateFileDropUpload(w http.ResponseWriter, r *http.Request, ns, token string) (*http.Request, bool) {
+	switch r.Method {
+	case http.MethodPut, http.MethodPost, MethodMkcol:
+	default:
+		return r, true
+	}
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	label := strings.TrimSpace(r.Header.Get(net.HeaderFileDropLabel))
+	if l, err := url.PathUnescape(label); err == nil {
+		label = strings.TrimSpace(l)
+	}
+	visitor, _ := publicshare.ContextGetVisitor(ctx)
+	id := publicshare.HashVisitor(visitor)
+
+	invalid := func(name string) bool {
+		return ValidateName(name, s.nameValidators) != nil || strings.Contains(name, "/")
+	}
+
+	var (
+		folder string
+		st     *rpc.Status
+		err    error
+	)
+	if label != "" {
+		if len(id) > fileDropFolderIDLength {
+			id = id[:fileDropFolderIDLength]
+		}
+		folder = label + " (" + id + ")"
+		if invalid(label) || invalid(folder) {
+			w.WriteHeader(http.StatusBadRequest)
+			b, err := ocdaverrors.Marshal(http.StatusBadRequest, "invalid file drop label", "", "")
+			ocdaverrors.HandleWebdavError(log, w, b, err)
+			return r, false
+		}
+		// an existing folder belongs to the same visitor, its name is derived from the visitor id
+		st, err = s.createFileDropFolder(ctx, path.Join(ns, token, folder), label, true)
+	} else {
+		folder, st, err = s.visitorFileDropFolder(ctx, path.Join(ns, token), token+"/"+id)
+	}
+	switch {
+	case err != nil:
+		log.Error().Err(err).Str("folder", folder).Msg("error creating file drop folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return r, false
+	case st.GetCode() != rpc.Code_CODE_OK:
+		ocdaverrors.HandleErrorStatus(log, w, st)
+		return r, false
+	}
+
+	_, rest := router.ShiftPath(r.URL.Path)
+	r.URL.Path = path.Join("/", token, folder, rest)
+	return r.WithContext(ContextWithFileDropLabel(ctx, folder)), true
+}
+
+// visitorFileDropFolder returns the folder of an uploader without a label in the file drop at dir,
+// the key identifies the uploader. The folder is named after the current time, a number is added
+// to the name if another uploader uses it already.
+func (s *svc) visitorFileDropFolder(ctx context.Context, dir, key string) (string, *rpc.Status, error) {
+	s.fileDropMu.Lock()
+	defer s.fileDropMu.Unlock()
+
+	if v, err := s.fileDropFolders.Get(key); err == nil {
+		folder := v.(string)
+		st, err := s.createFileDropFolder(ctx, path.Join(dir, folder), "", true)
+		return folder, st, err
+	}
+
+	name := time.Now().UTC().Format(fileDropFolderTimeFormat)
+	folder := name
+	// starts with two because the existing folder is the first one
+	for i := 2; i < fileDropFolderAttempts+2; i++ {
+		st, err := s.createFileDropFolder(ctx, path.Join(dir, folder), "", false)
+		if err != nil || st.GetCode() != rpc.Code_CODE_ALREADY_EXISTS {
+			if st.GetCode() == rpc.Code_CODE_OK {
+				_ = s.fileDropFolders.Set(key, folder)
+			}
+			return folder, st, err
+		}
+		folder = name + " (" + strconv.Itoa(i) + ")"
+	}
+	return "", nil, errors.New("could not determine a free file drop folder name")
+}
+
+// createFileDropFolder creates the folder of a file drop uploader. If the folder already exists it is
+// used if reuse is set, otherwise an ALREADY_EXISTS status is returned.
+func (s *svc) createFileDropFolder(ctx context.Context, fn, label string, reuse bool) (*rpc.Status, error) {
+	space, st, err := spacelookup.LookUpStorageSpaceForPath(ctx, s.gatewaySelector, fn)
+	if err != nil || st.GetCode() != rpc.Code_CODE_OK {
+		return st, err
+	}
+	ref := spacelookup.MakeRelativeReference(space, fn, false)
+
+	client, err := s.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.GetStatus().GetCode() == rpc.Code_CODE_ALREADY_EXISTS && reuse:
+		return &rpc.Status{Code: rpc.Code_CODE_OK}, nil
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return res.GetStatus(), nil
+	}
+
+	// record who the folder belongs to, the uploads themselves are owned by the link
+	md := map[string]string{
+		FileDropCreatedKey: time.Now().UTC().Format(time.RFC3339),
+	}
+	if label != "" {
+		md[FileDropLabelKey] = label
+	}
+	mdRes, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref:               ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+	})
+	if err != nil || mdRes.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		appctx.GetLogger(ctx).Warn().Err(err).Interface("status", mdRes.GetStatus()).Str("path", fn).Msg("could not store file drop metadata")
+	}
+	return res.GetStatus(), nil
+}
+
 // FindName returns the next filename available when the current
 func FindName(ctx context.Context, client gatewayv1beta1.GatewayAPIClient, name string, parentid *provider.ResourceId) (string, *rpc.Status, error) {
 	lReq := &provider.ListContainerRequest{
@@ -32,6 +32,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/net"
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/spacelookup"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
 	"github.com/rs/zerolog"
@@ -91,6 +92,10 @@ func (s *svc) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	}
 
 	dReq := &provider.InitiateFileDownloadRequest{Ref: ref}
+	if visitor, ok := publicshare.ContextGetVisitor(ctx); ok {
+		// the visitor counts against the visitor limit of public links
+		dReq.Opaque = utils.AppendPlainToOpaque(dReq.Opaque, publicshare.VisitorOpaqueKey, visitor)
+	}
 	dRes, err := client.InitiateFileDownload(ctx, dReq)
 	switch {
 	case err != nil:
@@ -117,8 +122,18 @@ func (s *svc) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	}
 	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
 
-	if r.Header.Get(net.HeaderRange) != "" {
-		httpReq.Header.Set(net.HeaderRange, r.Header.Get(net.HeaderRange))
+	// the data service evaluates range and conditional requests
+	for _, h := range []string{
+		net.HeaderRange,
+		net.HeaderIfRange,
+		net.HeaderIfMatch,
+		net.HeaderIfNoneMatch,
+		net.HeaderIfModifiedSince,
+		net.HeaderIfUnmodifiedSince,
+	} {
+		if v := r.Header.Values(h); len(v) > 0 {
+			httpReq.Header[h] = v
+		}
 	}
 
 	httpClient := s.client
@@ -34,6 +34,9 @@ const (
 	HeaderRange                      = "Range"
 	HeaderIfMatch                    = "If-Match"
 	HeaderIfNoneMatch                = "If-None-Match"
+	HeaderIfModifiedSince            = "If-Modified-Since"
+	HeaderIfUnmodifiedSince          = "If-Unmodified-Since"
+	HeaderIfRange                    = "If-Range"
 	HeaderPrefer                     = "Prefer"
 	HeaderPreferenceApplied          = "Preference-Applied"
 	HeaderVary                       = "Vary"
@@ -68,6 +71,7 @@ const (
 	HeaderOCMtime              = "X-OC-Mtime"
 	HeaderExpectedEntityLength = "X-Expected-Entity-Length"
 	HeaderLitmus               = "X-Litmus"
+	HeaderFileDropLabel        = "X-File-Drop-Label"
 	HeaderTransferAuth         = "TransferHeaderAuthorization"
 )
 
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -44,6 +45,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/favorite/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/templates"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -55,6 +57,7 @@ const tracerName = "ocdav"
 
 func init() {
 	global.Register("ocdav", New)
+	cfg.Register("http.services", "ocdav", config.Config{})
 }
 
 type svc struct {
@@ -68,6 +71,9 @@ type svc struct {
 	LockSystem          LockSystem
 	userIdentifierCache *ttlcache.Cache
 	nameValidators      []Validator
+	// fileDropFolders remembers the folder of the uploaders of secure file drops
+	fileDropFolders *ttlcache.Cache
+	fileDropMu      sync.Mutex
 }
 
 func (s *svc) Config() *config.Config {
@@ -128,8 +134,10 @@ func NewWith(conf *config.Config, fm favorite.Manager, ls LockSystem, _ *zerolog
 		LockSystem:          ls,
 		userIdentifierCache: ttlcache.NewCache(),
 		nameValidators:      ValidatorsFromConfig(conf),
+		fileDropFolders:     ttlcache.NewCache(),
 	}
 	_ = s.userIdentifierCache.SetTTL(60 * time.Second)
+	_ = s.fileDropFolders.SetTTL(fileDropFolderTTL)
 
 	// initialize handlers and set default configs
 	if err := s.webDavHandler.init(conf.WebdavNamespace, true); err != nil {
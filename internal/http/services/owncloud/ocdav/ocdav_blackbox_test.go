@@ -25,6 +25,7 @@ import (
 	"net/http/httptest"
 	"path"
 	"strings"
+	"time"
 
 	cs3gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -37,6 +38,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/config"
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/net"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
@@ -76,6 +78,9 @@ var _ = Describe("ocdav", func() {
 
 		basePath string
 
+		accessStatus *cs3rpc.Status
+		accessed     []*link.GetPublicShareRequest
+
 		// mockPathStat is used to by path based endpoints
 		mockPathStat = func(path string, s *cs3rpc.Status, info *cs3storageprovider.ResourceInfo) {
 			client.On("Stat", mock.Anything, mock.MatchedBy(func(req *cs3storageprovider.StatRequest) bool {
@@ -181,6 +186,15 @@ var _ = Describe("ocdav", func() {
 			},
 		}
 
+		// public link visits are counted against the link limits
+		accessStatus = status.NewOK(ctx)
+		accessed = nil
+		client.On("GetPublicShare", mock.Anything, mock.MatchedBy(func(req *link.GetPublicShareRequest) bool {
+			return utils.ExistsInOpaque(req.GetOpaque(), publicshare.AccessOpaqueKey)
+		})).Return(func(_ context.Context, req *link.GetPublicShareRequest, _ ...grpc.CallOption) (*link.GetPublicShareResponse, error) {
+			accessed = append(accessed, req)
+			return &link.GetPublicShareResponse{Status: accessStatus}, nil
+		})
 		client.On("GetPublicShare", mock.Anything, mock.Anything).Return(&link.GetPublicShareResponse{
 			Status: status.NewNotFound(ctx, "not found")},
 			nil)
@@ -1073,6 +1087,236 @@ var _ = Describe("ocdav", func() {
 			basePath = "/dav/public-files"
 		})
 
+		It("hands out a stable visitor cookie and only records visits of the link", func() {
+			var visitors []string
+			for i := 0; i < 2; i++ {
+				rr := httptest.NewRecorder()
+				req, err := http.NewRequest("DELETE", basePath+"/tokenforfile/foo", strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				req.RemoteAddr = "192.0.2.1:1234"
+
+				handler.Handler().ServeHTTP(rr, req)
+				Expect(rr).To(HaveHTTPStatus(http.StatusMethodNotAllowed))
+
+				cookies := rr.Result().Cookies()
+				Expect(cookies).To(HaveLen(1))
+				Expect(cookies[0].Name).To(Equal(publicshare.VisitorCookie))
+				Expect(cookies[0].Path).To(Equal("/"))
+				visitors = append(visitors, cookies[0].Value)
+			}
+			Expect(visitors[0]).To(Equal(visitors[1]))
+			Expect(accessed).To(BeEmpty())
+		})
+
+		It("records visits with the visitor cookie", func() {
+			accessStatus = status.NewPermissionDenied(ctx, nil, "public share visitor limit reached")
+
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("PROPFIND", basePath+"/tokenforfolder", strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			req.AddCookie(&http.Cookie{Name: publicshare.VisitorCookie, Value: "known"})
+
+			handler.Handler().ServeHTTP(rr, req)
+			Expect(rr.Result().Cookies()).To(BeEmpty())
+
+			a := publicshare.Access{}
+			Expect(accessed).To(HaveLen(1))
+			Expect(accessed[0].GetRef().GetToken()).To(Equal("tokenforfolder"))
+			Expect(utils.ReadJSONFromOpaque(accessed[0].GetOpaque(), publicshare.AccessOpaqueKey, &a)).To(Succeed())
+			Expect(a.Visitor).To(Equal("known"))
+			Expect(a.Download).To(BeFalse())
+		})
+
+		It("denies access when the link limits are reached", func() {
+			accessStatus = status.NewPermissionDenied(ctx, nil, "public share visitor limit reached")
+
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("PROPFIND", basePath+"/tokenforfolder", strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+
+			handler.Handler().ServeHTTP(rr, req)
+			Expect(rr).To(HaveHTTPStatus(http.StatusForbidden))
+			Expect(rr.Body.String()).To(ContainSubstring("public share visitor limit reached"))
+		})
+
+		It("returns not found for removed links", func() {
+			accessStatus = status.NewNotFound(ctx, "public share not found")
+
+			rr := httptest.NewRecorder()
+			req, err := http.NewRequest("PROPFIND", basePath+"/tokenforfolder", strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+
+			handler.Handler().ServeHTTP(rr, req)
+			Expect(rr).To(HaveHTTPStatus(http.StatusNotFound))
+		})
+
+		Context("with a secure file drop", func() {
+			var (
+				created  []*cs3storageprovider.CreateContainerRequest
+				metadata []*cs3storageprovider.SetArbitraryMetadataRequest
+				// taken is the number of folders that already exist
+				taken int
+			)
+
+			BeforeEach(func() {
+				created = nil
+				metadata = nil
+				taken = 0
+
+				cfg := &config.Config{
+					SecureFileDrop: true,
+					NameValidation: config.NameValidation{
+						MaxLength:    255,
+						InvalidChars: []string{"\f", "\r", "\n", "\\"},
+					},
+				}
+				sel := selector{
+					client: client,
+				}
+				handler, err = ocdav.NewWith(cfg, nil, ocdav.NewCS3LS(sel), nil, sel)
+				Expect(err).ToNot(HaveOccurred())
+
+				publicspace := &cs3storageprovider.StorageSpace{
+					Opaque: utils.AppendPlainToOpaque(nil, "path", "/public"),
+					Id:     &cs3storageprovider.StorageSpaceId{OpaqueId: utils.PublicStorageProviderID + "$" + utils.PublicStorageSpaceID},
+					Root:   &cs3storageprovider.ResourceId{StorageId: utils.PublicStorageProviderID, SpaceId: utils.PublicStorageSpaceID, OpaqueId: utils.PublicStorageSpaceID},
+				}
+				client.On("Stat", mock.Anything, mock.MatchedBy(func(req *cs3storageprovider.StatRequest) bool {
+					return req.GetRef().GetResourceId().GetOpaqueId() == "tokenfordrop" && req.GetRef().GetPath() == ""
+				})).Return(&cs3storageprovider.StatResponse{
+					Status: status.NewOK(ctx),
+					Info: &cs3storageprovider.ResourceInfo{
+						Type: cs3storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER,
+						PermissionSet: &cs3storageprovider.ResourcePermissions{
+							CreateContainer:    true,
+							InitiateFileUpload: true,
+						},
+					},
+				}, nil)
+				client.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&cs3storageprovider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*cs3storageprovider.StorageSpace{publicspace},
+				}, nil)
+				client.On("Stat", mock.Anything, mock.Anything).Return(&cs3storageprovider.StatResponse{
+					Status: status.NewNotFound(ctx, "not found"),
+				}, nil)
+				client.On("CreateContainer", mock.Anything, mock.Anything).Return(func(_ context.Context, req *cs3storageprovider.CreateContainerRequest, _ ...grpc.CallOption) (*cs3storageprovider.CreateContainerResponse, error) {
+					created = append(created, req)
+					if taken > 0 {
+						taken--
+						return &cs3storageprovider.CreateContainerResponse{Status: status.NewAlreadyExists(ctx, nil, "exists")}, nil
+					}
+					return &cs3storageprovider.CreateContainerResponse{Status: status.NewOK(ctx)}, nil
+				})
+				client.On("SetArbitraryMetadata", mock.Anything, mock.Anything).Return(func(_ context.Context, req *cs3storageprovider.SetArbitraryMetadataRequest, _ ...grpc.CallOption) (*cs3storageprovider.SetArbitraryMetadataResponse, error) {
+					metadata = append(metadata, req)
+					return &cs3storageprovider.SetArbitraryMetadataResponse{Status: status.NewOK(ctx)}, nil
+				})
+			})
+
+			It("places the uploads into the folder of the uploader", func() {
+				rr := httptest.NewRecorder()
+				req, err := http.NewRequest("MKCOL", basePath+"/tokenfordrop/foo", strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("X-File-Drop-Label", "Alice%20Smith")
+				req.AddCookie(&http.Cookie{Name: publicshare.VisitorCookie, Value: "v1"})
+				folder := "./tokenfordrop/Alice Smith (" + publicshare.HashVisitor("v1")[:8] + ")"
+
+				handler.Handler().ServeHTTP(rr, req)
+				Expect(rr).To(HaveHTTPStatus(http.StatusCreated))
+				Expect(created).To(HaveLen(2))
+				Expect(created[0].GetRef().GetPath()).To(Equal(folder))
+				Expect(created[1].GetRef().GetPath()).To(Equal(folder + "/foo"))
+				Expect(metadata).To(HaveLen(1))
+				Expect(metadata[0].GetRef().GetPath()).To(Equal(folder))
+				Expect(metadata[0].GetArbitraryMetadata().GetMetadata()).To(HaveKeyWithValue(ocdav.FileDropLabelKey, "Alice Smith"))
+				Expect(metadata[0].GetArbitraryMetadata().GetMetadata()).To(HaveKey(ocdav.FileDropCreatedKey))
+			})
+
+			It("names the folder after the upload time without a label", func() {
+				rr := httptest.NewRecorder()
+				req, err := http.NewRequest("MKCOL", basePath+"/tokenfordrop/foo", strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				req.AddCookie(&http.Cookie{Name: publicshare.VisitorCookie, Value: "v1"})
+
+				handler.Handler().ServeHTTP(rr, req)
+				Expect(rr).To(HaveHTTPStatus(http.StatusCreated))
+				Expect(created).To(HaveLen(2))
+				folder := strings.TrimPrefix(created[0].GetRef().GetPath(), "./tokenfordrop/")
+				_, err = time.Parse("2006-01-02 15-04-05", folder)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metadata[0].GetArbitraryMetadata().GetMetadata()).ToNot(HaveKey(ocdav.FileDropLabelKey))
+			})
+
+			It("keeps the folder of an uploader without a label", func() {
+				for _, name := range []string{"foo", "bar"} {
+					rr := httptest.NewRecorder()
+					req, err := http.NewRequest("MKCOL", basePath+"/tokenfordrop/"+name, strings.NewReader(""))
+					Expect(err).ToNot(HaveOccurred())
+					req.AddCookie(&http.Cookie{Name: publicshare.VisitorCookie, Value: "v1"})
+
+					handler.Handler().ServeHTTP(rr, req)
+					Expect(rr).To(HaveHTTPStatus(http.StatusCreated))
+				}
+				Expect(created).To(HaveLen(4))
+				Expect(created[2].GetRef().GetPath()).To(Equal(created[0].GetRef().GetPath()))
+				Expect(created[3].GetRef().GetPath()).To(Equal(created[0].GetRef().GetPath() + "/bar"))
+			})
+
+			It("renames the folder of an uploader without a label if it is taken", func() {
+				taken = 1
+				rr := httptest.NewRecorder()
+				req, err := http.NewRequest("MKCOL", basePath+"/tokenfordrop/foo", strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				req.AddCookie(&http.Cookie{Name: publicshare.VisitorCookie, Value: "v2"})
+
+				handler.Handler().ServeHTTP(rr, req)
+				Expect(rr).To(HaveHTTPStatus(http.StatusCreated))
+				Expect(created).To(HaveLen(3))
+				Expect(created[1].GetRef().GetPath()).To(Equal(created[0].GetRef().GetPath() + " (2)"))
+				Expect(created[2].GetRef().GetPath()).To(Equal(created[1].GetRef().GetPath() + "/foo"))
+				Expect(metadata).To(HaveLen(1))
+				Expect(metadata[0].GetRef().GetPath()).To(Equal(created[1].GetRef().GetPath()))
+			})
+
+			It("separates uploaders with the same label", func() {
+				for _, visitor := range []string{"v1", "v2"} {
+					rr := httptest.NewRecorder()
+					req, err := http.NewRequest("MKCOL", basePath+"/tokenfordrop/foo", strings.NewReader(""))
+					Expect(err).ToNot(HaveOccurred())
+					req.Header.Set("X-File-Drop-Label", "alice")
+					req.AddCookie(&http.Cookie{Name: publicshare.VisitorCookie, Value: visitor})
+
+					handler.Handler().ServeHTTP(rr, req)
+					Expect(rr).To(HaveHTTPStatus(http.StatusCreated))
+				}
+				Expect(created).To(HaveLen(4))
+				Expect(created[0].GetRef().GetPath()).ToNot(Equal(created[2].GetRef().GetPath()))
+			})
+
+			It("rejects labels that are not valid folder names", func() {
+				for _, label := range []string{"..", "a/b", "a\\b"} {
+					rr := httptest.NewRecorder()
+					req, err := http.NewRequest("MKCOL", basePath+"/tokenfordrop/foo", strings.NewReader(""))
+					Expect(err).ToNot(HaveOccurred())
+					req.Header.Set("X-File-Drop-Label", label)
+
+					handler.Handler().ServeHTTP(rr, req)
+					Expect(rr).To(HaveHTTPStatus(http.StatusBadRequest), label)
+				}
+				Expect(created).To(BeEmpty())
+			})
+
+			It("does not isolate read requests", func() {
+				rr := httptest.NewRecorder()
+				req, err := http.NewRequest("PROPFIND", basePath+"/tokenfordrop", strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("X-File-Drop-Label", "alice")
+
+				handler.Handler().ServeHTTP(rr, req)
+				Expect(created).To(BeEmpty())
+			})
+		})
 	})
 
 	// TODO restructure the tests and split them up by endpoint?
@@ -24,13 +24,17 @@ import (
 	"net/http"
 	"path"
 
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	ocdaverrors "github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/errors"
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/net"
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/propfind"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/router"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
 	"go.opentelemetry.io/otel/codes"
 	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
 )
@@ -91,6 +95,67 @@ func (h *PublicFileHandler) Handler(s *svc) http.Handler {
 	})
 }
 
+// recordPublicLinkVisit identifies the visitor of the public link, the visitor is added to the
+// context of the returned request so that downloads can be attributed to it. Only requests for
+// the link itself, GET and PROPFIND on its root, are counted against the visitor limit.
+// It returns false if the request must not be served, the response has been written in that case.
+func (s *svc) recordPublicLinkVisit(w http.ResponseWriter, r *http.Request, token string) (*http.Request, bool) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	visitor, setCookie := publicshare.Visitor(r)
+	if setCookie {
+		// the cookie is sent to the archiver as well, so it has to cover all paths
+		http.SetCookie(w, &http.Cookie{
+			Name:     publicshare.VisitorCookie,
+			Value:    visitor,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	r = r.WithContext(publicshare.ContextSetVisitor(ctx, visitor))
+
+	if _, rest := router.ShiftPath(r.URL.Path); rest != "/" || (r.Method != http.MethodGet && r.Method != MethodPropfind) {
+		return r, true
+	}
+
+	client, err := s.gatewaySelector.Next()
+	if err != nil {
+		log.Error().Err(err).Msg("error selecting next gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return r, false
+	}
+	res, err := client.GetPublicShare(ctx, &link.GetPublicShareRequest{
+		Opaque: utils.AppendJSONToOpaque(nil, publicshare.AccessOpaqueKey, publicshare.Access{Visitor: visitor}),
+		Ref: &link.PublicShareReference{
+			Spec: &link.PublicShareReference_Token{
+				Token: token,
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		log.Error().Err(err).Msg("error sending grpc get public share request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return r, false
+	case res.GetStatus().GetCode() == rpc.Code_CODE_PERMISSION_DENIED:
+		w.WriteHeader(http.StatusForbidden)
+		b, err := ocdaverrors.Marshal(http.StatusForbidden, res.GetStatus().GetMessage(), "", "")
+		ocdaverrors.HandleWebdavError(log, w, b, err)
+		return r, false
+	case res.GetStatus().GetCode() == rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+		return r, false
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		log.Error().Str("token", token).Interface("status", res.GetStatus()).Msg("grpc get public share request failed")
+		w.WriteHeader(http.StatusInternalServerError)
+		return r, false
+	}
+	return r, true
+}
+
 // ns is the namespace that is prefixed to the path in the cs3 namespace
 func (s *svc) handlePropfindOnToken(w http.ResponseWriter, r *http.Request, ns string, onContainer bool) {
 	ctx, span := appctx.GetTracerProvider(r.Context()).Tracer(tracerName).Start(r.Context(), "token_propfind")
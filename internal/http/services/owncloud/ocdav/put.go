@@ -36,6 +36,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/spacelookup"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
@@ -158,8 +159,7 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 	// Test if the target is a secret filedrop
 	tokenStatInfo, ok := TokenStatInfoFromContext(ctx)
-	// We assume that when the uploader can create containers, but is not allowed to list them, it is a secret file drop
-	if ok && tokenStatInfo.GetPermissionSet().CreateContainer && !tokenStatInfo.GetPermissionSet().ListContainer {
+	if ok && isSecretFileDrop(tokenStatInfo) {
 		// TODO we can skip this stat if the tokenStatInfo is the direct parent
 		sReq := &provider.StatRequest{
 			Ref: ref,
@@ -197,6 +197,9 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	}
 
 	opaque := &typespb.Opaque{}
+	if label, ok := FileDropLabelFromContext(ctx); ok {
+		utils.AppendPlainToOpaque(opaque, events.FileDropLabelOpaqueKey, label)
+	}
 	if mtime := r.Header.Get(net.HeaderOCMtime); mtime != "" {
 		utils.AppendPlainToOpaque(opaque, net.HeaderOCMtime, mtime)
 
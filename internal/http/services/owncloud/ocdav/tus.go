@@ -38,6 +38,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/spacelookup"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	"github.com/opencloud-eu/reva/v2/pkg/conversions"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
@@ -112,12 +113,8 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 	}
 
 	// Test if the target is a secret filedrop
-	var isSecretFileDrop bool
 	tokenStatInfo, ok := TokenStatInfoFromContext(ctx)
-	// We assume that when the uploader can create containers, but is not allowed to list them, it is a secret file drop
-	if ok && tokenStatInfo.GetPermissionSet().CreateContainer && !tokenStatInfo.GetPermissionSet().ListContainer {
-		isSecretFileDrop = true
-	}
+	secretFileDrop := ok && isSecretFileDrop(tokenStatInfo)
 
 	// r.Header.Get(net.HeaderOCChecksum)
 	// TODO must be SHA1, ADLER32 or MD5 ... in capital letters????
@@ -162,7 +159,7 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 				return
 			}
 		}
-		if isSecretFileDrop {
+		if secretFileDrop {
 			// find next filename
 			newName, status, err := FindName(ctx, client, filepath.Base(ref.Path), sRes.GetInfo().GetParentId())
 			if err != nil {
@@ -187,9 +184,13 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		return
 	}
 	if uploadLength == 0 {
-		tfRes, err := client.TouchFile(ctx, &provider.TouchFileRequest{
+		tfReq := &provider.TouchFileRequest{
 			Ref: ref,
-		})
+		}
+		if label, ok := FileDropLabelFromContext(ctx); ok {
+			tfReq.Opaque = utils.AppendPlainToOpaque(nil, events.FileDropLabelOpaqueKey, label)
+		}
+		tfRes, err := client.TouchFile(ctx, tfReq)
 		if err != nil {
 			log.Error().Err(err).Msg("error sending grpc stat request")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -216,6 +217,13 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		},
 	}
 
+	if label, ok := FileDropLabelFromContext(ctx); ok {
+		opaqueMap[events.FileDropLabelOpaqueKey] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(label),
+		}
+	}
+
 	mtime := meta["mtime"]
 	if mtime != "" {
 		opaqueMap[net.HeaderOCMtime] = &typespb.OpaqueEntry{
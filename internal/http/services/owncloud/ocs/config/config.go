@@ -32,6 +32,8 @@ type Config struct {
 	Capabilities                          ocs.CapabilitiesData              `mapstructure:"capabilities"`
 	GatewaySvc                            string                            `mapstructure:"gatewaysvc"`
 	StorageregistrySvc                    string                            `mapstructure:"storage_registry_svc"`
+	UserProviderSvc                       string                            `mapstructure:"user_provider_svc"`
+	GroupProviderSvc                      string                            `mapstructure:"group_provider_svc"`
 	DefaultUploadProtocol                 string                            `mapstructure:"default_upload_protocol"`
 	UserAgentChunkingMap                  map[string]string                 `mapstructure:"user_agent_chunking_map"`
 	SharePrefix                           string                            `mapstructure:"share_prefix"`
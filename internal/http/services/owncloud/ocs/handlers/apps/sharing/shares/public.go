@@ -46,13 +46,13 @@ import (
 
 var _defaultPublicLinkPermission = 1
 
-func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request, statInfo *provider.ResourceInfo) (*link.PublicShare, *ocsError) {
+func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request, statInfo *provider.ResourceInfo) (*link.PublicShare, *publicshare.Usage, *ocsError) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 
 	c, err := h.getClient()
 	if err != nil {
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaServerError.StatusCode,
 			Message: "error getting grpc gateway client",
 			Error:   err,
@@ -61,7 +61,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 
 	permKey, err := permKeyFromRequest(r, h)
 	if err != nil {
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaBadRequest.StatusCode,
 			Message: "Could not read permission from request",
 			Error:   err,
@@ -72,14 +72,14 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 	if permKey != nil && *permKey != 0 {
 		ok, err := utils.CheckPermission(ctx, permission.WritePublicLink, c)
 		if err != nil {
-			return nil, &ocsError{
+			return nil, nil, &ocsError{
 				Code:    response.MetaServerError.StatusCode,
 				Message: "failed to check user permission",
 				Error:   err,
 			}
 		}
 		if !ok {
-			return nil, &ocsError{
+			return nil, nil, &ocsError{
 				Code:    response.MetaForbidden.StatusCode,
 				Message: "user is not allowed to create a public link",
 			}
@@ -88,7 +88,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 
 	err = r.ParseForm()
 	if err != nil {
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaBadRequest.StatusCode,
 			Message: "Could not parse form from request",
 			Error:   err,
@@ -102,14 +102,14 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		req := link.ListPublicSharesRequest{Filters: f}
 		res, err := c.ListPublicShares(ctx, &req)
 		if err != nil {
-			return nil, &ocsError{
+			return nil, nil, &ocsError{
 				Code:    response.MetaServerError.StatusCode,
 				Message: "could not list public links",
 				Error:   err,
 			}
 		}
 		if res.Status.Code != rpc.Code_CODE_OK {
-			return nil, &ocsError{
+			return nil, nil, &ocsError{
 				Code:    int(res.Status.GetCode()),
 				Message: "could not list public links",
 			}
@@ -117,7 +117,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 
 		for _, l := range res.GetShare() {
 			if l.Quicklink {
-				return l, nil
+				return l, usageFromOpaque(res.GetOpaque(), l.GetId().GetOpaqueId()), nil
 			}
 		}
 	}
@@ -129,7 +129,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 	}
 	permissions, err := ocPublicPermToCs3(permKey)
 	if err != nil {
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaBadRequest.StatusCode,
 			Message: "Could not create permission from permission key",
 			Error:   err,
@@ -138,7 +138,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 
 	password := r.FormValue("password")
 	if h.enforcePassword(permKey) && len(password) == 0 {
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaBadRequest.StatusCode,
 			Message: "missing required password",
 			Error:   errors.New("missing required password"),
@@ -146,10 +146,11 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 	}
 	if len(password) > 0 {
 		if err := h.passwordValidator.Validate(password); err != nil {
-			return nil, &ocsError{
+			return nil, nil, &ocsError{
 				Code:    response.MetaBadRequest.StatusCode,
 				Message: xstrings.FirstRuneToUpper(err.Error()),
 				Error:   fmt.Errorf("password validation failed: %w", err),
+				Data:    violationsData(err),
 			}
 		}
 	}
@@ -165,7 +166,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 
 	if !sufficientPermissions(statInfo.PermissionSet, permissions, true) {
 		response.WriteOCSError(w, r, http.StatusForbidden, "no share permission", nil)
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    http.StatusForbidden,
 			Message: "Cannot set the requested share permissions",
 			Error:   errors.New("cannot set the requested share permissions"),
@@ -187,7 +188,7 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		if expireTimeString[0] != "" {
 			expireTime, err := conversions.ParseTimestamp(expireTimeString[0])
 			if err != nil {
-				return nil, &ocsError{
+				return nil, nil, &ocsError{
 					Code:    response.MetaBadRequest.StatusCode,
 					Message: err.Error(),
 					Error:   err,
@@ -207,25 +208,46 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		},
 	}
 
+	limits, err := limitsFromRequest(r)
+	if err != nil {
+		return nil, nil, &ocsError{
+			Code:    response.MetaBadRequest.StatusCode,
+			Message: err.Error(),
+			Error:   err,
+		}
+	}
+	if limits != nil {
+		req.Opaque = utils.AppendJSONToOpaque(req.Opaque, publicshare.LimitsOpaqueKey, limits)
+	}
+
 	createRes, err := c.CreatePublicShare(ctx, &req)
 	if err != nil {
 		log.Debug().Err(err).Str("createShare", "shares").Msgf("error creating a public share to resource id: %v", statInfo.GetId())
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaServerError.StatusCode,
 			Message: "error creating public share",
 			Error:   fmt.Errorf("error creating a public share to resource id: %v", statInfo.GetId()),
 		}
 	}
 
+	if d := statusViolationsData(createRes.GetStatus()); d != nil {
+		return nil, nil, &ocsError{
+			Code:    response.MetaBadRequest.StatusCode,
+			Message: xstrings.FirstRuneToUpper(createRes.GetStatus().GetMessage()),
+			Error:   errors.New("password validation failed"),
+			Data:    d,
+		}
+	}
+
 	if createRes.Status.Code != rpc.Code_CODE_OK {
 		log.Debug().Err(errors.New("create public share failed")).Str("shares", "createShare").Msgf("create public share failed with status code: %v", createRes.Status.Code.String())
-		return nil, &ocsError{
+		return nil, nil, &ocsError{
 			Code:    response.MetaServerError.StatusCode,
 			Message: "grpc create public share request failed",
 			Error:   nil,
 		}
 	}
-	return createRes.Share, nil
+	return createRes.Share, usageFromOpaque(createRes.GetOpaque(), ""), nil
 }
 
 func (h *Handler) listPublicShares(r *http.Request, filters []*link.ListPublicSharesRequest_Filter) ([]*conversions.ShareData, *rpc.Status, error) {
@@ -260,6 +282,7 @@ func (h *Handler) listPublicShares(r *http.Request, filters []*link.ListPublicSh
 			}
 
 			sData := conversions.PublicShare2ShareData(share, r, h.publicURL)
+			addUsage(sData, usageFromOpaque(res.GetOpaque(), share.GetId().GetOpaqueId()))
 
 			sData.Name = share.DisplayName
 
@@ -483,7 +506,7 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 		// skip validation if the clear password scenario
 		if len(newPassword[0]) > 0 {
 			if err := h.passwordValidator.Validate(newPassword[0]); err != nil {
-				response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, xstrings.FirstRuneToUpper(err.Error()), err)
+				response.WriteOCSData(w, r, response.Meta{Status: "error", StatusCode: response.MetaBadRequest.StatusCode, Message: xstrings.FirstRuneToUpper(err.Error())}, violationsData(err), err)
 				return
 			}
 		}
@@ -497,11 +520,33 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 		})
 	}
 
+	// Usage limits
+	limits, err := limitsFromRequest(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), err)
+		return
+	}
+	var limitsOpaque *types.Opaque
+	if limits != nil {
+		updatesFound = true
+		limitsOpaque = utils.AppendJSONToOpaque(nil, publicshare.LimitsOpaqueKey, limits)
+		// the limits are sent along with the last update or on their own
+		if len(updates) == 0 {
+			updates = append(updates, nil)
+		}
+	}
+
 	// Updates are atomical. See: https://github.com/cs3org/cs3apis/pull/67#issuecomment-617651428 so in order to get the latest updated version
+	var usage *publicshare.Usage
 	if len(updates) > 0 {
 		uRes := &link.UpdatePublicShareResponse{Share: share}
 		for k := range updates {
+			var o *types.Opaque
+			if k == len(updates)-1 {
+				o = limitsOpaque
+			}
 			uRes, err = gwC.UpdatePublicShare(r.Context(), &link.UpdatePublicShareRequest{
+				Opaque: o,
 				Ref: &link.PublicShareReference{
 					Spec: &link.PublicShareReference_Id{
 						Id: &link.PublicShareId{
@@ -516,6 +561,10 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "Error sending update request to public link provider", err)
 				return
 			}
+			if d := statusViolationsData(uRes.GetStatus()); d != nil {
+				response.WriteOCSData(w, r, response.Meta{Status: "error", StatusCode: response.MetaBadRequest.StatusCode, Message: xstrings.FirstRuneToUpper(uRes.GetStatus().GetMessage())}, d, nil)
+				return
+			}
 			if uRes.Status.Code != rpc.Code_CODE_OK {
 				log.Debug().Str("shareID", share.Id.OpaqueId).Msgf("sending update request to public link provider failed: %s", uRes.Status.Message)
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, fmt.Sprintf("Error sending update request to public link provider: %s", uRes.Status.Message), nil)
@@ -523,12 +572,14 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 			}
 		}
 		share = uRes.Share
+		usage = usageFromOpaque(uRes.GetOpaque(), "")
 	} else if !updatesFound {
 		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "No updates specified in request", nil)
 		return
 	}
 
 	s := conversions.PublicShare2ShareData(share, r, h.publicURL)
+	addUsage(s, usage)
 	h.addFileInfo(r.Context(), s, statRes.Info)
 	h.mapUserIds(r.Context(), gwC, s)
 
@@ -717,6 +768,70 @@ func (h *Handler) checkPasswordEnforcement(ctx context.Context, user *userv1beta
 	return nil
 }
 
+// limitsFromRequest reads the usage limits of a public link from the form, nil if none were given.
+// Limits that are not sent are reset.
+func limitsFromRequest(r *http.Request) (*publicshare.Limits, error) {
+	_, hasMaxDownloads := r.Form["max_downloads"]
+	_, hasMaxVisitors := r.Form["max_visitors"]
+	_, hasOneTime := r.Form["one_time"]
+	if !hasMaxDownloads && !hasMaxVisitors && !hasOneTime {
+		return nil, nil
+	}
+
+	l := &publicshare.Limits{}
+	var err error
+	if v := r.FormValue("max_downloads"); v != "" {
+		if l.MaxDownloads, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid max_downloads: %s", v)
+		}
+	}
+	if v := r.FormValue("max_visitors"); v != "" {
+		if l.MaxVisitors, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid max_visitors: %s", v)
+		}
+	}
+	if v := r.FormValue("one_time"); v != "" {
+		if l.OneTime, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid one_time: %s", v)
+		}
+	}
+	return l, nil
+}
+
+// usageFromOpaque reads the usage of a public link from a response opaque.
+// Responses listing several links carry the usages by share id.
+func usageFromOpaque(o *types.Opaque, id string) *publicshare.Usage {
+	if !utils.ExistsInOpaque(o, publicshare.UsageOpaqueKey) {
+		return nil
+	}
+	if id == "" {
+		u := &publicshare.Usage{}
+		if err := utils.ReadJSONFromOpaque(o, publicshare.UsageOpaqueKey, u); err != nil {
+			return nil
+		}
+		return u
+	}
+	usages := map[string]*publicshare.Usage{}
+	if err := utils.ReadJSONFromOpaque(o, publicshare.UsageOpaqueKey, &usages); err != nil {
+		return nil
+	}
+	return usages[id]
+}
+
+// addUsage adds the limits and counters of a public link to the share data
+func addUsage(s *conversions.ShareData, u *publicshare.Usage) {
+	if u == nil || !u.Limited() {
+		return
+	}
+	s.MaxDownloads = u.MaxDownloads
+	s.MaxVisitors = u.MaxVisitors
+	s.OneTime = u.OneTime
+	downloads := u.Downloads
+	visitors := uint64(len(u.Visitors))
+	s.DownloadCount = &downloads
+	s.VisitorCount = &visitors
+}
+
 // TODO: add mapping for user share permissions to role
 
 // Maps oc10 public link permissions to roles
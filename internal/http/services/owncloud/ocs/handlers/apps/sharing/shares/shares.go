@@ -107,6 +107,30 @@ type ocsError struct {
 	Error   error
 	Code    int
 	Message string
+	Data    interface{}
+}
+
+// passwordViolations is the ocs data of responses to requests with a password violating the password policy
+type passwordViolations struct {
+	Violations []*password.Violation `json:"violations" xml:"violations>element"`
+}
+
+// violationsData returns the ocs data for the password violations contained in err
+func violationsData(err error) interface{} {
+	vs := password.Violations(err)
+	if len(vs) == 0 {
+		return nil
+	}
+	return passwordViolations{Violations: vs}
+}
+
+// statusViolationsData returns the ocs data for the password violations carried by a rpc status
+func statusViolationsData(s *rpc.Status) interface{} {
+	vs := password.ViolationsFromStatus(s)
+	if len(vs) == 0 {
+		return nil
+	}
+	return passwordViolations{Violations: vs}
 }
 
 type passwordEnforced struct {
@@ -354,13 +378,14 @@ func (h *Handler) CreateShare(w http.ResponseWriter, r *http.Request) {
 			response.WriteOCSError(w, r, http.StatusForbidden, "No share permission", nil)
 			return
 		}
-		share, ocsErr := h.createPublicLinkShare(w, r, statRes.Info)
+		share, usage, ocsErr := h.createPublicLinkShare(w, r, statRes.Info)
 		if ocsErr != nil {
-			response.WriteOCSError(w, r, ocsErr.Code, ocsErr.Message, ocsErr.Error)
+			response.WriteOCSData(w, r, response.Meta{Status: "error", StatusCode: ocsErr.Code, Message: ocsErr.Message}, ocsErr.Data, ocsErr.Error)
 			return
 		}
 
 		s := conversions.PublicShare2ShareData(share, r, h.publicURL)
+		addUsage(s, usage)
 		h.addFileInfo(ctx, s, statRes.GetInfo())
 		h.addPath(ctx, s, statRes.GetInfo())
 		h.mapUserIds(ctx, client, s)
@@ -602,6 +627,7 @@ func (h *Handler) GetShare(w http.ResponseWriter, r *http.Request) {
 
 	if err == nil && psRes.GetShare() != nil {
 		share = conversions.PublicShare2ShareData(psRes.Share, r, h.publicURL)
+		addUsage(share, usageFromOpaque(psRes.GetOpaque(), ""))
 		resourceID = psRes.Share.ResourceId
 	}
 
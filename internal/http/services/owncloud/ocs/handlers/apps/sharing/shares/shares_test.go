@@ -20,6 +20,7 @@ package shares_test
 
 import (
 	"context"
+	"encoding/json"
 	"encoding/xml"
 	"math/rand"
 	"net/http/httptest"
@@ -41,6 +42,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocs/handlers/apps/sharing/shares"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/owncloud/ocs"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	helpers "github.com/opencloud-eu/reva/v2/pkg/storage/utils/decomposedfs/testhelpers"
@@ -230,6 +232,78 @@ var _ = Describe("The ocs API", func() {
 			})
 		})
 
+		Context("when the link password violates the password policy", func() {
+			BeforeEach(func() {
+				gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+					Status: status.NewOK(context.Background()),
+					Info: &provider.ResourceInfo{
+						Type:  provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+						Path:  "/",
+						Id:    &provider.ResourceId{StorageId: "share1-storageid", OpaqueId: "share1"},
+						Owner: user.Id,
+						PermissionSet: &provider.ResourcePermissions{
+							Stat:                 true,
+							ListContainer:        true,
+							GetPath:              true,
+							GetQuota:             true,
+							InitiateFileDownload: true,
+							AddGrant:             true,
+							ListGrants:           true,
+							ListRecycle:          true,
+							UpdateGrant:          true,
+							RemoveGrant:          true,
+						},
+						Space: &provider.StorageSpace{
+							SpaceType: "project",
+						},
+					},
+				}, nil)
+
+				st := status.NewInvalidArg(context.Background(), "the password was used recently, the last 3 passwords cannot be reused")
+				st.InnerError = password.ViolationsOpaqueEntry(&password.Violation{
+					Code:    password.ViolationReused,
+					Message: st.GetMessage(),
+					Params:  map[string]interface{}{"depth": 3},
+				})
+				gatewayClient.On("CreatePublicShare", mock.Anything, mock.Anything).Return(&link.CreatePublicShareResponse{
+					Status: st,
+				}, nil)
+			})
+
+			It("returns the violations", func() {
+				form := url.Values{}
+				form.Add("shareType", "3")
+				form.Add("path", "/")
+				form.Add("space", "storageid!spaceid")
+				form.Add("permissions", "1")
+				form.Add("password", "Passw0rd!")
+				req := httptest.NewRequest("POST", "/apps/files_sharing/api/v1/shares?format=json", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				req = req.WithContext(ctx)
+
+				w := httptest.NewRecorder()
+				h.CreateShare(w, req)
+
+				res := struct {
+					OCS struct {
+						Meta struct {
+							StatusCode int    `json:"statuscode"`
+							Message    string `json:"message"`
+						} `json:"meta"`
+						Data struct {
+							Violations []*password.Violation `json:"violations"`
+						} `json:"data"`
+					} `json:"ocs"`
+				}{}
+				Expect(json.NewDecoder(w.Result().Body).Decode(&res)).To(Succeed())
+				Expect(res.OCS.Meta.StatusCode).To(Equal(400))
+				Expect(res.OCS.Meta.Message).To(Equal("The password was used recently, the last 3 passwords cannot be reused"))
+				Expect(res.OCS.Data.Violations).To(HaveLen(1))
+				Expect(res.OCS.Data.Violations[0].Code).To(Equal(password.ViolationReused))
+				Expect(res.OCS.Data.Violations[0].Params).To(HaveKeyWithValue("depth", BeNumerically("==", 3)))
+			})
+		})
+
 		Context("when sharing a resource", func() {
 			var (
 				resID = &provider.ResourceId{
@@ -0,0 +1,461 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package users
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	admingrouppb "github.com/cs3org/go-cs3apis/cs3/admin/group/v1beta1"
+	adminuserpb "github.com/cs3org/go-cs3apis/cs3/admin/user/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	cs3identity "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocs/response"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/permission"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+// CreateUser handles POST requests on /cloud/users
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if _, ok := h.authorize(w, r, permission.WriteAccounts); !ok {
+		return
+	}
+
+	username := r.FormValue("userid")
+	if username == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing userid", nil)
+		return
+	}
+
+	client, err := h.adminUserClient()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting user provider client", err)
+		return
+	}
+
+	req := &adminuserpb.CreateUserRequest{
+		User: &cs3identity.User{
+			Username:    username,
+			DisplayName: r.FormValue("displayname"),
+			Mail:        r.FormValue("email"),
+		},
+	}
+	if password := r.FormValue("password"); password != "" {
+		req.Opaque = utils.AppendPlainToOpaque(req.Opaque, "password", password)
+	}
+	res, err := client.CreateUser(ctx, req)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error creating user", err)
+		return
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "error creating user")
+		return
+	}
+
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// EditUser handles PUT requests on /cloud/users/{userid}. Users can change
+// their own display name, email and password, changing other users requires
+// the Accounts.ReadWrite permission. Users changing their own password have to
+// send their current password as oldpassword.
+func (h *Handler) EditUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	currentUser, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return
+	}
+
+	username, err := url.PathUnescape(chi.URLParam(r, "userid"))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "could not unescape username", err)
+		return
+	}
+
+	var gwc gateway.GatewayAPIClient
+	if username == currentUser.Username {
+		if gwc, err = pool.GetGatewayServiceClient(h.gatewayAddr); err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+			return
+		}
+	} else if gwc, ok = h.authorize(w, r, permission.WriteAccounts); !ok {
+		return
+	}
+
+	user, ok := lookupUser(w, r, gwc, username)
+	if !ok {
+		return
+	}
+
+	req := &provisioning.UpdateUserRequest{
+		User: &cs3identity.User{
+			Id:           user.Id,
+			DisplayName:  user.DisplayName,
+			Mail:         user.Mail,
+			MailVerified: user.MailVerified,
+		},
+	}
+
+	value := r.FormValue("value")
+	switch r.FormValue("key") {
+	case "displayname", "display":
+		req.User.DisplayName = value
+	case "email":
+		req.User.Mail = value
+	case "password":
+		if value == "" {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "empty password", nil)
+			return
+		}
+		req.Password = value
+		req.CurrentPassword = r.FormValue("oldpassword")
+	default:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "unsupported key", nil)
+		return
+	}
+
+	client, err := pool.GetUserProvisioningGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.UpdateUser(ctx, req)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error updating user", err)
+		return
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "error updating user")
+		return
+	}
+
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// DeleteUser handles DELETE requests on /cloud/users/{userid}
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gwc, ok := h.authorize(w, r, permission.WriteAccounts)
+	if !ok {
+		return
+	}
+
+	username, err := url.PathUnescape(chi.URLParam(r, "userid"))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "could not unescape username", err)
+		return
+	}
+	if username == ctxpkg.ContextMustGetUser(ctx).Username {
+		response.WriteOCSError(w, r, response.MetaForbidden.StatusCode, "users cannot delete themselves", nil)
+		return
+	}
+
+	user, ok := lookupUser(w, r, gwc, username)
+	if !ok {
+		return
+	}
+
+	client, err := h.adminUserClient()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting user provider client", err)
+		return
+	}
+	res, err := client.DeleteUser(ctx, &adminuserpb.DeleteUserRequest{UserId: user.Id})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error deleting user", err)
+		return
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "error deleting user")
+		return
+	}
+
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// AddToGroup handles POST requests on /cloud/users/{userid}/groups
+func (h *Handler) AddToGroup(w http.ResponseWriter, r *http.Request) {
+	h.updateMembership(w, r, func(ctx context.Context, client admingrouppb.GroupAPIClient, gid *grouppb.GroupId, uid *cs3identity.UserId) (*cs3rpc.Status, error) {
+		res, err := client.AddUserToGroup(ctx, &admingrouppb.AddUserToGroupRequest{GroupId: gid, UserId: uid})
+		return res.GetStatus(), err
+	})
+}
+
+// RemoveFromGroup handles DELETE requests on /cloud/users/{userid}/groups
+func (h *Handler) RemoveFromGroup(w http.ResponseWriter, r *http.Request) {
+	h.updateMembership(w, r, func(ctx context.Context, client admingrouppb.GroupAPIClient, gid *grouppb.GroupId, uid *cs3identity.UserId) (*cs3rpc.Status, error) {
+		res, err := client.RemoveUserFromGroup(ctx, &admingrouppb.RemoveUserFromGroupRequest{GroupId: gid, UserId: uid})
+		return res.GetStatus(), err
+	})
+}
+
+type membershipFunc func(ctx context.Context, client admingrouppb.GroupAPIClient, gid *grouppb.GroupId, uid *cs3identity.UserId) (*cs3rpc.Status, error)
+
+func (h *Handler) updateMembership(w http.ResponseWriter, r *http.Request, fn membershipFunc) {
+	ctx := r.Context()
+	gwc, ok := h.authorize(w, r, permission.WriteGroups)
+	if !ok {
+		return
+	}
+
+	username, err := url.PathUnescape(chi.URLParam(r, "userid"))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "could not unescape username", err)
+		return
+	}
+	groupname := formValue(r, "groupid")
+	if groupname == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing groupid", nil)
+		return
+	}
+
+	user, ok := lookupUser(w, r, gwc, username)
+	if !ok {
+		return
+	}
+	group, ok := lookupGroup(w, r, gwc, groupname)
+	if !ok {
+		return
+	}
+
+	client, err := h.adminGroupClient()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting group provider client", err)
+		return
+	}
+	st, err := fn(ctx, client, group.Id, user.Id)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error updating group members", err)
+		return
+	}
+	if st.GetCode() != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, st, "error updating group members")
+		return
+	}
+
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// CreateGroup handles POST requests on /cloud/groups
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if _, ok := h.authorize(w, r, permission.WriteGroups); !ok {
+		return
+	}
+
+	groupname := r.FormValue("groupid")
+	if groupname == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing groupid", nil)
+		return
+	}
+	displayname := r.FormValue("displayname")
+	if displayname == "" {
+		displayname = groupname
+	}
+
+	client, err := h.adminGroupClient()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting group provider client", err)
+		return
+	}
+	res, err := client.CreateGroup(ctx, &admingrouppb.CreateGroupRequest{
+		Group: &grouppb.Group{
+			GroupName:   groupname,
+			DisplayName: displayname,
+		},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error creating group", err)
+		return
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "error creating group")
+		return
+	}
+
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// DeleteGroup handles DELETE requests on /cloud/groups/{groupid}
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gwc, ok := h.authorize(w, r, permission.WriteGroups)
+	if !ok {
+		return
+	}
+
+	groupname, err := url.PathUnescape(chi.URLParam(r, "groupid"))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "could not unescape group name", err)
+		return
+	}
+	group, ok := lookupGroup(w, r, gwc, groupname)
+	if !ok {
+		return
+	}
+
+	client, err := h.adminGroupClient()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting group provider client", err)
+		return
+	}
+	res, err := client.DeleteGroup(ctx, &admingrouppb.DeleteGroupRequest{GroupId: group.Id})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error deleting group", err)
+		return
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "error deleting group")
+		return
+	}
+
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// authorize checks that the current user has the given permission. It writes
+// an error response and returns false otherwise.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, perm string) (gateway.GatewayAPIClient, bool) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx)
+
+	gwc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return nil, false
+	}
+
+	ok, err := utils.CheckPermission(ctx, perm, gwc)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error checking user permissions")
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error checking user permissions", err)
+		return nil, false
+	}
+	if !ok {
+		sublog.Debug().Interface("user", ctxpkg.ContextMustGetUser(ctx).Id).Str("permission", perm).Msg("user not allowed to manage accounts")
+		response.WriteOCSError(w, r, response.MetaForbidden.StatusCode, "permission denied", nil)
+		return nil, false
+	}
+	return gwc, true
+}
+
+func (h *Handler) adminUserClient() (adminuserpb.UserAPIClient, error) {
+	if h.userProviderAddr == "" {
+		return nil, fmt.Errorf("user provisioning is not configured")
+	}
+	selector, err := pool.AdminUserSelector(h.userProviderAddr)
+	if err != nil {
+		return nil, err
+	}
+	return selector.Next()
+}
+
+func (h *Handler) adminGroupClient() (admingrouppb.GroupAPIClient, error) {
+	if h.groupProviderAddr == "" {
+		return nil, fmt.Errorf("group provisioning is not configured")
+	}
+	selector, err := pool.AdminGroupSelector(h.groupProviderAddr)
+	if err != nil {
+		return nil, err
+	}
+	return selector.Next()
+}
+
+func lookupUser(w http.ResponseWriter, r *http.Request, gwc gateway.GatewayAPIClient, username string) (*cs3identity.User, bool) {
+	res, err := gwc.GetUserByClaim(r.Context(), &cs3identity.GetUserByClaimRequest{
+		Claim:                  "username",
+		Value:                  username,
+		SkipFetchingUserGroups: true,
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error looking up user", err)
+		return nil, false
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "user not found")
+		return nil, false
+	}
+	return res.User, true
+}
+
+func lookupGroup(w http.ResponseWriter, r *http.Request, gwc gateway.GatewayAPIClient, groupname string) (*grouppb.Group, bool) {
+	res, err := gwc.GetGroupByClaim(r.Context(), &grouppb.GetGroupByClaimRequest{
+		Claim:               "group_name",
+		Value:               groupname,
+		SkipFetchingMembers: true,
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error looking up group", err)
+		return nil, false
+	}
+	if res.Status.Code != cs3rpc.Code_CODE_OK {
+		writeStatusError(w, r, res.Status, "group not found")
+		return nil, false
+	}
+	return res.Group, true
+}
+
+func writeStatusError(w http.ResponseWriter, r *http.Request, st *cs3rpc.Status, msg string) {
+	switch st.GetCode() {
+	case cs3rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, msg, nil)
+	case cs3rpc.Code_CODE_ALREADY_EXISTS:
+		response.WriteOCSError(w, r, response.MetaInvalidInput.StatusCode, "already exists", nil)
+	case cs3rpc.Code_CODE_INVALID_ARGUMENT:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, st.Message, nil)
+	case cs3rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaForbidden.StatusCode, "permission denied", nil)
+	case cs3rpc.Code_CODE_UNIMPLEMENTED:
+		response.WriteOCSError(w, r, response.MetaFailure.StatusCode, "not supported by the identity backend", nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, msg, fmt.Errorf("%s", st.Message))
+	}
+}
+
+// formValue returns the named value from the query or the form encoded body.
+// Unlike r.FormValue it also parses the body of DELETE requests.
+func formValue(r *http.Request, key string) string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return v
+	}
+	if r.Method != http.MethodDelete {
+		return r.FormValue(key)
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return ""
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get(key)
+}
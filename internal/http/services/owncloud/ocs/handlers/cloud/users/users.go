@@ -41,12 +41,16 @@ import (
 
 // Handler renders user data for the user id given in the url path
 type Handler struct {
-	gatewayAddr string
+	gatewayAddr       string
+	userProviderAddr  string
+	groupProviderAddr string
 }
 
 // Init initializes this and any contained handlers
 func (h *Handler) Init(c *config.Config) {
 	h.gatewayAddr = c.GatewaySvc
+	h.userProviderAddr = c.UserProviderSvc
+	h.groupProviderAddr = c.GroupProviderSvc
 }
 
 // GetGroups handles GET requests on /cloud/users/groups
@@ -34,11 +34,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocs/response"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	global.Register("ocs", New)
+	cfg.Register("http.services", "ocs", config.Config{})
 }
 
 type svc struct {
@@ -149,8 +151,17 @@ func (s *svc) routerInit(log *zerolog.Logger) error {
 			r.Get("/capabilities", capabilitiesHandler.GetCapabilities)
 			r.Get("/user", userHandler.GetSelf)
 			r.Route("/users", func(r chi.Router) {
+				r.Post("/", usersHandler.CreateUser)
 				r.Get("/{userid}", usersHandler.GetUsers)
+				r.Put("/{userid}", usersHandler.EditUser)
+				r.Delete("/{userid}", usersHandler.DeleteUser)
 				r.Get("/{userid}/groups", usersHandler.GetGroups)
+				r.Post("/{userid}/groups", usersHandler.AddToGroup)
+				r.Delete("/{userid}/groups", usersHandler.RemoveFromGroup)
+			})
+			r.Route("/groups", func(r chi.Router) {
+				r.Post("/", usersHandler.CreateGroup)
+				r.Delete("/{groupid}", usersHandler.DeleteGroup)
 			})
 		})
 	})
@@ -30,11 +30,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	global.Register("preferences", New)
+	cfg.Register("http.services", "preferences", Config{})
 }
 
 // Config holds the config options that for the preferences HTTP service
@@ -24,17 +24,24 @@ import (
 	"contrib.go.opencensus.io/exporter/prometheus"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"go.opencensus.io/stats/view"
 
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	global.Register("prometheus", New)
+	cfg.Register("http.services", "prometheus", config{})
 }
 
-// New returns a new prometheus service
+// New returns a new prometheus service. It serves the OpenCensus views
+// together with the collectors registered with the default prometheus
+// registry, e.g. by the prometheus interceptors. Exemplars are only exposed
+// to scrapers asking for the OpenMetrics format.
 func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
 	conf := &config{}
 	if err := mapstructure.Decode(m, conf); err != nil {
@@ -43,15 +50,20 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 
 	conf.init()
 
+	reg := prom.NewRegistry()
 	pe, err := prometheus.NewExporter(prometheus.Options{
 		Namespace: "revad",
+		Registry:  reg,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "prometheus: error creating exporter")
 	}
 
 	view.RegisterExporter(pe)
-	return &svc{prefix: conf.Prefix, h: pe}, nil
+	h := promhttp.HandlerFor(prom.Gatherers{reg, prom.DefaultGatherer}, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+	return &svc{prefix: conf.Prefix, h: h}, nil
 }
 
 type config struct {
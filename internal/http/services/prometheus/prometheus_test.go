@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	grpcprom "github.com/opencloud-eu/reva/v2/internal/grpc/interceptors/prometheus"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+func TestServesExemplars(t *testing.T) {
+	log := zerolog.Nop()
+	s, err := New(map[string]interface{}{}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interceptor, _, err := grpcprom.NewUnary(map[string]interface{}{"subsystem": "scrape"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceID := trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	info := &grpc.UnaryServerInfo{FullMethod: "/cs3.gateway.v1beta1.GatewayAPI/Stat"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Result().Body)
+
+	exemplar := `# {trace_id="` + traceID.String() + `"}`
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, "reva_scrape_grpc_request_duration_seconds_bucket{") && strings.Contains(line, exemplar) {
+			return
+		}
+	}
+	t.Errorf("no grpc_request_duration_seconds exemplar with trace id %s in:\n%s", traceID, body)
+}
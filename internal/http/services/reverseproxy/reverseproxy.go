@@ -29,11 +29,13 @@ import (
 	"github.com/mitchellh/mapstructure"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	global.Register("reverseproxy", New)
+	cfg.Register("http.services", "reverseproxy", config{})
 }
 
 type proxyRule struct {
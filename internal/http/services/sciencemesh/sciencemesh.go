@@ -32,6 +32,7 @@ import (
 
 func init() {
 	global.Register("sciencemesh", New)
+	cfg.Register("http.services", "sciencemesh", config{})
 }
 
 // New returns a new sciencemesh service.
@@ -28,10 +28,12 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	global.Register(serviceName, New)
+	cfg.Register("http.services", serviceName, config.Configuration{})
 }
 
 type svc struct {
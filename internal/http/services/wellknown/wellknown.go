@@ -30,6 +30,7 @@ import (
 
 func init() {
 	global.Register("wellknown", New)
+	cfg.Register("http.services", "wellknown", config{})
 }
 
 type svc struct {
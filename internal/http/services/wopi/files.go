@@ -0,0 +1,349 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/opencloud-eu/reva/v2/internal/http/services/datagateway"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/status"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/wopi"
+)
+
+const (
+	headerItemVersion     = "X-WOPI-ItemVersion"
+	headerMaxExpectedSize = "X-WOPI-MaxExpectedSize"
+	headerUploadLength    = "Upload-Length"
+)
+
+// fileInfo is the response of the CheckFileInfo operation.
+// https://learn.microsoft.com/en-us/microsoft-365/cloud-storage-partner-program/rest/files/checkfileinfo/checkfileinfo-response
+type fileInfo struct {
+	BaseFileName     string `json:"BaseFileName"`
+	OwnerID          string `json:"OwnerId"`
+	Size             int64  `json:"Size"`
+	UserID           string `json:"UserId"`
+	UserFriendlyName string `json:"UserFriendlyName,omitempty"`
+	Version          string `json:"Version"`
+	LastModifiedTime string `json:"LastModifiedTime,omitempty"`
+
+	ReadOnly                bool `json:"ReadOnly"`
+	UserCanWrite            bool `json:"UserCanWrite"`
+	UserCanRename           bool `json:"UserCanRename"`
+	UserCanNotWriteRelative bool `json:"UserCanNotWriteRelative"`
+	IsAnonymousUser         bool `json:"IsAnonymousUser"`
+
+	SupportsLocks              bool `json:"SupportsLocks"`
+	SupportsGetLock            bool `json:"SupportsGetLock"`
+	SupportsExtendedLockLength bool `json:"SupportsExtendedLockLength"`
+	SupportsUpdate             bool `json:"SupportsUpdate"`
+	SupportsRename             bool `json:"SupportsRename"`
+}
+
+func (s *svc) checkFileInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := claimsFromContext(ctx)
+
+	_, info, ok := s.stat(w, r)
+	if !ok {
+		return
+	}
+
+	canWrite := claims.CanWrite() && info.GetPermissionSet().GetInitiateFileUpload()
+	fi := fileInfo{
+		BaseFileName:     info.GetName(),
+		OwnerID:          info.GetOwner().GetOpaqueId(),
+		Size:             int64(info.GetSize()),
+		UserID:           claims.User.GetOpaqueId(),
+		UserFriendlyName: claims.UserName,
+		Version:          version(info),
+
+		ReadOnly:                !canWrite,
+		UserCanWrite:            canWrite,
+		UserCanRename:           canWrite && info.GetPermissionSet().GetMove(),
+		UserCanNotWriteRelative: !canWrite,
+		IsAnonymousUser:         claims.PublicShare,
+
+		SupportsLocks:              true,
+		SupportsGetLock:            true,
+		SupportsExtendedLockLength: true,
+		SupportsUpdate:             true,
+		SupportsRename:             true,
+	}
+	if info.GetMtime() != nil {
+		fi.LastModifiedTime = utils.TSToTime(info.GetMtime()).UTC().Format(time.RFC3339Nano)
+	}
+	writeJSON(w, r, fi)
+}
+
+func (s *svc) getFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	gwc, info, ok := s.stat(w, r)
+	if !ok {
+		return
+	}
+	if max := r.Header.Get(headerMaxExpectedSize); max != "" {
+		if m, err := strconv.ParseUint(max, 10, 64); err == nil && info.GetSize() > m {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+	}
+
+	res, err := gwc.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: fileRef(info.GetId())})
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error initiating download")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.GetStatus())
+		return
+	}
+	var endpoint, token string
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() == "simple" || (endpoint == "" && p.GetProtocol() == "spaces") {
+			endpoint, token = p.GetDownloadEndpoint(), p.GetToken()
+		}
+	}
+
+	req, err := rhttp.NewRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error creating download request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, token)
+	dres, err := s.client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error downloading file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer dres.Body.Close()
+	if dres.StatusCode != http.StatusOK {
+		log.Error().Int("status", dres.StatusCode).Msg("wopi: error downloading file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if dres.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(dres.ContentLength, 10))
+	}
+	w.Header().Set(headerItemVersion, version(info))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, dres.Body); err != nil {
+		log.Error().Err(err).Msg("wopi: error sending file")
+	}
+}
+
+func (s *svc) putFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	claims := claimsFromContext(ctx)
+
+	if !claims.CanWrite() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	gwc, info, ok := s.stat(w, r)
+	if !ok {
+		return
+	}
+
+	lockID := r.Header.Get(headerLock)
+	current, err := s.currentLock(ctx, gwc, info.GetId())
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error getting lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch {
+	case current != "" && current != lockID:
+		writeConflict(w, current, "file is locked")
+		return
+	case current == "" && info.GetSize() > 0:
+		// unlocked files may only be written when they are empty
+		writeConflict(w, "", "file is not locked")
+		return
+	}
+
+	if err := s.upload(ctx, gwc, fileRef(info.GetId()), r, current); err != nil {
+		s.writeUploadError(w, r, gwc, info.GetId(), err)
+		return
+	}
+
+	if _, info, ok = s.stat(w, r); !ok {
+		return
+	}
+	w.Header().Set(headerItemVersion, version(info))
+	w.WriteHeader(http.StatusOK)
+}
+
+// stat returns the resource the request is for. It writes the error response
+// and returns false if the resource cannot be stated.
+func (s *svc) stat(w http.ResponseWriter, r *http.Request) (gateway.GatewayAPIClient, *provider.ResourceInfo, bool) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	id, err := wopi.ParseFileID(chi.URLParam(r, "fileid"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, nil, false
+	}
+	gwc, err := s.gatewaySelector.Next()
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error selecting gateway")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	res, err := gwc.Stat(ctx, &provider.StatRequest{Ref: fileRef(id)})
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error stating file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.GetStatus())
+		return nil, nil, false
+	}
+	return gwc, res.GetInfo(), true
+}
+
+// upload replaces the content of a file with the body of the request.
+func (s *svc) upload(ctx context.Context, gwc gateway.GatewayAPIClient, ref *provider.Reference, r *http.Request, lockID string) error {
+	body := r.Body
+	length := r.ContentLength
+	if length < 0 {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		body, length = io.NopCloser(bytes.NewReader(b)), int64(len(b))
+	}
+
+	res, err := gwc.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref:    ref,
+		LockId: lockID,
+		Opaque: utils.AppendPlainToOpaque(nil, headerUploadLength, strconv.FormatInt(length, 10)),
+	})
+	if err != nil {
+		return err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return errtypes.NewErrtypeFromStatus(res.GetStatus())
+	}
+	if length == 0 {
+		// initiating the upload already created the empty file
+		return nil
+	}
+
+	var endpoint, token string
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() == "simple" {
+			endpoint, token = p.GetUploadEndpoint(), p.GetToken()
+		}
+	}
+	if endpoint == "" {
+		return errtypes.NotSupported("wopi: simple upload protocol not available")
+	}
+	req, err := rhttp.NewRequest(ctx, http.MethodPut, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, token)
+	req.ContentLength = length
+	ures, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer ures.Body.Close()
+	return errtypes.NewErrtypeFromHTTPStatusCode(ures.StatusCode, "wopi: upload failed")
+}
+
+func (s *svc) writeUploadError(w http.ResponseWriter, r *http.Request, gwc gateway.GatewayAPIClient, id *provider.ResourceId, err error) {
+	ctx := r.Context()
+	switch err.(type) {
+	case errtypes.Locked, errtypes.Aborted, errtypes.PreconditionFailed:
+		// the lock changed in the meantime
+		current, _ := s.currentLock(ctx, gwc, id)
+		writeConflict(w, current, "file is locked")
+	case errtypes.PermissionDenied:
+		w.WriteHeader(http.StatusUnauthorized)
+	case errtypes.InsufficientStorage:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errtypes.NotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error uploading file")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func fileRef(id *provider.ResourceId) *provider.Reference {
+	return &provider.Reference{ResourceId: id, Path: "."}
+}
+
+// version returns the WOPI version of a file, which changes on every write.
+func version(info *provider.ResourceInfo) string {
+	return strings.Trim(info.GetEtag(), `"`)
+}
+
+// writeStatus maps a CS3 status to the response codes of the WOPI protocol.
+func writeStatus(w http.ResponseWriter, r *http.Request, st *rpc.Status) {
+	switch st.GetCode() {
+	case rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+	case rpc.Code_CODE_PERMISSION_DENIED, rpc.Code_CODE_UNAUTHENTICATED:
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		appctx.GetLogger(r.Context()).Error().Str("code", st.GetCode().String()).Str("message", st.GetMessage()).Msg("wopi: unexpected status")
+		w.WriteHeader(status.HTTPStatusFromCode(st.GetCode()))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("wopi: error marshalling response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
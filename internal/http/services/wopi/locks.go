@@ -0,0 +1,236 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+const (
+	headerOverride          = "X-WOPI-Override"
+	headerLock              = "X-WOPI-Lock"
+	headerOldLock           = "X-WOPI-OldLock"
+	headerLockFailureReason = "X-WOPI-LockFailureReason"
+)
+
+// fileOperation dispatches the operations WOPI clients send as POST requests
+// to the file endpoint.
+func (s *svc) fileOperation(w http.ResponseWriter, r *http.Request) {
+	switch r.Header.Get(headerOverride) {
+	case "LOCK":
+		if r.Header.Get(headerOldLock) != "" {
+			s.unlockAndRelock(w, r)
+			return
+		}
+		s.lock(w, r)
+	case "GET_LOCK":
+		s.getLock(w, r)
+	case "REFRESH_LOCK":
+		s.refreshLock(w, r)
+	case "UNLOCK":
+		s.unlock(w, r)
+	case "PUT_RELATIVE":
+		s.putRelativeFile(w, r)
+	case "RENAME_FILE":
+		s.renameFile(w, r)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (s *svc) lock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gwc, info, lockID, ok := s.prepareLock(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := gwc.SetLock(ctx, &provider.SetLockRequest{Ref: fileRef(info.GetId()), Lock: s.newLock(ctx, lockID)})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error setting lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.GetStatus().GetCode() == rpc.Code_CODE_OK {
+		w.Header().Set(headerItemVersion, version(info))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	current, err := s.currentLock(ctx, gwc, info.GetId())
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error getting lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if current != lockID {
+		writeConflict(w, current, "file is locked")
+		return
+	}
+	// locking with the current lock id refreshes the lock
+	s.doRefresh(w, r, gwc, info, lockID, lockID)
+}
+
+func (s *svc) unlockAndRelock(w http.ResponseWriter, r *http.Request) {
+	gwc, info, lockID, ok := s.prepareLock(w, r)
+	if !ok {
+		return
+	}
+	s.doRefresh(w, r, gwc, info, lockID, r.Header.Get(headerOldLock))
+}
+
+func (s *svc) refreshLock(w http.ResponseWriter, r *http.Request) {
+	gwc, info, lockID, ok := s.prepareLock(w, r)
+	if !ok {
+		return
+	}
+	s.doRefresh(w, r, gwc, info, lockID, lockID)
+}
+
+func (s *svc) doRefresh(w http.ResponseWriter, r *http.Request, gwc gateway.GatewayAPIClient, info *provider.ResourceInfo, lockID, existingLockID string) {
+	ctx := r.Context()
+	res, err := gwc.RefreshLock(ctx, &provider.RefreshLockRequest{
+		Ref:            fileRef(info.GetId()),
+		Lock:           s.newLock(ctx, lockID),
+		ExistingLockId: existingLockID,
+	})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error refreshing lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		s.writeLockError(w, r, gwc, info, res.GetStatus())
+		return
+	}
+	w.Header().Set(headerItemVersion, version(info))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *svc) unlock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gwc, info, lockID, ok := s.prepareLock(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := gwc.Unlock(ctx, &provider.UnlockRequest{Ref: fileRef(info.GetId()), Lock: s.newLock(ctx, lockID)})
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error unlocking file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		s.writeLockError(w, r, gwc, info, res.GetStatus())
+		return
+	}
+	w.Header().Set(headerItemVersion, version(info))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *svc) getLock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gwc, info, ok := s.stat(w, r)
+	if !ok {
+		return
+	}
+	current, err := s.currentLock(ctx, gwc, info.GetId())
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error getting lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(headerLock, current)
+	w.WriteHeader(http.StatusOK)
+}
+
+// prepareLock stats the file and returns the lock id of a lock request. Only
+// users that may write the file can lock it.
+func (s *svc) prepareLock(w http.ResponseWriter, r *http.Request) (gateway.GatewayAPIClient, *provider.ResourceInfo, string, bool) {
+	if !claimsFromContext(r.Context()).CanWrite() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, nil, "", false
+	}
+	lockID := r.Header.Get(headerLock)
+	if lockID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+	gwc, info, ok := s.stat(w, r)
+	return gwc, info, lockID, ok
+}
+
+// currentLock returns the id of the lock on a file, or an empty string if the
+// file is not locked.
+func (s *svc) currentLock(ctx context.Context, gwc gateway.GatewayAPIClient, id *provider.ResourceId) (string, error) {
+	res, err := gwc.GetLock(ctx, &provider.GetLockRequest{Ref: fileRef(id)})
+	if err != nil {
+		return "", err
+	}
+	switch res.GetStatus().GetCode() {
+	case rpc.Code_CODE_OK:
+		return res.GetLock().GetLockId(), nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return "", nil
+	default:
+		return "", errtypes.NewErrtypeFromStatus(res.GetStatus())
+	}
+}
+
+func (s *svc) newLock(ctx context.Context, lockID string) *provider.Lock {
+	return &provider.Lock{
+		LockId:     lockID,
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		User:       claimsFromContext(ctx).User,
+		AppName:    s.conf.AppName,
+		Expiration: utils.TimeToTS(time.Now().Add(time.Duration(s.conf.LockExpiration) * time.Second)),
+	}
+}
+
+// writeLockError answers a failed lock operation. Conflicting locks are
+// reported with the id of the current lock.
+func (s *svc) writeLockError(w http.ResponseWriter, r *http.Request, gwc gateway.GatewayAPIClient, info *provider.ResourceInfo, st *rpc.Status) {
+	switch st.GetCode() {
+	case rpc.Code_CODE_ABORTED, rpc.Code_CODE_FAILED_PRECONDITION, rpc.Code_CODE_LOCKED, rpc.Code_CODE_NOT_FOUND:
+		current, err := s.currentLock(r.Context(), gwc, info.GetId())
+		if err != nil {
+			appctx.GetLogger(r.Context()).Error().Err(err).Msg("wopi: error getting lock")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeConflict(w, current, st.GetMessage())
+	default:
+		writeStatus(w, r, st)
+	}
+}
+
+func writeConflict(w http.ResponseWriter, current, reason string) {
+	w.Header().Set(headerLock, current)
+	w.Header().Set(headerLockFailureReason, reason)
+	w.WriteHeader(http.StatusConflict)
+}
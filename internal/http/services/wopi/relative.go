@@ -0,0 +1,255 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/wopi"
+)
+
+const (
+	headerSuggestedTarget      = "X-WOPI-SuggestedTarget"
+	headerRelativeTarget       = "X-WOPI-RelativeTarget"
+	headerOverwriteRelative    = "X-WOPI-OverwriteRelativeTarget"
+	headerValidRelativeTarget  = "X-WOPI-ValidRelativeTarget"
+	headerRequestedName        = "X-WOPI-RequestedName"
+	headerInvalidFileNameError = "X-WOPI-InvalidFileNameError"
+
+	maxFreeNameAttempts       = 100
+	invalidFileNameCharacters = `/\:*?"<>|`
+)
+
+func (s *svc) putRelativeFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if !claimsFromContext(ctx).CanWrite() {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	suggested, relative := r.Header.Get(headerSuggestedTarget), r.Header.Get(headerRelativeTarget)
+	switch {
+	case suggested != "" && relative != "":
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	case suggested == "" && relative == "":
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	gwc, info, ok := s.stat(w, r)
+	if !ok {
+		return
+	}
+	parent := info.GetParentId()
+
+	var name string
+	if suggested != "" {
+		// a suggested target starting with a dot only replaces the extension
+		name = wopi.DecodeUTF7(suggested)
+		if strings.HasPrefix(name, ".") {
+			name = strings.TrimSuffix(info.GetName(), path.Ext(info.GetName())) + name
+		}
+		if !validName(name) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		free, err := s.freeName(ctx, gwc, parent, name)
+		if err != nil {
+			log.Error().Err(err).Msg("wopi: error finding a free file name")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		name = free
+	} else {
+		name = wopi.DecodeUTF7(relative)
+		if !validName(name) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		target, err := s.child(ctx, gwc, parent, name)
+		if err != nil {
+			log.Error().Err(err).Msg("wopi: error stating relative target")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if target != nil {
+			current, err := s.currentLock(ctx, gwc, target.GetId())
+			if err != nil {
+				log.Error().Err(err).Msg("wopi: error getting lock of relative target")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if current != "" || !strings.EqualFold(r.Header.Get(headerOverwriteRelative), "true") {
+				if free, err := s.freeName(ctx, gwc, parent, name); err == nil {
+					w.Header().Set(headerValidRelativeTarget, wopi.EncodeUTF7(free))
+				}
+				writeConflict(w, current, "relative target exists")
+				return
+			}
+		}
+	}
+
+	ref := &provider.Reference{ResourceId: parent, Path: "./" + name}
+	if err := s.upload(ctx, gwc, ref, r, ""); err != nil {
+		s.writeUploadError(w, r, gwc, info.GetId(), err)
+		return
+	}
+	created, err := s.child(ctx, gwc, parent, name)
+	if err != nil || created == nil {
+		log.Error().Err(err).Str("name", name).Msg("wopi: error stating new file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	u, err := s.accessURL(r, created.GetId())
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error creating access token")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, map[string]string{"Name": name, "Url": u})
+}
+
+func (s *svc) renameFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if !claimsFromContext(ctx).CanWrite() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	gwc, info, ok := s.stat(w, r)
+	if !ok {
+		return
+	}
+
+	// the requested name does not include the extension
+	requested := wopi.DecodeUTF7(r.Header.Get(headerRequestedName))
+	target := requested + path.Ext(info.GetName())
+	if requested == "" || !validName(target) {
+		w.Header().Set(headerInvalidFileNameError, "invalid file name")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	lockID := r.Header.Get(headerLock)
+	current, err := s.currentLock(ctx, gwc, info.GetId())
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error getting lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if current != "" && current != lockID {
+		writeConflict(w, current, "file is locked")
+		return
+	}
+
+	res, err := gwc.Move(ctx, &provider.MoveRequest{
+		Source:      fileRef(info.GetId()),
+		Destination: &provider.Reference{ResourceId: info.GetParentId(), Path: "./" + target},
+		LockId:      current,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("wopi: error renaming file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch res.GetStatus().GetCode() {
+	case rpc.Code_CODE_OK:
+		writeJSON(w, r, map[string]string{"Name": requested})
+	case rpc.Code_CODE_ALREADY_EXISTS:
+		w.Header().Set(headerInvalidFileNameError, "file already exists")
+		w.WriteHeader(http.StatusBadRequest)
+	case rpc.Code_CODE_ABORTED, rpc.Code_CODE_FAILED_PRECONDITION, rpc.Code_CODE_LOCKED:
+		s.writeLockError(w, r, gwc, info, res.GetStatus())
+	default:
+		writeStatus(w, r, res.GetStatus())
+	}
+}
+
+// child returns the resource with the given name in a folder, or nil if it
+// does not exist.
+func (s *svc) child(ctx context.Context, gwc gateway.GatewayAPIClient, parent *provider.ResourceId, name string) (*provider.ResourceInfo, error) {
+	res, err := gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: parent, Path: "./" + name}})
+	if err != nil {
+		return nil, err
+	}
+	switch res.GetStatus().GetCode() {
+	case rpc.Code_CODE_OK:
+		return res.GetInfo(), nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	default:
+		return nil, errtypes.NewErrtypeFromStatus(res.GetStatus())
+	}
+}
+
+// freeName returns the name itself or the first "name (n).ext" that does not
+// exist in the folder.
+func (s *svc) freeName(ctx context.Context, gwc gateway.GatewayAPIClient, parent *provider.ResourceId, name string) (string, error) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; i <= maxFreeNameAttempts; i++ {
+		existing, err := s.child(ctx, gwc, parent, candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	return "", errtypes.AlreadyExists(name)
+}
+
+// accessURL returns the WOPISrc of a file with a new access token for the
+// user of the current request.
+func (s *svc) accessURL(r *http.Request, id *provider.ResourceId) (string, error) {
+	ctx := r.Context()
+	claims := claimsFromContext(ctx)
+	fileID := wopi.FileID(id)
+	tkn, err := wopi.NewAccessToken(s.conf.Secret, wopi.Claims{
+		FileID:      fileID,
+		ViewMode:    claims.ViewMode,
+		User:        claims.User,
+		UserName:    claims.UserName,
+		PublicShare: claims.PublicShare,
+	}, ctxpkg.ContextMustGetToken(ctx), claims.ExpiresAt.Time)
+	if err != nil {
+		return "", err
+	}
+	return s.publicURL(r) + "/files/" + fileID + "?access_token=" + url.QueryEscape(tkn), nil
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, invalidFileNameCharacters)
+}
@@ -0,0 +1,288 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package wopi implements a WOPI host on top of the CS3 APIs, so that WOPI
+// clients like Collabora or OnlyOffice can edit files without a separate
+// wopiserver.
+package wopi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
+	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+	"github.com/opencloud-eu/reva/v2/pkg/wopi"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	headerProof     = "X-WOPI-Proof"
+	headerProofOld  = "X-WOPI-ProofOld"
+	headerTimestamp = "X-WOPI-TimeStamp"
+
+	// proofKeysRefresh is the minimum interval between two fetches of the
+	// discovery triggered by requests with an invalid proof.
+	proofKeysRefresh = time.Minute
+)
+
+func init() {
+	global.Register("wopi", New)
+	cfg.Register("http.services", "wopi", config{})
+}
+
+type config struct {
+	Prefix            string `mapstructure:"prefix" docs:"wopi;The prefix of the WOPI endpoints."`
+	GatewaySvc        string `mapstructure:"gatewaysvc" docs:";The address of the gateway service."`
+	Secret            string `mapstructure:"secret" validate:"required" docs:";The secret used to sign the WOPI access tokens. It must match the wopi_host_secret of the wopi app provider."`
+	PublicURL         string `mapstructure:"public_url" docs:";The URL of the WOPI endpoints as seen by the WOPI clients, e.g. https://cloud.example.com/wopi. It is derived from the requests if empty."`
+	DiscoveryURL      string `mapstructure:"discovery_url" docs:";The discovery URL of the WOPI client, used to fetch the keys requests are signed with. Required unless insecure_skip_proof is set."`
+	AppName           string `mapstructure:"app_name" docs:"wopi;The app name set on the locks taken by WOPI clients."`
+	LockExpiration    int    `mapstructure:"lock_expiration" docs:"1800;The number of seconds a WOPI lock is valid without being refreshed."`
+	Insecure          bool   `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when talking to the WOPI client and the data gateway."`
+	InsecureSkipProof bool   `mapstructure:"insecure_skip_proof" docs:"false;Whether to accept requests that are not signed with the proof keys of the WOPI client. Only meant for WOPI clients that do not sign their requests."`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Prefix == "" {
+		c.Prefix = "wopi"
+	}
+	if c.AppName == "" {
+		c.AppName = "wopi"
+	}
+	if c.LockExpiration == 0 {
+		c.LockExpiration = 1800
+	}
+	c.PublicURL = strings.TrimSuffix(c.PublicURL, "/")
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf            *config
+	router          chi.Router
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+	client          *http.Client
+
+	proofMu      sync.Mutex
+	proofKeys    *wopi.ProofKeys
+	proofFetched time.Time
+}
+
+// New returns a new WOPI host service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+	switch {
+	case c.InsecureSkipProof:
+		log.Warn().Msg("wopi: proof keys are not validated, any client holding an access token is accepted")
+	case c.DiscoveryURL == "":
+		return nil, errors.New("wopi: discovery_url is required to validate the proof keys of requests, set insecure_skip_proof to accept unsigned requests")
+	}
+
+	gatewaySelector, err := pool.GatewaySelector(c.GatewaySvc)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: error getting gateway selector")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if c.Insecure {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return newService(&c, gatewaySelector, client), nil
+}
+
+func newService(c *config, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], client *http.Client) *svc {
+	s := &svc{
+		conf:            c,
+		router:          chi.NewRouter(),
+		gatewaySelector: gatewaySelector,
+		client:          client,
+	}
+	s.routerInit()
+	return s
+}
+
+func (s *svc) routerInit() {
+	s.router.Route("/files/{fileid}", func(r chi.Router) {
+		r.Use(s.authenticate)
+		r.Get("/", s.checkFileInfo)
+		r.Post("/", s.fileOperation)
+		r.Get("/contents", s.getFile)
+		r.Post("/contents", s.putFile)
+	})
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints, requests are authenticated with the WOPI
+// access tokens instead of reva tokens.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// unset raw path, otherwise chi uses it to route and then fails to match percent encoded path segments
+		r.URL.RawPath = ""
+		s.router.ServeHTTP(w, r)
+	})
+}
+
+type claimsKey struct{}
+
+func claimsFromContext(ctx context.Context) *wopi.Claims {
+	return ctx.Value(claimsKey{}).(*wopi.Claims)
+}
+
+// authenticate validates the access token and the proof of WOPI requests and
+// sets up the context to call the gateway with the reva token of the user.
+func (s *svc) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+
+		accessToken := r.URL.Query().Get("access_token")
+		claims, revaToken, err := wopi.ParseAccessToken(s.conf.Secret, accessToken)
+		if err != nil {
+			log.Debug().Err(err).Msg("wopi: invalid access token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if claims.FileID != chi.URLParam(r, "fileid") {
+			log.Debug().Str("fileid", chi.URLParam(r, "fileid")).Msg("wopi: access token issued for another file")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if !s.conf.InsecureSkipProof {
+			if err := s.verifyProof(ctx, r, accessToken); err != nil {
+				log.Info().Err(err).Msg("wopi: proof validation failed")
+				if errors.Is(err, wopi.ErrProofInvalid) {
+					w.WriteHeader(http.StatusUnauthorized)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+
+		ctx = ctxpkg.ContextSetToken(ctx, revaToken)
+		ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, revaToken)
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
+		sublog := log.With().Str("fileid", claims.FileID).Logger()
+		ctx = appctx.WithLogger(ctx, &sublog)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// verifyProof checks the proof headers of a request against the proof keys of
+// the WOPI client. The keys are fetched again when the proof does not match,
+// as the client may have rotated them.
+func (s *svc) verifyProof(ctx context.Context, r *http.Request, accessToken string) error {
+	url := s.publicURL(r) + r.URL.Path
+	if r.URL.RawQuery != "" {
+		url += "?" + r.URL.RawQuery
+	}
+	verify := func(keys *wopi.ProofKeys) error {
+		if keys == nil {
+			// the client does not sign its requests
+			return nil
+		}
+		return keys.Verify(accessToken, url, r.Header.Get(headerTimestamp), r.Header.Get(headerProof), r.Header.Get(headerProofOld), time.Now())
+	}
+
+	keys, err := s.getProofKeys(ctx, false)
+	if err != nil {
+		return err
+	}
+	if err := verify(keys); !errors.Is(err, wopi.ErrProofInvalid) {
+		return err
+	}
+	keys, err = s.getProofKeys(ctx, true)
+	if err != nil {
+		return err
+	}
+	return verify(keys)
+}
+
+func (s *svc) getProofKeys(ctx context.Context, refresh bool) (*wopi.ProofKeys, error) {
+	s.proofMu.Lock()
+	defer s.proofMu.Unlock()
+
+	if !s.proofFetched.IsZero() && (!refresh || time.Since(s.proofFetched) < proofKeysRefresh) {
+		return s.proofKeys, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.conf.DiscoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: error fetching discovery")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wopi: error fetching discovery: %s", res.Status)
+	}
+	keys, err := wopi.ParseProofKeys(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.proofKeys, s.proofFetched = keys, time.Now()
+	return keys, nil
+}
+
+// publicURL returns the base URL of the WOPI endpoints.
+func (s *svc) publicURL(r *http.Request) string {
+	if s.conf.PublicURL != "" {
+		return s.conf.PublicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + "/" + s.conf.Prefix
+}
@@ -0,0 +1,565 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/internal/http/services/datagateway"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/wopi"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeFile struct {
+	id      string
+	parent  string
+	name    string
+	data    []byte
+	version int
+	lock    string
+}
+
+// fakeGateway keeps the files of a single folder in memory and serves their
+// content with a minimal data gateway.
+type fakeGateway struct {
+	gateway.GatewayAPIClient
+
+	mu    sync.Mutex
+	srv   *httptest.Server
+	files map[string]*fakeFile
+	next  int
+}
+
+func newFakeGateway() *fakeGateway {
+	g := &fakeGateway{files: map[string]*fakeFile{
+		"root": {id: "root"},
+	}}
+	g.srv = httptest.NewServer(http.HandlerFunc(g.serveData))
+	return g
+}
+
+func (g *fakeGateway) Next(...pool.Option) (gateway.GatewayAPIClient, error) {
+	return g, nil
+}
+
+func (g *fakeGateway) add(name, content string) *provider.ResourceId {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f := g.create("root", name)
+	f.data = []byte(content)
+	return resourceID(f.id)
+}
+
+func (g *fakeGateway) create(parent, name string) *fakeFile {
+	g.next++
+	f := &fakeFile{id: fmt.Sprintf("file-%d", g.next), parent: parent, name: name}
+	g.files[f.id] = f
+	return f
+}
+
+// file returns a copy of a file.
+func (g *fakeGateway) file(id *provider.ResourceId) fakeFile {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return *g.files[id.GetOpaqueId()]
+}
+
+// lookup resolves a reference relative to a file or a folder.
+func (g *fakeGateway) lookup(ref *provider.Reference) (parent string, f *fakeFile) {
+	if ref.GetPath() == "." {
+		return "", g.files[ref.GetResourceId().GetOpaqueId()]
+	}
+	parent, name := ref.GetResourceId().GetOpaqueId(), strings.TrimPrefix(ref.GetPath(), "./")
+	for _, f := range g.files {
+		if f.parent == parent && f.name == name {
+			return parent, f
+		}
+	}
+	return parent, nil
+}
+
+func (g *fakeGateway) setLock(id *provider.ResourceId, lockID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.files[id.GetOpaqueId()].lock = lockID
+}
+
+func resourceID(id string) *provider.ResourceId {
+	return &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: id}
+}
+
+func st(code rpc.Code) *rpc.Status {
+	return &rpc.Status{Code: code}
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, req *provider.StatRequest, _ ...grpc.CallOption) (*provider.StatResponse, error) {
+	if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(ctxpkg.TokenHeader)) == 0 || md.Get(ctxpkg.TokenHeader)[0] != "reva-token" {
+		return &provider.StatResponse{Status: st(rpc.Code_CODE_UNAUTHENTICATED)}, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, f := g.lookup(req.GetRef())
+	if f == nil {
+		return &provider.StatResponse{Status: st(rpc.Code_CODE_NOT_FOUND)}, nil
+	}
+	return &provider.StatResponse{
+		Status: st(rpc.Code_CODE_OK),
+		Info: &provider.ResourceInfo{
+			Id:       resourceID(f.id),
+			ParentId: resourceID(f.parent),
+			Name:     f.name,
+			Size:     uint64(len(f.data)),
+			Etag:     fmt.Sprintf(`"%s-%d"`, f.id, f.version),
+			Owner:    &userpb.UserId{OpaqueId: "einstein"},
+			PermissionSet: &provider.ResourcePermissions{
+				InitiateFileUpload: true,
+				Move:               true,
+			},
+		},
+	}, nil
+}
+
+func (g *fakeGateway) GetLock(_ context.Context, req *provider.GetLockRequest, _ ...grpc.CallOption) (*provider.GetLockResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, f := g.lookup(req.GetRef())
+	if f == nil || f.lock == "" {
+		return &provider.GetLockResponse{Status: st(rpc.Code_CODE_NOT_FOUND)}, nil
+	}
+	return &provider.GetLockResponse{Status: st(rpc.Code_CODE_OK), Lock: &provider.Lock{LockId: f.lock}}, nil
+}
+
+func (g *fakeGateway) SetLock(_ context.Context, req *provider.SetLockRequest, _ ...grpc.CallOption) (*provider.SetLockResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, f := g.lookup(req.GetRef())
+	if f.lock != "" {
+		return &provider.SetLockResponse{Status: st(rpc.Code_CODE_FAILED_PRECONDITION)}, nil
+	}
+	f.lock = req.GetLock().GetLockId()
+	return &provider.SetLockResponse{Status: st(rpc.Code_CODE_OK)}, nil
+}
+
+func (g *fakeGateway) RefreshLock(_ context.Context, req *provider.RefreshLockRequest, _ ...grpc.CallOption) (*provider.RefreshLockResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, f := g.lookup(req.GetRef())
+	switch {
+	case f.lock == "":
+		return &provider.RefreshLockResponse{Status: st(rpc.Code_CODE_FAILED_PRECONDITION)}, nil
+	case f.lock != req.GetExistingLockId():
+		return &provider.RefreshLockResponse{Status: st(rpc.Code_CODE_ABORTED)}, nil
+	}
+	f.lock = req.GetLock().GetLockId()
+	return &provider.RefreshLockResponse{Status: st(rpc.Code_CODE_OK)}, nil
+}
+
+func (g *fakeGateway) Unlock(_ context.Context, req *provider.UnlockRequest, _ ...grpc.CallOption) (*provider.UnlockResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, f := g.lookup(req.GetRef())
+	switch {
+	case f.lock == "":
+		return &provider.UnlockResponse{Status: st(rpc.Code_CODE_ABORTED)}, nil
+	case f.lock != req.GetLock().GetLockId():
+		return &provider.UnlockResponse{Status: st(rpc.Code_CODE_LOCKED)}, nil
+	}
+	f.lock = ""
+	return &provider.UnlockResponse{Status: st(rpc.Code_CODE_OK)}, nil
+}
+
+func (g *fakeGateway) InitiateFileDownload(_ context.Context, req *provider.InitiateFileDownloadRequest, _ ...grpc.CallOption) (*gateway.InitiateFileDownloadResponse, error) {
+	return &gateway.InitiateFileDownloadResponse{
+		Status: st(rpc.Code_CODE_OK),
+		Protocols: []*gateway.FileDownloadProtocol{
+			{Protocol: "simple", DownloadEndpoint: g.srv.URL, Token: req.GetRef().GetResourceId().GetOpaqueId()},
+		},
+	}, nil
+}
+
+func (g *fakeGateway) InitiateFileUpload(_ context.Context, req *provider.InitiateFileUploadRequest, _ ...grpc.CallOption) (*gateway.InitiateFileUploadResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	parent, f := g.lookup(req.GetRef())
+	if f == nil {
+		f = g.create(parent, strings.TrimPrefix(req.GetRef().GetPath(), "./"))
+	}
+	if f.lock != "" && f.lock != req.GetLockId() {
+		return &gateway.InitiateFileUploadResponse{Status: st(rpc.Code_CODE_LOCKED)}, nil
+	}
+	return &gateway.InitiateFileUploadResponse{
+		Status: st(rpc.Code_CODE_OK),
+		Protocols: []*gateway.FileUploadProtocol{
+			{Protocol: "simple", UploadEndpoint: g.srv.URL, Token: f.id},
+		},
+	}, nil
+}
+
+func (g *fakeGateway) Move(_ context.Context, req *provider.MoveRequest, _ ...grpc.CallOption) (*provider.MoveResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, f := g.lookup(req.GetSource())
+	if f.lock != "" && f.lock != req.GetLockId() {
+		return &provider.MoveResponse{Status: st(rpc.Code_CODE_LOCKED)}, nil
+	}
+	if _, existing := g.lookup(req.GetDestination()); existing != nil {
+		return &provider.MoveResponse{Status: st(rpc.Code_CODE_ALREADY_EXISTS)}, nil
+	}
+	f.name = strings.TrimPrefix(req.GetDestination().GetPath(), "./")
+	return &provider.MoveResponse{Status: st(rpc.Code_CODE_OK)}, nil
+}
+
+func (g *fakeGateway) serveData(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f, ok := g.files[r.Header.Get(datagateway.TokenTransportHeader)]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		_, _ = w.Write(f.data)
+	case http.MethodPut:
+		f.data, _ = io.ReadAll(r.Body)
+		f.version++
+	}
+}
+
+// fakeOffice is a WOPI client signing its requests with proof keys.
+type fakeOffice struct {
+	t         *testing.T
+	key       *rsa.PrivateKey
+	host      string
+	discovery *httptest.Server
+}
+
+func newFakeOffice(t *testing.T) *fakeOffice {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	o := &fakeOffice{t: t, key: key}
+	o.discovery = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		modulus := base64.StdEncoding.EncodeToString(key.N.Bytes())
+		exponent := base64.StdEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		fmt.Fprintf(w, `<wopi-discovery><net-zone name="external-http"/><proof-key modulus="%s" exponent="%s"/></wopi-discovery>`, modulus, exponent)
+	}))
+	return o
+}
+
+func (o *fakeOffice) do(method, fileID, accessToken string, header map[string]string, body string) *http.Response {
+	target := o.host + "/files/" + fileID + "?access_token=" + url.QueryEscape(accessToken)
+
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	require.NoError(o.t, err)
+	ticks := wopi.TimeToTicks(time.Now())
+	hashed := sha256.Sum256(wopi.ExpectedProof(accessToken, target, ticks))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, o.key, crypto.SHA256, hashed[:])
+	require.NoError(o.t, err)
+	req.Header.Set(headerProof, base64.StdEncoding.EncodeToString(sig))
+	req.Header.Set(headerTimestamp, fmt.Sprint(ticks))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(o.t, err)
+	o.t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func (o *fakeOffice) checkFileInfo(fileID, accessToken string) fileInfo {
+	res := o.do(http.MethodGet, fileID, accessToken, nil, "")
+	require.Equal(o.t, http.StatusOK, res.StatusCode)
+	var fi fileInfo
+	require.NoError(o.t, json.NewDecoder(res.Body).Decode(&fi))
+	return fi
+}
+
+func (o *fakeOffice) getFile(fileID, accessToken string) string {
+	res := o.do(http.MethodGet, fileID+"/contents", accessToken, nil, "")
+	require.Equal(o.t, http.StatusOK, res.StatusCode)
+	b, err := io.ReadAll(res.Body)
+	require.NoError(o.t, err)
+	return string(b)
+}
+
+func setup(t *testing.T) (*fakeGateway, *fakeOffice) {
+	gw := newFakeGateway()
+	t.Cleanup(gw.srv.Close)
+	office := newFakeOffice(t)
+	t.Cleanup(office.discovery.Close)
+
+	c := &config{Secret: "secret", DiscoveryURL: office.discovery.URL}
+	c.ApplyDefaults()
+	s := newService(c, gw, http.DefaultClient)
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	c.PublicURL = srv.URL
+	office.host = srv.URL
+	return gw, office
+}
+
+func accessToken(t *testing.T, id *provider.ResourceId, mode appprovider.ViewMode) string {
+	tkn, err := wopi.NewAccessToken("secret", wopi.Claims{
+		FileID:   wopi.FileID(id),
+		ViewMode: mode,
+		User:     &userpb.UserId{OpaqueId: "einstein"},
+		UserName: "Albert Einstein",
+	}, "reva-token", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	return tkn
+}
+
+func TestNewRequiresProofKeys(t *testing.T) {
+	log := zerolog.Nop()
+	_, err := New(map[string]interface{}{"secret": "secret"}, &log)
+	assert.Error(t, err)
+
+	s, err := New(map[string]interface{}{"secret": "secret", "insecure_skip_proof": true}, &log)
+	require.NoError(t, err)
+	assert.NotNil(t, s)
+}
+
+func TestAuthentication(t *testing.T) {
+	gw, office := setup(t)
+	id := gw.add("report.docx", "content")
+	other := gw.add("other.docx", "other")
+	tkn := accessToken(t, id, appprovider.ViewMode_VIEW_MODE_READ_WRITE)
+
+	fi := office.checkFileInfo(wopi.FileID(id), tkn)
+	assert.Equal(t, "report.docx", fi.BaseFileName)
+	assert.Equal(t, int64(7), fi.Size)
+	assert.Equal(t, "einstein", fi.UserID)
+	assert.Equal(t, "Albert Einstein", fi.UserFriendlyName)
+	assert.True(t, fi.UserCanWrite)
+	assert.True(t, fi.SupportsLocks)
+
+	// tokens are bound to a file
+	res := office.do(http.MethodGet, wopi.FileID(other), tkn, nil, "")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = office.do(http.MethodGet, wopi.FileID(id), "invalid", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// unsigned requests are rejected
+	req, err := http.NewRequest(http.MethodGet, office.host+"/files/"+wopi.FileID(id)+"?access_token="+tkn, nil)
+	require.NoError(t, err)
+	unsigned, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer unsigned.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, unsigned.StatusCode)
+
+	// read only tokens cannot write
+	ro := accessToken(t, id, appprovider.ViewMode_VIEW_MODE_VIEW_ONLY)
+	fi = office.checkFileInfo(wopi.FileID(id), ro)
+	assert.False(t, fi.UserCanWrite)
+	assert.True(t, fi.ReadOnly)
+	res = office.do(http.MethodPost, wopi.FileID(id)+"/contents", ro, map[string]string{headerOverride: "PUT"}, "changed")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = office.do(http.MethodPost, wopi.FileID(id), ro, map[string]string{headerOverride: "LOCK", headerLock: "a"}, "")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestLocks(t *testing.T) {
+	gw, office := setup(t)
+	id := gw.add("report.docx", "content")
+	fileID := wopi.FileID(id)
+	tkn := accessToken(t, id, appprovider.ViewMode_VIEW_MODE_READ_WRITE)
+
+	lock := func(override string, header map[string]string) *http.Response {
+		h := map[string]string{headerOverride: override}
+		for k, v := range header {
+			h[k] = v
+		}
+		return office.do(http.MethodPost, fileID, tkn, h, "")
+	}
+
+	// writing an unlocked file that is not empty fails
+	res := office.do(http.MethodPost, fileID+"/contents", tkn, map[string]string{headerOverride: "PUT"}, "changed")
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "", res.Header.Get(headerLock))
+
+	res = lock("LOCK", map[string]string{headerLock: "a"})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "a", gw.file(id).lock)
+
+	// locking again with the same id refreshes the lock
+	res = lock("LOCK", map[string]string{headerLock: "a"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = lock("LOCK", map[string]string{headerLock: "b"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "a", res.Header.Get(headerLock))
+
+	res = lock("GET_LOCK", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "a", res.Header.Get(headerLock))
+
+	res = office.do(http.MethodPost, fileID+"/contents", tkn, map[string]string{headerOverride: "PUT", headerLock: "b"}, "changed")
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "a", res.Header.Get(headerLock))
+
+	res = office.do(http.MethodPost, fileID+"/contents", tkn, map[string]string{headerOverride: "PUT", headerLock: "a"}, "changed")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, fmt.Sprintf("%s-1", id.OpaqueId), res.Header.Get(headerItemVersion))
+	assert.Equal(t, "changed", office.getFile(fileID, tkn))
+
+	res = lock("REFRESH_LOCK", map[string]string{headerLock: "a"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = lock("REFRESH_LOCK", map[string]string{headerLock: "b"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "a", res.Header.Get(headerLock))
+
+	// unlock and relock
+	res = lock("LOCK", map[string]string{headerLock: "b", headerOldLock: "x"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "a", res.Header.Get(headerLock))
+	res = lock("LOCK", map[string]string{headerLock: "b", headerOldLock: "a"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "b", gw.file(id).lock)
+
+	res = lock("UNLOCK", map[string]string{headerLock: "a"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "b", res.Header.Get(headerLock))
+	res = lock("UNLOCK", map[string]string{headerLock: "b"})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = lock("GET_LOCK", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "", res.Header.Get(headerLock))
+
+	res = lock("UNLOCK", map[string]string{headerLock: "b"})
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	res = lock("UNKNOWN", nil)
+	assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+}
+
+func TestPutRelativeFile(t *testing.T) {
+	gw, office := setup(t)
+	id := gw.add("report.docx", "content")
+	gw.add("existing.pdf", "pdf")
+	fileID := wopi.FileID(id)
+	tkn := accessToken(t, id, appprovider.ViewMode_VIEW_MODE_READ_WRITE)
+
+	putRelative := func(header map[string]string, body string) *http.Response {
+		header[headerOverride] = "PUT_RELATIVE"
+		return office.do(http.MethodPost, fileID, tkn, header, body)
+	}
+	created := func(res *http.Response) (string, string) {
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var body struct{ Name, Url string }
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		u, err := url.Parse(body.Url)
+		require.NoError(t, err)
+		return body.Name, u.Query().Get("access_token")
+	}
+
+	// a suggested extension keeps the name of the file
+	name, newToken := created(putRelative(map[string]string{headerSuggestedTarget: ".pdf"}, "exported"))
+	assert.Equal(t, "report.pdf", name)
+	claims, _, err := wopi.ParseAccessToken("secret", newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "exported", office.getFile(claims.FileID, newToken))
+
+	// suggested names are made unique
+	name, _ = created(putRelative(map[string]string{headerSuggestedTarget: wopi.EncodeUTF7("report.pdf")}, "again"))
+	assert.Equal(t, "report (1).pdf", name)
+
+	// relative targets are not overwritten unless requested
+	res := putRelative(map[string]string{headerRelativeTarget: "existing.pdf"}, "new")
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "existing (1).pdf", wopi.DecodeUTF7(res.Header.Get(headerValidRelativeTarget)))
+
+	name, _ = created(putRelative(map[string]string{headerRelativeTarget: "existing.pdf", headerOverwriteRelative: "true"}, "new"))
+	assert.Equal(t, "existing.pdf", name)
+
+	name, _ = created(putRelative(map[string]string{headerRelativeTarget: wopi.EncodeUTF7("Résumé.pdf")}, "cv"))
+	assert.Equal(t, "Résumé.pdf", name)
+
+	res = putRelative(map[string]string{headerSuggestedTarget: ".pdf", headerRelativeTarget: "x.pdf"}, "")
+	assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+	res = putRelative(map[string]string{headerRelativeTarget: "a/b.pdf"}, "")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRenameFile(t *testing.T) {
+	gw, office := setup(t)
+	id := gw.add("report.docx", "content")
+	gw.add("taken.docx", "")
+	fileID := wopi.FileID(id)
+	tkn := accessToken(t, id, appprovider.ViewMode_VIEW_MODE_READ_WRITE)
+
+	rename := func(name, lockID string) *http.Response {
+		return office.do(http.MethodPost, fileID, tkn, map[string]string{
+			headerOverride:      "RENAME_FILE",
+			headerRequestedName: wopi.EncodeUTF7(name),
+			headerLock:          lockID,
+		}, "")
+	}
+
+	res := rename("Résumé", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var body struct{ Name string }
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	assert.Equal(t, "Résumé", body.Name)
+	assert.Equal(t, "Résumé.docx", gw.file(id).name)
+
+	res = rename("in/valid", "")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get(headerInvalidFileNameError))
+
+	res = rename("taken", "")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	gw.setLock(id, "a")
+	res = rename("other", "b")
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "a", res.Header.Get(headerLock))
+	res = rename("other", "a")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "other.docx", gw.file(id).name)
+}
@@ -49,6 +49,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/templates"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	wopipkg "github.com/opencloud-eu/reva/v2/pkg/wopi"
 	"github.com/pkg/errors"
 )
 
@@ -70,6 +71,8 @@ type config struct {
 	AppDesktopOnly            bool   `mapstructure:"app_desktop_only" docs:"false;Specifies if the app can be opened only on desktop."`
 	InsecureConnections       bool   `mapstructure:"insecure_connections"`
 	AppDisableChat            bool   `mapstructure:"app_disable_chat"`
+	WopiHostURL               string `mapstructure:"wopi_host_url" docs:";The URL of reva's built-in WOPI host, e.g. https://cloud.example.com/wopi. If set, files are opened without a wopiserver."`
+	WopiHostSecret            string `mapstructure:"wopi_host_secret" docs:";The secret used to sign the access tokens of the built-in WOPI host. Must match the secret of the wopi http service."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -100,6 +103,10 @@ func New(m map[string]interface{}) (app.Provider, error) {
 	if c.IOPSecret == "" {
 		c.IOPSecret = os.Getenv("REVA_APPPROVIDER_IOPSECRET")
 	}
+	c.WopiHostURL = strings.TrimSuffix(c.WopiHostURL, "/")
+	if c.WopiHostURL != "" && c.WopiHostSecret == "" {
+		return nil, errors.New("wopi: wopi_host_secret is required when using the built-in WOPI host")
+	}
 	c.JWTSecret = sharedconf.GetJWTSecret(c.JWTSecret)
 
 	appURLs, err := getAppURLs(c)
@@ -125,6 +132,10 @@ func New(m map[string]interface{}) (app.Provider, error) {
 func (p *wopiProvider) GetAppURL(ctx context.Context, resource *provider.ResourceInfo, viewMode appprovider.ViewMode, token, language string) (*appprovider.OpenInAppURL, error) {
 	log := appctx.GetLogger(ctx)
 
+	if p.conf.WopiHostURL != "" {
+		return p.getBuiltinAppURL(ctx, resource, viewMode, token, language)
+	}
+
 	ext := path.Ext(resource.Path)
 	wopiurl, err := url.Parse(p.conf.WopiURL)
 	if err != nil {
@@ -247,24 +258,11 @@ func (p *wopiProvider) GetAppURL(ctx context.Context, resource *provider.Resourc
 		return nil, err
 	}
 
-	url, err := url.Parse(result["app-url"].(string))
+	appFullURL, err := p.appURLWithOptions(result["app-url"].(string), nil, language)
 	if err != nil {
 		return nil, err
 	}
 
-	urlQuery := url.Query()
-	if language != "" {
-		urlQuery.Set("ui", language)                  // OnlyOffice
-		urlQuery.Set("lang", covertLangTag(language)) // Collabora, Impact on the default document language of OnlyOffice
-		urlQuery.Set("UI_LLCC", language)             // Office365
-	}
-	if p.conf.AppDisableChat {
-		urlQuery.Set("dchat", "1") // OnlyOffice disable chat
-	}
-
-	url.RawQuery = urlQuery.Encode()
-	appFullURL := url.String()
-
 	// Depending on whether wopi server returned any form parameters or not,
 	// we decide whether the request method is POST or GET
 	var formParams map[string]string
@@ -287,6 +285,99 @@ func (p *wopiProvider) GetAppURL(ctx context.Context, resource *provider.Resourc
 	}, nil
 }
 
+// getBuiltinAppURL returns the app URL for a file served by reva's built-in
+// WOPI host. The access token is issued here, no wopiserver is involved.
+func (p *wopiProvider) getBuiltinAppURL(ctx context.Context, resource *provider.ResourceInfo, viewMode appprovider.ViewMode, token, language string) (*appprovider.OpenInAppURL, error) {
+	log := appctx.GetLogger(ctx)
+
+	ext := path.Ext(resource.GetPath())
+	if ext == "" {
+		ext = path.Ext(resource.GetName())
+	}
+	appURL := p.actionURL(ext, resource.GetSize(), viewMode)
+	if appURL == "" {
+		return nil, errors.New("wopi: neither edit nor view app url found")
+	}
+
+	expiration, err := p.getAccessTokenExpiration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID := wopipkg.FileID(resource.GetId())
+	claims := wopipkg.Claims{
+		FileID:   fileID,
+		ViewMode: viewMode,
+	}
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok {
+		claims.User = u.GetId()
+		if _, ok := u.GetOpaque().GetMap()["public-share-role"]; ok {
+			claims.PublicShare = true
+		} else {
+			claims.UserName = u.GetDisplayName()
+		}
+	}
+	accessToken, err := wopipkg.NewAccessToken(p.conf.WopiHostSecret, claims, token, expiration)
+	if err != nil {
+		return nil, err
+	}
+
+	appFullURL, err := p.appURLWithOptions(appURL, map[string]string{"WOPISrc": p.conf.WopiHostURL + "/files/" + fileID}, language)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msg(fmt.Sprintf("wopi: returning app URL %s", appFullURL))
+	return &appprovider.OpenInAppURL{
+		AppUrl: appFullURL,
+		Method: "POST",
+		FormParameters: map[string]string{
+			"access_token":     accessToken,
+			"access_token_ttl": strconv.FormatInt(expiration.UnixMilli(), 10),
+		},
+	}, nil
+}
+
+// actionURL returns the discovery URL of the action matching the view mode.
+func (p *wopiProvider) actionURL(ext string, size uint64, viewMode appprovider.ViewMode) string {
+	if viewMode == appprovider.ViewMode_VIEW_MODE_READ_WRITE {
+		access := "edit"
+		if _, ok := p.appURLs["editnew"][ext]; ok && size == 0 {
+			access = "editnew"
+		}
+		if u, ok := p.appURLs[access][ext]; ok {
+			return u
+		}
+	}
+	// a view action is always available for the supported extensions, see GetAppURL
+	return p.appURLs["view"][ext]
+}
+
+// appURLWithOptions adds the given parameters and the UI options of the
+// different WOPI clients to an app URL.
+func (p *wopiProvider) appURLWithOptions(appURL string, params map[string]string, language string) (string, error) {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return "", err
+	}
+
+	urlQuery := u.Query()
+	for k, v := range params {
+		urlQuery.Set(k, v)
+	}
+	if language != "" {
+		urlQuery.Set("ui", language)                  // OnlyOffice
+		urlQuery.Set("lang", covertLangTag(language)) // Collabora, Impact on the default document language of OnlyOffice
+		urlQuery.Set("UI_LLCC", language)             // Office365
+	}
+	if p.conf.AppDisableChat {
+		urlQuery.Set("dchat", "1") // OnlyOffice disable chat
+	}
+
+	u.RawQuery = urlQuery.Encode()
+	return u.String(), nil
+}
+
 func (p *wopiProvider) GetAppProviderInfo(ctx context.Context) (*appregistry.ProviderInfo, error) {
 	// Initially we store the mime types in a map to avoid duplicates
 	mimeTypesMap := make(map[string]bool)
@@ -378,20 +469,29 @@ func getAppURLs(c *config) (map[string]map[string]string, error) {
 }
 
 func (p *wopiProvider) getAccessTokenTTL(ctx context.Context) (string, error) {
+	expiration, err := p.getAccessTokenExpiration(ctx)
+	if err != nil {
+		return "", err
+	}
+	// milliseconds since Jan 1, 1970 UTC as required in https://wopi.readthedocs.io/projects/wopirest/en/latest/concepts.html?highlight=access_token_ttl#term-access-token-ttl
+	return strconv.FormatInt(expiration.Unix()*1000, 10), nil
+}
+
+// getAccessTokenExpiration returns the expiration of the reva token in the context.
+func (p *wopiProvider) getAccessTokenExpiration(ctx context.Context) (time.Time, error) {
 	tkn := ctxpkg.ContextMustGetToken(ctx)
 	token, err := jwt.ParseWithClaims(tkn, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(p.conf.JWTSecret), nil
 	})
 	if err != nil {
-		return "", err
+		return time.Time{}, err
 	}
 
 	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		// milliseconds since Jan 1, 1970 UTC as required in https://wopi.readthedocs.io/projects/wopirest/en/latest/concepts.html?highlight=access_token_ttl#term-access-token-ttl
-		return strconv.FormatInt(claims.ExpiresAt.Unix()*1000, 10), nil
+		return claims.ExpiresAt.Time, nil
 	}
 
-	return "", errtypes.InvalidCredentials("wopi: invalid token present in ctx")
+	return time.Time{}, errtypes.InvalidCredentials("wopi: invalid token present in ctx")
 }
 
 func parseWopiDiscovery(body io.Reader) (map[string]map[string]string, error) {
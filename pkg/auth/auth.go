@@ -34,6 +34,13 @@ type Manager interface {
 	Authenticate(ctx context.Context, clientID, clientSecret string) (*user.User, map[string]*authpb.Scope, error)
 }
 
+// PasswordSetter is implemented by managers that can change the passwords of
+// their users. New passwords are validated against the password policy of
+// the manager, violations are returned as password.Violation errors.
+type PasswordSetter interface {
+	SetPassword(ctx context.Context, username, password string) error
+}
+
 // Credentials contains the auth type, client id and secret.
 type Credentials struct {
 	Type         string
@@ -30,11 +30,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("appauth", New)
+	cfg.Register("auth.manager", "appauth", manager{})
 }
 
 type manager struct {
@@ -27,10 +27,12 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	registry.Register("impersonator", New)
+	cfg.Register("auth.manager", "impersonator", struct{}{})
 }
 
 type mgr struct{}
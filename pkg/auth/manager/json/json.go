@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"sync"
 
 	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -31,11 +32,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("json", New)
+	cfg.Register("auth.manager", "json", config{})
 }
 
 // Credentials holds a pair of secret and userid
@@ -53,12 +57,19 @@ type Credentials struct {
 }
 
 type manager struct {
+	sync.RWMutex
+	users       string
+	loaded      os.FileInfo
 	credentials map[string]*Credentials
+	policy      password.Validator
+	history     *password.History
 }
 
 type config struct {
 	// Users holds a path to a file containing json conforming the Users struct
 	Users string `mapstructure:"users"`
+	// PasswordPolicy is applied when changing the password of a user
+	PasswordPolicy password.PolicyConfig `mapstructure:"password_policy"`
 }
 
 func (c *config) init() {
@@ -93,8 +104,24 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
-	m.credentials = map[string]*Credentials{}
-	f, err := os.ReadFile(c.Users)
+	m.users = c.Users
+	if err := m.load(); err != nil {
+		return err
+	}
+	m.policy = c.PasswordPolicy.Validator()
+	if m.history, err = c.PasswordPolicy.History.History(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// load reads the credentials from the users file
+func (m *manager) load() error {
+	f, err := os.ReadFile(m.users)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(m.users)
 	if err != nil {
 		return err
 	}
@@ -106,13 +133,41 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
+	m.credentials = map[string]*Credentials{}
 	for _, c := range credentials {
 		m.credentials[c.Username] = c
 	}
+	m.loaded = info
 	return nil
 }
 
+// reloadIfChanged reloads the credentials when the users file was changed,
+// e.g. by another manager instance setting a password. The loaded credentials
+// are kept if the file can't be read.
+func (m *manager) reloadIfChanged() error {
+	info, err := os.Stat(m.users)
+	if err != nil {
+		return nil
+	}
+	m.RLock()
+	// the file is replaced on changes, comparing the file identity catches
+	// changes within the resolution of the modification time
+	unchanged := os.SameFile(info, m.loaded) && info.ModTime().Equal(m.loaded.ModTime())
+	m.RUnlock()
+	if unchanged {
+		return nil
+	}
+	m.Lock()
+	defer m.Unlock()
+	return m.load()
+}
+
 func (m *manager) Authenticate(ctx context.Context, username string, secret string) (*user.User, map[string]*authpb.Scope, error) {
+	if err := m.reloadIfChanged(); err != nil {
+		return nil, nil, errors.Wrap(err, "error reloading users file")
+	}
+	m.RLock()
+	defer m.RUnlock()
 	if c, ok := m.credentials[username]; ok {
 		if c.Secret == secret {
 			var scopes map[string]*authpb.Scope
@@ -144,3 +199,78 @@ func (m *manager) Authenticate(ctx context.Context, username string, secret stri
 	}
 	return nil, nil, errtypes.InvalidCredentials(username)
 }
+
+// SetPassword changes the secret of a user and persists it in the users file
+func (m *manager) SetPassword(ctx context.Context, username, pw string) error {
+	if err := m.reloadIfChanged(); err != nil {
+		return errors.Wrap(err, "error reloading users file")
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.credentials[username]
+	if !ok {
+		return errtypes.NotFound(username)
+	}
+	if err := m.policy.Validate(pw); err != nil {
+		return err
+	}
+	subject := password.AccountSubject(username)
+	if err := m.history.Check(subject, pw); err != nil {
+		return err
+	}
+
+	if err := m.persistSecret(username, pw); err != nil {
+		return err
+	}
+	c.Secret = pw
+	if info, err := os.Stat(m.users); err == nil {
+		m.loaded = info
+	}
+	return m.history.Remember(subject, pw)
+}
+
+// persistSecret replaces the secret of a user in the users file. The file is
+// patched instead of re-encoding the credentials so that fields unknown to
+// this manager are kept.
+func (m *manager) persistSecret(username, secret string) error {
+	info, err := os.Stat(m.users)
+	if err != nil {
+		return errors.Wrap(err, "error reading users file")
+	}
+	f, err := os.ReadFile(m.users)
+	if err != nil {
+		return errors.Wrap(err, "error reading users file")
+	}
+	entries := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(f, &entries); err != nil {
+		return errors.Wrap(err, "error decoding users file")
+	}
+
+	encoded, err := json.Marshal(secret)
+	if err != nil {
+		return errors.Wrap(err, "error encoding secret")
+	}
+	found := false
+	for _, e := range entries {
+		var name string
+		if err := json.Unmarshal(e["username"], &name); err != nil || name != username {
+			continue
+		}
+		e["secret"] = encoded
+		found = true
+	}
+	if !found {
+		return errtypes.NotFound(username)
+	}
+
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding users")
+	}
+	tmp := m.users + ".tmp"
+	if err := os.WriteFile(tmp, b, info.Mode().Perm()); err != nil {
+		return errors.Wrap(err, "error writing users file")
+	}
+	return errors.Wrap(os.Rename(tmp, m.users), "error replacing users file")
+}
@@ -20,10 +20,14 @@ package json
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"testing"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/auth"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -198,3 +202,64 @@ func TestGetAuthenticatedManager(t *testing.T) {
 		})
 	}
 }
+
+func TestSetPassword(t *testing.T) {
+	users := t.TempDir() + "/users.json"
+	err := os.WriteFile(users, []byte(`[{"username":"einstein","secret":"albert","custom":{"nested":[1,2]}},{"username":"marie","secret":"radioactivity"}]`), 0600)
+	assert.NoError(t, err)
+
+	mgr, err := New(map[string]interface{}{
+		"users": users,
+		"password_policy": map[string]interface{}{
+			"min_characters": 8,
+			"history": map[string]interface{}{
+				"depth": 1,
+				"store": "memory",
+			},
+		},
+	})
+	assert.NoError(t, err)
+	setter := mgr.(auth.PasswordSetter)
+	other, err := New(map[string]interface{}{"users": users})
+	assert.NoError(t, err)
+
+	err = setter.SetPassword(ctx, "einstein", "short")
+	assert.Equal(t, []string{password.ViolationMinCharacters}, violationCodes(err))
+	assert.ErrorIs(t, setter.SetPassword(ctx, "nobody", "relativity"), errtypes.NotFound("nobody"))
+
+	assert.NoError(t, setter.SetPassword(ctx, "einstein", "relativity"))
+	assert.Equal(t, []string{password.ViolationReused}, violationCodes(setter.SetPassword(ctx, "einstein", "relativity")))
+
+	_, _, err = mgr.Authenticate(ctx, "einstein", "albert")
+	assert.Error(t, err)
+	_, _, err = mgr.Authenticate(ctx, "einstein", "relativity")
+	assert.NoError(t, err)
+
+	// other instances pick up the changed secret
+	_, _, err = other.Authenticate(ctx, "einstein", "relativity")
+	assert.NoError(t, err)
+
+	// the changed secret survives a restart
+	reloaded, err := New(map[string]interface{}{"users": users})
+	assert.NoError(t, err)
+	_, _, err = reloaded.Authenticate(ctx, "einstein", "relativity")
+	assert.NoError(t, err)
+	_, _, err = reloaded.Authenticate(ctx, "marie", "radioactivity")
+	assert.NoError(t, err)
+
+	// fields unknown to the manager are kept
+	raw, err := os.ReadFile(users)
+	assert.NoError(t, err)
+	entries := []map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(raw, &entries))
+	assert.Equal(t, map[string]interface{}{"nested": []interface{}{1.0, 2.0}}, entries[0]["custom"])
+	assert.NotContains(t, entries[1], "groups")
+}
+
+func violationCodes(err error) []string {
+	var codes []string
+	for _, v := range password.Violations(err) {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
@@ -48,6 +49,7 @@ type manager struct {
 
 func init() {
 	registry.Register("machine", New)
+	cfg.Register("auth.manager", "machine", manager{})
 }
 
 // Configure parses the map conf
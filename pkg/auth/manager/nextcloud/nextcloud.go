@@ -32,11 +32,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	"github.com/opencloud-eu/reva/v2/pkg/auth"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("nextcloud", New)
+	cfg.Register("auth.manager", "nextcloud", AuthManagerConfig{})
 }
 
 // Manager is the Nextcloud-based implementation of the auth.Manager interface
@@ -43,6 +43,7 @@ import (
 
 func init() {
 	registry.Register("ocmshares", New)
+	cfg.Register("auth.manager", "ocmshares", config{})
 }
 
 type manager struct {
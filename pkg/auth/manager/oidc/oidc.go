@@ -43,12 +43,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 )
 
 func init() {
 	registry.Register("oidc", New)
+	cfg.Register("auth.manager", "oidc", config{})
 }
 
 type mgr struct {
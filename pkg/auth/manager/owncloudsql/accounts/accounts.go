@@ -163,3 +163,15 @@ func (as *Accounts) GetAccountGroups(ctx context.Context, uid string) ([]string,
 	}
 	return groups, nil
 }
+
+// SetPasswordHash replaces the password hash of an account
+func (as *Accounts) SetPasswordHash(ctx context.Context, uid, hash string) error {
+	res, err := as.db.ExecContext(ctx, "UPDATE oc_users SET password=? WHERE uid=?", hash, uid)
+	if err != nil {
+		return errors.Wrap(err, "error updating password")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
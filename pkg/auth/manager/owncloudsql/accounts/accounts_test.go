@@ -265,4 +265,21 @@ var _ = Describe("Accounts", func() {
 			Expect(len(accounts)).To(Equal(0))
 		})
 	})
+
+	Describe("SetPasswordHash", func() {
+		BeforeEach(func() {
+			var err error
+			conn, err = accounts.New("sqlite3", sqldb, false, false, false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("replaces the password hash", func() {
+			Expect(conn.SetPasswordHash(context.Background(), "admin", "1|newhash")).To(Succeed())
+			account, err := conn.GetAccountByLogin(context.Background(), "admin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(account.PasswordHash).To(Equal("1|newhash"))
+		})
+		It("handles not existing account", func() {
+			Expect(conn.SetPasswordHash(context.Background(), "__notexisting__", "1|newhash")).To(MatchError(sql.ErrNoRows))
+		})
+	})
 })
@@ -37,6 +37,8 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 
 	// Provides mysql drivers
@@ -45,11 +47,14 @@ import (
 
 func init() {
 	registry.Register("owncloudsql", NewMysql)
+	cfg.Register("auth.manager", "owncloudsql", config{})
 }
 
 type manager struct {
-	c  *config
-	db *accounts.Accounts
+	c       *config
+	db      *accounts.Accounts
+	policy  password.Validator
+	history *password.History
 }
 
 type config struct {
@@ -63,6 +68,8 @@ type config struct {
 	LegacySalt       string `mapstructure:"legacy_salt"`
 	JoinUsername     bool   `mapstructure:"join_username"`
 	JoinOwnCloudUUID bool   `mapstructure:"join_ownclouduuid"`
+	// PasswordPolicy is applied when changing the password of a user
+	PasswordPolicy password.PolicyConfig `mapstructure:"password_policy"`
 }
 
 // NewMysql returns a new auth manager connection to an owncloud mysql database
@@ -98,6 +105,10 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 	}
 
 	m.c = c
+	m.policy = c.PasswordPolicy.Validator()
+	if m.history, err = c.PasswordPolicy.History.History(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -162,6 +173,30 @@ func (m *manager) Authenticate(ctx context.Context, login, clientSecret string)
 	return u, scopes, nil
 }
 
+// SetPassword stores a new password hash for a user
+func (m *manager) SetPassword(ctx context.Context, login, pw string) error {
+	account, err := m.db.GetAccountByLogin(ctx, login)
+	if err != nil {
+		return errtypes.NotFound(login)
+	}
+	if err := m.policy.Validate(pw); err != nil {
+		return err
+	}
+	subject := password.AccountSubject(account.UserID)
+	if err := m.history.Check(subject, pw); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrap(err, "error hashing password")
+	}
+	if err := m.db.SetPasswordHash(ctx, account.UserID, "1|"+string(hash)); err != nil {
+		return err
+	}
+	return m.history.Remember(subject, pw)
+}
+
 func (m *manager) verify(password, hash string) bool {
 	splitHash := strings.SplitN(hash, "|", 2)
 	switch len(splitHash) {
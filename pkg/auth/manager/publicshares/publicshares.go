@@ -35,11 +35,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("publicshares", New)
+	cfg.Register("auth.manager", "publicshares", config{})
 }
 
 type manager struct {
@@ -10,6 +10,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/auth"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/auth/scope"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
@@ -28,6 +29,7 @@ type manager struct {
 
 func init() {
 	registry.Register("serviceaccounts", New)
+	cfg.Register("auth.manager", "serviceaccounts", conf{})
 }
 
 // Configure parses the map conf
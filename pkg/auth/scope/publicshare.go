@@ -202,3 +202,14 @@ func AddPublicShareScope(share *link.PublicShare, role authpb.Role, scopes map[s
 	}
 	return scopes, nil
 }
+
+// IsPublicShareUploader tells whether the scopes only allow uploading to a
+// public share, as it is the case for the uploaders of secure file drops.
+func IsPublicShareUploader(scopes map[string]*authpb.Scope) bool {
+	for k, s := range scopes {
+		if strings.HasPrefix(k, "publicshare:") && s.GetRole() == authpb.Role_ROLE_UPLOADER {
+			return true
+		}
+	}
+	return false
+}
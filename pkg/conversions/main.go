@@ -177,6 +177,16 @@ type ShareData struct {
 	// PasswordProtected represents a public share is password protected
 	// PasswordProtected bool `json:"password_protected,omitempty" xml:"password_protected,omitempty"`
 	Hidden bool `json:"hidden" xml:"hidden"`
+	// MaxDownloads is the number of downloads after which a public link stops serving files
+	MaxDownloads uint64 `json:"max_downloads,omitempty" xml:"max_downloads,omitempty"`
+	// MaxVisitors is the number of distinct visitors a public link can be opened by
+	MaxVisitors uint64 `json:"max_visitors,omitempty" xml:"max_visitors,omitempty"`
+	// OneTime indicates that the public link is removed after its first use
+	OneTime bool `json:"one_time,omitempty" xml:"one_time,omitempty"`
+	// DownloadCount is the number of downloads counted against the limits of a public link
+	DownloadCount *uint64 `json:"download_count,omitempty" xml:"download_count,omitempty"`
+	// VisitorCount is the number of distinct visitors counted against the limits of a public link
+	VisitorCount *uint64 `json:"visitor_count,omitempty" xml:"visitor_count,omitempty"`
 }
 
 // ShareeData holds share recipient search results
@@ -20,6 +20,7 @@ package loader
 
 import (
 	// Load datatx drivers.
+	_ "github.com/opencloud-eu/reva/v2/pkg/datatx/manager/native"
 	_ "github.com/opencloud-eu/reva/v2/pkg/datatx/manager/rclone"
 	// Add your own here
 )
@@ -0,0 +1,210 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package native
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+	"time"
+
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/pkg/errors"
+	microstore "go-micro.dev/v4/store"
+)
+
+// job is the persisted state of a transfer.
+type job struct {
+	ID         string
+	Status     datatx.Status
+	Error      string `json:",omitempty"`
+	SrcRemote  string
+	SrcPath    string
+	SrcToken   string `json:"-"`
+	DestRemote string
+	DestPath   string
+	DestToken  string `json:"-"`
+	Ctime      uint64
+
+	// Tokens holds the source and destination tokens encrypted with the token secret.
+	Tokens []byte `json:",omitempty"`
+
+	// Version is incremented on every save to detect concurrent modifications.
+	Version uint64
+	// Owner is the id of the instance running the transfer. It owns the transfer until Lease, a unix timestamp,
+	// has passed. The lease is renewed while the transfer is running.
+	Owner string `json:",omitempty"`
+	Lease int64  `json:",omitempty"`
+
+	// Listed is set once the source tree has been enumerated into Files.
+	Listed bool
+	Files  []*file
+
+	// mu guards the file entries while the workers of a running transfer update them.
+	mu sync.Mutex
+}
+
+// file is a single entry of the source tree and the progress of its upload.
+type file struct {
+	// Path is relative to the source and destination path of the transfer.
+	Path string
+	Dir  bool  `json:",omitempty"`
+	Size int64 `json:",omitempty"`
+	// Checksum is the source checksum in the "TYPE:sum" format used by oc:checksums.
+	Checksum string `json:",omitempty"`
+	// UploadURL is the tus upload the data is sent to, including the transfer token.
+	UploadURL string `json:",omitempty"`
+	Offset    int64  `json:",omitempty"`
+	Done      bool   `json:",omitempty"`
+}
+
+// txEndStatuses are the final statuses that are not changed anymore by a running transfer.
+var txEndStatuses = map[datatx.Status]bool{
+	datatx.Status_STATUS_INVALID:                true,
+	datatx.Status_STATUS_DESTINATION_NOT_FOUND:  true,
+	datatx.Status_STATUS_TRANSFER_COMPLETE:      true,
+	datatx.Status_STATUS_TRANSFER_FAILED:        true,
+	datatx.Status_STATUS_TRANSFER_CANCELLED:     true,
+	datatx.Status_STATUS_TRANSFER_CANCEL_FAILED: true,
+	datatx.Status_STATUS_TRANSFER_EXPIRED:       true,
+}
+
+func isEndStatus(s datatx.Status) bool {
+	return txEndStatuses[s]
+}
+
+// interrupted returns true if the transfer is not in an end state but the instance running it stopped renewing its lease.
+func (j *job) interrupted(now time.Time) bool {
+	return !isEndStatus(j.Status) && j.Lease < now.Unix()
+}
+
+// tokens are the credentials of a transfer as they are encrypted in the job.
+type tokens struct {
+	Src  string
+	Dest string
+}
+
+func (j *job) info(s datatx.Status) *datatx.TxInfo {
+	return &datatx.TxInfo{
+		Id:     &datatx.TxId{OpaqueId: j.ID},
+		Status: s,
+		Ctime:  &typespb.Timestamp{Seconds: j.Ctime},
+	}
+}
+
+func (m *manager) load(id string) (*job, error) {
+	recs, err := m.store.Read(id)
+	if err != nil {
+		if err == microstore.ErrNotFound {
+			return nil, errtypes.NotFound("native: transfer not found: " + id)
+		}
+		return nil, errors.Wrap(err, "native: error reading transfer "+id)
+	}
+	if len(recs) == 0 {
+		return nil, errtypes.NotFound("native: transfer not found: " + id)
+	}
+	j := &job{}
+	if err := json.Unmarshal(recs[0].Value, j); err != nil {
+		return nil, errors.Wrap(err, "native: error decoding transfer "+id)
+	}
+	if len(j.Tokens) > 0 {
+		if err := m.openTokens(j); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// save persists the job. It fails with errtypes.Aborted if the persisted job has been
+// modified since the job was loaded, e.g. because it has been cancelled by another instance.
+func (m *manager) save(j *job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return m.saveLocked(j)
+}
+
+// update applies fn to the job and persists the result while holding its lock.
+func (m *manager) update(j *job, fn func()) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn()
+	return m.saveLocked(j)
+}
+
+func (m *manager) saveLocked(j *job) error {
+	stored, err := m.load(j.ID)
+	switch {
+	case errors.As(err, new(errtypes.IsNotFound)):
+		if j.Version != 0 {
+			return errtypes.Aborted("native: transfer " + j.ID + " has been removed")
+		}
+	case err != nil:
+		return err
+	case stored.Version != j.Version:
+		return errtypes.Aborted("native: transfer " + j.ID + " has been modified concurrently")
+	}
+
+	if err := m.sealTokens(j); err != nil {
+		return err
+	}
+	j.Version++
+	data, err := json.Marshal(j)
+	if err != nil {
+		j.Version--
+		return errors.Wrap(err, "native: error encoding transfer "+j.ID)
+	}
+	if err := m.store.Write(&microstore.Record{Key: j.ID, Value: data}); err != nil {
+		j.Version--
+		return errors.Wrap(err, "native: error writing transfer "+j.ID)
+	}
+	return nil
+}
+
+// sealTokens encrypts the tokens of the job so they are not persisted in plaintext.
+func (m *manager) sealTokens(j *job) error {
+	data, err := json.Marshal(tokens{Src: j.SrcToken, Dest: j.DestToken})
+	if err != nil {
+		return errors.Wrap(err, "native: error encoding tokens of transfer "+j.ID)
+	}
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "native: error generating nonce")
+	}
+	j.Tokens = m.aead.Seal(nonce, nonce, data, []byte(j.ID))
+	return nil
+}
+
+func (m *manager) openTokens(j *job) error {
+	n := m.aead.NonceSize()
+	if len(j.Tokens) < n {
+		return errors.New("native: invalid tokens of transfer " + j.ID)
+	}
+	data, err := m.aead.Open(nil, j.Tokens[:n], j.Tokens[n:], []byte(j.ID))
+	if err != nil {
+		return errors.Wrap(err, "native: error decrypting tokens of transfer "+j.ID)
+	}
+	var t tokens
+	if err := json.Unmarshal(data, &t); err != nil {
+		return errors.Wrap(err, "native: error decoding tokens of transfer "+j.ID)
+	}
+	j.SrcToken, j.DestToken = t.Src, t.Dest
+	return nil
+}
@@ -0,0 +1,383 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package native implements a datatx manager that copies data from a remote
+// WebDAV endpoint into local storage through the CS3 data gateways, without
+// relying on an external rclone deployment.
+package native
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	"github.com/google/uuid"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	txdriver "github.com/opencloud-eu/reva/v2/pkg/datatx"
+	registry "github.com/opencloud-eu/reva/v2/pkg/datatx/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
+	"github.com/opencloud-eu/reva/v2/pkg/store"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	microstore "go-micro.dev/v4/store"
+)
+
+func init() {
+	registry.Register("native", New)
+	cfg.Register("datatx.manager", "native", config{})
+}
+
+const (
+	// sourceAuthToken sends the source token in the x-access-token header, as expected by reva based remotes.
+	sourceAuthToken = "x-access-token"
+	// sourceAuthBearer sends the source token as a bearer token, as expected by OCM WebDAV endpoints.
+	sourceAuthBearer = "bearer"
+)
+
+type config struct {
+	GatewaySVC  string `mapstructure:"gatewaysvc"`
+	Insecure    bool   `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when talking to the source and the data gateway."`
+	Parallel    int    `mapstructure:"parallel" docs:"4;Number of files that are transferred concurrently per transfer."`
+	ChunkSize   int64  `mapstructure:"chunk_size" docs:"8388608;Size in bytes of the chunks sent to the data gateway with a single tus PATCH request."`
+	SourceAuth  string `mapstructure:"source_auth" docs:"x-access-token;How the source token is presented to the remote. Either x-access-token or bearer."`
+	TokenSecret string `mapstructure:"token_secret" docs:"the shared jwt_secret;Secret the source and destination tokens are encrypted with before a transfer is persisted."`
+	LeaseTTL    int    `mapstructure:"lease_ttl" docs:"60;Seconds a transfer stays owned by the instance running it without being renewed. Transfers whose owner stopped renewing the lease are considered interrupted and can be retried by any instance."`
+
+	Store             string   `mapstructure:"store" docs:"memory;The store used to persist transfer jobs. Use a persistent store to be able to retry and cancel transfers across restarts."`
+	StoreNodes        []string `mapstructure:"store_nodes"`
+	StoreDatabase     string   `mapstructure:"store_database"`
+	StoreTable        string   `mapstructure:"store_table"`
+	StoreAuthUsername string   `mapstructure:"store_auth_username"`
+	StoreAuthPassword string   `mapstructure:"store_auth_password"`
+}
+
+func (c *config) ApplyDefaults() {
+	c.GatewaySVC = sharedconf.GetGatewaySVC(c.GatewaySVC)
+	if c.Parallel <= 0 {
+		c.Parallel = 4
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 8 * 1024 * 1024
+	}
+	if c.SourceAuth == "" {
+		c.SourceAuth = sourceAuthToken
+	}
+	c.TokenSecret = sharedconf.GetJWTSecret(c.TokenSecret)
+	if c.LeaseTTL <= 0 {
+		c.LeaseTTL = 60
+	}
+	if c.Store == "" {
+		c.Store = store.TypeMemory
+	}
+	if c.StoreDatabase == "" {
+		c.StoreDatabase = "reva"
+	}
+	if c.StoreTable == "" {
+		c.StoreTable = "datatx"
+	}
+}
+
+// running keeps track of a transfer that is currently being executed by this instance.
+type running struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type manager struct {
+	c       *config
+	id      string
+	gateway gateway.GatewayAPIClient
+	client  *http.Client
+	store   microstore.Store
+	aead    cipher.AEAD
+	log     *zerolog.Logger
+
+	mu      sync.Mutex
+	running map[string]*running
+}
+
+// New returns a new native datatx manager.
+func New(m map[string]interface{}) (txdriver.Manager, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, errors.Wrap(err, "native: error decoding config")
+	}
+	if c.SourceAuth != sourceAuthToken && c.SourceAuth != sourceAuthBearer {
+		return nil, errtypes.BadRequest("native: unknown source_auth " + c.SourceAuth)
+	}
+	if c.TokenSecret == "" {
+		return nil, errtypes.BadRequest("native: token_secret or the shared jwt_secret must be set")
+	}
+
+	gw, err := pool.GetGatewayServiceClient(c.GatewaySVC)
+	if err != nil {
+		return nil, errors.Wrap(err, "native: error getting gateway client")
+	}
+
+	s, err := store.New(
+		store.Store(c.Store),
+		microstore.Nodes(c.StoreNodes...),
+		microstore.Database(c.StoreDatabase),
+		microstore.Table(c.StoreTable),
+		store.Authentication(c.StoreAuthUsername, c.StoreAuthPassword),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "native: error creating store")
+	}
+
+	mgr, err := newManager(&c, gw, s)
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.interruptExpired(); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+func newManager(c *config, gw gateway.GatewayAPIClient, s microstore.Store) (*manager, error) {
+	key, err := hkdf.Key(sha256.New, []byte(c.TokenSecret), nil, "reva datatx native transfer tokens", 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "native: error deriving token key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "native: error creating token cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "native: error creating token cipher")
+	}
+
+	client := &http.Client{}
+	if c.Insecure {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	log := appctx.GetLogger(context.Background()).With().Str("pkg", "datatx").Str("driver", "native").Logger()
+	return &manager{
+		c:       c,
+		id:      uuid.New().String(),
+		gateway: gw,
+		client:  client,
+		store:   s,
+		aead:    aead,
+		log:     &log,
+		running: map[string]*running{},
+	}, nil
+}
+
+// interruptExpired marks the transfers whose owner stopped renewing the lease, e.g. because
+// the instance running them has been stopped, as failed so that they can be resumed with
+// RetryTransfer. Transfers run by other instances are not touched.
+func (m *manager) interruptExpired() error {
+	ids, err := m.store.List()
+	if err != nil {
+		return errors.Wrap(err, "native: error listing transfers")
+	}
+	for _, id := range ids {
+		j, err := m.load(id)
+		if err != nil {
+			m.log.Error().Err(err).Str("transfer", id).Msg("could not load transfer")
+			continue
+		}
+		if !j.interrupted(time.Now()) {
+			continue
+		}
+		err = m.update(j, func() {
+			j.Status = datatx.Status_STATUS_TRANSFER_FAILED
+			j.Error = "transfer interrupted"
+		})
+		switch {
+		case errors.As(err, new(errtypes.IsAborted)):
+			// the transfer has been picked up in the meantime
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// lease returns the time the lease of a transfer started now expires at
+func (m *manager) lease() int64 {
+	return time.Now().Add(time.Duration(m.c.LeaseTTL) * time.Second).Unix()
+}
+
+// StartTransfer initiates a transfer job and returns a TxInfo object that includes a unique transfer id.
+func (m *manager) StartTransfer(ctx context.Context, srcRemote string, srcPath string, srcToken string, destRemote string, destPath string, destToken string) (*datatx.TxInfo, error) {
+	j := &job{
+		ID:         uuid.New().String(),
+		Status:     datatx.Status_STATUS_TRANSFER_NEW,
+		SrcRemote:  srcRemote,
+		SrcPath:    srcPath,
+		SrcToken:   srcToken,
+		DestRemote: destRemote,
+		DestPath:   destPath,
+		DestToken:  destToken,
+		Ctime:      uint64(time.Now().Unix()),
+		Owner:      m.id,
+		Lease:      m.lease(),
+	}
+	if err := m.save(j); err != nil {
+		return j.info(datatx.Status_STATUS_INVALID), err
+	}
+
+	info := j.info(j.Status)
+	m.start(j)
+	return info, nil
+}
+
+// GetTransferStatus returns the status of the transfer with the specified transfer id.
+func (m *manager) GetTransferStatus(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	j, err := m.load(transferID)
+	if err != nil {
+		return invalid(transferID), err
+	}
+	if j.interrupted(time.Now()) {
+		return j.info(datatx.Status_STATUS_TRANSFER_FAILED), nil
+	}
+	return j.info(j.Status), nil
+}
+
+// CancelTransfer cancels the transfer with the specified transfer id.
+func (m *manager) CancelTransfer(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	j, err := m.load(transferID)
+	if err != nil {
+		return invalid(transferID), err
+	}
+
+	m.mu.Lock()
+	r, ok := m.running[transferID]
+	m.mu.Unlock()
+	if ok {
+		r.cancel()
+		<-r.done
+		j, err = m.load(transferID)
+		if err != nil {
+			return invalid(transferID), err
+		}
+		return j.info(j.Status), nil
+	}
+
+	if isEndStatus(j.Status) {
+		return j.info(datatx.Status_STATUS_INVALID), errors.New("native: transfer already in end state")
+	}
+	// a transfer run by another instance is stopped by it once it notices the modification
+	j.Status = datatx.Status_STATUS_TRANSFER_CANCELLED
+	if err := m.save(j); err != nil {
+		return j.info(datatx.Status_STATUS_TRANSFER_CANCEL_FAILED), err
+	}
+	return j.info(j.Status), nil
+}
+
+// RetryTransfer resumes the transfer with the specified transfer id.
+// Files that were already transferred are skipped and partially uploaded
+// files continue from the offset the data gateway reports.
+func (m *manager) RetryTransfer(ctx context.Context, transferID string) (*datatx.TxInfo, error) {
+	j, err := m.load(transferID)
+	if err != nil {
+		return invalid(transferID), err
+	}
+
+	m.mu.Lock()
+	_, ok := m.running[transferID]
+	m.mu.Unlock()
+	switch {
+	case ok || (!isEndStatus(j.Status) && !j.interrupted(time.Now())):
+		return j.info(j.Status), errors.New("native: transfer still running, unable to restart")
+	case j.Status == datatx.Status_STATUS_TRANSFER_COMPLETE:
+		return j.info(j.Status), errors.New("native: transfer already completed, unable to restart")
+	}
+
+	err = m.update(j, func() {
+		j.Status = datatx.Status_STATUS_TRANSFER_NEW
+		j.Error = ""
+		j.Owner, j.Lease = m.id, m.lease()
+	})
+	if err != nil {
+		return j.info(datatx.Status_STATUS_INVALID), err
+	}
+
+	info := j.info(j.Status)
+	m.start(j)
+	return info, nil
+}
+
+// start runs the transfer in the background.
+func (m *manager) start(j *job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &running{cancel: cancel, done: make(chan struct{})}
+
+	m.mu.Lock()
+	m.running[j.ID] = r
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.running, j.ID)
+			m.mu.Unlock()
+			cancel()
+			close(r.done)
+		}()
+		go m.renewLease(ctx, cancel, j)
+		m.run(ctx, j)
+	}()
+}
+
+// renewLease keeps the transfer owned by this instance until ctx is done. The transfer is
+// canceled if it has been modified by someone else, e.g. cancelled by another instance.
+func (m *manager) renewLease(ctx context.Context, cancel context.CancelFunc, j *job) {
+	ticker := time.NewTicker(time.Duration(m.c.LeaseTTL) * time.Second / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := m.update(j, func() { j.Lease = m.lease() })
+			switch {
+			case errors.As(err, new(errtypes.IsAborted)):
+				m.log.Info().Str("transfer", j.ID).Msg("transfer has been modified by another instance, stopping")
+				cancel()
+				return
+			case err != nil:
+				m.log.Error().Err(err).Str("transfer", j.ID).Msg("could not renew transfer lease")
+			}
+		}
+	}
+}
+
+func invalid(transferID string) *datatx.TxInfo {
+	return &datatx.TxInfo{
+		Id:     &datatx.TxId{OpaqueId: transferID},
+		Status: datatx.Status_STATUS_INVALID,
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package native
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/store"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+	"google.golang.org/grpc"
+)
+
+// fakeGateway records the uploads initiated through it and serves them with a minimal tus endpoint.
+type fakeGateway struct {
+	gateway.GatewayAPIClient
+
+	mu      sync.Mutex
+	srv     *httptest.Server
+	folders map[string]bool
+	uploads map[string]*fakeUpload // by upload id
+	files   map[string]*fakeUpload // by destination path
+	patches int
+	// failAfter makes PATCH requests fail once the given number of requests was served.
+	failAfter int
+}
+
+type fakeUpload struct {
+	size int64
+	data []byte
+}
+
+func newFakeGateway() *fakeGateway {
+	g := &fakeGateway{
+		folders: map[string]bool{},
+		uploads: map[string]*fakeUpload{},
+		files:   map[string]*fakeUpload{},
+	}
+	g.srv = httptest.NewServer(http.HandlerFunc(g.serveData))
+	return g
+}
+
+func (g *fakeGateway) CreateContainer(_ context.Context, req *provider.CreateContainerRequest, _ ...grpc.CallOption) (*provider.CreateContainerResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.folders[req.GetRef().GetPath()] = true
+	return &provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil
+}
+
+func (g *fakeGateway) InitiateFileUpload(_ context.Context, req *provider.InitiateFileUploadRequest, _ ...grpc.CallOption) (*gateway.InitiateFileUploadResponse, error) {
+	size, _ := strconv.ParseInt(string(req.GetOpaque().GetMap()[headerUploadLength].GetValue()), 10, 64)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := strconv.Itoa(len(g.uploads))
+	u := &fakeUpload{size: size}
+	g.uploads[id] = u
+	g.files[req.GetRef().GetPath()] = u
+	return &gateway.InitiateFileUploadResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileUploadProtocol{
+			{Protocol: "simple", UploadEndpoint: g.srv.URL + "/simple", Token: id},
+			{Protocol: "tus", UploadEndpoint: g.srv.URL + "/tus", Token: id},
+		},
+	}, nil
+}
+
+func (g *fakeGateway) Stat(_ context.Context, req *provider.StatRequest, _ ...grpc.CallOption) (*provider.StatResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	u, ok := g.files[req.GetRef().GetPath()]
+	if !ok {
+		return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	sum := sha1.Sum(u.data)
+	return &provider.StatResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Info: &provider.ResourceInfo{
+			Size: uint64(len(u.data)),
+			Checksum: &provider.ResourceChecksum{
+				Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1,
+				Sum:  hex.EncodeToString(sum[:]),
+			},
+		},
+	}, nil
+}
+
+func (g *fakeGateway) serveData(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	u, ok := g.uploads[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		u.data, _ = io.ReadAll(r.Body)
+	case http.MethodHead:
+		w.Header().Set(headerUploadOffset, strconv.Itoa(len(u.data)))
+	case http.MethodPatch:
+		if g.failAfter > 0 && g.patches >= g.failAfter {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get(headerUploadOffset) != strconv.Itoa(len(u.data)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		g.patches++
+		data, _ := io.ReadAll(r.Body)
+		u.data = append(u.data, data...)
+		w.Header().Set(headerUploadOffset, strconv.Itoa(len(u.data)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newSource(t *testing.T, files map[string]string) *httptest.Server {
+	fs := webdav.NewMemFS()
+	for name, content := range files {
+		dir := path.Dir(name)
+		for i := 1; i <= len(dir); i++ {
+			if i == len(dir) || dir[i] == '/' {
+				if err := fs.Mkdir(context.Background(), dir[:i], 0700); err != nil && !os.IsExist(err) {
+					require.NoError(t, err)
+				}
+			}
+		}
+		f, err := fs.OpenFile(context.Background(), name, os.O_RDWR|os.O_CREATE, 0600)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	srv := httptest.NewServer(&webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()})
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestManager(t *testing.T, gw gateway.GatewayAPIClient) *manager {
+	c := &config{ChunkSize: 4, TokenSecret: "secret"}
+	c.ApplyDefaults()
+	m, err := newManager(c, gw, store.Create(store.Store(store.TypeMemory)))
+	require.NoError(t, err)
+	return m
+}
+
+func waitFor(t *testing.T, m *manager, id string) *datatx.TxInfo {
+	var info *datatx.TxInfo
+	require.Eventually(t, func() bool {
+		var err error
+		info, err = m.GetTransferStatus(context.Background(), id)
+		require.NoError(t, err)
+		return isEndStatus(info.GetStatus())
+	}, 5*time.Second, 10*time.Millisecond)
+	return info
+}
+
+func TestTransferFolder(t *testing.T) {
+	src := newSource(t, map[string]string{
+		"/data/a.txt":     "hello world",
+		"/data/sub/b.txt": "some more content",
+		"/data/empty.txt": "",
+	})
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	m := newTestManager(t, gw)
+
+	info, err := m.StartTransfer(context.Background(), src.URL, "/data", "src-token", "", "/home/dst", "dst-token")
+	require.NoError(t, err)
+	info = waitFor(t, m, info.GetId().GetOpaqueId())
+	j, err := m.load(info.GetId().GetOpaqueId())
+	require.NoError(t, err)
+	require.Equal(t, datatx.Status_STATUS_TRANSFER_COMPLETE, info.GetStatus(), j.Error)
+
+	assert.True(t, gw.folders["/home/dst"])
+	assert.True(t, gw.folders["/home/dst/sub"])
+	assert.Equal(t, "hello world", string(gw.files["/home/dst/a.txt"].data))
+	assert.Equal(t, "some more content", string(gw.files["/home/dst/sub/b.txt"].data))
+	assert.Empty(t, gw.files["/home/dst/empty.txt"].data)
+}
+
+func TestRetryResumesUpload(t *testing.T) {
+	content := "0123456789abcdefghij"
+	src := newSource(t, map[string]string{"/data/file.txt": content})
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	gw.failAfter = 2
+	m := newTestManager(t, gw)
+
+	info, err := m.StartTransfer(context.Background(), src.URL, "/data", "src-token", "", "/home/dst", "dst-token")
+	require.NoError(t, err)
+	id := info.GetId().GetOpaqueId()
+	info = waitFor(t, m, id)
+	require.Equal(t, datatx.Status_STATUS_TRANSFER_FAILED, info.GetStatus())
+	assert.Equal(t, "01234567", string(gw.files["/home/dst/file.txt"].data))
+
+	gw.mu.Lock()
+	gw.failAfter = 0
+	gw.mu.Unlock()
+
+	_, err = m.RetryTransfer(context.Background(), id)
+	require.NoError(t, err)
+	info = waitFor(t, m, id)
+	assert.Equal(t, datatx.Status_STATUS_TRANSFER_COMPLETE, info.GetStatus())
+	assert.Equal(t, content, string(gw.files["/home/dst/file.txt"].data))
+	assert.Len(t, gw.uploads, 1, "the upload should have been resumed")
+	assert.Equal(t, 5, gw.patches)
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	src := newSource(t, map[string]string{"/data/file.txt": "content"})
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	m := newTestManager(t, gw)
+
+	j := &job{ID: "tx", SrcRemote: src.URL, SrcPath: "/data", DestPath: "/home/dst"}
+	require.NoError(t, m.save(j))
+	require.NoError(t, m.transfer(context.Background(), j))
+
+	j.Files[1].Checksum = fmt.Sprintf("%x", sha1.Sum([]byte("other")))
+	assert.Error(t, m.verify(context.Background(), j))
+}
+
+func TestInterruptedTransfersCanBeRetried(t *testing.T) {
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	m := newTestManager(t, gw)
+
+	j := &job{ID: "tx", Status: datatx.Status_STATUS_TRANSFER_IN_PROGRESS}
+	require.NoError(t, m.save(j))
+	running := &job{ID: "running", Status: datatx.Status_STATUS_TRANSFER_IN_PROGRESS, Owner: "other", Lease: time.Now().Add(time.Minute).Unix()}
+	require.NoError(t, m.save(running))
+	require.NoError(t, m.interruptExpired())
+
+	info, err := m.GetTransferStatus(context.Background(), "tx")
+	require.NoError(t, err)
+	assert.Equal(t, datatx.Status_STATUS_TRANSFER_FAILED, info.GetStatus())
+
+	_, err = m.CancelTransfer(context.Background(), "tx")
+	assert.Error(t, err, "a transfer in an end state cannot be cancelled")
+
+	info, err = m.GetTransferStatus(context.Background(), "running")
+	require.NoError(t, err)
+	assert.Equal(t, datatx.Status_STATUS_TRANSFER_IN_PROGRESS, info.GetStatus(), "transfers of other instances are not interrupted")
+	_, err = m.RetryTransfer(context.Background(), "running")
+	assert.Error(t, err, "a transfer running on another instance cannot be retried")
+}
+
+func TestRetryRejectsCompletedTransfers(t *testing.T) {
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	m := newTestManager(t, gw)
+
+	require.NoError(t, m.save(&job{ID: "tx", Status: datatx.Status_STATUS_TRANSFER_COMPLETE}))
+	_, err := m.RetryTransfer(context.Background(), "tx")
+	assert.Error(t, err)
+}
+
+func TestConcurrentModificationsAreDetected(t *testing.T) {
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	m := newTestManager(t, gw)
+
+	j := &job{ID: "tx", Status: datatx.Status_STATUS_TRANSFER_IN_PROGRESS}
+	require.NoError(t, m.save(j))
+	other, err := m.load("tx")
+	require.NoError(t, err)
+	require.NoError(t, m.update(other, func() { other.Status = datatx.Status_STATUS_TRANSFER_CANCELLED }))
+
+	err = m.update(j, func() { j.Status = datatx.Status_STATUS_TRANSFER_COMPLETE })
+	assert.True(t, errors.As(err, new(errtypes.IsAborted)), "expected an aborted error, got %v", err)
+}
+
+func TestTokensAreEncrypted(t *testing.T) {
+	gw := newFakeGateway()
+	defer gw.srv.Close()
+	m := newTestManager(t, gw)
+
+	require.NoError(t, m.save(&job{ID: "tx", SrcToken: "src-token", DestToken: "dst-token"}))
+	recs, err := m.store.Read("tx")
+	require.NoError(t, err)
+	assert.NotContains(t, string(recs[0].Value), "src-token")
+	assert.NotContains(t, string(recs[0].Value), "dst-token")
+
+	j, err := m.load("tx")
+	require.NoError(t, err)
+	assert.Equal(t, "src-token", j.SrcToken)
+	assert.Equal(t, "dst-token", j.DestToken)
+}
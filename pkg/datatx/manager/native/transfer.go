@@ -0,0 +1,446 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package native
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
+	"github.com/pkg/errors"
+	"github.com/studio-b12/gowebdav"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	tusVersion = "1.0.0"
+
+	headerTusResumable = "Tus-Resumable"
+	headerUploadLength = "Upload-Length"
+	headerUploadOffset = "Upload-Offset"
+)
+
+// run executes the transfer and records its outcome.
+func (m *manager) run(ctx context.Context, j *job) {
+	log := m.log.With().Str("transfer", j.ID).Logger()
+
+	if err := m.update(j, func() { j.Status = datatx.Status_STATUS_TRANSFER_IN_PROGRESS }); err != nil {
+		log.Error().Err(err).Msg("could not persist transfer state")
+	}
+
+	err := m.transfer(ctx, j)
+	status := datatx.Status_STATUS_TRANSFER_COMPLETE
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		status = datatx.Status_STATUS_TRANSFER_CANCELLED
+	case errors.As(err, new(errtypes.IsNotFound)):
+		status = datatx.Status_STATUS_DESTINATION_NOT_FOUND
+	default:
+		status = datatx.Status_STATUS_TRANSFER_FAILED
+	}
+	if err != nil {
+		log.Error().Err(err).Str("status", status.String()).Msg("transfer did not complete")
+	}
+
+	if err := m.update(j, func() {
+		j.Status = status
+		if err != nil {
+			j.Error = err.Error()
+		}
+	}); err != nil {
+		log.Error().Err(err).Msg("could not persist transfer state")
+	}
+}
+
+func (m *manager) transfer(ctx context.Context, j *job) error {
+	src := m.sourceClient(j)
+
+	if !j.Listed {
+		files, err := list(src, j.SrcPath)
+		if err != nil {
+			return errors.Wrap(err, "native: error listing source")
+		}
+		if err := m.update(j, func() { j.Files, j.Listed = files, true }); err != nil {
+			return err
+		}
+	}
+
+	if err := m.createFolders(ctx, j); err != nil {
+		return err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(m.c.Parallel)
+	for _, f := range j.Files {
+		if f.Dir || f.Done {
+			continue
+		}
+		f := f
+		g.Go(func() error {
+			return m.upload(gctx, j, src, f)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return m.verify(ctx, j)
+}
+
+func (m *manager) sourceClient(j *job) *gowebdav.Client {
+	c := gowebdav.NewClient(j.SrcRemote, "", "")
+	if m.c.SourceAuth == sourceAuthBearer {
+		c.SetHeader("Authorization", "Bearer "+j.SrcToken)
+	} else {
+		c.SetHeader(ctxpkg.TokenHeader, j.SrcToken)
+	}
+	c.SetTransport(m.client.Transport)
+	return c
+}
+
+// withToken returns a context that authenticates gateway and data gateway requests with the destination token.
+func withToken(ctx context.Context, token string) context.Context {
+	ctx = ctxpkg.ContextSetToken(ctx, token)
+	return metadata.AppendToOutgoingContext(ctx, ctxpkg.TokenHeader, token)
+}
+
+// listProps are the properties requested from the source. gowebdav prefixes
+// every property with the DAV: namespace, so oc:checksums rebinds the prefix.
+// Explicit properties are used instead of allprop, which does not necessarily
+// include oc:checksums and is rejected by some servers.
+var listProps = []string{
+	"resourcetype",
+	"getcontentlength",
+	"getlastmodified",
+	"getetag",
+	"checksums xmlns:d='http://owncloud.org/ns'",
+}
+
+// list enumerates the source tree. Folders always precede their children.
+// If the source is a single file the result only contains that file.
+func list(c *gowebdav.Client, root string) ([]*file, error) {
+	info, err := c.StatWithProps(root, listProps)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []*file{{Size: info.Size(), Checksum: sha1Checksum(info)}}, nil
+	}
+
+	files := []*file{{Dir: true}}
+	for i := 0; i < len(files); i++ {
+		if !files[i].Dir {
+			continue
+		}
+		entries, err := c.ReadDirWithProps(path.Join(root, files[i].Path), listProps)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			f := &file{Path: path.Join(files[i].Path, e.Name()), Dir: e.IsDir()}
+			if !f.Dir {
+				f.Size = e.Size()
+				f.Checksum = sha1Checksum(e)
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// sha1Checksum extracts the SHA1 checksum from the oc:checksums property, if the remote provides it.
+func sha1Checksum(info interface{ Sys() interface{} }) string {
+	props, ok := info.Sys().(gowebdav.Props)
+	if !ok {
+		return ""
+	}
+	checksums := props.GetString(xml.Name{Space: "http://owncloud.org/ns", Local: "checksums"})
+	for _, c := range strings.Fields(checksums) {
+		if t, sum, ok := strings.Cut(c, ":"); ok && strings.EqualFold(t, "sha1") {
+			return strings.ToLower(sum)
+		}
+	}
+	return ""
+}
+
+func (m *manager) createFolders(ctx context.Context, j *job) error {
+	ctx = withToken(ctx, j.DestToken)
+	for _, f := range j.Files {
+		if !f.Dir || f.Done {
+			continue
+		}
+		res, err := m.gateway.CreateContainer(ctx, &provider.CreateContainerRequest{
+			Ref: &provider.Reference{Path: path.Join(j.DestPath, f.Path)},
+		})
+		if err != nil {
+			return errors.Wrap(err, "native: error creating folder")
+		}
+		if code := res.GetStatus().GetCode(); code != rpc.Code_CODE_OK && code != rpc.Code_CODE_ALREADY_EXISTS {
+			return errors.Wrap(errtypes.NewErrtypeFromStatus(res.GetStatus()), "native: error creating folder "+f.Path)
+		}
+		if err := m.update(j, func() { f.Done = true }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upload sends a single file to the data gateway using tus. An upload that was
+// started by a previous attempt is resumed from the offset the server reports.
+func (m *manager) upload(ctx context.Context, j *job, src *gowebdav.Client, f *file) error {
+	dest := path.Join(j.DestPath, f.Path)
+	ctx = withToken(ctx, j.DestToken)
+
+	if f.Size == 0 {
+		// tus only finishes empty uploads on creation, which the gateway does
+		// on our behalf, so empty files are sent with the simple protocol.
+		if err := m.uploadEmpty(ctx, dest); err != nil {
+			return errors.Wrap(err, "native: error uploading "+f.Path)
+		}
+		return m.update(j, func() {
+			f.Done = true
+			if f.Checksum == "" {
+				f.Checksum = hex.EncodeToString(sha1.New().Sum(nil))
+			}
+		})
+	}
+
+	var offset int64
+	resumed := false
+	if f.UploadURL != "" {
+		o, err := m.uploadOffset(ctx, f.UploadURL)
+		if err != nil {
+			m.log.Debug().Err(err).Str("transfer", j.ID).Str("path", f.Path).Msg("could not resume upload, starting over")
+		} else {
+			offset, resumed = o, true
+		}
+	}
+	if !resumed {
+		ep, err := m.initiateUpload(ctx, dest, f.Size, "tus")
+		if err != nil {
+			return errors.Wrap(err, "native: error initiating upload of "+f.Path)
+		}
+		if err := m.update(j, func() { f.UploadURL, f.Offset = ep, 0 }); err != nil {
+			return err
+		}
+	}
+
+	// the checksum is needed for the final verification. If the remote does
+	// not advertise one it is computed while streaming, which requires reading
+	// the source from the beginning.
+	start := offset
+	var h hash.Hash
+	if f.Checksum == "" {
+		h = sha1.New()
+		start = 0
+	}
+	var body io.Reader = bytes.NewReader(nil)
+	if f.Size > start {
+		rc, err := src.ReadStreamRange(path.Join(j.SrcPath, f.Path), start, f.Size-start)
+		if err != nil {
+			return errors.Wrap(err, "native: error reading source "+f.Path)
+		}
+		defer rc.Close()
+		body = rc
+	}
+
+	r := body
+	if h != nil {
+		if _, err := io.CopyN(h, body, offset); err != nil {
+			return errors.Wrap(err, "native: error reading source "+f.Path)
+		}
+		r = io.TeeReader(body, h)
+	}
+
+	buf := make([]byte, min(m.c.ChunkSize, f.Size))
+	for offset < f.Size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(m.c.ChunkSize, f.Size-offset)
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return errors.Wrap(err, "native: error reading source "+f.Path)
+		}
+		next, err := m.patch(ctx, f.UploadURL, buf[:n], offset)
+		if err != nil {
+			return errors.Wrap(err, "native: error uploading "+f.Path)
+		}
+		offset = next
+		// the offset is not persisted for every chunk, a resumed upload asks the data gateway for it
+		j.mu.Lock()
+		f.Offset = offset
+		j.mu.Unlock()
+	}
+
+	return m.update(j, func() {
+		f.Offset, f.UploadURL, f.Done = offset, "", true
+		if h != nil {
+			f.Checksum = hex.EncodeToString(h.Sum(nil))
+		}
+	})
+}
+
+func (m *manager) initiateUpload(ctx context.Context, dest string, size int64, protocol string) (string, error) {
+	res, err := m.gateway.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{Path: dest},
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				headerUploadLength: {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatInt(size, 10)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return "", errtypes.NewErrtypeFromStatus(res.GetStatus())
+	}
+
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() != protocol {
+			continue
+		}
+		// the transfer token is appended to the upload endpoint, the data
+		// gateway pulls it back into the request header.
+		ep := p.GetUploadEndpoint()
+		if p.GetToken() != "" {
+			ep = strings.TrimSuffix(ep, "/") + "/" + p.GetToken()
+		}
+		return ep, nil
+	}
+	return "", errtypes.NotSupported("destination does not support " + protocol + " uploads")
+}
+
+func (m *manager) uploadEmpty(ctx context.Context, dest string) error {
+	ep, err := m.initiateUpload(ctx, dest, 0, "simple")
+	if err != nil {
+		return err
+	}
+	req, err := rhttp.NewRequest(ctx, http.MethodPut, ep, http.NoBody)
+	if err != nil {
+		return err
+	}
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (m *manager) uploadOffset(ctx context.Context, url string) (int64, error) {
+	req, err := rhttp.NewRequest(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(headerTusResumable, tusVersion)
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return strconv.ParseInt(res.Header.Get(headerUploadOffset), 10, 64)
+}
+
+func (m *manager) patch(ctx context.Context, url string, data []byte, offset int64) (int64, error) {
+	req, err := rhttp.NewRequest(ctx, http.MethodPatch, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(headerTusResumable, tusVersion)
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set(headerUploadOffset, strconv.FormatInt(offset, 10))
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		return 0, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	next, err := strconv.ParseInt(res.Header.Get(headerUploadOffset), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if next != offset+int64(len(data)) {
+		return 0, fmt.Errorf("server accepted %d of %d bytes", next-offset, len(data))
+	}
+	return next, nil
+}
+
+// verify compares the size and, when the destination provides one, the SHA1
+// checksum of every transferred file with the source.
+func (m *manager) verify(ctx context.Context, j *job) error {
+	ctx = withToken(ctx, j.DestToken)
+	for _, f := range j.Files {
+		if f.Dir {
+			continue
+		}
+		res, err := m.gateway.Stat(ctx, &provider.StatRequest{
+			Ref: &provider.Reference{Path: path.Join(j.DestPath, f.Path)},
+		})
+		if err != nil {
+			return errors.Wrap(err, "native: error verifying "+f.Path)
+		}
+		if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+			return errors.Wrap(errtypes.NewErrtypeFromStatus(res.GetStatus()), "native: error verifying "+f.Path)
+		}
+		if size := int64(res.GetInfo().GetSize()); size != f.Size {
+			return errtypes.ChecksumMismatch(fmt.Sprintf("native: size mismatch for %s: expected %d, got %d", f.Path, f.Size, size))
+		}
+		cs := res.GetInfo().GetChecksum()
+		if cs.GetType() != provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1 {
+			m.log.Debug().Str("transfer", j.ID).Str("path", f.Path).Msg("destination provides no sha1 checksum, only the size was verified")
+			continue
+		}
+		if !strings.EqualFold(cs.GetSum(), f.Checksum) {
+			return errtypes.ChecksumMismatch(fmt.Sprintf("native: checksum mismatch for %s: expected %s, got %s", f.Path, f.Checksum, cs.GetSum()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package events
+
+import (
+	"encoding/json"
+)
+
+// CacheInvalidated is emitted when entries of a shared cache were changed, so
+// that other instances evict them from their local caches
+type CacheInvalidated struct {
+	Cache    string
+	Database string
+	Table    string
+	Keys     []string
+	// Origin identifies the cache instance that changed the entries
+	Origin string
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (CacheInvalidated) Unmarshal(v []byte) (interface{}, error) {
+	e := CacheInvalidated{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -60,6 +60,26 @@ func (FileUploaded) Unmarshal(v []byte) (interface{}, error) {
 	return e, err
 }
 
+// FileDropLabelOpaqueKey is the opaque key carrying the uploader label of a secure file drop upload
+const FileDropLabelOpaqueKey = "filedrop-label"
+
+// FileDropped is emitted when an upload into a secure file drop has finished
+type FileDropped struct {
+	SpaceOwner *user.UserId
+	Executant  *user.UserId
+	Ref        *provider.Reference
+	Owner      *user.UserId
+	Label      string
+	Timestamp  *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (FileDropped) Unmarshal(v []byte) (interface{}, error) {
+	e := FileDropped{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
 // FileTouched is emitted when a file is uploaded
 type FileTouched struct {
 	SpaceOwner        *user.UserId
@@ -29,27 +29,9 @@ type NatsConfig struct {
 
 // NatsFromConfig returns a nats stream from the given config
 func NatsFromConfig(connName string, disableDurability bool, cfg NatsConfig) (events.Stream, error) {
-	var tlsConf *tls.Config
-	if cfg.EnableTLS {
-		var rootCAPool *x509.CertPool
-		if cfg.TLSRootCACertificate != "" {
-			rootCrtFile, err := os.Open(cfg.TLSRootCACertificate)
-			if err != nil {
-				return nil, err
-			}
-
-			rootCAPool, err = newCertPoolFromPEM(rootCrtFile)
-			if err != nil {
-				return nil, err
-			}
-			cfg.TLSInsecure = false
-		}
-
-		tlsConf = &tls.Config{
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: cfg.TLSInsecure, //nolint:gosec
-			RootCAs:            rootCAPool,
-		}
+	tlsConf, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	opts := []natsjs.Option{
@@ -68,6 +50,32 @@ func NatsFromConfig(connName string, disableDurability bool, cfg NatsConfig) (ev
 	return Nats(opts...)
 }
 
+func (cfg NatsConfig) tlsConfig() (*tls.Config, error) {
+	if !cfg.EnableTLS {
+		return nil, nil
+	}
+	var rootCAPool *x509.CertPool
+	if cfg.TLSRootCACertificate != "" {
+		rootCrtFile, err := os.Open(cfg.TLSRootCACertificate)
+		if err != nil {
+			return nil, err
+		}
+		defer rootCrtFile.Close()
+
+		rootCAPool, err = newCertPoolFromPEM(rootCrtFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLSInsecure = false
+	}
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure, //nolint:gosec
+		RootCAs:            rootCAPool,
+	}, nil
+}
+
 // nats returns a nats streaming client
 // retries exponentially to connect to a nats server
 func Nats(opts ...natsjs.Option) (events.Stream, error) {
@@ -0,0 +1,78 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"go-micro.dev/v4/events"
+)
+
+// NatsCoreFromConfig returns a stream on plain nats subjects. Unlike the
+// jetstream based streams, messages are not persisted and the server keeps no
+// consumer state: a message is only delivered to the consumers connected when
+// it is published, and publishing only buffers the message in the client.
+// This suits notifications that are only relevant for running instances.
+func NatsCoreFromConfig(connName string, cfg NatsConfig) (events.Stream, error) {
+	tlsConf, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []nats.Option{
+		nats.Name(connName),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+	}
+	if tlsConf != nil {
+		opts = append(opts, nats.Secure(tlsConf))
+	}
+	if cfg.AuthUsername != "" {
+		opts = append(opts, nats.UserInfo(cfg.AuthUsername, cfg.AuthPassword))
+	}
+	conn, err := nats.Connect(cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to nats server: %w", err)
+	}
+	return &natsCore{conn: conn}, nil
+}
+
+type natsCore struct {
+	conn *nats.Conn
+}
+
+// Publish implementation
+func (s *natsCore) Publish(topic string, msg interface{}, _ ...events.PublishOption) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(events.Event{
+		ID:        uuid.New().String(),
+		Topic:     topic,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	})
+	if err != nil {
+		return err
+	}
+	return s.conn.Publish(topic, data)
+}
+
+// Consume implementation. Options are ignored, messages are not acknowledged.
+func (s *natsCore) Consume(topic string, _ ...events.ConsumeOption) (<-chan events.Event, error) {
+	ch := make(chan events.Event)
+	_, err := s.conn.Subscribe(topic, func(msg *nats.Msg) {
+		var evt events.Event
+		if err := json.Unmarshal(msg.Data, &evt); err != nil {
+			return
+		}
+		ch <- evt
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error subscribing to topic: %w", err)
+	}
+	return ch, nil
+}
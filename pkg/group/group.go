@@ -33,3 +33,16 @@ type Manager interface {
 	GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error)
 	HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error)
 }
+
+// Provisioner is the optional interface implemented by managers that allow
+// creating and deleting groups and managing their members.
+type Provisioner interface {
+	// CreateGroup creates a new group. A new id is generated if the group does not carry one.
+	CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error)
+	// DeleteGroup deletes the group identified by a gid.
+	DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error
+	// AddMember adds a user to a group.
+	AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error
+	// RemoveMember removes a user from a group.
+	RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error
+}
@@ -22,24 +22,31 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/group"
 	"github.com/opencloud-eu/reva/v2/pkg/group/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
 )
 
 func init() {
 	registry.Register("json", New)
+	cfg.Register("group.manager", "json", config{})
 }
 
 type manager struct {
+	sync.RWMutex
+	file   string
 	groups []*grouppb.Group
 }
 
@@ -84,11 +91,14 @@ func New(m map[string]interface{}) (group.Manager, error) {
 	}
 
 	return &manager{
+		file:   c.Groups,
 		groups: groups,
 	}, nil
 }
 
 func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId, skipFetchingMembers bool) (*grouppb.Group, error) {
+	m.RLock()
+	defer m.RUnlock()
 	for _, g := range m.groups {
 		if (g.Id.GetOpaqueId() == gid.OpaqueId || g.GroupName == gid.OpaqueId) && (gid.Idp == "" || gid.Idp == g.Id.GetIdp()) {
 			group := proto.Clone(g).(*grouppb.Group)
@@ -102,6 +112,8 @@ func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId, skipFetchi
 }
 
 func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string, skipFetchingMembers bool) (*grouppb.Group, error) {
+	m.RLock()
+	defer m.RUnlock()
 	for _, g := range m.groups {
 		if groupClaim, err := extractClaim(g, claim); err == nil && value == groupClaim {
 			group := proto.Clone(g).(*grouppb.Group)
@@ -129,6 +141,8 @@ func extractClaim(g *grouppb.Group, claim string) (string, error) {
 }
 
 func (m *manager) FindGroups(ctx context.Context, query string, skipFetchingMembers bool) ([]*grouppb.Group, error) {
+	m.RLock()
+	defer m.RUnlock()
 	groups := []*grouppb.Group{}
 	for _, g := range m.groups {
 		if groupContains(g, query) {
@@ -149,9 +163,15 @@ func groupContains(g *grouppb.Group, query string) bool {
 }
 
 func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
+	m.RLock()
+	defer m.RUnlock()
 	for _, g := range m.groups {
 		if g.Id.GetOpaqueId() == gid.OpaqueId || g.GroupName == gid.OpaqueId {
-			return g.Members, nil
+			members := make([]*userpb.UserId, 0, len(g.Members))
+			for _, u := range g.Members {
+				members = append(members, proto.Clone(u).(*userpb.UserId))
+			}
+			return members, nil
 		}
 	}
 	return nil, errtypes.NotFound(gid.OpaqueId)
@@ -170,3 +190,136 @@ func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *user
 	}
 	return false, nil
 }
+
+// CreateGroup adds a group to the json file.
+func (m *manager) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	if g.GetGroupName() == "" {
+		return nil, errtypes.BadRequest("group name missing")
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	ng := proto.Clone(g).(*grouppb.Group)
+	if ng.Id == nil {
+		ng.Id = &grouppb.GroupId{}
+	}
+	if ng.Id.OpaqueId == "" {
+		ng.Id.OpaqueId = uuid.New().String()
+	}
+	for _, existing := range m.groups {
+		if existing.Id.GetOpaqueId() == ng.Id.OpaqueId || existing.GroupName == ng.GroupName {
+			return nil, errtypes.AlreadyExists(ng.GroupName)
+		}
+	}
+
+	groups := append(m.groups, ng)
+	if err := m.persist(groups); err != nil {
+		return nil, err
+	}
+	m.groups = groups
+	return proto.Clone(ng).(*grouppb.Group), nil
+}
+
+// DeleteGroup removes a group from the json file.
+func (m *manager) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	i := m.indexOf(gid)
+	if i < 0 {
+		return errtypes.NotFound(gid.GetOpaqueId())
+	}
+
+	groups := make([]*grouppb.Group, 0, len(m.groups)-1)
+	groups = append(groups, m.groups[:i]...)
+	groups = append(groups, m.groups[i+1:]...)
+	if err := m.persist(groups); err != nil {
+		return err
+	}
+	m.groups = groups
+	return nil
+}
+
+// AddMember adds a user to the members of a group. Adding an existing member is a no-op.
+func (m *manager) AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	return m.updateMembers(gid, func(members []*userpb.UserId) []*userpb.UserId {
+		for _, u := range members {
+			if isSameUser(u, uid) {
+				return members
+			}
+		}
+		return append(members, proto.Clone(uid).(*userpb.UserId))
+	})
+}
+
+// RemoveMember removes a user from the members of a group.
+func (m *manager) RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	return m.updateMembers(gid, func(members []*userpb.UserId) []*userpb.UserId {
+		remaining := make([]*userpb.UserId, 0, len(members))
+		for _, u := range members {
+			if !isSameUser(u, uid) {
+				remaining = append(remaining, u)
+			}
+		}
+		return remaining
+	})
+}
+
+func (m *manager) updateMembers(gid *grouppb.GroupId, fn func([]*userpb.UserId) []*userpb.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	i := m.indexOf(gid)
+	if i < 0 {
+		return errtypes.NotFound(gid.GetOpaqueId())
+	}
+
+	groups := make([]*grouppb.Group, len(m.groups))
+	copy(groups, m.groups)
+	updated := proto.Clone(groups[i]).(*grouppb.Group)
+	updated.Members = fn(updated.Members)
+	groups[i] = updated
+
+	if err := m.persist(groups); err != nil {
+		return err
+	}
+	m.groups = groups
+	return nil
+}
+
+func (m *manager) indexOf(gid *grouppb.GroupId) int {
+	for i, g := range m.groups {
+		if (g.Id.GetOpaqueId() == gid.GetOpaqueId() || g.GroupName == gid.GetOpaqueId()) && (gid.GetIdp() == "" || gid.GetIdp() == g.Id.GetIdp()) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isSameUser(a, b *userpb.UserId) bool {
+	return a.GetOpaqueId() == b.GetOpaqueId() && (a.GetIdp() == "" || b.GetIdp() == "" || a.GetIdp() == b.GetIdp())
+}
+
+// persist atomically replaces the json file with the given groups
+func (m *manager) persist(groups []*grouppb.Group) error {
+	b, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json: error marshaling groups")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.file), filepath.Base(m.file)+".*")
+	if err != nil {
+		return errors.Wrap(err, "json: error creating temporary file")
+	}
+	defer os.Remove(tmp.Name())
+	if fi, err := os.Stat(m.file); err == nil {
+		_ = tmp.Chmod(fi.Mode().Perm())
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "json: error writing groups")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "json: error writing groups")
+	}
+	return os.Rename(tmp.Name(), m.file)
+}
@@ -28,6 +28,7 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/google/go-cmp/cmp"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/group"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
 )
@@ -172,3 +173,61 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf("group differ: expected=%v got=%v", "sailing-lovers", resFind[0].GroupName)
 	}
 }
+
+func TestGroupProvisioning(t *testing.T) {
+	tempdir := t.TempDir()
+	file := tempdir + "/groups.json"
+	groupJSON := `[{"id":{"idp":"localhost","opaque_id":"sailing-lovers"},"group_name":"sailing-lovers","members":[{"idp":"localhost","opaque_id":"einstein","type":1}]}]`
+	if err := os.WriteFile(file, []byte(groupJSON), 0600); err != nil {
+		t.Fatalf("error while writing temp file: %v", err)
+	}
+
+	mgr, err := New(map[string]interface{}{"groups": file})
+	if err != nil {
+		t.Fatalf("error while get manager: %v", err)
+	}
+	p := mgr.(group.Provisioner)
+
+	created, err := p.CreateGroup(ctx, &grouppb.Group{GroupName: "physics-lovers", DisplayName: "Physics Lovers"})
+	if err != nil {
+		t.Fatalf("error creating group: %v", err)
+	}
+	if _, err := p.CreateGroup(ctx, &grouppb.Group{GroupName: "physics-lovers"}); err == nil {
+		t.Fatalf("creating a duplicate group must fail")
+	}
+
+	marie := &userpb.UserId{Idp: "localhost", OpaqueId: "marie", Type: userpb.UserType_USER_TYPE_PRIMARY}
+	if err := p.AddMember(ctx, created.Id, marie); err != nil {
+		t.Fatalf("error adding member: %v", err)
+	}
+	// adding a member twice is a no-op
+	if err := p.AddMember(ctx, created.Id, marie); err != nil {
+		t.Fatalf("error adding member: %v", err)
+	}
+
+	reloaded, err := New(map[string]interface{}{"groups": file})
+	if err != nil {
+		t.Fatalf("error while reloading manager: %v", err)
+	}
+	members, err := reloaded.GetMembers(ctx, created.Id)
+	if err != nil {
+		t.Fatalf("error getting members: %v", err)
+	}
+	if len(members) != 1 || !proto.Equal(members[0], marie) {
+		t.Fatalf("unexpected members: %v", members)
+	}
+
+	if err := p.RemoveMember(ctx, created.Id, marie); err != nil {
+		t.Fatalf("error removing member: %v", err)
+	}
+	if ok, _ := mgr.HasMember(ctx, created.Id, marie); ok {
+		t.Fatalf("removed member still present")
+	}
+
+	if err := p.DeleteGroup(ctx, &grouppb.GroupId{OpaqueId: "sailing-lovers"}); err != nil {
+		t.Fatalf("error deleting group: %v", err)
+	}
+	if _, err := mgr.GetGroup(ctx, &grouppb.GroupId{OpaqueId: "sailing-lovers"}, true); err == nil {
+		t.Fatalf("deleted group still found")
+	}
+}
@@ -33,12 +33,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/group"
 	"github.com/opencloud-eu/reva/v2/pkg/group/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	ldapIdentity "github.com/opencloud-eu/reva/v2/pkg/utils/ldap"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("ldap", New)
+	cfg.Register("group.manager", "ldap", config{})
 }
 
 type manager struct {
@@ -324,3 +326,71 @@ func (m *manager) ldapEntryToUserID(entry *ldap.Entry) (*userpb.UserId, error) {
 		Type:     userpb.UserType_USER_TYPE_PRIMARY,
 	}, nil
 }
+
+// CreateGroup implements the group.Provisioner interface. Creates a new group entry
+// below the configured group base DN, requires write_enabled to be set.
+func (m *manager) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	log := appctx.GetLogger(ctx)
+	if g.GetId().GetIdp() != "" && g.GetId().GetIdp() != m.c.Idp {
+		return nil, errtypes.BadRequest("idp mismatch")
+	}
+
+	groupEntry, err := m.c.LDAPIdentity.AddLDAPGroup(log, m.ldapClient, g)
+	if err != nil {
+		return nil, err
+	}
+	return m.ldapEntryToGroup(groupEntry)
+}
+
+// DeleteGroup implements the group.Provisioner interface. Deletes the group entry.
+func (m *manager) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	log := appctx.GetLogger(ctx)
+	if gid.GetIdp() != "" && gid.GetIdp() != m.c.Idp {
+		return errtypes.NotFound("idp mismatch")
+	}
+
+	groupEntry, err := m.c.LDAPIdentity.GetLDAPGroupByID(log, m.ldapClient, gid.GetOpaqueId())
+	if err != nil {
+		return err
+	}
+	return m.c.LDAPIdentity.DeleteLDAPGroup(log, m.ldapClient, groupEntry)
+}
+
+// AddMember implements the group.Provisioner interface. Adds the user to the
+// member attribute of the group entry.
+func (m *manager) AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	log := appctx.GetLogger(ctx)
+	groupEntry, userEntry, err := m.lookupMembership(ctx, gid, uid)
+	if err != nil {
+		return err
+	}
+	return m.c.LDAPIdentity.AddLDAPGroupMember(log, m.ldapClient, groupEntry, userEntry)
+}
+
+// RemoveMember implements the group.Provisioner interface. Removes the user from
+// the member attribute of the group entry.
+func (m *manager) RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	log := appctx.GetLogger(ctx)
+	groupEntry, userEntry, err := m.lookupMembership(ctx, gid, uid)
+	if err != nil {
+		return err
+	}
+	return m.c.LDAPIdentity.RemoveLDAPGroupMember(log, m.ldapClient, groupEntry, userEntry)
+}
+
+func (m *manager) lookupMembership(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (*ldap.Entry, *ldap.Entry, error) {
+	log := appctx.GetLogger(ctx)
+	if gid.GetIdp() != "" && gid.GetIdp() != m.c.Idp {
+		return nil, nil, errtypes.NotFound("idp mismatch")
+	}
+
+	groupEntry, err := m.c.LDAPIdentity.GetLDAPGroupByID(log, m.ldapClient, gid.GetOpaqueId())
+	if err != nil {
+		return nil, nil, err
+	}
+	userEntry, err := m.c.LDAPIdentity.GetLDAPUserByID(log, m.ldapClient, uid.GetOpaqueId())
+	if err != nil {
+		return nil, nil, err
+	}
+	return groupEntry, userEntry, nil
+}
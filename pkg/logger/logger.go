@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -32,6 +33,7 @@ import (
 func init() {
 	zerolog.CallerSkipFrameCount = 2
 	zerolog.TimeFieldFormat = time.RFC3339Nano
+	cfg.Register("", "log", LogConf{})
 }
 
 // Mode changes the logging format.
@@ -18,6 +18,8 @@
 
 package config
 
+import "github.com/opencloud-eu/reva/v2/pkg/events/stream"
+
 // Config holds the config options that need to be passed down to the metrics reader(driver)
 type Config struct {
 	MetricsDataDriverType string `mapstructure:"metrics_data_driver_type"`
@@ -26,6 +28,18 @@ type Config struct {
 	XcloudInstance        string `mapstructure:"xcloud_instance"`
 	XcloudPullInterval    int    `mapstructure:"xcloud_pull_interval"`
 	InsecureSkipVerify    bool   `mapstructure:"insecure_skip_verify"`
+
+	// options of the usage driver
+	UsageGatewayAddr          string            `mapstructure:"usage_gateway_addr"`
+	UsageServiceAccountID     string            `mapstructure:"usage_service_account_id"`
+	UsageServiceAccountSecret string            `mapstructure:"usage_service_account_secret"`
+	UsageCollectInterval      int               `mapstructure:"usage_collect_interval"`   // seconds between two collections
+	UsageCollectTreeStats     bool              `mapstructure:"usage_collect_tree_stats"` // walk the spaces to count files and versions, expensive
+	UsageTopSpaces            int               `mapstructure:"usage_top_spaces"`         // number of largest spaces exported individually
+	UsageTopUsers             int               `mapstructure:"usage_top_users"`          // number of most active users exported individually
+	UsageSpaceTypes           []string          `mapstructure:"usage_space_types"`        // space types to export, all if empty
+	UsageSpaceLabels          []string          `mapstructure:"usage_space_labels"`       // labels of the space metrics, a subset of space_id, space_name, space_type and owner, spaces sharing the same label values are summed up
+	UsageEvents               stream.NatsConfig `mapstructure:"usage_events"`             // events to count the user activity, disabled if no address is set
 }
 
 // Init sets sane defaults
@@ -44,4 +58,19 @@ func (c *Config) Init() {
 	if c.XcloudPullInterval == 0 {
 		c.XcloudPullInterval = 5
 	}
+
+	if c.MetricsDataDriverType == "usage" {
+		if c.UsageCollectInterval == 0 {
+			c.UsageCollectInterval = 300
+		}
+		if c.UsageTopSpaces == 0 {
+			c.UsageTopSpaces = 100
+		}
+		if c.UsageTopUsers == 0 {
+			c.UsageTopUsers = 100
+		}
+		if len(c.UsageSpaceLabels) == 0 {
+			c.UsageSpaceLabels = []string{"space_id", "space_type"}
+		}
+	}
 }
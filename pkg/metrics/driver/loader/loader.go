@@ -22,6 +22,7 @@ import (
 	// Load metrics drivers.
 	_ "github.com/opencloud-eu/reva/v2/pkg/metrics/driver/dummy"
 	_ "github.com/opencloud-eu/reva/v2/pkg/metrics/driver/json"
+	_ "github.com/opencloud-eu/reva/v2/pkg/metrics/driver/usage"
 	_ "github.com/opencloud-eu/reva/v2/pkg/metrics/driver/xcloud"
 	// Add your own here
 )
@@ -0,0 +1,109 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
+)
+
+// executant holds the fields events use to reference the user who triggered them
+type executant struct {
+	Executant     *user.UserId
+	ExecutingUser *user.User
+}
+
+// activity counts the events triggered by each user. The counts of the current
+// window are exported once the window is rotated.
+type activity struct {
+	sync.Mutex
+	current map[string]map[string]uint64
+	last    map[string]map[string]uint64
+}
+
+func newActivity() *activity {
+	return &activity{
+		current: map[string]map[string]uint64{},
+		last:    map[string]map[string]uint64{},
+	}
+}
+
+// consume counts the events of the given event stream
+func (a *activity) consume(cfg stream.NatsConfig) error {
+	s, err := stream.NatsFromConfig("usage-metrics", false, cfg)
+	if err != nil {
+		return err
+	}
+	ch, err := events.ConsumeAll(s, "usage-metrics")
+	if err != nil {
+		return err
+	}
+	go func() {
+		for e := range ch {
+			payload, ok := e.Event.([]byte)
+			if !ok {
+				continue
+			}
+			var ex executant
+			if err := json.Unmarshal(payload, &ex); err != nil {
+				continue
+			}
+			userID := ex.Executant.GetOpaqueId()
+			if userID == "" {
+				userID = ex.ExecutingUser.GetId().GetOpaqueId()
+			}
+			a.add(userID, e.Type)
+		}
+	}()
+	return nil
+}
+
+// add counts an event of the given type for the given user. Events without a user are ignored.
+func (a *activity) add(userID, eventType string) {
+	if userID == "" {
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	name := strings.TrimPrefix(eventType, "events.")
+	if a.current[userID] == nil {
+		a.current[userID] = map[string]uint64{}
+	}
+	a.current[userID][name]++
+}
+
+// rotate starts a new window
+func (a *activity) rotate() {
+	a.Lock()
+	defer a.Unlock()
+	a.last = a.current
+	a.current = map[string]map[string]uint64{}
+}
+
+// counts returns the counts of the last complete window
+func (a *activity) counts() map[string]map[string]uint64 {
+	a.Lock()
+	defer a.Unlock()
+	return a.last
+}
@@ -0,0 +1,232 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// other is the label value the spaces and users beyond the top N are aggregated under
+const other = "other"
+
+var spaceLabels = []string{"space_id", "space_name", "space_type", "owner"}
+
+func validSpaceLabel(l string) bool {
+	return slices.Contains(spaceLabels, l)
+}
+
+// collector exports the last snapshot of the driver. Spaces and users that are
+// no longer part of the snapshot disappear from the exported metrics.
+type collector struct {
+	d      *Driver
+	labels []string
+
+	quota, used, trash, versions, files *prometheus.Desc
+	spaces, activity                    *prometheus.Desc
+	timestamp, duration                 *prometheus.Desc
+}
+
+func newCollector(d *Driver) *collector {
+	labels := make([]string, 0, len(spaceLabels))
+	for _, l := range spaceLabels {
+		if slices.Contains(d.conf.UsageSpaceLabels, l) {
+			labels = append(labels, l)
+		}
+	}
+	return &collector{
+		d:         d,
+		labels:    labels,
+		quota:     prometheus.NewDesc("reva_space_quota_bytes", "Quota of the storage space in bytes.", labels, nil),
+		used:      prometheus.NewDesc("reva_space_used_bytes", "Bytes used by the storage space.", labels, nil),
+		trash:     prometheus.NewDesc("reva_space_trash_bytes", "Bytes used by the trash of the storage space.", labels, nil),
+		versions:  prometheus.NewDesc("reva_space_versions_bytes", "Bytes used by the file versions of the storage space.", labels, nil),
+		files:     prometheus.NewDesc("reva_space_files", "Number of files in the storage space.", labels, nil),
+		spaces:    prometheus.NewDesc("reva_spaces", "Number of storage spaces.", []string{"space_type"}, nil),
+		activity:  prometheus.NewDesc("reva_user_activity", "Number of events triggered by the user during the last collection interval.", []string{"user_id", "activity"}, nil),
+		timestamp: prometheus.NewDesc("reva_usage_last_collection_timestamp_seconds", "Time of the last usage collection.", nil, nil),
+		duration:  prometheus.NewDesc("reva_usage_collection_duration_seconds", "Duration of the last usage collection.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.quota, c.used, c.trash, c.versions, c.files, c.spaces, c.activity, c.timestamp, c.duration} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.d.RLock()
+	spaces, last, duration := c.d.spaces, c.d.last, c.d.duration
+	c.d.RUnlock()
+	if last.IsZero() {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.timestamp, prometheus.GaugeValue, float64(last.Unix()))
+	ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, duration.Seconds())
+
+	perType := map[string]int{}
+	for _, s := range spaces {
+		perType[s.Type]++
+	}
+	for t, n := range perType {
+		ch <- prometheus.MustNewConstMetric(c.spaces, prometheus.GaugeValue, float64(n), t)
+	}
+
+	series, labelValues := c.sumByLabels(topSpaces(spaces, c.d.conf.UsageTopSpaces, slices.Contains(c.labels, "space_type")))
+	for i, s := range series {
+		values := labelValues[i]
+		ch <- prometheus.MustNewConstMetric(c.quota, prometheus.GaugeValue, float64(s.Quota), values...)
+		ch <- prometheus.MustNewConstMetric(c.used, prometheus.GaugeValue, float64(s.Used), values...)
+		ch <- prometheus.MustNewConstMetric(c.trash, prometheus.GaugeValue, float64(s.Trash), values...)
+		if c.d.conf.UsageCollectTreeStats {
+			ch <- prometheus.MustNewConstMetric(c.versions, prometheus.GaugeValue, float64(s.Versions), values...)
+			ch <- prometheus.MustNewConstMetric(c.files, prometheus.GaugeValue, float64(s.Files), values...)
+		}
+	}
+
+	for userID, counts := range topUsers(c.d.activity.counts(), c.d.conf.UsageTopUsers) {
+		for name, n := range counts {
+			ch <- prometheus.MustNewConstMetric(c.activity, prometheus.GaugeValue, float64(n), userID, name)
+		}
+	}
+}
+
+func (c *collector) labelValues(s spaceUsage) []string {
+	values := make([]string, 0, len(c.labels))
+	for _, l := range c.labels {
+		switch l {
+		case "space_id":
+			values = append(values, s.ID)
+		case "space_name":
+			values = append(values, s.Name)
+		case "space_type":
+			values = append(values, s.Type)
+		case "owner":
+			values = append(values, s.Owner)
+		}
+	}
+	return values
+}
+
+// sumByLabels sums up the spaces that share the same label values, e.g. all
+// spaces of a type if space_id is not one of the labels. It returns the
+// summed up spaces and their label values.
+func (c *collector) sumByLabels(spaces []spaceUsage) ([]spaceUsage, [][]string) {
+	series := make([]spaceUsage, 0, len(spaces))
+	values := make([][]string, 0, len(spaces))
+	index := map[string]int{}
+	for _, s := range spaces {
+		v := c.labelValues(s)
+		key := strings.Join(v, "\x00")
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(series)
+			series = append(series, s)
+			values = append(values, v)
+			continue
+		}
+		series[i].Quota += s.Quota
+		series[i].Used += s.Used
+		series[i].Trash += s.Trash
+		series[i].Versions += s.Versions
+		series[i].Files += s.Files
+	}
+	return series, values
+}
+
+// topSpaces returns the n largest spaces. The remaining spaces are summed up in
+// an aggregate per space type if byType is set, in a single aggregate otherwise.
+func topSpaces(spaces []spaceUsage, n int, byType bool) []spaceUsage {
+	if len(spaces) <= n {
+		return spaces
+	}
+	sorted := slices.Clone(spaces)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Used > sorted[j].Used })
+
+	top := sorted[:n:n]
+	rest := map[string]*spaceUsage{}
+	var types []string
+	for _, s := range sorted[n:] {
+		t := other
+		if byType {
+			t = s.Type
+		}
+		agg, ok := rest[t]
+		if !ok {
+			agg = &spaceUsage{ID: other, Name: other, Type: t, Owner: other}
+			rest[t] = agg
+			types = append(types, t)
+		}
+		agg.Quota += s.Quota
+		agg.Used += s.Used
+		agg.Trash += s.Trash
+		agg.Versions += s.Versions
+		agg.Files += s.Files
+	}
+	for _, t := range types {
+		top = append(top, *rest[t])
+	}
+	return top
+}
+
+// topUsers returns the counts of the n most active users. The counts of the
+// remaining users are summed up under the other user.
+func topUsers(counts map[string]map[string]uint64, n int) map[string]map[string]uint64 {
+	if len(counts) <= n {
+		return counts
+	}
+	type total struct {
+		id string
+		n  uint64
+	}
+	totals := make([]total, 0, len(counts))
+	for id, c := range counts {
+		t := total{id: id}
+		for _, v := range c {
+			t.n += v
+		}
+		totals = append(totals, t)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].n != totals[j].n {
+			return totals[i].n > totals[j].n
+		}
+		return totals[i].id < totals[j].id
+	})
+
+	top := make(map[string]map[string]uint64, n+1)
+	for _, t := range totals[:n] {
+		top[t.id] = counts[t.id]
+	}
+	rest := map[string]uint64{}
+	for _, t := range totals[n:] {
+		for name, v := range counts[t.id] {
+			rest[name] += v
+		}
+	}
+	top[other] = rest
+	return top
+}
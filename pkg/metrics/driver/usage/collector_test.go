@@ -0,0 +1,125 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/reva/v2/pkg/metrics/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Collector", func() {
+	var (
+		d *Driver
+		c *collector
+	)
+
+	BeforeEach(func() {
+		d = &Driver{
+			conf: &config.Config{
+				UsageTopSpaces:   2,
+				UsageTopUsers:    1,
+				UsageSpaceLabels: []string{"space_type", "space_id"},
+			},
+			activity: newActivity(),
+			last:     time.Unix(1700000000, 0),
+			spaces: []spaceUsage{
+				{ID: "a", Type: "personal", Used: 10, Quota: 100},
+				{ID: "b", Type: "personal", Used: 30, Quota: 100},
+				{ID: "c", Type: "project", Used: 20, Quota: 50},
+				{ID: "d", Type: "project", Used: 5, Quota: 50},
+				{ID: "e", Type: "personal", Used: 1, Quota: 100},
+			},
+		}
+		c = newCollector(d)
+	})
+
+	It("orders the labels consistently", func() {
+		Expect(c.labels).To(Equal([]string{"space_id", "space_type"}))
+	})
+
+	It("exports the largest spaces and aggregates the rest per space type", func() {
+		expected := `
+# HELP reva_space_used_bytes Bytes used by the storage space.
+# TYPE reva_space_used_bytes gauge
+reva_space_used_bytes{space_id="b",space_type="personal"} 30
+reva_space_used_bytes{space_id="c",space_type="project"} 20
+reva_space_used_bytes{space_id="other",space_type="personal"} 11
+reva_space_used_bytes{space_id="other",space_type="project"} 5
+# HELP reva_spaces Number of storage spaces.
+# TYPE reva_spaces gauge
+reva_spaces{space_type="personal"} 3
+reva_spaces{space_type="project"} 2
+`
+		Expect(testutil.CollectAndCompare(c, strings.NewReader(expected), "reva_space_used_bytes", "reva_spaces")).To(Succeed())
+	})
+
+	It("sums up spaces sharing the same label values", func() {
+		d.conf.UsageSpaceLabels = []string{"space_type"}
+		c = newCollector(d)
+		expected := `
+# HELP reva_space_used_bytes Bytes used by the storage space.
+# TYPE reva_space_used_bytes gauge
+reva_space_used_bytes{space_type="personal"} 41
+reva_space_used_bytes{space_type="project"} 25
+`
+		Expect(testutil.CollectAndCompare(c, strings.NewReader(expected), "reva_space_used_bytes")).To(Succeed())
+	})
+
+	It("only exports tree stats when they are collected", func() {
+		Expect(testutil.CollectAndCount(c, "reva_space_files", "reva_space_versions_bytes")).To(Equal(0))
+		d.conf.UsageCollectTreeStats = true
+		Expect(testutil.CollectAndCount(c, "reva_space_files", "reva_space_versions_bytes")).To(Equal(8))
+	})
+
+	It("exports the activity of the last window", func() {
+		d.activity.add("einstein", "events.FileUploaded")
+		d.activity.add("einstein", "events.FileUploaded")
+		d.activity.add("marie", "events.FileDownloaded")
+		d.activity.add("", "events.FileDownloaded")
+		Expect(testutil.CollectAndCount(c, "reva_user_activity")).To(Equal(0))
+
+		d.activity.rotate()
+		d.activity.add("richard", "events.FileUploaded")
+		expected := `
+# HELP reva_user_activity Number of events triggered by the user during the last collection interval.
+# TYPE reva_user_activity gauge
+reva_user_activity{activity="FileDownloaded",user_id="other"} 1
+reva_user_activity{activity="FileUploaded",user_id="einstein"} 2
+`
+		Expect(testutil.CollectAndCompare(c, strings.NewReader(expected), "reva_user_activity")).To(Succeed())
+	})
+
+	It("is gathered from the default registry", func() {
+		Expect(d.register(prometheus.DefaultRegisterer)).To(Succeed())
+		DeferCleanup(func() { prometheus.DefaultRegisterer.Unregister(newCollector(d)) })
+
+		Expect(testutil.GatherAndCount(prometheus.DefaultGatherer, "reva_spaces")).To(Equal(2))
+	})
+
+	It("returns the totals of the snapshot", func() {
+		Expect(d.GetNumUsers()).To(Equal(int64(3)))
+		Expect(d.GetAmountStorage()).To(Equal(int64(66)))
+	})
+})
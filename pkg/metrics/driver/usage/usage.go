@@ -0,0 +1,273 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/logger"
+	"github.com/opencloud-eu/reva/v2/pkg/metrics/config"
+	"github.com/opencloud-eu/reva/v2/pkg/metrics/driver/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+var log zerolog.Logger
+
+func init() {
+	log = logger.New().With().Int("pid", os.Getpid()).Logger()
+	driver := &Driver{}
+	registry.Register(driverName(), driver)
+}
+
+func driverName() string {
+	return "usage"
+}
+
+// spaceUsage holds the usage numbers of a single storage space
+type spaceUsage struct {
+	ID       string
+	Name     string
+	Type     string
+	Owner    string
+	Quota    uint64
+	Used     uint64
+	Trash    uint64
+	Versions uint64
+	Files    uint64
+}
+
+// Driver periodically collects the usage of all storage spaces and the activity of the users
+// and exports them as prometheus metrics
+type Driver struct {
+	conf     *config.Config
+	gwc      gateway.GatewayAPIClient
+	activity *activity
+
+	sync.RWMutex
+	spaces   []spaceUsage
+	last     time.Time
+	duration time.Duration
+}
+
+// Configure configures this driver
+func (d *Driver) Configure(c *config.Config) error {
+	if c.UsageGatewayAddr == "" {
+		return errors.New("usage: missing usage_gateway_addr config parameter")
+	}
+	if c.UsageServiceAccountID == "" || c.UsageServiceAccountSecret == "" {
+		return errors.New("usage: missing usage_service_account_id or usage_service_account_secret config parameter")
+	}
+	for _, l := range c.UsageSpaceLabels {
+		if !validSpaceLabel(l) {
+			return errors.New("usage: unknown space label " + l)
+		}
+	}
+
+	gwc, err := pool.GetGatewayServiceClient(c.UsageGatewayAddr)
+	if err != nil {
+		return err
+	}
+	d.conf = c
+	d.gwc = gwc
+	d.activity = newActivity()
+
+	if c.UsageEvents.Endpoint != "" {
+		if err := d.activity.consume(c.UsageEvents); err != nil {
+			return err
+		}
+	}
+
+	if err := d.register(prometheus.DefaultRegisterer); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(c.UsageCollectInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			if err := d.collect(context.Background()); err != nil {
+				log.Err(err).Msg("usage: error collecting the space usage")
+			}
+			<-ticker.C
+		}
+	}()
+	return nil
+}
+
+// register registers the collector of the driver. The prometheus http service
+// serves the default registry next to the OpenCensus views of the other drivers.
+func (d *Driver) register(r prometheus.Registerer) error {
+	if err := r.Register(newCollector(d)); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNumUsers returns the number of personal spaces
+func (d *Driver) GetNumUsers() int64 {
+	d.RLock()
+	defer d.RUnlock()
+	var n int64
+	for _, s := range d.spaces {
+		if s.Type == "personal" {
+			n++
+		}
+	}
+	return n
+}
+
+// GetNumGroups returns the number of site groups; groups are not tracked by this driver
+func (d *Driver) GetNumGroups() int64 {
+	return 0
+}
+
+// GetAmountStorage returns the amount of bytes used by all spaces
+func (d *Driver) GetAmountStorage() int64 {
+	d.RLock()
+	defer d.RUnlock()
+	var n int64
+	for _, s := range d.spaces {
+		n += int64(s.Used)
+	}
+	return n
+}
+
+// collect lists all spaces and replaces the last snapshot of their usage
+func (d *Driver) collect(ctx context.Context) error {
+	start := time.Now()
+	ctx, err := utils.GetServiceUserContextWithContext(ctx, d.gwc, d.conf.UsageServiceAccountID, d.conf.UsageServiceAccountSecret)
+	if err != nil {
+		return err
+	}
+
+	req := &provider.ListStorageSpacesRequest{
+		Opaque: utils.AppendPlainToOpaque(nil, "unrestricted", "true"),
+	}
+	for _, t := range d.conf.UsageSpaceTypes {
+		req.Filters = append(req.Filters, &provider.ListStorageSpacesRequest_Filter{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+			Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: t},
+		})
+	}
+	res, err := d.gwc.ListStorageSpaces(ctx, req)
+	if err != nil {
+		return err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return errors.New("usage: error listing spaces: " + res.GetStatus().GetMessage())
+	}
+
+	spaces := make([]spaceUsage, 0, len(res.StorageSpaces))
+	for _, s := range res.StorageSpaces {
+		if utils.ReadPlainFromOpaque(s.Opaque, "trashed") == "trashed" {
+			continue
+		}
+		u := spaceUsage{
+			ID:    s.GetId().GetOpaqueId(),
+			Name:  s.GetName(),
+			Type:  s.GetSpaceType(),
+			Owner: s.GetOwner().GetId().GetOpaqueId(),
+			Quota: s.GetQuota().GetQuotaMaxBytes(),
+			Used:  s.GetRootInfo().GetSize(),
+		}
+		if used, err := strconv.ParseUint(utils.ReadPlainFromOpaque(s.Opaque, "quota.used"), 10, 64); err == nil {
+			u.Used = used
+		}
+		if s.Root != nil {
+			u.Trash = d.trashSize(ctx, s.Root)
+			if d.conf.UsageCollectTreeStats {
+				u.Files, u.Versions = d.treeStats(ctx, s.Root)
+			}
+		}
+		spaces = append(spaces, u)
+	}
+
+	d.activity.rotate()
+
+	d.Lock()
+	d.spaces = spaces
+	d.last = time.Now()
+	d.duration = time.Since(start)
+	d.Unlock()
+	return nil
+}
+
+// trashSize returns the sum of the sizes of all items in the trash of a space
+func (d *Driver) trashSize(ctx context.Context, root *provider.ResourceId) uint64 {
+	res, err := d.gwc.ListRecycle(ctx, &provider.ListRecycleRequest{
+		Ref: &provider.Reference{ResourceId: root, Path: "."},
+	})
+	if err != nil || res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		log.Debug().Err(err).Str("space", root.GetSpaceId()).Msg("usage: could not list the trash")
+		return 0
+	}
+	var size uint64
+	for _, item := range res.RecycleItems {
+		size += item.GetSize()
+	}
+	return size
+}
+
+// treeStats walks a space and returns the number of files and the size of all their versions
+func (d *Driver) treeStats(ctx context.Context, root *provider.ResourceId) (files, versions uint64) {
+	queue := []*provider.ResourceId{root}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		res, err := d.gwc.ListContainer(ctx, &provider.ListContainerRequest{
+			Ref: &provider.Reference{ResourceId: id, Path: "."},
+		})
+		if err != nil || res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+			log.Debug().Err(err).Str("space", root.GetSpaceId()).Msg("usage: could not list a container")
+			continue
+		}
+		for _, info := range res.Infos {
+			switch info.GetType() {
+			case provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+				queue = append(queue, info.GetId())
+			case provider.ResourceType_RESOURCE_TYPE_FILE:
+				files++
+				vres, err := d.gwc.ListFileVersions(ctx, &provider.ListFileVersionsRequest{
+					Ref: &provider.Reference{ResourceId: info.GetId()},
+				})
+				if err != nil || vres.GetStatus().GetCode() != rpc.Code_CODE_OK {
+					continue
+				}
+				for _, v := range vres.Versions {
+					versions += v.GetSize()
+				}
+			}
+		}
+	}
+	return files, versions
+}
@@ -0,0 +1,116 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usage
+
+import (
+	"context"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/reva/v2/pkg/metrics/config"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Driver", func() {
+	var (
+		gwc *mocks.GatewayAPIClient
+		d   *Driver
+		ok  = &rpc.Status{Code: rpc.Code_CODE_OK}
+	)
+
+	BeforeEach(func() {
+		gwc = &mocks.GatewayAPIClient{}
+		d = &Driver{
+			conf: &config.Config{
+				UsageServiceAccountID:     "service",
+				UsageServiceAccountSecret: "secret",
+				UsageSpaceTypes:           []string{"personal"},
+			},
+			gwc:      gwc,
+			activity: newActivity(),
+		}
+		gwc.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: ok, Token: "token"}, nil)
+		gwc.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(func(req *provider.ListStorageSpacesRequest) bool {
+			return utils.ReadPlainFromOpaque(req.Opaque, "unrestricted") == "true" &&
+				len(req.Filters) == 1 && req.Filters[0].GetSpaceType() == "personal"
+		})).Return(&provider.ListStorageSpacesResponse{
+			Status: ok,
+			StorageSpaces: []*provider.StorageSpace{
+				{
+					Id:        &provider.StorageSpaceId{OpaqueId: "s1"},
+					Root:      &provider.ResourceId{SpaceId: "s1", OpaqueId: "s1"},
+					SpaceType: "personal",
+					Owner:     &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}},
+					Quota:     &provider.Quota{QuotaMaxBytes: 1000},
+					RootInfo:  &provider.ResourceInfo{Size: 1},
+					Opaque:    utils.AppendPlainToOpaque(nil, "quota.used", "300"),
+				},
+				{
+					Id:        &provider.StorageSpaceId{OpaqueId: "s2"},
+					SpaceType: "personal",
+					Opaque:    utils.AppendPlainToOpaque(nil, "trashed", "trashed"),
+				},
+			},
+		}, nil)
+		gwc.On("ListRecycle", mock.Anything, mock.Anything).Return(&provider.ListRecycleResponse{
+			Status:       ok,
+			RecycleItems: []*provider.RecycleItem{{Size: 20}, {Size: 22}},
+		}, nil)
+	})
+
+	It("collects the usage of the spaces", func() {
+		Expect(d.collect(context.Background())).To(Succeed())
+		Expect(d.spaces).To(Equal([]spaceUsage{
+			{ID: "s1", Type: "personal", Owner: "einstein", Quota: 1000, Used: 300, Trash: 42},
+		}))
+		Expect(d.last.IsZero()).To(BeFalse())
+		gwc.AssertNotCalled(GinkgoT(), "ListContainer", mock.Anything, mock.Anything)
+	})
+
+	It("walks the spaces to collect the tree stats", func() {
+		d.conf.UsageCollectTreeStats = true
+		root := &provider.ResourceId{SpaceId: "s1", OpaqueId: "s1"}
+		dir := &provider.ResourceId{SpaceId: "s1", OpaqueId: "dir"}
+		gwc.On("ListContainer", mock.Anything, mock.MatchedBy(func(req *provider.ListContainerRequest) bool {
+			return req.Ref.ResourceId.OpaqueId == "s1"
+		})).Return(&provider.ListContainerResponse{Status: ok, Infos: []*provider.ResourceInfo{
+			{Id: dir, Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER},
+			{Id: &provider.ResourceId{SpaceId: "s1", OpaqueId: "f1"}, Type: provider.ResourceType_RESOURCE_TYPE_FILE},
+		}}, nil)
+		gwc.On("ListContainer", mock.Anything, mock.MatchedBy(func(req *provider.ListContainerRequest) bool {
+			return req.Ref.ResourceId.OpaqueId == "dir"
+		})).Return(&provider.ListContainerResponse{Status: ok, Infos: []*provider.ResourceInfo{
+			{Id: &provider.ResourceId{SpaceId: "s1", OpaqueId: "f2"}, Type: provider.ResourceType_RESOURCE_TYPE_FILE},
+		}}, nil)
+		gwc.On("ListFileVersions", mock.Anything, mock.Anything).Return(&provider.ListFileVersionsResponse{
+			Status:   ok,
+			Versions: []*provider.FileVersion{{Size: 5}},
+		}, nil)
+
+		files, versions := d.treeStats(context.Background(), root)
+		Expect(files).To(Equal(uint64(2)))
+		Expect(versions).To(Equal(uint64(10)))
+	})
+})
@@ -0,0 +1,91 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BreachedList rejects passwords contained in a local copy of a breached
+// passwords dump. The dump is split into k-anonymity ranges like the ones served
+// by the Pwned Passwords API: the directory holds one file per five character
+// prefix of the upper case SHA-1 hex digest, named after the prefix with an
+// optional ".txt" extension. Every line of a file holds the remaining 35
+// characters of a digest and the number of times it was seen in breaches,
+// separated by a colon.
+type BreachedList struct {
+	dir      string
+	minCount uint64
+}
+
+// NewBreachedList returns a validator rejecting passwords seen in at least
+// minCount breaches of the dump in dir
+func NewBreachedList(dir string, minCount uint64) Validator {
+	if minCount == 0 {
+		minCount = 1
+	}
+	return BreachedList{dir: dir, minCount: minCount}
+}
+
+// Validate implements the Validator interface
+func (b BreachedList) Validate(str string) error {
+	sum := sha1.Sum([]byte(str))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	f, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if os.IsNotExist(err) {
+		f, err = os.Open(filepath.Join(b.dir, prefix))
+	}
+	switch {
+	case os.IsNotExist(err):
+		// no breached password shares the prefix
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "error opening breached passwords range")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, c, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(hash, suffix) {
+			continue
+		}
+		count := uint64(1)
+		if c != "" {
+			if count, err = strconv.ParseUint(c, 10, 64); err != nil {
+				return errors.Wrap(err, "error parsing breached passwords range")
+			}
+		}
+		if count < b.minCount {
+			return nil
+		}
+		return newViolation(ViolationBreached, map[string]interface{}{"count": count}, "the password appeared in a data breach, please pick a different password")
+	}
+	return errors.Wrap(scanner.Err(), "error reading breached passwords range")
+}
@@ -0,0 +1,46 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRange(t *testing.T, dir, name string, passwords map[string]string) {
+	var lines []string
+	for pw, count := range passwords {
+		sum := sha1.Sum([]byte(pw))
+		digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+		lines = append(lines, digest[5:]+":"+count)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\r\n")), 0600))
+}
+
+func prefix(pw string) string {
+	sum := sha1.Sum([]byte(pw))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))[:5]
+}
+
+func TestBreachedList_Validate(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, prefix("password")+".txt", map[string]string{"password": "9545824"})
+	writeRange(t, dir, prefix("rarely-breached"), map[string]string{"rarely-breached": "2"})
+
+	b := NewBreachedList(dir, 3)
+
+	vs := Violations(b.Validate("password"))
+	if assert.Len(t, vs, 1) {
+		assert.Equal(t, ViolationBreached, vs[0].Code)
+		assert.Equal(t, uint64(9545824), vs[0].Params["count"])
+	}
+	assert.NoError(t, b.Validate("rarely-breached"))
+	assert.NoError(t, b.Validate("never-breached"))
+
+	assert.Error(t, NewBreachedList(dir, 0).Validate("rarely-breached"))
+}
@@ -0,0 +1,47 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import "errors"
+
+// Chain validates a password with several validators and reports the
+// violations of all of them.
+type Chain []Validator
+
+// NewChain returns a chain of the given validators, nil validators are skipped
+func NewChain(validators ...Validator) Chain {
+	c := make(Chain, 0, len(validators))
+	for _, v := range validators {
+		if v != nil {
+			c = append(c, v)
+		}
+	}
+	return c
+}
+
+// Validate runs all validators of the chain and joins their errors
+func (c Chain) Validate(str string) error {
+	var allErr error
+	for _, v := range c {
+		if err := v.Validate(str); err != nil {
+			allErr = errors.Join(allErr, err)
+		}
+	}
+	return allErr
+}
@@ -0,0 +1,135 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import (
+	"errors"
+
+	"github.com/opencloud-eu/reva/v2/pkg/store"
+	"github.com/rs/zerolog/log"
+	microstore "go-micro.dev/v4/store"
+)
+
+// Config configures the rules a password has to satisfy
+type Config struct {
+	MinCharacters          int                 `mapstructure:"min_characters"`
+	MinLowerCaseCharacters int                 `mapstructure:"min_lowercase_characters"`
+	MinUpperCaseCharacters int                 `mapstructure:"min_uppercase_characters"`
+	MinDigits              int                 `mapstructure:"min_digits"`
+	MinSpecialCharacters   int                 `mapstructure:"min_special_characters"`
+	BannedPasswordsList    map[string]struct{} `mapstructure:"banned_passwords_list"`
+	MinScore               int                 `mapstructure:"min_score" docs:"0;The minimum strength score from 0 (too guessable) to 4 (very unguessable). 0 disables the check."`
+	BreachedPasswordsDir   string              `mapstructure:"breached_passwords_dir" docs:";A directory holding a k-anonymity range dump of breached password hashes. Empty disables the check."`
+	BreachedMinCount       uint64              `mapstructure:"breached_min_count" docs:"1;The number of breaches a password must have been seen in to be rejected."`
+}
+
+// Validator returns a chain of the validators enabled by the config
+func (c Config) Validator() Validator {
+	validators := []Validator{
+		NewPasswordPolicy(c.MinCharacters, c.MinLowerCaseCharacters, c.MinUpperCaseCharacters, c.MinDigits, c.MinSpecialCharacters, c.BannedPasswordsList),
+	}
+	if c.MinScore > 0 {
+		validators = append(validators, NewStrength(c.MinScore))
+	}
+	if c.BreachedPasswordsDir != "" {
+		validators = append(validators, NewBreachedList(c.BreachedPasswordsDir, c.BreachedMinCount))
+	}
+	return NewChain(validators...)
+}
+
+// HistoryConfig configures the password history
+type HistoryConfig struct {
+	Depth             int      `mapstructure:"depth" docs:"0;The number of previous passwords that cannot be reused. 0 disables the history."`
+	Store             string   `mapstructure:"store" docs:";The store used to persist the password hashes. Required if the history is enabled, the memory store loses the history on restarts and is not shared between instances."`
+	StoreNodes        []string `mapstructure:"store_nodes"`
+	StoreDatabase     string   `mapstructure:"store_database"`
+	StoreTable        string   `mapstructure:"store_table"`
+	StoreAuthUsername string   `mapstructure:"store_auth_username"`
+	StoreAuthPassword string   `mapstructure:"store_auth_password"`
+}
+
+// History returns the configured history, or nil if it is disabled
+func (c HistoryConfig) History() (*History, error) {
+	if c.Depth <= 0 {
+		return nil, nil
+	}
+	switch c.Store {
+	case "":
+		return nil, errors.New("password history: a store is required to persist the password hashes")
+	case store.TypeMemory, store.TypeOCMem, store.TypeNoop:
+		log.Warn().Str("store", c.Store).Msg("password history: the store does not persist the password hashes, the history is lost on restarts and not shared between instances")
+	}
+	if c.StoreDatabase == "" {
+		c.StoreDatabase = "reva"
+	}
+	if c.StoreTable == "" {
+		c.StoreTable = "password_history"
+	}
+	s := store.Create(
+		store.Store(c.Store),
+		microstore.Nodes(c.StoreNodes...),
+		microstore.Database(c.StoreDatabase),
+		microstore.Table(c.StoreTable),
+		store.Authentication(c.StoreAuthUsername, c.StoreAuthPassword),
+	)
+	return NewHistory(c.Depth, NewMicroHistoryStore(s)), nil
+}
+
+// PolicyConfig configures the password policies of a service. The policy of
+// a space type replaces the default policy for passwords protecting
+// resources in spaces of that type.
+type PolicyConfig struct {
+	Config     `mapstructure:",squash"`
+	SpaceTypes map[string]Config `mapstructure:"space_types"`
+	History    HistoryConfig     `mapstructure:"history"`
+}
+
+// Policies returns the policies selected by space type
+func (c PolicyConfig) Policies() *SpacePolicies {
+	spaceTypes := make(map[string]Validator, len(c.SpaceTypes))
+	for t, sc := range c.SpaceTypes {
+		spaceTypes[t] = sc.Validator()
+	}
+	return NewSpacePolicies(c.Config.Validator(), spaceTypes)
+}
+
+// SpacePolicies selects the password policy by the type of the space a
+// password protects, falling back to a default policy.
+type SpacePolicies struct {
+	fallback   Validator
+	spaceTypes map[string]Validator
+}
+
+// NewSpacePolicies returns policies using the validators of spaceTypes and fallback for all other types
+func NewSpacePolicies(fallback Validator, spaceTypes map[string]Validator) *SpacePolicies {
+	return &SpacePolicies{fallback: fallback, spaceTypes: spaceTypes}
+}
+
+// For returns the validator of a space type
+func (p *SpacePolicies) For(spaceType string) Validator {
+	if v, ok := p.spaceTypes[spaceType]; ok {
+		return v
+	}
+	return p.fallback
+}
+
+// Validate validates a password with the default policy
+func (p *SpacePolicies) Validate(str string) error {
+	return p.fallback.Validate(str)
+}
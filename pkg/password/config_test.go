@@ -0,0 +1,58 @@
+package password
+
+import (
+	"testing"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func codes(vs []*Violation) []string {
+	c := make([]string, 0, len(vs))
+	for _, v := range vs {
+		c = append(c, v.Code)
+	}
+	return c
+}
+
+func TestPolicyConfig_Policies(t *testing.T) {
+	c := PolicyConfig{
+		Config: Config{MinCharacters: 8},
+		SpaceTypes: map[string]Config{
+			"project": {MinCharacters: 12, MinScore: 4},
+		},
+	}
+	p := c.Policies()
+
+	assert.NoError(t, p.Validate("Passw0rd"))
+	assert.NoError(t, p.For("personal").Validate("Passw0rd"))
+	assert.Equal(t, []string{ViolationMinCharacters, ViolationTooWeak}, codes(Violations(p.For("project").Validate("Passw0rd"))))
+	assert.NoError(t, p.For("project").Validate("hX7$kq2!Lp9z"))
+}
+
+func TestHistoryConfig(t *testing.T) {
+	h, err := HistoryConfig{}.History()
+	assert.NoError(t, err)
+	assert.Nil(t, h)
+
+	_, err = HistoryConfig{Depth: 2}.History()
+	assert.Error(t, err)
+
+	h, err = HistoryConfig{Depth: 2, Store: "memory"}.History()
+	assert.NoError(t, err)
+	assert.NotNil(t, h)
+}
+
+func TestViolationsStatusRoundTrip(t *testing.T) {
+	err := NewPasswordPolicy(8, 0, 0, 2, 0, nil).Validate("abc")
+	st := &rpc.Status{InnerError: ViolationsOpaqueEntry(err)}
+
+	vs := ViolationsFromStatus(st)
+	if assert.Len(t, vs, 2) {
+		assert.Equal(t, ViolationMinCharacters, vs[0].Code)
+		assert.Equal(t, "at least 8 characters are required", vs[0].Message)
+		assert.EqualValues(t, 8, vs[0].Params["min"])
+		assert.Equal(t, ViolationMinDigits, vs[1].Code)
+	}
+	assert.Nil(t, ViolationsFromStatus(&rpc.Status{}))
+}
@@ -0,0 +1,143 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	microstore "go-micro.dev/v4/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HistoryStore persists the hashes of the previous passwords of a subject
+type HistoryStore interface {
+	Get(subject string) ([]string, error)
+	Set(subject string, hashes []string) error
+	Delete(subject string) error
+}
+
+// LinkSubject returns the history subject of a public link
+func LinkSubject(id string) string {
+	return "link:" + id
+}
+
+// AccountSubject returns the history subject of a user account
+func AccountSubject(username string) string {
+	return "account:" + username
+}
+
+// History remembers the last passwords of links or accounts and rejects
+// reusing any of them. Only bcrypt hashes of the passwords are kept.
+type History struct {
+	depth int
+	store HistoryStore
+}
+
+// NewHistory returns a history keeping depth passwords per subject
+func NewHistory(depth int, s HistoryStore) *History {
+	return &History{depth: depth, store: s}
+}
+
+// Check returns a violation if password is one of the remembered passwords of subject
+func (h *History) Check(subject, password string) error {
+	if h == nil {
+		return nil
+	}
+	hashes, err := h.store.Get(subject)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return newViolation(ViolationReused, map[string]interface{}{"depth": h.depth}, "the password was used recently, the last %d passwords cannot be reused", h.depth)
+		}
+	}
+	return nil
+}
+
+// Remember adds password to the history of subject, dropping the oldest
+// passwords beyond the depth of the history
+func (h *History) Remember(subject, password string) error {
+	if h == nil {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrap(err, "error hashing password")
+	}
+	hashes, err := h.store.Get(subject)
+	if err != nil {
+		return err
+	}
+	hashes = append([]string{string(hash)}, hashes...)
+	if len(hashes) > h.depth {
+		hashes = hashes[:h.depth]
+	}
+	return h.store.Set(subject, hashes)
+}
+
+// Forget removes the history of subject
+func (h *History) Forget(subject string) error {
+	if h == nil {
+		return nil
+	}
+	return h.store.Delete(subject)
+}
+
+type microHistoryStore struct {
+	s microstore.Store
+}
+
+// NewMicroHistoryStore returns a history store persisting into a go-micro store
+func NewMicroHistoryStore(s microstore.Store) HistoryStore {
+	return microHistoryStore{s: s}
+}
+
+func (m microHistoryStore) Get(subject string) ([]string, error) {
+	recs, err := m.s.Read(subject)
+	switch {
+	case err == microstore.ErrNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "error reading password history")
+	case len(recs) == 0:
+		return nil, nil
+	}
+	var hashes []string
+	if err := json.Unmarshal(recs[0].Value, &hashes); err != nil {
+		return nil, errors.Wrap(err, "error decoding password history")
+	}
+	return hashes, nil
+}
+
+func (m microHistoryStore) Set(subject string, hashes []string) error {
+	b, err := json.Marshal(hashes)
+	if err != nil {
+		return errors.Wrap(err, "error encoding password history")
+	}
+	return errors.Wrap(m.s.Write(&microstore.Record{Key: subject, Value: b}), "error writing password history")
+}
+
+func (m microHistoryStore) Delete(subject string) error {
+	if err := m.s.Delete(subject); err != nil && err != microstore.ErrNotFound {
+		return errors.Wrap(err, "error deleting password history")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+)
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(2, NewMicroHistoryStore(microstore.NewMemoryStore()))
+	subject := LinkSubject("share")
+
+	require.NoError(t, h.Check(subject, "first"))
+	require.NoError(t, h.Remember(subject, "first"))
+	require.NoError(t, h.Remember(subject, "second"))
+
+	vs := Violations(h.Check(subject, "first"))
+	if assert.Len(t, vs, 1) {
+		assert.Equal(t, ViolationReused, vs[0].Code)
+	}
+	assert.NoError(t, h.Check(AccountSubject("share"), "first"))
+
+	require.NoError(t, h.Remember(subject, "third"))
+	assert.NoError(t, h.Check(subject, "first"))
+	assert.Error(t, h.Check(subject, "second"))
+
+	require.NoError(t, h.Forget(subject))
+	assert.NoError(t, h.Check(subject, "third"))
+
+	var disabled *History
+	assert.NoError(t, disabled.Check(subject, "third"))
+	assert.NoError(t, disabled.Remember(subject, "third"))
+}
@@ -2,7 +2,6 @@ package password
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
 	"strings"
 	"unicode/utf8"
@@ -48,7 +47,7 @@ func NewPasswordPolicy(minCharacters, minLowerCaseCharacters, minUpperCaseCharac
 func (s Policies) Validate(str string) error {
 	var allErr error
 	if !utf8.ValidString(str) {
-		return fmt.Errorf("the password contains invalid characters")
+		return newViolation(ViolationInvalidCharacters, nil, "the password contains invalid characters")
 	}
 	err := s.validateBannedList(str)
 	if err != nil {
@@ -85,42 +84,42 @@ func (s Policies) validateBannedList(str string) error {
 		return nil
 	}
 	if _, ok := s.bannedPasswordsList[str]; ok {
-		return fmt.Errorf("unfortunately, your password is commonly used. please pick a harder-to-guess password for your safety")
+		return newViolation(ViolationBanned, nil, "unfortunately, your password is commonly used. please pick a harder-to-guess password for your safety")
 	}
 	return nil
 }
 
 func (s Policies) validateCharacters(str string) error {
 	if s.count(str) < s.minCharacters {
-		return fmt.Errorf("at least %d characters are required", s.minCharacters)
+		return newViolation(ViolationMinCharacters, map[string]interface{}{"min": s.minCharacters}, "at least %d characters are required", s.minCharacters)
 	}
 	return nil
 }
 
 func (s Policies) validateLowerCase(str string) error {
 	if s.countLowerCaseCharacters(str) < s.minLowerCaseCharacters {
-		return fmt.Errorf("at least %d lowercase letters are required", s.minLowerCaseCharacters)
+		return newViolation(ViolationMinLowerCase, map[string]interface{}{"min": s.minLowerCaseCharacters}, "at least %d lowercase letters are required", s.minLowerCaseCharacters)
 	}
 	return nil
 }
 
 func (s Policies) validateUpperCase(str string) error {
 	if s.countUpperCaseCharacters(str) < s.minUpperCaseCharacters {
-		return fmt.Errorf("at least %d uppercase letters are required", s.minUpperCaseCharacters)
+		return newViolation(ViolationMinUpperCase, map[string]interface{}{"min": s.minUpperCaseCharacters}, "at least %d uppercase letters are required", s.minUpperCaseCharacters)
 	}
 	return nil
 }
 
 func (s Policies) validateDigits(str string) error {
 	if s.countDigits(str) < s.minDigits {
-		return fmt.Errorf("at least %d numbers are required", s.minDigits)
+		return newViolation(ViolationMinDigits, map[string]interface{}{"min": s.minDigits}, "at least %d numbers are required", s.minDigits)
 	}
 	return nil
 }
 
 func (s Policies) validateSpecialCharacters(str string) error {
 	if s.countSpecialCharacters(str) < s.minSpecialCharacters {
-		return fmt.Errorf("at least %d special characters are required %s", s.minSpecialCharacters, _defaultSpecialCharacters)
+		return newViolation(ViolationMinSpecialCharacters, map[string]interface{}{"min": s.minSpecialCharacters, "characters": _defaultSpecialCharacters}, "at least %d special characters are required %s", s.minSpecialCharacters, _defaultSpecialCharacters)
 	}
 	return nil
 }
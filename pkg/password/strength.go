@@ -0,0 +1,185 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonWords are matched case insensitively and after undoing common
+// character substitutions, they add little entropy to a password.
+var commonWords = []string{
+	"password", "passwort", "passw", "secret", "qwerty", "qwertz", "azerty", "letmein",
+	"welcome", "admin", "login", "iloveyou", "monkey", "dragon", "master", "shadow",
+	"sunshine", "princess", "football", "baseball", "superman", "batman", "trustno",
+	"hello", "freedom", "whatever", "starwars", "summer", "winter", "spring", "autumn",
+	"changeme", "default", "guest", "user", "test", "love", "abc", "opencloud", "cloud",
+}
+
+var substitutions = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's', '!': 'i',
+}
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// Strength rejects passwords whose estimated strength is below a minimum score.
+type Strength struct {
+	minScore int
+}
+
+// NewStrength returns a validator requiring at least the given Score
+func NewStrength(minScore int) Validator {
+	return Strength{minScore: minScore}
+}
+
+// Validate implements the Validator interface
+func (s Strength) Validate(str string) error {
+	if score := Score(str); score < s.minScore {
+		return newViolation(ViolationTooWeak, map[string]interface{}{"min": s.minScore, "score": score}, "the password is too easy to guess, a strength of at least %d is required", s.minScore)
+	}
+	return nil
+}
+
+// Score rates how hard a password is to guess on the scale used by zxcvbn,
+// from 0 (too guessable) to 4 (very unguessable).
+func Score(str string) int {
+	bits := Entropy(str)
+	switch {
+	case bits < 20:
+		return 0
+	case bits < 35:
+		return 1
+	case bits < 50:
+		return 2
+	case bits < 65:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Entropy estimates the entropy of a password in bits. Every character adds
+// the entropy of the character classes used in the password unless it repeats
+// or continues the previous one, e.g. in "aaa", "abc" or "asdf". Common words
+// only add the entropy of picking them from the word list.
+func Entropy(str string) float64 {
+	runes := []rune(strings.ToLower(str))
+	normalized := make([]rune, len(runes))
+	for i, r := range runes {
+		if s, ok := substitutions[r]; ok {
+			r = s
+		}
+		normalized[i] = r
+	}
+
+	var bits float64
+	covered := make([]bool, len(runes))
+	wordBits := math.Log2(float64(len(commonWords))) + 1
+	for _, w := range commonWords {
+		word := []rune(w)
+		for i := 0; i+len(word) <= len(normalized); i++ {
+			if string(normalized[i:i+len(word)]) != w || anyCovered(covered[i:i+len(word)]) {
+				continue
+			}
+			for j := i; j < i+len(word); j++ {
+				covered[j] = true
+			}
+			bits += wordBits
+		}
+	}
+
+	charBits := math.Log2(float64(charsetSize(str)))
+	for i, r := range runes {
+		if covered[i] {
+			continue
+		}
+		if i > 0 && !covered[i-1] && predictable(runes[i-1], r) {
+			bits++
+			continue
+		}
+		bits += charBits
+	}
+	return bits
+}
+
+func anyCovered(c []bool) bool {
+	for _, b := range c {
+		if b {
+			return true
+		}
+	}
+	return false
+}
+
+// predictable reports whether r repeats or continues prev
+func predictable(prev, r rune) bool {
+	if prev == r {
+		return true
+	}
+	if (unicode.IsLetter(prev) && unicode.IsLetter(r)) || (unicode.IsDigit(prev) && unicode.IsDigit(r)) {
+		if d := r - prev; d == 1 || d == -1 {
+			return true
+		}
+	}
+	for _, row := range keyboardRows {
+		i, j := strings.IndexRune(row, prev), strings.IndexRune(row, r)
+		if i >= 0 && j >= 0 && (i-j == 1 || j-i == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// charsetSize returns the number of characters in the classes used by str
+func charsetSize(str string) int {
+	var lower, upper, digits, special, other bool
+	for _, r := range str {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digits = true
+		case strings.ContainsRune(_defaultSpecialCharacters, r):
+			special = true
+		default:
+			other = true
+		}
+	}
+	size := 1
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digits {
+		size += 10
+	}
+	if special {
+		size += len(_defaultSpecialCharacters)
+	}
+	if other {
+		size += 100
+	}
+	return size
+}
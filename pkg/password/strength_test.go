@@ -0,0 +1,44 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"", 0},
+		{"aaaaaaaaaaaaaaaa", 0},
+		{"abcdefgh", 0},
+		{"qwertzuiop", 0},
+		{"P@ssw0rd123", 0},
+		{"SecretPassw0rd!", 0},
+		{"kd8Wm", 1},
+		{"hq7mzkbe", 2},
+		{"hG7$kq2!Lp", 3},
+		{"hX7$kq2!Lp9z", 4},
+		{"correct horse battery staple", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			assert.Equal(t, tt.want, Score(tt.password))
+		})
+	}
+}
+
+func TestStrength_Validate(t *testing.T) {
+	s := NewStrength(3)
+	assert.NoError(t, s.Validate("hG7$kq2!Lp"))
+
+	err := s.Validate("SecretPassw0rd!")
+	vs := Violations(err)
+	if assert.Len(t, vs, 1) {
+		assert.Equal(t, ViolationTooWeak, vs[0].Code)
+		assert.Equal(t, 3, vs[0].Params["min"])
+		assert.Equal(t, "the password is too easy to guess, a strength of at least 3 is required", err.Error())
+	}
+}
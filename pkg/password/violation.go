@@ -0,0 +1,116 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// Codes of the rules a password can violate. Clients can use them to look up
+// a translated message and fill in the Params of the Violation.
+const (
+	ViolationInvalidCharacters    = "invalid_characters"
+	ViolationBanned               = "banned"
+	ViolationMinCharacters        = "min_characters"
+	ViolationMinLowerCase         = "min_lowercase_characters"
+	ViolationMinUpperCase         = "min_uppercase_characters"
+	ViolationMinDigits            = "min_digits"
+	ViolationMinSpecialCharacters = "min_special_characters"
+	ViolationTooWeak              = "too_weak"
+	ViolationBreached             = "breached"
+	ViolationReused               = "reused"
+)
+
+// violationsDecoder is the decoder name used for violations in opaque entries
+const violationsDecoder = "json"
+
+// Violation is returned by validators when a password does not satisfy a rule.
+type Violation struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty" xml:"-"`
+}
+
+func newViolation(code string, params map[string]interface{}, format string, a ...interface{}) *Violation {
+	return &Violation{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+		Params:  params,
+	}
+}
+
+// Error returns the english message of the violation
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// Violations returns all violations contained in err, which may have been
+// joined from the results of several validators.
+func Violations(err error) []*Violation {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var vs []*Violation
+		for _, e := range joined.Unwrap() {
+			vs = append(vs, Violations(e)...)
+		}
+		return vs
+	}
+	var v *Violation
+	if errors.As(err, &v) {
+		return []*Violation{v}
+	}
+	return nil
+}
+
+// ViolationsOpaqueEntry encodes the violations contained in err so they can be
+// passed along as the inner error of a rpc status.
+func ViolationsOpaqueEntry(err error) *types.OpaqueEntry {
+	vs := Violations(err)
+	if len(vs) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(vs)
+	if err != nil {
+		return nil
+	}
+	return &types.OpaqueEntry{
+		Decoder: violationsDecoder,
+		Value:   b,
+	}
+}
+
+// ViolationsFromStatus decodes the violations carried by a rpc status
+func ViolationsFromStatus(s *rpc.Status) []*Violation {
+	e := s.GetInnerError()
+	if e == nil || e.GetDecoder() != violationsDecoder {
+		return nil
+	}
+	var vs []*Violation
+	if err := json.Unmarshal(e.GetValue(), &vs); err != nil {
+		return nil
+	}
+	return vs
+}
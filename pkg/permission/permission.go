@@ -37,6 +37,10 @@ const (
 	WriteFavorites string = "Favorites.Write"
 	// DeleteReadOnlyPassword is the hardcoded name for the ReadOnlyPublicLinkPassword.Delete permission
 	DeleteReadOnlyPassword string = "ReadOnlyPublicLinkPassword.Delete"
+	// WriteAccounts is the hardcoded name for the Accounts.ReadWrite permission
+	WriteAccounts string = "Accounts.ReadWrite"
+	// WriteGroups is the hardcoded name for the Groups.ReadWrite permission
+	WriteGroups string = "Groups.ReadWrite"
 )
 
 // Manager defines the interface for the permission service driver
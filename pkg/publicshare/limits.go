@@ -0,0 +1,134 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+)
+
+const (
+	// LimitsOpaqueKey is the opaque key carrying the usage limits in create
+	// and update requests.
+	LimitsOpaqueKey = "link-limits"
+	// UsageOpaqueKey is the opaque key carrying the limits and counters of
+	// public shares in responses. Single share responses carry a Usage,
+	// list responses a map of usages by share id.
+	UsageOpaqueKey = "link-usage"
+	// AccessOpaqueKey is the opaque key of GetPublicShare requests that make
+	// the provider record an Access of the share.
+	AccessOpaqueKey = "link-access"
+)
+
+// Limits restrict how often a public share can be used. Zero values mean
+// unlimited.
+type Limits struct {
+	// MaxDownloads is the number of downloads after which the share stops working
+	MaxDownloads uint64 `json:"max_downloads,omitempty"`
+	// MaxVisitors is the number of distinct visitors that can access the share
+	MaxVisitors uint64 `json:"max_visitors,omitempty"`
+	// OneTime shares can only be downloaded once
+	OneTime bool `json:"one_time,omitempty"`
+}
+
+// Limited tells whether any limit is set.
+func (l Limits) Limited() bool {
+	return l.MaxDownloads > 0 || l.MaxVisitors > 0 || l.OneTime
+}
+
+// Usage holds the limits of a public share and counts its uses. Only shares
+// with limits are tracked.
+type Usage struct {
+	Limits
+	// Downloads counts the downloads
+	Downloads uint64 `json:"downloads,omitempty"`
+	// Visitors are the hashed ids of the visitors, they are only tracked when
+	// the number of visitors is limited
+	Visitors []string `json:"visitors,omitempty"`
+}
+
+// Access is an access of a public share.
+type Access struct {
+	// Download is set if the access is a download
+	Download bool `json:"download,omitempty"`
+	// Visitor identifies the visitor, if known
+	Visitor string `json:"visitor,omitempty"`
+}
+
+// Record checks the access against the limits and counts it. It returns
+// whether the usage changed, or a PermissionDenied error without counting
+// the access if a limit has been reached. Every download counts, visitors
+// are identified by values the client controls and can't be trusted to
+// tell a resumed download from a new one.
+func (u *Usage) Record(a Access) (bool, error) {
+	if a.Download {
+		if u.OneTime && u.Downloads > 0 {
+			return false, errtypes.PermissionDenied("public share has already been used")
+		}
+		if u.MaxDownloads > 0 && u.Downloads >= u.MaxDownloads {
+			return false, errtypes.PermissionDenied("public share download limit reached")
+		}
+	}
+
+	changed := false
+	if u.MaxVisitors > 0 && a.Visitor != "" && !slices.Contains(u.Visitors, a.Visitor) {
+		if uint64(len(u.Visitors)) >= u.MaxVisitors {
+			return false, errtypes.PermissionDenied("public share visitor limit reached")
+		}
+		u.Visitors = append(u.Visitors, a.Visitor)
+		changed = true
+	}
+	if a.Download {
+		u.Downloads++
+		changed = true
+	}
+	return changed, nil
+}
+
+// HashVisitor returns the id under which a visitor is tracked, so that the
+// managers do not persist the identifiers handed out to the clients.
+func HashVisitor(visitor string) string {
+	if visitor == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(visitor))
+	return hex.EncodeToString(h[:16])
+}
+
+// LimitedManager is implemented by managers that support usage limits on
+// public shares.
+type LimitedManager interface {
+	// SetLimits replaces the limits of a public share, the counters are kept.
+	// Setting no limits stops tracking the share.
+	SetLimits(ctx context.Context, u *user.User, ref *link.PublicShareReference, l Limits) error
+	// GetUsage returns the usage of the public shares with the given ids.
+	// Shares without limits are omitted.
+	GetUsage(ctx context.Context, ids []string) (map[string]*Usage, error)
+	// RecordAccess atomically records an access of a public share. It returns
+	// the updated usage, nil if the share has no limits, and a
+	// PermissionDenied error if a limit has been reached. Accesses recorded by
+	// several instances of the manager must not get lost.
+	RecordAccess(ctx context.Context, ref *link.PublicShareReference, a Access) (*Usage, error)
+}
@@ -51,6 +51,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/metadata"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
@@ -58,6 +59,9 @@ func init() {
 	registry.Register("json", NewFile)
 	registry.Register("jsoncs3", NewCS3)
 	registry.Register("jsonmemory", NewMemory)
+	cfg.Register("publicshare.manager", "json", fileConfig{})
+	cfg.Register("publicshare.manager", "jsoncs3", cs3Config{})
+	cfg.Register("publicshare.manager", "jsonmemory", commonConfig{})
 }
 
 // NewFile returns a new filesystem public shares manager.
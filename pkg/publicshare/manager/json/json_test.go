@@ -20,6 +20,7 @@ package json_test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -170,6 +171,139 @@ var _ = Describe("Json", func() {
 				Expect(loadedPublicShare).ToNot(BeNil())
 			})
 		})
+
+		Describe("Limits", func() {
+			var (
+				lm    publicshare.LimitedManager
+				share *link.PublicShare
+				ref   *link.PublicShareReference
+			)
+
+			BeforeEach(func() {
+				var err error
+				lm = m.(publicshare.LimitedManager)
+				share, err = m.CreatePublicShare(ctx, user1, sharedResource, grant)
+				Expect(err).ToNot(HaveOccurred())
+				ref = &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: share.Token}}
+			})
+
+			It("does not track shares without limits", func() {
+				u, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: "v1"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(u).To(BeNil())
+
+				usages, err := lm.GetUsage(ctx, []string{share.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages).To(BeEmpty())
+			})
+
+			It("enforces the download limit", func() {
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{MaxDownloads: 2})).To(Succeed())
+
+				for i := 0; i < 2; i++ {
+					_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+					Expect(err).ToNot(HaveOccurred())
+				}
+				_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).To(MatchError(ContainSubstring("download limit reached")))
+
+				// visits are still possible
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v1"})
+				Expect(err).ToNot(HaveOccurred())
+
+				usages, err := lm.GetUsage(ctx, []string{share.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages[share.Id.OpaqueId].Downloads).To(Equal(uint64(2)))
+			})
+
+			It("enforces the visitor limit", func() {
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{MaxVisitors: 1})).To(Succeed())
+
+				_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v1"})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v1", Download: true})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v2"})
+				Expect(err).To(MatchError(ContainSubstring("visitor limit reached")))
+
+				// raising the limit keeps the counters
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{MaxVisitors: 2})).To(Succeed())
+				u, err := lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v2"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(u.Visitors).To(Equal([]string{"v1", "v2"}))
+				Expect(u.Downloads).To(Equal(uint64(1)))
+			})
+
+			It("allows a single download of one-time shares", func() {
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{OneTime: true})).To(Succeed())
+
+				_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v2"})
+				Expect(err).ToNot(HaveOccurred())
+				u, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: "v1"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(u.Downloads).To(Equal(uint64(1)))
+
+				// the visitor id is sent by the client, it does not grant further downloads
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: "v1"})
+				Expect(err).To(MatchError(ContainSubstring("already been used")))
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: "v2"})
+				Expect(err).To(MatchError(ContainSubstring("already been used")))
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).To(MatchError(ContainSubstring("already been used")))
+			})
+
+			It("does not lose the accesses of other instances", func() {
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{MaxDownloads: 100})).To(Succeed())
+				m2, err := json.NewFile(map[string]interface{}{
+					"file":         tmpFile.Name(),
+					"gateway_addr": "https://localhost:9200",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				wg := sync.WaitGroup{}
+				for i, lm := range []publicshare.LimitedManager{lm, m2.(publicshare.LimitedManager)} {
+					for j := 0; j < 10; j++ {
+						wg.Add(1)
+						go func() {
+							defer GinkgoRecover()
+							defer wg.Done()
+							_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: fmt.Sprintf("v%d-%d", i, j)})
+							Expect(err).ToNot(HaveOccurred())
+						}()
+					}
+				}
+				wg.Wait()
+
+				usages, err := lm.GetUsage(ctx, []string{share.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages[share.Id.OpaqueId].Downloads).To(Equal(uint64(20)))
+			})
+
+			It("stops tracking when the limits are removed", func() {
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{MaxDownloads: 1})).To(Succeed())
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{})).To(Succeed())
+
+				usages, err := lm.GetUsage(ctx, []string{share.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages).To(BeEmpty())
+			})
+
+			It("keeps the usage when the share is updated", func() {
+				Expect(lm.SetLimits(ctx, user1, ref, publicshare.Limits{MaxDownloads: 1})).To(Succeed())
+				_, err := m.UpdatePublicShare(ctx, user1, &link.UpdatePublicShareRequest{
+					Ref: ref,
+					Update: &link.UpdatePublicShareRequest_Update{
+						Type:        link.UpdatePublicShareRequest_Update_TYPE_DISPLAYNAME,
+						DisplayName: "renamed",
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				usages, err := lm.GetUsage(ctx, []string{share.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages[share.Id.OpaqueId].MaxDownloads).To(Equal(uint64(1)))
+			})
+		})
 	})
 
 	Context("with a cs3 persistence layer", func() {
@@ -0,0 +1,213 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/json/persistence"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the usage of shares with limits is kept next to the share and its password
+const usageKey = "usage"
+
+// recordAttempts is the number of times an access is recorded before giving
+// up on concurrent writes of other instances
+const recordAttempts = 5
+
+// SetLimits replaces the limits of a public share
+func (m *manager) SetLimits(ctx context.Context, _ *user.User, ref *link.PublicShareReference, l publicshare.Limits) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.init(); err != nil {
+		return err
+	}
+
+	db, err := m.persistence.Read(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, data, err := findEntry(db, ref)
+	if err != nil {
+		return err
+	}
+
+	if !l.Limited() {
+		if _, ok := data[usageKey]; !ok {
+			return nil
+		}
+		delete(data, usageKey)
+		return m.persistence.Write(ctx, db)
+	}
+
+	u, err := decodeUsage(data)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		u = &publicshare.Usage{}
+	}
+	u.Limits = l
+	if err := encodeUsage(data, u); err != nil {
+		return err
+	}
+	return m.persistence.Write(ctx, db)
+}
+
+// GetUsage returns the usage of the public shares with limits
+func (m *manager) GetUsage(ctx context.Context, ids []string) (map[string]*publicshare.Usage, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+
+	db, err := m.persistence.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := map[string]*publicshare.Usage{}
+	for _, id := range ids {
+		data, ok := db[id].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		u, err := decodeUsage(data)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			usages[id] = u
+		}
+	}
+	return usages, nil
+}
+
+// RecordAccess records an access of a public share with limits. Persistence
+// layers that detect concurrent writes of other instances are retried, the
+// others are locked.
+func (m *manager) RecordAccess(ctx context.Context, ref *link.PublicShareReference, a publicshare.Access) (*publicshare.Usage, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+
+	if l, ok := m.persistence.(persistence.Locker); ok {
+		unlock, err := l.Lock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = unlock() }()
+	}
+
+	for i := 0; i < recordAttempts; i++ {
+		u, err := m.recordAccess(ctx, ref, a)
+		switch err.(type) {
+		case errtypes.IsPreconditionFailed, errtypes.IsAborted:
+			// the db was written concurrently, try again
+			continue
+		}
+		return u, err
+	}
+	return nil, errtypes.Aborted("public share is being accessed concurrently")
+}
+
+// recordAccess doesn't have a lock inside, ensure a lock before call
+func (m *manager) recordAccess(ctx context.Context, ref *link.PublicShareReference, a publicshare.Access) (*publicshare.Usage, error) {
+	db, err := m.persistence.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err := findEntry(db, ref)
+	if err != nil {
+		return nil, err
+	}
+	u, err := decodeUsage(data)
+	if err != nil || u == nil {
+		return nil, err
+	}
+
+	changed, err := u.Record(a)
+	if err != nil || !changed {
+		return u, err
+	}
+	if err := encodeUsage(data, u); err != nil {
+		return nil, err
+	}
+	return u, m.persistence.Write(ctx, db)
+}
+
+// findEntry doesn't have a lock inside, ensure a lock before call
+func findEntry(db map[string]interface{}, ref *link.PublicShareReference) (string, map[string]interface{}, error) {
+	if id := ref.GetId().GetOpaqueId(); id != "" {
+		if data, ok := db[id].(map[string]interface{}); ok {
+			return id, data, nil
+		}
+		return "", nil, errtypes.NotFound("no shares found by id:" + id)
+	}
+	if token := ref.GetToken(); token != "" {
+		for id, v := range db {
+			data := v.(map[string]interface{})
+			var ps link.PublicShare
+			if err := utils.UnmarshalJSONToProtoV1([]byte(data["share"].(string)), &ps); err != nil {
+				return "", nil, err
+			}
+			if ps.Token == token {
+				return id, data, nil
+			}
+		}
+		return "", nil, errtypes.NotFound("no shares found by token")
+	}
+	return "", nil, errtypes.BadRequest("neither id nor token given")
+}
+
+func decodeUsage(data map[string]interface{}) (*publicshare.Usage, error) {
+	v, ok := data[usageKey].(string)
+	if !ok {
+		return nil, nil
+	}
+	u := &publicshare.Usage{}
+	if err := json.Unmarshal([]byte(v), u); err != nil {
+		return nil, errors.Wrap(err, "could not decode public share usage")
+	}
+	return u, nil
+}
+
+func encodeUsage(data map[string]interface{}, u *publicshare.Usage) error {
+	v, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	data[usageKey] = string(v)
+	return nil
+}
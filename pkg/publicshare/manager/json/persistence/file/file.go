@@ -27,6 +27,7 @@ import (
 	"sync"
 
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/json/persistence"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/filelocks"
 )
 
 type file struct {
@@ -104,6 +105,18 @@ func (p *file) Write(_ context.Context, db persistence.PublicShares) error {
 	return os.WriteFile(p.path, dbAsJSON, 0644)
 }
 
+// Lock locks the db file against other processes
+func (p *file) Lock(_ context.Context) (func() error, error) {
+	if !p.isInitialized() {
+		return nil, fmt.Errorf("not initialized")
+	}
+	l, err := filelocks.AcquireWriteLock(p.path)
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return filelocks.ReleaseLock(l) }, nil
+}
+
 func (p *file) isInitialized() bool {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -29,3 +29,9 @@ type Persistence interface {
 	Read(context.Context) (PublicShares, error)
 	Write(context.Context, PublicShares) error
 }
+
+// Locker is implemented by persistence layers that are shared by processes
+// which cannot detect concurrent writes, it guards read-modify-write cycles.
+type Locker interface {
+	Lock(context.Context) (unlock func() error, err error)
+}
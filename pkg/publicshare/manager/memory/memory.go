@@ -36,10 +36,12 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 func init() {
 	registry.Register("memory", New)
+	cfg.Register("publicshare.manager", "memory", struct{}{})
 }
 
 // New returns a new memory manager.
@@ -0,0 +1,212 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package owncloudsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
+	"github.com/pkg/errors"
+)
+
+// The ownCloud 10 schema has no place for the usage limits of public links,
+// they are kept in an additional table that is created on first use.
+const createUsageTable = `CREATE TABLE IF NOT EXISTS oc_share_link_usage (
+	share_id BIGINT NOT NULL PRIMARY KEY,
+	max_downloads BIGINT NOT NULL DEFAULT 0,
+	max_visitors BIGINT NOT NULL DEFAULT 0,
+	one_time SMALLINT NOT NULL DEFAULT 0,
+	downloads BIGINT NOT NULL DEFAULT 0,
+	visitors TEXT,
+	version BIGINT NOT NULL DEFAULT 0
+)`
+
+// recordAttempts is the number of times an access is recorded before giving
+// up on concurrent updates of the same share
+const recordAttempts = 5
+
+func (m *mgr) ensureUsageTable() error {
+	m.usageTableOnce.Do(func() {
+		_, m.usageTableErr = m.db.Exec(createUsageTable)
+	})
+	return errors.Wrap(m.usageTableErr, "could not create public link usage table")
+}
+
+// SetLimits replaces the limits of a public share
+func (m *mgr) SetLimits(ctx context.Context, _ *user.User, ref *link.PublicShareReference, l publicshare.Limits) error {
+	if err := m.ensureUsageTable(); err != nil {
+		return err
+	}
+	id, err := m.shareID(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	if !l.Limited() {
+		_, err := m.db.Exec("DELETE FROM oc_share_link_usage WHERE share_id=?", id)
+		return err
+	}
+
+	res, err := m.db.Exec("UPDATE oc_share_link_usage SET max_downloads=?, max_visitors=?, one_time=?, version=version+1 WHERE share_id=?",
+		l.MaxDownloads, l.MaxVisitors, boolToInt(l.OneTime), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = m.db.Exec("INSERT INTO oc_share_link_usage (share_id, max_downloads, max_visitors, one_time) VALUES (?,?,?,?)",
+		id, l.MaxDownloads, l.MaxVisitors, boolToInt(l.OneTime))
+	return err
+}
+
+// GetUsage returns the usage of the public shares with limits
+func (m *mgr) GetUsage(_ context.Context, ids []string) (map[string]*publicshare.Usage, error) {
+	usages := map[string]*publicshare.Usage{}
+	if len(ids) == 0 {
+		return usages, nil
+	}
+	if err := m.ensureUsageTable(); err != nil {
+		return nil, err
+	}
+
+	params := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		params = append(params, id)
+	}
+	query := `SELECT share_id, max_downloads, max_visitors, one_time, downloads, coalesce(visitors, '') as visitors
+			FROM oc_share_link_usage
+			WHERE share_id IN (?` + strings.Repeat(",?", len(ids)-1) + ")"
+	rows, err := m.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		u, err := scanUsage(rows.Scan, &id)
+		if err != nil {
+			return nil, err
+		}
+		usages[id] = u
+	}
+	return usages, rows.Err()
+}
+
+// RecordAccess records an access of a public share with limits. Concurrent
+// accesses are serialized by the version of the usage row.
+func (m *mgr) RecordAccess(ctx context.Context, ref *link.PublicShareReference, a publicshare.Access) (*publicshare.Usage, error) {
+	if err := m.ensureUsageTable(); err != nil {
+		return nil, err
+	}
+	id, err := m.shareID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT max_downloads, max_visitors, one_time, downloads, coalesce(visitors, '') as visitors, version
+			FROM oc_share_link_usage WHERE share_id=?`
+	for i := 0; i < recordAttempts; i++ {
+		var version int64
+		u, err := scanUsage(m.db.QueryRow(query, id).Scan, nil, &version)
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, nil
+		case err != nil:
+			return nil, err
+		}
+
+		changed, err := u.Record(a)
+		if err != nil || !changed {
+			return u, err
+		}
+
+		visitors, err := json.Marshal(u.Visitors)
+		if err != nil {
+			return nil, err
+		}
+		res, err := m.db.Exec("UPDATE oc_share_link_usage SET downloads=?, visitors=?, version=version+1 WHERE share_id=? AND version=?",
+			u.Downloads, string(visitors), id, version)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			// the usage was changed concurrently, try again
+			continue
+		}
+		return u, nil
+	}
+	return nil, errtypes.Aborted("public share is being accessed concurrently")
+}
+
+// shareID resolves the id of a public share
+func (m *mgr) shareID(ctx context.Context, ref *link.PublicShareReference) (string, error) {
+	if id := ref.GetId().GetOpaqueId(); id != "" {
+		return id, nil
+	}
+	if ref.GetToken() == "" {
+		return "", errtypes.BadRequest("neither id nor token given")
+	}
+	s, err := getByToken(m.db, ref.GetToken())
+	if err != nil {
+		return "", err
+	}
+	return s.ID, nil
+}
+
+// removeOrphanedUsage removes the usage of shares that no longer exist. The
+// table only exists if limits have been used, so errors are ignored.
+func (m *mgr) removeOrphanedUsage() {
+	_, _ = m.db.Exec("DELETE FROM oc_share_link_usage WHERE share_id NOT IN (SELECT id FROM oc_share)")
+}
+
+// scanUsage scans a usage row with the leading and trailing columns given as destinations
+func scanUsage(scan func(dest ...interface{}) error, id *string, trailing ...interface{}) (*publicshare.Usage, error) {
+	u := &publicshare.Usage{}
+	var oneTime int
+	var visitors string
+	dest := []interface{}{&u.MaxDownloads, &u.MaxVisitors, &oneTime, &u.Downloads, &visitors}
+	if id != nil {
+		dest = append([]interface{}{id}, dest...)
+	}
+	if err := scan(append(dest, trailing...)...); err != nil {
+		return nil, err
+	}
+	u.OneTime = oneTime != 0
+	if visitors != "" {
+		if err := json.Unmarshal([]byte(visitors), &u.Visitors); err != nil {
+			return nil, errors.Wrap(err, "could not decode public share visitors")
+		}
+	}
+	return u, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
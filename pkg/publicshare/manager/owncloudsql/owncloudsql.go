@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -40,6 +41,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/publicshare/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/protobuf/proto"
@@ -54,6 +56,7 @@ const (
 
 func init() {
 	registry.Register("owncloudsql", NewMysql)
+	cfg.Register("publicshare.manager", "owncloudsql", Config{})
 }
 
 // Config configures an owncloudsql publicshare manager
@@ -73,6 +76,9 @@ type mgr struct {
 	db            *sql.DB
 	c             Config
 	userConverter UserConverter
+
+	usageTableOnce sync.Once
+	usageTableErr  error
 }
 
 // NewMysql returns a new publicshare manager connection to a mysql database
@@ -489,6 +495,7 @@ func (m *mgr) RevokePublicShare(ctx context.Context, u *user.User, ref *link.Pub
 	if rowCnt == 0 {
 		return errtypes.NotFound(ref.String())
 	}
+	m.removeOrphanedUsage()
 	return nil
 }
 
@@ -480,5 +480,89 @@ var _ = Describe("SQL manager", func() {
 
 			})
 		})
+
+		Describe("Limits", func() {
+			var (
+				lm  publicshare.LimitedManager
+				ref *link.PublicShareReference
+			)
+
+			JustBeforeEach(func() {
+				lm = m.(publicshare.LimitedManager)
+				ref = &link.PublicShareReference{
+					Spec: &link.PublicShareReference_Token{
+						Token: existingShare.Token,
+					},
+				}
+			})
+
+			It("does not track shares without limits", func() {
+				u, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(u).To(BeNil())
+			})
+
+			It("enforces the download and visitor limits", func() {
+				Expect(lm.SetLimits(ctx, user, ref, publicshare.Limits{MaxDownloads: 1, MaxVisitors: 1})).To(Succeed())
+
+				_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v1"})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Visitor: "v2"})
+				Expect(err).To(MatchError(ContainSubstring("visitor limit reached")))
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).To(MatchError(ContainSubstring("download limit reached")))
+
+				usages, err := lm.GetUsage(ctx, []string{existingShare.Id.OpaqueId, "unknown"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages).To(HaveLen(1))
+				u := usages[existingShare.Id.OpaqueId]
+				Expect(u.Downloads).To(Equal(uint64(1)))
+				Expect(u.Visitors).To(Equal([]string{"v1"}))
+				Expect(u.MaxDownloads).To(Equal(uint64(1)))
+			})
+
+			It("updates the limits and keeps the counters", func() {
+				Expect(lm.SetLimits(ctx, user, ref, publicshare.Limits{MaxDownloads: 1})).To(Succeed())
+				_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(lm.SetLimits(ctx, user, ref, publicshare.Limits{MaxDownloads: 2})).To(Succeed())
+				u, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(u.Downloads).To(Equal(uint64(2)))
+
+				Expect(lm.SetLimits(ctx, user, ref, publicshare.Limits{})).To(Succeed())
+				usages, err := lm.GetUsage(ctx, []string{existingShare.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages).To(BeEmpty())
+			})
+
+			It("allows a single download of one-time shares", func() {
+				Expect(lm.SetLimits(ctx, user, ref, publicshare.Limits{OneTime: true})).To(Succeed())
+				u, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: "v1"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(u.Downloads).To(Equal(uint64(1)))
+
+				for _, visitor := range []string{"v1", "v2"} {
+					_, err := lm.RecordAccess(ctx, ref, publicshare.Access{Download: true, Visitor: visitor})
+					Expect(err).To(MatchError(ContainSubstring("already been used")))
+				}
+
+				usages, err := lm.GetUsage(ctx, []string{existingShare.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages[existingShare.Id.OpaqueId].Downloads).To(Equal(uint64(1)))
+			})
+
+			It("removes the usage when the share is revoked", func() {
+				Expect(lm.SetLimits(ctx, user, ref, publicshare.Limits{MaxDownloads: 1})).To(Succeed())
+				Expect(m.RevokePublicShare(ctx, user, ref)).To(Succeed())
+
+				usages, err := lm.GetUsage(ctx, []string{existingShare.Id.OpaqueId})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usages).To(BeEmpty())
+			})
+		})
 	})
 })
@@ -0,0 +1,63 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+const (
+	// VisitorCookie identifies the browser of a public link visitor, it is
+	// used to count distinct visitors.
+	VisitorCookie = "link_visitor"
+	// VisitorOpaqueKey is the opaque key of InitiateFileDownload requests
+	// that carries the visitor downloading from a public share.
+	VisitorOpaqueKey = "link-visitor"
+)
+
+type visitorKey struct{}
+
+// Visitor returns the id of the visitor sending the request and whether the
+// visitor cookie has to be set. Clients that do not keep the cookie are
+// identified by their address and user agent, so that their requests are
+// still counted as one visitor.
+func Visitor(r *http.Request) (string, bool) {
+	if c, err := r.Cookie(VisitorCookie); err == nil && c.Value != "" {
+		return c.Value, false
+	}
+	ip, _ := utils.GetClientIP(r)
+	h := sha256.Sum256([]byte(ip + "\n" + r.UserAgent()))
+	return hex.EncodeToString(h[:16]), true
+}
+
+// ContextSetVisitor stores the visitor of a public share in the context.
+func ContextSetVisitor(ctx context.Context, visitor string) context.Context {
+	return context.WithValue(ctx, visitorKey{}, visitor)
+}
+
+// ContextGetVisitor returns the visitor of a public share stored in the context.
+func ContextGetVisitor(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(visitorKey{}).(string)
+	return v, ok && v != ""
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/internal/grpc/interceptors/useragent"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	rtrace "github.com/opencloud-eu/reva/v2/pkg/trace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	mtls "go-micro.dev/v4/util/tls"
@@ -46,6 +47,10 @@ import (
 	"google.golang.org/grpc/reflection"
 )
 
+func init() {
+	cfg.Register("", "grpc", config{})
+}
+
 // UnaryInterceptors is a map of registered unary grpc interceptors.
 var UnaryInterceptors = map[string]NewUnaryInterceptor{}
 
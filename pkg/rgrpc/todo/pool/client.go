@@ -38,6 +38,7 @@ import (
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	storageregistry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
 )
 
 // GetGatewayServiceClient returns a GatewayServiceClient.
@@ -52,6 +53,18 @@ func GetUserProviderServiceClient(id string, opts ...Option) (user.UserAPIClient
 	return selector.Next()
 }
 
+// GetUserProvisioningServiceClient returns a UserProvisioningAPIClient.
+func GetUserProvisioningServiceClient(id string, opts ...Option) (provisioning.UserProvisioningAPIClient, error) {
+	selector, _ := UserProvisioningSelector(id, opts...)
+	return selector.Next()
+}
+
+// GetUserProvisioningGatewayServiceClient returns a UserProvisioningGatewayAPIClient.
+func GetUserProvisioningGatewayServiceClient(id string, opts ...Option) (provisioning.UserProvisioningGatewayAPIClient, error) {
+	selector, _ := UserProvisioningGatewaySelector(id, opts...)
+	return selector.Next()
+}
+
 // GetGroupProviderServiceClient returns a GroupProviderServiceClient.
 func GetGroupProviderServiceClient(id string, opts ...Option) (group.GroupAPIClient, error) {
 	selector, _ := IdentityGroupSelector(id, opts...)
@@ -23,6 +23,8 @@ import (
 	"strings"
 	"sync"
 
+	adminGroup "github.com/cs3org/go-cs3apis/cs3/admin/group/v1beta1"
+	adminUser "github.com/cs3org/go-cs3apis/cs3/admin/user/v1beta1"
 	appProvider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
 	appRegistry "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
 	authApplication "github.com/cs3org/go-cs3apis/cs3/auth/applications/v1beta1"
@@ -43,6 +45,7 @@ import (
 	storageRegistry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	tx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/user/provisioning"
 	"github.com/pkg/errors"
 	"github.com/sercand/kuberesolver/v5"
 	"google.golang.org/grpc"
@@ -174,6 +177,46 @@ func IdentityGroupSelector(id string, options ...Option) (*Selector[identityGrou
 	), nil
 }
 
+// AdminUserSelector returns a Selector[adminUser.UserAPIClient].
+func AdminUserSelector(id string, options ...Option) (*Selector[adminUser.UserAPIClient], error) {
+	return GetSelector[adminUser.UserAPIClient](
+		"AdminUserSelector",
+		id,
+		adminUser.NewUserAPIClient,
+		options...,
+	), nil
+}
+
+// UserProvisioningSelector returns a Selector[provisioning.UserProvisioningAPIClient].
+func UserProvisioningSelector(id string, options ...Option) (*Selector[provisioning.UserProvisioningAPIClient], error) {
+	return GetSelector[provisioning.UserProvisioningAPIClient](
+		"UserProvisioningSelector",
+		id,
+		provisioning.NewUserProvisioningAPIClient,
+		options...,
+	), nil
+}
+
+// UserProvisioningGatewaySelector returns a Selector[provisioning.UserProvisioningGatewayAPIClient].
+func UserProvisioningGatewaySelector(id string, options ...Option) (*Selector[provisioning.UserProvisioningGatewayAPIClient], error) {
+	return GetSelector[provisioning.UserProvisioningGatewayAPIClient](
+		"UserProvisioningGatewaySelector",
+		id,
+		provisioning.NewUserProvisioningGatewayAPIClient,
+		options...,
+	), nil
+}
+
+// AdminGroupSelector returns a Selector[adminGroup.GroupAPIClient].
+func AdminGroupSelector(id string, options ...Option) (*Selector[adminGroup.GroupAPIClient], error) {
+	return GetSelector[adminGroup.GroupAPIClient](
+		"AdminGroupSelector",
+		id,
+		adminGroup.NewGroupAPIClient,
+		options...,
+	), nil
+}
+
 // StorageProviderSelector returns a Selector[storageProvider.ProviderAPIClient].
 func StorageProviderSelector(id string, options ...Option) (*Selector[storageProvider.ProviderAPIClient], error) {
 	return GetSelector[storageProvider.ProviderAPIClient](
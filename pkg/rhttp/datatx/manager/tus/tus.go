@@ -38,6 +38,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx/metrics"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx/utils/download"
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 )
@@ -177,7 +178,13 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 			setHeaders(fs, w, r)
 			handler.PostFile(w, r)
 		case "HEAD":
-			handler.HeadFile(w, r)
+			// tus clients query the offset of an upload with HEAD, other
+			// HEAD requests are downloads
+			if isTusRequest(r) {
+				handler.HeadFile(w, r)
+				return
+			}
+			download.GetOrHeadFile(w, r, fs, "")
 		case "PATCH":
 			metrics.UploadsActive.Add(1)
 			defer func() {
@@ -193,10 +200,7 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 			defer func() {
 				metrics.DownloadsActive.Sub(1)
 			}()
-			// NOTE: this is breaking change - allthought it does not seem to be used
-			// We can make a switch here depending on some header value if that is needed
-			// download.GetOrHeadFile(w, r, fs, "")
-			handler.GetFile(w, r)
+			download.GetOrHeadFile(w, r, fs, "")
 		default:
 			w.WriteHeader(http.StatusNotImplemented)
 		}
@@ -205,6 +209,12 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	return h, nil
 }
 
+// isTusRequest returns true for requests following the tus protocol or the
+// IETF resumable upload draft
+func isTusRequest(r *http.Request) bool {
+	return r.Header.Get(net.HeaderTusResumable) != "" || r.Header.Get("Upload-Draft-Interop-Version") != ""
+}
+
 func setHeaders(fs storage.FS, w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := path.Base(r.URL.Path)
@@ -89,38 +89,27 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 			Path: utils.MakeRelativePath(fn),
 		}
 	}
-	// TODO check preconditions like If-Range, If-Match ...
-
 	var md *provider.ResourceInfo
 	var content io.ReadCloser
 	var err error
-	var notModified bool
+	var precondition int
+	var rangeHeader string
 
 	// do a stat to set Content-Length and etag headers
 
 	md, content, err = fs.Download(ctx, ref, func(md *provider.ResourceInfo) bool {
+		// evaluate If-Match, If-Unmodified-Since, If-None-Match, If-Modified-Since and If-Range
+		// before opening the reader, see https://www.rfc-editor.org/rfc/rfc9110#section-13.2.2
+		precondition, rangeHeader = CheckPreconditions(r, md.GetEtag(), utils.TSToTime(md.GetMtime()))
+		if precondition != 0 {
+			return false
+		}
 		// range requests always need to open the reader to check if it is seekable
-		if r.Header.Get("Range") != "" {
+		if rangeHeader != "" {
 			return true
 		}
 		// otherwise, HEAD requests do not need to open a reader
-		if r.Method == "HEAD" {
-			return false
-		}
-
-		// check etag, see https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/If-None-Match
-		for _, etag := range r.Header.Values(net.HeaderIfNoneMatch) {
-			if md.Etag == etag {
-				// When the condition fails for GET and HEAD methods, then the server must return
-				// HTTP status code 304 (Not Modified). [...] Note that the server generating a
-				// 304 response MUST generate any of the following header fields that would have
-				// been sent in a 200 (OK) response to the same request:
-				// Cache-Control, Content-Location, Date, ETag, Expires, and Vary.
-				notModified = true
-				return false
-			}
-		}
-		return true
+		return r.Method != "HEAD"
 	})
 	if err != nil {
 		handleError(w, &sublog, err, "download")
@@ -129,10 +118,20 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 	if content != nil {
 		defer content.Close()
 	}
-	if notModified {
-		w.Header().Set(net.HeaderETag, md.Etag)
+	switch precondition {
+	case http.StatusNotModified:
+		// When the condition fails for GET and HEAD methods, then the server must return
+		// HTTP status code 304 (Not Modified). [...] Note that the server generating a
+		// 304 response MUST generate any of the following header fields that would have
+		// been sent in a 200 (OK) response to the same request:
+		// Cache-Control, Content-Location, Date, ETag, Expires, and Vary.
+		w.Header().Set(net.HeaderETag, QuoteEtag(md.Etag))
 		w.WriteHeader(http.StatusNotModified)
 		return
+	case http.StatusPreconditionFailed:
+		sublog.Debug().Str("etag", md.Etag).Msg("precondition failed")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
 	}
 
 	// fill in storage provider id if it is missing
@@ -142,13 +141,12 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 
 	var ranges []HTTPRange
 
-	if r.Header.Get("Range") != "" {
-		ranges, err = ParseRange(r.Header.Get("Range"), int64(md.Size))
+	if rangeHeader != "" {
+		ranges, err = ParseRange(rangeHeader, int64(md.Size))
 		if err != nil {
-			if err == ErrNoOverlap {
-				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", md.Size))
-			}
-			sublog.Error().Err(err).Interface("md", md).Interface("ranges", ranges).Msg("range request not satisfiable")
+			// a 416 response should tell the client the current size of the file
+			w.Header().Set(net.HeaderContentRange, fmt.Sprintf("bytes */%d", md.Size))
+			sublog.Debug().Err(err).Interface("md", md).Interface("ranges", ranges).Msg("range request not satisfiable")
 			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
 
 			return
@@ -174,13 +172,14 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 
 	w.Header().Set(net.HeaderContentType, strings.Join([]string{md.MimeType, "charset=UTF-8"}, "; "))
 
+	if len(ranges) > 0 && s == nil {
+		// a server may ignore the Range header, sending the whole file is better than failing
+		sublog.Debug().Int64("start", ranges[0].Start).Int64("length", ranges[0].Length).Msg("ReadCloser is not seekable, ignoring range request")
+		ranges = nil
+	}
+
 	if len(ranges) > 0 {
-		sublog.Debug().Int64("start", ranges[0].Start).Int64("length", ranges[0].Length).Msg("range request")
-		if s == nil {
-			sublog.Error().Int64("start", ranges[0].Start).Int64("length", ranges[0].Length).Msg("ReadCloser is not seekable")
-			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-			return
-		}
+		sublog.Debug().Int("ranges", len(ranges)).Int64("start", ranges[0].Start).Int64("length", ranges[0].Length).Msg("range request")
 
 		code = http.StatusPartialContent
 
@@ -204,9 +203,11 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 				return
 			}
 			sendSize = ra.Length
-			w.Header().Set("Content-Range", ra.ContentRange(int64(md.Size)))
+			w.Header().Set(net.HeaderContentRange, ra.ContentRange(int64(md.Size)))
 		case len(ranges) > 1:
-			sendSize = RangesMIMESize(ranges, md.MimeType, int64(md.Size))
+			// the part headers must match the ones written below or the Content-Length is off
+			partType := md.MimeType + "; charset=UTF-8"
+			sendSize = RangesMIMESize(ranges, partType, int64(md.Size))
 
 			pr, pw := io.Pipe()
 			mw := multipart.NewWriter(pw)
@@ -215,7 +216,7 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 			defer pr.Close() // cause writing goroutine to fail and exit if CopyN doesn't finish.
 			go func() {
 				for _, ra := range ranges {
-					part, err := mw.CreatePart(ra.MimeHeader(md.MimeType+"; charset=UTF-8", int64(md.Size)))
+					part, err := mw.CreatePart(ra.MimeHeader(partType, int64(md.Size)))
 					if err != nil {
 						_ = pw.CloseWithError(err) // CloseWithError always returns nil
 						return
@@ -240,7 +241,7 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 	}
 
 	w.Header().Set(net.HeaderContentDisposistion, net.ContentDispositionAttachment(path.Base(md.Path)))
-	w.Header().Set(net.HeaderETag, md.Etag)
+	w.Header().Set(net.HeaderETag, QuoteEtag(md.Etag))
 	w.Header().Set(net.HeaderOCFileID, storagespace.FormatResourceID(md.Id))
 	w.Header().Set(net.HeaderOCETag, md.Etag)
 	w.Header().Set(net.HeaderLastModified, net.RFC1123Z(md.Mtime))
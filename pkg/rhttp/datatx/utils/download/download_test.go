@@ -0,0 +1,216 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx/utils/download"
+	"github.com/opencloud-eu/reva/v2/pkg/storage"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const content = "0123456789abcdefghij"
+
+var mtime = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testFS serves a single file
+type testFS struct {
+	storage.FS
+	opened bool
+}
+
+type readSeekCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekCloser) Close() error { return nil }
+
+func (fs *testFS) Download(_ context.Context, ref *provider.Reference, openReaderFunc func(*provider.ResourceInfo) bool) (*provider.ResourceInfo, io.ReadCloser, error) {
+	md := &provider.ResourceInfo{
+		Id:       &provider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "fileid"},
+		Path:     ref.GetPath(),
+		Etag:     `"etag"`,
+		MimeType: "text/plain",
+		Size:     uint64(len(content)),
+		Mtime:    utils.TimeToTS(mtime),
+	}
+	if !openReaderFunc(md) {
+		return md, nil, nil
+	}
+	fs.opened = true
+	return md, readSeekCloser{bytes.NewReader([]byte(content))}, nil
+}
+
+var _ = Describe("GetOrHeadFile", func() {
+	var (
+		fs  *testFS
+		req *http.Request
+	)
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		download.GetOrHeadFile(w, req, fs, "")
+		return w
+	}
+
+	BeforeEach(func() {
+		fs = &testFS{}
+		req = httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	})
+
+	It("serves the whole file", func() {
+		w := serve()
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal(content))
+		Expect(w.Header().Get("ETag")).To(Equal(`"etag"`))
+	})
+
+	Describe("preconditions", func() {
+		It("responds with 304 when If-None-Match matches", func() {
+			req.Header.Set("If-None-Match", `"other", W/"etag"`)
+			w := serve()
+			Expect(w.Code).To(Equal(http.StatusNotModified))
+			Expect(w.Header().Get("ETag")).To(Equal(`"etag"`))
+			Expect(fs.opened).To(BeFalse())
+		})
+
+		It("responds with 304 when not modified since", func() {
+			req.Header.Set("If-Modified-Since", mtime.Format(http.TimeFormat))
+			Expect(serve().Code).To(Equal(http.StatusNotModified))
+		})
+
+		It("accepts the Last-Modified format we send", func() {
+			req.Header.Set("If-Modified-Since", mtime.Format(time.RFC1123Z))
+			Expect(serve().Code).To(Equal(http.StatusNotModified))
+		})
+
+		It("serves the file when modified since", func() {
+			req.Header.Set("If-Modified-Since", mtime.Add(-time.Hour).Format(http.TimeFormat))
+			Expect(serve().Code).To(Equal(http.StatusOK))
+		})
+
+		It("ignores If-Modified-Since when If-None-Match is present", func() {
+			req.Header.Set("If-None-Match", `"other"`)
+			req.Header.Set("If-Modified-Since", mtime.Format(http.TimeFormat))
+			Expect(serve().Code).To(Equal(http.StatusOK))
+		})
+
+		It("responds with 412 when If-Match does not match", func() {
+			req.Header.Set("If-Match", `"other"`)
+			w := serve()
+			Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+			Expect(fs.opened).To(BeFalse())
+		})
+
+		It("does not match weak etags with If-Match", func() {
+			req.Header.Set("If-Match", `W/"etag"`)
+			Expect(serve().Code).To(Equal(http.StatusPreconditionFailed))
+		})
+
+		It("serves the file when If-Match matches", func() {
+			req.Header.Set("If-Match", `"other", "etag"`)
+			Expect(serve().Code).To(Equal(http.StatusOK))
+		})
+
+		It("responds with 412 when modified since If-Unmodified-Since", func() {
+			req.Header.Set("If-Unmodified-Since", mtime.Add(-time.Hour).Format(http.TimeFormat))
+			Expect(serve().Code).To(Equal(http.StatusPreconditionFailed))
+		})
+	})
+
+	Describe("ranges", func() {
+		It("serves a single range", func() {
+			req.Header.Set("Range", "bytes=2-5")
+			w := serve()
+			Expect(w.Code).To(Equal(http.StatusPartialContent))
+			Expect(w.Header().Get("Content-Range")).To(Equal("bytes 2-5/20"))
+			Expect(w.Header().Get("Content-Length")).To(Equal("4"))
+			Expect(w.Body.String()).To(Equal("2345"))
+		})
+
+		It("serves multiple ranges as multipart/byteranges", func() {
+			req.Header.Set("Range", "bytes=0-1, 10-12, -2")
+			w := serve()
+			Expect(w.Code).To(Equal(http.StatusPartialContent))
+			Expect(w.Header().Get("Content-Length")).To(Equal(strconv.Itoa(w.Body.Len())))
+
+			mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mediaType).To(Equal("multipart/byteranges"))
+
+			mr := multipart.NewReader(w.Body, params["boundary"])
+			parts := map[string]string{}
+			for {
+				p, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).ToNot(HaveOccurred())
+				b, err := io.ReadAll(p)
+				Expect(err).ToNot(HaveOccurred())
+				parts[p.Header.Get("Content-Range")] = string(b)
+			}
+			Expect(parts).To(Equal(map[string]string{
+				"bytes 0-1/20":   "01",
+				"bytes 10-12/20": "abc",
+				"bytes 18-19/20": "ij",
+			}))
+		})
+
+		It("responds with 416 for unsatisfiable ranges", func() {
+			req.Header.Set("Range", "bytes=30-40")
+			w := serve()
+			Expect(w.Code).To(Equal(http.StatusRequestedRangeNotSatisfiable))
+			Expect(w.Header().Get("Content-Range")).To(Equal("bytes */20"))
+		})
+
+		It("serves the range when If-Range matches", func() {
+			req.Header.Set("Range", "bytes=2-5")
+			req.Header.Set("If-Range", `"etag"`)
+			Expect(serve().Code).To(Equal(http.StatusPartialContent))
+
+			req.Header.Set("If-Range", mtime.Format(http.TimeFormat))
+			Expect(serve().Code).To(Equal(http.StatusPartialContent))
+		})
+
+		It("serves the whole file when If-Range does not match", func() {
+			req.Header.Set("Range", "bytes=2-5")
+			req.Header.Set("If-Range", `"other"`)
+			w := serve()
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(content))
+
+			req.Header.Set("If-Range", mtime.Add(time.Hour).Format(http.TimeFormat))
+			Expect(serve().Code).To(Equal(http.StatusOK))
+		})
+	})
+})
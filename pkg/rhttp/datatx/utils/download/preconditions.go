@@ -0,0 +1,267 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package download
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/internal/http/services/owncloud/ocdav/net"
+)
+
+// taken from https://golang.org/src/net/http/fs.go
+
+// condResult is the result of an HTTP request precondition check.
+// See https://www.rfc-editor.org/rfc/rfc9110#section-13.1
+type condResult int
+
+const (
+	condNone condResult = iota
+	condTrue
+	condFalse
+)
+
+// CheckPreconditions evaluates the conditional request headers against the
+// etag and modification time of the requested file, following the order
+// defined in RFC 9110, section 13.2.2. It returns the status to respond with
+// when a precondition prevents serving the file, either http.StatusNotModified
+// or http.StatusPreconditionFailed, and 0 otherwise. The returned range header
+// is empty when the request has no Range header or its If-Range does not hold.
+func CheckPreconditions(r *http.Request, etag string, modtime time.Time) (status int, rangeHeader string) {
+	etag = QuoteEtag(etag)
+
+	ch := checkIfMatch(r, etag)
+	if ch == condNone {
+		ch = checkIfUnmodifiedSince(r, modtime)
+	}
+	if ch == condFalse {
+		return http.StatusPreconditionFailed, ""
+	}
+	switch checkIfNoneMatch(r, etag) {
+	case condFalse:
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return http.StatusNotModified, ""
+		}
+		return http.StatusPreconditionFailed, ""
+	case condNone:
+		if checkIfModifiedSince(r, modtime) == condFalse {
+			return http.StatusNotModified, ""
+		}
+	}
+
+	rangeHeader = r.Header.Get(net.HeaderRange)
+	if rangeHeader != "" && checkIfRange(r, etag, modtime) == condFalse {
+		rangeHeader = ""
+	}
+	return 0, rangeHeader
+}
+
+// QuoteEtag returns the etag as a quoted string as required by RFC 9110.
+// Storage drivers differ in whether they quote the etags they return.
+func QuoteEtag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// scanETag determines if a syntactically valid ETag is present at s. If so,
+// the ETag and remaining text after consuming ETag is returned. Otherwise,
+// it returns "", "".
+func scanETag(s string) (etag string, remain string) {
+	s = textproto.TrimString(s)
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s[start:]) < 2 || s[start] != '"' {
+		return "", ""
+	}
+	// ETag is either W/"text" or "text".
+	// See RFC 9110, section 8.8.3.
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		// Character values allowed in ETags.
+		case c == 0x21 || c >= 0x23 && c <= 0x7E || c >= 0x80:
+		case c == '"':
+			return s[:i+1], s[i+1:]
+		default:
+			return "", ""
+		}
+	}
+	return "", ""
+}
+
+// etagStrongMatch reports whether a and b match using strong ETag comparison.
+// Assumes a and b are valid ETags.
+func etagStrongMatch(a, b string) bool {
+	return a == b && a != "" && a[0] == '"'
+}
+
+// etagWeakMatch reports whether a and b match using weak ETag comparison.
+// Assumes a and b are valid ETags.
+func etagWeakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+func checkIfMatch(r *http.Request, etag string) condResult {
+	im := r.Header.Get(net.HeaderIfMatch)
+	if im == "" {
+		return condNone
+	}
+	for {
+		im = textproto.TrimString(im)
+		if len(im) == 0 {
+			break
+		}
+		if im[0] == ',' {
+			im = im[1:]
+			continue
+		}
+		if im[0] == '*' {
+			return condTrue
+		}
+		e, remain := scanETag(im)
+		if e == "" {
+			break
+		}
+		if etagStrongMatch(e, etag) {
+			return condTrue
+		}
+		im = remain
+	}
+	return condFalse
+}
+
+func checkIfUnmodifiedSince(r *http.Request, modtime time.Time) condResult {
+	ius := r.Header.Get(net.HeaderIfUnmodifiedSince)
+	if ius == "" || isZeroTime(modtime) {
+		return condNone
+	}
+	t, err := parseTime(ius)
+	if err != nil {
+		return condNone
+	}
+	// The Last-Modified header truncates sub-second precision so
+	// the modtime needs to be truncated too.
+	if modtime.Truncate(time.Second).Compare(t) <= 0 {
+		return condTrue
+	}
+	return condFalse
+}
+
+func checkIfNoneMatch(r *http.Request, etag string) condResult {
+	inm := r.Header.Get(net.HeaderIfNoneMatch)
+	if inm == "" {
+		return condNone
+	}
+	buf := inm
+	for {
+		buf = textproto.TrimString(buf)
+		if len(buf) == 0 {
+			break
+		}
+		if buf[0] == ',' {
+			buf = buf[1:]
+			continue
+		}
+		if buf[0] == '*' {
+			return condFalse
+		}
+		e, remain := scanETag(buf)
+		if e == "" {
+			break
+		}
+		if etagWeakMatch(e, etag) {
+			return condFalse
+		}
+		buf = remain
+	}
+	return condTrue
+}
+
+func checkIfModifiedSince(r *http.Request, modtime time.Time) condResult {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return condNone
+	}
+	ims := r.Header.Get(net.HeaderIfModifiedSince)
+	if ims == "" || isZeroTime(modtime) {
+		return condNone
+	}
+	t, err := parseTime(ims)
+	if err != nil {
+		return condNone
+	}
+	// The Last-Modified header truncates sub-second precision so
+	// the modtime needs to be truncated too.
+	if modtime.Truncate(time.Second).Compare(t) <= 0 {
+		return condFalse
+	}
+	return condTrue
+}
+
+func checkIfRange(r *http.Request, etag string, modtime time.Time) condResult {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return condNone
+	}
+	ir := r.Header.Get(net.HeaderIfRange)
+	if ir == "" {
+		return condNone
+	}
+	if e, _ := scanETag(ir); e != "" {
+		if etagStrongMatch(e, etag) {
+			return condTrue
+		}
+		return condFalse
+	}
+	// The If-Range value is typically the ETag value, but it may also be
+	// the modtime date. See golang.org/issue/8367.
+	if isZeroTime(modtime) {
+		return condFalse
+	}
+	t, err := parseTime(ir)
+	if err != nil {
+		return condFalse
+	}
+	if t.Unix() == modtime.Unix() {
+		return condTrue
+	}
+	return condFalse
+}
+
+var unixEpochTime = time.Unix(0, 0)
+
+// isZeroTime reports whether t is obviously unspecified (either zero or Unix()=0).
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(unixEpochTime)
+}
+
+// parseTime parses a time header. Besides the formats accepted by
+// http.ParseTime it accepts the RFC 1123 format with a numeric zone we use
+// for the Last-Modified header, so clients can echo it back.
+func parseTime(text string) (time.Time, error) {
+	t, err := http.ParseTime(text)
+	if err != nil {
+		return time.Parse(time.RFC1123Z, text)
+	}
+	return t, nil
+}
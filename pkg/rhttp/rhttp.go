@@ -35,6 +35,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/global"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/router"
 	rtrace "github.com/opencloud-eu/reva/v2/pkg/trace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/propagation"
@@ -44,6 +45,10 @@ import (
 // name is the Tracer name used to identify this instrumentation library.
 const tracerName = "rhttp"
 
+func init() {
+	cfg.Register("", "http", config{})
+}
+
 // New returns a new server
 func New(m interface{}, l zerolog.Logger, tp trace.TracerProvider) (*Server, error) {
 	conf := &config{}
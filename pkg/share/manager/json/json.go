@@ -41,6 +41,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/share"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/protobuf/encoding/prototext"
@@ -51,6 +52,7 @@ import (
 
 func init() {
 	registry.Register("json", New)
+	cfg.Register("share.manager", "json", config{})
 }
 
 // New returns a new mgr.
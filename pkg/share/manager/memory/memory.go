@@ -28,6 +28,7 @@ import (
 
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/share"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"google.golang.org/genproto/protobuf/field_mask"
 
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -43,6 +44,7 @@ var counter uint64
 
 func init() {
 	registry.Register("memory", New)
+	cfg.Register("share.manager", "memory", struct{}{})
 }
 
 // New returns a new manager.
@@ -37,6 +37,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/share"
 	"github.com/opencloud-eu/reva/v2/pkg/share/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"google.golang.org/genproto/protobuf/field_mask"
 
@@ -51,6 +52,7 @@ const (
 
 func init() {
 	registry.Register("owncloudsql", NewMysql)
+	cfg.Register("share.manager", "owncloudsql", config{})
 }
 
 type config struct {
@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 )
 
 var (
@@ -31,6 +32,10 @@ var (
 	sharedConfOnce sync.Once
 )
 
+func init() {
+	cfg.Register("", "shared", conf{})
+}
+
 // ClientOptions represent additional options (e.g. tls settings) for the grpc clients
 type ClientOptions struct {
 	TLSMode    string `mapstructure:"tls_mode"`
@@ -27,8 +27,11 @@ import (
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
 	"github.com/opencloud-eu/reva/v2/pkg/store"
 	"github.com/shamaton/msgpack/v2"
+	"go-micro.dev/v4/logger"
 	microstore "go-micro.dev/v4/store"
 )
 
@@ -53,6 +56,23 @@ type Config struct {
 	DisablePersistence bool          `mapstructure:"cache_disable_persistence"`
 	AuthUsername       string        `mapstructure:"cache_auth_username"`
 	AuthPassword       string        `mapstructure:"cache_auth_password"`
+
+	// TLSEnabled enables TLS for the connections to the store nodes
+	TLSEnabled bool `mapstructure:"cache_tls_enabled"`
+	// TLSInsecure disables the verification of the store certificates
+	TLSInsecure bool `mapstructure:"cache_tls_insecure"`
+	// TLSRootCACertificate is the root CA certificate used to verify the store certificates
+	TLSRootCACertificate string `mapstructure:"cache_tls_root_ca_cert"`
+
+	// LocalSize enables a local in-memory cache holding up to the given number
+	// of entries in front of the configured store
+	LocalSize int `mapstructure:"cache_local_size"`
+	// LocalTTL limits how long entries are kept in the local cache, defaults to
+	// TTL but at most one minute
+	LocalTTL time.Duration `mapstructure:"cache_local_ttl"`
+	// Invalidation configures the nats server used to evict entries changed
+	// by other instances from the local cache
+	Invalidation stream.NatsConfig `mapstructure:"cache_invalidation"`
 }
 
 // Cache handles key value operations on caches
@@ -228,13 +248,47 @@ func (cache cacheStore) Delete(key string, opts ...microstore.DeleteOption) erro
 	return cache.s.Delete(key, o...)
 }
 
+// batchDeleter is implemented by stores that can delete several keys at once
+type batchDeleter interface {
+	DeleteMany(keys []string, opts ...microstore.DeleteOption) error
+}
+
+// deleteKeys deletes the given keys on the configured database and table of the underlying store.
+// Stores that do not support batch deletes get one concurrent delete per key.
+func (cache cacheStore) deleteKeys(keys []string) error {
+	if bd, ok := cache.s.(batchDeleter); ok {
+		return bd.DeleteMany(keys, microstore.DeleteFrom(cache.database, cache.table))
+	}
+	return deleteEach(cache.s, keys, microstore.DeleteFrom(cache.database, cache.table))
+}
+
+// deleteEach deletes the keys one by one, concurrently, and returns the first error
+func deleteEach(s microstore.Store, keys []string, opts ...microstore.DeleteOption) error {
+	errs := make([]error, len(keys))
+	wg := sync.WaitGroup{}
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, k string) {
+			defer wg.Done()
+			errs[i] = s.Delete(k, opts...)
+		}(i, key)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the underlying store
 func (cache cacheStore) Close() error {
 	return cache.s.Close()
 }
 
 func getStore(cfg Config) microstore.Store {
-	return store.Create(
+	shared := store.Create(
 		store.Store(cfg.Store),
 		microstore.Nodes(cfg.Nodes...),
 		microstore.Database(cfg.Database),
@@ -243,5 +297,31 @@ func getStore(cfg Config) microstore.Store {
 		store.Size(cfg.Size),
 		store.DisablePersistence(cfg.DisablePersistence),
 		store.Authentication(cfg.AuthUsername, cfg.AuthPassword),
+		store.TLS(cfg.TLSEnabled, cfg.TLSInsecure, cfg.TLSRootCACertificate),
 	)
+	if cfg.LocalSize <= 0 {
+		return shared
+	}
+
+	localTTL := cfg.LocalTTL
+	if localTTL == 0 {
+		localTTL = cfg.TTL
+	}
+	if localTTL <= 0 || (cfg.LocalTTL == 0 && localTTL > defaultLocalTTL) {
+		localTTL = defaultLocalTTL
+	}
+	var s events.Stream
+	if cfg.Invalidation.Endpoint != "" {
+		var err error
+		if s, err = getInvalidationStream(cfg.Invalidation); err != nil {
+			logger.DefaultLogger.Logf(logger.ErrorLevel, "could not connect to cache invalidation stream, local cache entries expire after %s: %s", localTTL, err)
+		}
+	}
+	name := cfg.Database + "/" + cfg.Table
+	ls, err := newLayeredStore(name, shared, cfg.LocalSize, localTTL, s)
+	if err != nil {
+		logger.DefaultLogger.Logf(logger.ErrorLevel, "could not consume cache invalidations for %s, not using a local cache: %s", name, err)
+		return shared
+	}
+	return ls
 }
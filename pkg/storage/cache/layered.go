@@ -0,0 +1,264 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
+	"github.com/opencloud-eu/reva/v2/pkg/events/stream"
+	"github.com/opencloud-eu/reva/v2/pkg/store/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	microevents "go-micro.dev/v4/events"
+	"go-micro.dev/v4/logger"
+	microstore "go-micro.dev/v4/store"
+)
+
+// invalidationTopic is the topic cache changes are announced on. It is kept
+// apart from the main event queue as every instance consumes all messages.
+const invalidationTopic = "cache-invalidation"
+
+// defaultLocalTTL bounds how long entries are kept in the local cache if
+// neither a local TTL nor a TTL is configured. Invalidation messages are not
+// guaranteed to be delivered, so local entries must expire eventually.
+const defaultLocalTTL = time.Minute
+
+var (
+	// cacheHits counts the lookups answered by a cache, by tier
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reva_cache_hits_total",
+		Help: "Number of cache lookups that found an entry",
+	}, []string{"cache", "tier"})
+	// cacheMisses counts the lookups that found no entry in any tier
+	cacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reva_cache_misses_total",
+		Help: "Number of cache lookups that found no entry",
+	}, []string{"cache"})
+	// cacheInvalidations counts the local entries evicted because another instance changed them
+	cacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reva_cache_invalidations_total",
+		Help: "Number of local cache entries evicted by invalidation messages",
+	}, []string{"cache"})
+
+	// invalidationStreams are shared by all caches using the same nats server
+	invalidationStreams   = map[stream.NatsConfig]events.Stream{}
+	invalidationStreamsMu sync.Mutex
+)
+
+// layeredStore is a store that serves reads from a local in-memory store in
+// front of a shared store. Changes are written to the shared store and
+// announced on the events stream, so that other instances evict the changed
+// entries from their local store.
+type layeredStore struct {
+	microstore.Store // the shared store
+
+	name     string
+	id       string
+	local    microstore.Store
+	localTTL time.Duration
+	stream   events.Stream
+
+	// mu orders filling the local store after a shared read with evictions.
+	// generation is increased by every eviction, a read only fills the local
+	// store if no eviction happened since it started reading the shared store.
+	mu         sync.Mutex
+	generation uint64
+}
+
+// newLayeredStore puts a local store with the given size in front of the
+// shared store. Without a stream, local entries are only evicted when their
+// TTL expires.
+func newLayeredStore(name string, shared microstore.Store, size int, localTTL time.Duration, s events.Stream) (*layeredStore, error) {
+	ls := &layeredStore{
+		Store: shared,
+		name:  name,
+		id:    uuid.New().String(),
+		local: memory.NewMultiMemStore(
+			microstore.WithContext(memory.NewContext(context.Background(), map[string]interface{}{
+				"maxCap": size,
+			})),
+		),
+		localTTL: localTTL,
+		stream:   s,
+	}
+	if s == nil {
+		return ls, nil
+	}
+
+	ch, err := s.Consume(invalidationTopic)
+	if err != nil {
+		return nil, err
+	}
+	go ls.evict(ch)
+	return ls, nil
+}
+
+// getInvalidationStream returns the stream used to announce cache changes.
+func getInvalidationStream(cfg stream.NatsConfig) (events.Stream, error) {
+	invalidationStreamsMu.Lock()
+	defer invalidationStreamsMu.Unlock()
+
+	if s, ok := invalidationStreams[cfg]; ok {
+		return s, nil
+	}
+	// invalidations are only relevant for running instances, they are neither
+	// persisted nor do they need consumer state on the server
+	s, err := stream.NatsCoreFromConfig("reva-cache-invalidation", cfg)
+	if err != nil {
+		return nil, err
+	}
+	invalidationStreams[cfg] = s
+	return s, nil
+}
+
+func (ls *layeredStore) evict(ch <-chan microevents.Event) {
+	for e := range ch {
+		var ev events.CacheInvalidated
+		if err := json.Unmarshal(e.Payload, &ev); err != nil || ev.Cache != ls.name || ev.Origin == ls.id {
+			continue
+		}
+		ls.evictLocal(ev.Database, ev.Table, ev.Keys...)
+		cacheInvalidations.WithLabelValues(ls.name).Add(float64(len(ev.Keys)))
+	}
+}
+
+// Read serves single key reads from the local store if possible. Prefix and
+// suffix reads always go to the shared store.
+func (ls *layeredStore) Read(key string, opts ...microstore.ReadOption) ([]*microstore.Record, error) {
+	ropts := microstore.ReadOptions{}
+	for _, o := range opts {
+		o(&ropts)
+	}
+	if ropts.Prefix || ropts.Suffix {
+		return ls.Store.Read(key, opts...)
+	}
+
+	if records, err := ls.local.Read(key, microstore.ReadFrom(ropts.Database, ropts.Table)); err == nil && len(records) > 0 {
+		cacheHits.WithLabelValues(ls.name, "local").Inc()
+		return records, nil
+	}
+
+	ls.mu.Lock()
+	generation := ls.generation
+	ls.mu.Unlock()
+
+	records, err := ls.Store.Read(key, opts...)
+	if err != nil || len(records) == 0 {
+		cacheMisses.WithLabelValues(ls.name).Inc()
+		return records, err
+	}
+	cacheHits.WithLabelValues(ls.name, "shared").Inc()
+
+	r := *records[0]
+	if ls.localTTL != 0 && (r.Expiry == 0 || r.Expiry > ls.localTTL) {
+		r.Expiry = ls.localTTL
+	}
+	ls.mu.Lock()
+	if ls.generation == generation {
+		_ = ls.local.Write(&r, microstore.WriteTo(ropts.Database, ropts.Table))
+	}
+	ls.mu.Unlock()
+	return records, nil
+}
+
+// evictLocal removes the keys from the local store and keeps reads that
+// started before from adding them again.
+func (ls *layeredStore) evictLocal(database, table string, keys ...string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.generation++
+	for _, key := range keys {
+		_ = ls.local.Delete(key, microstore.DeleteFrom(database, table))
+	}
+}
+
+// Write writes the record to the shared store and invalidates it everywhere else.
+func (ls *layeredStore) Write(r *microstore.Record, opts ...microstore.WriteOption) error {
+	wopts := microstore.WriteOptions{}
+	for _, o := range opts {
+		o(&wopts)
+	}
+	if err := ls.Store.Write(r, opts...); err != nil {
+		return err
+	}
+	ls.evictLocal(wopts.Database, wopts.Table, r.Key)
+	ls.invalidate(wopts.Database, wopts.Table, r.Key)
+	return nil
+}
+
+// Delete deletes the key from the shared store and invalidates it everywhere else.
+func (ls *layeredStore) Delete(key string, opts ...microstore.DeleteOption) error {
+	dopts := microstore.DeleteOptions{}
+	for _, o := range opts {
+		o(&dopts)
+	}
+	if err := ls.Store.Delete(key, opts...); err != nil {
+		return err
+	}
+	ls.evictLocal(dopts.Database, dopts.Table, key)
+	ls.invalidate(dopts.Database, dopts.Table, key)
+	return nil
+}
+
+// DeleteMany deletes the keys from the shared store and invalidates them everywhere else.
+func (ls *layeredStore) DeleteMany(keys []string, opts ...microstore.DeleteOption) error {
+	dopts := microstore.DeleteOptions{}
+	for _, o := range opts {
+		o(&dopts)
+	}
+	var err error
+	if bd, ok := ls.Store.(batchDeleter); ok {
+		err = bd.DeleteMany(keys, opts...)
+	} else {
+		err = deleteEach(ls.Store, keys, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	ls.evictLocal(dopts.Database, dopts.Table, keys...)
+	ls.invalidate(dopts.Database, dopts.Table, keys...)
+	return nil
+}
+
+func (ls *layeredStore) invalidate(database, table string, keys ...string) {
+	if ls.stream == nil {
+		return
+	}
+	err := ls.stream.Publish(invalidationTopic, events.CacheInvalidated{
+		Cache:    ls.name,
+		Database: database,
+		Table:    table,
+		Keys:     keys,
+		Origin:   ls.id,
+	})
+	if err != nil {
+		logger.DefaultLogger.Logf(logger.ErrorLevel, "could not publish cache invalidation for %s: %s", ls.name, err)
+	}
+}
+
+// Close closes the local and the shared store.
+func (ls *layeredStore) Close() error {
+	_ = ls.local.Close()
+	return ls.Store.Close()
+}
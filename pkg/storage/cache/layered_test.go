@@ -0,0 +1,178 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/store/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+	microstore "go-micro.dev/v4/store"
+)
+
+// broadcast is an in-memory stream delivering every message to all consumers.
+type broadcast struct {
+	mu        sync.Mutex
+	consumers []chan microevents.Event
+}
+
+func (b *broadcast) Publish(_ string, msg interface{}, _ ...microevents.PublishOption) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.consumers {
+		c <- microevents.Event{Payload: payload}
+	}
+	return nil
+}
+
+func (b *broadcast) Consume(_ string, _ ...microevents.ConsumeOption) (<-chan microevents.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan microevents.Event, 10)
+	b.consumers = append(b.consumers, c)
+	return c, nil
+}
+
+func read(t *testing.T, s microstore.Store, key string) string {
+	records, err := s.Read(key, microstore.ReadFrom("db", "table"))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	return string(records[0].Value)
+}
+
+func TestLayeredStoreInvalidation(t *testing.T) {
+	cacheHits.Reset()
+	cacheMisses.Reset()
+	cacheInvalidations.Reset()
+
+	shared := memory.NewMultiMemStore()
+	stream := &broadcast{}
+	a, err := newLayeredStore("test-invalidation", shared, 10, time.Hour, stream)
+	require.NoError(t, err)
+	b, err := newLayeredStore("test-invalidation", shared, 10, time.Hour, stream)
+	require.NoError(t, err)
+	other, err := newLayeredStore("test-other", shared, 10, time.Hour, stream)
+	require.NoError(t, err)
+
+	invalidated := func(n float64) func() bool {
+		return func() bool {
+			return testutil.ToFloat64(cacheInvalidations.WithLabelValues("test-invalidation")) == n
+		}
+	}
+
+	require.NoError(t, a.Write(&microstore.Record{Key: "key", Value: []byte("v1")}, microstore.WriteTo("db", "table")))
+	require.Eventually(t, invalidated(1), time.Second, 10*time.Millisecond)
+	assert.Equal(t, "v1", read(t, b, "key"))
+	assert.Equal(t, "v1", read(t, b, "key"))
+	assert.Equal(t, "v1", read(t, other, "key"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheHits.WithLabelValues("test-invalidation", "shared")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheHits.WithLabelValues("test-invalidation", "local")))
+
+	require.NoError(t, a.Write(&microstore.Record{Key: "key", Value: []byte("v2")}, microstore.WriteTo("db", "table")))
+	require.Eventually(t, invalidated(2), time.Second, 10*time.Millisecond)
+	assert.Equal(t, "v2", read(t, b, "key"))
+	// caches with another name are not invalidated
+	assert.Equal(t, "v1", read(t, other, "key"))
+
+	require.NoError(t, a.Delete("key", microstore.DeleteFrom("db", "table")))
+	assert.Eventually(t, func() bool {
+		_, err := b.Read("key", microstore.ReadFrom("db", "table"))
+		return err == microstore.ErrNotFound
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheMisses.WithLabelValues("test-invalidation")))
+}
+
+func TestLayeredStoreLocalTTL(t *testing.T) {
+	shared := memory.NewMultiMemStore()
+	s, err := newLayeredStore("test-ttl", shared, 10, 20*time.Millisecond, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(&microstore.Record{Key: "key", Value: []byte("v1")}, microstore.WriteTo("db", "table")))
+	assert.Equal(t, "v1", read(t, s, "key"))
+
+	// changes of other instances become visible once the local entry expired
+	require.NoError(t, shared.Write(&microstore.Record{Key: "key", Value: []byte("v2")}, microstore.WriteTo("db", "table")))
+	assert.Equal(t, "v1", read(t, s, "key"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "v2", read(t, s, "key"))
+
+	// prefix reads are served by the shared store
+	records, err := s.Read("k", microstore.ReadFrom("db", "table"), microstore.ReadPrefix())
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestLocalCacheConfig(t *testing.T) {
+	c := NewStatCache(Config{Store: "memory", Database: "db", Table: "stat", LocalSize: 10})
+	require.IsType(t, &layeredStore{}, c.(*statCache).s)
+
+	require.NoError(t, c.PushToCache("key", map[string]string{"a": "b"}))
+	var v map[string]string
+	require.NoError(t, c.PullFromCache("key", &v))
+	assert.Equal(t, map[string]string{"a": "b"}, v)
+}
+
+// evictingStore simulates an invalidation that arrives while a value is read
+// from the shared store.
+type evictingStore struct {
+	microstore.Store
+	during func()
+}
+
+func (s evictingStore) Read(key string, opts ...microstore.ReadOption) ([]*microstore.Record, error) {
+	records, err := s.Store.Read(key, opts...)
+	s.during()
+	return records, err
+}
+
+func TestLayeredStoreReadRacingInvalidation(t *testing.T) {
+	shared := memory.NewMultiMemStore()
+	require.NoError(t, shared.Write(&microstore.Record{Key: "key", Value: []byte("v1")}, microstore.WriteTo("db", "table")))
+
+	var s *layeredStore
+	racing := evictingStore{Store: shared, during: func() {
+		require.NoError(t, shared.Write(&microstore.Record{Key: "key", Value: []byte("v2")}, microstore.WriteTo("db", "table")))
+		s.evictLocal("db", "table", "key")
+	}}
+	s, err := newLayeredStore("test-race", racing, 10, time.Hour, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", read(t, s, "key"))
+	// the stale value must not have been added to the local store
+	_, err = s.local.Read("key", microstore.ReadFrom("db", "table"))
+	assert.Equal(t, microstore.ErrNotFound, err)
+}
+
+func TestLocalTTLDefault(t *testing.T) {
+	c := NewStatCache(Config{Store: "memory", Database: "db", Table: "stat-ttl", LocalSize: 10})
+	assert.Equal(t, defaultLocalTTL, c.(*statCache).s.(*layeredStore).localTTL)
+
+	c = NewStatCache(Config{Store: "memory", Database: "db", Table: "stat-ttl-short", TTL: time.Second, LocalSize: 10})
+	assert.Equal(t, time.Second, c.(*statCache).s.(*layeredStore).localTTL)
+}
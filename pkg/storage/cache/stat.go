@@ -75,23 +75,31 @@ func (c statCache) RemoveStatContext(ctx context.Context, userID *userpb.UserId,
 	// This shotgun invalidation wipes all cache entries for the user, space, and nodeid of a changed resource, which means the stat cache is mostly empty, anyway.
 	prefixes := []string{uid, "*" + sid, "*" + oid}
 
+	mu := sync.Mutex{}
+	keys := map[string]struct{}{}
 	wg := sync.WaitGroup{}
 	for _, prefix := range prefixes {
 		wg.Add(1)
 		go func(p string) {
 			defer wg.Done()
-			keys, _ := c.List(store.ListPrefix(p), store.ListLimit(100))
-			for _, key := range keys {
-				wg.Add(1)
-				go func(k string) {
-					defer wg.Done()
-					_ = c.Delete(k)
-				}(key)
+			found, _ := c.List(store.ListPrefix(p), store.ListLimit(100))
+			mu.Lock()
+			for _, key := range found {
+				keys[key] = struct{}{}
 			}
+			mu.Unlock()
 		}(prefix)
 	}
-
 	wg.Wait()
+
+	if len(keys) == 0 {
+		return
+	}
+	batch := make([]string, 0, len(keys))
+	for key := range keys {
+		batch = append(batch, key)
+	}
+	_ = c.deleteKeys(batch)
 }
 
 // RemoveStatContext(ctx,  removes a reference from the stat cache
@@ -27,11 +27,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/options"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	registry.Register("decomposed", New)
+	cfg.Register("storage.fs", "decomposed", options.Options{})
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
@@ -26,11 +26,17 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/decomposeds3/blobstore"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/options"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	registry.Register("decomposeds3", New)
+	cfg.Register("storage.fs", "decomposeds3", struct {
+		Options `mapstructure:",squash"`
+		FS      options.Options `mapstructure:",squash"`
+	}{})
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
@@ -24,12 +24,14 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/localfs"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	registry.Register("localhome", New)
+	cfg.Register("storage.fs", "localhome", config{})
 }
 
 type config struct {
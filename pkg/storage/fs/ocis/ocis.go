@@ -27,11 +27,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/decomposedfs"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/decomposedfs/options"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	registry.Register("ocis", New)
+	cfg.Register("storage.fs", "ocis", options.Options{})
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
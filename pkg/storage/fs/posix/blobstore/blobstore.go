@@ -24,7 +24,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"syscall"
 
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
 	"github.com/pkg/errors"
 )
@@ -62,12 +64,15 @@ func (bs *Blobstore) Upload(node *node.Node, source, copyTarget string) error {
 
 	w := bufio.NewWriter(f)
 	_, err = w.ReadFrom(file)
-	if err != nil {
-		return errors.Wrapf(err, "could not write blob '%s'", node.InternalPath())
+	if err == nil {
+		err = w.Flush()
+	}
+	if errors.Is(err, syscall.EDQUOT) {
+		// the project quota of the space has been exceeded
+		return errtypes.InsufficientStorage("quota exceeded")
 	}
-	err = w.Flush()
 	if err != nil {
-		return err
+		return errors.Wrapf(err, "could not write blob '%s'", node.InternalPath())
 	}
 	err = os.Chtimes(path, fi.ModTime(), fi.ModTime())
 	if err != nil {
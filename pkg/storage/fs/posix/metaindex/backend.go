@@ -0,0 +1,284 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex
+
+import (
+	"context"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
+	"github.com/rogpeppe/go-internal/lockedfile"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+)
+
+// Backend stores the file attributes in the index database
+type Backend struct {
+	idx              *Index
+	metadataPathFunc metadata.MetadataPathFunc
+}
+
+// NewBackend returns a new Backend instance storing the attributes in the given index.
+// The lock files are kept in the directory returned by metadataPathFunc.
+func NewBackend(idx *Index, metadataPathFunc metadata.MetadataPathFunc) *Backend {
+	return &Backend{
+		idx:              idx,
+		metadataPathFunc: metadataPathFunc,
+	}
+}
+
+// Name returns the name of the backend
+func (*Backend) Name() string { return "index" }
+
+// IdentifyPath returns the space id, node id, parent id and mtime of a file
+func (b *Backend) IdentifyPath(_ context.Context, path string) (string, string, string, time.Time, error) {
+	attribs, err := b.attributes(path)
+	if err != nil {
+		return "", "", "", time.Time{}, nil
+	}
+	mtime, _ := time.Parse(time.RFC3339Nano, string(attribs[prefixes.MTimeAttr]))
+	return string(attribs[prefixes.SpaceIDAttr]), string(attribs[prefixes.IDAttr]), string(attribs[prefixes.ParentidAttr]), mtime, nil
+}
+
+// attributes returns the attributes of the file at the given path
+func (b *Backend) attributes(path string) (map[string][]byte, error) {
+	k, ino, err := identify(path)
+	if err != nil {
+		return nil, err
+	}
+	var attribs map[string][]byte
+	err = b.idx.db.View(func(tx *bolt.Tx) error {
+		r, err := getRecord(tx, k, ino)
+		if err != nil || r == nil {
+			return err
+		}
+		attribs = r.Attrs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if attribs == nil {
+		attribs = map[string][]byte{}
+	}
+	return attribs, nil
+}
+
+// Get an attribute value for the given key
+func (b *Backend) Get(_ context.Context, n metadata.MetadataNode, key string) ([]byte, error) {
+	attribs, err := b.attributes(n.InternalPath())
+	if err != nil {
+		return nil, err
+	}
+	val, ok := attribs[key]
+	if !ok {
+		return nil, &xattr.Error{Op: "IndexBackend.Get", Path: n.InternalPath(), Name: key, Err: xattr.ENOATTR}
+	}
+	return val, nil
+}
+
+// GetInt64 reads a string as int64 from the attributes
+func (b *Backend) GetInt64(ctx context.Context, n metadata.MetadataNode, key string) (int64, error) {
+	attr, err := b.Get(ctx, n, key)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(string(attr), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// All reads all attributes for a node
+func (b *Backend) All(_ context.Context, n metadata.MetadataNode) (map[string][]byte, error) {
+	return b.attributes(n.InternalPath())
+}
+
+// AllWithLockedSource reads all attributes for a node. The source is ignored
+// as the attributes are not stored in a file.
+func (b *Backend) AllWithLockedSource(ctx context.Context, n metadata.MetadataNode, _ io.Reader) (map[string][]byte, error) {
+	return b.All(ctx, n)
+}
+
+// Set sets one attribute for the given node
+func (b *Backend) Set(ctx context.Context, n metadata.MetadataNode, key string, val []byte) error {
+	return b.SetMultiple(ctx, n, map[string][]byte{key: val}, true)
+}
+
+// SetMultiple sets a set of attributes for the given node
+func (b *Backend) SetMultiple(_ context.Context, n metadata.MetadataNode, attribs map[string][]byte, acquireLock bool) error {
+	if acquireLock {
+		unlock, err := b.Lock(n)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = unlock() }()
+	}
+	return b.update(n, func(existing map[string][]byte) error {
+		maps.Copy(existing, attribs)
+		return nil
+	})
+}
+
+// Remove removes an attribute key
+func (b *Backend) Remove(_ context.Context, n metadata.MetadataNode, key string, acquireLock bool) error {
+	if acquireLock {
+		unlock, err := b.Lock(n)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = unlock() }()
+	}
+	return b.update(n, func(existing map[string][]byte) error {
+		if _, ok := existing[key]; !ok {
+			return &xattr.Error{Op: "IndexBackend.Remove", Path: n.InternalPath(), Name: key, Err: xattr.ENOATTR}
+		}
+		delete(existing, key)
+		return nil
+	})
+}
+
+// update applies f to the attributes of the given node and stores the result
+func (b *Backend) update(n metadata.MetadataNode, f func(map[string][]byte) error) error {
+	return b.idx.db.Update(func(tx *bolt.Tx) error {
+		k, r, err := ownRecord(tx, n.InternalPath())
+		if err != nil {
+			return err
+		}
+		if err := f(r.Attrs); err != nil {
+			return err
+		}
+		if err := putRecord(tx, k, r); err != nil {
+			return err
+		}
+		if n.GetSpaceID() == "" || n.GetID() == "" {
+			return nil
+		}
+		return tx.Bucket(nodesBucket).Put(nodeKey(n.GetSpaceID(), n.GetID()), k[:])
+	})
+}
+
+// Lock locks the metadata for the given node
+func (b *Backend) Lock(n metadata.MetadataNode) (metadata.UnlockFunc, error) {
+	metaLockPath := b.LockfilePath(n)
+	mlock, err := lockedfile.OpenFile(metaLockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		// create the parent directory
+		if err := os.MkdirAll(filepath.Dir(metaLockPath), 0700); err != nil {
+			return nil, err
+		}
+		mlock, err = lockedfile.OpenFile(metaLockPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func() error {
+		err := mlock.Close()
+		if err != nil {
+			return err
+		}
+		return os.Remove(metaLockPath)
+	}, nil
+}
+
+// Purge removes the attributes of the given node. Attributes that are left in
+// the extended attributes of the file, e.g. after copying it, are removed as well.
+func (b *Backend) Purge(_ context.Context, n metadata.MetadataNode) error {
+	path := n.InternalPath()
+	nk := nodeKey(n.GetSpaceID(), n.GetID())
+
+	err := b.idx.db.Update(func(tx *bolt.Tx) error {
+		nodes := tx.Bucket(nodesBucket)
+		var k []byte
+		if fk, ino, err := identify(path); err == nil {
+			// only purge the record of this file, not the one of the file it was copied from
+			r, err := getRecord(tx, fk, ino)
+			if err != nil {
+				return err
+			}
+			if r != nil {
+				k = fk[:]
+			}
+		} else {
+			// the file is gone, look up the file it was stored in
+			k = nodes.Get(nk)
+		}
+		if k == nil {
+			return nil
+		}
+		if v := nodes.Get(nk); v != nil && string(v) == string(k) {
+			if err := nodes.Delete(nk); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(attrsBucket).Delete(k)
+	})
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		return nil
+	}
+	if err := removeOcAttributes(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Rename moves the attributes of the old node to the new node. The attributes
+// follow the file, so only the node mapping is updated.
+func (b *Backend) Rename(oldNode, newNode metadata.MetadataNode) error {
+	k, _, err := identify(oldNode.InternalPath())
+	if err != nil {
+		return err
+	}
+	if k == (fileKey{}) {
+		return nil
+	}
+	return b.idx.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(nodesBucket).Put(nodeKey(newNode.GetSpaceID(), newNode.GetID()), k[:])
+	})
+}
+
+// MetadataPath returns the path of the file holding the metadata for the given node
+func (b *Backend) MetadataPath(metadata.MetadataNode) string {
+	return b.idx.Path()
+}
+
+// LockfilePath returns the path of the lock file
+func (b *Backend) LockfilePath(n metadata.MetadataNode) string {
+	return filepath.Join(b.metadataPathFunc(n), "locks", n.GetID()+".mlock")
+}
+
+// IsMetaFile returns whether the given path represents a meta file
+func (*Backend) IsMetaFile(path string) bool { return strings.HasSuffix(path, ".meta.lock") }
@@ -0,0 +1,220 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/xattr"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/metaindex"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+)
+
+type testNode struct {
+	spaceID, id, path string
+}
+
+func (n testNode) GetSpaceID() string   { return n.spaceID }
+func (n testNode) GetID() string        { return n.id }
+func (n testNode) InternalPath() string { return n.path }
+
+var _ = Describe("Backend", func() {
+	var (
+		tmpdir  string
+		idx     *metaindex.Index
+		backend *metaindex.Backend
+		n       testNode
+		ctx     = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpdir, err = os.MkdirTemp(os.TempDir(), "MetaindexBackendTest-")
+		Expect(err).ToNot(HaveOccurred())
+
+		idx, err = metaindex.Open(filepath.Join(tmpdir, "index.db"))
+		Expect(err).ToNot(HaveOccurred())
+		backend = metaindex.NewBackend(idx, func(metadata.MetadataNode) string { return filepath.Join(tmpdir, "meta") })
+
+		n = testNode{spaceID: "spaceid", id: "nodeid", path: filepath.Join(tmpdir, "file")}
+		Expect(os.WriteFile(n.path, []byte("content"), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(idx.Close()).To(Succeed())
+		if tmpdir != "" {
+			os.RemoveAll(tmpdir)
+		}
+	})
+
+	Describe("Set", func() {
+		It("stores the attributes in the index", func() {
+			Expect(backend.Set(ctx, n, "user.oc.foo", []byte("bar"))).To(Succeed())
+
+			v, err := backend.Get(ctx, n, "user.oc.foo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(v)).To(Equal("bar"))
+
+			_, err = xattr.Get(n.path, "user.oc.foo")
+			Expect(metadata.IsAttrUnset(err)).To(BeTrue())
+		})
+
+		It("merges the attributes", func() {
+			Expect(backend.SetMultiple(ctx, n, map[string][]byte{"user.oc.foo": []byte("bar"), "user.oc.baz": []byte("qux")}, true)).To(Succeed())
+			Expect(backend.Set(ctx, n, "user.oc.foo", []byte("new"))).To(Succeed())
+
+			attribs, err := backend.All(ctx, n)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(attribs).To(Equal(map[string][]byte{"user.oc.foo": []byte("new"), "user.oc.baz": []byte("qux")}))
+		})
+
+		It("stores large attributes", func() {
+			grant := make([]byte, 64*1024)
+			Expect(backend.Set(ctx, n, prefixes.GrantUserAcePrefix+"einstein", grant)).To(Succeed())
+
+			v, err := backend.Get(ctx, n, prefixes.GrantUserAcePrefix+"einstein")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v).To(HaveLen(64 * 1024))
+		})
+
+		It("fails for files that do not exist", func() {
+			n.path = filepath.Join(tmpdir, "missing")
+			Expect(backend.Set(ctx, n, "user.oc.foo", []byte("bar"))).ToNot(Succeed())
+		})
+	})
+
+	Describe("Get", func() {
+		It("returns an unset attribute error for missing keys", func() {
+			_, err := backend.Get(ctx, n, "user.oc.foo")
+			Expect(metadata.IsAttrUnset(err)).To(BeTrue())
+		})
+	})
+
+	Describe("Remove", func() {
+		It("removes an attribute", func() {
+			Expect(backend.Set(ctx, n, "user.oc.foo", []byte("bar"))).To(Succeed())
+			Expect(backend.Remove(ctx, n, "user.oc.foo", true)).To(Succeed())
+
+			_, err := backend.Get(ctx, n, "user.oc.foo")
+			Expect(metadata.IsAttrUnset(err)).To(BeTrue())
+			Expect(metadata.IsAttrUnset(backend.Remove(ctx, n, "user.oc.foo", true))).To(BeTrue())
+		})
+	})
+
+	Describe("IdentifyPath", func() {
+		It("follows the file when it is renamed", func() {
+			Expect(backend.SetMultiple(ctx, n, map[string][]byte{
+				prefixes.IDAttr:       []byte("nodeid"),
+				prefixes.ParentidAttr: []byte("parentid"),
+			}, true)).To(Succeed())
+
+			newPath := filepath.Join(tmpdir, "renamed")
+			Expect(os.Rename(n.path, newPath)).To(Succeed())
+
+			_, id, parentID, _, err := backend.IdentifyPath(ctx, newPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("nodeid"))
+			Expect(parentID).To(Equal("parentid"))
+		})
+
+		It("does not identify copies", func() {
+			Expect(backend.Set(ctx, n, prefixes.IDAttr, []byte("nodeid"))).To(Succeed())
+
+			content, err := os.ReadFile(n.path)
+			Expect(err).ToNot(HaveOccurred())
+			copyPath := filepath.Join(tmpdir, "copy")
+			Expect(os.WriteFile(copyPath, content, 0600)).To(Succeed())
+
+			_, id, _, _, err := backend.IdentifyPath(ctx, copyPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(BeEmpty())
+		})
+
+		It("does not identify copies carrying the extended attributes", func() {
+			Expect(backend.Set(ctx, n, prefixes.IDAttr, []byte("nodeid"))).To(Succeed())
+
+			copied := testNode{spaceID: "spaceid", id: "copyid", path: filepath.Join(tmpdir, "copy")}
+			Expect(os.WriteFile(copied.path, []byte("content"), 0600)).To(Succeed())
+			names, err := xattr.List(n.path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).ToNot(BeEmpty())
+			for _, name := range names {
+				v, err := xattr.Get(n.path, name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(xattr.Set(copied.path, name, v)).To(Succeed())
+			}
+
+			_, id, _, _, err := backend.IdentifyPath(ctx, copied.path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(BeEmpty())
+
+			// writing to the copy leaves the original alone
+			Expect(backend.Set(ctx, copied, prefixes.IDAttr, []byte("copyid"))).To(Succeed())
+			_, id, _, _, err = backend.IdentifyPath(ctx, copied.path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("copyid"))
+			_, id, _, _, err = backend.IdentifyPath(ctx, n.path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("nodeid"))
+
+			Expect(backend.Purge(ctx, copied)).To(Succeed())
+			_, id, _, _, err = backend.IdentifyPath(ctx, n.path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("nodeid"))
+		})
+	})
+
+	Describe("Purge", func() {
+		It("removes the attributes of deleted files", func() {
+			Expect(backend.Set(ctx, n, prefixes.IDAttr, []byte("nodeid"))).To(Succeed())
+			Expect(os.Remove(n.path)).To(Succeed())
+			Expect(backend.Purge(ctx, n)).To(Succeed())
+
+			// a new file reusing the inode must not inherit the attributes
+			Expect(os.WriteFile(n.path, []byte("new"), 0600)).To(Succeed())
+			_, id, _, _, err := backend.IdentifyPath(ctx, n.path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(BeEmpty())
+		})
+
+		It("removes leftover extended attributes", func() {
+			Expect(xattr.Set(n.path, prefixes.IDAttr, []byte("copied"))).To(Succeed())
+			Expect(backend.Purge(ctx, n)).To(Succeed())
+
+			_, err := xattr.Get(n.path, prefixes.IDAttr)
+			Expect(metadata.IsAttrUnset(err)).To(BeTrue())
+		})
+	})
+
+	Describe("Lock", func() {
+		It("creates the lock file and removes it on unlock", func() {
+			unlock, err := backend.Lock(n)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backend.LockfilePath(n)).To(BeAnExistingFile())
+			Expect(unlock()).To(Succeed())
+			Expect(backend.LockfilePath(n)).ToNot(BeAnExistingFile())
+		})
+	})
+})
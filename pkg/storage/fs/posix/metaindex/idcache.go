@@ -0,0 +1,151 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// IDCache keeps the mapping between node ids and paths in the index database.
+// Unlike a cache it is persistent, so id lookups do not depend on a warm cache.
+type IDCache struct {
+	idx *Index
+}
+
+// NewIDCache returns a new IDCache storing the mapping in the given index
+func NewIDCache(idx *Index) *IDCache {
+	return &IDCache{idx: idx}
+}
+
+// Get returns the path of the given node
+func (c *IDCache) Get(_ context.Context, spaceID, nodeID string) (string, bool) {
+	var path string
+	_ = c.idx.db.View(func(tx *bolt.Tx) error {
+		path = string(tx.Bucket(idsBucket).Get(nodeKey(spaceID, nodeID)))
+		return nil
+	})
+	return path, path != ""
+}
+
+// GetByPath returns the space and node id of the given path
+func (c *IDCache) GetByPath(_ context.Context, path string) (string, string, bool) {
+	var v []byte
+	_ = c.idx.db.View(func(tx *bolt.Tx) error {
+		v = bytes.Clone(tx.Bucket(pathsBucket).Get([]byte(path)))
+		return nil
+	})
+	spaceID, nodeID, ok := strings.Cut(string(v), "!")
+	return spaceID, nodeID, ok
+}
+
+// Set sets the path of the given node. The file found at the path becomes the
+// file holding the attributes of the node, this keeps the index in sync with
+// the moves the watcher reports.
+func (c *IDCache) Set(_ context.Context, spaceID, nodeID, path string) error {
+	k := nodeKey(spaceID, nodeID)
+	fk, _, ferr := identify(path)
+	return c.idx.db.Update(func(tx *bolt.Tx) error {
+		ids, paths := tx.Bucket(idsBucket), tx.Bucket(pathsBucket)
+		if err := ids.Put(k, []byte(path)); err != nil {
+			return err
+		}
+		if err := paths.Put([]byte(path), k); err != nil {
+			return err
+		}
+		if ferr != nil || fk == (fileKey{}) {
+			return nil
+		}
+		return tx.Bucket(nodesBucket).Put(k, fk[:])
+	})
+}
+
+// Delete removes the entries of the given node
+func (c *IDCache) Delete(_ context.Context, spaceID, nodeID string) error {
+	k := nodeKey(spaceID, nodeID)
+	return c.idx.db.Update(func(tx *bolt.Tx) error {
+		ids, paths := tx.Bucket(idsBucket), tx.Bucket(pathsBucket)
+		if path := ids.Get(k); path != nil {
+			if bytes.Equal(paths.Get(path), k) {
+				if err := paths.Delete(path); err != nil {
+					return err
+				}
+			}
+		}
+		return ids.Delete(k)
+	})
+}
+
+// DeleteByPath removes the entries of the given path and all paths below it.
+// The attributes of nodes whose files are gone are removed as well, they would
+// never be reachable again.
+func (c *IDCache) DeleteByPath(_ context.Context, path string) error {
+	return c.idx.db.Update(func(tx *bolt.Tx) error {
+		ids, paths, nodes := tx.Bucket(idsBucket), tx.Bucket(pathsBucket), tx.Bucket(nodesBucket)
+		deleteEntry := func(p, k []byte) error {
+			if bytes.Equal(ids.Get(k), p) {
+				if err := ids.Delete(k); err != nil {
+					return err
+				}
+				if _, err := os.Lstat(string(p)); errors.Is(err, os.ErrNotExist) {
+					if fk := nodes.Get(k); fk != nil {
+						if err := tx.Bucket(attrsBucket).Delete(fk); err != nil {
+							return err
+						}
+					}
+					if err := nodes.Delete(k); err != nil {
+						return err
+					}
+				}
+			}
+			return paths.Delete(p)
+		}
+
+		if k := paths.Get([]byte(path)); k != nil {
+			if err := deleteEntry([]byte(path), bytes.Clone(k)); err != nil {
+				return err
+			}
+		}
+
+		prefix := []byte(path + "/")
+		var children [][2][]byte
+		cur := paths.Cursor()
+		for p, k := cur.Seek(prefix); p != nil && bytes.HasPrefix(p, prefix); p, k = cur.Next() {
+			children = append(children, [2][]byte{bytes.Clone(p), bytes.Clone(k)})
+		}
+		for _, child := range children {
+			if err := deleteEntry(child[0], child[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeletePath removes only the path entry
+func (c *IDCache) DeletePath(_ context.Context, path string) error {
+	return c.idx.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pathsBucket).Delete([]byte(path))
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/metaindex"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+)
+
+var _ = Describe("IDCache", func() {
+	var (
+		tmpdir string
+		idx    *metaindex.Index
+		cache  *metaindex.IDCache
+		ctx    = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpdir, err = os.MkdirTemp(os.TempDir(), "MetaindexIDCacheTest-")
+		Expect(err).ToNot(HaveOccurred())
+
+		idx, err = metaindex.Open(filepath.Join(tmpdir, "index.db"))
+		Expect(err).ToNot(HaveOccurred())
+		cache = metaindex.NewIDCache(idx)
+
+		Expect(cache.Set(ctx, "spaceID", "nodeID", "path")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(idx.Close()).To(Succeed())
+		if tmpdir != "" {
+			os.RemoveAll(tmpdir)
+		}
+	})
+
+	It("maps ids and paths", func() {
+		v, ok := cache.Get(ctx, "spaceID", "nodeID")
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal("path"))
+
+		spaceID, nodeID, ok := cache.GetByPath(ctx, "path")
+		Expect(ok).To(BeTrue())
+		Expect(spaceID).To(Equal("spaceID"))
+		Expect(nodeID).To(Equal("nodeID"))
+
+		_, ok = cache.Get(ctx, "spaceID", "missing")
+		Expect(ok).To(BeFalse())
+		_, _, ok = cache.GetByPath(ctx, "missing")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("survives reopening the index", func() {
+		Expect(idx.Close()).To(Succeed())
+		var err error
+		idx, err = metaindex.Open(filepath.Join(tmpdir, "index.db"))
+		Expect(err).ToNot(HaveOccurred())
+
+		v, ok := metaindex.NewIDCache(idx).Get(ctx, "spaceID", "nodeID")
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal("path"))
+	})
+
+	Describe("Delete", func() {
+		It("deletes both entries", func() {
+			Expect(cache.Delete(ctx, "spaceID", "nodeID")).To(Succeed())
+
+			_, ok := cache.Get(ctx, "spaceID", "nodeID")
+			Expect(ok).To(BeFalse())
+			_, _, ok = cache.GetByPath(ctx, "path")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("DeletePath", func() {
+		It("only deletes the path entry", func() {
+			Expect(cache.DeletePath(ctx, "path")).To(Succeed())
+
+			_, ok := cache.Get(ctx, "spaceID", "nodeID")
+			Expect(ok).To(BeTrue())
+			_, _, ok = cache.GetByPath(ctx, "path")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("DeleteByPath", func() {
+		It("deletes recursively", func() {
+			Expect(cache.Set(ctx, "spaceID", "nodeID2", "path/child")).To(Succeed())
+			Expect(cache.Set(ctx, "spaceID", "nodeID3", "path/child/grandchild")).To(Succeed())
+			Expect(cache.Set(ctx, "spaceID", "nodeID4", "pathsibling")).To(Succeed())
+
+			Expect(cache.DeleteByPath(ctx, "path")).To(Succeed())
+
+			for _, id := range []string{"nodeID", "nodeID2", "nodeID3"} {
+				_, ok := cache.Get(ctx, "spaceID", id)
+				Expect(ok).To(BeFalse())
+			}
+			v, ok := cache.Get(ctx, "spaceID", "nodeID4")
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal("pathsibling"))
+		})
+
+		It("removes the attributes of deleted files", func() {
+			backend := metaindex.NewBackend(idx, func(metadata.MetadataNode) string { return filepath.Join(tmpdir, "meta") })
+			dir := testNode{spaceID: "spaceID", id: "dirID", path: filepath.Join(tmpdir, "dir")}
+			child := testNode{spaceID: "spaceID", id: "childID", path: filepath.Join(dir.path, "child")}
+			Expect(os.Mkdir(dir.path, 0700)).To(Succeed())
+			Expect(os.WriteFile(child.path, []byte("content"), 0600)).To(Succeed())
+			for _, n := range []testNode{dir, child} {
+				Expect(backend.Set(ctx, n, prefixes.IDAttr, []byte(n.id))).To(Succeed())
+				Expect(cache.Set(ctx, n.spaceID, n.id, n.path)).To(Succeed())
+			}
+
+			// the new location keeps the attributes when the node is moved
+			moved := filepath.Join(tmpdir, "moved")
+			Expect(cache.DeleteByPath(ctx, dir.path)).To(Succeed())
+			Expect(os.Rename(dir.path, moved)).To(Succeed())
+			_, id, _, _, err := backend.IdentifyPath(ctx, filepath.Join(moved, "child"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("childID"))
+
+			Expect(cache.Set(ctx, dir.spaceID, dir.id, moved)).To(Succeed())
+			Expect(cache.Set(ctx, child.spaceID, child.id, filepath.Join(moved, "child"))).To(Succeed())
+			Expect(os.RemoveAll(moved)).To(Succeed())
+			Expect(cache.DeleteByPath(ctx, moved)).To(Succeed())
+
+			// look into the database, the records can't be reached through the backend anymore
+			Expect(idx.Close()).To(Succeed())
+			db, err := bolt.Open(filepath.Join(tmpdir, "index.db"), 0600, &bolt.Options{ReadOnly: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(db.View(func(tx *bolt.Tx) error {
+				Expect(tx.Bucket([]byte("attrs")).Stats().KeyN).To(BeZero())
+				Expect(tx.Bucket([]byte("nodes")).Stats().KeyN).To(BeZero())
+				return nil
+			})).To(Succeed())
+			Expect(db.Close()).To(Succeed())
+			idx, err = metaindex.Open(filepath.Join(tmpdir, "index.db"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("keeps the id of a node that was moved already", func() {
+			Expect(cache.Set(ctx, "spaceID", "nodeID", "newpath")).To(Succeed())
+			Expect(cache.DeleteByPath(ctx, "path")).To(Succeed())
+
+			v, ok := cache.Get(ctx, "spaceID", "nodeID")
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal("newpath"))
+		})
+	})
+})
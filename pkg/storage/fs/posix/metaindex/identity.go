@@ -0,0 +1,64 @@
+//go:build unix
+
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/pkg/xattr"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+)
+
+// identityAttr holds the key of the record of a file. It lives outside of the
+// oc namespace so removing the node metadata from a file does not remove it.
+const identityAttr = "user.reva.mdx"
+
+// identify returns the key and the inode of the file at the given path. The
+// key is zero if no record was assigned to the file yet.
+func identify(path string) (fileKey, uint64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileKey{}, 0, err
+	}
+	ino := uint64(fi.Sys().(*syscall.Stat_t).Ino)
+
+	var k fileKey
+	v, err := xattr.Get(path, identityAttr)
+	switch {
+	case err == nil && len(v) == len(k):
+		copy(k[:], v)
+	case err != nil && !metadata.IsAttrUnset(err):
+		return fileKey{}, 0, err
+	}
+	return k, ino, nil
+}
+
+// assignKey stores a new record key on the file at the given path
+func assignKey(path string) (fileKey, error) {
+	k := fileKey(uuid.New())
+	if err := xattr.Set(path, identityAttr, k[:]); err != nil {
+		return fileKey{}, err
+	}
+	return k, nil
+}
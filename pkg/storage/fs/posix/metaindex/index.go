@@ -0,0 +1,185 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package metaindex implements a metadata backend for posixfs that keeps the
+// node attributes in a local bbolt database instead of extended attributes.
+// Every file carries the key of its record in a small extended attribute, so
+// the attributes follow the file when it is renamed outside of reva and
+// survive remounts that change the device number. The same database holds the
+// mapping between node ids and paths.
+package metaindex
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shamaton/msgpack/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// attrsBucket maps file keys to the records holding their attributes
+	attrsBucket = []byte("attrs")
+	// nodesBucket maps node keys to the file keys of the nodes
+	nodesBucket = []byte("nodes")
+	// idsBucket maps node keys to paths
+	idsBucket = []byte("ids")
+	// pathsBucket maps paths to node keys
+	pathsBucket = []byte("paths")
+	// metaBucket holds information about the index itself
+	metaBucket = []byte("meta")
+
+	migratedKey = []byte("migrated")
+)
+
+// handles keeps the open indexes by path. bbolt locks the database file,
+// so all users of the same file share one handle.
+var (
+	handlesMu sync.Mutex
+	handles   = map[string]*Index{}
+)
+
+// Index is a metadata index stored in a bbolt database
+type Index struct {
+	db   *bolt.DB
+	path string
+	refs int
+}
+
+// record is the representation of the attributes of a file in the database
+type record struct {
+	// Ino is the inode of the file, copies of a file carrying the same key are
+	// told apart by it
+	Ino   uint64            `msgpack:"i"`
+	Attrs map[string][]byte `msgpack:"a"`
+}
+
+// Open opens the index stored in the given file, creating it if necessary
+func Open(path string) (*Index, error) {
+	path = filepath.Clean(path)
+
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	if idx, ok := handles[path]; ok {
+		idx.refs++
+		return idx, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "metaindex: could not open database "+path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{attrsBucket, nodesBucket, idsBucket, pathsBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	idx := &Index{db: db, path: path, refs: 1}
+	handles[path] = idx
+	return idx, nil
+}
+
+// Close releases the index. The database is closed once all users released it.
+func (idx *Index) Close() error {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+	idx.refs--
+	if idx.refs > 0 {
+		return nil
+	}
+	delete(handles, idx.path)
+	return idx.db.Close()
+}
+
+// Path returns the path of the database file
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+// fileKey identifies the record of a file
+type fileKey [16]byte
+
+// nodeKey returns the key of a node in the nodes and ids buckets
+func nodeKey(spaceID, nodeID string) []byte {
+	return []byte(spaceID + "!" + nodeID)
+}
+
+// getRecord returns the attributes of the file with the given key and inode.
+// Records of a different file carrying the same key, e.g. a copy made with its
+// extended attributes, are ignored.
+func getRecord(tx *bolt.Tx, k fileKey, ino uint64) (*record, error) {
+	if k == (fileKey{}) {
+		return nil, nil
+	}
+	v := tx.Bucket(attrsBucket).Get(k[:])
+	if v == nil {
+		return nil, nil
+	}
+	r := &record{}
+	if err := msgpack.Unmarshal(v, r); err != nil {
+		return nil, err
+	}
+	if r.Ino != ino {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// ownRecord returns the key and the record of the file at the given path. A new
+// key is assigned if the file has none yet or carries the key of another file.
+func ownRecord(tx *bolt.Tx, path string) (fileKey, *record, error) {
+	k, ino, err := identify(path)
+	if err != nil {
+		return fileKey{}, nil, err
+	}
+	r, err := getRecord(tx, k, ino)
+	if err != nil {
+		return fileKey{}, nil, err
+	}
+	if r != nil {
+		return k, r, nil
+	}
+	if k == (fileKey{}) || tx.Bucket(attrsBucket).Get(k[:]) != nil {
+		if k, err = assignKey(path); err != nil {
+			return fileKey{}, nil, err
+		}
+	}
+	return k, &record{Ino: ino, Attrs: map[string][]byte{}}, nil
+}
+
+func putRecord(tx *bolt.Tx, k fileKey, r *record) error {
+	v, err := msgpack.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(attrsBucket).Put(k[:], v)
+}
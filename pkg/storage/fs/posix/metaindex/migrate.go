@@ -0,0 +1,205 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/xattr"
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+)
+
+// legacyNode is a node of the xattrs layout that is being migrated
+type legacyNode struct {
+	spaceID, id, path string
+}
+
+func (n legacyNode) GetSpaceID() string   { return n.spaceID }
+func (n legacyNode) GetID() string        { return n.id }
+func (n legacyNode) InternalPath() string { return n.path }
+
+// Migrated returns whether the index already holds the metadata of the tree
+func (idx *Index) Migrated() bool {
+	migrated := false
+	_ = idx.db.View(func(tx *bolt.Tx) error {
+		migrated = tx.Bucket(metaBucket).Get(migratedKey) != nil
+		return nil
+	})
+	return migrated
+}
+
+// Migrate moves the metadata of the tree below root from the extended attributes
+// used by the xattrs and hybrid backends into the index. Metadata offloaded by
+// the hybrid backend is moved as well. The migration runs only once, later calls
+// return immediately.
+func (idx *Index) Migrate(ctx context.Context, root string, log *zerolog.Logger) error {
+	if idx.Migrated() {
+		return nil
+	}
+
+	spaceRoots := map[string]string{}
+	legacy := metadata.NewHybridBackend(1024, func(n metadata.MetadataNode) string {
+		return filepath.Join(spaceRoots[n.GetSpaceID()], lookup.MetadataDir)
+	}, cache.Config{Store: "noop"})
+
+	// spaces holds the space id of the directories visited so far
+	spaces := map[string]string{}
+	count := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.Contains(path, lookup.MetadataDir) {
+			// revisions keep their metadata, there are no ids to map
+			if d.Type().IsRegular() && path != idx.path {
+				if err := idx.migrateRevision(path); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		spaceID := spaces[filepath.Dir(path)]
+		if v, err := xattr.Get(path, prefixes.SpaceIDAttr); err == nil && len(v) > 0 {
+			spaceID = string(v)
+			spaceRoots[spaceID] = path
+		}
+		if d.IsDir() {
+			spaces[path] = spaceID
+		}
+
+		id, err := xattr.Get(path, prefixes.IDAttr)
+		if err != nil || len(id) == 0 || spaceID == "" {
+			return nil
+		}
+
+		n := legacyNode{spaceID: spaceID, id: string(id), path: path}
+		if err := idx.migrateNode(ctx, legacy, n); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("nodes", count).Str("root", root).Msg("metaindex: migrated metadata from extended attributes")
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(migratedKey, []byte("1"))
+	})
+}
+
+func (idx *Index) migrateRevision(path string) error {
+	names, err := xattr.List(path)
+	if err != nil {
+		return err
+	}
+	attribs := make(map[string][]byte, len(names))
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefixes.OcPrefix) {
+			continue
+		}
+		val, err := xattr.Get(path, name)
+		if err != nil {
+			return err
+		}
+		attribs[name] = val
+	}
+	if len(attribs) == 0 {
+		return nil
+	}
+
+	err = idx.db.Update(func(tx *bolt.Tx) error {
+		k, r, err := ownRecord(tx, path)
+		if err != nil {
+			return err
+		}
+		r.Attrs = attribs
+		return putRecord(tx, k, r)
+	})
+	if err != nil {
+		return err
+	}
+	return removeOcAttributes(path)
+}
+
+// removeOcAttributes removes the extended attributes holding node metadata
+func removeOcAttributes(path string) error {
+	names, err := xattr.List(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefixes.OcPrefix) {
+			continue
+		}
+		if err := xattr.Remove(path, name); err != nil && !metadata.IsAttrUnset(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *Index) migrateNode(ctx context.Context, legacy metadata.HybridBackend, n legacyNode) error {
+	attribs, err := legacy.All(ctx, n)
+	if err != nil {
+		return err
+	}
+	nk := nodeKey(n.spaceID, n.id)
+	err = idx.db.Update(func(tx *bolt.Tx) error {
+		k, r, err := ownRecord(tx, n.path)
+		if err != nil {
+			return err
+		}
+		r.Attrs = attribs
+		if err := putRecord(tx, k, r); err != nil {
+			return err
+		}
+		if err := tx.Bucket(nodesBucket).Put(nk, k[:]); err != nil {
+			return err
+		}
+		if err := tx.Bucket(idsBucket).Put(nk, []byte(n.path)); err != nil {
+			return err
+		}
+		return tx.Bucket(pathsBucket).Put([]byte(n.path), nk)
+	})
+	if err != nil {
+		return err
+	}
+
+	// remove the migrated metadata from the file system
+	if err := removeOcAttributes(n.path); err != nil {
+		return err
+	}
+	if err := os.Remove(legacy.MetadataPath(n)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metaindex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/xattr"
+	"github.com/rs/zerolog"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/metaindex"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+)
+
+var _ = Describe("Migrate", func() {
+	var (
+		root      string
+		spaceRoot string
+		file      testNode
+		idx       *metaindex.Index
+		log       = zerolog.Nop()
+		ctx       = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(os.TempDir(), "MetaindexMigrateTest-")
+		Expect(err).ToNot(HaveOccurred())
+
+		// a space with a file in the layout of the hybrid backend
+		spaceRoot = filepath.Join(root, "users", "einstein")
+		Expect(os.MkdirAll(filepath.Join(spaceRoot, "dir"), 0700)).To(Succeed())
+		file = testNode{spaceID: "spaceid", id: "fileid", path: filepath.Join(spaceRoot, "dir", "file")}
+		Expect(os.WriteFile(file.path, []byte("content"), 0600)).To(Succeed())
+
+		hybrid := metadata.NewHybridBackend(1024, func(metadata.MetadataNode) string {
+			return filepath.Join(spaceRoot, lookup.MetadataDir)
+		}, cache.Config{Store: "noop"})
+		Expect(hybrid.SetMultiple(ctx, testNode{spaceID: "spaceid", id: "spaceid", path: spaceRoot}, map[string][]byte{
+			prefixes.IDAttr:      []byte("spaceid"),
+			prefixes.SpaceIDAttr: []byte("spaceid"),
+		}, true)).To(Succeed())
+		Expect(hybrid.SetMultiple(ctx, testNode{spaceID: "spaceid", id: "dirid", path: filepath.Join(spaceRoot, "dir")}, map[string][]byte{
+			prefixes.IDAttr:       []byte("dirid"),
+			prefixes.ParentidAttr: []byte("spaceid"),
+		}, true)).To(Succeed())
+		// the grant is large enough to be offloaded
+		Expect(hybrid.SetMultiple(ctx, file, map[string][]byte{
+			prefixes.IDAttr:                       []byte("fileid"),
+			prefixes.ParentidAttr:                 []byte("dirid"),
+			prefixes.GrantUserAcePrefix + "marie": []byte(strings.Repeat("x", 2048)),
+		}, true)).To(Succeed())
+		Expect(hybrid.MetadataPath(file)).To(BeAnExistingFile())
+
+		idx, err = metaindex.Open(filepath.Join(root, lookup.MetadataDir, "index.db"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(idx.Close()).To(Succeed())
+		if root != "" {
+			os.RemoveAll(root)
+		}
+	})
+
+	It("moves the metadata into the index", func() {
+		Expect(idx.Migrated()).To(BeFalse())
+		Expect(idx.Migrate(ctx, root, &log)).To(Succeed())
+		Expect(idx.Migrated()).To(BeTrue())
+
+		backend := metaindex.NewBackend(idx, func(metadata.MetadataNode) string { return filepath.Join(spaceRoot, lookup.MetadataDir) })
+		attribs, err := backend.All(ctx, file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(attribs[prefixes.IDAttr])).To(Equal("fileid"))
+		Expect(string(attribs[prefixes.ParentidAttr])).To(Equal("dirid"))
+		Expect(attribs[prefixes.GrantUserAcePrefix+"marie"]).To(HaveLen(2048))
+
+		spaceID, id, _, _, err := backend.IdentifyPath(ctx, spaceRoot)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(spaceID).To(Equal("spaceid"))
+		Expect(id).To(Equal("spaceid"))
+
+		ids := metaindex.NewIDCache(idx)
+		path, ok := ids.Get(ctx, "spaceid", "fileid")
+		Expect(ok).To(BeTrue())
+		Expect(path).To(Equal(file.path))
+		path, ok = ids.Get(ctx, "spaceid", "dirid")
+		Expect(ok).To(BeTrue())
+		Expect(path).To(Equal(filepath.Join(spaceRoot, "dir")))
+	})
+
+	It("removes the metadata from the file system", func() {
+		Expect(idx.Migrate(ctx, root, &log)).To(Succeed())
+
+		names, err := xattr.List(file.path)
+		Expect(err).ToNot(HaveOccurred())
+		for _, name := range names {
+			Expect(name).ToNot(HavePrefix(prefixes.OcPrefix))
+		}
+		offloaded, err := filepath.Glob(filepath.Join(spaceRoot, lookup.MetadataDir, "*", "*", "*", "*", "*.mpk"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offloaded).To(BeEmpty())
+	})
+
+	It("runs only once", func() {
+		Expect(idx.Migrate(ctx, root, &log)).To(Succeed())
+		Expect(xattr.Set(file.path, prefixes.IDAttr, []byte("other"))).To(Succeed())
+		Expect(idx.Migrate(ctx, root, &log)).To(Succeed())
+
+		_, ok := metaindex.NewIDCache(idx).Get(ctx, "spaceid", "other")
+		Expect(ok).To(BeFalse())
+	})
+})
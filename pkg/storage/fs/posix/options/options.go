@@ -31,6 +31,10 @@ type Options struct {
 
 	UseSpaceGroups bool `mapstructure:"use_space_groups"`
 
+	// MetadataIndexPath is the database file of the "index" metadata backend. It
+	// should be on a local filesystem and defaults to a file in the storage root.
+	MetadataIndexPath string `mapstructure:"metadata_index_path"`
+
 	ScanDebounceDelay time.Duration `mapstructure:"scan_debounce_delay"`
 
 	// Allows generating revisions from changes done to the local storage.
@@ -39,6 +43,11 @@ type Options struct {
 	// a revision when the file is changed.
 	EnableFSRevisions bool `mapstructure:"enable_fs_revisions"`
 
+	// UseProjectQuotas syncs the space quotas to filesystem project quotas
+	// (XFS or ext4 with prjquota) and reads the space usage back from them.
+	// Falls back to logical quota accounting if they are not available.
+	UseProjectQuotas bool `mapstructure:"use_project_quotas"`
+
 	ScanFS                  bool   `mapstructure:"scan_fs"`
 	WatchFS                 bool   `mapstructure:"watch_fs"`
 	WatchType               string `mapstructure:"watch_type"`
@@ -47,6 +56,12 @@ type Options struct {
 
 	// InotifyWatcher specific options
 	InotifyStatsFrequency time.Duration `mapstructure:"inotify_stats_frequency"`
+
+	// FanotifyWatcher specific options
+	// FanotifyMarkType selects the fanotify mark placed on the storage root.
+	// Only "filesystem" (default) is supported, mount marks can't report the
+	// create, delete and move events the watcher relies on.
+	FanotifyMarkType string `mapstructure:"fanotify_mark_type"`
 }
 
 // New returns a new Options instance for the given configuration
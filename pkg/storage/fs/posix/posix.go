@@ -23,8 +23,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"syscall"
 
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/rs/zerolog"
 	tusd "github.com/tus/tusd/v2/pkg/handler"
 	microstore "go-micro.dev/v4/store"
@@ -34,7 +36,9 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/blobstore"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/metaindex"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/options"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/quota"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/timemanager"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/trashbin"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/tree"
@@ -42,23 +46,32 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/aspects"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/permissions"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/upload"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/usermapper"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/middleware"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/store"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("posix", New)
+	cfg.Register("storage.fs", "posix", options.Options{})
 }
 
 type posixFS struct {
 	storage.FS
 
-	um usermapper.Mapper
+	um  usermapper.Mapper
+	lu  *lookup.Lookup
+	tp  *tree.Tree
+	qm  *quota.Manager
+	log *zerolog.Logger
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
@@ -81,22 +94,38 @@ func New(m map[string]interface{}, stream events.Stream, log *zerolog.Logger) (s
 	}
 
 	var lu *lookup.Lookup
+	metadataPath := func(n metadata.MetadataNode) string {
+		spaceRoot, _ := lu.IDCache.Get(context.Background(), n.GetSpaceID(), n.GetSpaceID())
+		if len(spaceRoot) == 0 {
+			return ""
+		}
+
+		return filepath.Join(spaceRoot, lookup.MetadataDir)
+	}
 	switch o.MetadataBackend {
 	case "xattrs":
 		lu = lookup.New(metadata.NewXattrsBackend(o.FileMetadataCache), um, o, &timemanager.Manager{})
 	case "hybrid":
 		lu = lookup.New(metadata.NewHybridBackend(1024, // start offloading grants after 1KB
-			func(n metadata.MetadataNode) string {
-				spaceRoot, _ := lu.IDCache.Get(context.Background(), n.GetSpaceID(), n.GetSpaceID())
-				if len(spaceRoot) == 0 {
-					return ""
-				}
-
-				return filepath.Join(spaceRoot, lookup.MetadataDir)
-			},
+			metadataPath,
 			o.FileMetadataCache), um, o, &timemanager.Manager{})
+	case "index":
+		indexPath := o.MetadataIndexPath
+		if indexPath == "" {
+			indexPath = filepath.Join(o.Root, lookup.MetadataDir, "index.db")
+		}
+		idx, err := metaindex.Open(indexPath)
+		if err != nil {
+			return nil, err
+		}
+		// move the metadata of existing trees out of the extended attributes
+		if err := idx.Migrate(context.Background(), o.Root, log); err != nil {
+			return nil, errors.Wrap(err, "could not migrate metadata to the index")
+		}
+		lu = lookup.New(metaindex.NewBackend(idx, metadataPath), um, o, &timemanager.Manager{})
+		lu.IDCache = metaindex.NewIDCache(idx)
 	default:
-		return nil, fmt.Errorf("unknown metadata backend %s, only 'xattrs' or 'hybrid' (default) supported", o.MetadataBackend)
+		return nil, fmt.Errorf("unknown metadata backend %s, only 'xattrs', 'hybrid' (default) or 'index' supported", o.MetadataBackend)
 	}
 
 	permissionsSelector, err := pool.PermissionsSelector(o.PermissionsSVC, pool.WithTLSMode(o.PermTLSMode))
@@ -124,7 +153,7 @@ func New(m map[string]interface{}, stream events.Stream, log *zerolog.Logger) (s
 		return nil, fmt.Errorf("the posix driver requires a shared id cache, e.g. nats-js-kv or redis")
 	}
 
-	tp, err := tree.New(lu, bs, um, trashbin, p, o, stream, store.Create(
+	idCache, err := store.New(
 		store.Store(o.IDCache.Store),
 		store.TTL(o.IDCache.TTL),
 		store.Size(o.IDCache.Size),
@@ -133,19 +162,26 @@ func New(m map[string]interface{}, stream events.Stream, log *zerolog.Logger) (s
 		microstore.Table(o.IDCache.Table),
 		store.DisablePersistence(o.IDCache.DisablePersistence),
 		store.Authentication(o.IDCache.AuthUsername, o.IDCache.AuthPassword),
-	), log)
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := tree.New(lu, bs, um, trashbin, p, o, stream, idCache, log)
 	if err != nil {
 		return nil, err
 	}
 
 	aspects := aspects.Aspects{
-		Lookup:            lu,
-		Tree:              tp,
-		Permissions:       p,
-		EventStream:       stream,
-		UserMapper:        um,
-		DisableVersioning: o.DisableVersioning,
-		Trashbin:          trashbin,
+		Lookup:                lu,
+		Tree:                  tp,
+		Permissions:           p,
+		EventStream:           stream,
+		UserMapper:            um,
+		DisableVersioning:     o.DisableVersioning,
+		CountTrashInQuota:     o.CountTrashInQuota,
+		CountRevisionsInQuota: o.CountRevisionsInQuota,
+		Trashbin:              trashbin,
+		RevisionRetention:     o.RevisionRetention.RevisionPolicy,
 	}
 
 	dfs, err := decomposedfs.New(&o.Options, aspects, log)
@@ -189,10 +225,24 @@ func New(m map[string]interface{}, stream events.Stream, log *zerolog.Logger) (s
 	mw := middleware.NewFS(dfs, hooks...)
 	fs.FS = mw
 	fs.um = um
+	fs.lu = lu
+	fs.tp = tp
+	fs.log = log
+	if o.UseProjectQuotas {
+		fs.qm = quota.New(o.Root, log)
+	}
 
 	return fs, nil
 }
 
+// Shutdown stops the filesystem watcher and shuts down the storage
+func (fs *posixFS) Shutdown(ctx context.Context) error {
+	if err := fs.tp.Close(); err != nil {
+		fs.log.Error().Err(err).Msg("failed to close filesystem watcher")
+	}
+	return fs.FS.Shutdown(ctx)
+}
+
 // ListUploadSessions returns the upload sessions matching the given filter
 func (fs *posixFS) ListUploadSessions(ctx context.Context, filter storage.UploadSessionFilter) ([]storage.UploadSession, error) {
 	return fs.FS.(storage.UploadSessionLister).ListUploadSessions(ctx, filter)
@@ -233,3 +283,129 @@ func (fs *posixFS) AsLengthDeclarableUpload(up tusd.Upload) tusd.LengthDeclarabl
 func (fs *posixFS) AsConcatableUpload(up tusd.Upload) tusd.ConcatableUpload {
 	return up.(*upload.DecomposedFsSession)
 }
+
+// CreateStorageSpace creates a storage space and sets up its project quota
+func (fs *posixFS) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	res, err := fs.FS.CreateStorageSpace(ctx, req)
+	if err == nil && res.GetStorageSpace().GetRoot() != nil && fs.qm.Enabled() {
+		fs.syncQuota(ctx, res.GetStorageSpace().GetRoot().GetSpaceId())
+	}
+	return res, err
+}
+
+// UpdateStorageSpace updates a storage space and syncs a changed quota to its project quota
+func (fs *posixFS) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	res, err := fs.FS.UpdateStorageSpace(ctx, req)
+	if err == nil && req.GetStorageSpace().GetQuota() != nil && fs.qm.Enabled() {
+		if rid, err := storagespace.ParseID(req.GetStorageSpace().GetId().GetOpaqueId()); err == nil {
+			fs.syncQuota(ctx, rid.GetSpaceId())
+		}
+	}
+	return res, err
+}
+
+// DeleteStorageSpace deletes a storage space and releases the project quota of purged spaces
+func (fs *posixFS) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	if err := fs.FS.DeleteStorageSpace(ctx, req); err != nil {
+		return err
+	}
+	if _, purge := req.GetOpaque().GetMap()["purge"]; !purge || !fs.qm.Enabled() {
+		return nil
+	}
+	if _, spaceID, _, err := storagespace.SplitID(req.GetId().GetOpaqueId()); err == nil {
+		if err := fs.qm.Release(spaceID); err != nil {
+			fs.log.Error().Err(err).Str("spaceid", spaceID).Msg("could not release project quota")
+		}
+	}
+	return nil
+}
+
+// GetQuota returns the quota of the space, using the usage of the project quota if available
+func (fs *posixFS) GetQuota(ctx context.Context, ref *provider.Reference) (uint64, uint64, uint64, error) {
+	total, used, remaining, err := fs.FS.GetQuota(ctx, ref)
+	if err != nil || !fs.qm.Enabled() {
+		return total, used, remaining, err
+	}
+
+	spaceID := ref.GetResourceId().GetSpaceId()
+	if u, ok := fs.spaceUsage(ctx, spaceID); ok {
+		used = u
+		remaining = remainingBytes(total, used, remaining)
+	}
+	return total, used, remaining, nil
+}
+
+// ListStorageSpaces lists the spaces, using the usage of their project quotas if available
+func (fs *posixFS) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter, unrestricted bool) ([]*provider.StorageSpace, error) {
+	spaces, err := fs.FS.ListStorageSpaces(ctx, filter, unrestricted)
+	if err != nil || !fs.qm.Enabled() {
+		return spaces, err
+	}
+
+	for _, space := range spaces {
+		u, ok := fs.spaceUsage(ctx, space.GetRoot().GetSpaceId())
+		if !ok {
+			continue
+		}
+		total, _ := strconv.ParseUint(utils.ReadPlainFromOpaque(space.Opaque, "quota.total"), 10, 64)
+		remaining, _ := strconv.ParseUint(utils.ReadPlainFromOpaque(space.Opaque, "quota.remaining"), 10, 64)
+		space.Opaque = utils.AppendPlainToOpaque(space.Opaque, "quota.used", strconv.FormatUint(u, 10))
+		space.Opaque = utils.AppendPlainToOpaque(space.Opaque, "quota.remaining", strconv.FormatUint(remainingBytes(total, u, remaining), 10))
+	}
+	return spaces, nil
+}
+
+// syncQuota sets the project quota limit of the space to its current space quota
+func (fs *posixFS) syncQuota(ctx context.Context, spaceID string) {
+	if err := fs.doSyncQuota(ctx, spaceID); err != nil {
+		fs.log.Error().Err(err).Str("spaceid", spaceID).Msg("could not sync project quota")
+	}
+}
+
+func (fs *posixFS) doSyncQuota(ctx context.Context, spaceID string) error {
+	spaceRoot, err := fs.lu.NodeFromSpaceID(ctx, spaceID)
+	if err != nil {
+		return err
+	}
+
+	// negative values are the magic numbers for uncalculated, unknown and unlimited quotas
+	limit, err := spaceRoot.XattrInt64(ctx, prefixes.QuotaAttr)
+	if err != nil || limit < 0 {
+		limit = 0
+	}
+	return fs.qm.Sync(spaceRoot.InternalPath(), spaceID, uint64(limit))
+}
+
+// spaceUsage returns the bytes used by the space according to its project quota.
+// Spaces that have been created before project quotas were enabled are set up on first use.
+func (fs *posixFS) spaceUsage(ctx context.Context, spaceID string) (uint64, bool) {
+	if spaceID == "" {
+		return 0, false
+	}
+
+	root := fs.lu.InternalPath(spaceID, spaceID)
+	used, err := fs.qm.Usage(root)
+	if errors.Is(err, quota.ErrNoProject) {
+		if err = fs.doSyncQuota(ctx, spaceID); err == nil {
+			used, err = fs.qm.Usage(root)
+		}
+	}
+	if err != nil {
+		fs.log.Debug().Err(err).Str("spaceid", spaceID).Msg("could not read project quota usage, using logical accounting")
+		return 0, false
+	}
+	return used, true
+}
+
+// remainingBytes recalculates the remaining bytes for a limited quota
+func remainingBytes(total, used, remaining uint64) uint64 {
+	switch {
+	case total == 0:
+		// unlimited or unknown quota, keep what the logical accounting returned
+		return remaining
+	case total > used:
+		return total - used
+	default:
+		return 0
+	}
+}
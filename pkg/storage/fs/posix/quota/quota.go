@@ -0,0 +1,238 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package quota manages filesystem project quotas for posixfs spaces.
+// Every space root is assigned a project id that is inherited by everything
+// created below it, so the kernel accounts and limits the space usage even
+// for files that are written directly to the filesystem. The project ids in
+// use are kept in a registry next to the other indexes of the storage root,
+// so no two spaces share a project.
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rogpeppe/go-internal/lockedfile"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrUnsupported is returned when the filesystem does not support or has not enabled project quotas
+	ErrUnsupported = errors.New("project quotas are not supported")
+	// ErrNoProject is returned when no project has been assigned to a space yet
+	ErrNoProject = errors.New("no project assigned")
+	// ErrProjectInUse is returned when the project of a space root is assigned to a different space
+	ErrProjectInUse = errors.New("project is assigned to a different space")
+)
+
+// Quotactl abstracts the kernel interfaces used to manage project quotas
+type Quotactl interface {
+	// Supported returns an error wrapping ErrUnsupported if project quotas are not enabled for the filesystem of path
+	Supported(path string) error
+	// ProjectID returns the project id of the given file or directory
+	ProjectID(path string) (uint32, error)
+	// SetProjectID assigns the project id to the given file or directory. Directories pass it on to new children.
+	SetProjectID(path string, id uint32) error
+	// SetLimit sets the hard block limit of the project in bytes, 0 removes the limit
+	SetLimit(path string, id uint32, limit uint64) error
+	// Usage returns the number of bytes used by the project
+	Usage(path string, id uint32) (uint64, error)
+}
+
+// Manager syncs space quotas to filesystem project quotas
+type Manager struct {
+	q        Quotactl
+	log      *zerolog.Logger
+	root     string
+	registry string
+	enabled  bool
+}
+
+// New returns a Manager using the project quotas of the filesystem the root lives on.
+// If they are not available the manager is disabled and callers fall back to logical accounting.
+func New(root string, log *zerolog.Logger) *Manager {
+	return NewWithQuotactl(root, newQuotactl(), log)
+}
+
+// NewWithQuotactl returns a Manager using the given Quotactl implementation
+func NewWithQuotactl(root string, q Quotactl, log *zerolog.Logger) *Manager {
+	m := &Manager{
+		q:        q,
+		log:      log,
+		root:     root,
+		registry: filepath.Join(root, "indexes", "projects.json"),
+	}
+	if err := q.Supported(root); err != nil {
+		log.Warn().Err(err).Str("root", root).Msg("project quotas are not available, falling back to logical quota accounting")
+		return m
+	}
+	m.enabled = true
+	return m
+}
+
+// Enabled returns true if project quotas are available
+func (m *Manager) Enabled() bool {
+	return m != nil && m.enabled
+}
+
+// preferredID returns the project id tried first for new spaces with the given
+// id. 0 is reserved for files that do not belong to any project.
+func preferredID(spaceID string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(spaceID))
+	if id := h.Sum32(); id != 0 {
+		return id
+	}
+	return 1
+}
+
+// Sync assigns a project to the space root if it does not have one yet and sets
+// the project limit to the given number of bytes. A limit of 0 means unlimited.
+func (m *Manager) Sync(root, spaceID string, limit uint64) error {
+	if !m.Enabled() {
+		return ErrUnsupported
+	}
+
+	id, err := m.q.ProjectID(root)
+	if err != nil {
+		return errors.Wrap(err, "could not read project id")
+	}
+	if id == 0 {
+		if id, err = m.register(spaceID, 0); err != nil {
+			return errors.Wrapf(err, "could not allocate project for space %s", spaceID)
+		}
+		if err := m.assign(root, id); err != nil {
+			return errors.Wrapf(err, "could not assign project %d to space %s", id, spaceID)
+		}
+		m.log.Debug().Str("spaceid", spaceID).Uint32("project", id).Msg("assigned project to space")
+	} else if _, err := m.register(spaceID, id); err != nil {
+		// keep projects that have been set up by an administrator, unless another space uses them
+		return errors.Wrapf(err, "could not register project %d of space %s", id, spaceID)
+	}
+
+	return errors.Wrapf(m.q.SetLimit(root, id, limit), "could not set limit of project %d", id)
+}
+
+// Release removes the limit of the project of a purged space and frees the
+// project id for new spaces.
+func (m *Manager) Release(spaceID string) error {
+	if !m.Enabled() {
+		return ErrUnsupported
+	}
+
+	return m.updateRegistry(func(projects map[string]uint32) error {
+		id, ok := projects[spaceID]
+		if !ok {
+			return nil
+		}
+		if err := m.q.SetLimit(m.root, id, 0); err != nil {
+			return errors.Wrapf(err, "could not remove limit of project %d", id)
+		}
+		delete(projects, spaceID)
+		return nil
+	})
+}
+
+// Usage returns the number of bytes used by the project of the space root
+func (m *Manager) Usage(root string) (uint64, error) {
+	if !m.Enabled() {
+		return 0, ErrUnsupported
+	}
+
+	id, err := m.q.ProjectID(root)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not read project id")
+	}
+	if id == 0 {
+		return 0, ErrNoProject
+	}
+	return m.q.Usage(root, id)
+}
+
+// register records the project of the space. If id is 0 the registered project
+// of the space is returned or a project that is not used by any other space is
+// allocated. A given id must not be registered for a different space.
+func (m *Manager) register(spaceID string, id uint32) (uint32, error) {
+	err := m.updateRegistry(func(projects map[string]uint32) error {
+		if id == 0 {
+			if registered, ok := projects[spaceID]; ok {
+				id = registered
+				return nil
+			}
+		}
+
+		used := make(map[uint32]string, len(projects))
+		for s, pid := range projects {
+			used[pid] = s
+		}
+		if id != 0 {
+			if s, ok := used[id]; ok && s != spaceID {
+				return errors.Wrap(ErrProjectInUse, fmt.Sprintf("project %d is assigned to space %s", id, s))
+			}
+		} else {
+			id = preferredID(spaceID)
+			for _, ok := used[id]; ok || id == 0; _, ok = used[id] {
+				id++
+			}
+		}
+		projects[spaceID] = id
+		return nil
+	})
+	return id, err
+}
+
+// updateRegistry applies f to the projects registered by space id while
+// holding a lock on the registry
+func (m *Manager) updateRegistry(f func(map[string]uint32) error) error {
+	if err := os.MkdirAll(filepath.Dir(m.registry), 0700); err != nil {
+		return err
+	}
+	return lockedfile.Transform(m.registry, func(b []byte) ([]byte, error) {
+		projects := map[string]uint32{}
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &projects); err != nil {
+				return nil, errors.Wrap(err, "could not read project registry")
+			}
+		}
+		if err := f(projects); err != nil {
+			return nil, err
+		}
+		return json.Marshal(projects)
+	})
+}
+
+// assign sets the project id on the root and all existing files and
+// directories below it. New children inherit the id from their parent.
+func (m *Manager) assign(root string, id uint32) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// only files and directories carry a project, opening fifos or devices might block
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		return m.q.SetProjectID(path, id)
+	})
+}
@@ -0,0 +1,218 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package quota_test
+
+import (
+	"hash/fnv"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/quota"
+)
+
+// fakeQuotactl keeps project ids, limits and usage in memory
+type fakeQuotactl struct {
+	supported error
+	projects  map[string]uint32
+	limits    map[uint32]uint64
+	usage     map[uint32]uint64
+}
+
+func newFakeQuotactl() *fakeQuotactl {
+	return &fakeQuotactl{
+		projects: map[string]uint32{},
+		limits:   map[uint32]uint64{},
+		usage:    map[uint32]uint64{},
+	}
+}
+
+func (f *fakeQuotactl) Supported(string) error { return f.supported }
+
+func (f *fakeQuotactl) ProjectID(path string) (uint32, error) { return f.projects[path], nil }
+
+func (f *fakeQuotactl) SetProjectID(path string, id uint32) error {
+	f.projects[path] = id
+	return nil
+}
+
+func (f *fakeQuotactl) SetLimit(_ string, id uint32, limit uint64) error {
+	f.limits[id] = limit
+	return nil
+}
+
+func (f *fakeQuotactl) Usage(_ string, id uint32) (uint64, error) { return f.usage[id], nil }
+
+var _ = Describe("Manager", func() {
+	var (
+		tmpdir string
+		root   string
+		fake   *fakeQuotactl
+		log    = zerolog.Nop()
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpdir, err = os.MkdirTemp(os.TempDir(), "QuotaTest-")
+		Expect(err).ToNot(HaveOccurred())
+
+		root = filepath.Join(tmpdir, "space")
+		Expect(os.MkdirAll(filepath.Join(root, "dir"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "dir", "file"), []byte("content"), 0600)).To(Succeed())
+		Expect(os.Symlink("dir", filepath.Join(root, "link"))).To(Succeed())
+
+		fake = newFakeQuotactl()
+	})
+
+	AfterEach(func() {
+		if tmpdir != "" {
+			os.RemoveAll(tmpdir)
+		}
+	})
+
+	Context("without project quota support", func() {
+		var m *quota.Manager
+
+		BeforeEach(func() {
+			fake.supported = quota.ErrUnsupported
+			m = quota.NewWithQuotactl(tmpdir, fake, &log)
+		})
+
+		It("is disabled", func() {
+			Expect(m.Enabled()).To(BeFalse())
+		})
+
+		It("does not touch the space", func() {
+			Expect(m.Sync(root, "space-1", 1024)).To(MatchError(quota.ErrUnsupported))
+			Expect(fake.projects).To(BeEmpty())
+
+			_, err := m.Usage(root)
+			Expect(err).To(MatchError(quota.ErrUnsupported))
+		})
+	})
+
+	Context("with project quota support", func() {
+		var m *quota.Manager
+
+		BeforeEach(func() {
+			m = quota.NewWithQuotactl(tmpdir, fake, &log)
+		})
+
+		It("is enabled", func() {
+			Expect(m.Enabled()).To(BeTrue())
+		})
+
+		It("assigns the project to the existing tree", func() {
+			Expect(m.Sync(root, "space-1", 1024)).To(Succeed())
+
+			id := fake.projects[root]
+			Expect(id).ToNot(BeZero())
+			Expect(fake.projects).To(Equal(map[string]uint32{
+				root:                               id,
+				filepath.Join(root, "dir"):         id,
+				filepath.Join(root, "dir", "file"): id,
+			}))
+			Expect(fake.limits[id]).To(Equal(uint64(1024)))
+		})
+
+		It("keeps an existing project", func() {
+			fake.projects[root] = 42
+
+			Expect(m.Sync(root, "space-1", 2048)).To(Succeed())
+			Expect(fake.projects).To(HaveLen(1))
+			Expect(fake.limits[42]).To(Equal(uint64(2048)))
+		})
+
+		It("updates the limit", func() {
+			Expect(m.Sync(root, "space-1", 1024)).To(Succeed())
+			Expect(m.Sync(root, "space-1", 0)).To(Succeed())
+			Expect(fake.limits[fake.projects[root]]).To(BeZero())
+		})
+
+		It("assigns a different project to every space", func() {
+			// occupy the project the id of space-2 hashes to
+			other := filepath.Join(tmpdir, "other")
+			Expect(os.Mkdir(other, 0700)).To(Succeed())
+			h := fnv.New32a()
+			_, _ = h.Write([]byte("space-2"))
+			fake.projects[other] = h.Sum32()
+			Expect(m.Sync(other, "space-1", 1024)).To(Succeed())
+
+			Expect(m.Sync(root, "space-2", 2048)).To(Succeed())
+			Expect(fake.projects[root]).ToNot(BeZero())
+			Expect(fake.projects[root]).ToNot(Equal(fake.projects[other]))
+			Expect(fake.limits[fake.projects[other]]).To(Equal(uint64(1024)))
+			Expect(fake.limits[fake.projects[root]]).To(Equal(uint64(2048)))
+		})
+
+		It("rejects projects assigned to a different space", func() {
+			Expect(m.Sync(root, "space-1", 1024)).To(Succeed())
+
+			other := filepath.Join(tmpdir, "other")
+			Expect(os.Mkdir(other, 0700)).To(Succeed())
+			fake.projects[other] = fake.projects[root]
+			Expect(m.Sync(other, "space-2", 2048)).To(MatchError(quota.ErrProjectInUse))
+			Expect(fake.limits[fake.projects[root]]).To(Equal(uint64(1024)))
+		})
+
+		It("persists the projects of the spaces", func() {
+			Expect(m.Sync(root, "space-1", 1024)).To(Succeed())
+			id := fake.projects[root]
+
+			// a new manager sees the project as taken
+			m = quota.NewWithQuotactl(tmpdir, fake, &log)
+			other := filepath.Join(tmpdir, "other")
+			Expect(os.Mkdir(other, 0700)).To(Succeed())
+			fake.projects[other] = id
+			Expect(m.Sync(other, "space-2", 2048)).To(MatchError(quota.ErrProjectInUse))
+		})
+
+		It("releases the project of a purged space", func() {
+			Expect(m.Sync(root, "space-1", 1024)).To(Succeed())
+			id := fake.projects[root]
+
+			Expect(m.Release("space-1")).To(Succeed())
+			Expect(fake.limits[id]).To(BeZero())
+
+			// the project can be used by another space now
+			other := filepath.Join(tmpdir, "other")
+			Expect(os.Mkdir(other, 0700)).To(Succeed())
+			fake.projects[other] = id
+			Expect(m.Sync(other, "space-2", 2048)).To(Succeed())
+			Expect(fake.limits[id]).To(Equal(uint64(2048)))
+
+			Expect(m.Release("unknown")).To(Succeed())
+		})
+
+		It("reports the usage of the project", func() {
+			_, err := m.Usage(root)
+			Expect(err).To(MatchError(quota.ErrNoProject))
+
+			Expect(m.Sync(root, "space-1", 1024)).To(Succeed())
+			fake.usage[fake.projects[root]] = 512
+
+			used, err := m.Usage(root)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(used).To(Equal(uint64(512)))
+		})
+	})
+})
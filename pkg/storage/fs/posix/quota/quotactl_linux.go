@@ -0,0 +1,176 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package quota
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// constants from linux/fs.h and linux/quota.h that are not part of x/sys/unix
+const (
+	fsIocFsGetXattr = 0x801c581f // _IOR('X', 31, struct fsxattr)
+	fsIocFsSetXattr = 0x401c5820 // _IOW('X', 32, struct fsxattr)
+
+	fsXflagProjInherit = 0x00000200
+
+	qGetInfo  = 0x800005
+	qGetQuota = 0x800007
+	qSetQuota = 0x800008
+	prjQuota  = 2
+
+	qifBLimits   = 1
+	qifDqBlkSize = 1024
+)
+
+// fsxattr mirrors struct fsxattr
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// ifDqblk mirrors struct if_dqblk
+type ifDqblk struct {
+	bHardLimit uint64
+	bSoftLimit uint64
+	curSpace   uint64
+	iHardLimit uint64
+	iSoftLimit uint64
+	curInodes  uint64
+	bTime      uint64
+	iTime      uint64
+	valid      uint32
+}
+
+// ifDqinfo mirrors struct if_dqinfo
+type ifDqinfo struct {
+	bGrace uint64
+	iGrace uint64
+	flags  uint32
+	valid  uint32
+}
+
+type linuxQuotactl struct{}
+
+func newQuotactl() Quotactl {
+	return linuxQuotactl{}
+}
+
+// Supported checks that project quota accounting is enabled for the filesystem of path
+func (linuxQuotactl) Supported(path string) error {
+	info := ifDqinfo{}
+	if err := quotactl(path, qGetInfo, 0, unsafe.Pointer(&info)); err != nil {
+		return err
+	}
+	_, err := linuxQuotactl{}.ProjectID(path)
+	return err
+}
+
+// ProjectID returns the project id of the given file or directory
+func (linuxQuotactl) ProjectID(path string) (uint32, error) {
+	fsx := fsxattr{}
+	err := withFile(path, func(fd uintptr) error {
+		return ioctl(fd, fsIocFsGetXattr, unsafe.Pointer(&fsx))
+	})
+	return fsx.projid, err
+}
+
+// SetProjectID assigns the project id to the given file or directory
+func (linuxQuotactl) SetProjectID(path string, id uint32) error {
+	return withFile(path, func(fd uintptr) error {
+		fsx := fsxattr{}
+		if err := ioctl(fd, fsIocFsGetXattr, unsafe.Pointer(&fsx)); err != nil {
+			return err
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fsx.projid = id
+		if fi.IsDir() {
+			fsx.xflags |= fsXflagProjInherit
+		}
+		return ioctl(fd, fsIocFsSetXattr, unsafe.Pointer(&fsx))
+	})
+}
+
+// SetLimit sets the hard block limit of the project
+func (linuxQuotactl) SetLimit(path string, id uint32, limit uint64) error {
+	dq := ifDqblk{
+		// the kernel counts in blocks of 1KiB, round up to not deny writes that fit the quota
+		bHardLimit: (limit + qifDqBlkSize - 1) / qifDqBlkSize,
+		valid:      qifBLimits,
+	}
+	return quotactl(path, qSetQuota, id, unsafe.Pointer(&dq))
+}
+
+// Usage returns the number of bytes used by the project
+func (linuxQuotactl) Usage(path string, id uint32) (uint64, error) {
+	dq := ifDqblk{}
+	err := quotactl(path, qGetQuota, id, unsafe.Pointer(&dq))
+	if errors.Is(err, unix.ENOENT) {
+		// the project has not been charged anything yet
+		return 0, nil
+	}
+	return dq.curSpace, err
+}
+
+// quotactl calls quotactl_fd(2) for the project quota of the filesystem path lives on
+func quotactl(path string, cmd int, id uint32, addr unsafe.Pointer) error {
+	return withFile(path, func(fd uintptr) error {
+		_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL_FD, fd, uintptr(cmd<<8|prjQuota), uintptr(id), uintptr(addr), 0, 0)
+		switch errno {
+		case 0:
+			return nil
+		case unix.ENOSYS, unix.ESRCH, unix.ENOTTY, unix.EOPNOTSUPP, unix.EINVAL:
+			// no kernel support, quotas not enabled or not a supported filesystem
+			return errors.Wrap(ErrUnsupported, errno.Error())
+		default:
+			return errno
+		}
+	})
+}
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg))
+	switch errno {
+	case 0:
+		return nil
+	case unix.ENOTTY, unix.EOPNOTSUPP:
+		return errors.Wrap(ErrUnsupported, errno.Error())
+	default:
+		return errno
+	}
+}
+
+func withFile(path string, f func(fd uintptr) error) error {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+	return f(uintptr(fd))
+}
@@ -0,0 +1,104 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package quota_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/quota"
+)
+
+// These tests need root privileges and xfsprogs to mount a loopback XFS
+// image with project quotas. They are skipped when that is not possible.
+var _ = Describe("Project quotas on XFS", func() {
+	var (
+		tmpdir  string
+		mnt     string
+		mounted bool
+		log     = zerolog.Nop()
+	)
+
+	BeforeEach(func() {
+		if os.Geteuid() != 0 {
+			Skip("mounting a loopback image requires root privileges")
+		}
+		if _, err := exec.LookPath("mkfs.xfs"); err != nil {
+			Skip("mkfs.xfs is not available")
+		}
+
+		var err error
+		tmpdir, err = os.MkdirTemp(os.TempDir(), "QuotaXFSTest-")
+		Expect(err).ToNot(HaveOccurred())
+
+		img := filepath.Join(tmpdir, "xfs.img")
+		f, err := os.Create(img)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Truncate(512 << 20)).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+		if out, err := exec.Command("mkfs.xfs", "-q", img).CombinedOutput(); err != nil {
+			Skip("could not create xfs image: " + string(out))
+		}
+
+		mnt = filepath.Join(tmpdir, "mnt")
+		Expect(os.Mkdir(mnt, 0700)).To(Succeed())
+		if out, err := exec.Command("mount", "-o", "loop,prjquota", img, mnt).CombinedOutput(); err != nil {
+			Skip("could not mount xfs image: " + string(out))
+		}
+		mounted = true
+	})
+
+	AfterEach(func() {
+		if mounted {
+			Expect(exec.Command("umount", mnt).Run()).To(Succeed())
+			mounted = false
+		}
+		if tmpdir != "" {
+			os.RemoveAll(tmpdir)
+		}
+	})
+
+	It("enforces the space quota", func() {
+		m := quota.New(mnt, &log)
+		Expect(m.Enabled()).To(BeTrue())
+
+		root := filepath.Join(mnt, "space")
+		Expect(os.Mkdir(root, 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "existing"), make([]byte, 64<<10), 0600)).To(Succeed())
+
+		Expect(m.Sync(root, "space-1", 1<<20)).To(Succeed())
+
+		used, err := m.Usage(root)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(used).To(BeNumerically(">=", 64<<10))
+
+		// files created directly on the filesystem are charged to the space
+		err = os.WriteFile(filepath.Join(root, "big"), make([]byte, 2<<20), 0600)
+		Expect(err).To(MatchError(syscall.EDQUOT))
+
+		Expect(m.Sync(root, "space-1", 0)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "big"), make([]byte, 2<<20), 0600)).To(Succeed())
+	})
+})
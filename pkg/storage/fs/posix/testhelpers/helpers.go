@@ -41,6 +41,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/metaindex"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/options"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/timemanager"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/trashbin"
@@ -199,6 +200,20 @@ func NewTestEnv(config map[string]interface{}) (*TestEnv, error) {
 			}), um, o, &timemanager.Manager{})
 	case "messagepack":
 		lu = lookup.New(metadata.NewMessagePackBackend(o.FileMetadataCache), um, o, &timemanager.Manager{})
+	case "index":
+		idx, err := metaindex.Open(filepath.Join(tmpRoot, lookup.MetadataDir, "index.db"))
+		if err != nil {
+			return nil, err
+		}
+		lu = lookup.New(metaindex.NewBackend(idx, func(n metadata.MetadataNode) string {
+			spaceRoot, _ := lu.IDCache.Get(context.Background(), n.GetSpaceID(), n.GetSpaceID())
+			if len(spaceRoot) == 0 {
+				return ""
+			}
+
+			return filepath.Join(spaceRoot, lookup.MetadataDir)
+		}), um, o, &timemanager.Manager{})
+		lu.IDCache = metaindex.NewIDCache(idx)
 	default:
 		return nil, fmt.Errorf("unknown metadata backend %s", o.MetadataBackend)
 	}
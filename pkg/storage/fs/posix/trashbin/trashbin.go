@@ -208,7 +208,7 @@ func (tb *Trashbin) ListRecycle(ctx context.Context, spaceID string, key, relati
 		}
 		item := &provider.RecycleItem{
 			Key:  key,
-			Size: uint64(fi.Size()),
+			Size: tb.itemSize(ctx, spaceID, base, fi),
 			Ref: &provider.Reference{
 				ResourceId: &provider.ResourceId{
 					SpaceId:  spaceID,
@@ -273,7 +273,7 @@ func (tb *Trashbin) ListRecycle(ctx context.Context, spaceID string, key, relati
 
 		item := &provider.RecycleItem{
 			Key:  filepath.Join(key, relativePath, entryKey),
-			Size: uint64(fi.Size()),
+			Size: tb.itemSize(ctx, spaceID, filepath.Join(base, entry.Name()), fi),
 			Ref: &provider.Reference{
 				ResourceId: &provider.ResourceId{
 					SpaceId:  spaceID,
@@ -295,6 +295,22 @@ func (tb *Trashbin) ListRecycle(ctx context.Context, spaceID string, key, relati
 	return items, nil
 }
 
+// itemSize returns the size of a file or the tree size of a directory in the trash
+func (tb *Trashbin) itemSize(ctx context.Context, spaceID, path string, fi os.FileInfo) uint64 {
+	if !fi.IsDir() {
+		return uint64(fi.Size())
+	}
+	_, id, _, _, err := tb.lu.MetadataBackend().IdentifyPath(ctx, path)
+	if err != nil || id == "" {
+		return uint64(fi.Size())
+	}
+	treeSize, err := tb.lu.MetadataBackend().GetInt64(ctx, &trashNode{spaceID: spaceID, id: id, path: path}, prefixes.TreesizeAttr)
+	if err != nil || treeSize < 0 {
+		return uint64(fi.Size())
+	}
+	return uint64(treeSize)
+}
+
 // RestoreRecycleItem restores the specified item
 func (tb *Trashbin) RestoreRecycleItem(ctx context.Context, spaceID string, key, relativePath string, restoreRef *provider.Reference) (*node.Node, error) {
 	_, span := tracer.Start(ctx, "RestoreRecycleItem")
@@ -0,0 +1,342 @@
+//go:build linux
+
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/options"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// fanotifyEventMask lists the events the fanotify watcher subscribes to
+	fanotifyEventMask = unix.FAN_CREATE | unix.FAN_DELETE | unix.FAN_MOVED_FROM | unix.FAN_MOVED_TO | unix.FAN_CLOSE_WRITE | unix.FAN_ONDIR
+
+	// fanotifyMetadataSize is the size of struct fanotify_event_metadata
+	fanotifyMetadataSize = 24
+	// fanotifyInfoHeaderSize is the size of struct fanotify_event_info_header
+	fanotifyInfoHeaderSize = 4
+	// fanotifyFsidSize is the size of the __kernel_fsid_t in struct fanotify_event_info_fid
+	fanotifyFsidSize = 8
+	// fanotifyFileHandleHeaderSize is the size of the handle_bytes and handle_type fields of struct file_handle
+	fanotifyFileHandleHeaderSize = 8
+)
+
+// FanotifyWatcher watches a storage root using a single fanotify mark.
+// Unlike inotify it does not need one watch per directory, events carry the
+// handle of the parent directory and the name of the affected entry.
+type FanotifyWatcher struct {
+	tree    *Tree
+	options *options.Options
+	log     *zerolog.Logger
+
+	fd      int
+	mountFD int
+	// stopFD is an eventfd used to wake up the watcher when it is closed
+	stopFD int
+
+	mu       sync.Mutex
+	watching bool
+	closed   bool
+}
+
+// errFanotifyClosed is returned by read when the watcher has been closed
+var errFanotifyClosed = errors.New("fanotify watcher closed")
+
+// fanotifyEvent is a single event as read from the fanotify file descriptor
+type fanotifyEvent struct {
+	mask   uint64
+	handle unix.FileHandle
+	name   string
+}
+
+// NewFanotifyWatcher returns a new fanotify watcher marking the storage root.
+// It requires CAP_SYS_ADMIN for the mark and CAP_DAC_READ_SEARCH for
+// resolving the reported directory handles.
+func NewFanotifyWatcher(tree *Tree, o *options.Options, log *zerolog.Logger) (*FanotifyWatcher, error) {
+	switch o.FanotifyMarkType {
+	case "", "filesystem":
+	case "mount":
+		// the kernel rejects directory entry events on mount marks
+		return nil, fmt.Errorf("fanotify mark type 'mount' does not report create, delete and move events, use 'filesystem'")
+	default:
+		return nil, fmt.Errorf("unknown fanotify mark type '%s'", o.FanotifyMarkType)
+	}
+
+	fd, err := unix.FanotifyInit(unix.FAN_CLASS_NOTIF|unix.FAN_CLOEXEC|unix.FAN_NONBLOCK|unix.FAN_UNLIMITED_QUEUE|unix.FAN_REPORT_DFID_NAME, unix.O_RDONLY|unix.O_LARGEFILE|unix.O_CLOEXEC)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not initialize fanotify")
+	}
+	if err := unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_FILESYSTEM, fanotifyEventMask, unix.AT_FDCWD, o.Root); err != nil {
+		_ = unix.Close(fd)
+		return nil, errors.Wrapf(err, "could not add fanotify mark for '%s'", o.Root)
+	}
+	mountFD, err := unix.Open(o.Root, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, errors.Wrapf(err, "could not open '%s'", o.Root)
+	}
+	stopFD, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		_ = unix.Close(mountFD)
+		_ = unix.Close(fd)
+		return nil, errors.Wrap(err, "could not create eventfd")
+	}
+
+	return &FanotifyWatcher{
+		tree:    tree,
+		options: o,
+		log:     log,
+		fd:      fd,
+		mountFD: mountFD,
+		stopFD:  stopFD,
+	}, nil
+}
+
+// Watch reads events from the fanotify file descriptor and feeds the ones
+// below path into the assimilation
+func (fw *FanotifyWatcher) Watch(path string) {
+	fw.mu.Lock()
+	if fw.closed {
+		fw.mu.Unlock()
+		return
+	}
+	fw.watching = true
+	fw.mu.Unlock()
+	defer func() {
+		fw.mu.Lock()
+		defer fw.mu.Unlock()
+		fw.watching = false
+		if err := fw.release(); err != nil {
+			fw.log.Error().Err(err).Msg("failed to close fanotify watcher")
+		}
+	}()
+
+	root := filepath.Clean(path)
+	for {
+		events, err := fw.read()
+		if err == errFanotifyClosed {
+			return
+		}
+		if err != nil {
+			fw.log.Error().Err(err).Msg("failed to read fanotify events, stopping watcher")
+			return
+		}
+		for _, ev := range events {
+			if ev.mask&unix.FAN_Q_OVERFLOW != 0 {
+				fw.log.Warn().Str("root", root).Msg("fanotify queue overflowed, rescanning storage")
+				go func() {
+					if err := fw.tree.WarmupIDCache(root, true, false); err != nil {
+						fw.log.Error().Err(err).Str("root", root).Msg("failed to rescan storage")
+					}
+				}()
+				continue
+			}
+
+			p, err := fw.resolve(ev)
+			if err != nil {
+				// the parent directory may be gone already, its own delete event takes care of it
+				fw.log.Debug().Err(err).Str("name", ev.name).Msg("could not resolve fanotify event")
+				continue
+			}
+			if p != root && !strings.HasPrefix(p, root+string(filepath.Separator)) {
+				continue
+			}
+			if fw.tree.isIgnored(p) {
+				continue
+			}
+			go fw.handle(p, ev.mask)
+		}
+	}
+}
+
+// Close stops the watcher and releases the fanotify file descriptors. A
+// running Watch releases them when it returns.
+func (fw *FanotifyWatcher) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.closed {
+		return nil
+	}
+	fw.closed = true
+	if fw.watching {
+		buf := make([]byte, 8)
+		binary.NativeEndian.PutUint64(buf, 1)
+		_, err := unix.Write(fw.stopFD, buf)
+		return err
+	}
+	return fw.release()
+}
+
+// release closes the file descriptors of the watcher
+func (fw *FanotifyWatcher) release() error {
+	var firstErr error
+	for _, fd := range []int{fw.stopFD, fw.mountFD, fw.fd} {
+		if err := unix.Close(fd); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// handle dispatches an event to the assimilation. fanotify merges events
+// for the same entry, so the current state of the path decides whether it
+// is treated as removed or as created/updated.
+func (fw *FanotifyWatcher) handle(path string, mask uint64) {
+	isDir := mask&unix.FAN_ONDIR != 0
+	_, statErr := os.Lstat(path)
+	exists := statErr == nil
+
+	var err error
+	switch {
+	case !exists && mask&unix.FAN_MOVED_FROM != 0:
+		err = fw.tree.Scan(path, ActionMoveFrom, isDir)
+	case !exists && mask&unix.FAN_DELETE != 0:
+		err = fw.tree.Scan(path, ActionDelete, isDir)
+	case exists && mask&(unix.FAN_CREATE|unix.FAN_MOVED_TO) != 0:
+		err = fw.tree.Scan(path, ActionCreate, isDir)
+	case exists && mask&unix.FAN_CLOSE_WRITE != 0:
+		err = fw.tree.Scan(path, ActionUpdate, isDir)
+	default:
+		return
+	}
+	if err != nil {
+		fw.log.Error().Err(err).Str("path", path).Msg("error scanning file")
+	}
+}
+
+// read blocks until events are available and returns them. It returns
+// errFanotifyClosed when the watcher is closed.
+func (fw *FanotifyWatcher) read() ([]fanotifyEvent, error) {
+	buf := make([]byte, 64*1024)
+	fds := []unix.PollFd{
+		{Fd: int32(fw.fd), Events: unix.POLLIN},
+		{Fd: int32(fw.stopFD), Events: unix.POLLIN},
+	}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return nil, err
+		}
+		if fds[1].Revents != 0 {
+			return nil, errFanotifyClosed
+		}
+		n, err := unix.Read(fw.fd, buf)
+		switch {
+		case err == unix.EINTR || err == unix.EAGAIN:
+			continue
+		case err != nil:
+			return nil, err
+		}
+		return parseFanotifyEvents(buf[:n])
+	}
+}
+
+// resolve returns the absolute path of the entry an event refers to
+func (fw *FanotifyWatcher) resolve(ev fanotifyEvent) (string, error) {
+	fd, err := unix.OpenByHandleAt(fw.mountFD, ev.handle, unix.O_PATH|unix.O_CLOEXEC)
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(fd)
+
+	dir, err := os.Readlink(filepath.Join("/proc/self/fd", strconv.Itoa(fd)))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ev.name), nil
+}
+
+// parseFanotifyEvents decodes a buffer read from a fanotify file descriptor
+// initialized with FAN_REPORT_DFID_NAME
+func parseFanotifyEvents(buf []byte) ([]fanotifyEvent, error) {
+	events := []fanotifyEvent{}
+	for len(buf) >= fanotifyMetadataSize {
+		eventLen := int(binary.NativeEndian.Uint32(buf[0:4]))
+		version := buf[4]
+		metadataLen := int(binary.NativeEndian.Uint16(buf[6:8]))
+		if version != unix.FANOTIFY_METADATA_VERSION {
+			return nil, fmt.Errorf("unsupported fanotify metadata version %d", version)
+		}
+		if eventLen < fanotifyMetadataSize || eventLen > len(buf) || metadataLen < fanotifyMetadataSize || metadataLen > eventLen {
+			return nil, fmt.Errorf("malformed fanotify event of length %d", eventLen)
+		}
+
+		ev := fanotifyEvent{mask: binary.NativeEndian.Uint64(buf[8:16])}
+		if fd := int32(binary.NativeEndian.Uint32(buf[16:20])); fd >= 0 {
+			_ = unix.Close(int(fd))
+		}
+
+		info := buf[metadataLen:eventLen]
+		for len(info) >= fanotifyInfoHeaderSize {
+			infoType := info[0]
+			infoLen := int(binary.NativeEndian.Uint16(info[2:4]))
+			if infoLen < fanotifyInfoHeaderSize || infoLen > len(info) {
+				return nil, fmt.Errorf("malformed fanotify info record of length %d", infoLen)
+			}
+			if infoType == unix.FAN_EVENT_INFO_TYPE_DFID_NAME {
+				handle, name, err := parseFanotifyDFIDName(info[fanotifyInfoHeaderSize:infoLen])
+				if err != nil {
+					return nil, err
+				}
+				ev.handle, ev.name = handle, name
+			}
+			info = info[infoLen:]
+		}
+
+		events = append(events, ev)
+		buf = buf[eventLen:]
+	}
+	return events, nil
+}
+
+// parseFanotifyDFIDName decodes the fsid, file handle and name of a
+// FAN_EVENT_INFO_TYPE_DFID_NAME record
+func parseFanotifyDFIDName(record []byte) (unix.FileHandle, string, error) {
+	if len(record) < fanotifyFsidSize+fanotifyFileHandleHeaderSize {
+		return unix.FileHandle{}, "", fmt.Errorf("fanotify fid record too short")
+	}
+	record = record[fanotifyFsidSize:]
+	handleBytes := int(binary.NativeEndian.Uint32(record[0:4]))
+	handleType := int32(binary.NativeEndian.Uint32(record[4:8]))
+	record = record[fanotifyFileHandleHeaderSize:]
+	if handleBytes > len(record) {
+		return unix.FileHandle{}, "", fmt.Errorf("fanotify file handle exceeds record")
+	}
+	handle := unix.NewFileHandle(handleType, record[:handleBytes])
+	name := record[handleBytes:]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return handle, string(name), nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+// Copyright 2025 OpenCloud GmbH <mail@opencloud.eu>
+// SPDX-License-Identifier: Apache-2.0
+
+package tree
+
+import (
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/options"
+	"github.com/rs/zerolog"
+)
+
+// NewFanotifyWatcher returns a new fanotify watcher
+func NewFanotifyWatcher(_ *Tree, _ *options.Options, _ *zerolog.Logger) (*NullWatcher, error) {
+	return nil, errtypes.NotSupported("fanotify watcher is not supported on this platform")
+}
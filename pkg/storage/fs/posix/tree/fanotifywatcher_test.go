@@ -0,0 +1,154 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tree
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/options"
+	decomposedoptions "github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/options"
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
+)
+
+// fanotifyRecord builds a fanotify event carrying a DFID_NAME record
+func fanotifyRecord(mask uint64, handle []byte, name string) []byte {
+	info := make([]byte, fanotifyInfoHeaderSize+fanotifyFsidSize+fanotifyFileHandleHeaderSize)
+	info[0] = unix.FAN_EVENT_INFO_TYPE_DFID_NAME
+	binary.NativeEndian.PutUint32(info[fanotifyInfoHeaderSize+fanotifyFsidSize:], uint32(len(handle)))
+	binary.NativeEndian.PutUint32(info[fanotifyInfoHeaderSize+fanotifyFsidSize+4:], 1)
+	info = append(info, handle...)
+	info = append(info, name...)
+	info = append(info, 0)
+	for len(info)%4 != 0 {
+		info = append(info, 0)
+	}
+	binary.NativeEndian.PutUint16(info[2:4], uint16(len(info)))
+
+	meta := make([]byte, fanotifyMetadataSize)
+	binary.NativeEndian.PutUint32(meta[0:4], uint32(fanotifyMetadataSize+len(info)))
+	meta[4] = unix.FANOTIFY_METADATA_VERSION
+	binary.NativeEndian.PutUint16(meta[6:8], fanotifyMetadataSize)
+	binary.NativeEndian.PutUint64(meta[8:16], mask)
+	binary.NativeEndian.PutUint32(meta[16:20], uint32(0xffffffff)) // FAN_NOFD
+	return append(meta, info...)
+}
+
+var _ = Describe("FanotifyWatcher", func() {
+	Describe("parseFanotifyEvents", func() {
+		It("decodes the mask, directory handle and name of each event", func() {
+			buf := append(fanotifyRecord(unix.FAN_CREATE|unix.FAN_ONDIR, []byte{1, 2, 3, 4, 5, 6, 7, 8}, "dir"),
+				fanotifyRecord(unix.FAN_CLOSE_WRITE, []byte{9, 10, 11, 12}, "file.txt")...)
+
+			events, err := parseFanotifyEvents(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].mask).To(Equal(uint64(unix.FAN_CREATE | unix.FAN_ONDIR)))
+			Expect(events[0].name).To(Equal("dir"))
+			Expect(events[0].handle.Bytes()).To(Equal([]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+			Expect(events[1].mask).To(Equal(uint64(unix.FAN_CLOSE_WRITE)))
+			Expect(events[1].name).To(Equal("file.txt"))
+			Expect(events[1].handle.Type()).To(Equal(int32(1)))
+		})
+
+		It("rejects truncated events", func() {
+			buf := fanotifyRecord(unix.FAN_DELETE, []byte{1, 2, 3, 4}, "gone")
+			_, err := parseFanotifyEvents(buf[:len(buf)-4])
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("watching a storage root", func() {
+		var (
+			root string
+			fw   *FanotifyWatcher
+		)
+
+		BeforeEach(func() {
+			var err error
+			root, err = os.MkdirTemp("", "fanotify-*")
+			Expect(err).ToNot(HaveOccurred())
+			root, err = filepath.EvalSymlinks(root)
+			Expect(err).ToNot(HaveOccurred())
+
+			log := zerolog.Nop()
+			fw, err = NewFanotifyWatcher(nil, &options.Options{Options: decomposedoptions.Options{Root: root}}, &log)
+			if err != nil {
+				Skip("fanotify is not available: " + err.Error())
+			}
+		})
+
+		AfterEach(func() {
+			if fw != nil {
+				Expect(fw.Close()).To(Succeed())
+			}
+			os.RemoveAll(root)
+		})
+
+		It("reports created files with their full path", func() {
+			Expect(os.Mkdir(filepath.Join(root, "sub"), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "sub", "file.txt"), []byte("content"), 0600)).To(Succeed())
+
+			seen := map[string]uint64{}
+			Eventually(func() map[string]uint64 {
+				events, err := fw.read()
+				Expect(err).ToNot(HaveOccurred())
+				for _, ev := range events {
+					p, err := fw.resolve(ev)
+					if err == nil {
+						seen[p] |= ev.mask
+					}
+				}
+				return seen
+			}, 5*time.Second).Should(And(
+				HaveKeyWithValue(filepath.Join(root, "sub"), BeNumerically(">", uint64(0))),
+				HaveKey(filepath.Join(root, "sub", "file.txt")),
+			))
+			Expect(seen[filepath.Join(root, "sub")] & unix.FAN_ONDIR).ToNot(BeZero())
+			Expect(seen[filepath.Join(root, "sub", "file.txt")] & unix.FAN_CLOSE_WRITE).ToNot(BeZero())
+		})
+
+		It("stops watching when closed", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				fw.Watch(root)
+			}()
+			Eventually(func() bool {
+				fw.mu.Lock()
+				defer fw.mu.Unlock()
+				return fw.watching
+			}, 5*time.Second).Should(BeTrue())
+
+			Expect(fw.Close()).To(Succeed())
+			Eventually(done, 5*time.Second).Should(BeClosed())
+		})
+	})
+
+	It("rejects mount marks", func() {
+		log := zerolog.Nop()
+		_, err := NewFanotifyWatcher(nil, &options.Options{FanotifyMarkType: "mount"}, &log)
+		Expect(err).To(MatchError(ContainSubstring("use 'filesystem'")))
+	})
+})
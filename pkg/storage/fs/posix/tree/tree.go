@@ -128,6 +128,12 @@ func New(lu node.PathLookup, bs node.Blobstore, um usermapper.Mapper, trashbin *
 			if err != nil {
 				return nil, err
 			}
+		case "fanotify":
+			t.watcher, err = NewFanotifyWatcher(t, o, log)
+			if err != nil {
+				return nil, err
+			}
+			watchPath = o.Root
 		default:
 			t.watcher, err = NewInotifyWatcher(t, o, log)
 			if err != nil {
@@ -148,6 +154,14 @@ func New(lu node.PathLookup, bs node.Blobstore, um usermapper.Mapper, trashbin *
 	return t, nil
 }
 
+// Close stops the filesystem watcher of the tree
+func (t *Tree) Close() error {
+	if c, ok := t.watcher.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 func (t *Tree) PublishEvent(ev interface{}) {
 	if t.es == nil {
 		return
@@ -601,7 +615,7 @@ func (t *Tree) InitNewNode(ctx context.Context, n *node.Node, fsize uint64) (met
 	}
 	h.Close()
 
-	if _, err := node.CheckQuota(ctx, n.SpaceRoot, false, 0, fsize); err != nil {
+	if _, err := node.CheckQuota(ctx, n.SpaceRoot, false, 0, fsize, node.UsageAttrs(t.options.CountTrashInQuota, t.options.CountRevisionsInQuota)...); err != nil {
 		return unlock, err
 	}
 
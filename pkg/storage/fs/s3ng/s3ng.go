@@ -26,11 +26,17 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/fs/s3ng/blobstore"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/decomposedfs"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/decomposedfs/options"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/rs/zerolog"
 )
 
 func init() {
 	registry.Register("s3ng", New)
+	cfg.Register("storage.fs", "s3ng", struct {
+		Options `mapstructure:",squash"`
+		FS      options.Options `mapstructure:",squash"`
+	}{})
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
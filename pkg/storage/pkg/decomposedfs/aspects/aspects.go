@@ -24,15 +24,19 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/permissions"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/trashbin"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/usermapper"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
 )
 
 // Aspects holds dependencies for handling aspects of the decomposedfs
 type Aspects struct {
-	Lookup            node.PathLookup
-	Tree              node.Tree
-	Trashbin          trashbin.Trashbin
-	Permissions       permissions.Permissions
-	EventStream       events.Stream
-	DisableVersioning bool
-	UserMapper        usermapper.Mapper
+	Lookup                node.PathLookup
+	Tree                  node.Tree
+	Trashbin              trashbin.Trashbin
+	Permissions           permissions.Permissions
+	EventStream           events.Stream
+	DisableVersioning     bool
+	CountTrashInQuota     bool
+	CountRevisionsInQuota bool
+	UserMapper            usermapper.Mapper
+	RevisionRetention     retention.RevisionPolicy
 }
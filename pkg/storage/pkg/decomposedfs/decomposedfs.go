@@ -42,6 +42,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/events"
@@ -52,6 +53,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/aspects"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/lookup"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/options"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/permissions"
@@ -63,6 +65,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/usermapper"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/chunking"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/filelocks"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/templates"
 	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
 	"github.com/opencloud-eu/reva/v2/pkg/store"
@@ -130,6 +133,9 @@ type Decomposedfs struct {
 	groupSpaceIndex *spaceidindex.Index
 	spaceTypeIndex  *spaceidindex.Index
 
+	revisionRetention    retention.RevisionPolicy
+	stopRevisionsPruning context.CancelFunc
+
 	log *zerolog.Logger
 }
 
@@ -160,7 +166,7 @@ func NewDefault(m map[string]interface{}, bs node.Blobstore, es events.Stream, l
 	}
 	p := permissions.NewPermissions(node.NewPermissions(lu), permissionsSelector)
 
-	tp := tree.New(lu, bs, o, p, store.Create(
+	idCache, err := store.New(
 		store.Store(o.IDCache.Store),
 		store.TTL(o.IDCache.TTL),
 		store.Size(o.IDCache.Size),
@@ -169,15 +175,22 @@ func NewDefault(m map[string]interface{}, bs node.Blobstore, es events.Stream, l
 		microstore.Table(o.IDCache.Table),
 		store.DisablePersistence(o.IDCache.DisablePersistence),
 		store.Authentication(o.IDCache.AuthUsername, o.IDCache.AuthPassword),
-	), log)
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := tree.New(lu, bs, o, p, idCache, log)
 
 	aspects := aspects.Aspects{
-		Lookup:            lu,
-		Tree:              tp,
-		Permissions:       p,
-		EventStream:       es,
-		DisableVersioning: o.DisableVersioning,
-		Trashbin:          &DecomposedfsTrashbin{},
+		Lookup:                lu,
+		Tree:                  tp,
+		Permissions:           p,
+		EventStream:           es,
+		DisableVersioning:     o.DisableVersioning,
+		CountTrashInQuota:     o.CountTrashInQuota,
+		CountRevisionsInQuota: o.CountRevisionsInQuota,
+		Trashbin:              &DecomposedfsTrashbin{},
+		RevisionRetention:     o.RevisionRetention.RevisionPolicy,
 	}
 
 	return New(o, aspects, log)
@@ -241,6 +254,8 @@ func New(o *options.Options, aspects aspects.Aspects, log *zerolog.Logger) (stor
 		groupSpaceIndex: groupSpaceIndex,
 		spaceTypeIndex:  spaceTypeIndex,
 		log:             log,
+
+		revisionRetention: aspects.RevisionRetention,
 	}
 	fs.sessionStore = upload.NewSessionStore(fs, aspects, o.Root, o.AsyncFileUploads, o.Tokens, log)
 	if err = fs.trashbin.Setup(fs); err != nil {
@@ -267,6 +282,20 @@ func New(o *options.Options, aspects aspects.Aspects, log *zerolog.Logger) (stor
 		}
 	}
 
+	pruneRevisions := fs.revisionRetention.Enabled() && o.RevisionRetention.Interval > 0
+	if pruneRevisions || o.CountRevisionsInQuota {
+		var ctx context.Context
+		ctx, fs.stopRevisionsPruning = context.WithCancel(context.Background())
+		go func() {
+			if o.CountRevisionsInQuota {
+				fs.initRevisionSizes(ctx)
+			}
+			if pruneRevisions {
+				fs.pruneRevisionsPeriodically(ctx, o.RevisionRetention.Interval)
+			}
+		}()
+	}
+
 	return fs, nil
 }
 
@@ -326,6 +355,9 @@ func (fs *Decomposedfs) Postprocessing(ch <-chan events.Event) {
 					unmarkPostprocessing = false
 				} else {
 					metrics.UploadSessionsFinalized.Inc()
+					if err := session.PublishFileDropped(ctx, n); err != nil {
+						sublog.Error().Err(err).Msg("Failed to publish FileDropped event")
+					}
 				}
 			case events.PPOutcomeDelete:
 				failed = true
@@ -560,6 +592,9 @@ func (fs *Decomposedfs) Postprocessing(ch <-chan events.Event) {
 
 // Shutdown shuts down the storage
 func (fs *Decomposedfs) Shutdown(ctx context.Context) error {
+	if fs.stopRevisionsPruning != nil {
+		fs.stopRevisionsPruning()
+	}
 	return nil
 }
 
@@ -605,7 +640,13 @@ func (fs *Decomposedfs) GetQuota(ctx context.Context, ref *provider.Reference) (
 		quotaStr = string(ri.Opaque.Map["quota"].Value)
 	}
 
-	return fs.calculateTotalUsedRemaining(quotaStr, ri.Size)
+	inUse = ri.Size
+	for _, attr := range node.UsageAttrs(fs.o.CountTrashInQuota, fs.o.CountRevisionsInQuota) {
+		size, _ := n.SpaceRoot.XattrUint64(ctx, attr)
+		inUse += size
+	}
+
+	return fs.calculateTotalUsedRemaining(quotaStr, inUse)
 }
 
 func (fs *Decomposedfs) calculateTotalUsedRemaining(quotaStr string, inUse uint64) (uint64, uint64, uint64, error) {
@@ -1056,7 +1097,17 @@ func (fs *Decomposedfs) Delete(ctx context.Context, ref *provider.Reference) (er
 		return err
 	}
 
-	return fs.tp.Delete(ctx, node)
+	// remember the size before the node is moved to the trash
+	size := uint64(node.Blobsize)
+	if node.IsDir(ctx) {
+		size, _ = node.GetTreeSize(ctx)
+	}
+
+	if err := fs.tp.Delete(ctx, node); err != nil {
+		return err
+	}
+	fs.addTrashSize(ctx, node.SpaceID, int64(size))
+	return nil
 }
 
 // Download returns a reader to the specified resource
@@ -1317,7 +1368,11 @@ func (fs *Decomposedfs) RestoreRecycleItem(ctx context.Context, space *provider.
 		sizeDiff = restoredNode.Blobsize
 	}
 
-	return fs.tp.Propagate(ctx, restoredNode, sizeDiff)
+	if err := fs.tp.Propagate(ctx, restoredNode, sizeDiff); err != nil {
+		return err
+	}
+	fs.addTrashSize(ctx, spaceID, -sizeDiff)
+	return nil
 }
 
 func (fs *Decomposedfs) PurgeRecycleItem(ctx context.Context, space *provider.Reference, key, relativePath string) error {
@@ -1354,7 +1409,19 @@ func (fs *Decomposedfs) PurgeRecycleItem(ctx context.Context, space *provider.Re
 		return errtypes.NotFound(key)
 	}
 
-	return fs.trashbin.PurgeRecycleItem(ctx, spaceID, key, relativePath)
+	// remember the size before the item is gone
+	size, sizeErr := fs.recycleItemSize(ctx, spaceID, key, relativePath)
+
+	if err := fs.trashbin.PurgeRecycleItem(ctx, spaceID, key, relativePath); err != nil {
+		return err
+	}
+	if sizeErr != nil {
+		appctx.GetLogger(ctx).Error().Err(sizeErr).Str("spaceid", spaceID).Str("key", key).Msg("could not determine the size of the purged item, recalculating trash size")
+		fs.resetTrashSize(ctx, spaceID)
+		return nil
+	}
+	fs.addTrashSize(ctx, spaceID, -int64(size))
+	return nil
 }
 
 func (fs *Decomposedfs) EmptyRecycle(ctx context.Context, space *provider.Reference) error {
@@ -1385,7 +1452,101 @@ func (fs *Decomposedfs) EmptyRecycle(ctx context.Context, space *provider.Refere
 		}
 		return errtypes.NotFound(spaceID)
 	}
-	return fs.trashbin.EmptyRecycle(ctx, spaceID)
+	if err := fs.trashbin.EmptyRecycle(ctx, spaceID); err != nil {
+		return err
+	}
+	// always remove the trash size so that disabling count_trash_in_quota takes effect
+	if err := trashBaseNode.RemoveXattr(ctx, prefixes.TrashsizeAttr, true); err != nil && !metadata.IsAttrUnset(err) {
+		appctx.GetLogger(ctx).Error().Err(err).Str("spaceid", spaceID).Msg("could not reset trash size")
+	}
+	return nil
+}
+
+// addTrashSize adds delta to the size of the trash bin stored on the space root.
+// The size is calculated from the trash bin if it has not been stored yet.
+// It is a noop unless trashed items count against the quota.
+func (fs *Decomposedfs) addTrashSize(ctx context.Context, spaceID string, delta int64) {
+	if !fs.o.CountTrashInQuota || delta == 0 {
+		return
+	}
+	fs.updateTrashSize(ctx, spaceID, func(size uint64, ok bool) (uint64, bool) {
+		switch {
+		case !ok:
+			// the change is already part of the calculated size
+			return 0, false
+		case delta < 0 && uint64(-delta) > size:
+			return 0, true
+		default:
+			return uint64(int64(size) + delta), true
+		}
+	})
+}
+
+// resetTrashSize recalculates the size of the trash bin of a space from its items.
+// It is a noop unless trashed items count against the quota.
+func (fs *Decomposedfs) resetTrashSize(ctx context.Context, spaceID string) {
+	if !fs.o.CountTrashInQuota {
+		return
+	}
+	fs.updateTrashSize(ctx, spaceID, func(uint64, bool) (uint64, bool) { return 0, false })
+}
+
+// updateTrashSize updates the trash size on the space root while holding its lock. f is called
+// with the stored size and returns the new size. If the size has not been stored yet or f does
+// not return a size, the size is calculated by listing the trash bin.
+func (fs *Decomposedfs) updateTrashSize(ctx context.Context, spaceID string, f func(size uint64, ok bool) (uint64, bool)) {
+	log := appctx.GetLogger(ctx).With().Str("spaceid", spaceID).Logger()
+	spaceRoot, err := fs.lu.NodeFromSpaceID(ctx, spaceID)
+	if err != nil {
+		log.Error().Err(err).Msg("could not read space root to store trash size")
+		return
+	}
+
+	err = spaceRoot.UpdateSizeAttr(ctx, prefixes.TrashsizeAttr, func(stored uint64, ok bool) (uint64, error) {
+		if size, ok := f(stored, ok); ok {
+			return size, nil
+		}
+		items, err := fs.trashbin.ListRecycle(ctx, spaceID, "", "")
+		if err != nil {
+			return 0, errors.Wrap(err, "could not list trash bin to calculate its size")
+		}
+		return retention.Size(items), nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("could not store trash size")
+	}
+}
+
+// recycleItemSize returns the size of an item in the trash bin
+func (fs *Decomposedfs) recycleItemSize(ctx context.Context, spaceID, key, relativePath string) (uint64, error) {
+	if !fs.o.CountTrashInQuota {
+		return 0, nil
+	}
+
+	relativePath = strings.Trim(relativePath, "/")
+	if relativePath == "" || relativePath == "." {
+		items, err := fs.trashbin.ListRecycle(ctx, spaceID, key, "")
+		if err != nil {
+			return 0, err
+		}
+		if len(items) != 1 {
+			return 0, errtypes.NotFound(key)
+		}
+		return items[0].GetSize(), nil
+	}
+
+	// list the parent to find the child
+	parent, name := filepath.Split(relativePath)
+	items, err := fs.trashbin.ListRecycle(ctx, spaceID, key, "/"+parent)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range items {
+		if filepath.Base(item.GetKey()) == name {
+			return item.GetSize(), nil
+		}
+	}
+	return 0, errtypes.NotFound(filepath.Join(key, relativePath))
 }
 
 func (fs *Decomposedfs) getNodePath(ctx context.Context, n *node.Node, perms *provider.ResourcePermissions) (string, error) {
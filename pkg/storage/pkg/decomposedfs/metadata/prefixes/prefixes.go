@@ -86,6 +86,14 @@ const (
 	// stored as uint64, little endian
 	TreesizeAttr string = OcPrefix + "treesize"
 
+	// the size of all items in the trash bin of a storage space,
+	// only maintained on the space root when count_trash_in_quota is true
+	TrashsizeAttr string = OcPrefix + "trashsize"
+
+	// the size of all revisions in a storage space,
+	// only maintained on the space root when count_revisions_in_quota is true
+	RevisionsizeAttr string = OcPrefix + "revisionsize"
+
 	// the quota for the storage space / tree, regardless who accesses it
 	QuotaAttr string = OcPrefix + "quota"
 
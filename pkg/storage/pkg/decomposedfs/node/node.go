@@ -1034,6 +1034,86 @@ func (n *Node) SetTreeSize(ctx context.Context, ts uint64) (err error) {
 	return n.SetXattrString(ctx, prefixes.TreesizeAttr, strconv.FormatUint(ts, 10))
 }
 
+// GetTrashSize reads the size of the trash bin from the extended attributes of a space root
+func (n *Node) GetTrashSize(ctx context.Context) (trashsize uint64, err error) {
+	return n.XattrUint64(ctx, prefixes.TrashsizeAttr)
+}
+
+// SetTrashSize writes the size of the trash bin to the extended attributes of a space root
+func (n *Node) SetTrashSize(ctx context.Context, ts uint64) (err error) {
+	return n.SetXattrString(ctx, prefixes.TrashsizeAttr, strconv.FormatUint(ts, 10))
+}
+
+// UsageAttrs returns the space root attributes holding sizes that count against the quota in addition to the tree size
+func UsageAttrs(countTrash, countRevisions bool) []string {
+	attrs := []string{}
+	if countTrash {
+		attrs = append(attrs, prefixes.TrashsizeAttr)
+	}
+	if countRevisions {
+		attrs = append(attrs, prefixes.RevisionsizeAttr)
+	}
+	return attrs
+}
+
+// UpdateSizeAttr updates a size attribute of a space root while holding its lock. f is called with
+// the stored size and whether it has been stored at all and returns the new size.
+func (n *Node) UpdateSizeAttr(ctx context.Context, attr string, f func(size uint64, ok bool) (uint64, error)) error {
+	unlock, err := n.lu.MetadataBackend().Lock(n)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unlock() }()
+
+	// read the stored size from the backend, the node might hold stale attributes
+	var stored uint64
+	raw, err := n.lu.MetadataBackend().Get(ctx, n, attr)
+	if err == nil {
+		stored, err = strconv.ParseUint(string(raw), 10, 64)
+	}
+	size, err := f(stored, err == nil)
+	if err != nil {
+		return err
+	}
+
+	attrs := Attributes{}
+	attrs.SetString(attr, strconv.FormatUint(size, 10))
+	return n.lu.MetadataBackend().SetMultiple(ctx, n, attrs, false)
+}
+
+// AddRevisionSize adds delta to the size of all revisions stored on a space root.
+// It is a noop as long as the size has not been initialized.
+func (n *Node) AddRevisionSize(ctx context.Context, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	err := n.UpdateSizeAttr(ctx, prefixes.RevisionsizeAttr, func(size uint64, ok bool) (uint64, error) {
+		switch {
+		case !ok:
+			return 0, errRevisionSizeUnset
+		case delta < 0 && uint64(-delta) > size:
+			return 0, nil
+		default:
+			return uint64(int64(size) + delta), nil
+		}
+	})
+	if err == errRevisionSizeUnset {
+		return nil
+	}
+	return err
+}
+
+var errRevisionSizeUnset = errors.New("revision size has not been initialized")
+
+// RevisionsSize returns the total size of the given revisions
+func RevisionsSize(revisions []*provider.FileVersion) uint64 {
+	var size uint64
+	for _, rev := range revisions {
+		size += rev.GetSize()
+	}
+	return size
+}
+
 // GetBlobSize reads the blobsize from the extended attributes
 func (n *Node) GetBlobSize(ctx context.Context) (treesize uint64, err error) {
 	s, err := n.XattrInt64(ctx, prefixes.BlobsizeAttr)
@@ -1365,8 +1445,15 @@ func (n *Node) ScanData(ctx context.Context) (scanned bool, virus string, scanti
 // when creating a new file version. In such a case the function will
 // reduce the used bytes by the old file size and then add the new size.
 // If overwrite is false oldSize will be ignored.
-var CheckQuota = func(ctx context.Context, spaceRoot *Node, overwrite bool, oldSize, newSize uint64) (quotaSufficient bool, err error) {
+// usageAttrs name the space root attributes holding additional sizes that count against the quota, see UsageAttrs.
+var CheckQuota = func(ctx context.Context, spaceRoot *Node, overwrite bool, oldSize, newSize uint64, usageAttrs ...string) (quotaSufficient bool, err error) {
 	used, _ := spaceRoot.GetTreeSize(ctx)
+	// stale sizes are ignored when the options have been switched off
+	for _, attr := range usageAttrs {
+		if size, err := spaceRoot.XattrUint64(ctx, attr); err == nil {
+			used += size
+		}
+	}
 	if !enoughDiskSpace(spaceRoot.InternalPath(), newSize) {
 		return false, errtypes.InsufficientStorage("disk full")
 	}
@@ -0,0 +1,126 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package node
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
+)
+
+// Revisions returns the revisions of the node without checking any permissions
+func (n *Node) Revisions(ctx context.Context) ([]*provider.FileVersion, error) {
+	items, err := filepath.Glob(n.lu.VersionPath(n.SpaceID, n.ID, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*provider.FileVersion, 0, len(items))
+	for _, item := range items {
+		if n.lu.MetadataBackend().IsMetaFile(item) || strings.HasSuffix(item, ".mlock") {
+			continue
+		}
+		fi, err := os.Stat(item)
+		if err != nil {
+			continue
+		}
+		parts := strings.SplitN(fi.Name(), RevisionIDDelimiter, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		rev := &provider.FileVersion{
+			Key:   n.ID + RevisionIDDelimiter + parts[1],
+			Mtime: uint64(fi.ModTime().Unix()),
+		}
+		if _, blobSize, err := n.lu.ReadBlobIDAndSizeAttr(ctx, NewBaseNode(n.SpaceID, rev.Key, n.lu), nil); err == nil {
+			rev.Size = uint64(blobSize)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// PurgeRevision removes the given revision of the node including its metadata and blob
+func (n *Node) PurgeRevision(ctx context.Context, tp Tree, revisionKey string) error {
+	revisionNode := NewBaseNode(n.SpaceID, revisionKey, n.lu)
+	blobID, blobSize, err := n.lu.ReadBlobIDAndSizeAttr(ctx, revisionNode, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(revisionNode.InternalPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(n.lu.MetadataBackend().MetadataPath(revisionNode)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_ = os.Remove(n.lu.MetadataBackend().LockfilePath(revisionNode))
+	if err := n.lu.MetadataBackend().Purge(ctx, revisionNode); err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("revisionKey", revisionKey).Msg("could not purge revision from cache, continuing")
+	}
+
+	if blobID == "" {
+		return nil
+	}
+	return tp.DeleteBlob(&Node{
+		BaseNode: BaseNode{SpaceID: n.SpaceID, ID: revisionKey},
+		BlobID:   blobID,
+		Blobsize: blobSize,
+	})
+}
+
+// PruneRevisions removes all revisions of the node that are expired according to the given policy.
+// Revisions listed in keep are never removed. It returns the removed revisions.
+func (n *Node) PruneRevisions(ctx context.Context, tp Tree, policy retention.RevisionPolicy, now time.Time, keep ...string) ([]*provider.FileVersion, error) {
+	if !policy.Enabled() {
+		return nil, nil
+	}
+	revisions, err := n.Revisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []*provider.FileVersion{}
+	for _, rev := range policy.Expired(revisions, now) {
+		if contains(keep, rev.GetKey()) {
+			continue
+		}
+		if err := n.PurgeRevision(ctx, tp, rev.GetKey()); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("spaceid", n.SpaceID).Str("nodeid", n.ID).Str("revisionKey", rev.GetKey()).Msg("could not purge expired revision")
+			continue
+		}
+		pruned = append(pruned, rev)
+	}
+	return pruned, nil
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
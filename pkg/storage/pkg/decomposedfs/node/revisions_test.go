@@ -0,0 +1,170 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package node_test
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	helpers "github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/testhelpers"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Revisions", func() {
+	var (
+		env  *helpers.DecomposedTestEnv
+		n    *node.Node
+		now  time.Time
+		keys []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		env, err = helpers.NewTestEnv(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		n, err = env.CreateTestFile("file", "blobid", env.SpaceRootRes.OpaqueId, env.SpaceRootRes.SpaceId, 10)
+		Expect(err).ToNot(HaveOccurred())
+
+		now = time.Now()
+		keys = []string{}
+		for i := 1; i <= 3; i++ {
+			mtime := now.Add(-time.Duration(i) * time.Hour).UTC()
+			timestamp := mtime.Format(time.RFC3339Nano)
+			revisionNode := node.NewBaseNode(n.SpaceID, n.ID+node.RevisionIDDelimiter+timestamp, env.Lookup)
+			Expect(os.WriteFile(revisionNode.InternalPath(), nil, 0600)).To(Succeed())
+			Expect(env.Lookup.MetadataBackend().SetMultiple(env.Ctx, revisionNode, map[string][]byte{
+				prefixes.BlobIDAttr:   []byte("blob" + strconv.Itoa(i)),
+				prefixes.BlobsizeAttr: []byte(strconv.Itoa(i)),
+			}, true)).To(Succeed())
+			Expect(os.Chtimes(revisionNode.InternalPath(), mtime, mtime)).To(Succeed())
+			keys = append(keys, revisionNode.ID)
+		}
+	})
+
+	AfterEach(func() {
+		if env != nil {
+			env.Cleanup()
+		}
+	})
+
+	Describe("Revisions", func() {
+		It("lists all revisions with their size", func() {
+			revisions, err := n.Revisions(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(3))
+			for _, rev := range revisions {
+				Expect(keys).To(ContainElement(rev.Key))
+				Expect(rev.Size).To(BeNumerically(">", 0))
+			}
+		})
+	})
+
+	Describe("PruneRevisions", func() {
+		It("does nothing without a policy", func() {
+			pruned, err := n.PruneRevisions(env.Ctx, env.Tree, retention.RevisionPolicy{}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pruned).To(BeEmpty())
+		})
+
+		It("purges expired revisions and their blobs", func() {
+			env.Blobstore.On("Delete", mock.Anything).Return(nil).Times(2)
+
+			pruned, err := n.PruneRevisions(env.Ctx, env.Tree, retention.RevisionPolicy{MaxCount: 1}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pruned).To(HaveLen(2))
+
+			revisions, err := n.Revisions(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revisions).To(HaveLen(1))
+			Expect(revisions[0].Key).To(Equal(keys[0]))
+			env.Blobstore.AssertNumberOfCalls(GinkgoT(), "Delete", 2)
+		})
+
+		It("never purges the revisions to keep", func() {
+			env.Blobstore.On("Delete", mock.Anything).Return(nil)
+
+			pruned, err := n.PruneRevisions(env.Ctx, env.Tree, retention.RevisionPolicy{MaxCount: 1}, now, keys[2])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pruned).To(HaveLen(1))
+			Expect(pruned[0].Key).To(Equal(keys[1]))
+
+			_, err = os.Stat(env.Lookup.VersionPath(n.SpaceID, n.ID, keys[2][len(n.ID+node.RevisionIDDelimiter):]))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reports the size of the pruned revisions", func() {
+			env.Blobstore.On("Delete", mock.Anything).Return(nil)
+
+			pruned, err := n.PruneRevisions(env.Ctx, env.Tree, retention.RevisionPolicy{MaxCount: 1}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(node.RevisionsSize(pruned)).To(Equal(uint64(2 + 3)))
+		})
+	})
+
+	Describe("AddRevisionSize", func() {
+		var spaceRoot *node.Node
+
+		BeforeEach(func() {
+			var err error
+			spaceRoot, err = env.Lookup.NodeFromSpaceID(env.Ctx, env.SpaceRootRes.SpaceId)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does nothing until the revision size has been initialized", func() {
+			Expect(spaceRoot.AddRevisionSize(env.Ctx, 10)).To(Succeed())
+			_, err := env.Lookup.MetadataBackend().Get(env.Ctx, spaceRoot, prefixes.RevisionsizeAttr)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("adds to the revision size and never drops below zero", func() {
+			Expect(spaceRoot.SetXattrString(env.Ctx, prefixes.RevisionsizeAttr, "6")).To(Succeed())
+
+			Expect(spaceRoot.AddRevisionSize(env.Ctx, 4)).To(Succeed())
+			size, err := env.Lookup.MetadataBackend().Get(env.Ctx, spaceRoot, prefixes.RevisionsizeAttr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(size)).To(Equal("10"))
+
+			Expect(spaceRoot.AddRevisionSize(env.Ctx, -20)).To(Succeed())
+			size, err = env.Lookup.MetadataBackend().Get(env.Ctx, spaceRoot, prefixes.RevisionsizeAttr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(size)).To(Equal("0"))
+		})
+
+		It("counts against the quota when requested", func() {
+			treeSize, err := spaceRoot.GetTreeSize(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spaceRoot.SetXattrString(env.Ctx, prefixes.QuotaAttr, strconv.FormatUint(treeSize+100, 10))).To(Succeed())
+			Expect(spaceRoot.SetXattrString(env.Ctx, prefixes.RevisionsizeAttr, "95")).To(Succeed())
+
+			ok, err := node.CheckQuota(env.Ctx, spaceRoot, false, 0, 10, node.UsageAttrs(true, false)...)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			_, err = node.CheckQuota(env.Ctx, spaceRoot, false, 0, 10, node.UsageAttrs(false, true)...)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
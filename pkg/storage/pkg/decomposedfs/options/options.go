@@ -27,6 +27,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/sharedconf"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
 	"github.com/pkg/errors"
 )
 
@@ -91,8 +92,20 @@ type Options struct {
 
 	MaxQuota uint64 `mapstructure:"max_quota"`
 
+	// count the items in the trash bin of a space against its quota
+	CountTrashInQuota bool `mapstructure:"count_trash_in_quota"`
+
+	// count the revisions of the files in a space against its quota
+	CountRevisionsInQuota bool `mapstructure:"count_revisions_in_quota"`
+
+	// the id of the space holding the templates project spaces can be created from
+	SpaceTemplatesSpaceID string `mapstructure:"space_templates_space_id"`
+
 	DisableVersioning bool `mapstructure:"disable_versioning"`
 
+	// expire and thin out old revisions
+	RevisionRetention RevisionRetentionOptions `mapstructure:"revision_retention"`
+
 	MountID string `mapstructure:"mount_id"`
 }
 
@@ -101,6 +114,18 @@ type AsyncPropagatorOptions struct {
 	PropagationDelay time.Duration `mapstructure:"propagation_delay"`
 }
 
+// RevisionRetentionOptions holds the configuration for pruning revisions
+type RevisionRetentionOptions struct {
+	retention.RevisionPolicy `mapstructure:",squash"`
+
+	// Interval configures how often all spaces are scanned for expired revisions. 0 disables the background job,
+	// revisions are then only pruned when a new revision of a file is created.
+	Interval time.Duration `mapstructure:"interval"`
+
+	// SpaceTypes lists the types of the spaces scanned by the background job. Defaults to personal and project spaces.
+	SpaceTypes []string `mapstructure:"space_types"`
+}
+
 // EventOptions are the configurable options for events
 type EventOptions struct {
 	NumConsumers int `mapstructure:"numconsumers"`
@@ -168,6 +193,10 @@ func New(m map[string]interface{}) (*Options, error) {
 		o.AsyncPropagatorOptions.PropagationDelay = 5 * time.Second
 	}
 
+	if len(o.RevisionRetention.SpaceTypes) == 0 {
+		o.RevisionRetention.SpaceTypes = []string{"personal", "project"}
+	}
+
 	if o.UploadDirectory == "" {
 		o.UploadDirectory = filepath.Join(o.Root, "uploads")
 	}
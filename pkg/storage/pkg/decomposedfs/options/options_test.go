@@ -44,6 +44,19 @@ var _ = Describe("Options", func() {
 
 		It("sets defaults", func() {
 			Expect(o.MetadataBackend).ToNot(BeEmpty())
+			Expect(o.RevisionRetention.SpaceTypes).To(ConsistOf("personal", "project"))
+		})
+
+		Context("with revision retention space types", func() {
+			BeforeEach(func() {
+				config["revision_retention"] = map[string]interface{}{
+					"space_types": []string{"project"},
+				}
+			})
+
+			It("only scans the configured space types", func() {
+				Expect(o.RevisionRetention.SpaceTypes).To(ConsistOf("project"))
+			})
 		})
 
 		Context("with unclean root path configuration", func() {
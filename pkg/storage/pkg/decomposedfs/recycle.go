@@ -225,6 +225,19 @@ func readTrashLink(path string) (string, string, string, error) {
 	return resolved, link[15:51], link[54:], nil
 }
 
+// trashItemSize returns the blob size of a file or the tree size of a container,
+// falling back to the size of the node on disk when the attributes are missing
+func trashItemSize(attrs node.Attributes, typ provider.ResourceType, fi os.FileInfo) uint64 {
+	attr := prefixes.BlobsizeAttr
+	if typ == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		attr = prefixes.TreesizeAttr
+	}
+	if size, err := attrs.UInt64(attr); err == nil {
+		return size
+	}
+	return uint64(fi.Size())
+}
+
 func (tb *DecomposedfsTrashbin) listTrashRoot(ctx context.Context, spaceID string) ([]*provider.RecycleItem, error) {
 	log := appctx.GetLogger(ctx)
 	trashRoot := tb.getRecycleRoot(spaceID)
@@ -301,7 +314,7 @@ func (tb *DecomposedfsTrashbin) listTrashRoot(ctx context.Context, spaceID strin
 
 					item := &provider.RecycleItem{
 						Type: provider.ResourceType(typeInt),
-						Size: uint64(md.Size()),
+						Size: trashItemSize(attrs, provider.ResourceType(typeInt), md),
 						Key:  nodeID,
 					}
 					if deletionTime, err := time.Parse(time.RFC3339Nano, timeSuffix); err == nil {
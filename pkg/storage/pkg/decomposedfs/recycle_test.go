@@ -26,7 +26,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	posixhelpers "github.com/opencloud-eu/reva/v2/pkg/storage/fs/posix/testhelpers"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/permissions/mocks"
 	helpers "github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/testhelpers"
 	"github.com/stretchr/testify/mock"
@@ -472,6 +475,103 @@ var _ = Describe("Recycle", func() {
 
 		AssertBehavior()
 	})
+
+	Context("with trashed items counting against the quota", func() {
+		var (
+			denv      *helpers.DecomposedTestEnv
+			spaceRef  *provider.Reference
+			trashSize func() uint64
+		)
+
+		BeforeEach(func() {
+			var err error
+			denv, err = helpers.NewTestEnv(map[string]interface{}{
+				"count_trash_in_quota": true,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			env = denv
+
+			registerPermissions(denv.Permissions, "", &provider.ResourcePermissions{
+				Stat:               true,
+				InitiateFileUpload: true,
+				Delete:             true,
+				ListRecycle:        true,
+				PurgeRecycle:       true,
+				RestoreRecycleItem: true,
+				GetQuota:           true,
+			})
+			denv.Blobstore.On("Delete", mock.Anything).Return(nil)
+
+			spaceRef = &provider.Reference{ResourceId: denv.SpaceRootRes}
+			trashSize = func() uint64 {
+				spaceRoot, err := denv.Lookup.NodeFromSpaceID(denv.Ctx, denv.SpaceRootRes.SpaceId)
+				Expect(err).ToNot(HaveOccurred())
+				size, err := spaceRoot.GetTrashSize(denv.Ctx)
+				Expect(err).ToNot(HaveOccurred())
+				return size
+			}
+		})
+
+		deleteFile := func() *provider.RecycleItem {
+			err := denv.Fs.Delete(denv.Ctx, &provider.Reference{ResourceId: denv.SpaceRootRes, Path: "/dir1/file1"})
+			Expect(err).ToNot(HaveOccurred())
+			items, err := denv.Fs.ListRecycle(denv.Ctx, spaceRef, "", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+			return items[0]
+		}
+
+		It("adds deleted items to the trash size", func() {
+			deleteFile()
+			Expect(trashSize()).To(Equal(uint64(1234)))
+
+			_, used, _, err := denv.Fs.GetQuota(denv.Ctx, spaceRef)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(used).To(BeNumerically(">=", 1234))
+		})
+
+		It("subtracts restored items from the trash size", func() {
+			item := deleteFile()
+			Expect(denv.Fs.RestoreRecycleItem(denv.Ctx, spaceRef, item.Key, "", nil)).To(Succeed())
+			Expect(trashSize()).To(BeZero())
+		})
+
+		It("subtracts purged items from the trash size", func() {
+			item := deleteFile()
+			Expect(denv.Fs.PurgeRecycleItem(denv.Ctx, spaceRef, item.Key, "")).To(Succeed())
+			Expect(trashSize()).To(BeZero())
+		})
+
+		It("resets the trash size when the trash bin is emptied", func() {
+			deleteFile()
+			Expect(denv.Fs.EmptyRecycle(denv.Ctx, spaceRef)).To(Succeed())
+
+			spaceRoot, err := denv.Lookup.NodeFromSpaceID(denv.Ctx, denv.SpaceRootRes.SpaceId)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = spaceRoot.GetTrashSize(denv.Ctx)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with a stale trash size", func() {
+		It("is ignored by the quota check when trashed items do not count", func() {
+			denv, err := helpers.NewTestEnv(nil)
+			Expect(err).ToNot(HaveOccurred())
+			env = denv
+
+			spaceRoot, err := denv.Lookup.NodeFromSpaceID(denv.Ctx, denv.SpaceRootRes.SpaceId)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spaceRoot.SetXattrString(denv.Ctx, prefixes.QuotaAttr, "100000")).To(Succeed())
+			Expect(spaceRoot.SetTrashSize(denv.Ctx, 100000)).To(Succeed())
+
+			ok, err := node.CheckQuota(denv.Ctx, spaceRoot, false, 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			_, err = node.CheckQuota(denv.Ctx, spaceRoot, false, 0, 10, prefixes.TrashsizeAttr)
+			Expect(err).To(MatchError(errtypes.InsufficientStorage("quota exceeded")))
+		})
+	})
 })
 
 func registerPermissions(m *mocks.PermissionsChecker, uid string, exp *provider.ResourcePermissions) {
@@ -135,6 +135,13 @@ func (fs *Decomposedfs) RestoreRevision(ctx context.Context, ref *provider.Refer
 		return err
 	}
 
+	if fs.o.CountRevisionsInQuota {
+		// the current version became a revision and the restored revision became the current version
+		if err := n.SpaceRoot.AddRevisionSize(ctx, -sizeDiff); err != nil {
+			log.Error().Err(err).Str("spaceid", spaceID).Msg("could not update revision size")
+		}
+	}
+
 	// drop old revision
 	if err := os.Remove(restoredRevisionPath); err != nil {
 		log.Warn().Err(err).Interface("ref", ref).Str("originalnode", kp[0]).Str("revisionKey", revisionKey).Msg("could not delete old revision, continuing")
@@ -163,10 +170,18 @@ func (fs *Decomposedfs) DeleteRevision(ctx context.Context, ref *provider.Refere
 		return err
 	}
 
+	revisionSize, _ := fs.lu.MetadataBackend().GetInt64(ctx, node.NewBaseNode(n.SpaceID, revisionKey, fs.lu), prefixes.BlobsizeAttr)
+
 	if err := os.RemoveAll(fs.lu.InternalPath(n.SpaceID, revisionKey)); err != nil {
 		return err
 	}
 
+	if fs.o.CountRevisionsInQuota {
+		if err := n.SpaceRoot.AddRevisionSize(ctx, -revisionSize); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("spaceid", n.SpaceID).Msg("could not update revision size")
+		}
+	}
+
 	return fs.tp.DeleteBlob(n)
 }
 
@@ -207,3 +222,138 @@ func (fs *Decomposedfs) getRevisionNode(ctx context.Context, ref *provider.Refer
 
 	return n, nil
 }
+
+// pruneRevisionsPeriodically prunes expired revisions in all spaces until the context is canceled
+func (fs *Decomposedfs) pruneRevisionsPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fs.pruneRevisions(ctx, time.Now())
+		}
+	}
+}
+
+// pruneRevisions prunes expired revisions of all files in the spaces of the configured types
+func (fs *Decomposedfs) pruneRevisions(ctx context.Context, now time.Time) {
+	log := fs.log.With().Str("job", "revision-retention").Logger()
+	ctx = log.WithContext(ctx)
+	fs.walkSpaces(ctx, func(root *node.Node) {
+		pruned := 0
+		var prunedSize uint64
+		err := fs.walkFiles(ctx, root, func(n *node.Node) {
+			revisions, err := fs.pruneNodeRevisions(ctx, n, now)
+			if err != nil {
+				log.Error().Err(err).Str("spaceid", n.SpaceID).Str("nodeid", n.ID).Msg("could not prune revisions")
+			}
+			pruned += len(revisions)
+			prunedSize += node.RevisionsSize(revisions)
+		})
+		if err != nil {
+			log.Error().Err(err).Str("spaceid", root.SpaceID).Msg("could not prune revisions")
+		}
+		if fs.o.CountRevisionsInQuota {
+			if err := root.AddRevisionSize(ctx, -int64(prunedSize)); err != nil {
+				log.Error().Err(err).Str("spaceid", root.SpaceID).Msg("could not update revision size")
+			}
+		}
+		if pruned > 0 {
+			log.Debug().Str("spaceid", root.SpaceID).Int("pruned", pruned).Uint64("size", prunedSize).Msg("pruned expired revisions")
+		}
+	})
+}
+
+// pruneNodeRevisions prunes the expired revisions of the node while holding the
+// write lock of the node, so revisions are not removed while they are restored
+// or created by an upload
+func (fs *Decomposedfs) pruneNodeRevisions(ctx context.Context, n *node.Node, now time.Time) ([]*provider.FileVersion, error) {
+	f, err := lockedfile.OpenFile(fs.lu.MetadataBackend().LockfilePath(n), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(fs.lu.MetadataBackend().LockfilePath(n))
+	}()
+
+	return n.PruneRevisions(ctx, fs.tp, fs.revisionRetention, now)
+}
+
+// initRevisionSizes calculates the size of all revisions in the spaces of the configured types
+// that do not have a revision size yet
+func (fs *Decomposedfs) initRevisionSizes(ctx context.Context) {
+	log := fs.log.With().Str("job", "revision-size").Logger()
+	ctx = log.WithContext(ctx)
+	fs.walkSpaces(ctx, func(root *node.Node) {
+		if _, err := fs.lu.MetadataBackend().Get(ctx, root, prefixes.RevisionsizeAttr); err == nil {
+			return
+		}
+		var size uint64
+		err := fs.walkFiles(ctx, root, func(n *node.Node) {
+			revisions, err := n.Revisions(ctx)
+			if err != nil {
+				log.Error().Err(err).Str("spaceid", n.SpaceID).Str("nodeid", n.ID).Msg("could not list revisions")
+			}
+			size += node.RevisionsSize(revisions)
+		})
+		if err != nil {
+			log.Error().Err(err).Str("spaceid", root.SpaceID).Msg("could not calculate revision size")
+			return
+		}
+		err = root.UpdateSizeAttr(ctx, prefixes.RevisionsizeAttr, func(uint64, bool) (uint64, error) {
+			return size, nil
+		})
+		if err != nil {
+			log.Error().Err(err).Str("spaceid", root.SpaceID).Msg("could not store revision size")
+		}
+	})
+}
+
+// walkSpaces calls f with the root of every space of the types configured for the revision retention
+func (fs *Decomposedfs) walkSpaces(ctx context.Context, f func(root *node.Node)) {
+	log := appctx.GetLogger(ctx)
+	for _, spaceType := range fs.o.RevisionRetention.SpaceTypes {
+		spaces, err := fs.spaceTypeIndex.Load(spaceType)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Error().Err(err).Str("spacetype", spaceType).Msg("could not read space type index")
+			}
+			continue
+		}
+		for spaceID := range spaces {
+			if ctx.Err() != nil {
+				return
+			}
+			root, err := fs.lu.NodeFromSpaceID(ctx, spaceID)
+			if err != nil || !root.Exists {
+				continue
+			}
+			f(root)
+		}
+	}
+}
+
+// walkFiles calls f with every file below n
+func (fs *Decomposedfs) walkFiles(ctx context.Context, n *node.Node, f func(n *node.Node)) error {
+	children, err := fs.tp.ListFolder(ctx, n)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch child.Type(ctx) {
+		case provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+			if err := fs.walkFiles(ctx, child, f); err != nil {
+				return err
+			}
+		case provider.ResourceType_RESOURCE_TYPE_FILE:
+			f(child)
+		}
+	}
+	return nil
+}
@@ -117,6 +117,19 @@ func (fs *Decomposedfs) CreateStorageSpace(ctx context.Context, req *provider.Cr
 		return nil, errtypes.AlreadyExists("decomposedfs: spaces: space already exists")
 	}
 
+	var tmpl *spaceTemplate
+	if name := utils.ReadPlainFromOpaque(req.Opaque, SpaceTemplateOpaqueKey); name != "" {
+		if req.Type == _spaceTypePersonal {
+			return nil, errtypes.BadRequest("decomposedfs: spaces: personal spaces cannot be created from a template")
+		}
+		if tmpl, err = fs.loadSpaceTemplate(ctx, name); err != nil {
+			return nil, err
+		}
+		if description == "" {
+			description = tmpl.Description
+		}
+	}
+
 	// create a directory node
 	root.SetType(provider.ResourceType_RESOURCE_TYPE_CONTAINER)
 	if rootPath == "" {
@@ -165,7 +178,11 @@ func (fs *Decomposedfs) CreateStorageSpace(ctx context.Context, req *provider.Cr
 		metadata.SetString(prefixes.SpaceTypeAttr, req.Type)
 	}
 
-	if q := req.GetQuota(); q != nil {
+	q := req.GetQuota()
+	if q == nil && tmpl != nil && tmpl.Quota > 0 {
+		q = &provider.Quota{QuotaMaxBytes: tmpl.Quota}
+	}
+	if q != nil {
 		// set default space quota
 		if fs.o.MaxQuota != quotaUnrestricted && q.GetQuotaMaxBytes() > fs.o.MaxQuota {
 			return nil, errtypes.BadRequest("decompsedFS: requested quota is higher than allowed")
@@ -189,32 +206,61 @@ func (fs *Decomposedfs) CreateStorageSpace(ctx context.Context, req *provider.Cr
 		return nil, err
 	}
 
+	// files and grants of a template are discarded with the space if applying the template fails
+	var (
+		copied  []*node.Node
+		granted []*provider.Grantee
+	)
+	if tmpl != nil {
+		if err := fs.applySpaceTemplate(ctx, root, tmpl, &copied); err != nil {
+			fs.discardSpace(ctx, root, req.Type, rootPath, copied, nil)
+			return nil, err
+		}
+	}
+
 	// Write index
-	err = fs.updateIndexes(ctx, &provider.Grantee{
+	ownerGrantee := &provider.Grantee{
 		Type: provider.GranteeType_GRANTEE_TYPE_USER,
 		Id:   &provider.Grantee_UserId{UserId: req.GetOwner().GetId()},
-	}, req.Type, root.ID, root.ID)
+	}
+	err = fs.updateIndexes(ctx, ownerGrantee, req.Type, root.ID, root.ID)
 	if err != nil {
+		if tmpl != nil {
+			fs.discardSpace(ctx, root, req.Type, rootPath, copied, []*provider.Grantee{ownerGrantee})
+		}
 		return nil, err
 	}
+	granted = append(granted, ownerGrantee)
 
 	ctx = storageprovider.WithSpaceType(ctx, req.Type)
 
 	if req.Type != _spaceTypePersonal {
+		creatorGrantee := &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id: &provider.Grantee_UserId{
+				UserId: u.Id,
+			},
+		}
+		granted = append(granted, creatorGrantee)
 		if err := fs.AddGrant(ctx, &provider.Reference{
 			ResourceId: &provider.ResourceId{
 				SpaceId:  spaceID,
 				OpaqueId: spaceID,
 			},
 		}, &provider.Grant{
-			Grantee: &provider.Grantee{
-				Type: provider.GranteeType_GRANTEE_TYPE_USER,
-				Id: &provider.Grantee_UserId{
-					UserId: u.Id,
-				},
-			},
+			Grantee:     creatorGrantee,
 			Permissions: ocsconv.NewManagerRole().CS3ResourcePermissions(),
 		}); err != nil {
+			if tmpl != nil {
+				fs.discardSpace(ctx, root, req.Type, rootPath, copied, granted)
+			}
+			return nil, err
+		}
+	}
+
+	if tmpl != nil {
+		if err := fs.grantTemplateMembers(ctx, root, tmpl, []*userv1beta1.UserId{u.Id, req.GetOwner().GetId()}, &granted); err != nil {
+			fs.discardSpace(ctx, root, req.Type, rootPath, copied, granted)
 			return nil, err
 		}
 	}
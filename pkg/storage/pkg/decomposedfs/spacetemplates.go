@@ -0,0 +1,332 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	groupv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/google/uuid"
+	"github.com/opencloud-eu/reva/v2/pkg/appctx"
+	ocsconv "github.com/opencloud-eu/reva/v2/pkg/conversions"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SpaceTemplateOpaqueKey is the opaque key of CreateStorageSpaceRequests referencing a space template
+	SpaceTemplateOpaqueKey = "template"
+
+	spaceTemplateManifest = "template.json"
+	spaceTemplateContent  = "content"
+)
+
+// spaceTemplate describes the initial state of a project space. Templates are
+// folders in the configured templates space, named after the template. A folder
+// holds an optional template.json manifest and a content folder whose files and
+// folders are copied into the new space.
+type spaceTemplate struct {
+	Description string                `json:"description"`
+	Quota       uint64                `json:"quota"`
+	Readme      string                `json:"readme"`
+	Image       string                `json:"image"`
+	Members     []spaceTemplateMember `json:"members"`
+
+	content *node.Node
+}
+
+// spaceTemplateMember is a user or group that becomes a member of spaces created from a template
+type spaceTemplateMember struct {
+	User  *userv1beta1.UserId   `json:"user,omitempty"`
+	Group *groupv1beta1.GroupId `json:"group,omitempty"`
+	Role  string                `json:"role"`
+}
+
+func (m spaceTemplateMember) grant() (*provider.Grant, error) {
+	role := ocsconv.RoleFromName(m.Role)
+	if role.Name == ocsconv.RoleUnknown {
+		return nil, errtypes.BadRequest("decomposedfs: space template: unknown member role " + m.Role)
+	}
+	g := &provider.Grant{Permissions: role.CS3ResourcePermissions()}
+	switch {
+	case m.User.GetOpaqueId() != "" && m.Group == nil:
+		g.Grantee = &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: m.User},
+		}
+	case m.Group.GetOpaqueId() != "" && m.User == nil:
+		g.Grantee = &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+			Id:   &provider.Grantee_GroupId{GroupId: m.Group},
+		}
+	default:
+		return nil, errtypes.BadRequest("decomposedfs: space template: a member needs either a user or a group")
+	}
+	return g, nil
+}
+
+// loadSpaceTemplate reads a template from the templates space. The templates
+// space is managed by admins, so it is read without checking permissions.
+func (fs *Decomposedfs) loadSpaceTemplate(ctx context.Context, name string) (*spaceTemplate, error) {
+	if fs.o.SpaceTemplatesSpaceID == "" {
+		return nil, errtypes.NotSupported("decomposedfs: space templates are not configured")
+	}
+	if name == "." || name == ".." || strings.ContainsRune(name, '/') {
+		return nil, errtypes.BadRequest("decomposedfs: invalid space template name " + name)
+	}
+
+	templates, err := node.ReadNode(ctx, fs.lu, fs.o.SpaceTemplatesSpaceID, fs.o.SpaceTemplatesSpaceID, false, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if !templates.Exists {
+		return nil, errtypes.NotFound("decomposedfs: space templates space " + fs.o.SpaceTemplatesSpaceID)
+	}
+	folder, err := templates.Child(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !folder.Exists || !folder.IsDir(ctx) {
+		return nil, errtypes.NotFound("decomposedfs: space template " + name)
+	}
+
+	t := &spaceTemplate{}
+	manifest, err := folder.Child(ctx, spaceTemplateManifest)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Exists {
+		r, err := fs.tp.ReadBlob(manifest)
+		if err != nil {
+			return nil, errors.Wrap(err, "decomposedfs: could not read space template manifest")
+		}
+		err = json.NewDecoder(r).Decode(t)
+		r.Close()
+		if err != nil {
+			return nil, errtypes.BadRequest("decomposedfs: invalid space template manifest: " + err.Error())
+		}
+	}
+	for _, m := range t.Members {
+		if _, err := m.grant(); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := folder.Child(ctx, spaceTemplateContent)
+	if err != nil {
+		return nil, err
+	}
+	if content.Exists && content.IsDir(ctx) {
+		t.content = content
+	}
+	return t, nil
+}
+
+// applySpaceTemplate copies the content of a template into a new space and
+// sets the readme and image of the space. Files that were created are added
+// to copied so their blobs can be removed if creating the space fails.
+func (fs *Decomposedfs) applySpaceTemplate(ctx context.Context, root *node.Node, t *spaceTemplate, copied *[]*node.Node) error {
+	if t.content == nil {
+		return nil
+	}
+	if err := fs.copyTemplateTree(ctx, t.content, root, copied); err != nil {
+		return err
+	}
+
+	attrs := node.Attributes{}
+	for attr, p := range map[string]string{prefixes.SpaceReadmeAttr: t.Readme, prefixes.SpaceImageAttr: t.Image} {
+		if p == "" {
+			continue
+		}
+		n, err := fs.templateNode(ctx, root, p)
+		if err != nil {
+			return err
+		}
+		attrs.SetString(attr, n.ID)
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return root.SetXattrsWithContext(ctx, attrs, true)
+}
+
+// templateNode resolves a path relative to the root of a space created from a template
+func (fs *Decomposedfs) templateNode(ctx context.Context, root *node.Node, p string) (*node.Node, error) {
+	n := root
+	for _, name := range strings.Split(strings.Trim(path.Clean("/"+p), "/"), "/") {
+		c, err := n.Child(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !c.Exists {
+			return nil, errtypes.BadRequest("decomposedfs: space template does not contain " + p)
+		}
+		n = c
+	}
+	return n, nil
+}
+
+func (fs *Decomposedfs) copyTemplateTree(ctx context.Context, src, dst *node.Node, copied *[]*node.Node) error {
+	children, err := fs.tp.ListFolder(ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		target, err := dst.Child(ctx, child.Name)
+		if err != nil {
+			return err
+		}
+		if child.IsDir(ctx) {
+			if err := fs.tp.CreateDir(ctx, target); err != nil {
+				return err
+			}
+			if err := fs.copyTemplateTree(ctx, child, target, copied); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fs.copyTemplateFile(ctx, child, target); err != nil {
+			return err
+		}
+		*copied = append(*copied, target)
+	}
+	return nil
+}
+
+func (fs *Decomposedfs) copyTemplateFile(ctx context.Context, src, target *node.Node) error {
+	r, err := fs.tp.ReadBlob(src)
+	if err != nil {
+		return errors.Wrap(err, "decomposedfs: could not read space template file")
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(fs.o.UploadDirectory, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(fs.o.UploadDirectory, "template-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "decomposedfs: could not copy space template file")
+	}
+
+	target.BlobID = uuid.New().String()
+	target.Blobsize = src.Blobsize
+	if err := fs.tp.TouchFile(ctx, target, false, ""); err != nil {
+		return err
+	}
+	if err := fs.tp.WriteBlob(target, tmp.Name()); err != nil {
+		return err
+	}
+
+	attrs, err := src.Xattrs(ctx)
+	if err != nil {
+		return err
+	}
+	checksums := node.Attributes{}
+	for k, v := range attrs {
+		if strings.HasPrefix(k, prefixes.ChecksumPrefix) {
+			checksums[k] = v
+		}
+	}
+	if len(checksums) > 0 {
+		if err := target.SetXattrsWithContext(ctx, checksums, true); err != nil {
+			return err
+		}
+	}
+	return fs.tp.Propagate(ctx, target, target.Blobsize)
+}
+
+// grantTemplateMembers adds the members of a template to a new space. The
+// creator and the owner of the space keep their grants.
+func (fs *Decomposedfs) grantTemplateMembers(ctx context.Context, root *node.Node, t *spaceTemplate, skip []*userv1beta1.UserId, granted *[]*provider.Grantee) error {
+	for _, m := range t.Members {
+		if isTemplateMemberSkipped(m.User, skip) {
+			continue
+		}
+		g, err := m.grant()
+		if err != nil {
+			return err
+		}
+		if err := fs.storeGrant(ctx, root, g); err != nil {
+			return err
+		}
+		*granted = append(*granted, g.GetGrantee())
+	}
+	return nil
+}
+
+func isTemplateMemberSkipped(u *userv1beta1.UserId, skip []*userv1beta1.UserId) bool {
+	for _, s := range skip {
+		if utils.UserIDEqual(u, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// discardSpace removes a space that could not be created completely
+func (fs *Decomposedfs) discardSpace(ctx context.Context, root *node.Node, spaceType, rootPath string, files []*node.Node, grantees []*provider.Grantee) {
+	log := appctx.GetLogger(ctx).With().Str("spaceid", root.SpaceID).Logger()
+
+	if err := fs.spaceTypeIndex.Remove(spaceType, root.SpaceID); err != nil {
+		log.Error().Err(err).Msg("could not remove space type index entry")
+	}
+	for _, g := range grantees {
+		var err error
+		switch g.GetType() {
+		case provider.GranteeType_GRANTEE_TYPE_USER:
+			err = fs.userSpaceIndex.Remove(g.GetUserId().GetOpaqueId(), root.SpaceID)
+		case provider.GranteeType_GRANTEE_TYPE_GROUP:
+			err = fs.groupSpaceIndex.Remove(g.GetGroupId().GetOpaqueId(), root.SpaceID)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("could not remove space grant index entry")
+		}
+	}
+	for _, n := range files {
+		if err := fs.tp.DeleteBlob(n); err != nil {
+			log.Error().Err(err).Str("blobid", n.BlobID).Msg("could not delete blob")
+		}
+	}
+	if err := fs.lu.MetadataBackend().Purge(ctx, root); err != nil {
+		log.Error().Err(err).Msg("could not purge space metadata")
+	}
+	for _, p := range []string{rootPath, fs.lu.InternalSpaceRoot(root.SpaceID)} {
+		if err := os.RemoveAll(p); err != nil {
+			log.Error().Err(err).Str("path", p).Msg("could not remove space")
+		}
+	}
+}
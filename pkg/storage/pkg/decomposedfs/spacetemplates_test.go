@@ -0,0 +1,217 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs_test
+
+import (
+	"io"
+	"strings"
+
+	groupv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	cs3permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencloud-eu/reva/v2/pkg/conversions"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	helpers "github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/testhelpers"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Space templates", func() {
+	var (
+		env       *helpers.DecomposedTestEnv
+		blobs     map[string]string
+		templates provider.ResourceId
+		template  *node.Node
+	)
+
+	createFile := func(parent *node.Node, name, content string) {
+		blobs[name] = content
+		_, err := env.CreateTestFile(name, name, parent.ID, parent.SpaceID, int64(len(content)))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	createDir := func(name string) *node.Node {
+		n, err := env.CreateTestDir(name, &provider.Reference{ResourceId: &templates})
+		Expect(err).ToNot(HaveOccurred())
+		return n
+	}
+	createSpace := func(name string) (*provider.CreateStorageSpaceResponse, error) {
+		return env.Fs.CreateStorageSpace(env.Ctx, &provider.CreateStorageSpaceRequest{
+			Name:   "Research " + name,
+			Type:   "project",
+			Opaque: utils.AppendPlainToOpaque(nil, decomposedfs.SpaceTemplateOpaqueKey, name),
+		})
+	}
+	projectSpaces := func() int {
+		spaces, err := env.Fs.ListStorageSpaces(env.Ctx, []*provider.ListStorageSpacesRequest_Filter{
+			{Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE, Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: "project"}},
+		}, true)
+		Expect(err).ToNot(HaveOccurred())
+		return len(spaces)
+	}
+
+	BeforeEach(func() {
+		var err error
+		env, err = helpers.NewTestEnv(nil)
+		Expect(err).ToNot(HaveOccurred())
+		env.PermissionsClient.On("CheckPermission", mock.Anything, mock.Anything, mock.Anything).Return(&cs3permissions.CheckPermissionResponse{Status: &rpcv1beta1.Status{Code: rpcv1beta1.Code_CODE_OK}}, nil)
+		env.Permissions.On("AssemblePermissions", mock.Anything, mock.Anything, mock.Anything).Return(node.OwnerPermissions(), nil)
+
+		blobs = map[string]string{}
+		env.Blobstore.On("Download", mock.Anything).Return(func(n *node.Node) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(blobs[n.BlobID])), nil
+		})
+		env.Blobstore.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		env.Blobstore.On("Delete", mock.Anything).Return(nil)
+
+		res, err := env.Fs.CreateStorageSpace(env.Ctx, &provider.CreateStorageSpaceRequest{Name: "Templates", Type: "templates"})
+		Expect(err).ToNot(HaveOccurred())
+		templates, err = storagespace.ParseID(res.GetStorageSpace().GetId().GetOpaqueId())
+		Expect(err).ToNot(HaveOccurred())
+		env.Options.SpaceTemplatesSpaceID = templates.GetSpaceId()
+
+		template = createDir("research")
+		createFile(template, "template.json", `{
+			"description": "A research project",
+			"quota": 4096,
+			"readme": ".space/readme.md",
+			"members": [{"group": {"opaque_id": "researchers"}, "role": "spaceeditor"}]
+		}`)
+		content := createDir("research/content")
+		space := createDir("research/content/.space")
+		createDir("research/content/data")
+		createFile(space, "readme.md", "# Research")
+		createFile(content, "guidelines.txt", "be nice")
+	})
+
+	AfterEach(func() {
+		if env != nil {
+			env.Cleanup()
+		}
+	})
+
+	It("creates a project space from a template", func() {
+		res, err := createSpace("research")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.GetStatus().GetCode()).To(Equal(rpcv1beta1.Code_CODE_OK))
+
+		space := res.GetStorageSpace()
+		Expect(utils.ReadPlainFromOpaque(space.GetOpaque(), "description")).To(Equal("A research project"))
+		Expect(space.GetQuota().GetQuotaMaxBytes()).To(Equal(uint64(4096)))
+		Expect(utils.ReadPlainFromOpaque(space.GetOpaque(), "readme")).ToNot(BeEmpty())
+
+		root, err := storagespace.ParseID(space.GetId().GetOpaqueId())
+		Expect(err).ToNot(HaveOccurred())
+		infos, err := env.Fs.ListFolder(env.Ctx, &provider.Reference{ResourceId: &root, Path: "."}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		names := []string{}
+		for _, i := range infos {
+			names = append(names, i.GetName())
+		}
+		Expect(names).To(ConsistOf(".space", "data", "guidelines.txt"))
+
+		info, err := env.Fs.GetMD(env.Ctx, &provider.Reference{ResourceId: &root, Path: "./.space/readme.md"}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.GetSize()).To(Equal(uint64(len("# Research"))))
+		env.Blobstore.AssertNumberOfCalls(GinkgoT(), "Upload", 2)
+
+		grants, err := env.Fs.ListGrants(env.Ctx, &provider.Reference{ResourceId: &root})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(grants).To(ContainElement(HaveField("Grantee.Id", Equal(&provider.Grantee_GroupId{GroupId: &groupv1beta1.GroupId{OpaqueId: "researchers"}}))))
+		for _, g := range grants {
+			if g.GetGrantee().GetGroupId().GetOpaqueId() == "researchers" {
+				Expect(g.GetPermissions()).To(Equal(conversions.NewSpaceEditorRole().CS3ResourcePermissions()))
+			}
+			if g.GetGrantee().GetUserId().GetOpaqueId() == helpers.OwnerID {
+				Expect(g.GetPermissions()).To(Equal(conversions.NewManagerRole().CS3ResourcePermissions()))
+			}
+		}
+	})
+
+	It("prefers the requested quota and description", func() {
+		res, err := env.Fs.CreateStorageSpace(env.Ctx, &provider.CreateStorageSpaceRequest{
+			Name:   "Research",
+			Type:   "project",
+			Quota:  &provider.Quota{QuotaMaxBytes: 100},
+			Opaque: utils.AppendPlainToOpaque(utils.AppendPlainToOpaque(nil, "description", "custom"), decomposedfs.SpaceTemplateOpaqueKey, "research"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.GetStorageSpace().GetQuota().GetQuotaMaxBytes()).To(Equal(uint64(100)))
+		Expect(utils.ReadPlainFromOpaque(res.GetStorageSpace().GetOpaque(), "description")).To(Equal("custom"))
+	})
+
+	It("fails for unknown templates", func() {
+		_, err := createSpace("unknown")
+		Expect(err).To(HaveOccurred())
+		_, ok := err.(errtypes.IsNotFound)
+		Expect(ok).To(BeTrue())
+		Expect(projectSpaces()).To(Equal(0))
+	})
+
+	It("rejects templates with unknown member roles", func() {
+		blobs["template.json"] = `{"members": [{"group": {"opaque_id": "researchers"}, "role": "overlord"}]}`
+		_, err := createSpace("research")
+		Expect(err).To(HaveOccurred())
+		_, ok := err.(errtypes.IsBadRequest)
+		Expect(ok).To(BeTrue())
+		Expect(projectSpaces()).To(Equal(0))
+	})
+
+	It("does not grant template roles to the owner of the space", func() {
+		blobs["template.json"] = `{"members": [{"user": {"opaque_id": "alice"}, "role": "viewer"}]}`
+		res, err := env.Fs.CreateStorageSpace(env.Ctx, &provider.CreateStorageSpaceRequest{
+			Name:   "Research",
+			Type:   "project",
+			Owner:  &userv1beta1.User{Id: &userv1beta1.UserId{OpaqueId: "alice"}},
+			Opaque: utils.AppendPlainToOpaque(nil, decomposedfs.SpaceTemplateOpaqueKey, "research"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		root, err := storagespace.ParseID(res.GetStorageSpace().GetId().GetOpaqueId())
+		Expect(err).ToNot(HaveOccurred())
+		grants, err := env.Fs.ListGrants(env.Ctx, &provider.Reference{ResourceId: &root})
+		Expect(err).ToNot(HaveOccurred())
+		for _, g := range grants {
+			Expect(g.GetGrantee().GetUserId().GetOpaqueId()).ToNot(Equal("alice"))
+		}
+	})
+
+	It("discards the space when the template cannot be applied", func() {
+		blobs["template.json"] = `{"image": ".space/missing.png"}`
+		_, err := createSpace("research")
+		Expect(err).To(HaveOccurred())
+		Expect(projectSpaces()).To(Equal(0))
+		env.Blobstore.AssertCalled(GinkgoT(), "Delete", mock.Anything)
+	})
+
+	It("cannot create personal spaces from a template", func() {
+		_, err := env.Fs.CreateStorageSpace(env.Ctx, &provider.CreateStorageSpaceRequest{
+			Type:   "personal",
+			Owner:  env.Users[0],
+			Opaque: utils.AppendPlainToOpaque(nil, decomposedfs.SpaceTemplateOpaqueKey, "research"),
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -674,7 +674,7 @@ func (t *Tree) InitNewNode(ctx context.Context, n *node.Node, fsize uint64) (met
 	h.Close()
 
 	_, subspan = tracer.Start(ctx, "node.CheckQuota")
-	_, err = node.CheckQuota(ctx, n.SpaceRoot, false, 0, fsize)
+	_, err = node.CheckQuota(ctx, n.SpaceRoot, false, 0, fsize, node.UsageAttrs(t.options.CountTrashInQuota, t.options.CountRevisionsInQuota)...)
 	subspan.End()
 	if err != nil {
 		return unlock, err
@@ -739,6 +739,7 @@ func (t *Tree) removeNode(ctx context.Context, n *node.Node) error {
 	}
 
 	// delete revisions
+	var revisionsSize int64
 	originalNodeID := nodeIDRegep.ReplaceAllString(n.InternalPath(), "$1")
 	revs, err := filepath.Glob(originalNodeID + node.RevisionIDDelimiter + "*")
 	if err != nil {
@@ -754,7 +755,7 @@ func (t *Tree) removeNode(ctx context.Context, n *node.Node) error {
 		revID = strings.ReplaceAll(revID, "/", "")
 		revNode := node.NewBaseNode(n.SpaceID, revID, t.lookup)
 
-		bID, _, err := t.lookup.ReadBlobIDAndSizeAttr(ctx, revNode, nil)
+		bID, bSize, err := t.lookup.ReadBlobIDAndSizeAttr(ctx, revNode, nil)
 		if err != nil {
 			logger.Error().Err(err).Str("revision", rev).Msg("error reading blobid attribute")
 			return err
@@ -764,6 +765,7 @@ func (t *Tree) removeNode(ctx context.Context, n *node.Node) error {
 			logger.Error().Err(err).Str("revision", rev).Msg("error removing revision node")
 			return err
 		}
+		revisionsSize += bSize
 
 		if bID != "" {
 			if err := t.DeleteBlob(&node.Node{
@@ -777,6 +779,16 @@ func (t *Tree) removeNode(ctx context.Context, n *node.Node) error {
 
 	}
 
+	if t.options.CountRevisionsInQuota && revisionsSize > 0 {
+		spaceRoot, err := t.lookup.NodeFromSpaceID(ctx, n.SpaceID)
+		if err == nil {
+			err = spaceRoot.AddRevisionSize(ctx, -revisionsSize)
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("spaceid", n.SpaceID).Msg("could not update revision size")
+		}
+	}
+
 	return nil
 }
 
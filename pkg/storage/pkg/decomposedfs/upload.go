@@ -34,6 +34,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx/metrics"
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
@@ -203,6 +204,9 @@ func (fs *Decomposedfs) InitiateUpload(ctx context.Context, ref *provider.Refere
 				session.SetMetadata("mtime", metadata["mtime"])
 			}
 		}
+		if label, ok := metadata[events.FileDropLabelOpaqueKey]; ok {
+			session.SetMetadata(events.FileDropLabelOpaqueKey, label)
+		}
 		if expiration, ok := metadata["expires"]; ok {
 			if expiration != "null" {
 				session.SetMetadata("expires", metadata["expires"])
@@ -242,7 +246,7 @@ func (fs *Decomposedfs) InitiateUpload(ctx context.Context, ref *provider.Refere
 
 	log.Debug().Str("uploadid", session.ID()).Str("spaceid", n.SpaceID).Str("nodeid", n.ID).Interface("metadata", metadata).Msg("Decomposedfs: resolved filename")
 
-	_, err = node.CheckQuota(ctx, n.SpaceRoot, n.Exists, uint64(n.Blobsize), uint64(session.Size()))
+	_, err = node.CheckQuota(ctx, n.SpaceRoot, n.Exists, uint64(n.Blobsize), uint64(session.Size()), node.UsageAttrs(fs.o.CountTrashInQuota, fs.o.CountRevisionsInQuota)...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +43,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/options"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/usermapper"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
 	"github.com/pkg/errors"
 	"github.com/rogpeppe/go-internal/lockedfile"
 	"github.com/rs/zerolog"
@@ -58,31 +59,37 @@ type PermissionsChecker interface {
 
 // DecomposedFsStore manages upload sessions
 type DecomposedFsStore struct {
-	fs                storage.FS
-	lu                node.PathLookup
-	tp                node.Tree
-	um                usermapper.Mapper
-	root              string
-	pub               events.Publisher
-	async             bool
-	tknopts           options.TokenOptions
-	disableVersioning bool
-	log               *zerolog.Logger
+	fs                    storage.FS
+	lu                    node.PathLookup
+	tp                    node.Tree
+	um                    usermapper.Mapper
+	root                  string
+	pub                   events.Publisher
+	async                 bool
+	tknopts               options.TokenOptions
+	disableVersioning     bool
+	countTrashInQuota     bool
+	countRevisionsInQuota bool
+	retention             retention.RevisionPolicy
+	log                   *zerolog.Logger
 }
 
 // NewSessionStore returns a new DecomposedFsStore
 func NewSessionStore(fs storage.FS, aspects aspects.Aspects, root string, async bool, tknopts options.TokenOptions, log *zerolog.Logger) *DecomposedFsStore {
 	return &DecomposedFsStore{
-		fs:                fs,
-		lu:                aspects.Lookup,
-		tp:                aspects.Tree,
-		root:              root,
-		pub:               aspects.EventStream,
-		async:             async,
-		tknopts:           tknopts,
-		disableVersioning: aspects.DisableVersioning,
-		um:                aspects.UserMapper,
-		log:               log,
+		fs:                    fs,
+		lu:                    aspects.Lookup,
+		tp:                    aspects.Tree,
+		root:                  root,
+		pub:                   aspects.EventStream,
+		async:                 async,
+		tknopts:               tknopts,
+		disableVersioning:     aspects.DisableVersioning,
+		countTrashInQuota:     aspects.CountTrashInQuota,
+		countRevisionsInQuota: aspects.CountRevisionsInQuota,
+		retention:             aspects.RevisionRetention,
+		um:                    aspects.UserMapper,
+		log:                   log,
 	}
 }
 
@@ -317,7 +324,7 @@ func (store DecomposedFsStore) updateExistingNode(ctx context.Context, session *
 	}
 
 	old, _ := node.ReadNode(ctx, store.lu, spaceID, n.ID, false, nil, false)
-	if _, err := node.CheckQuota(ctx, n.SpaceRoot, true, uint64(old.Blobsize), fsize); err != nil {
+	if _, err := node.CheckQuota(ctx, n.SpaceRoot, true, uint64(old.Blobsize), fsize, node.UsageAttrs(store.countTrashInQuota, store.countRevisionsInQuota)...); err != nil {
 		return unlock, err
 	}
 
@@ -367,11 +374,16 @@ func (store DecomposedFsStore) updateExistingNode(ctx context.Context, session *
 		timestamp := oldNodeMtime.UTC().Format(time.RFC3339Nano)
 		versionID := n.ID + node.RevisionIDDelimiter + timestamp
 		versionPath, err := session.store.tp.CreateRevision(ctx, n, timestamp, f)
-		if err != nil {
-			if !errors.Is(err, os.ErrExist) {
-				return unlock, err
+		switch {
+		case err == nil:
+			if store.countRevisionsInQuota {
+				if err := n.SpaceRoot.AddRevisionSize(ctx, old.Blobsize); err != nil {
+					appctx.GetLogger(ctx).Error().Err(err).Str("spaceid", n.SpaceID).Msg("could not update revision size")
+				}
 			}
-
+		case !errors.Is(err, os.ErrExist):
+			return unlock, err
+		default:
 			// a revision with this mtime does already exist.
 			// If the blobs are the same we can just delete the old one
 			versionNode := node.NewBaseNode(n.SpaceID, versionID, session.store.lu)
@@ -400,6 +412,20 @@ func (store DecomposedFsStore) updateExistingNode(ctx context.Context, session *
 		if err := os.Chtimes(versionPath, oldNodeMtime, oldNodeMtime); err != nil {
 			return unlock, errtypes.InternalError(fmt.Sprintf("failed to change mtime of version node: %s", err))
 		}
+
+		if store.retention.Enabled() {
+			span.AddEvent("PruneRevisions")
+			// never prune the revision we just created, it is needed to restore the node if postprocessing fails
+			pruned, err := n.PruneRevisions(ctx, session.store.tp, store.retention, time.Now(), versionID)
+			if err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Str("spaceid", n.SpaceID).Str("nodeid", n.ID).Msg("could not prune revisions")
+			}
+			if store.countRevisionsInQuota {
+				if err := n.SpaceRoot.AddRevisionSize(ctx, -int64(node.RevisionsSize(pruned))); err != nil {
+					appctx.GetLogger(ctx).Error().Err(err).Str("spaceid", n.SpaceID).Msg("could not update revision size")
+				}
+			}
+		}
 	}
 
 	session.info.MetaData["sizeDiff"] = strconv.FormatInt((int64(fsize) - old.Blobsize), 10)
@@ -235,11 +235,39 @@ func (session *DecomposedFsSession) FinishUploadDecomposed(ctx context.Context)
 			return err
 		}
 		metrics.UploadSessionsFinalized.Inc()
+		if err := session.PublishFileDropped(ctx, n); err != nil {
+			log.Error().Err(err).Msg("failed to publish FileDropped event")
+		}
 	}
 
 	return session.store.tp.Propagate(ctx, n, session.SizeDiff())
 }
 
+// PublishFileDropped announces a finished upload into a secure file drop. The label of the
+// uploader is only recorded for uploads by the uploaders of public links.
+func (session *DecomposedFsSession) PublishFileDropped(ctx context.Context, n *node.Node) error {
+	label, ok := session.info.MetaData[events.FileDropLabelOpaqueKey]
+	if !ok || session.store.pub == nil {
+		return nil
+	}
+	executant := session.Executant()
+	owner := n.SpaceOwnerOrManager(ctx)
+	return events.Publish(ctx, session.store.pub, events.FileDropped{
+		SpaceOwner: owner,
+		Owner:      owner,
+		Executant:  &executant,
+		Ref: &provider.Reference{
+			ResourceId: &provider.ResourceId{
+				StorageId: session.ProviderID(),
+				SpaceId:   n.SpaceID,
+				OpaqueId:  n.ID,
+			},
+		},
+		Label:     label,
+		Timestamp: utils.TSNow(),
+	})
+}
+
 // Terminate terminates the upload
 func (session *DecomposedFsSession) Terminate(_ context.Context) error {
 	session.Cleanup(true, true, true)
@@ -265,6 +265,37 @@ var _ = Describe("Async file uploads", Ordered, func() {
 
 		})
 
+		It("announces uploads into secure file drops when they have finished", func() {
+			succeedPostprocessing(uploadID)
+
+			dropRef := &provider.Reference{ResourceId: ref.ResourceId, Path: "/drop"}
+			uploadIds, err := fs.InitiateUpload(ctx, dropRef, 10, map[string]string{events.FileDropLabelOpaqueKey: "alice"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = fs.Upload(ctx, storage.UploadRequest{
+				Ref:    &provider.Reference{Path: "/" + uploadIds["simple"]},
+				Body:   io.NopCloser(bytes.NewReader(firstContent)),
+				Length: int64(len(firstContent)),
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := (<-pub).(events.BytesReceived)
+			Expect(ok).To(BeTrue())
+
+			con <- events.PostprocessingFinished{
+				UploadID: uploadIds["simple"],
+				Outcome:  events.PPOutcomeContinue,
+			}
+			// the events are published concurrently
+			var dropped []events.FileDropped
+			for i := 0; i < 2; i++ {
+				if ev, ok := (<-pub).(events.FileDropped); ok {
+					dropped = append(dropped, ev)
+				}
+			}
+			Expect(dropped).To(HaveLen(1))
+			Expect(dropped[0].Label).To(Equal("alice"))
+			Expect(dropped[0].Ref.GetResourceId().GetOpaqueId()).ToNot(BeEmpty())
+		})
+
 		It("deletes node and bytes when instructed", func() {
 			// node is created
 			resources, err := fs.ListFolder(ctx, rootRef, []string{}, []string{})
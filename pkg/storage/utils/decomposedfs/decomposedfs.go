@@ -151,7 +151,7 @@ func NewDefault(m map[string]interface{}, bs tree.Blobstore, es events.Stream, l
 		return nil, fmt.Errorf("unknown metadata backend %s, only 'messagepack' or 'xattrs' (default) supported", o.MetadataBackend)
 	}
 
-	tp := tree.New(lu, bs, o, store.Create(
+	idCache, err := store.New(
 		store.Store(o.IDCache.Store),
 		store.TTL(o.IDCache.TTL),
 		store.Size(o.IDCache.Size),
@@ -160,7 +160,11 @@ func NewDefault(m map[string]interface{}, bs tree.Blobstore, es events.Stream, l
 		microstore.Table(o.IDCache.Table),
 		store.DisablePersistence(o.IDCache.DisablePersistence),
 		store.Authentication(o.IDCache.AuthUsername, o.IDCache.AuthPassword),
-	), log)
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := tree.New(lu, bs, o, idCache, log)
 
 	permissionsSelector, err := pool.PermissionsSelector(o.PermissionsSVC, pool.WithTLSMode(o.PermTLSMode))
 	if err != nil {
@@ -330,6 +334,9 @@ func (fs *Decomposedfs) Postprocessing(ch <-chan events.Event) {
 					unmarkPostprocessing = false
 				} else {
 					metrics.UploadSessionsFinalized.Inc()
+					if err := session.PublishFileDropped(ctx, n); err != nil {
+						sublog.Error().Err(err).Msg("Failed to publish FileDropped event")
+					}
 				}
 			case events.PPOutcomeDelete:
 				failed = true
@@ -34,6 +34,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	ctxpkg "github.com/opencloud-eu/reva/v2/pkg/ctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/events"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp/datatx/metrics"
 	"github.com/opencloud-eu/reva/v2/pkg/storage"
 	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/chunking"
@@ -199,6 +200,9 @@ func (fs *Decomposedfs) InitiateUpload(ctx context.Context, ref *provider.Refere
 				session.SetMetadata("mtime", metadata["mtime"])
 			}
 		}
+		if label, ok := metadata[events.FileDropLabelOpaqueKey]; ok {
+			session.SetMetadata(events.FileDropLabelOpaqueKey, label)
+		}
 		if expiration, ok := metadata["expires"]; ok {
 			if expiration != "null" {
 				session.SetMetadata("expires", metadata["expires"])
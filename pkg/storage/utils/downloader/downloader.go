@@ -29,8 +29,10 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/opencloud-eu/reva/v2/internal/http/services/datagateway"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/publicshare"
 	"github.com/opencloud-eu/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/opencloud-eu/reva/v2/pkg/rhttp"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
 )
 
 // Downloader is the interface implemented by the objects that are able to
@@ -67,12 +69,16 @@ func (r *revaDownloader) Download(ctx context.Context, id *provider.ResourceId,
 	if err != nil {
 		return err
 	}
-	downResp, err := gatewayClient.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{
+	dReq := &provider.InitiateFileDownloadRequest{
 		Ref: &provider.Reference{
 			ResourceId: id,
 			Path:       ".",
 		},
-	})
+	}
+	if visitor, ok := publicshare.ContextGetVisitor(ctx); ok {
+		dReq.Opaque = utils.AppendPlainToOpaque(dReq.Opaque, publicshare.VisitorOpaqueKey, visitor)
+	}
+	downResp, err := gatewayClient.InitiateFileDownload(ctx, dReq)
 
 	switch {
 	case err != nil:
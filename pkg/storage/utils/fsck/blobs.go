@@ -0,0 +1,76 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fsck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// checkBlobs reports blobs that are missing for files and blobs that are
+// no longer referenced. Blobs of spaces whose root could not be found or
+// whose blob references could not be fully collected are left alone.
+func (c *Checker) checkBlobs(_ context.Context) error {
+	blobs, err := c.blobs.List()
+	if err != nil {
+		return errors.Wrap(err, "fsck: could not list blobs")
+	}
+	c.report.Blobs = len(blobs)
+
+	present := map[string]map[string]struct{}{}
+	for _, b := range blobs {
+		if present[b.SpaceID] == nil {
+			present[b.SpaceID] = map[string]struct{}{}
+		}
+		present[b.SpaceID][b.BlobID] = struct{}{}
+
+		if _, ok := c.spaceRoots[b.SpaceID]; !ok || !c.blobRefsComplete[b.SpaceID] {
+			continue
+		}
+		if _, ok := c.blobRefs[b.SpaceID][b.BlobID]; ok {
+			continue
+		}
+		c.issue(Issue{
+			Kind:    KindDanglingBlob,
+			SpaceID: b.SpaceID,
+			Path:    b.BlobID,
+			Message: "blob is not referenced by any node, revision or upload",
+		}, func() error {
+			return c.blobs.Delete(b)
+		})
+	}
+
+	for spaceID, files := range c.fileBlobs {
+		for blobID, nodeID := range files {
+			if _, ok := present[spaceID][blobID]; ok {
+				continue
+			}
+			c.issue(Issue{
+				Kind:    KindMissingBlob,
+				SpaceID: spaceID,
+				NodeID:  nodeID,
+				Path:    blobID,
+				Message: fmt.Sprintf("blob '%s' of the file does not exist", blobID),
+			}, nil)
+		}
+	}
+	return nil
+}
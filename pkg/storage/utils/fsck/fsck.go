@@ -0,0 +1,268 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package fsck checks decomposedfs and posixfs storage trees for
+// inconsistencies between metadata, indexes, blobs and upload sessions and
+// optionally repairs them.
+package fsck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// LayoutDecomposed is the layout of decomposedfs based drivers
+	LayoutDecomposed = "decomposed"
+	// LayoutPosix is the layout of the posix driver
+	LayoutPosix = "posix"
+
+	// posixMetadataDir is the directory posixfs keeps offloaded metadata and revisions in
+	posixMetadataDir = ".oc-nodes"
+)
+
+// Kind classifies an issue
+type Kind string
+
+const (
+	// KindUnreadable is reported for nodes whose metadata cannot be read
+	KindUnreadable Kind = "unreadable"
+	// KindDanglingChild is reported for child links pointing to a non existing node
+	KindDanglingChild Kind = "dangling_child"
+	// KindMissingID is reported for entries that have not been assimilated yet
+	KindMissingID Kind = "missing_id"
+	// KindParentMismatch is reported for nodes whose parentid does not match the directory they are linked from
+	KindParentMismatch Kind = "parent_mismatch"
+	// KindNameMismatch is reported for nodes whose name does not match the name they are linked with
+	KindNameMismatch Kind = "name_mismatch"
+	// KindMissingIndexEntry is reported for spaces missing from the space type index
+	KindMissingIndexEntry Kind = "missing_index_entry"
+	// KindStaleIndexEntry is reported for index entries referencing non existing spaces
+	KindStaleIndexEntry Kind = "stale_index_entry"
+	// KindTreeSize is reported for directories whose treesize differs from the size of their children
+	KindTreeSize Kind = "tree_size"
+	// KindTreeMTime is reported for directories whose tmtime is older than the tmtime of a child directory
+	KindTreeMTime Kind = "tree_mtime"
+	// KindMissingBlob is reported for files whose blob does not exist
+	KindMissingBlob Kind = "missing_blob"
+	// KindDanglingBlob is reported for blobs not referenced by any node, revision or upload
+	KindDanglingBlob Kind = "dangling_blob"
+	// KindOrphanedUpload is reported for upload sessions that can never be finished
+	KindOrphanedUpload Kind = "orphaned_upload"
+)
+
+// Options configures a Checker
+type Options struct {
+	// Root is the root directory of the storage
+	Root string `mapstructure:"root"`
+	// Layout is either "decomposed" (default) or "posix"
+	Layout string `mapstructure:"layout"`
+	// MetadataBackend is the metadata backend of the storage. Defaults to
+	// "messagepack" for the decomposed and "hybrid" for the posix layout.
+	MetadataBackend string `mapstructure:"metadata_backend"`
+	// Repair fixes the issues that can be fixed instead of only reporting them
+	Repair bool `mapstructure:"repair"`
+}
+
+// Blobstore is the part of a blobstore needed to find missing and dangling blobs
+type Blobstore interface {
+	List() ([]*node.Node, error)
+	Delete(node *node.Node) error
+}
+
+// Issue is a single inconsistency found in the storage
+type Issue struct {
+	Kind        Kind   `json:"kind"`
+	SpaceID     string `json:"space_id,omitempty"`
+	NodeID      string `json:"node_id,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Message     string `json:"message"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// Report summarizes a check run
+type Report struct {
+	Spaces  int     `json:"spaces"`
+	Nodes   int     `json:"nodes"`
+	Blobs   int     `json:"blobs"`
+	Uploads int     `json:"uploads"`
+	Issues  []Issue `json:"issues"`
+}
+
+// Unresolved returns the number of issues that have not been repaired
+func (r *Report) Unresolved() int {
+	n := 0
+	for _, i := range r.Issues {
+		if !i.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// Checker walks a storage root and verifies its consistency
+type Checker struct {
+	o       Options
+	backend metadata.Backend
+	blobs   Blobstore
+	log     *zerolog.Logger
+	now     func() time.Time
+
+	// spaceRoots maps space ids to the path of their root node
+	spaceRoots map[string]string
+	// blobRefs holds the blobs referenced by nodes, revisions and uploads per space
+	blobRefs map[string]map[string]struct{}
+	// blobRefsComplete marks the spaces whose blob references could be fully collected
+	blobRefsComplete map[string]bool
+	// fileBlobs holds the blobs of the current file versions per space
+	fileBlobs map[string]map[string]string
+	report    *Report
+}
+
+// New returns a new Checker. The blobstore is optional, blobs are not checked without it.
+func New(o Options, bs Blobstore, log *zerolog.Logger) (*Checker, error) {
+	if o.Root == "" {
+		return nil, errors.New("fsck: root must not be empty")
+	}
+	if log == nil {
+		l := zerolog.Nop()
+		log = &l
+	}
+	if o.Layout == "" {
+		o.Layout = LayoutDecomposed
+	}
+	c := &Checker{
+		o:          o,
+		blobs:      bs,
+		log:        log,
+		now:        time.Now,
+		spaceRoots: map[string]string{},
+	}
+
+	// never serve attributes from a cache, the point is to look at what is on disk
+	noCache := cache.Config{Store: "noop"}
+	switch o.Layout {
+	case LayoutDecomposed:
+		switch o.MetadataBackend {
+		case "", "messagepack":
+			c.backend = metadata.NewMessagePackBackend(noCache)
+		case "xattrs":
+			c.backend = metadata.NewXattrsBackend(noCache)
+		default:
+			return nil, fmt.Errorf("fsck: unsupported metadata backend '%s' for the decomposed layout", o.MetadataBackend)
+		}
+	case LayoutPosix:
+		switch o.MetadataBackend {
+		case "", "hybrid":
+			c.backend = metadata.NewHybridBackend(1024, func(n metadata.MetadataNode) string {
+				spaceRoot := c.spaceRoots[n.GetSpaceID()]
+				if spaceRoot == "" {
+					return ""
+				}
+				return filepath.Join(spaceRoot, posixMetadataDir)
+			}, noCache)
+		case "xattrs":
+			c.backend = metadata.NewXattrsBackend(noCache)
+		default:
+			return nil, fmt.Errorf("fsck: unsupported metadata backend '%s' for the posix layout", o.MetadataBackend)
+		}
+		// posixfs stores the file content in place, there are no blobs to check
+		c.blobs = nil
+	default:
+		return nil, fmt.Errorf("fsck: unknown layout '%s'", o.Layout)
+	}
+	return c, nil
+}
+
+// Run checks the storage and returns a report of the issues found.
+// In repair mode the issues are fixed where possible.
+func (c *Checker) Run(ctx context.Context) (*Report, error) {
+	c.report = &Report{Issues: []Issue{}}
+	c.spaceRoots = map[string]string{}
+	c.blobRefs = map[string]map[string]struct{}{}
+	c.blobRefsComplete = map[string]bool{}
+	c.fileBlobs = map[string]map[string]string{}
+
+	if err := c.discoverSpaces(ctx); err != nil {
+		return nil, err
+	}
+	c.report.Spaces = len(c.spaceRoots)
+
+	if err := c.checkIndexes(ctx); err != nil {
+		return nil, err
+	}
+	for spaceID, root := range c.spaceRoots {
+		c.checkTree(ctx, spaceID, root)
+	}
+	if err := c.checkUploads(ctx); err != nil {
+		return nil, err
+	}
+	if c.blobs != nil {
+		if err := c.checkBlobs(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.report, nil
+}
+
+// issue records an issue and runs the repair function in repair mode
+func (c *Checker) issue(i Issue, repair func() error) {
+	if c.o.Repair && repair != nil {
+		if err := repair(); err != nil {
+			i.RepairError = err.Error()
+		} else {
+			i.Repaired = true
+		}
+	}
+	c.log.Info().Str("kind", string(i.Kind)).Str("spaceid", i.SpaceID).Str("nodeid", i.NodeID).Str("path", i.Path).
+		Bool("repaired", i.Repaired).Str("repair_error", i.RepairError).Msg(i.Message)
+	c.report.Issues = append(c.report.Issues, i)
+}
+
+// entry is a node on disk, identified by its space id, id and internal path
+type entry struct {
+	spaceID string
+	id      string
+	path    string
+}
+
+func (e entry) GetSpaceID() string   { return e.spaceID }
+func (e entry) GetID() string        { return e.id }
+func (e entry) InternalPath() string { return e.path }
+
+// isLockFile returns true for the lock files living next to nodes and sessions
+func isLockFile(name string) bool {
+	return strings.HasSuffix(name, ".flock") || strings.HasSuffix(name, ".mlock") || strings.HasSuffix(name, ".lock")
+}
+
+// exists returns true if the path exists, without following symlinks
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
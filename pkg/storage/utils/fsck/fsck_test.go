@@ -0,0 +1,209 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fsck_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/cache"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/node"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/spaceidindex"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/fsck"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	spaceID   = "aaaaaaaa-1111-4111-8111-111111111111"
+	fileID    = "bbbbbbbb-2222-4222-8222-222222222222"
+	uploadID  = "cccccccc-3333-4333-8333-333333333333"
+	staleID   = "dddddddd-4444-4444-8444-444444444444"
+	fileBlob  = "eeeeeeee-5555-4555-8555-555555555555"
+	extraBlob = "ffffffff-6666-4666-8666-666666666666"
+)
+
+type testNode struct {
+	spaceID, id, path string
+}
+
+func (n testNode) GetSpaceID() string   { return n.spaceID }
+func (n testNode) GetID() string        { return n.id }
+func (n testNode) InternalPath() string { return n.path }
+
+type testBlobstore struct {
+	blobs []*node.Node
+}
+
+func (bs *testBlobstore) List() ([]*node.Node, error) {
+	return bs.blobs, nil
+}
+
+func (bs *testBlobstore) Delete(n *node.Node) error {
+	for i, b := range bs.blobs {
+		if b.SpaceID == n.SpaceID && b.BlobID == n.BlobID {
+			bs.blobs = append(bs.blobs[:i], bs.blobs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func blob(spaceID, blobID string) *node.Node {
+	return &node.Node{BaseNode: node.BaseNode{SpaceID: spaceID}, BlobID: blobID}
+}
+
+var _ = Describe("Checker", func() {
+	var (
+		ctx     = context.Background()
+		root    string
+		rootDir string
+		backend metadata.Backend
+		bs      *testBlobstore
+	)
+
+	nodePath := func(id string) string {
+		return filepath.Join(root, "spaces", lookup.Pathify(spaceID, 1, 2), "nodes", lookup.Pathify(id, 4, 2))
+	}
+	kinds := func(r *fsck.Report) []fsck.Kind {
+		k := []fsck.Kind{}
+		for _, i := range r.Issues {
+			k = append(k, i.Kind)
+		}
+		return k
+	}
+	run := func(repair bool) *fsck.Report {
+		c, err := fsck.New(fsck.Options{Root: root, Repair: repair}, bs, nil)
+		Expect(err).ToNot(HaveOccurred())
+		r, err := c.Run(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		return r
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		backend = metadata.NewMessagePackBackend(cache.Config{Store: "noop"})
+		bs = &testBlobstore{blobs: []*node.Node{blob(spaceID, fileBlob), blob(spaceID, extraBlob)}}
+
+		rootDir = nodePath(spaceID)
+		Expect(os.MkdirAll(rootDir, 0700)).To(Succeed())
+		Expect(backend.SetMultiple(ctx, testNode{spaceID, spaceID, rootDir}, map[string][]byte{
+			prefixes.IDAttr:        []byte(spaceID),
+			prefixes.NameAttr:      []byte(spaceID),
+			prefixes.SpaceTypeAttr: []byte("project"),
+			prefixes.TreesizeAttr:  []byte("3"),
+		}, true)).To(Succeed())
+
+		filePath := nodePath(fileID)
+		Expect(os.MkdirAll(filepath.Dir(filePath), 0700)).To(Succeed())
+		Expect(os.WriteFile(filePath, nil, 0600)).To(Succeed())
+		Expect(backend.SetMultiple(ctx, testNode{spaceID, fileID, filePath}, map[string][]byte{
+			prefixes.IDAttr:       []byte(fileID),
+			prefixes.ParentidAttr: []byte(spaceID),
+			prefixes.NameAttr:     []byte("file.txt"),
+			prefixes.BlobIDAttr:   []byte(fileBlob),
+			prefixes.BlobsizeAttr: []byte("3"),
+		}, true)).To(Succeed())
+		link, err := filepath.Rel(rootDir, filePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Symlink(link, filepath.Join(rootDir, "file.txt"))).To(Succeed())
+
+		idx := spaceidindex.New(filepath.Join(root, "indexes"), "by-type")
+		Expect(idx.Init()).To(Succeed())
+		Expect(idx.Add("project", spaceID, "../../../spaces/"+lookup.Pathify(spaceID, 1, 2)+"/nodes/"+lookup.Pathify(spaceID, 4, 2))).To(Succeed())
+	})
+
+	It("reports nothing for a consistent storage", func() {
+		bs.blobs = bs.blobs[:1]
+		r := run(false)
+		Expect(r.Issues).To(BeEmpty())
+		Expect(r.Spaces).To(Equal(1))
+		Expect(r.Nodes).To(Equal(2))
+		Expect(r.Blobs).To(Equal(1))
+	})
+
+	It("keeps the blobs of spaces with unreadable metadata", func() {
+		Expect(os.WriteFile(nodePath(fileID)+".mpk", []byte("garbage"), 0600)).To(Succeed())
+
+		r := run(true)
+		Expect(kinds(r)).To(ContainElement(fsck.KindUnreadable))
+		Expect(kinds(r)).ToNot(ContainElement(fsck.KindDanglingBlob))
+		Expect(bs.blobs).To(HaveLen(2))
+	})
+
+	Context("with inconsistencies", func() {
+		BeforeEach(func() {
+			// a child link to a node that is gone
+			Expect(os.Symlink("../../../../../gg/gg/gg/gg/gone", filepath.Join(rootDir, "gone"))).To(Succeed())
+			// a treesize that was not propagated
+			Expect(backend.Set(ctx, testNode{spaceID, spaceID, rootDir}, prefixes.TreesizeAttr, []byte("10"))).To(Succeed())
+			// an index entry for a space that no longer exists
+			idx := spaceidindex.New(filepath.Join(root, "indexes"), "by-user-id")
+			Expect(idx.Init()).To(Succeed())
+			Expect(idx.Add("einstein", staleID, "../../../spaces/dd/ddd")).To(Succeed())
+			// upload data without a session
+			Expect(os.MkdirAll(filepath.Join(root, "uploads"), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "uploads", uploadID), []byte("abc"), 0600)).To(Succeed())
+		})
+
+		It("reports the issues without touching the storage", func() {
+			r := run(false)
+			Expect(kinds(r)).To(ConsistOf(
+				fsck.KindDanglingChild,
+				fsck.KindTreeSize,
+				fsck.KindStaleIndexEntry,
+				fsck.KindOrphanedUpload,
+				fsck.KindDanglingBlob,
+			))
+			Expect(r.Unresolved()).To(Equal(5))
+
+			Expect(run(false).Issues).To(HaveLen(5))
+		})
+
+		It("repairs the issues", func() {
+			r := run(true)
+			Expect(r.Issues).To(HaveLen(5))
+			Expect(r.Unresolved()).To(BeZero())
+
+			Expect(filepath.Join(rootDir, "gone")).ToNot(BeAnExistingFile())
+			Expect(filepath.Join(root, "uploads", uploadID)).ToNot(BeAnExistingFile())
+			Expect(bs.blobs).To(HaveLen(1))
+			treesize, err := backend.Get(ctx, testNode{spaceID, spaceID, rootDir}, prefixes.TreesizeAttr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(treesize)).To(Equal("3"))
+
+			Expect(run(false).Issues).To(BeEmpty())
+		})
+
+		It("reports spaces missing from the space type index", func() {
+			idx := spaceidindex.New(filepath.Join(root, "indexes"), "by-type")
+			Expect(idx.Remove("project", spaceID)).To(Succeed())
+
+			Expect(kinds(run(true))).To(ContainElement(fsck.KindMissingIndexEntry))
+			links, err := idx.Load("project")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(links).To(HaveKey(spaceID))
+		})
+	})
+})
@@ -0,0 +1,197 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fsck
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/spaceidindex"
+	"github.com/pkg/errors"
+)
+
+// discoverSpaces finds the root nodes of all spaces in the storage
+func (c *Checker) discoverSpaces(ctx context.Context) error {
+	switch c.o.Layout {
+	case LayoutPosix:
+		return c.discoverPosixSpaces(ctx)
+	default:
+		return c.discoverDecomposedSpaces(ctx)
+	}
+}
+
+// discoverDecomposedSpaces finds the spaces in /spaces/<pathified spaceid>
+func (c *Checker) discoverDecomposedSpaces(_ context.Context) error {
+	spacesDir := filepath.Join(c.o.Root, "spaces")
+	dirs, err := filepath.Glob(filepath.Join(spacesDir, "*", "*"))
+	if err != nil {
+		return errors.Wrap(err, "fsck: could not list spaces")
+	}
+	for _, d := range dirs {
+		rel, err := filepath.Rel(spacesDir, d)
+		if err != nil {
+			continue
+		}
+		spaceID := strings.ReplaceAll(rel, string(filepath.Separator), "")
+		rootPath := filepath.Join(d, "nodes", lookup.Pathify(spaceID, 4, 2))
+		if !exists(rootPath) {
+			c.issue(Issue{Kind: KindUnreadable, SpaceID: spaceID, Path: d, Message: "space has no root node"}, nil)
+			continue
+		}
+		c.spaceRoots[spaceID] = rootPath
+	}
+	return nil
+}
+
+// discoverPosixSpaces finds the directories whose id equals their space id
+func (c *Checker) discoverPosixSpaces(ctx context.Context) error {
+	root := filepath.Clean(c.o.Root)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if c.ignoredPosixPath(path) {
+			return filepath.SkipDir
+		}
+		spaceID, id, _, _, err := c.backend.IdentifyPath(ctx, path)
+		if err == nil && id != "" && id == spaceID {
+			c.spaceRoots[spaceID] = path
+			// spaces do not nest
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// ignoredPosixPath returns true for paths posixfs does not assimilate
+func (c *Checker) ignoredPosixPath(path string) bool {
+	name := filepath.Base(path)
+	return path == filepath.Join(c.o.Root, "indexes") ||
+		path == filepath.Join(c.o.Root, "uploads") ||
+		name == posixMetadataDir ||
+		name == ".Trash" ||
+		strings.HasSuffix(name, ".trashinfo") ||
+		strings.HasSuffix(name, ".trashitem") ||
+		isLockFile(name)
+}
+
+// indexEntry returns the value the space id indexes store for a space
+func (c *Checker) indexEntry(spaceID string) string {
+	if c.o.Layout == LayoutPosix {
+		return spaceID
+	}
+	return "../../../spaces/" + lookup.Pathify(spaceID, 1, 2) + "/nodes/" + lookup.Pathify(spaceID, 4, 2)
+}
+
+// checkIndexes verifies that every space is listed in the space type index
+// and that the indexes do not reference spaces that no longer exist
+func (c *Checker) checkIndexes(ctx context.Context) error {
+	indexRoot := filepath.Join(c.o.Root, "indexes")
+	typeIndex := spaceidindex.New(indexRoot, "by-type")
+
+	indexed := map[string]bool{}
+	for _, name := range []string{"by-type", "by-user-id", "by-group-id"} {
+		idx := spaceidindex.New(indexRoot, name)
+		keys, err := indexKeys(filepath.Join(indexRoot, name))
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			links, err := idx.Load(key)
+			if err != nil {
+				c.issue(Issue{Kind: KindUnreadable, Path: filepath.Join(indexRoot, name, key+".mpk"), Message: "could not read index: " + err.Error()}, nil)
+				continue
+			}
+			for spaceID := range links {
+				if _, ok := c.spaceRoots[spaceID]; ok {
+					if name == "by-type" {
+						indexed[key+"/"+spaceID] = true
+					}
+					continue
+				}
+				c.issue(Issue{
+					Kind:    KindStaleIndexEntry,
+					SpaceID: spaceID,
+					Path:    filepath.Join(indexRoot, name, key+".mpk"),
+					Message: "index '" + name + "/" + key + "' references a space that does not exist",
+				}, func() error {
+					return idx.Remove(key, spaceID)
+				})
+			}
+		}
+	}
+
+	for spaceID, rootPath := range c.spaceRoots {
+		root := entry{spaceID: spaceID, id: spaceID, path: rootPath}
+		spaceType, err := c.backend.Get(ctx, root, prefixes.SpaceTypeAttr)
+		if err != nil || len(spaceType) == 0 {
+			// not every space root carries a type, e.g. spaces that are being created
+			continue
+		}
+		if indexed[string(spaceType)+"/"+spaceID] {
+			continue
+		}
+		c.issue(Issue{
+			Kind:    KindMissingIndexEntry,
+			SpaceID: spaceID,
+			NodeID:  spaceID,
+			Path:    rootPath,
+			Message: "space is missing from the '" + string(spaceType) + "' space type index",
+		}, func() error {
+			if err := typeIndex.Init(); err != nil {
+				return err
+			}
+			return typeIndex.Add(string(spaceType), spaceID, c.indexEntry(spaceID))
+		})
+	}
+	return nil
+}
+
+// indexKeys lists the index files of a space id index
+func indexKeys(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "fsck: could not list index %s", dir)
+	}
+	keys := []string{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".mpk") {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(e.Name(), ".mpk"))
+	}
+	return keys, nil
+}
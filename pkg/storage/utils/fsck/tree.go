@@ -0,0 +1,288 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fsck
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/lookup"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/pkg/decomposedfs/metadata/prefixes"
+)
+
+// child is an entry linked from a directory
+type child struct {
+	entry
+	// name is the name the child is linked with
+	name string
+}
+
+// nodesDir returns the directory holding the nodes of a decomposedfs space
+func (c *Checker) nodesDir(spaceID string) string {
+	return filepath.Join(c.o.Root, "spaces", lookup.Pathify(spaceID, 1, 2), "nodes")
+}
+
+// checkTree verifies the tree of a space starting at its root node
+func (c *Checker) checkTree(ctx context.Context, spaceID, rootPath string) {
+	root := entry{spaceID: spaceID, id: spaceID, path: rootPath}
+	attrs, err := c.backend.All(ctx, root)
+	if err != nil {
+		c.issue(Issue{Kind: KindUnreadable, SpaceID: spaceID, NodeID: spaceID, Path: rootPath, Message: "could not read space root: " + err.Error()}, nil)
+		return
+	}
+	c.checkDir(ctx, root, attrs)
+
+	if c.o.Layout == LayoutDecomposed && c.blobs != nil {
+		if err := c.collectBlobRefs(ctx, spaceID); err != nil {
+			c.issue(Issue{Kind: KindUnreadable, SpaceID: spaceID, Path: c.nodesDir(spaceID), Message: "could not collect blob references, skipping dangling blobs: " + err.Error()}, nil)
+			return
+		}
+		c.blobRefsComplete[spaceID] = true
+	}
+}
+
+// checkDir verifies the children of a directory and its treesize and
+// tmtime. It returns the size of the tree and its newest tmtime.
+func (c *Checker) checkDir(ctx context.Context, dir entry, attrs map[string][]byte) (uint64, time.Time) {
+	c.report.Nodes++
+
+	var size uint64
+	var newest time.Time
+	for _, ch := range c.children(ctx, dir) {
+		chAttrs, err := c.backend.All(ctx, ch.entry)
+		if err != nil {
+			c.issue(Issue{Kind: KindUnreadable, SpaceID: ch.spaceID, NodeID: ch.id, Path: ch.path, Message: "could not read node metadata: " + err.Error()}, nil)
+			continue
+		}
+		c.checkLink(ctx, dir, ch, chAttrs)
+
+		fi, err := os.Lstat(ch.path)
+		if err != nil {
+			c.issue(Issue{Kind: KindUnreadable, SpaceID: ch.spaceID, NodeID: ch.id, Path: ch.path, Message: "could not stat node: " + err.Error()}, nil)
+			continue
+		}
+		if fi.IsDir() {
+			s, t := c.checkDir(ctx, ch.entry, chAttrs)
+			size += s
+			if t.After(newest) {
+				newest = t
+			}
+			continue
+		}
+
+		c.report.Nodes++
+		size += c.fileSize(chAttrs, fi)
+		if blobID := string(chAttrs[prefixes.BlobIDAttr]); blobID != "" && c.o.Layout == LayoutDecomposed {
+			if c.fileBlobs[ch.spaceID] == nil {
+				c.fileBlobs[ch.spaceID] = map[string]string{}
+			}
+			c.fileBlobs[ch.spaceID][blobID] = ch.id
+		}
+	}
+
+	if v, ok := attrs[prefixes.TreesizeAttr]; ok {
+		treesize, err := strconv.ParseUint(string(v), 10, 64)
+		if err != nil || treesize != size {
+			c.issue(Issue{
+				Kind:    KindTreeSize,
+				SpaceID: dir.spaceID,
+				NodeID:  dir.id,
+				Path:    dir.path,
+				Message: fmt.Sprintf("treesize is '%s' but the children add up to %d", v, size),
+			}, func() error {
+				return c.backend.Set(ctx, dir, prefixes.TreesizeAttr, []byte(strconv.FormatUint(size, 10)))
+			})
+		}
+	}
+
+	tmtime := newest
+	if v, ok := attrs[prefixes.TreeMTimeAttr]; ok {
+		t, err := time.Parse(time.RFC3339Nano, string(v))
+		switch {
+		case err != nil || t.Before(newest):
+			c.issue(Issue{
+				Kind:    KindTreeMTime,
+				SpaceID: dir.spaceID,
+				NodeID:  dir.id,
+				Path:    dir.path,
+				Message: fmt.Sprintf("tmtime '%s' is older than the tmtime '%s' of a child directory", v, newest.UTC().Format(time.RFC3339Nano)),
+			}, func() error {
+				return c.backend.Set(ctx, dir, prefixes.TreeMTimeAttr, []byte(newest.UTC().Format(time.RFC3339Nano)))
+			})
+		default:
+			tmtime = t
+		}
+	}
+	return size, tmtime
+}
+
+// checkLink verifies that a child points back to the directory it is linked from
+func (c *Checker) checkLink(ctx context.Context, dir entry, ch child, attrs map[string][]byte) {
+	if parentID := string(attrs[prefixes.ParentidAttr]); parentID != dir.id {
+		c.issue(Issue{
+			Kind:    KindParentMismatch,
+			SpaceID: ch.spaceID,
+			NodeID:  ch.id,
+			Path:    ch.path,
+			Message: fmt.Sprintf("parentid is '%s' but the node is linked from '%s'", parentID, dir.id),
+		}, func() error {
+			return c.backend.Set(ctx, ch.entry, prefixes.ParentidAttr, []byte(dir.id))
+		})
+	}
+	// posixfs uses the name of the file on disk
+	if c.o.Layout != LayoutDecomposed {
+		return
+	}
+	if name := string(attrs[prefixes.NameAttr]); name != ch.name {
+		c.issue(Issue{
+			Kind:    KindNameMismatch,
+			SpaceID: ch.spaceID,
+			NodeID:  ch.id,
+			Path:    ch.path,
+			Message: fmt.Sprintf("name is '%s' but the node is linked as '%s'", name, ch.name),
+		}, func() error {
+			return c.backend.Set(ctx, ch.entry, prefixes.NameAttr, []byte(ch.name))
+		})
+	}
+}
+
+// fileSize returns the size a file contributes to the treesize of its parent
+func (c *Checker) fileSize(attrs map[string][]byte, fi os.FileInfo) uint64 {
+	if c.o.Layout == LayoutPosix {
+		return uint64(fi.Size())
+	}
+	size, _ := strconv.ParseUint(string(attrs[prefixes.BlobsizeAttr]), 10, 64)
+	return size
+}
+
+// children returns the entries linked from a directory
+func (c *Checker) children(ctx context.Context, dir entry) []child {
+	entries, err := os.ReadDir(dir.path)
+	if err != nil {
+		c.issue(Issue{Kind: KindUnreadable, SpaceID: dir.spaceID, NodeID: dir.id, Path: dir.path, Message: "could not list directory: " + err.Error()}, nil)
+		return nil
+	}
+
+	children := make([]child, 0, len(entries))
+	for _, e := range entries {
+		path := filepath.Join(dir.path, e.Name())
+		switch c.o.Layout {
+		case LayoutPosix:
+			if c.ignoredPosixPath(path) {
+				continue
+			}
+			spaceID, id, _, _, err := c.backend.IdentifyPath(ctx, path)
+			if err != nil || id == "" {
+				c.issue(Issue{Kind: KindMissingID, SpaceID: dir.spaceID, Path: path, Message: "entry has not been assimilated yet"}, nil)
+				continue
+			}
+			if spaceID == "" {
+				spaceID = dir.spaceID
+			}
+			children = append(children, child{entry: entry{spaceID: spaceID, id: id, path: path}, name: e.Name()})
+		default:
+			// decomposedfs links children with symlinks to their node
+			if e.Type()&fs.ModeSymlink == 0 {
+				continue
+			}
+			link, err := os.Readlink(path)
+			if err != nil {
+				c.issue(Issue{Kind: KindUnreadable, SpaceID: dir.spaceID, NodeID: dir.id, Path: path, Message: "could not read child link: " + err.Error()}, nil)
+				continue
+			}
+			target := link
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir.path, link)
+			}
+			if !exists(target) {
+				c.issue(Issue{
+					Kind:    KindDanglingChild,
+					SpaceID: dir.spaceID,
+					NodeID:  dir.id,
+					Path:    path,
+					Message: fmt.Sprintf("child '%s' links to non existing node '%s'", e.Name(), link),
+				}, func() error {
+					return os.Remove(path)
+				})
+				continue
+			}
+			rel, err := filepath.Rel(c.nodesDir(dir.spaceID), target)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				c.issue(Issue{Kind: KindUnreadable, SpaceID: dir.spaceID, NodeID: dir.id, Path: path, Message: fmt.Sprintf("child '%s' links outside of the space", e.Name())}, nil)
+				continue
+			}
+			id := strings.ReplaceAll(rel, string(filepath.Separator), "")
+			children = append(children, child{entry: entry{spaceID: dir.spaceID, id: id, path: target}, name: e.Name()})
+		}
+	}
+	return children
+}
+
+// collectBlobRefs records the blobs referenced by any node of a space,
+// including revisions and trashed nodes. It returns an error if a directory
+// or the metadata of a node could not be read, the recorded references are
+// incomplete then.
+func (c *Checker) collectBlobRefs(ctx context.Context, spaceID string) error {
+	nodesDir := c.nodesDir(spaceID)
+	if c.blobRefs[spaceID] == nil {
+		c.blobRefs[spaceID] = map[string]struct{}{}
+	}
+	refs := c.blobRefs[spaceID]
+
+	return filepath.WalkDir(nodesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == nodesDir {
+			return nil
+		}
+		rel, err := filepath.Rel(nodesDir, path)
+		if err != nil {
+			return err
+		}
+		// the first four levels are the pathified node id, the fifth is the node itself
+		if strings.Count(rel, string(filepath.Separator)) < 4 {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink == 0 && !c.backend.IsMetaFile(path) && !isLockFile(d.Name()) {
+			n := entry{spaceID: spaceID, id: strings.ReplaceAll(rel, string(filepath.Separator), ""), path: path}
+			blobID, err := c.backend.Get(ctx, n, prefixes.BlobIDAttr)
+			switch {
+			case err == nil:
+				if len(blobID) > 0 {
+					refs[string(blobID)] = struct{}{}
+				}
+			case !metadata.IsAttrUnset(err):
+				return err
+			}
+		}
+		if d.IsDir() {
+			// child links of directories are checked by the tree walk
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
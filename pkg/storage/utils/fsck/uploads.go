@@ -0,0 +1,128 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fsck
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
+)
+
+// checkUploads reports upload sessions that can never be finished: sessions
+// of spaces that no longer exist, sessions whose data is gone, sessions
+// that expired before all bytes were received and data without a session.
+func (c *Checker) checkUploads(_ context.Context) error {
+	dir := filepath.Join(c.o.Root, "uploads")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "fsck: could not list uploads")
+	}
+
+	infos := map[string]bool{}
+	bins := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() || isLockFile(e.Name()) {
+			continue
+		}
+		if id, ok := strings.CutSuffix(e.Name(), ".info"); ok {
+			infos[id] = true
+			continue
+		}
+		// chunking and other temporary files share the directory, only session ids are considered
+		if _, err := uuid.Parse(e.Name()); err == nil {
+			bins[e.Name()] = true
+		}
+	}
+
+	for id := range infos {
+		c.report.Uploads++
+		infoPath := filepath.Join(dir, id+".info")
+		binPath := filepath.Join(dir, id)
+		purge := func() error {
+			if err := os.Remove(binPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return os.Remove(infoPath)
+		}
+
+		info := tusd.FileInfo{}
+		data, err := os.ReadFile(infoPath)
+		if err == nil {
+			err = json.Unmarshal(data, &info)
+		}
+		if err != nil {
+			c.issue(Issue{Kind: KindOrphanedUpload, NodeID: id, Path: infoPath, Message: "upload session info is unreadable: " + err.Error()}, purge)
+			continue
+		}
+
+		spaceID := info.Storage["SpaceRoot"]
+		var reason string
+		switch {
+		case spaceID != "" && c.spaceRoots[spaceID] == "":
+			reason = "the space of the upload does not exist"
+		case !bins[id]:
+			reason = "the data of the upload is missing"
+		case info.Offset < info.Size && c.expired(info):
+			reason = "the upload expired before all bytes were received"
+		}
+		if reason == "" {
+			// the blob of an upload is stored with the session id
+			if spaceID != "" {
+				if c.blobRefs[spaceID] == nil {
+					c.blobRefs[spaceID] = map[string]struct{}{}
+				}
+				c.blobRefs[spaceID][id] = struct{}{}
+			}
+			continue
+		}
+		c.issue(Issue{Kind: KindOrphanedUpload, SpaceID: spaceID, NodeID: info.Storage["NodeId"], Path: infoPath, Message: reason}, purge)
+	}
+
+	for id := range bins {
+		if infos[id] {
+			continue
+		}
+		c.report.Uploads++
+		binPath := filepath.Join(dir, id)
+		c.issue(Issue{Kind: KindOrphanedUpload, Path: binPath, Message: "upload data has no session info"}, func() error {
+			return os.Remove(binPath)
+		})
+	}
+	return nil
+}
+
+// expired returns true if the session has an expiry in the past
+func (c *Checker) expired(info tusd.FileInfo) bool {
+	v, ok := info.MetaData["expires"]
+	if !ok {
+		return false
+	}
+	t, err := utils.MTimeToTime(v)
+	return err == nil && !t.IsZero() && t.Before(c.now())
+}
@@ -0,0 +1,142 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package retention
+
+import (
+	"sort"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// ThinningRule keeps at most one revision per Interval among the revisions younger than MaxAge.
+// A MaxAge of 0 applies the rule to all revisions older than the ones covered by other rules.
+type ThinningRule struct {
+	MaxAge   time.Duration `mapstructure:"max_age" docs:"0;The rule applies to revisions younger than this duration."`
+	Interval time.Duration `mapstructure:"interval" docs:"0;Only one revision is kept per interval."`
+}
+
+// DefaultThinningRules mimic the version expiration of Nextcloud: all revisions of the last second,
+// one per 2 seconds for the first 10 seconds, one per 10 seconds for the first minute, one per minute
+// for the first hour, one per hour for the first day, one per day for the first 30 days and one per
+// week for everything older.
+var DefaultThinningRules = []ThinningRule{
+	{MaxAge: 10 * time.Second, Interval: 2 * time.Second},
+	{MaxAge: time.Minute, Interval: 10 * time.Second},
+	{MaxAge: time.Hour, Interval: time.Minute},
+	{MaxAge: 24 * time.Hour, Interval: time.Hour},
+	{MaxAge: 30 * 24 * time.Hour, Interval: 24 * time.Hour},
+	{MaxAge: 0, Interval: 7 * 24 * time.Hour},
+}
+
+// RevisionPolicy describes which revisions of a file are kept
+type RevisionPolicy struct {
+	KeepLast        int            `mapstructure:"keep_last" docs:"0;The number of newest revisions that are always kept."`
+	MaxCount        int            `mapstructure:"max_count" docs:"0;The maximum number of revisions kept per file. 0 disables the limit."`
+	MaxAge          time.Duration  `mapstructure:"max_age" docs:"0;Revisions older than this duration are removed. 0 disables the age limit."`
+	Thinning        []ThinningRule `mapstructure:"thinning" docs:"nil;Rules to thin out revisions the older they get."`
+	DefaultThinning bool           `mapstructure:"default_thinning" docs:"false;Use thinning rules similar to the version expiration of Nextcloud when no rules are configured."`
+}
+
+// Enabled returns true when the policy removes revisions at all
+func (p RevisionPolicy) Enabled() bool {
+	return p.MaxCount > 0 || p.MaxAge > 0 || len(p.rules()) > 0
+}
+
+// Expired returns the revisions that have to be removed to satisfy the policy.
+// The revisions are evaluated from the newest to the oldest. The KeepLast newest
+// revisions are always kept, the others are removed when they exceed MaxCount or
+// MaxAge or when a newer revision was kept within the interval of the thinning
+// rule matching their age.
+func (p RevisionPolicy) Expired(revisions []*provider.FileVersion, now time.Time) []*provider.FileVersion {
+	if !p.Enabled() || len(revisions) == 0 {
+		return nil
+	}
+
+	sorted := make([]*provider.FileVersion, len(revisions))
+	copy(sorted, revisions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetMtime() > sorted[j].GetMtime()
+	})
+
+	rules := p.rules()
+	expired := []*provider.FileVersion{}
+	kept := 0
+	var lastKept time.Time
+	for i, rev := range sorted {
+		mtime := time.Unix(int64(rev.GetMtime()), 0)
+		age := now.Sub(mtime)
+
+		expire := false
+		switch {
+		case i < p.KeepLast:
+			// always keep
+		case p.MaxCount > 0 && kept >= p.MaxCount:
+			expire = true
+		case p.MaxAge > 0 && age > p.MaxAge:
+			expire = true
+		case kept > 0:
+			if rule, ok := matchingRule(rules, age); ok && lastKept.Sub(mtime) < rule.Interval {
+				expire = true
+			}
+		}
+
+		if expire {
+			expired = append(expired, rev)
+			continue
+		}
+		kept++
+		lastKept = mtime
+	}
+	return expired
+}
+
+// rules returns the thinning rules ordered by their max age, open ended rules last
+func (p RevisionPolicy) rules() []ThinningRule {
+	rules := p.Thinning
+	if len(rules) == 0 && p.DefaultThinning {
+		rules = DefaultThinningRules
+	}
+	sorted := make([]ThinningRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Interval > 0 {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		switch {
+		case sorted[i].MaxAge == 0:
+			return false
+		case sorted[j].MaxAge == 0:
+			return true
+		default:
+			return sorted[i].MaxAge < sorted[j].MaxAge
+		}
+	})
+	return sorted
+}
+
+func matchingRule(rules []ThinningRule, age time.Duration) (ThinningRule, bool) {
+	for _, r := range rules {
+		if r.MaxAge == 0 || age <= r.MaxAge {
+			return r, true
+		}
+	}
+	return ThinningRule{}, false
+}
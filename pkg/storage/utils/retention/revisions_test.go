@@ -0,0 +1,97 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package retention_test
+
+import (
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RevisionPolicy", func() {
+	var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	rev := func(key string, age time.Duration) *provider.FileVersion {
+		return &provider.FileVersion{
+			Key:   key,
+			Mtime: uint64(now.Add(-age).Unix()),
+		}
+	}
+	keys := func(revs []*provider.FileVersion) []string {
+		k := []string{}
+		for _, r := range revs {
+			k = append(k, r.Key)
+		}
+		return k
+	}
+
+	It("expires nothing when disabled", func() {
+		p := retention.RevisionPolicy{KeepLast: 3}
+		Expect(p.Enabled()).To(BeFalse())
+		Expect(p.Expired([]*provider.FileVersion{rev("a", time.Hour)}, now)).To(BeEmpty())
+	})
+
+	It("keeps at most max count revisions", func() {
+		p := retention.RevisionPolicy{MaxCount: 2}
+		revs := []*provider.FileVersion{rev("c", 3*time.Hour), rev("a", time.Hour), rev("b", 2*time.Hour)}
+		Expect(keys(p.Expired(revs, now))).To(ConsistOf("c"))
+	})
+
+	It("expires revisions older than max age but keeps the last ones", func() {
+		p := retention.RevisionPolicy{MaxAge: 24 * time.Hour, KeepLast: 1}
+		revs := []*provider.FileVersion{rev("a", 48*time.Hour), rev("b", 72*time.Hour)}
+		Expect(keys(p.Expired(revs, now))).To(ConsistOf("b"))
+	})
+
+	It("thins out revisions per interval", func() {
+		p := retention.RevisionPolicy{
+			Thinning: []retention.ThinningRule{
+				{MaxAge: 0, Interval: 24 * time.Hour},
+				{MaxAge: time.Hour, Interval: 10 * time.Minute},
+			},
+		}
+		revs := []*provider.FileVersion{
+			rev("1m", time.Minute),
+			rev("5m", 5*time.Minute),
+			rev("15m", 15*time.Minute),
+			rev("2h", 2*time.Hour),
+			rev("3h", 3*time.Hour),
+			rev("30h", 30*time.Hour),
+		}
+		Expect(keys(p.Expired(revs, now))).To(ConsistOf("5m", "2h", "3h"))
+	})
+
+	It("uses the default thinning rules when requested", func() {
+		p := retention.RevisionPolicy{DefaultThinning: true}
+		Expect(p.Enabled()).To(BeTrue())
+
+		revs := []*provider.FileVersion{}
+		// one revision per hour for the last three days
+		for i := 1; i <= 72; i++ {
+			revs = append(revs, rev(time.Duration(i*int(time.Hour)).String(), time.Duration(i)*time.Hour))
+		}
+		expired := p.Expired(revs, now)
+		// all revisions of the first day are kept, then one per day
+		Expect(len(revs) - len(expired)).To(Equal(24 + 2))
+	})
+})
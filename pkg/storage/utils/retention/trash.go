@@ -0,0 +1,90 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package retention
+
+import (
+	"sort"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+)
+
+// TrashPolicy limits how long and how much data is kept in the trash bin of a space
+type TrashPolicy struct {
+	MaxAge  time.Duration `mapstructure:"max_age" docs:"0;Items deleted longer ago than this duration are purged. 0 disables the age limit."`
+	MaxSize uint64        `mapstructure:"max_size" docs:"0;Maximum number of bytes kept in the trash bin. The oldest items are purged first. 0 disables the size limit."`
+}
+
+// Enabled returns true when the policy limits the trash bin in any way
+func (p TrashPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxSize > 0
+}
+
+// Expired returns the items that have to be purged to satisfy the policy.
+// Items deleted before now-MaxAge are always expired. If the size of the remaining
+// items exceeds MaxSize the oldest of them are expired until the newer ones fit.
+// Items without a deletion time are treated as the oldest ones.
+func (p TrashPolicy) Expired(items []*provider.RecycleItem, now time.Time) []*provider.RecycleItem {
+	if !p.Enabled() || len(items) == 0 {
+		return nil
+	}
+
+	// sort a copy, newest items first
+	sorted := make([]*provider.RecycleItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return deletionTime(sorted[i]).After(deletionTime(sorted[j]))
+	})
+
+	expired := []*provider.RecycleItem{}
+	var kept uint64
+	full := false
+	for _, item := range sorted {
+		switch {
+		case full:
+			expired = append(expired, item)
+		case p.MaxAge > 0 && now.Sub(deletionTime(item)) > p.MaxAge:
+			expired = append(expired, item)
+		case p.MaxSize > 0 && kept+item.GetSize() > p.MaxSize:
+			// this and all older items have to go
+			full = true
+			expired = append(expired, item)
+		default:
+			kept += item.GetSize()
+		}
+	}
+	return expired
+}
+
+// Size returns the number of bytes used by the given items
+func Size(items []*provider.RecycleItem) uint64 {
+	var size uint64
+	for _, item := range items {
+		size += item.GetSize()
+	}
+	return size
+}
+
+func deletionTime(item *provider.RecycleItem) time.Time {
+	if item.GetDeletionTime() == nil {
+		return time.Time{}
+	}
+	return utils.TSToTime(item.GetDeletionTime())
+}
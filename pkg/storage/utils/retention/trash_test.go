@@ -0,0 +1,94 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package retention_test
+
+import (
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/opencloud-eu/reva/v2/pkg/storage/utils/retention"
+	"github.com/opencloud-eu/reva/v2/pkg/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrashPolicy", func() {
+	var (
+		now   = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		items []*provider.RecycleItem
+	)
+
+	item := func(key string, age time.Duration, size uint64) *provider.RecycleItem {
+		return &provider.RecycleItem{
+			Key:          key,
+			Size:         size,
+			DeletionTime: utils.TimeToTS(now.Add(-age)),
+		}
+	}
+	keys := func(items []*provider.RecycleItem) []string {
+		k := []string{}
+		for _, i := range items {
+			k = append(k, i.Key)
+		}
+		return k
+	}
+
+	BeforeEach(func() {
+		items = []*provider.RecycleItem{
+			item("new", time.Hour, 10),
+			item("old", 30*24*time.Hour, 20),
+			item("mid", 7*24*time.Hour, 30),
+		}
+	})
+
+	It("expires nothing when disabled", func() {
+		p := retention.TrashPolicy{}
+		Expect(p.Enabled()).To(BeFalse())
+		Expect(p.Expired(items, now)).To(BeEmpty())
+	})
+
+	It("expires items older than the max age", func() {
+		p := retention.TrashPolicy{MaxAge: 14 * 24 * time.Hour}
+		Expect(keys(p.Expired(items, now))).To(ConsistOf("old"))
+	})
+
+	It("expires the oldest items exceeding the max size", func() {
+		p := retention.TrashPolicy{MaxSize: 45}
+		Expect(keys(p.Expired(items, now))).To(ConsistOf("old"))
+
+		p = retention.TrashPolicy{MaxSize: 35}
+		Expect(keys(p.Expired(items, now))).To(ConsistOf("mid", "old"))
+	})
+
+	It("combines age and size limits", func() {
+		p := retention.TrashPolicy{MaxAge: 14 * 24 * time.Hour, MaxSize: 5}
+		Expect(keys(p.Expired(items, now))).To(ConsistOf("new", "mid", "old"))
+	})
+
+	It("treats items without deletion time as the oldest", func() {
+		items = append(items, &provider.RecycleItem{Key: "unknown", Size: 1})
+		p := retention.TrashPolicy{MaxSize: 60}
+		Expect(keys(p.Expired(items, now))).To(ConsistOf("unknown"))
+	})
+
+	It("sums up the item sizes", func() {
+		Expect(retention.Size(items)).To(Equal(uint64(60)))
+	})
+})
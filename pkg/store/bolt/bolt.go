@@ -0,0 +1,458 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package bolt implements a go-micro store persisted in a local bbolt
+// database file. It is meant for single node installations that want their
+// caches and id mappings to survive restarts without running a separate
+// key-value server.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultPath is the database file used when no node is configured.
+	DefaultPath = "/var/tmp/reva/store.db"
+
+	defaultDatabase = "micro"
+	defaultTable    = "micro"
+
+	// sweepInterval is the interval in which expired records are removed
+	// from the database file.
+	sweepInterval = time.Minute
+)
+
+// handles keeps the open databases by path. bbolt locks the database file,
+// so all stores using the same file share one handle.
+var (
+	handlesMu sync.Mutex
+	handles   = map[string]*handle{}
+)
+
+type handle struct {
+	db   *bolt.DB
+	refs int
+	stop chan struct{}
+}
+
+type defaultTTLContextKey struct{}
+
+// DefaultTTL sets the time to live of records written without an expiry.
+func DefaultTTL(ttl time.Duration) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, defaultTTLContextKey{}, ttl)
+	}
+}
+
+// record is the representation of a store record in the database.
+type record struct {
+	Value    []byte                 `json:"v"`
+	Metadata map[string]interface{} `json:"m,omitempty"`
+	// ExpiresAt is the expiration as unix nanoseconds, 0 means the record never expires
+	ExpiresAt int64 `json:"e,omitempty"`
+}
+
+func (r *record) expired(now time.Time) bool {
+	return r.ExpiresAt != 0 && now.UnixNano() >= r.ExpiresAt
+}
+
+// Store is a go-micro store backed by a bbolt database. Databases and tables
+// are mapped to nested buckets.
+type Store struct {
+	options    store.Options
+	defaultTTL time.Duration
+	path       string
+	db         *bolt.DB
+	err        error
+}
+
+// NewStore creates a new go-micro store persisted in the database file given
+// as first node.
+func NewStore(opts ...store.Option) (store.Store, error) {
+	s := &Store{}
+	if err := s.Init(opts...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Init opens the database file. A previously opened file is released.
+func (s *Store) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	if s.options.Database == "" {
+		s.options.Database = defaultDatabase
+	}
+	if s.options.Table == "" {
+		s.options.Table = defaultTable
+	}
+	if s.options.Context != nil {
+		s.defaultTTL, _ = s.options.Context.Value(defaultTTLContextKey{}).(time.Duration)
+	}
+
+	path := DefaultPath
+	if len(s.options.Nodes) > 0 && s.options.Nodes[0] != "" {
+		path = s.options.Nodes[0]
+	}
+	if s.db != nil {
+		if path == s.path {
+			return nil
+		}
+		_ = release(s.path)
+		s.db = nil
+	}
+
+	s.path = path
+	s.db, s.err = acquire(path)
+	return s.err
+}
+
+func acquire(path string) (*bolt.DB, error) {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+
+	if h, ok := handles[path]; ok {
+		h.refs++
+		return h.db, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, errors.Wrap(err, "bolt: error creating database directory")
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "bolt: error opening database "+path)
+	}
+	h := &handle{db: db, refs: 1, stop: make(chan struct{})}
+	handles[path] = h
+	go sweep(h)
+	return db, nil
+}
+
+func release(path string) error {
+	handlesMu.Lock()
+	defer handlesMu.Unlock()
+
+	h, ok := handles[path]
+	if !ok {
+		return nil
+	}
+	h.refs--
+	if h.refs > 0 {
+		return nil
+	}
+	delete(handles, path)
+	close(h.stop)
+	return h.db.Close()
+}
+
+// sweep periodically removes the expired records of a database.
+func sweep(h *handle) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			_ = removeExpired(h.db, time.Now())
+		}
+	}
+}
+
+func removeExpired(db *bolt.DB, now time.Time) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, database *bolt.Bucket) error {
+			return database.ForEachBucket(func(name []byte) error {
+				table := database.Bucket(name)
+				var expired [][]byte
+				err := table.ForEach(func(k, v []byte) error {
+					r := &record{}
+					if err := json.Unmarshal(v, r); err != nil || r.expired(now) {
+						expired = append(expired, k)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				for _, k := range expired {
+					if err := table.Delete(k); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	})
+}
+
+// Options returns the store options.
+func (s *Store) Options() store.Options {
+	return s.options
+}
+
+func (s *Store) namespace(database, table string) (string, string) {
+	if database == "" {
+		database = s.options.Database
+	}
+	if table == "" {
+		table = s.options.Table
+	}
+	return database, table
+}
+
+func (s *Store) bucket(tx *bolt.Tx, database, table string) *bolt.Bucket {
+	db := tx.Bucket([]byte(database))
+	if db == nil {
+		return nil
+	}
+	return db.Bucket([]byte(table))
+}
+
+// Write stores a record. The expiry is taken from the TTL or Expiry write
+// options, the expiry of the record or the default TTL of the store, in that
+// order.
+func (s *Store) Write(r *store.Record, opts ...store.WriteOption) error {
+	if s.err != nil {
+		return s.err
+	}
+	wopts := store.WriteOptions{}
+	for _, o := range opts {
+		o(&wopts)
+	}
+	database, table := s.namespace(wopts.Database, wopts.Table)
+
+	rec := record{Value: r.Value, Metadata: r.Metadata}
+	switch {
+	case wopts.TTL != 0:
+		rec.ExpiresAt = time.Now().Add(wopts.TTL).UnixNano()
+	case !wopts.Expiry.IsZero():
+		rec.ExpiresAt = wopts.Expiry.UnixNano()
+	case r.Expiry != 0:
+		rec.ExpiresAt = time.Now().Add(r.Expiry).UnixNano()
+	case s.defaultTTL != 0:
+		rec.ExpiresAt = time.Now().Add(s.defaultTTL).UnixNano()
+	}
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		db, err := tx.CreateBucketIfNotExists([]byte(database))
+		if err != nil {
+			return err
+		}
+		b, err := db.CreateBucketIfNotExists([]byte(table))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(r.Key), value)
+	})
+}
+
+// Read returns the record with the key or, with the prefix or suffix
+// options, all records matching the key. Records are returned sorted by key.
+func (s *Store) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	ropts := store.ReadOptions{}
+	for _, o := range opts {
+		o(&ropts)
+	}
+	database, table := s.namespace(ropts.Database, ropts.Table)
+	now := time.Now()
+
+	var records []*store.Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := s.bucket(tx, database, table)
+		if b == nil {
+			return nil
+		}
+
+		if !ropts.Prefix && !ropts.Suffix {
+			v := b.Get([]byte(key))
+			if v == nil {
+				return nil
+			}
+			r, err := decode(key, v, now)
+			if err != nil || r == nil {
+				return err
+			}
+			records = append(records, r)
+			return nil
+		}
+
+		var prefix, suffix string
+		if ropts.Prefix {
+			prefix = key
+		}
+		if ropts.Suffix {
+			suffix = key
+		}
+		var skipped uint
+		return scan(b, prefix, suffix, func(k, v []byte) (bool, error) {
+			r, err := decode(string(k), v, now)
+			if err != nil || r == nil {
+				return false, err
+			}
+			if skipped < ropts.Offset {
+				skipped++
+				return false, nil
+			}
+			records = append(records, r)
+			return ropts.Limit > 0 && uint(len(records)) >= ropts.Limit, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 && !ropts.Prefix && !ropts.Suffix {
+		return nil, store.ErrNotFound
+	}
+	return records, nil
+}
+
+// Delete removes the record with the key.
+func (s *Store) Delete(key string, opts ...store.DeleteOption) error {
+	if s.err != nil {
+		return s.err
+	}
+	dopts := store.DeleteOptions{}
+	for _, o := range opts {
+		o(&dopts)
+	}
+	database, table := s.namespace(dopts.Database, dopts.Table)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx, database, table)
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// List returns the keys of the records that have not expired, sorted.
+func (s *Store) List(opts ...store.ListOption) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	lopts := store.ListOptions{}
+	for _, o := range opts {
+		o(&lopts)
+	}
+	database, table := s.namespace(lopts.Database, lopts.Table)
+	now := time.Now()
+
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := s.bucket(tx, database, table)
+		if b == nil {
+			return nil
+		}
+		var skipped uint
+		return scan(b, lopts.Prefix, lopts.Suffix, func(k, v []byte) (bool, error) {
+			r := &record{}
+			if err := json.Unmarshal(v, r); err != nil {
+				return false, errors.Wrap(err, "bolt: error decoding record "+string(k))
+			}
+			if r.expired(now) {
+				return false, nil
+			}
+			if skipped < lopts.Offset {
+				skipped++
+				return false, nil
+			}
+			keys = append(keys, string(k))
+			return lopts.Limit > 0 && uint(len(keys)) >= lopts.Limit, nil
+		})
+	})
+	return keys, err
+}
+
+// scan calls fn for the entries of a bucket whose keys have the prefix and
+// the suffix, in key order, until fn returns true.
+func scan(b *bolt.Bucket, prefix, suffix string, fn func(k, v []byte) (bool, error)) error {
+	c := b.Cursor()
+	for k, v := c.Seek([]byte(prefix)); k != nil; k, v = c.Next() {
+		if !bytes.HasPrefix(k, []byte(prefix)) {
+			break
+		}
+		if !bytes.HasSuffix(k, []byte(suffix)) {
+			continue
+		}
+		done, err := fn(k, v)
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode returns the store record for a database value, or nil if the record
+// expired.
+func decode(key string, v []byte, now time.Time) (*store.Record, error) {
+	r := &record{}
+	if err := json.Unmarshal(v, r); err != nil {
+		return nil, errors.Wrap(err, "bolt: error decoding record "+key)
+	}
+	if r.expired(now) {
+		return nil, nil
+	}
+	rec := &store.Record{
+		Key:      key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+	if r.ExpiresAt != 0 {
+		rec.Expiry = time.Unix(0, r.ExpiresAt).Sub(now)
+	}
+	return rec, nil
+}
+
+// Close releases the database file.
+func (s *Store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	s.db, s.err = nil, errors.New("bolt: store closed")
+	return release(s.path)
+}
+
+// String returns the name of the implementation.
+func (s *Store) String() string {
+	return "bolt"
+}
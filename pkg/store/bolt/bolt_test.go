@@ -0,0 +1,178 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package bolt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-micro.dev/v4/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStore(t *testing.T, path string, opts ...store.Option) store.Store {
+	s, err := NewStore(append(opts, store.Nodes(path))...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func keys(records []*store.Record) []string {
+	k := make([]string, 0, len(records))
+	for _, r := range records {
+		k = append(k, r.Key)
+	}
+	return k
+}
+
+func TestReadWrite(t *testing.T) {
+	s := newTestStore(t, filepath.Join(t.TempDir(), "store.db"))
+	for _, k := range []string{"abaya", "abaaz", "abayakjdkj", "zzzz", "abazzz", "mbzzaamb"} {
+		require.NoError(t, s.Write(&store.Record{Key: k, Value: []byte("v-" + k), Metadata: map[string]interface{}{"key": k}}))
+	}
+
+	records, err := s.Read("abaya")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("v-abaya"), records[0].Value)
+	assert.Equal(t, "abaya", records[0].Metadata["key"])
+
+	_, err = s.Read("missing")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	records, err = s.Read("aba", store.ReadPrefix())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abaaz", "abaya", "abayakjdkj", "abazzz"}, keys(records))
+
+	records, err = s.Read("zz", store.ReadSuffix())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abazzz", "zzzz"}, keys(records))
+
+	records, err = s.Read("aba", store.ReadPrefix(), store.ReadOffset(1), store.ReadLimit(2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abaya", "abayakjdkj"}, keys(records))
+
+	records, err = s.Read("none", store.ReadPrefix())
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	listed, err := s.List(store.ListPrefix("aba"), store.ListSuffix("z"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abaaz", "abazzz"}, listed)
+
+	listed, err = s.List(store.ListOffset(2), store.ListLimit(3))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abayakjdkj", "abazzz", "mbzzaamb"}, listed)
+
+	require.NoError(t, s.Delete("abaya"))
+	_, err = s.Read("abaya")
+	assert.Equal(t, store.ErrNotFound, err)
+	require.NoError(t, s.Delete("abaya"))
+}
+
+func TestNamespaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	a := newTestStore(t, path, store.Database("a"), store.Table("t"))
+	b := newTestStore(t, path, store.Database("b"), store.Table("t"))
+
+	require.NoError(t, a.Write(&store.Record{Key: "key", Value: []byte("a")}))
+	require.NoError(t, b.Write(&store.Record{Key: "key", Value: []byte("b")}))
+	require.NoError(t, a.Write(&store.Record{Key: "key", Value: []byte("other")}, store.WriteTo("a", "other")))
+
+	records, err := a.Read("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), records[0].Value)
+	records, err = b.Read("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), records[0].Value)
+	records, err = b.Read("key", store.ReadFrom("a", "other"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("other"), records[0].Value)
+
+	require.NoError(t, a.Delete("key"))
+	_, err = a.Read("key")
+	assert.Equal(t, store.ErrNotFound, err)
+	_, err = b.Read("key")
+	assert.NoError(t, err)
+}
+
+func TestExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	s := newTestStore(t, path, DefaultTTL(time.Hour))
+
+	require.NoError(t, s.Write(&store.Record{Key: "ttl", Value: []byte("v")}, store.WriteTTL(20*time.Millisecond)))
+	require.NoError(t, s.Write(&store.Record{Key: "expiry", Value: []byte("v"), Expiry: 20 * time.Millisecond}))
+	require.NoError(t, s.Write(&store.Record{Key: "default", Value: []byte("v")}))
+
+	records, err := s.Read("default")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour, records[0].Expiry, float64(time.Minute))
+
+	time.Sleep(50 * time.Millisecond)
+	_, err = s.Read("ttl")
+	assert.Equal(t, store.ErrNotFound, err)
+	_, err = s.Read("expiry")
+	assert.Equal(t, store.ErrNotFound, err)
+	listed, err := s.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, listed)
+
+	// expired records are removed from the file
+	db := s.(*Store).db
+	require.NoError(t, removeExpired(db, time.Now()))
+	require.NoError(t, db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 1, tx.Bucket([]byte(defaultDatabase)).Bucket([]byte(defaultTable)).Stats().KeyN)
+		return nil
+	}))
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "store.db")
+	s, err := NewStore(store.Nodes(path))
+	require.NoError(t, err)
+	require.NoError(t, s.Write(&store.Record{Key: "key", Value: []byte("value")}))
+
+	// a second store on the same file shares the database
+	other := newTestStore(t, path)
+	require.NoError(t, s.Close())
+	_, err = s.Read("key")
+	assert.Error(t, err)
+	records, err := other.Read("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), records[0].Value)
+	require.NoError(t, other.Close())
+
+	reopened := newTestStore(t, path)
+	records, err = reopened.Read("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), records[0].Value)
+}
+
+func TestNewStoreError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0600))
+
+	// the parent of the database is a file, the database can't be created
+	_, err := NewStore(store.Nodes(filepath.Join(file, "store.db")))
+	assert.Error(t, err)
+}
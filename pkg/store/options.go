@@ -34,8 +34,11 @@ type typeContextKey struct{}
 //   - "nats-js" for nats-js, needs to have TTL configured at creation
 //   - "redis", for redis
 //   - "redis-sentinel", for redis-sentinel
+//   - "redis-cluster", for redis cluster, the nodes are the seed nodes of the cluster
 //   - "ocmem", custom in-memory implementation, with fixed size and optimized prefix
 //     and suffix search
+//   - "bolt", for an embedded store persisted in the database file given as first node,
+//     the file is locked by one process, so it can't be shared between instances
 func Store(val string) store.Option {
 	return func(o *store.Options) {
 		if o.Context == nil {
@@ -66,6 +69,7 @@ func Size(val int) store.Option {
 type ttlContextKey struct{}
 
 // TTL is the time to live for documents stored in the store
+// Only supported by the `natsjs`, `natsjskv`, `bolt` and `redis-cluster` implementations.
 func TTL(val time.Duration) store.Option {
 	return func(o *store.Options) {
 		if o.Context == nil {
@@ -93,7 +97,7 @@ func DisablePersistence(val bool) store.Option {
 type authenticationContextKey struct{}
 
 // Authentication configures the username and password to use for authentication.
-// Only supported by the `natsjskv` implementation.
+// Only supported by the `natsjskv` and `redis-cluster` implementations.
 func Authentication(username, password string) store.Option {
 	return func(o *store.Options) {
 		if o.Context == nil {
@@ -103,3 +107,28 @@ func Authentication(username, password string) store.Option {
 		o.Context = context.WithValue(o.Context, authenticationContextKey{}, []string{username, password})
 	}
 }
+
+type tlsContextKey struct{}
+
+type tlsOptions struct {
+	enabled           bool
+	insecure          bool
+	rootCACertificate string
+}
+
+// TLS configures TLS for the connections to the store nodes. The root CA
+// certificate is the path of a PEM file, if empty the system roots are used.
+// Only supported by the `redis-cluster` implementation.
+func TLS(enabled, insecure bool, rootCACertificate string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+
+		o.Context = context.WithValue(o.Context, tlsContextKey{}, tlsOptions{
+			enabled:           enabled,
+			insecure:          insecure,
+			rootCACertificate: rootCACertificate,
+		})
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package rediscluster implements a go-micro store on top of a Redis Cluster.
+// In contrast to the redis store, which only scans the node it happens to be
+// connected to, keys are listed by scanning all masters of the cluster and
+// bulk operations are pipelined and grouped by hash slot.
+package rediscluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"go-micro.dev/v4/store"
+)
+
+const (
+	// DefaultAddr is the node used when no node is configured.
+	DefaultAddr = "127.0.0.1:6379"
+
+	// Slots is the number of hash slots of a Redis Cluster.
+	Slots = 16384
+
+	defaultDatabase = "micro"
+	defaultTable    = "micro"
+
+	// scanCount is the number of keys requested per SCAN call.
+	scanCount = 1000
+)
+
+type defaultTTLContextKey struct{}
+
+// DefaultTTL sets the time to live of records written without an expiry.
+func DefaultTTL(ttl time.Duration) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, defaultTTLContextKey{}, ttl)
+	}
+}
+
+type credentialsContextKey struct{}
+
+// Credentials sets the username and password used to authenticate against
+// the cluster nodes. An empty username uses the default user.
+func Credentials(username, password string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, credentialsContextKey{}, []string{username, password})
+	}
+}
+
+type tlsContextKey struct{}
+
+type tlsOptions struct {
+	insecure bool
+	rootCA   string
+}
+
+// TLS enables TLS for the connections to the cluster nodes. The root CA
+// certificate is the path of a PEM file, if empty the system roots are used.
+func TLS(insecure bool, rootCACertificate string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, tlsContextKey{}, tlsOptions{insecure: insecure, rootCA: rootCACertificate})
+	}
+}
+
+// Store is a go-micro store backed by a Redis Cluster. Records are stored
+// as plain strings below "<database>/<table>/", their metadata is not
+// persisted.
+type Store struct {
+	options    store.Options
+	client     *redis.ClusterClient
+	defaultTTL time.Duration
+	err        error
+}
+
+// NewStore creates a new go-micro store using the cluster nodes given as
+// nodes.
+func NewStore(opts ...store.Option) (store.Store, error) {
+	s := &Store{}
+	if err := s.Init(opts...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Init configures the cluster client. A previously created client is closed.
+func (s *Store) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	if s.options.Database == "" {
+		s.options.Database = defaultDatabase
+	}
+	if s.options.Table == "" {
+		s.options.Table = defaultTable
+	}
+	if s.client != nil {
+		_ = s.client.Close()
+		s.client = nil
+	}
+
+	copts, err := s.clusterOptions()
+	if err != nil {
+		s.err = err
+		return err
+	}
+	s.client, s.err = redis.NewClusterClient(copts), nil
+	return nil
+}
+
+func (s *Store) clusterOptions() (*redis.ClusterOptions, error) {
+	copts := &redis.ClusterOptions{}
+	enableTLS := false
+	for _, node := range s.options.Nodes {
+		switch {
+		case strings.HasPrefix(node, "rediss://"):
+			enableTLS = true
+			node = strings.TrimPrefix(node, "rediss://")
+		case strings.HasPrefix(node, "redis://"):
+			node = strings.TrimPrefix(node, "redis://")
+		}
+		if node != "" {
+			copts.Addrs = append(copts.Addrs, node)
+		}
+	}
+	if len(copts.Addrs) == 0 {
+		copts.Addrs = []string{DefaultAddr}
+	}
+
+	ctx := s.options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.defaultTTL, _ = ctx.Value(defaultTTLContextKey{}).(time.Duration)
+	if creds, ok := ctx.Value(credentialsContextKey{}).([]string); ok && len(creds) == 2 {
+		copts.Username, copts.Password = creds[0], creds[1]
+	}
+	topts, ok := ctx.Value(tlsContextKey{}).(tlsOptions)
+	if !ok && !enableTLS {
+		return copts, nil
+	}
+
+	copts.TLSConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: topts.insecure, //nolint:gosec
+	}
+	if topts.rootCA != "" {
+		pem, err := os.ReadFile(topts.rootCA)
+		if err != nil {
+			return nil, errors.Wrap(err, "rediscluster: error reading root ca certificate")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("rediscluster: no certificates found in " + topts.rootCA)
+		}
+		copts.TLSConfig.RootCAs = pool
+		copts.TLSConfig.InsecureSkipVerify = false
+	}
+	return copts, nil
+}
+
+// Options returns the options of the store.
+func (s *Store) Options() store.Options {
+	return s.options
+}
+
+// namespace returns the prefix of the redis keys of a database and table.
+func (s *Store) namespace(database, table string) string {
+	if database == "" {
+		database = s.options.Database
+	}
+	if table == "" {
+		table = s.options.Table
+	}
+	return database + "/" + table + "/"
+}
+
+// Write stores a record. The expiry is taken from the TTL or Expiry write
+// options, the expiry of the record or the default TTL of the store, in that
+// order. Writing a record that already expired deletes it.
+func (s *Store) Write(r *store.Record, opts ...store.WriteOption) error {
+	if s.err != nil {
+		return s.err
+	}
+	wopts := store.WriteOptions{}
+	for _, o := range opts {
+		o(&wopts)
+	}
+	key := s.namespace(wopts.Database, wopts.Table) + r.Key
+
+	var ttl time.Duration
+	switch {
+	case wopts.TTL != 0:
+		ttl = wopts.TTL
+	case !wopts.Expiry.IsZero():
+		ttl = time.Until(wopts.Expiry)
+	case r.Expiry != 0:
+		ttl = r.Expiry
+	case s.defaultTTL != 0:
+		ttl = s.defaultTTL
+	}
+	ctx := context.Background()
+	if ttl < 0 {
+		return s.client.Del(ctx, key).Err()
+	}
+	return s.client.Set(ctx, key, r.Value, ttl).Err()
+}
+
+// Read returns the record with the key or, with the prefix or suffix
+// options, all records matching the key. As in the redis store the key is
+// used as a match pattern when reading by prefix or suffix. Records are
+// returned sorted by key.
+func (s *Store) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	ropts := store.ReadOptions{}
+	for _, o := range opts {
+		o(&ropts)
+	}
+	ns := s.namespace(ropts.Database, ropts.Table)
+	ctx := context.Background()
+
+	if !ropts.Prefix && !ropts.Suffix {
+		records, err := s.get(ctx, ns, []string{key})
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, store.ErrNotFound
+		}
+		return records, nil
+	}
+
+	prefix, suffix := "", ""
+	if ropts.Prefix {
+		prefix = key
+	}
+	if ropts.Suffix {
+		suffix = key
+	}
+	keys, err := s.keys(ctx, ns, prefix, suffix, scanLimit(ropts.Offset, ropts.Limit))
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, ns, page(keys, ropts.Offset, ropts.Limit))
+}
+
+// get fetches the values and remaining time to live of the keys in one
+// pipeline. Keys that do not exist are skipped.
+func (s *Store) get(ctx context.Context, ns string, keys []string) ([]*store.Record, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range keys {
+			gets[i] = p.Get(ctx, ns+key)
+			ttls[i] = p.PTTL(ctx, ns+key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, errors.Wrap(err, "rediscluster: error reading records")
+	}
+
+	records := make([]*store.Record, 0, len(keys))
+	for i, key := range keys {
+		value, err := gets[i].Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "rediscluster: error reading record "+key)
+		}
+		r := &store.Record{Key: key, Value: value}
+		if ttl := ttls[i].Val(); ttl > 0 {
+			r.Expiry = ttl
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Delete removes the record with the key.
+func (s *Store) Delete(key string, opts ...store.DeleteOption) error {
+	return s.DeleteMany([]string{key}, opts...)
+}
+
+// DeleteMany removes the records with the keys. The keys are grouped by hash
+// slot and deleted with one command per slot in a single pipeline.
+func (s *Store) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	dopts := store.DeleteOptions{}
+	for _, o := range opts {
+		o(&dopts)
+	}
+	ns := s.namespace(dopts.Database, dopts.Table)
+
+	slots := map[int][]string{}
+	for _, key := range keys {
+		slot := Slot(ns + key)
+		slots[slot] = append(slots[slot], ns+key)
+	}
+	ctx := context.Background()
+	_, err := s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, keys := range slots {
+			p.Del(ctx, keys...)
+		}
+		return nil
+	})
+	return errors.Wrap(err, "rediscluster: error deleting records")
+}
+
+// List returns the keys of the records, sorted. As in the redis store the
+// prefix and suffix are used as match patterns.
+func (s *Store) List(opts ...store.ListOption) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	lopts := store.ListOptions{}
+	for _, o := range opts {
+		o(&lopts)
+	}
+	ns := s.namespace(lopts.Database, lopts.Table)
+
+	keys, err := s.keys(context.Background(), ns, lopts.Prefix, lopts.Suffix, scanLimit(lopts.Offset, lopts.Limit))
+	if err != nil {
+		return nil, err
+	}
+	return page(keys, lopts.Offset, lopts.Limit), nil
+}
+
+// scanLimit returns the number of keys needed to serve a page, 0 if all keys
+// are needed.
+func scanLimit(offset, limit uint) uint {
+	if limit == 0 {
+		return 0
+	}
+	return offset + limit
+}
+
+// keys scans all masters of the cluster concurrently for the keys of the
+// namespace matching the prefix and suffix and returns them sorted, without
+// the namespace. If max is not 0 the scan stops once max keys were found, the
+// keys are then sorted among themselves but not necessarily the first ones of
+// the whole namespace.
+func (s *Store) keys(ctx context.Context, ns, prefix, suffix string, max uint) ([]string, error) {
+	pattern := escape(ns) + prefix + "*" + suffix
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	found := map[string]struct{}{}
+	full := func() bool {
+		return max > 0 && uint(len(found)) >= max
+	}
+	err := s.client.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error {
+		iter := c.Scan(ctx, 0, pattern, scanCount).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			if full() {
+				mu.Unlock()
+				return nil
+			}
+			found[strings.TrimPrefix(iter.Val(), ns)] = struct{}{}
+			if full() {
+				// stop the scans of the other masters
+				cancel()
+				mu.Unlock()
+				return nil
+			}
+			mu.Unlock()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if full() {
+			return nil
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "rediscluster: error scanning keys")
+	}
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// page applies the offset and limit to the keys.
+func page(keys []string, offset, limit uint) []string {
+	if offset >= uint(len(keys)) {
+		return nil
+	}
+	keys = keys[offset:]
+	if limit > 0 && limit < uint(len(keys)) {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+// escape quotes the characters with a special meaning in match patterns.
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Slot returns the hash slot of a key. If the key contains a hash tag, only
+// the tag is hashed.
+func Slot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % Slots
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Close closes the cluster client.
+func (s *Store) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client, s.err = nil, errors.New("rediscluster: store closed")
+	return err
+}
+
+// String returns the name of the implementation.
+func (s *Store) String() string {
+	return "redis-cluster"
+}
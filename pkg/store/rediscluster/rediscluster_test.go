@@ -0,0 +1,257 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package rediscluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-micro.dev/v4/store"
+)
+
+func newTestStore(t *testing.T, addr string, opts ...store.Option) store.Store {
+	s, err := NewStore(append(opts, store.Nodes(addr))...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func keys(records []*store.Record) []string {
+	k := make([]string, 0, len(records))
+	for _, r := range records {
+		k = append(k, r.Key)
+	}
+	return k
+}
+
+func TestSlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31c3), crc16("123456789"))
+	assert.Equal(t, 12182, Slot("foo"))
+	assert.Equal(t, Slot("user1000"), Slot("{user1000}.following"))
+	assert.Equal(t, Slot("{user1000}.following"), Slot("{user1000}.followers"))
+	// empty hash tags are ignored, only the first tag counts
+	assert.Equal(t, int(crc16("foo{}{bar}"))%Slots, Slot("foo{}{bar}"))
+	assert.Equal(t, Slot("{bar"), Slot("foo{{bar}}zap"))
+}
+
+func TestReadWrite(t *testing.T) {
+	m := miniredis.RunT(t)
+	s := newTestStore(t, m.Addr())
+
+	for _, k := range []string{"uid:1!sid:a", "uid:1!sid:b", "uid:2!sid:a", "other"} {
+		require.NoError(t, s.Write(&store.Record{Key: k, Value: []byte("v-" + k)}))
+	}
+	assert.True(t, m.Exists("micro/micro/other"))
+
+	records, err := s.Read("uid:1!sid:b")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("v-uid:1!sid:b"), records[0].Value)
+	assert.Zero(t, records[0].Expiry)
+
+	_, err = s.Read("missing")
+	assert.Equal(t, store.ErrNotFound, err)
+
+	records, err = s.Read("uid:1", store.ReadPrefix())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid:1!sid:a", "uid:1!sid:b"}, keys(records))
+
+	records, err = s.Read("sid:a", store.ReadSuffix())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid:1!sid:a", "uid:2!sid:a"}, keys(records))
+
+	records, err = s.Read("uid", store.ReadPrefix(), store.ReadOffset(1), store.ReadLimit(1))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid:1!sid:b"}, keys(records))
+
+	list, err := s.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other", "uid:1!sid:a", "uid:1!sid:b", "uid:2!sid:a"}, list)
+
+	// the prefix is a match pattern, like in the redis store
+	list, err = s.List(store.ListPrefix("*sid:a"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid:1!sid:a", "uid:2!sid:a"}, list)
+
+	list, err = s.List(store.ListPrefix("uid"), store.ListOffset(2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid:2!sid:a"}, list)
+
+	require.NoError(t, s.Delete("other"))
+	_, err = s.Read("other")
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestListLimit(t *testing.T) {
+	m := miniredis.RunT(t)
+	s := newTestStore(t, m.Addr())
+	for i := 0; i < 50; i++ {
+		require.NoError(t, s.Write(&store.Record{Key: fmt.Sprintf("key-%02d", i), Value: []byte("v")}))
+	}
+
+	ns := "micro/micro/"
+	found, err := s.(*Store).keys(context.Background(), ns, "", "", 5)
+	require.NoError(t, err)
+	assert.Len(t, found, 5)
+
+	list, err := s.List(store.ListLimit(3), store.ListOffset(1))
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+}
+
+func TestNamespaces(t *testing.T) {
+	m := miniredis.RunT(t)
+	s := newTestStore(t, m.Addr(), store.Database("db"), store.Table("t1"))
+
+	require.NoError(t, s.Write(&store.Record{Key: "k", Value: []byte("t1")}))
+	require.NoError(t, s.Write(&store.Record{Key: "k", Value: []byte("t2")}, store.WriteTo("db", "t2")))
+	// special characters in the namespace must not act as patterns
+	require.NoError(t, s.Write(&store.Record{Key: "k", Value: []byte("any")}, store.WriteTo("db", "*")))
+	assert.True(t, m.Exists("db/t2/k"))
+
+	records, err := s.Read("k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("t1"), records[0].Value)
+
+	records, err = s.Read("k", store.ReadFrom("db", "t2"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("t2"), records[0].Value)
+
+	list, err := s.List(store.ListFrom("db", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k"}, list)
+
+	require.NoError(t, s.Delete("k", store.DeleteFrom("db", "t2")))
+	assert.False(t, m.Exists("db/t2/k"))
+	assert.True(t, m.Exists("db/t1/k"))
+}
+
+func TestExpiry(t *testing.T) {
+	m := miniredis.RunT(t)
+	s := newTestStore(t, m.Addr(), DefaultTTL(time.Hour))
+
+	require.NoError(t, s.Write(&store.Record{Key: "default", Value: []byte("v")}))
+	require.NoError(t, s.Write(&store.Record{Key: "record", Value: []byte("v"), Expiry: time.Minute}))
+	require.NoError(t, s.Write(&store.Record{Key: "option", Value: []byte("v")}, store.WriteTTL(time.Second)))
+	require.NoError(t, s.Write(&store.Record{Key: "expired", Value: []byte("v")}, store.WriteExpiry(time.Now().Add(-time.Second))))
+
+	assert.Equal(t, time.Hour, m.TTL("micro/micro/default"))
+	assert.Equal(t, time.Minute, m.TTL("micro/micro/record"))
+	assert.Equal(t, time.Second, m.TTL("micro/micro/option"))
+	assert.False(t, m.Exists("micro/micro/expired"))
+
+	records, err := s.Read("record")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, records[0].Expiry)
+
+	m.FastForward(2 * time.Second)
+	list, err := s.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "record"}, list)
+}
+
+func TestDeleteMany(t *testing.T) {
+	m := miniredis.RunT(t)
+	s := newTestStore(t, m.Addr())
+
+	for _, k := range []string{"a", "b", "c", "{tag}1", "{tag}2"} {
+		require.NoError(t, s.Write(&store.Record{Key: k, Value: []byte("v")}))
+	}
+
+	require.NoError(t, s.(*Store).DeleteMany([]string{"a", "c", "{tag}1", "{tag}2", "missing"}))
+	list, err := s.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, list)
+
+	require.NoError(t, s.(*Store).DeleteMany(nil))
+}
+
+func TestAuthentication(t *testing.T) {
+	m := miniredis.RunT(t)
+	m.RequireUserAuth("reva", "secret")
+
+	s := newTestStore(t, m.Addr(), Credentials("reva", "wrong"))
+	assert.Error(t, s.Write(&store.Record{Key: "k", Value: []byte("v")}))
+
+	s = newTestStore(t, m.Addr(), Credentials("reva", "secret"))
+	require.NoError(t, s.Write(&store.Record{Key: "k", Value: []byte("v")}))
+	assert.True(t, m.Exists("micro/micro/k"))
+}
+
+func TestTLS(t *testing.T) {
+	cert, caFile := newCertificate(t)
+	m, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+
+	s := newTestStore(t, m.Addr())
+	assert.Error(t, s.Write(&store.Record{Key: "k", Value: []byte("v")}))
+
+	s = newTestStore(t, m.Addr(), TLS(false, caFile))
+	require.NoError(t, s.Write(&store.Record{Key: "k", Value: []byte("v")}))
+	assert.True(t, m.Exists("micro/micro/k"))
+
+	s = newTestStore(t, "rediss://"+m.Addr(), TLS(true, ""))
+	records, err := s.Read("k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v"), records[0].Value)
+
+	_, err = NewStore(store.Nodes(m.Addr()), TLS(false, filepath.Join(t.TempDir(), "missing.pem")))
+	assert.Error(t, err)
+}
+
+// newCertificate creates a self-signed certificate for 127.0.0.1 and writes
+// it to a PEM file.
+func newCertificate(t *testing.T) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "miniredis"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
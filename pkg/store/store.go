@@ -20,6 +20,7 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -28,8 +29,10 @@ import (
 	"github.com/go-micro/plugins/v4/store/redis"
 	redisopts "github.com/go-redis/redis/v8"
 	"github.com/nats-io/nats.go"
+	"github.com/opencloud-eu/reva/v2/pkg/store/bolt"
 	"github.com/opencloud-eu/reva/v2/pkg/store/etcd"
 	"github.com/opencloud-eu/reva/v2/pkg/store/memory"
+	"github.com/opencloud-eu/reva/v2/pkg/store/rediscluster"
 	"go-micro.dev/v4/logger"
 	microstore "go-micro.dev/v4/store"
 )
@@ -47,16 +50,36 @@ const (
 	TypeRedis = "redis"
 	// TypeRedisSentinel represents redis-sentinel stores
 	TypeRedisSentinel = "redis-sentinel"
+	// TypeRedisCluster represents redis-cluster stores
+	TypeRedisCluster = "redis-cluster"
 	// TypeOCMem represents ocmem stores
 	TypeOCMem = "ocmem"
 	// TypeNatsJS represents nats-js stores
 	TypeNatsJS = "nats-js"
 	// TypeNatsJSKV represents nats-js-kv stores
 	TypeNatsJSKV = "nats-js-kv"
+	// TypeBolt represents embedded stores persisted in a local bbolt file
+	TypeBolt = "bolt"
 )
 
-// Create initializes a new store
+// Create initializes a new store. Errors of stores that fail to initialize are
+// logged and returned by the operations of the store, use New to handle them.
 func Create(opts ...microstore.Option) microstore.Store {
+	s, err := New(opts...)
+	if err != nil {
+		options := microstore.Options{Logger: logger.DefaultLogger}
+		for _, o := range opts {
+			o(&options)
+		}
+		options.Logger.Logf(logger.ErrorLevel, "error initializing store: %v", err)
+		return failedStore{Store: microstore.NewNoopStore(opts...), err: err}
+	}
+	return s
+}
+
+// New initializes a new store and returns the error of stores that fail to
+// connect to or open their backend.
+func New(opts ...microstore.Option) (microstore.Store, error) {
 	options := &microstore.Options{
 		Context: context.Background(),
 	}
@@ -68,19 +91,19 @@ func Create(opts ...microstore.Option) microstore.Store {
 
 	switch storeType {
 	case TypeNoop:
-		return microstore.NewNoopStore(opts...)
+		return microstore.NewNoopStore(opts...), nil
 	case TypeEtcd:
-		return etcd.NewStore(opts...)
+		return etcd.NewStore(opts...), nil
 	case TypeRedis:
-		// FIXME redis plugin does not support redis cluster or ring -> needs upstream patch or our implementation
-		return redis.NewStore(opts...)
+		// the redis plugin does not support redis cluster, use TypeRedisCluster for that
+		return redis.NewStore(opts...), nil
 	case TypeRedisSentinel:
 		redisMaster := ""
 		redisNodes := []string{}
 		for _, node := range options.Nodes {
 			parts := strings.SplitN(node, "/", 2)
 			if len(parts) != 2 {
-				return nil
+				return nil, fmt.Errorf("invalid redis-sentinel node %q, expected host:port/master", node)
 			}
 			// the first node is used to retrieve the redis master
 			redisNodes = append(redisNodes, parts[0])
@@ -95,7 +118,17 @@ func Create(opts ...microstore.Option) microstore.Store {
 			redis.WithRedisOptions(redisopts.UniversalOptions{
 				MasterName: redisMaster,
 			}),
-		)
+		), nil
+	case TypeRedisCluster:
+		ttl, _ := options.Context.Value(ttlContextKey{}).(time.Duration)
+		opts = append(opts, rediscluster.DefaultTTL(ttl))
+		if auth, ok := options.Context.Value(authenticationContextKey{}).([]string); ok && len(auth) == 2 {
+			opts = append(opts, rediscluster.Credentials(auth[0], auth[1]))
+		}
+		if t, ok := options.Context.Value(tlsContextKey{}).(tlsOptions); ok && t.enabled {
+			opts = append(opts, rediscluster.TLS(t.insecure, t.rootCACertificate))
+		}
+		return rediscluster.NewStore(opts...)
 	case TypeOCMem:
 		if ocMemStore == nil {
 			var memStore microstore.Store
@@ -116,7 +149,7 @@ func Create(opts ...microstore.Option) microstore.Store {
 			}
 			ocMemStore = &memStore
 		}
-		return *ocMemStore
+		return *ocMemStore, nil
 	case TypeNatsJS:
 		ttl, _ := options.Context.Value(ttlContextKey{}).(time.Duration)
 		if mem, _ := options.Context.Value(disablePersistanceContextKey{}).(bool); mem {
@@ -135,7 +168,7 @@ func Create(opts ...microstore.Option) microstore.Store {
 			append(opts,
 				natsjs.NatsOptions(natsOptions), // always pass in properly initialized default nats options
 				natsjs.DefaultTTL(ttl))...,
-		) // TODO test with OpenCloud nats
+		), nil // TODO test with OpenCloud nats
 	case TypeNatsJSKV:
 		// NOTE: nats needs a DefaultTTL option as it does not support per Write TTL ...
 		ttl, _ := options.Context.Value(ttlContextKey{}).(time.Duration)
@@ -154,15 +187,40 @@ func Create(opts ...microstore.Option) microstore.Store {
 				natsjskv.NatsOptions(natsOptions), // always pass in properly initialized default nats options
 				natsjskv.EncodeKeys(),
 				natsjskv.DefaultTTL(ttl))...,
-		)
+		), nil
+	case TypeBolt:
+		ttl, _ := options.Context.Value(ttlContextKey{}).(time.Duration)
+		return bolt.NewStore(append(opts, bolt.DefaultTTL(ttl))...)
 	case TypeMemory, "mem", "": // allow existing short form and use as default
-		return microstore.NewMemoryStore(opts...)
+		return microstore.NewMemoryStore(opts...), nil
 	default:
 		// try to log an error
 		if options.Logger == nil {
 			options.Logger = logger.DefaultLogger
 		}
 		options.Logger.Logf(logger.ErrorLevel, "unknown store type: '%s', falling back to memory", storeType)
-		return microstore.NewMemoryStore(opts...)
+		return microstore.NewMemoryStore(opts...), nil
 	}
 }
+
+// failedStore is returned by Create for stores that failed to initialize.
+type failedStore struct {
+	microstore.Store
+	err error
+}
+
+func (s failedStore) Read(string, ...microstore.ReadOption) ([]*microstore.Record, error) {
+	return nil, s.err
+}
+
+func (s failedStore) Write(*microstore.Record, ...microstore.WriteOption) error {
+	return s.err
+}
+
+func (s failedStore) Delete(string, ...microstore.DeleteOption) error {
+	return s.err
+}
+
+func (s failedStore) List(...microstore.ListOption) ([]string, error) {
+	return nil, s.err
+}
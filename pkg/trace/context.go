@@ -46,3 +46,12 @@ func ContextGetTracerProvider(ctx context.Context) trace.TracerProvider {
 	}
 	return noop.NewTracerProvider()
 }
+
+// ContextGetTraceID returns the id of the trace the span associated with ctx belongs to.
+func ContextGetTraceID(ctx context.Context) (string, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return "", false
+	}
+	return sc.TraceID().String(), true
+}
@@ -28,11 +28,13 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"google.golang.org/protobuf/proto"
 )
 
 func init() {
 	registry.Register("demo", New)
+	cfg.Register("user.manager", "demo", struct{}{})
 }
 
 type manager struct {
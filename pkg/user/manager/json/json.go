@@ -22,12 +22,16 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
 
@@ -37,9 +41,12 @@ import (
 
 func init() {
 	registry.Register("json", New)
+	cfg.Register("user.manager", "json", config{})
 }
 
 type manager struct {
+	sync.RWMutex
+	file  string
 	users []*userpb.User
 }
 
@@ -91,11 +98,14 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 	if err != nil {
 		return err
 	}
+	m.file = c.Users
 	m.users = users
 	return nil
 }
 
 func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingGroups bool) (*userpb.User, error) {
+	m.RLock()
+	defer m.RUnlock()
 	for _, u := range m.users {
 		if (u.Id.GetOpaqueId() == uid.OpaqueId || u.Username == uid.OpaqueId) && (uid.Idp == "" || uid.Idp == u.Id.GetIdp()) {
 			user := proto.Clone(u).(*userpb.User)
@@ -109,6 +119,8 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingG
 }
 
 func (m *manager) GetUserByClaim(ctx context.Context, claim, value string, skipFetchingGroups bool) (*userpb.User, error) {
+	m.RLock()
+	defer m.RUnlock()
 	for _, u := range m.users {
 		if userClaim, err := extractClaim(u, claim); err == nil && value == userClaim {
 			user := proto.Clone(u).(*userpb.User)
@@ -145,6 +157,8 @@ func userContains(u *userpb.User, query string) bool {
 }
 
 func (m *manager) FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error) {
+	m.RLock()
+	defer m.RUnlock()
 	users := []*userpb.User{}
 	for _, u := range m.users {
 		if userContains(u, query) {
@@ -165,3 +179,114 @@ func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]stri
 	}
 	return user.Groups, nil
 }
+
+// CreateUser adds a user to the json file. Credentials are managed by the
+// json auth manager.
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	if u.GetUsername() == "" {
+		return nil, errtypes.BadRequest("username missing")
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	nu := proto.Clone(u).(*userpb.User)
+	if nu.Id == nil {
+		nu.Id = &userpb.UserId{}
+	}
+	if nu.Id.OpaqueId == "" {
+		nu.Id.OpaqueId = uuid.New().String()
+	}
+	if nu.Id.Type == userpb.UserType_USER_TYPE_INVALID {
+		nu.Id.Type = userpb.UserType_USER_TYPE_PRIMARY
+	}
+	for _, existing := range m.users {
+		if existing.Id.GetOpaqueId() == nu.Id.OpaqueId || existing.Username == nu.Username {
+			return nil, errtypes.AlreadyExists(nu.Username)
+		}
+	}
+
+	users := append(m.users, nu)
+	if err := m.persist(users); err != nil {
+		return nil, err
+	}
+	m.users = users
+	return proto.Clone(nu).(*userpb.User), nil
+}
+
+// UpdateUser updates the display name and mail of a user in the json file.
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	i := m.indexOf(u.GetId())
+	if i < 0 {
+		return nil, errtypes.NotFound(u.GetId().GetOpaqueId())
+	}
+
+	users := make([]*userpb.User, len(m.users))
+	copy(users, m.users)
+	updated := proto.Clone(users[i]).(*userpb.User)
+	updated.DisplayName = u.DisplayName
+	updated.Mail = u.Mail
+	updated.MailVerified = u.MailVerified
+	users[i] = updated
+
+	if err := m.persist(users); err != nil {
+		return nil, err
+	}
+	m.users = users
+	return proto.Clone(updated).(*userpb.User), nil
+}
+
+// DeleteUser removes a user from the json file.
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	i := m.indexOf(uid)
+	if i < 0 {
+		return errtypes.NotFound(uid.GetOpaqueId())
+	}
+
+	users := make([]*userpb.User, 0, len(m.users)-1)
+	users = append(users, m.users[:i]...)
+	users = append(users, m.users[i+1:]...)
+	if err := m.persist(users); err != nil {
+		return err
+	}
+	m.users = users
+	return nil
+}
+
+func (m *manager) indexOf(uid *userpb.UserId) int {
+	for i, u := range m.users {
+		if (u.Id.GetOpaqueId() == uid.GetOpaqueId() || u.Username == uid.GetOpaqueId()) && (uid.GetIdp() == "" || uid.GetIdp() == u.Id.GetIdp()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// persist atomically replaces the json file with the given users
+func (m *manager) persist(users []*userpb.User) error {
+	b, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json: error marshaling users")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.file), filepath.Base(m.file)+".*")
+	if err != nil {
+		return errors.Wrap(err, "json: error creating temporary file")
+	}
+	defer os.Remove(tmp.Name())
+	if fi, err := os.Stat(m.file); err == nil {
+		_ = tmp.Chmod(fi.Mode().Perm())
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "json: error writing users")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "json: error writing users")
+	}
+	return os.Rename(tmp.Name(), m.file)
+}
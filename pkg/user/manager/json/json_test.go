@@ -26,6 +26,7 @@ import (
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -147,3 +148,63 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf("user differ: expected=%v got=%v", "einstein", resUser[0].Username)
 	}
 }
+
+func TestUserProvisioning(t *testing.T) {
+	tempdir := t.TempDir()
+	file := tempdir + "/users.json"
+	userJSON := `[{"id":{"idp":"localhost","opaque_id":"einstein","type":1},"username":"einstein","mail":"einstein@example.org","display_name":"Albert Einstein"}]`
+	if err := os.WriteFile(file, []byte(userJSON), 0600); err != nil {
+		t.Fatalf("error while writing temp file: %v", err)
+	}
+
+	mgr, err := New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatalf("error while get manager: %v", err)
+	}
+	p := mgr.(user.Provisioner)
+
+	// creating a user with an existing username fails
+	_, err = p.CreateUser(ctx, &userpb.User{Username: "einstein"})
+	if _, ok := err.(errtypes.AlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	created, err := p.CreateUser(ctx, &userpb.User{Username: "marie", Mail: "marie@example.org", DisplayName: "Marie Curie"})
+	if err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	if created.Id.OpaqueId == "" || created.Id.Type != userpb.UserType_USER_TYPE_PRIMARY {
+		t.Fatalf("expected generated primary user id, got %v", created.Id)
+	}
+
+	updated, err := p.UpdateUser(ctx, &userpb.User{Id: created.Id, Mail: "curie@example.org", DisplayName: "Marie Curie"})
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	if updated.Mail != "curie@example.org" || updated.Username != "marie" {
+		t.Fatalf("user not updated: %v", updated)
+	}
+
+	// changes are persisted
+	reloaded, err := New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatalf("error while reloading manager: %v", err)
+	}
+	u, err := reloaded.GetUserByClaim(ctx, "username", "marie", true)
+	if err != nil {
+		t.Fatalf("created user not persisted: %v", err)
+	}
+	if u.Mail != "curie@example.org" {
+		t.Fatalf("updated mail not persisted: %v", u.Mail)
+	}
+
+	if err := p.DeleteUser(ctx, created.Id); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if _, err := mgr.GetUser(ctx, created.Id, true); err == nil {
+		t.Fatalf("deleted user still found")
+	}
+	if err := p.DeleteUser(ctx, created.Id); err == nil {
+		t.Fatalf("deleting a missing user must fail")
+	}
+}
@@ -29,20 +29,25 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/opencloud-eu/reva/v2/pkg/appctx"
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/opencloud-eu/reva/v2/pkg/password"
 	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
 	"github.com/opencloud-eu/reva/v2/pkg/utils"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	ldapIdentity "github.com/opencloud-eu/reva/v2/pkg/utils/ldap"
 	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("ldap", New)
+	cfg.Register("user.manager", "ldap", config{})
 }
 
 type manager struct {
 	c          *config
 	ldapClient ldap.Client
+	policy     password.Validator
+	history    *password.History
 }
 
 type config struct {
@@ -51,6 +56,8 @@ type config struct {
 	Idp            string                `mapstructure:"idp"`
 	// Nobody specifies the fallback uid number for users that don't have a uidNumber set in LDAP
 	Nobody int64 `mapstructure:"nobody"`
+	// PasswordPolicy is applied when changing the password of a user
+	PasswordPolicy password.PolicyConfig `mapstructure:"password_policy"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -91,6 +98,10 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return fmt.Errorf("error setting up Identity config: %w", err)
 	}
 	m.c = c
+	m.policy = c.PasswordPolicy.Validator()
+	if m.history, err = c.PasswordPolicy.History.History(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -264,3 +275,73 @@ func (m *manager) ldapEntryToUserID(entry *ldap.Entry) (*userpb.UserId, error) {
 		Type:     m.c.LDAPIdentity.GetUserType(entry),
 	}, nil
 }
+
+// CreateUser implements the user.Provisioner interface. Creates a new user entry
+// below the configured user base DN, requires write_enabled to be set.
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	log := appctx.GetLogger(ctx)
+	if u.GetId().GetIdp() != "" && u.GetId().GetIdp() != m.c.Idp {
+		return nil, errtypes.BadRequest("idp mismatch")
+	}
+
+	userEntry, err := m.c.LDAPIdentity.AddLDAPUser(log, m.ldapClient, u)
+	if err != nil {
+		return nil, err
+	}
+	return m.ldapEntryToUser(userEntry)
+}
+
+// UpdateUser implements the user.Provisioner interface. Updates the display name
+// and mail of an existing user entry.
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	log := appctx.GetLogger(ctx)
+	if u.GetId().GetIdp() != "" && u.GetId().GetIdp() != m.c.Idp {
+		return nil, errtypes.NotFound("idp mismatch")
+	}
+
+	userEntry, err := m.c.LDAPIdentity.GetLDAPUserByID(log, m.ldapClient, u.GetId().GetOpaqueId())
+	if err != nil {
+		return nil, err
+	}
+	if err := m.c.LDAPIdentity.UpdateLDAPUser(log, m.ldapClient, userEntry, u); err != nil {
+		return nil, err
+	}
+	return m.GetUser(ctx, u.Id, true)
+}
+
+// SetPassword implements the auth.PasswordSetter interface. Validates the
+// password against the configured policy and sets it on the user entry.
+func (m *manager) SetPassword(ctx context.Context, username, pw string) error {
+	log := appctx.GetLogger(ctx)
+	if err := m.policy.Validate(pw); err != nil {
+		return err
+	}
+	subject := password.AccountSubject(username)
+	if err := m.history.Check(subject, pw); err != nil {
+		return err
+	}
+
+	userEntry, err := m.c.LDAPIdentity.GetLDAPUserByAttribute(log, m.ldapClient, "username", username)
+	if err != nil {
+		return err
+	}
+	if err := m.c.LDAPIdentity.SetLDAPPassword(log, m.ldapClient, userEntry.DN, pw); err != nil {
+		return err
+	}
+	return m.history.Remember(subject, pw)
+}
+
+// DeleteUser implements the user.Provisioner interface. Removes the user from
+// its groups and deletes the user entry.
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	log := appctx.GetLogger(ctx)
+	if uid.GetIdp() != "" && uid.GetIdp() != m.c.Idp {
+		return errtypes.NotFound("idp mismatch")
+	}
+
+	userEntry, err := m.c.LDAPIdentity.GetLDAPUserByID(log, m.ldapClient, uid.GetOpaqueId())
+	if err != nil {
+		return err
+	}
+	return m.c.LDAPIdentity.DeleteLDAPUser(log, m.ldapClient, userEntry)
+}
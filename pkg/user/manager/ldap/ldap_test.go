@@ -21,6 +21,7 @@ package ldap
 import (
 	"testing"
 
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	ldapIdentity "github.com/opencloud-eu/reva/v2/pkg/utils/ldap"
 )
 
@@ -66,3 +67,18 @@ func TestUserManager(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 }
+
+func TestConfigSchema(t *testing.T) {
+	s, ok := cfg.Lookup("user.manager", "ldap")
+	if !ok {
+		t.Fatal("expected a config schema for the ldap user manager")
+	}
+	errs := s.Validate(map[string]interface{}{
+		"uri":         "ldaps://localhost:636",
+		"user_schema": map[string]interface{}{"mail": "email"},
+		"unknown":     true,
+	})
+	if len(errs) != 1 || errs[0].Error() != "unknown: unknown key" {
+		t.Fatalf("expected only the unknown key to be reported, got %v", errs)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
 	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -40,6 +41,7 @@ import (
 
 func init() {
 	registry.Register("nextcloud", New)
+	cfg.Register("user.manager", "nextcloud", UserManagerConfig{})
 }
 
 // Manager is the Nextcloud-based implementation of the share.Manager interface
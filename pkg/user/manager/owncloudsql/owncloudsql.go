@@ -30,6 +30,7 @@ import (
 	"github.com/opencloud-eu/reva/v2/pkg/user"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/owncloudsql/accounts"
 	"github.com/opencloud-eu/reva/v2/pkg/user/manager/registry"
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
 	"github.com/pkg/errors"
 
 	// Provides mysql drivers
@@ -38,6 +39,7 @@ import (
 
 func init() {
 	registry.Register("owncloudsql", NewMysql)
+	cfg.Register("user.manager", "owncloudsql", config{})
 }
 
 type manager struct {
@@ -39,3 +39,15 @@ type Manager interface {
 	// FindUsers returns all the user objects which match a query parameter.
 	FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error)
 }
+
+// Provisioner is the optional interface implemented by managers that allow
+// creating, updating and deleting user accounts.
+type Provisioner interface {
+	// CreateUser creates a new user account. A new id is generated if the user
+	// does not carry one. Passwords are set with an auth.PasswordSetter.
+	CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error)
+	// UpdateUser updates the display name and mail of an existing user.
+	UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error)
+	// DeleteUser deletes the user identified by a uid.
+	DeleteUser(ctx context.Context, uid *userpb.UserId) error
+}
@@ -0,0 +1,198 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cfg
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONSchema returns a JSON schema describing the configuration of all registered services
+// and drivers. Sections are nested according to their kind, driver configurations are
+// referenced from the definitions.
+func JSONSchema() map[string]any {
+	all := Schemas()
+
+	driverKinds := map[string]bool{}
+	for _, s := range all {
+		for _, d := range s.Drivers {
+			driverKinds[d.Kind] = true
+		}
+	}
+
+	root := objectSchema()
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "revad configuration"
+	definitions := map[string]any{}
+
+	for _, s := range all {
+		schema := schemaOf(reflect.TypeOf(s.Config))
+		if props, ok := schema["properties"].(map[string]any); ok {
+			for _, d := range s.Drivers {
+				refs := map[string]any{}
+				for _, ds := range all {
+					if ds.Kind == d.Kind {
+						refs[ds.Name] = map[string]any{"$ref": "#/definitions/" + definitionName(ds)}
+					}
+				}
+				drivers := objectSchema()
+				drivers["properties"] = refs
+				if p, ok := props[d.Map].(map[string]any); ok {
+					if desc, ok := p["description"]; ok {
+						drivers["description"] = desc
+					}
+				}
+				props[d.Map] = drivers
+			}
+		}
+
+		if driverKinds[s.Kind] {
+			definitions[definitionName(s)] = schema
+			continue
+		}
+
+		section := root
+		for _, key := range s.Path()[:len(s.Path())-1] {
+			props := properties(section)
+			next, ok := props[key].(map[string]any)
+			if !ok {
+				next = objectSchema()
+				props[key] = next
+			}
+			section = next
+		}
+		props := properties(section)
+		if existing, ok := props[s.Name].(map[string]any); ok {
+			// keep the sections that have already been nested into the existing schema
+			for k, v := range properties(existing) {
+				properties(schema)[k] = v
+			}
+		}
+		props[s.Name] = schema
+	}
+
+	if len(definitions) > 0 {
+		root["definitions"] = definitions
+	}
+	return root
+}
+
+func definitionName(s Schema) string {
+	return s.Kind + "." + s.Name
+}
+
+func objectSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+// properties returns the properties of an object schema, adding them if necessary
+func properties(schema map[string]any) map[string]any {
+	props, ok := schema["properties"].(map[string]any)
+	if !ok {
+		props = map[string]any{}
+		schema["properties"] = props
+	}
+	return props
+}
+
+func schemaOf(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		schema := objectSchema()
+		fields, remain := structFields(t)
+		props := schema["properties"].(map[string]any)
+		for name, f := range fields {
+			fs := schemaOf(f.Type)
+			applyDocs(fs, f)
+			props[name] = fs
+		}
+		schema["additionalProperties"] = remain
+		return schema
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem()),
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{
+			"type":  "array",
+			"items": schemaOf(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t == durationType {
+			return map[string]any{"type": "integer", "description": "duration in nanoseconds"}
+		}
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// applyDocs adds the default and the description from the `docs` struct tag to the schema.
+// The tag either has the form "default;description" or "url:reference".
+func applyDocs(schema map[string]any, f reflect.StructField) {
+	docs, ok := f.Tag.Lookup("docs")
+	if !ok || docs == "" {
+		return
+	}
+	if ref, ok := strings.CutPrefix(docs, "url:"); ok {
+		schema["description"] = "See " + ref
+		return
+	}
+	def, desc, _ := strings.Cut(docs, ";")
+	if desc != "" {
+		schema["description"] = desc
+	}
+	if def == "" || def == "nil" {
+		return
+	}
+	switch schema["type"] {
+	case "string":
+		schema["default"] = def
+	case "boolean":
+		if b, err := strconv.ParseBool(def); err == nil {
+			schema["default"] = b
+		}
+	case "integer":
+		if i, err := strconv.ParseInt(def, 10, 64); err == nil {
+			schema["default"] = i
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(def, 64); err == nil {
+			schema["default"] = n
+		}
+	}
+}
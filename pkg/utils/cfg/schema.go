@@ -0,0 +1,383 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Driver describes a map of driver configurations that is part of a configuration struct,
+// e.g. the `drivers` of a storage provider.
+type Driver struct {
+	// Selector is the key holding the name of the enabled driver, e.g. "driver"
+	Selector string
+	// Map is the key holding the configurations of the drivers, e.g. "drivers"
+	Map string
+	// Kind is the kind the drivers have been registered with. By convention it is the
+	// path of the driver registry package relative to pkg, e.g. "storage.fs"
+	Kind string
+}
+
+// Schema describes the configuration of a service or driver.
+type Schema struct {
+	// Kind is the dotted path of the section the configuration is found in, e.g. "grpc.services",
+	// or the kind of driver, e.g. "storage.fs". Top level sections use an empty kind.
+	Kind string
+	// Name is the name the service or driver has been registered with.
+	Name string
+	// Config is the configuration struct the configuration is decoded into.
+	Config any
+	// Drivers lists the driver maps of the configuration.
+	Drivers []Driver
+}
+
+// Path returns the path of the configuration section.
+func (s Schema) Path() []string {
+	if s.Kind == "" {
+		return []string{s.Name}
+	}
+	return append(strings.Split(s.Kind, "."), s.Name)
+}
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = map[string]Schema{}
+)
+
+// Register registers the configuration struct of a service or driver so the
+// configuration can be validated before it is decoded.
+func Register(kind, name string, conf any, drivers ...Driver) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	schemas[kind+"/"+name] = Schema{Kind: kind, Name: name, Config: conf, Drivers: drivers}
+}
+
+// Lookup returns the schema registered for the given kind and name.
+func Lookup(kind, name string) (Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	s, ok := schemas[kind+"/"+name]
+	return s, ok
+}
+
+// Schemas returns all registered schemas sorted by kind and name.
+func Schemas() []Schema {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	all := make([]Schema, 0, len(schemas))
+	for _, s := range schemas {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Kind != all[j].Kind {
+			return all[i].Kind < all[j].Kind
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// FieldError describes a problem with a single configuration key.
+type FieldError struct {
+	// Path is the path of the offending key relative to the validated configuration.
+	Path []string
+	Msg  string
+}
+
+func (e FieldError) Error() string {
+	return strings.Join(e.Path, ".") + ": " + e.Msg
+}
+
+// Validate checks the given configuration against the schema. It reports unknown keys and
+// values that cannot be decoded into the configuration struct. The configuration of the
+// enabled driver, or of all configured drivers if none is selected, is validated as well if
+// a schema has been registered for it.
+func (s Schema) Validate(input map[string]any) []FieldError {
+	errs := []FieldError{}
+	validateValue(nil, reflect.TypeOf(s.Config), input, &errs)
+
+	for _, d := range s.Drivers {
+		drivers, ok := input[d.Map].(map[string]any)
+		if !ok {
+			continue
+		}
+		selected, _ := input[d.Selector].(string)
+		for name, conf := range drivers {
+			if selected != "" && name != selected {
+				continue
+			}
+			m, ok := conf.(map[string]any)
+			if !ok {
+				continue
+			}
+			ds, ok := Lookup(d.Kind, name)
+			if !ok {
+				continue
+			}
+			for _, err := range ds.Validate(m) {
+				err.Path = append([]string{d.Map, name}, err.Path...)
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// FieldType returns the type of the configuration field at the given path relative to the
+// schema. Paths into the driver maps are resolved with the schemas registered for the drivers.
+func (s Schema) FieldType(path []string) (reflect.Type, bool) {
+	if len(path) >= 2 {
+		for _, d := range s.Drivers {
+			if path[0] != d.Map {
+				continue
+			}
+			ds, ok := Lookup(d.Kind, path[1])
+			if !ok {
+				return nil, false
+			}
+			return ds.FieldType(path[2:])
+		}
+	}
+
+	t := reflect.TypeOf(s.Config)
+	for _, key := range path {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil {
+			return nil, false
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			fields, _ := structFields(t)
+			f, ok := lookupField(fields, key)
+			if !ok {
+				return nil, false
+			}
+			t = f.Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return nil, false
+		}
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t != nil
+}
+
+// FieldType returns the type of the configuration field at the given path of a configuration
+// file, e.g. grpc.services.storageprovider.drivers.posix.root, if a schema has been registered for it.
+func FieldType(path []string) (reflect.Type, bool) {
+	for _, s := range Schemas() {
+		p := s.Path()
+		if len(path) > len(p) && slices.Equal(path[:len(p)], p) {
+			return s.FieldType(path[len(p):])
+		}
+	}
+	return nil, false
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+func validateValue(path []string, t reflect.Type, v any, errs *[]FieldError) {
+	if v == nil || t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fail := func(expected string) {
+		*errs = append(*errs, FieldError{
+			Path: append([]string{}, path...),
+			Msg:  fmt.Sprintf("expected %s, got %s", expected, typeName(v)),
+		})
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return
+	case reflect.Struct:
+		if t == timeType {
+			if _, ok := v.(time.Time); !ok {
+				fail("datetime")
+			}
+			return
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			fail("table")
+			return
+		}
+		fields, remain := structFields(t)
+		for key, value := range m {
+			f, ok := lookupField(fields, key)
+			if !ok {
+				if !remain {
+					*errs = append(*errs, FieldError{Path: appendPath(path, key), Msg: "unknown key"})
+				}
+				continue
+			}
+			validateValue(appendPath(path, key), f.Type, value, errs)
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			fail("table")
+			return
+		}
+		for key, value := range m {
+			validateValue(appendPath(path, key), t.Elem(), value, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			fail("array")
+			return
+		}
+		for i := 0; i < rv.Len(); i++ {
+			validateValue(appendPath(path, fmt.Sprintf("%d", i)), t.Elem(), rv.Index(i).Interface(), errs)
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			fail("string")
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			fail("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !isNumber(v) {
+			if t == durationType {
+				fail("duration in nanoseconds")
+				return
+			}
+			fail("integer")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !isNumber(v) {
+			fail("integer")
+			return
+		}
+		if reflect.ValueOf(v).Convert(reflect.TypeOf(float64(0))).Float() < 0 {
+			fail("non-negative integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if !isNumber(v) {
+			fail("float")
+		}
+	}
+}
+
+// structFields returns the fields of a struct keyed by their configuration key. Embedded
+// structs are flattened. The returned bool is true if the struct collects unknown keys.
+func structFields(t reflect.Type) (map[string]reflect.StructField, bool) {
+	fields := map[string]reflect.StructField{}
+	remain := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if strings.Contains(opts, "remain") {
+			remain = true
+			continue
+		}
+		if ft.Kind() == reflect.Struct && (strings.Contains(opts, "squash") || (f.Anonymous && name == "")) {
+			embedded, r := structFields(ft)
+			for k, v := range embedded {
+				if _, ok := fields[k]; !ok {
+					fields[k] = v
+				}
+			}
+			remain = remain || r
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields, remain
+}
+
+// lookupField finds the field for a key, falling back to a case insensitive match like mapstructure does
+func lookupField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if f, ok := fields[key]; ok {
+		return f, true
+	}
+	for name, f := range fields {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func appendPath(path []string, key string) []string {
+	p := make([]string, 0, len(path)+1)
+	return append(append(p, path...), key)
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		return "float"
+	case time.Time:
+		return "datetime"
+	case map[string]any:
+		return "table"
+	}
+	if k := reflect.TypeOf(v).Kind(); k == reflect.Slice || k == reflect.Array {
+		return "array"
+	}
+	return reflect.TypeOf(v).String()
+}
@@ -0,0 +1,140 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cfg_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencloud-eu/reva/v2/pkg/utils/cfg"
+	"github.com/stretchr/testify/assert"
+)
+
+type Embedded struct {
+	E string `mapstructure:"e"`
+}
+
+type Service struct {
+	Embedded
+	S       string                            `mapstructure:"s" docs:"foo;A string."`
+	I       int                               `mapstructure:"i"`
+	U       uint64                            `mapstructure:"u"`
+	D       time.Duration                     `mapstructure:"d"`
+	L       []string                          `mapstructure:"l"`
+	T       struct{ B bool }                  `mapstructure:"t"`
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+}
+
+type Driver struct {
+	Root string `mapstructure:"root"`
+}
+
+func TestValidate(t *testing.T) {
+	cfg.Register("test.drivers", "foo", Driver{})
+	cfg.Register("test.services", "svc", Service{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "test.drivers"})
+	s, ok := cfg.Lookup("test.services", "svc")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"test", "services", "svc"}, s.Path())
+
+	errs := s.Validate(map[string]any{
+		"e":      "embedded",
+		"s":      "string",
+		"i":      int64(1),
+		"u":      int64(1),
+		"d":      int64(1000),
+		"l":      []any{"a", "b"},
+		"t":      map[string]any{"b": true},
+		"driver": "foo",
+		"drivers": map[string]any{
+			"foo": map[string]any{"root": "/var/tmp"},
+			"bar": map[string]any{"unknown": "ignored, not the enabled driver"},
+		},
+	})
+	assert.Empty(t, errs)
+
+	errs = s.Validate(map[string]any{
+		"typo":   "x",
+		"i":      "1",
+		"u":      int64(-1),
+		"d":      "1h",
+		"l":      []any{"a", int64(1)},
+		"t":      map[string]any{"b": "yes"},
+		"driver": "foo",
+		"drivers": map[string]any{
+			"foo": map[string]any{"rot": "/var/tmp"},
+		},
+	})
+	msgs := map[string]string{}
+	for _, err := range errs {
+		msgs[err.Error()] = ""
+	}
+	assert.Equal(t, map[string]string{
+		"typo: unknown key":                               "",
+		"i: expected integer, got string":                 "",
+		"u: expected non-negative integer, got integer":   "",
+		"d: expected duration in nanoseconds, got string": "",
+		"l.1: expected string, got integer":               "",
+		"t.b: expected boolean, got string":               "",
+		"drivers.foo.rot: unknown key":                    "",
+	}, msgs)
+}
+
+func TestFieldType(t *testing.T) {
+	cfg.Register("test.drivers", "foo", Driver{})
+	cfg.Register("test.services", "svc", Service{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "test.drivers"})
+
+	for path, kind := range map[string]reflect.Kind{
+		"test.services.svc.e":                 reflect.String,
+		"test.services.svc.i":                 reflect.Int,
+		"test.services.svc.d":                 reflect.Int64,
+		"test.services.svc.l.0":               reflect.String,
+		"test.services.svc.t.b":               reflect.Bool,
+		"test.services.svc.drivers.foo.root":  reflect.String,
+		"test.services.svc.drivers.bar.root":  reflect.Invalid,
+		"test.services.svc.unknown":           reflect.Invalid,
+		"test.services.unregistered.whatever": reflect.Invalid,
+	} {
+		ft, ok := cfg.FieldType(strings.Split(path, "."))
+		if kind == reflect.Invalid {
+			assert.False(t, ok, path)
+			continue
+		}
+		assert.True(t, ok, path)
+		assert.Equal(t, kind, ft.Kind(), path)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	cfg.Register("test.drivers", "foo", Driver{})
+	cfg.Register("test.services", "svc", Service{}, cfg.Driver{Selector: "driver", Map: "drivers", Kind: "test.drivers"})
+
+	schema := cfg.JSONSchema()
+	definitions := schema["definitions"].(map[string]any)
+	assert.Contains(t, definitions, "test.drivers.foo")
+
+	svc := schema["properties"].(map[string]any)["test"].(map[string]any)["properties"].(map[string]any)["services"].(map[string]any)["properties"].(map[string]any)["svc"].(map[string]any)
+	props := svc["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "default": "foo", "description": "A string."}, props["s"])
+	assert.Contains(t, props, "e")
+	drivers := props["drivers"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/definitions/test.drivers.foo"}, drivers["foo"])
+}
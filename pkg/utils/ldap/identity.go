@@ -20,7 +20,9 @@ package ldap
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	identityUser "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/go-ldap/ldap/v3"
@@ -34,6 +36,11 @@ import (
 type Identity struct {
 	User  userConfig  `mapstructure:",squash"`
 	Group groupConfig `mapstructure:",squash"`
+	// PageSize is the number of entries requested per page using the RFC 2696
+	// paged results control. Set to 0 to disable paging.
+	PageSize uint32 `mapstructure:"page_size"`
+	// WriteEnabled allows creating, updating and deleting users and groups.
+	WriteEnabled bool `mapstructure:"write_enabled"`
 }
 
 type userConfig struct {
@@ -48,6 +55,9 @@ type userConfig struct {
 	Schema              userSchema `mapstructure:"user_schema"`
 	SubstringFilterType string     `mapstructure:"user_substring_filter_type"`
 	substringFilterVal  int
+	// CreateObjectclasses are the object classes of newly created users in
+	// addition to the configured user objectclass.
+	CreateObjectclasses []string `mapstructure:"user_create_objectclasses"`
 }
 
 type groupConfig struct {
@@ -61,6 +71,16 @@ type groupConfig struct {
 	substringFilterVal  int
 	// LocalDisabledDN contains the full DN of a group that contains disabled users.
 	LocalDisabledDN string `mapstructure:"group_local_disabled_dn"`
+	// NestedMembership configures how memberships in nested groups are resolved,
+	// can be "none", "recursive" or "in_chain" (Active Directory only).
+	NestedMembership string `mapstructure:"group_nested_membership"`
+	// MembershipCacheTTL is the number of seconds resolved group memberships are
+	// cached. Set to 0 to disable the cache.
+	MembershipCacheTTL int `mapstructure:"group_membership_cache_ttl"`
+	membershipCache    *membershipCache
+	// CreateObjectclasses are the object classes of newly created groups in
+	// addition to the configured group objectclass.
+	CreateObjectclasses []string `mapstructure:"group_create_objectclasses"`
 }
 
 type groupSchema struct {
@@ -126,13 +146,16 @@ var groupDefaults = groupConfig{
 		Member:          "memberUid",
 	},
 	SubstringFilterType: "initial",
+	NestedMembership:    nestedNone,
+	MembershipCacheTTL:  0,
 }
 
 // New initializes the default config
 func New() Identity {
 	return Identity{
-		User:  userDefaults,
-		Group: groupDefaults,
+		User:     userDefaults,
+		Group:    groupDefaults,
+		PageSize: 500,
 	}
 }
 
@@ -171,6 +194,24 @@ func (i *Identity) Setup() error {
 		return fmt.Errorf("invalid disable mechanism setting: %s", i.User.DisableMechanism)
 	}
 
+	switch i.Group.NestedMembership {
+	case "", nestedNone:
+	case nestedRecursive, nestedInChain:
+		if strings.ToLower(i.Group.Objectclass) == "posixgroup" {
+			return fmt.Errorf("error configuring nested group membership, posixGroup members are not DNs")
+		}
+	default:
+		return fmt.Errorf("invalid nested group membership setting: %s", i.Group.NestedMembership)
+	}
+
+	if i.User.CreateObjectclasses == nil {
+		i.User.CreateObjectclasses = []string{"inetOrgPerson"}
+	}
+
+	if i.Group.MembershipCacheTTL > 0 {
+		i.Group.membershipCache = newMembershipCache(time.Duration(i.Group.MembershipCacheTTL) * time.Second)
+	}
+
 	return nil
 }
 
@@ -289,7 +330,7 @@ func (i *Identity) GetLDAPUsers(log *zerolog.Logger, lc ldap.Client, query strin
 	)
 
 	log.Debug().Str("backend", "ldap").Str("basedn", i.User.BaseDN).Str("filter", filter).Int("scope", i.User.scopeVal).Msg("LDAP Search")
-	sr, err := lc.Search(searchRequest)
+	sr, err := i.search(lc, searchRequest)
 	if err != nil {
 		log.Debug().Str("backend", "ldap").Err(err).Str("filter", filter).Msg("Error searching users")
 		return nil, errtypes.NotFound(query)
@@ -314,7 +355,7 @@ func (i *Identity) IsLDAPUserInDisabledGroup(log *zerolog.Logger, lc ldap.Client
 		nil,
 	)
 	log.Debug().Str("backend", "ldap").Str("basedn", i.Group.LocalDisabledDN).Str("filter", filter).Int("scope", i.Group.scopeVal).Msg("LDAP Search")
-	sr, err := lc.Search(searchRequest)
+	sr, err := i.search(lc, searchRequest)
 	if err != nil {
 		log.Error().Str("backend", "ldap").Err(err).Str("filter", filter).Msg("Error looking up error group")
 		// Err on the side of caution.
@@ -325,36 +366,32 @@ func (i *Identity) IsLDAPUserInDisabledGroup(log *zerolog.Logger, lc ldap.Client
 }
 
 // GetLDAPUserGroups looks up the group member ship of the supplied LDAP user entry.
+// Depending on the nested membership setting this includes the groups the user
+// is only a member of through other groups.
 // Returns a slice of strings with groupids
 func (i *Identity) GetLDAPUserGroups(log *zerolog.Logger, lc ldap.Client, userEntry *ldap.Entry) ([]string, error) {
-	var memberValue string
-
-	if strings.ToLower(i.Group.Objectclass) == "posixgroup" {
-		// posixGroup usually means that the member attribute just contains the username
-		memberValue = userEntry.GetEqualFoldAttributeValue(i.User.Schema.Username)
-	} else {
-		// In all other case we assume the member Attribute to contain full LDAP DNs
-		memberValue = userEntry.DN
+	if groups, ok := i.cachedMembership("groups:" + userEntry.DN); ok {
+		return slices.Clone(groups.([]string)), nil
 	}
 
-	filter := i.getGroupMemberFilter(memberValue)
-	searchRequest := ldap.NewSearchRequest(
-		i.Group.BaseDN, i.Group.scopeVal,
-		ldap.NeverDerefAliases, 0, 0, false,
-		filter,
-		[]string{i.Group.Schema.ID},
-		nil,
-	)
+	memberValue := i.memberValue(userEntry)
 
-	log.Debug().Str("backend", "ldap").Str("basedn", i.Group.BaseDN).Str("filter", filter).Int("scope", i.Group.scopeVal).Msg("LDAP Search")
-	sr, err := lc.Search(searchRequest)
+	var entries []*ldap.Entry
+	var err error
+	switch i.Group.NestedMembership {
+	case nestedInChain:
+		entries, err = i.searchGroups(log, lc, i.getGroupMemberInChainFilter(memberValue))
+	case nestedRecursive:
+		entries, err = i.getNestedGroups(log, lc, memberValue)
+	default:
+		entries, err = i.searchGroups(log, lc, i.getGroupMemberFilter(memberValue))
+	}
 	if err != nil {
-		log.Debug().Str("backend", "ldap").Err(err).Str("filter", filter).Msg("Error looking up group memberships")
 		return []string{}, err
 	}
 
-	groups := make([]string, 0, len(sr.Entries))
-	for _, entry := range sr.Entries {
+	groups := make([]string, 0, len(entries))
+	for _, entry := range entries {
 		// FIXME this makes the users groups use the cn, not an immutable id
 		// FIXME 1. use the memberof or members attribute of a user to get the groups
 		// FIXME 2. ook up the id for each group
@@ -372,9 +409,29 @@ func (i *Identity) GetLDAPUserGroups(log *zerolog.Logger, lc ldap.Client, userEn
 
 		groups = append(groups, groupID)
 	}
+	i.cacheMembership("groups:"+userEntry.DN, slices.Clone(groups))
 	return groups, nil
 }
 
+// searchGroups returns the DN and ID of the groups matching the supplied filter.
+func (i *Identity) searchGroups(log *zerolog.Logger, lc ldap.Client, filter string) ([]*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		i.Group.BaseDN, i.Group.scopeVal,
+		ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{i.Group.Schema.ID},
+		nil,
+	)
+
+	log.Debug().Str("backend", "ldap").Str("basedn", i.Group.BaseDN).Str("filter", filter).Int("scope", i.Group.scopeVal).Msg("LDAP Search")
+	sr, err := i.search(lc, searchRequest)
+	if err != nil {
+		log.Debug().Str("backend", "ldap").Err(err).Str("filter", filter).Msg("Error looking up group memberships")
+		return nil, err
+	}
+	return sr.Entries, nil
+}
+
 // GetLDAPGroupByID looks up a group by the supplied Id. Returns the corresponding
 // ldap.Entry
 func (i *Identity) GetLDAPGroupByID(log *zerolog.Logger, lc ldap.Client, id string) (*ldap.Entry, error) {
@@ -450,7 +507,7 @@ func (i *Identity) GetLDAPGroups(log *zerolog.Logger, lc ldap.Client, query stri
 		nil,
 	)
 
-	sr, err := lc.Search(searchRequest)
+	sr, err := i.search(lc, searchRequest)
 	if err != nil {
 		log.Debug().Str("backend", "ldap").Err(err).Str("query", query).Msg("Error search for groups")
 		return nil, errtypes.NotFound(query)
@@ -459,12 +516,39 @@ func (i *Identity) GetLDAPGroups(log *zerolog.Logger, lc ldap.Client, query stri
 }
 
 // GetLDAPGroupMembers looks up all members of the supplied LDAP group entry and returns the
-// corresponding LDAP user entries
+// corresponding LDAP user entries. Depending on the nested membership setting this includes
+// the members of nested groups.
 func (i *Identity) GetLDAPGroupMembers(log *zerolog.Logger, lc ldap.Client, group *ldap.Entry) ([]*ldap.Entry, error) {
+	if members, ok := i.cachedMembership("members:" + group.DN); ok {
+		return slices.Clone(members.([]*ldap.Entry)), nil
+	}
+
+	var memberEntries []*ldap.Entry
+	var err error
+	switch i.Group.NestedMembership {
+	case nestedInChain:
+		memberEntries, err = i.getLDAPUsersMemberOfInChain(log, lc, group.DN)
+	case nestedRecursive:
+		memberEntries = i.getNestedMembers(log, lc, group, map[string]bool{strings.ToLower(group.DN): true})
+	default:
+		memberEntries = i.getDirectMembers(log, lc, group)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	i.cacheMembership("members:"+group.DN, slices.Clone(memberEntries))
+	return memberEntries, nil
+}
+
+func (i *Identity) getDirectMembers(log *zerolog.Logger, lc ldap.Client, group *ldap.Entry) []*ldap.Entry {
 	members := group.GetEqualFoldAttributeValues(i.Group.Schema.Member)
 	log.Debug().Str("dn", group.DN).Interface("member", members).Msg("Get Group members")
 	memberEntries := make([]*ldap.Entry, 0, len(members))
 	for _, member := range members {
+		if isMemberPlaceholder(group, member) {
+			continue
+		}
 		var e *ldap.Entry
 		var err error
 		if strings.ToLower(i.Group.Objectclass) == "posixgroup" {
@@ -479,7 +563,7 @@ func (i *Identity) GetLDAPGroupMembers(log *zerolog.Logger, lc ldap.Client, grou
 		memberEntries = append(memberEntries, e)
 	}
 
-	return memberEntries, nil
+	return memberEntries
 }
 
 func filterEscapeBinaryUUID(value uuid.UUID) string {
@@ -642,6 +726,17 @@ func stringToFilterType(t string) (int, error) {
 	return s, nil
 }
 
+// memberValue returns the value of the group member attribute referencing the
+// supplied user entry.
+func (i *Identity) memberValue(userEntry *ldap.Entry) string {
+	if strings.ToLower(i.Group.Objectclass) == "posixgroup" {
+		// posixGroup usually means that the member attribute just contains the username
+		return userEntry.GetEqualFoldAttributeValue(i.User.Schema.Username)
+	}
+	// In all other case we assume the member Attribute to contain full LDAP DNs
+	return userEntry.DN
+}
+
 func (i *Identity) getGroupMemberFilter(memberName string) string {
 	return fmt.Sprintf("(&%s(objectclass=%s)(%s=%s))",
 		i.Group.Filter,
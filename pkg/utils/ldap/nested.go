@@ -0,0 +1,253 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// nestedNone only resolves direct group memberships
+	nestedNone = "none"
+	// nestedRecursive resolves nested group memberships by expanding groups client side
+	nestedRecursive = "recursive"
+	// nestedInChain lets the server resolve nested group memberships using the
+	// LDAP_MATCHING_RULE_IN_CHAIN extensible match rule of Active Directory
+	nestedInChain = "in_chain"
+
+	matchingRuleInChain = "1.2.840.113556.1.4.1941"
+)
+
+// search runs the supplied search request. Searches that can return more than
+// one entry use the paged results control if a page size is configured, so
+// that big directories don't fail with a size limit exceeded error.
+func (i *Identity) search(lc ldap.Client, searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if i.PageSize == 0 || searchRequest.SizeLimit == 1 {
+		return lc.Search(searchRequest)
+	}
+	return lc.SearchWithPaging(searchRequest, i.PageSize)
+}
+
+// membershipCache caches resolved group memberships for a fixed time. Expired entries are
+// dropped when they are read or while the cache is written to, so it needs no goroutine
+// that would have to be stopped.
+type membershipCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]membershipCacheEntry
+	nextSweep time.Time
+}
+
+type membershipCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newMembershipCache(ttl time.Duration) *membershipCache {
+	return &membershipCache{ttl: ttl, entries: map[string]membershipCacheEntry{}}
+}
+
+func (c *membershipCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *membershipCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.After(c.nextSweep) {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+	c.entries[key] = membershipCacheEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+func (c *membershipCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]membershipCacheEntry{}
+}
+
+func (i *Identity) cachedMembership(key string) (interface{}, bool) {
+	if i.Group.membershipCache == nil {
+		return nil, false
+	}
+	return i.Group.membershipCache.get(key)
+}
+
+func (i *Identity) cacheMembership(key string, value interface{}) {
+	if i.Group.membershipCache == nil {
+		return
+	}
+	i.Group.membershipCache.set(key, value)
+}
+
+// getNestedGroups walks up the group hierarchy starting with the groups that
+// have the supplied DN as a direct member. Every group is only expanded once,
+// so cycles in the directory don't lead to endless lookups.
+func (i *Identity) getNestedGroups(log *zerolog.Logger, lc ldap.Client, memberDN string) ([]*ldap.Entry, error) {
+	visited := map[string]bool{}
+	groups := []*ldap.Entry{}
+	queue := []string{memberDN}
+	for len(queue) > 0 {
+		dn := queue[0]
+		queue = queue[1:]
+
+		entries, err := i.searchGroups(log, lc, i.getGroupMemberFilter(dn))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			key := strings.ToLower(e.DN)
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			groups = append(groups, e)
+			queue = append(queue, e.DN)
+		}
+	}
+	return groups, nil
+}
+
+// getNestedMembers returns the users that are members of the supplied group
+// either directly or through any of its sub-groups. visited contains the
+// lower-cased DNs of the groups that were already expanded.
+func (i *Identity) getNestedMembers(log *zerolog.Logger, lc ldap.Client, group *ldap.Entry, visited map[string]bool) []*ldap.Entry {
+	seen := map[string]bool{}
+	memberEntries := []*ldap.Entry{}
+	queue := []*ldap.Entry{group}
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+
+		members := g.GetEqualFoldAttributeValues(i.Group.Schema.Member)
+		log.Debug().Str("dn", g.DN).Interface("member", members).Msg("Get Group members")
+		for _, member := range members {
+			key := strings.ToLower(member)
+			if member == "" || seen[key] || visited[key] {
+				// skip the placeholder of an empty groupOfNames
+				continue
+			}
+			if e, err := i.GetLDAPUserByDN(log, lc, member); err == nil {
+				seen[key] = true
+				memberEntries = append(memberEntries, e)
+				continue
+			}
+			visited[key] = true
+			sub, err := i.getLDAPGroupByDN(log, lc, member)
+			if err != nil {
+				log.Warn().Err(err).Interface("member", member).Msg("Failed read user or group entry for member")
+				continue
+			}
+			queue = append(queue, sub)
+		}
+	}
+	return memberEntries
+}
+
+// getLDAPUsersMemberOfInChain lets the server resolve all users that are
+// members of the supplied group, directly or through nested groups.
+func (i *Identity) getLDAPUsersMemberOfInChain(log *zerolog.Logger, lc ldap.Client, groupDN string) ([]*ldap.Entry, error) {
+	filter := fmt.Sprintf("(&%s(objectclass=%s)(memberOf:%s:=%s))",
+		i.User.Filter,
+		i.User.Objectclass,
+		matchingRuleInChain,
+		ldap.EscapeFilter(groupDN),
+	)
+	searchRequest := ldap.NewSearchRequest(
+		i.User.BaseDN,
+		i.User.scopeVal, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{
+			i.User.Schema.DisplayName,
+			i.User.Schema.ID,
+			i.User.Schema.Mail,
+			i.User.Schema.Username,
+			i.User.Schema.UIDNumber,
+			i.User.Schema.GIDNumber,
+			i.User.EnabledProperty,
+		},
+		nil,
+	)
+
+	log.Debug().Str("backend", "ldap").Str("basedn", i.User.BaseDN).Str("filter", filter).Int("scope", i.User.scopeVal).Msg("LDAP Search")
+	sr, err := i.search(lc, searchRequest)
+	if err != nil {
+		log.Debug().Str("backend", "ldap").Err(err).Str("filter", filter).Msg("Error looking up nested group members")
+		return nil, err
+	}
+	return sr.Entries, nil
+}
+
+// getLDAPGroupByDN looks up a single group by the supplied LDAP DN
+func (i *Identity) getLDAPGroupByDN(log *zerolog.Logger, lc ldap.Client, dn string) (*ldap.Entry, error) {
+	filter := fmt.Sprintf("(&%s(objectclass=%s))", i.Group.Filter, i.Group.Objectclass)
+	searchRequest := ldap.NewSearchRequest(
+		dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		filter,
+		[]string{
+			i.Group.Schema.ID,
+			i.Group.Schema.Member,
+		},
+		nil,
+	)
+	log.Debug().Str("backend", "ldap").Str("basedn", dn).Str("filter", filter).Int("scope", ldap.ScopeBaseObject).Msg("LDAP Search")
+	res, err := lc.Search(searchRequest)
+	if err != nil {
+		log.Debug().Str("backend", "ldap").Err(err).Str("dn", dn).Msg("Error looking up group by DN")
+		return nil, errtypes.NotFound(dn)
+	}
+	if len(res.Entries) == 0 {
+		return nil, errtypes.NotFound(dn)
+	}
+	return res.Entries[0], nil
+}
+
+func (i *Identity) getGroupMemberInChainFilter(memberName string) string {
+	return fmt.Sprintf("(&%s(objectclass=%s)(%s:%s:=%s))",
+		i.Group.Filter,
+		i.Group.Objectclass,
+		i.Group.Schema.Member,
+		matchingRuleInChain,
+		ldap.EscapeFilter(memberName),
+	)
+}
@@ -0,0 +1,226 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/rs/zerolog"
+)
+
+// fakeDirectory is a minimal ldap.Client that answers the searches issued by Identity
+type fakeDirectory struct {
+	ldap.Client
+	entries  []*ldap.Entry
+	searches int
+	paged    int
+}
+
+var memberFilter = regexp.MustCompile(`\(member=([^)]*)\)`)
+
+func (d *fakeDirectory) Search(sr *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d.searches++
+	res := &ldap.SearchResult{}
+	if m := memberFilter.FindStringSubmatch(sr.Filter); m != nil {
+		for _, e := range d.entries {
+			for _, v := range e.GetEqualFoldAttributeValues("member") {
+				if strings.EqualFold(v, m[1]) {
+					res.Entries = append(res.Entries, e)
+				}
+			}
+		}
+		return res, nil
+	}
+	for _, e := range d.entries {
+		if strings.EqualFold(e.DN, sr.BaseDN) && strings.Contains(sr.Filter, "(objectclass="+e.GetAttributeValue("objectClass")+")") {
+			res.Entries = append(res.Entries, e)
+		}
+	}
+	return res, nil
+}
+
+func (d *fakeDirectory) SearchWithPaging(sr *ldap.SearchRequest, _ uint32) (*ldap.SearchResult, error) {
+	d.paged++
+	return d.Search(sr)
+}
+
+func newFakeDirectory() *fakeDirectory {
+	user := func(dn, name string) *ldap.Entry {
+		return ldap.NewEntry(dn, map[string][]string{"objectClass": {"inetOrgPerson"}, "uid": {name}})
+	}
+	group := func(dn, name string, members ...string) *ldap.Entry {
+		return ldap.NewEntry(dn, map[string][]string{"objectClass": {"groupOfNames"}, "cn": {name}, "member": members})
+	}
+	return &fakeDirectory{entries: []*ldap.Entry{
+		user("uid=alice,ou=users,dc=test", "alice"),
+		user("uid=bob,ou=users,dc=test", "bob"),
+		group("cn=company,ou=groups,dc=test", "company", "cn=department,ou=groups,dc=test", "uid=bob,ou=users,dc=test"),
+		group("cn=department,ou=groups,dc=test", "department", "cn=team,ou=groups,dc=test"),
+		// team and department form a cycle
+		group("cn=team,ou=groups,dc=test", "team", "uid=alice,ou=users,dc=test", "cn=department,ou=groups,dc=test"),
+	}}
+}
+
+func newTestIdentity(t *testing.T, nested string, ttl int) *Identity {
+	i := New()
+	i.User.BaseDN = "ou=users,dc=test"
+	i.User.Objectclass = "inetOrgPerson"
+	i.User.Schema.ID = "uid"
+	i.Group.BaseDN = "ou=groups,dc=test"
+	i.Group.Objectclass = "groupOfNames"
+	i.Group.Schema.ID = "cn"
+	i.Group.Schema.Member = "member"
+	i.Group.NestedMembership = nested
+	i.Group.MembershipCacheTTL = ttl
+	if err := i.Setup(); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	return &i
+}
+
+func TestGetLDAPUserGroupsNested(t *testing.T) {
+	log := zerolog.Nop()
+	alice := ldap.NewEntry("uid=alice,ou=users,dc=test", nil)
+
+	for nested, expected := range map[string][]string{
+		nestedNone:      {"team"},
+		nestedRecursive: {"company", "department", "team"},
+	} {
+		d := newFakeDirectory()
+		i := newTestIdentity(t, nested, 0)
+		groups, err := i.GetLDAPUserGroups(&log, d, alice)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", nested, err)
+		}
+		sort.Strings(groups)
+		if strings.Join(groups, ",") != strings.Join(expected, ",") {
+			t.Fatalf("%s: expected groups %v, got %v", nested, expected, groups)
+		}
+		if d.paged == 0 {
+			t.Fatalf("%s: expected the group search to use paging", nested)
+		}
+	}
+}
+
+func TestGetLDAPGroupMembersNested(t *testing.T) {
+	log := zerolog.Nop()
+	d := newFakeDirectory()
+	i := newTestIdentity(t, nestedRecursive, 0)
+
+	members, err := i.GetLDAPGroupMembers(&log, d, d.entries[2])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, m := range members {
+		names = append(names, m.GetAttributeValue("uid"))
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "alice,bob" {
+		t.Fatalf("expected members alice and bob, got %v", names)
+	}
+}
+
+func TestInChainFilter(t *testing.T) {
+	i := newTestIdentity(t, nestedInChain, 0)
+	expected := "(&(objectclass=groupOfNames)(member:1.2.840.113556.1.4.1941:=uid=alice,ou=users,dc=test))"
+	if f := i.getGroupMemberInChainFilter("uid=alice,ou=users,dc=test"); f != expected {
+		t.Fatalf("expected filter %s, got %s", expected, f)
+	}
+}
+
+func TestMembershipCache(t *testing.T) {
+	log := zerolog.Nop()
+	d := newFakeDirectory()
+	i := newTestIdentity(t, nestedRecursive, 60)
+	alice := ldap.NewEntry("uid=alice,ou=users,dc=test", nil)
+
+	groups, err := i.GetLDAPUserGroups(&log, d, alice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := strings.Join(groups, ",")
+	searches := d.searches
+	// callers must not be able to modify the cached memberships
+	groups[0] = "modified"
+	cached, err := i.GetLDAPUserGroups(&log, d, alice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.searches != searches {
+		t.Fatalf("expected the memberships to be served from the cache")
+	}
+	if strings.Join(cached, ",") != expected {
+		t.Fatalf("expected cached groups %s, got %v", expected, cached)
+	}
+	cached[0] = "modified"
+	if again, _ := i.GetLDAPUserGroups(&log, d, alice); strings.Join(again, ",") != expected {
+		t.Fatalf("expected cached groups %s, got %v", expected, again)
+	}
+}
+
+func TestMembershipCacheDisabledByDefault(t *testing.T) {
+	i := New()
+	if err := i.Setup(); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if i.Group.membershipCache != nil {
+		t.Fatalf("expected the membership cache to be disabled by default")
+	}
+}
+
+func TestMembershipCacheExpires(t *testing.T) {
+	c := newMembershipCache(time.Millisecond)
+	c.set("key", "value")
+	if v, ok := c.get("key"); !ok || v != "value" {
+		t.Fatalf("expected a cached value, got %v", v)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := c.get("key"); ok {
+		t.Fatalf("expected the entry to expire")
+	}
+}
+
+func TestGetLDAPGroupMembersSkipsPlaceholder(t *testing.T) {
+	log := zerolog.Nop()
+	d := newFakeDirectory()
+	i := newTestIdentity(t, nestedRecursive, 0)
+	empty := ldap.NewEntry("cn=empty,ou=groups,dc=test", map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"empty"}, "member": {""}})
+
+	members, err := i.GetLDAPGroupMembers(&log, d, empty)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(members) != 0 || d.searches != 0 {
+		t.Fatalf("expected the placeholder member to be skipped, got %d members after %d searches", len(members), d.searches)
+	}
+}
+
+func TestSetupRejectsNestedPosixGroups(t *testing.T) {
+	i := New()
+	i.Group.NestedMembership = nestedRecursive
+	if err := i.Setup(); err == nil {
+		t.Fatal("expected error but got none")
+	}
+}
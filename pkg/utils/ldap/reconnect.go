@@ -304,7 +304,19 @@ func (c *ConnWithReconnect) PasswordModify(*ldap.PasswordModifyRequest) (*ldap.P
 
 // SearchWithPaging implements the ldap.Client interface
 func (c *ConnWithReconnect) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
-	return nil, ldap.NewError(ldap.LDAPResultNotSupported, fmt.Errorf("not implemented"))
+	var err error
+	var res *ldap.SearchResult
+
+	retryErr := c.retry(func(c ldap.Client) error {
+		// the paging control carries the cookie of the connection it was used
+		// on, so every attempt starts with a fresh copy of the request
+		sr := *searchRequest
+		sr.Controls = append([]ldap.Control(nil), searchRequest.Controls...)
+		res, err = c.SearchWithPaging(&sr, pagingSize)
+		return err
+	})
+
+	return res, retryErr
 }
 
 // SearchAsync implements the ldap.Client interface
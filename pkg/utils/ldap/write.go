@@ -0,0 +1,378 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	identityUser "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/rs/zerolog"
+)
+
+// generatedIDAttributes are operational attributes that are assigned by the
+// directory server and must not be set when creating entries.
+var generatedIDAttributes = map[string]bool{
+	"entryuuid":   true,
+	"objectguid":  true,
+	"nsuniqueid":  true,
+	"ipauniqueid": true,
+	"orclguid":    true,
+	"guid":        true,
+}
+
+// AddLDAPUser creates a new user entry below the user base DN and returns the
+// created entry. Passwords are set separately with SetLDAPPassword.
+func (i *Identity) AddLDAPUser(log *zerolog.Logger, lc ldap.Client, u *identityUser.User) (*ldap.Entry, error) {
+	if err := i.checkWritable(); err != nil {
+		return nil, err
+	}
+	if u.GetUsername() == "" {
+		return nil, errtypes.BadRequest("username missing")
+	}
+
+	classes := objectclasses(i.User.CreateObjectclasses, i.User.Objectclass)
+	attrs := map[string][]string{}
+	set := func(attr, value string) {
+		if attr != "" && value != "" {
+			attrs[attr] = []string{value}
+		}
+	}
+
+	set(i.User.Schema.Username, u.Username)
+	set("cn", u.Username)
+	if hasObjectclass(classes, "inetOrgPerson") || hasObjectclass(classes, "person") {
+		set("sn", u.Username)
+	}
+	set(i.User.Schema.DisplayName, u.DisplayName)
+	set(i.User.Schema.Mail, u.Mail)
+	if u.UidNumber != 0 {
+		set(i.User.Schema.UIDNumber, strconv.FormatInt(u.UidNumber, 10))
+	}
+	if u.GidNumber != 0 {
+		set(i.User.Schema.GIDNumber, strconv.FormatInt(u.GidNumber, 10))
+	}
+	if hasObjectclass(classes, "posixAccount") {
+		if u.UidNumber == 0 || u.GidNumber == 0 {
+			return nil, errtypes.BadRequest("posixAccount users require a uid and gid number")
+		}
+		set("uid", u.Username)
+		set("homeDirectory", "/home/"+u.Username)
+	}
+
+	var rawID []byte
+	if !generatedIDAttributes[strings.ToLower(i.User.Schema.ID)] {
+		id := u.GetId().GetOpaqueId()
+		if id == "" {
+			id = uuid.New().String()
+		}
+		if i.User.Schema.IDIsOctetString {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return nil, errtypes.BadRequest("user id is not a uuid")
+			}
+			rawID = parsed[:]
+		} else {
+			set(i.User.Schema.ID, id)
+		}
+	}
+
+	dn := fmt.Sprintf("%s=%s,%s", i.User.Schema.Username, ldap.EscapeDN(u.Username), i.User.BaseDN)
+	ar := newAddRequest(dn, classes, attrs)
+	if rawID != nil {
+		ar.Attribute(i.User.Schema.ID, []string{string(rawID)})
+	}
+
+	log.Debug().Str("backend", "ldap").Str("dn", dn).Msg("LDAP Add user")
+	if err := lc.Add(ar); err != nil {
+		return nil, ldapWriteError(err, u.Username)
+	}
+	return i.GetLDAPUserByDN(log, lc, dn)
+}
+
+// UpdateLDAPUser replaces the display name and mail of the supplied user entry.
+func (i *Identity) UpdateLDAPUser(log *zerolog.Logger, lc ldap.Client, userEntry *ldap.Entry, u *identityUser.User) error {
+	if err := i.checkWritable(); err != nil {
+		return err
+	}
+
+	mr := ldap.NewModifyRequest(userEntry.DN, nil)
+	replace := func(attr, value string) {
+		if attr == "" || value == userEntry.GetEqualFoldAttributeValue(attr) {
+			return
+		}
+		if value == "" {
+			mr.Delete(attr, nil)
+			return
+		}
+		mr.Replace(attr, []string{value})
+	}
+	replace(i.User.Schema.DisplayName, u.DisplayName)
+	replace(i.User.Schema.Mail, u.Mail)
+	if len(mr.Changes) > 0 {
+		log.Debug().Str("backend", "ldap").Str("dn", userEntry.DN).Msg("LDAP Modify user")
+		if err := lc.Modify(mr); err != nil {
+			return ldapWriteError(err, userEntry.DN)
+		}
+	}
+	return nil
+}
+
+// SetLDAPPassword sets the password of the user entry with the password modify
+// extended operation (RFC 3062), which lets the server hash the password
+// according to its policy.
+func (i *Identity) SetLDAPPassword(log *zerolog.Logger, lc ldap.Client, dn, password string) error {
+	if err := i.checkWritable(); err != nil {
+		return err
+	}
+	log.Debug().Str("backend", "ldap").Str("dn", dn).Msg("LDAP Password Modify")
+	if _, err := lc.PasswordModify(ldap.NewPasswordModifyRequest(dn, "", password)); err != nil {
+		return ldapWriteError(err, dn)
+	}
+	return nil
+}
+
+// DeleteLDAPUser removes the user from all groups it is a direct member of
+// and deletes the user entry.
+func (i *Identity) DeleteLDAPUser(log *zerolog.Logger, lc ldap.Client, userEntry *ldap.Entry) error {
+	if err := i.checkWritable(); err != nil {
+		return err
+	}
+
+	groups, err := i.searchGroups(log, lc, i.getGroupMemberFilter(i.memberValue(userEntry)))
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		// re-read the group to get its members
+		if group, err = i.getLDAPGroupByDN(log, lc, group.DN); err != nil {
+			return err
+		}
+		if err := i.RemoveLDAPGroupMember(log, lc, group, userEntry); err != nil {
+			return err
+		}
+	}
+
+	log.Debug().Str("backend", "ldap").Str("dn", userEntry.DN).Msg("LDAP Delete user")
+	if err := lc.Del(ldap.NewDelRequest(userEntry.DN, nil)); err != nil {
+		return ldapWriteError(err, userEntry.DN)
+	}
+	i.purgeMembershipCache()
+	return nil
+}
+
+// AddLDAPGroup creates a new group entry below the group base DN and returns
+// the created entry.
+func (i *Identity) AddLDAPGroup(log *zerolog.Logger, lc ldap.Client, g *grouppb.Group) (*ldap.Entry, error) {
+	if err := i.checkWritable(); err != nil {
+		return nil, err
+	}
+	if g.GetGroupName() == "" {
+		return nil, errtypes.BadRequest("group name missing")
+	}
+
+	classes := objectclasses(i.Group.CreateObjectclasses, i.Group.Objectclass)
+	attrs := map[string][]string{}
+	set := func(attr, value string) {
+		if attr != "" && value != "" {
+			attrs[attr] = []string{value}
+		}
+	}
+
+	set(i.Group.Schema.Groupname, g.GroupName)
+	set("cn", g.GroupName)
+	if !strings.EqualFold(i.Group.Schema.DisplayName, i.Group.Schema.Groupname) {
+		set(i.Group.Schema.DisplayName, g.DisplayName)
+	}
+	set(i.Group.Schema.Mail, g.Mail)
+	if g.GidNumber != 0 {
+		set(i.Group.Schema.GIDNumber, strconv.FormatInt(g.GidNumber, 10))
+	} else if hasObjectclass(classes, "posixGroup") {
+		return nil, errtypes.BadRequest("posixGroup groups require a gid number")
+	}
+	dn := fmt.Sprintf("%s=%s,%s", i.Group.Schema.Groupname, ldap.EscapeDN(g.GroupName), i.Group.BaseDN)
+	if i.requiresMember() {
+		// groupOfNames and groupOfUniqueNames require at least one member,
+		// the group references itself until members are added.
+		attrs[i.Group.Schema.Member] = []string{dn}
+	}
+
+	var rawID []byte
+	if !generatedIDAttributes[strings.ToLower(i.Group.Schema.ID)] {
+		id := g.GetId().GetOpaqueId()
+		if id == "" {
+			id = uuid.New().String()
+		}
+		if i.Group.Schema.IDIsOctetString {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return nil, errtypes.BadRequest("group id is not a uuid")
+			}
+			rawID = parsed[:]
+		} else {
+			set(i.Group.Schema.ID, id)
+		}
+	}
+
+	ar := newAddRequest(dn, classes, attrs)
+	if rawID != nil {
+		ar.Attribute(i.Group.Schema.ID, []string{string(rawID)})
+	}
+
+	log.Debug().Str("backend", "ldap").Str("dn", dn).Msg("LDAP Add group")
+	if err := lc.Add(ar); err != nil {
+		return nil, ldapWriteError(err, g.GroupName)
+	}
+	return i.GetLDAPGroupByAttribute(log, lc, "group_name", g.GroupName)
+}
+
+// DeleteLDAPGroup deletes the supplied group entry.
+func (i *Identity) DeleteLDAPGroup(log *zerolog.Logger, lc ldap.Client, group *ldap.Entry) error {
+	if err := i.checkWritable(); err != nil {
+		return err
+	}
+
+	log.Debug().Str("backend", "ldap").Str("dn", group.DN).Msg("LDAP Delete group")
+	if err := lc.Del(ldap.NewDelRequest(group.DN, nil)); err != nil {
+		return ldapWriteError(err, group.DN)
+	}
+	i.purgeMembershipCache()
+	return nil
+}
+
+// AddLDAPGroupMember adds the user to the members of the group and drops the
+// placeholder of an empty group. Adding an existing member is a no-op.
+func (i *Identity) AddLDAPGroupMember(log *zerolog.Logger, lc ldap.Client, group, userEntry *ldap.Entry) error {
+	if err := i.checkWritable(); err != nil {
+		return err
+	}
+
+	mr := ldap.NewModifyRequest(group.DN, nil)
+	mr.Add(i.Group.Schema.Member, []string{i.memberValue(userEntry)})
+	if i.requiresMember() {
+		for _, m := range group.GetEqualFoldAttributeValues(i.Group.Schema.Member) {
+			if isMemberPlaceholder(group, m) {
+				mr.Delete(i.Group.Schema.Member, []string{m})
+			}
+		}
+	}
+	log.Debug().Str("backend", "ldap").Str("dn", group.DN).Str("member", userEntry.DN).Msg("LDAP Add group member")
+	if err := lc.Modify(mr); err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultAttributeOrValueExists) {
+		return ldapWriteError(err, group.DN)
+	}
+	i.purgeMembershipCache()
+	return nil
+}
+
+// RemoveLDAPGroupMember removes the user from the members of the group.
+// Removing a user that is no member is a no-op.
+func (i *Identity) RemoveLDAPGroupMember(log *zerolog.Logger, lc ldap.Client, group, userEntry *ldap.Entry) error {
+	if err := i.checkWritable(); err != nil {
+		return err
+	}
+
+	mr := ldap.NewModifyRequest(group.DN, nil)
+	value := i.memberValue(userEntry)
+	members := group.GetEqualFoldAttributeValues(i.Group.Schema.Member)
+	if i.requiresMember() && len(members) == 1 && strings.EqualFold(members[0], value) {
+		// groupOfNames must not become empty, fall back to the placeholder
+		mr.Replace(i.Group.Schema.Member, []string{group.DN})
+	} else {
+		mr.Delete(i.Group.Schema.Member, []string{value})
+	}
+	log.Debug().Str("backend", "ldap").Str("dn", group.DN).Str("member", userEntry.DN).Msg("LDAP Remove group member")
+	if err := lc.Modify(mr); err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchAttribute) {
+		return ldapWriteError(err, group.DN)
+	}
+	i.purgeMembershipCache()
+	return nil
+}
+
+func (i *Identity) checkWritable() error {
+	if !i.WriteEnabled {
+		return errtypes.NotSupported("ldap write support is disabled")
+	}
+	return nil
+}
+
+// requiresMember reports whether the group objectclass needs at least one
+// member, which is the case for groupOfNames and groupOfUniqueNames.
+func (i *Identity) requiresMember() bool {
+	return strings.ToLower(i.Group.Objectclass) != "posixgroup"
+}
+
+// isMemberPlaceholder reports whether the member value only keeps an empty
+// group valid. Groups created by reva reference themselves, other tools use
+// an empty DN.
+func isMemberPlaceholder(group *ldap.Entry, member string) bool {
+	return member == "" || strings.EqualFold(member, group.DN)
+}
+
+func (i *Identity) purgeMembershipCache() {
+	if i.Group.membershipCache != nil {
+		i.Group.membershipCache.purge()
+	}
+}
+
+func newAddRequest(dn string, classes []string, attrs map[string][]string) *ldap.AddRequest {
+	ar := ldap.NewAddRequest(dn, nil)
+	ar.Attribute("objectClass", classes)
+	for attr, values := range attrs {
+		ar.Attribute(attr, values)
+	}
+	return ar
+}
+
+// objectclasses returns the configured classes for new entries followed by
+// the class used in search filters, without duplicates.
+func objectclasses(create []string, search string) []string {
+	classes := make([]string, 0, len(create)+1)
+	for _, c := range append(create, search) {
+		if c != "" && !hasObjectclass(classes, c) {
+			classes = append(classes, c)
+		}
+	}
+	return classes
+}
+
+func hasObjectclass(classes []string, class string) bool {
+	for _, c := range classes {
+		if strings.EqualFold(c, class) {
+			return true
+		}
+	}
+	return false
+}
+
+func ldapWriteError(err error, name string) error {
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists):
+		return errtypes.AlreadyExists(name)
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return errtypes.NotFound(name)
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights):
+		return errtypes.PermissionDenied(name)
+	}
+	return err
+}
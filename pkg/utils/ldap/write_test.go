@@ -0,0 +1,181 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	identityUser "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/opencloud-eu/reva/v2/pkg/errtypes"
+	"github.com/rs/zerolog"
+)
+
+// writableDirectory records the write requests and adds created entries to the fake directory
+type writableDirectory struct {
+	*fakeDirectory
+	adds      []*ldap.AddRequest
+	modifies  []*ldap.ModifyRequest
+	passwords []*ldap.PasswordModifyRequest
+}
+
+func (d *writableDirectory) Add(ar *ldap.AddRequest) error {
+	d.adds = append(d.adds, ar)
+	attrs := map[string][]string{}
+	for _, a := range ar.Attributes {
+		attrs[a.Type] = a.Vals
+	}
+	d.entries = append(d.entries, ldap.NewEntry(ar.DN, attrs))
+	return nil
+}
+
+func (d *writableDirectory) Modify(mr *ldap.ModifyRequest) error {
+	d.modifies = append(d.modifies, mr)
+	return nil
+}
+
+func (d *writableDirectory) PasswordModify(pr *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	d.passwords = append(d.passwords, pr)
+	return &ldap.PasswordModifyResult{}, nil
+}
+
+func TestWriteDisabled(t *testing.T) {
+	i := newTestIdentity(t, nestedNone, 0)
+	log := zerolog.Nop()
+	_, err := i.AddLDAPUser(&log, &writableDirectory{fakeDirectory: newFakeDirectory()}, &identityUser.User{Username: "carol"})
+	if _, ok := err.(errtypes.NotSupported); !ok {
+		t.Fatalf("expected not supported error, got %v", err)
+	}
+}
+
+func TestAddLDAPUser(t *testing.T) {
+	i := newTestIdentity(t, nestedNone, 0)
+	i.WriteEnabled = true
+	log := zerolog.Nop()
+	d := &writableDirectory{fakeDirectory: newFakeDirectory()}
+
+	entry, err := i.AddLDAPUser(&log, d, &identityUser.User{Username: "carol", Mail: "carol@example.org"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.DN != "cn=carol,ou=users,dc=test" {
+		t.Fatalf("unexpected dn %s", entry.DN)
+	}
+
+	added := ldap.NewEntry(d.adds[0].DN, map[string][]string{})
+	for _, a := range d.adds[0].Attributes {
+		added.Attributes = append(added.Attributes, ldap.NewEntryAttribute(a.Type, a.Vals))
+	}
+	for attr, want := range map[string]string{"sn": "carol", "mail": "carol@example.org"} {
+		if got := added.GetEqualFoldAttributeValue(attr); got != want {
+			t.Errorf("attribute %s: expected %q, got %q", attr, want, got)
+		}
+	}
+	if added.GetEqualFoldAttributeValue("uid") == "" {
+		t.Errorf("expected a generated id")
+	}
+	if added.GetEqualFoldAttributeValue("displayName") != "" {
+		t.Errorf("empty attributes must not be written")
+	}
+	if added.GetEqualFoldAttributeValue("userPassword") != "" {
+		t.Errorf("the password must not be written as attribute")
+	}
+	if len(d.passwords) != 0 {
+		t.Errorf("expected no password modify request, got %+v", d.passwords)
+	}
+}
+
+func TestSetLDAPPassword(t *testing.T) {
+	i := newTestIdentity(t, nestedNone, 0)
+	i.WriteEnabled = true
+	log := zerolog.Nop()
+	d := &writableDirectory{fakeDirectory: newFakeDirectory()}
+
+	user := ldap.NewEntry("uid=alice,ou=users,dc=test", map[string][]string{"mail": {"alice@example.org"}})
+	if err := i.UpdateLDAPUser(&log, d, user, &identityUser.User{Mail: "alice@example.org"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := i.SetLDAPPassword(&log, d, user.DN, "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.modifies) != 0 {
+		t.Errorf("expected no modify request, got %+v", d.modifies)
+	}
+	if len(d.passwords) != 1 || d.passwords[0].UserIdentity != user.DN || d.passwords[0].NewPassword != "secret" {
+		t.Errorf("expected a password modify request for %s, got %+v", user.DN, d.passwords)
+	}
+}
+
+func TestAddLDAPGroupPlaceholder(t *testing.T) {
+	i := newTestIdentity(t, nestedNone, 0)
+	i.WriteEnabled = true
+	log := zerolog.Nop()
+	d := &writableDirectory{fakeDirectory: newFakeDirectory()}
+
+	// the fake directory can't search groups by name, only the add request matters
+	_, _ = i.AddLDAPGroup(&log, d, &grouppb.Group{GroupName: "empty"})
+	if len(d.adds) != 1 {
+		t.Fatalf("expected one add request, got %d", len(d.adds))
+	}
+	for _, a := range d.adds[0].Attributes {
+		if a.Type == "member" && (len(a.Vals) != 1 || a.Vals[0] != d.adds[0].DN) {
+			t.Fatalf("expected the group to reference itself, got %v", a.Vals)
+		}
+	}
+
+	user := ldap.NewEntry("uid=alice,ou=users,dc=test", nil)
+	group := ldap.NewEntry(d.adds[0].DN, map[string][]string{"member": {d.adds[0].DN}})
+	if err := i.AddLDAPGroupMember(&log, d, group, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changes := d.modifies[0].Changes
+	if len(changes) != 2 || changes[1].Operation != ldap.DeleteAttribute || changes[1].Modification.Vals[0] != group.DN {
+		t.Fatalf("expected the placeholder to be removed, got %+v", changes)
+	}
+	if members := i.getDirectMembers(&log, d, group); len(members) != 0 {
+		t.Fatalf("the placeholder must not be returned as member, got %v", members)
+	}
+}
+
+func TestRemoveLastGroupMemberKeepsPlaceholder(t *testing.T) {
+	i := newTestIdentity(t, nestedNone, 0)
+	i.WriteEnabled = true
+	log := zerolog.Nop()
+	d := &writableDirectory{fakeDirectory: newFakeDirectory()}
+
+	user := ldap.NewEntry("uid=alice,ou=users,dc=test", nil)
+	group := ldap.NewEntry("cn=solo,ou=groups,dc=test", map[string][]string{"member": {"uid=alice,ou=users,dc=test"}})
+	if err := i.RemoveLDAPGroupMember(&log, d, group, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	change := d.modifies[0].Changes[0]
+	if change.Operation != ldap.ReplaceAttribute || len(change.Modification.Vals) != 1 || change.Modification.Vals[0] != group.DN {
+		t.Fatalf("expected the member to be replaced by the placeholder, got %+v", change)
+	}
+
+	group = ldap.NewEntry("cn=pair,ou=groups,dc=test", map[string][]string{"member": {"uid=alice,ou=users,dc=test", "uid=bob,ou=users,dc=test"}})
+	if err := i.RemoveLDAPGroupMember(&log, d, group, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	change = d.modifies[1].Changes[0]
+	if change.Operation != ldap.DeleteAttribute || change.Modification.Vals[0] != "uid=alice,ou=users,dc=test" {
+		t.Fatalf("expected the member value to be deleted, got %+v", change)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+)
+
+const (
+	// unixEpochTicks is the number of .NET ticks (100ns intervals since
+	// 0001-01-01) at the unix epoch.
+	unixEpochTicks = 621355968000000000
+	// maxProofAge is the maximum age of a signed request accepted by the host,
+	// timestamps ahead of the clock of the host are allowed the same skew.
+	maxProofAge = 20 * time.Minute
+)
+
+// ErrProofInvalid is returned when the proof headers of a request don't
+// match the proof keys of the WOPI client.
+var ErrProofInvalid = errors.New("wopi: invalid proof")
+
+// ProofKeys holds the current and the previous public key WOPI clients use to
+// sign their requests.
+type ProofKeys struct {
+	Current *rsa.PublicKey
+	Old     *rsa.PublicKey
+}
+
+// ParseProofKeys reads the proof keys from a WOPI discovery document. It
+// returns nil if the discovery does not announce proof keys.
+func ParseProofKeys(discovery io.Reader) (*ProofKeys, error) {
+	doc := etree.NewDocument()
+	if _, err := doc.ReadFrom(discovery); err != nil {
+		return nil, err
+	}
+	root := doc.SelectElement("wopi-discovery")
+	if root == nil {
+		return nil, errors.New("wopi-discovery response malformed")
+	}
+	el := root.SelectElement("proof-key")
+	if el == nil {
+		return nil, nil
+	}
+
+	current, err := publicKey(el.SelectAttrValue("modulus", ""), el.SelectAttrValue("exponent", ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: invalid proof key")
+	}
+	keys := &ProofKeys{Current: current}
+	if old, err := publicKey(el.SelectAttrValue("oldmodulus", ""), el.SelectAttrValue("oldexponent", "")); err == nil {
+		keys.Old = old
+	}
+	return keys, nil
+}
+
+// Verify checks the proof headers of a request, see
+// https://learn.microsoft.com/en-us/microsoft-365/cloud-storage-partner-program/online/scenarios/proofkeys
+// The url must be the full request url including the access token.
+func (k *ProofKeys) Verify(accessToken, url, timestamp, proof, oldProof string, now time.Time) error {
+	ticks, err := parseTicks(timestamp)
+	if err != nil {
+		return err
+	}
+	if age := now.Sub(TicksToTime(ticks)); age > maxProofAge || age < -maxProofAge {
+		return errors.Wrap(ErrProofInvalid, "request timestamp out of range")
+	}
+
+	expected := ExpectedProof(accessToken, url, ticks)
+	switch {
+	case verify(k.Current, expected, proof),
+		verify(k.Current, expected, oldProof),
+		verify(k.Old, expected, proof):
+		return nil
+	}
+	return ErrProofInvalid
+}
+
+// ExpectedProof returns the bytes signed by the WOPI client.
+func ExpectedProof(accessToken, url string, ticks int64) []byte {
+	var b bytes.Buffer
+	write := func(data []byte) {
+		_ = binary.Write(&b, binary.BigEndian, int32(len(data)))
+		b.Write(data)
+	}
+	write([]byte(accessToken))
+	write([]byte(strings.ToUpper(url)))
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(ticks))
+	write(ts)
+	return b.Bytes()
+}
+
+// TimeToTicks converts a time to .NET ticks as used by the X-WOPI-TimeStamp header.
+func TimeToTicks(t time.Time) int64 {
+	return t.UnixNano()/100 + unixEpochTicks
+}
+
+// TicksToTime converts .NET ticks to a time.
+func TicksToTime(ticks int64) time.Time {
+	return time.Unix(0, (ticks-unixEpochTicks)*100)
+}
+
+func parseTicks(timestamp string) (int64, error) {
+	var ticks int64
+	for _, c := range timestamp {
+		if c < '0' || c > '9' {
+			return 0, errors.Wrap(ErrProofInvalid, "malformed timestamp")
+		}
+		ticks = ticks*10 + int64(c-'0')
+	}
+	if ticks == 0 {
+		return 0, errors.Wrap(ErrProofInvalid, "missing timestamp")
+	}
+	return ticks, nil
+}
+
+func verify(key *rsa.PublicKey, expected []byte, proof string) bool {
+	if key == nil || proof == "" {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(proof)
+	if err != nil {
+		return false
+	}
+	hashed := sha256.Sum256(expected)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig) == nil
+}
+
+func publicKey(modulus, exponent string) (*rsa.PublicKey, error) {
+	if modulus == "" || exponent == "" {
+		return nil, errors.New("missing modulus or exponent")
+	}
+	n, err := base64.StdEncoding.DecodeString(modulus)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.StdEncoding.DecodeString(exponent)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package wopi contains the building blocks shared by the WOPI app provider
+// and reva's built-in WOPI host: access tokens, file ids and the validation
+// of proof keys sent by WOPI clients.
+package wopi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"time"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/opencloud-eu/reva/v2/pkg/storagespace"
+	"github.com/pkg/errors"
+)
+
+// Claims are the claims of a WOPI access token. The token is handed to the
+// WOPI client, so the reva token it carries is encrypted.
+type Claims struct {
+	jwt.RegisteredClaims
+	// FileID is the WOPI file id the token was issued for
+	FileID string `json:"fid"`
+	// ViewMode is the app provider view mode the file was opened with
+	ViewMode appprovider.ViewMode `json:"vm"`
+	// User is the id of the user that opened the file
+	User *userpb.UserId `json:"u"`
+	// UserName is the display name shown by the WOPI client
+	UserName string `json:"un,omitempty"`
+	// PublicShare is set for users accessing the file through a public link
+	PublicShare bool `json:"ps,omitempty"`
+	// RevaToken is the encrypted reva access token used to call the gateway
+	RevaToken string `json:"rt"`
+}
+
+// CanWrite returns true if the token was issued for editing.
+func (c *Claims) CanWrite() bool {
+	return c.ViewMode == appprovider.ViewMode_VIEW_MODE_READ_WRITE
+}
+
+// HKDF info strings used to derive independent keys for signing the access
+// token and encrypting the reva token from the configured secret.
+const (
+	signingKeyInfo    = "reva wopi access token signing"
+	encryptionKeyInfo = "reva wopi reva token encryption"
+)
+
+// NewAccessToken returns a signed WOPI access token for the claims. The token
+// is signed and the reva token encrypted with separate keys derived from the
+// secret.
+func NewAccessToken(secret string, claims Claims, revaToken string, expiration time.Time) (string, error) {
+	encrypted, err := encrypt(secret, revaToken)
+	if err != nil {
+		return "", err
+	}
+	claims.RevaToken = encrypted
+	claims.ExpiresAt = jwt.NewNumericDate(expiration)
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+
+	key, err := deriveKey(secret, signingKeyInfo)
+	if err != nil {
+		return "", err
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tkn, err := t.SignedString(key)
+	if err != nil {
+		return "", errors.Wrap(err, "wopi: error signing access token")
+	}
+	return tkn, nil
+}
+
+// ParseAccessToken verifies a WOPI access token and returns its claims and the
+// decrypted reva token.
+func ParseAccessToken(secret, tkn string) (*Claims, string, error) {
+	key, err := deriveKey(secret, signingKeyInfo)
+	if err != nil {
+		return nil, "", err
+	}
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(tkn, claims, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, "", errors.Wrap(err, "wopi: invalid access token")
+	}
+
+	revaToken, err := decrypt(secret, claims.RevaToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return claims, revaToken, nil
+}
+
+// FileID returns the WOPI file id of a resource. The id is stable, so that
+// all users editing a file join the same session in the WOPI client.
+func FileID(id *provider.ResourceId) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(storagespace.FormatResourceID(id)))
+}
+
+// ParseFileID returns the resource id encoded in a WOPI file id.
+func ParseFileID(fileID string) (*provider.ResourceId, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(fileID)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: invalid file id")
+	}
+	id, err := storagespace.ParseID(string(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: invalid file id")
+	}
+	return &id, nil
+}
+
+func deriveKey(secret, info string) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, info, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: error deriving key")
+	}
+	return key, nil
+}
+
+func gcm(secret string) (cipher.AEAD, error) {
+	key, err := deriveKey(secret, encryptionKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encrypt(secret, plain string) (string, error) {
+	aead, err := gcm(secret)
+	if err != nil {
+		return "", errors.Wrap(err, "wopi: error creating cipher")
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "wopi: error generating nonce")
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func decrypt(secret, encrypted string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.Wrap(err, "wopi: malformed reva token")
+	}
+	aead, err := gcm(secret)
+	if err != nil {
+		return "", errors.Wrap(err, "wopi: error creating cipher")
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", errors.New("wopi: malformed reva token")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.Wrap(err, "wopi: error decrypting reva token")
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode/utf16"
+)
+
+// WOPI encodes file names in headers with UTF-7 (RFC 2152).
+
+var utf7Encoding = base64.StdEncoding.WithPadding(base64.NoPadding)
+
+// DecodeUTF7 decodes a UTF-7 encoded header value.
+func DecodeUTF7(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '+' {
+			out.WriteByte(s[i])
+			continue
+		}
+		end := i + 1
+		for end < len(s) && isBase64(s[end]) {
+			end++
+		}
+		if end == i+1 {
+			// "+-" is an escaped plus sign
+			out.WriteByte('+')
+		} else {
+			out.WriteString(decodeUTF16(s[i+1 : end]))
+		}
+		if end < len(s) && s[end] == '-' {
+			end++
+		}
+		i = end - 1
+	}
+	return out.String()
+}
+
+// EncodeUTF7 encodes a value with UTF-7 for use in a WOPI header.
+func EncodeUTF7(s string) string {
+	var out strings.Builder
+	var pending []rune
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		units := utf16.Encode(pending)
+		raw := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			raw = append(raw, byte(u>>8), byte(u))
+		}
+		out.WriteByte('+')
+		out.WriteString(utf7Encoding.EncodeToString(raw))
+		out.WriteByte('-')
+		pending = pending[:0]
+	}
+	for _, r := range s {
+		switch {
+		case r == '+':
+			flush()
+			out.WriteString("+-")
+		case r >= 0x20 && r < 0x7f && r != '\\' && r != '~':
+			flush()
+			out.WriteRune(r)
+		default:
+			pending = append(pending, r)
+		}
+	}
+	flush()
+	return out.String()
+}
+
+func decodeUTF16(b64 string) string {
+	raw, err := utf7Encoding.DecodeString(b64)
+	if err != nil {
+		return b64
+	}
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func isBase64(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/'
+}
@@ -0,0 +1,211 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAccessToken(t *testing.T) {
+	claims := Claims{
+		FileID:   "file",
+		ViewMode: appprovider.ViewMode_VIEW_MODE_READ_WRITE,
+		User:     &userpb.UserId{Idp: "idp", OpaqueId: "einstein"},
+		UserName: "Albert Einstein",
+	}
+	tkn, err := NewAccessToken("secret", claims, "reva-token", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(tkn, "reva-token") {
+		t.Fatal("access token leaks the reva token")
+	}
+
+	parsed, revaToken, err := ParseAccessToken("secret", tkn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revaToken != "reva-token" {
+		t.Errorf("expected reva token %q, got %q", "reva-token", revaToken)
+	}
+	if parsed.FileID != "file" || !parsed.CanWrite() || !proto.Equal(parsed.User, claims.User) || parsed.UserName != claims.UserName {
+		t.Errorf("unexpected claims %+v", parsed)
+	}
+
+	if _, _, err := ParseAccessToken("other", tkn); err == nil {
+		t.Error("expected an error for a token signed with another secret")
+	}
+
+	// the secret itself is not used as signing key
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, parsed).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseAccessToken("secret", raw); err == nil {
+		t.Error("expected an error for a token signed with the raw secret")
+	}
+	signing, _ := deriveKey("secret", signingKeyInfo)
+	encryption, _ := deriveKey("secret", encryptionKeyInfo)
+	if string(signing) == string(encryption) {
+		t.Error("signing and encryption keys must differ")
+	}
+
+	expired, err := NewAccessToken("secret", claims, "reva-token", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseAccessToken("secret", expired); err == nil {
+		t.Error("expected an error for an expired token")
+	}
+}
+
+func TestFileID(t *testing.T) {
+	id := &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"}
+	parsed, err := ParseFileID(FileID(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(parsed, id) {
+		t.Errorf("expected %v, got %v", id, parsed)
+	}
+	if _, err := ParseFileID("%%%"); err == nil {
+		t.Error("expected an error for a malformed file id")
+	}
+}
+
+func TestUTF7(t *testing.T) {
+	tests := map[string]string{
+		"report.docx":  "report.docx",
+		"a+b.docx":     "a+-b.docx",
+		"Résumé.odt":   "R+AOk-sum+AOk-.odt",
+		"日本語.xlsx":     "+ZeVnLIqe-.xlsx",
+		"smile 😀.pptx": "smile +2D3eAA-.pptx",
+	}
+	for plain, encoded := range tests {
+		if got := EncodeUTF7(plain); got != encoded {
+			t.Errorf("EncodeUTF7(%q) = %q, expected %q", plain, got, encoded)
+		}
+		if got := DecodeUTF7(encoded); got != plain {
+			t.Errorf("DecodeUTF7(%q) = %q, expected %q", encoded, got, plain)
+		}
+	}
+	// the terminating dash is optional before characters outside of base64
+	if got := DecodeUTF7("R+AOk sum"); got != "Ré sum" {
+		t.Errorf("unexpected decoding %q", got)
+	}
+}
+
+func TestProofKeys(t *testing.T) {
+	current, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discovery := fmt.Sprintf(`<wopi-discovery><net-zone name="external-http"/><proof-key oldvalue="" oldmodulus="%s" oldexponent="%s" value="" modulus="%s" exponent="%s"/></wopi-discovery>`,
+		modulus(old), exponent(old), modulus(current), exponent(current))
+	keys, err := ParseProofKeys(strings.NewReader(discovery))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys == nil || !keys.Current.Equal(&current.PublicKey) || !keys.Old.Equal(&old.PublicKey) {
+		t.Fatal("unexpected proof keys")
+	}
+
+	none, err := ParseProofKeys(strings.NewReader(`<wopi-discovery><net-zone name="external-http"/></wopi-discovery>`))
+	if err != nil || none != nil {
+		t.Fatalf("expected no proof keys, got %v, %v", none, err)
+	}
+
+	now := time.Now()
+	url := "https://cloud.example.com/wopi/files/abc?access_token=tkn"
+	ticks := TimeToTicks(now)
+	ts := fmt.Sprint(ticks)
+	tests := []struct {
+		name      string
+		proof     string
+		oldProof  string
+		timestamp string
+		valid     bool
+	}{
+		{"current key", sign(t, current, "tkn", url, ticks), "", ts, true},
+		{"current key in old proof", sign(t, other, "tkn", url, ticks), sign(t, current, "tkn", url, ticks), ts, true},
+		{"old key", sign(t, old, "tkn", url, ticks), "", ts, true},
+		{"old key in old proof", "", sign(t, old, "tkn", url, ticks), ts, false},
+		{"unknown key", sign(t, other, "tkn", url, ticks), "", ts, false},
+		{"other url", sign(t, current, "tkn", url+"x", ticks), "", ts, false},
+		{"stale timestamp", sign(t, current, "tkn", url, TimeToTicks(now.Add(-time.Hour))), "", fmt.Sprint(TimeToTicks(now.Add(-time.Hour))), false},
+		{"future timestamp", sign(t, current, "tkn", url, TimeToTicks(now.Add(time.Hour))), "", fmt.Sprint(TimeToTicks(now.Add(time.Hour))), false},
+		{"skewed timestamp", sign(t, current, "tkn", url, TimeToTicks(now.Add(time.Minute))), "", fmt.Sprint(TimeToTicks(now.Add(time.Minute))), true},
+		{"missing timestamp", sign(t, current, "tkn", url, ticks), "", "", false},
+	}
+	for _, tt := range tests {
+		err := keys.Verify("tkn", url, tt.timestamp, tt.proof, tt.oldProof, now)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// the url is compared case insensitively
+	if err := keys.Verify("tkn", strings.ToLower(url), ts, sign(t, current, "tkn", url, ticks), "", now); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, accessToken, url string, ticks int64) string {
+	hashed := sha256.Sum256(ExpectedProof(accessToken, url, ticks))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func modulus(key *rsa.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.N.Bytes())
+}
+
+func exponent(key *rsa.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+}